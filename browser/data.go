@@ -0,0 +1,171 @@
+package browser
+
+import (
+	"sort"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+)
+
+type (
+	// Dataset holds the results of a database which can be browsed. The
+	// results are loaded once so navigating between views does not query
+	// MongoDB again.
+	Dataset struct {
+		Name         string
+		Beacons      []beacon.Result
+		SNIBeacons   []beaconsni.Result
+		ProxyBeacons []beaconproxy.Result
+		LongConns    []uconn.LongConnResult
+	}
+
+	// HostProfile gathers the results which involve a single host
+	HostProfile struct {
+		IP              string
+		BeaconsAsSrc    []beacon.Result
+		BeaconsAsDst    []beacon.Result
+		SNIBeacons      []beaconsni.Result
+		ProxyBeacons    []beaconproxy.Result
+		LongConns       []uconn.LongConnResult
+		MaxBeaconScore  float64
+		TotalConns      int64
+		TotalBytes      int64
+		LongestDuration float64
+	}
+
+	// loaders reads the results of each module from the database. The
+	// existing read paths are used in RITA while tests substitute their own.
+	loaders struct {
+		beacons      func(*resources.Resources, float64) ([]beacon.Result, error)
+		sniBeacons   func(*resources.Resources, float64) ([]beaconsni.Result, error)
+		proxyBeacons func(*resources.Resources, float64) ([]beaconproxy.Result, error)
+		longConns    func(*resources.Resources, int, int, bool) ([]uconn.LongConnResult, error)
+	}
+)
+
+// longConnThresh is the minimum duration in seconds of the long connections
+// shown in host profiles. This matches show-long-connections.
+const longConnThresh = 60
+
+// longConnLimit caps the number of long connections loaded for host profiles
+const longConnLimit = 1000
+
+var defaultLoaders = loaders{
+	beacons:      beacon.Results,
+	sniBeacons:   beaconsni.Results,
+	proxyBeacons: beaconproxy.Results,
+	longConns:    uconn.LongConnResults,
+}
+
+// LoadDataset reads the results of the given database which are shown while
+// browsing. The results of disabled modules are skipped.
+func LoadDataset(res *resources.Resources, db string) (*Dataset, error) {
+	return loadDataset(res, db, defaultLoaders)
+}
+
+func loadDataset(res *resources.Resources, db string, load loaders) (*Dataset, error) {
+	res.DB.SelectDB(db)
+	dataset := &Dataset{Name: db}
+
+	var err error
+	if res.Config.S.Beacon.Enabled {
+		dataset.Beacons, err = load.beacons(res, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if res.Config.S.BeaconSNI.Enabled {
+		dataset.SNIBeacons, err = load.sniBeacons(res, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if res.Config.S.BeaconProxy.Enabled {
+		dataset.ProxyBeacons, err = load.proxyBeacons(res, 0)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dataset.LongConns, err = load.longConns(res, longConnThresh, longConnLimit, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return dataset, nil
+}
+
+// FilterBeacons returns the beacons scoring at least minScore, highest score first
+func (d *Dataset) FilterBeacons(minScore float64) []beacon.Result {
+	filtered := make([]beacon.Result, 0, len(d.Beacons))
+	for _, result := range d.Beacons {
+		if result.Score >= minScore {
+			filtered = append(filtered, result)
+		}
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return filtered[i].Score > filtered[j].Score
+	})
+	return filtered
+}
+
+// HostProfile gathers the results in the dataset which involve the given host
+func (d *Dataset) HostProfile(ip string) HostProfile {
+	profile := HostProfile{IP: ip}
+
+	addBeacon := func(result beacon.Result) {
+		profile.TotalConns += result.Connections
+		profile.TotalBytes += result.TotalBytes
+		if result.Score > profile.MaxBeaconScore {
+			profile.MaxBeaconScore = result.Score
+		}
+	}
+
+	for _, result := range d.Beacons {
+		if result.SrcIP == ip {
+			profile.BeaconsAsSrc = append(profile.BeaconsAsSrc, result)
+			addBeacon(result)
+		} else if result.DstIP == ip {
+			profile.BeaconsAsDst = append(profile.BeaconsAsDst, result)
+			addBeacon(result)
+		}
+	}
+
+	for _, result := range d.SNIBeacons {
+		if result.SrcIP == ip {
+			profile.SNIBeacons = append(profile.SNIBeacons, result)
+		}
+	}
+
+	for _, result := range d.ProxyBeacons {
+		if result.SrcIP == ip || viaProxy(result, ip) {
+			profile.ProxyBeacons = append(profile.ProxyBeacons, result)
+		}
+	}
+
+	for _, result := range d.LongConns {
+		if result.SrcIP == ip || result.DstIP == ip {
+			profile.LongConns = append(profile.LongConns, result)
+			if result.MaxDuration > profile.LongestDuration {
+				profile.LongestDuration = result.MaxDuration
+			}
+		}
+	}
+
+	return profile
+}
+
+// viaProxy reports whether the proxy beacon went through the proxy at ip
+func viaProxy(result beaconproxy.Result, ip string) bool {
+	for _, proxy := range result.Proxies() {
+		if proxy.IP == ip {
+			return true
+		}
+	}
+	return false
+}
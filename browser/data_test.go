@@ -0,0 +1,153 @@
+package browser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestBeacon(src, dst string, score float64, conns int64) beacon.Result {
+	return beacon.Result{
+		UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: src}, data.UniqueIP{IP: dst}),
+		Connections:  conns,
+		TotalBytes:   conns * 100,
+		Score:        score,
+	}
+}
+
+func newTestResources(t *testing.T) *resources.Resources {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	return &resources.Resources{Config: conf, DB: &database.DB{}}
+}
+
+// newTestLoaders returns loaders which serve fixed results and record which
+// modules were read
+func newTestLoaders(called map[string]bool) loaders {
+	return loaders{
+		beacons: func(res *resources.Resources, cutoff float64) ([]beacon.Result, error) {
+			called["beacons"] = true
+			return []beacon.Result{
+				newTestBeacon("10.0.0.1", "8.8.8.8", 0.9, 100),
+				newTestBeacon("10.0.0.2", "1.1.1.1", 0.5, 50),
+				newTestBeacon("10.0.0.1", "1.1.1.1", 0.7, 30),
+			}, nil
+		},
+		sniBeacons: func(res *resources.Resources, cutoff float64) ([]beaconsni.Result, error) {
+			called["sni"] = true
+			return []beaconsni.Result{{
+				UniqueSrcFQDNPair: data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: "10.0.0.1"}, "c2.example.com"),
+				Score:             0.8,
+			}}, nil
+		},
+		proxyBeacons: func(res *resources.Resources, cutoff float64) ([]beaconproxy.Result, error) {
+			called["proxy"] = true
+			return []beaconproxy.Result{{
+				SrcIP:     "10.0.0.2",
+				FQDN:      "proxied.example.com",
+				Proxy:     data.UniqueIP{IP: "10.0.0.254"},
+				ProxyList: []data.UniqueIP{{IP: "10.0.0.253"}, {IP: "10.0.0.254"}},
+			}}, nil
+		},
+		longConns: func(res *resources.Resources, thresh, limit int, noLimit bool) ([]uconn.LongConnResult, error) {
+			called["long"] = true
+			return []uconn.LongConnResult{{
+				UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "4.4.4.4"}),
+				MaxDuration:  7200,
+			}}, nil
+		},
+	}
+}
+
+func TestLoadDataset(t *testing.T) {
+	res := newTestResources(t)
+	called := make(map[string]bool)
+
+	dataset, err := loadDataset(res, "testdb", newTestLoaders(called))
+	require.Nil(t, err)
+
+	assert.Equal(t, "testdb", res.DB.GetSelectedDB(), "the browsed database should be selected")
+	assert.Equal(t, "testdb", dataset.Name)
+	assert.Len(t, dataset.Beacons, 3)
+	assert.Len(t, dataset.SNIBeacons, 1)
+	assert.Len(t, dataset.ProxyBeacons, 1)
+	assert.Len(t, dataset.LongConns, 1)
+
+	// disabled modules are not read
+	res.Config.S.BeaconSNI.Enabled = false
+	res.Config.S.BeaconProxy.Enabled = false
+	called = make(map[string]bool)
+	dataset, err = loadDataset(res, "testdb", newTestLoaders(called))
+	require.Nil(t, err)
+	assert.True(t, called["beacons"])
+	assert.False(t, called["sni"])
+	assert.False(t, called["proxy"])
+	assert.Empty(t, dataset.SNIBeacons)
+	assert.Empty(t, dataset.ProxyBeacons)
+
+	// read errors are returned
+	failing := newTestLoaders(make(map[string]bool))
+	failing.longConns = func(res *resources.Resources, thresh, limit int, noLimit bool) ([]uconn.LongConnResult, error) {
+		return nil, errors.New("connection lost")
+	}
+	_, err = loadDataset(res, "testdb", failing)
+	assert.NotNil(t, err)
+}
+
+func TestFilterBeacons(t *testing.T) {
+	dataset, err := loadDataset(newTestResources(t), "testdb", newTestLoaders(make(map[string]bool)))
+	require.Nil(t, err)
+
+	all := dataset.FilterBeacons(0)
+	require.Len(t, all, 3)
+	assert.Equal(t, 0.9, all[0].Score)
+	assert.Equal(t, 0.7, all[1].Score)
+	assert.Equal(t, 0.5, all[2].Score)
+
+	top := dataset.FilterBeacons(0.7)
+	require.Len(t, top, 2, "the minimum score is inclusive")
+	assert.Equal(t, "8.8.8.8", top[0].DstIP)
+
+	assert.Empty(t, dataset.FilterBeacons(0.95))
+}
+
+func TestHostProfile(t *testing.T) {
+	dataset, err := loadDataset(newTestResources(t), "testdb", newTestLoaders(make(map[string]bool)))
+	require.Nil(t, err)
+
+	src := dataset.HostProfile("10.0.0.1")
+	assert.Len(t, src.BeaconsAsSrc, 2)
+	assert.Empty(t, src.BeaconsAsDst)
+	assert.Len(t, src.SNIBeacons, 1)
+	assert.Empty(t, src.ProxyBeacons)
+	assert.Len(t, src.LongConns, 1)
+	assert.Equal(t, 0.9, src.MaxBeaconScore)
+	assert.Equal(t, int64(130), src.TotalConns)
+	assert.Equal(t, int64(13000), src.TotalBytes)
+	assert.Equal(t, 7200.0, src.LongestDuration)
+
+	dst := dataset.HostProfile("1.1.1.1")
+	assert.Empty(t, dst.BeaconsAsSrc)
+	assert.Len(t, dst.BeaconsAsDst, 2)
+	assert.Equal(t, 0.7, dst.MaxBeaconScore)
+
+	// proxies are profiled through the beacons relayed through them
+	proxy := dataset.HostProfile("10.0.0.254")
+	assert.Len(t, proxy.ProxyBeacons, 1)
+	assert.Len(t, dataset.HostProfile("10.0.0.253").ProxyBeacons, 1, "including the proxies after the first")
+
+	unknown := dataset.HostProfile("192.168.1.1")
+	assert.Empty(t, unknown.BeaconsAsSrc)
+	assert.Empty(t, unknown.LongConns)
+	assert.Equal(t, 0.0, unknown.MaxBeaconScore)
+}
@@ -0,0 +1,218 @@
+package browser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	beaconsPage = "beacons"
+	profilePage = "profile"
+
+	beaconsHelp = "[Enter] source profile  [d] destination profile  [/] filter by score  [q] quit"
+	profileHelp = "[Esc] back to beacons  [q] quit"
+)
+
+// ui is the interactive terminal interface used to browse a dataset
+type ui struct {
+	dataset      *Dataset
+	showNetNames bool
+	beacons      []beacon.Result // beacons currently listed in the table
+
+	app     *tview.Application
+	pages   *tview.Pages
+	table   *tview.Table
+	filter  *tview.InputField
+	status  *tview.TextView
+	profile *tview.TextView
+}
+
+// Browse runs the interactive results browser for the dataset until the user quits
+func Browse(dataset *Dataset, showNetNames bool) error {
+	return newUI(dataset, showNetNames).app.Run()
+}
+
+func newUI(dataset *Dataset, showNetNames bool) *ui {
+	u := &ui{
+		dataset:      dataset,
+		showNetNames: showNetNames,
+		app:          tview.NewApplication(),
+		pages:        tview.NewPages(),
+		table:        tview.NewTable(),
+		filter:       tview.NewInputField(),
+		status:       tview.NewTextView(),
+		profile:      tview.NewTextView(),
+	}
+
+	u.table.SetSelectable(true, false).SetFixed(1, 0)
+	u.table.SetBorder(true).SetTitle(" Beacons: " + dataset.Name + " ")
+	u.table.SetSelectedFunc(func(row, column int) {
+		if result, ok := u.selectedBeacon(row); ok {
+			u.showProfile(result.SrcIP)
+		}
+	})
+	u.table.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Rune() {
+		case 'q':
+			u.app.Stop()
+			return nil
+		case '/':
+			u.app.SetFocus(u.filter)
+			return nil
+		case 'd':
+			row, _ := u.table.GetSelection()
+			if result, ok := u.selectedBeacon(row); ok {
+				u.showProfile(result.DstIP)
+			}
+			return nil
+		}
+		return event
+	})
+
+	u.filter.SetLabel("Minimum score: ").SetText("0")
+	u.filter.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEnter {
+			minScore, err := strconv.ParseFloat(strings.TrimSpace(u.filter.GetText()), 64)
+			if err != nil {
+				u.status.SetText("Invalid score: " + u.filter.GetText())
+				return
+			}
+			u.showBeacons(minScore)
+		}
+		u.app.SetFocus(u.table)
+	})
+
+	u.profile.SetBorder(true)
+	u.profile.SetDoneFunc(func(key tcell.Key) {
+		if key == tcell.KeyEscape {
+			u.pages.SwitchToPage(beaconsPage)
+			u.app.SetFocus(u.table)
+			u.status.SetText(beaconsHelp)
+		}
+	})
+	u.profile.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Rune() == 'q' {
+			u.app.Stop()
+			return nil
+		}
+		return event
+	})
+
+	beaconsView := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.filter, 1, 0, false).
+		AddItem(u.table, 0, 1, true)
+
+	u.pages.AddPage(beaconsPage, beaconsView, true, true)
+	u.pages.AddPage(profilePage, u.profile, true, false)
+
+	root := tview.NewFlex().SetDirection(tview.FlexRow).
+		AddItem(u.pages, 0, 1, true).
+		AddItem(u.status, 1, 0, false)
+
+	u.showBeacons(0)
+	u.app.SetRoot(root, true).SetFocus(u.table)
+	return u
+}
+
+// selectedBeacon returns the beacon listed in the given table row
+func (u *ui) selectedBeacon(row int) (beacon.Result, bool) {
+	// the first row holds the header
+	if row < 1 || row > len(u.beacons) {
+		return beacon.Result{}, false
+	}
+	return u.beacons[row-1], true
+}
+
+// showBeacons lists the beacons scoring at least minScore
+func (u *ui) showBeacons(minScore float64) {
+	u.beacons = u.dataset.FilterBeacons(minScore)
+
+	header := []string{"Score", "Source IP", "Destination IP", "Connections", "Avg. Bytes", "Intvl", "Size"}
+	if u.showNetNames {
+		header = []string{"Score", "Source", "Destination", "Connections", "Avg. Bytes", "Intvl", "Size"}
+	}
+
+	u.table.Clear()
+	for col, title := range header {
+		u.table.SetCell(0, col, tview.NewTableCell(title).
+			SetTextColor(tcell.ColorYellow).
+			SetSelectable(false))
+	}
+
+	for i, result := range u.beacons {
+		src, dst := result.SrcIP, result.DstIP
+		if u.showNetNames {
+			src = result.SrcNetworkName + ":" + src
+			dst = result.DstNetworkName + ":" + dst
+		}
+		row := []string{
+			fmt.Sprintf("%.3f", result.Score),
+			src,
+			dst,
+			strconv.FormatInt(result.Connections, 10),
+			fmt.Sprintf("%.0f", result.AvgBytes),
+			strconv.FormatInt(result.Ts.Mode, 10),
+			strconv.FormatInt(result.Ds.Mode, 10),
+		}
+		for col, text := range row {
+			u.table.SetCell(i+1, col, tview.NewTableCell(text))
+		}
+	}
+	u.table.Select(1, 0).ScrollToBeginning()
+
+	u.status.SetText(fmt.Sprintf("%d of %d beacons  %s", len(u.beacons), len(u.dataset.Beacons), beaconsHelp))
+}
+
+// showProfile switches to the profile of the given host
+func (u *ui) showProfile(ip string) {
+	u.profile.SetTitle(" Host: " + ip + " ")
+	u.profile.SetText(formatHostProfile(u.dataset.HostProfile(ip))).ScrollToBeginning()
+	u.pages.SwitchToPage(profilePage)
+	u.app.SetFocus(u.profile)
+	u.status.SetText(profileHelp)
+}
+
+// formatHostProfile renders a host profile as aligned plain text
+func formatHostProfile(profile HostProfile) string {
+	var out strings.Builder
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintf(w, "Highest beacon score:\t%.3f\n", profile.MaxBeaconScore)
+	fmt.Fprintf(w, "Beacon connections:\t%d\n", profile.TotalConns)
+	fmt.Fprintf(w, "Beacon bytes:\t%d\n", profile.TotalBytes)
+	fmt.Fprintf(w, "Longest connection:\t%.0fs\n", profile.LongestDuration)
+
+	fmt.Fprintf(w, "\nBeacons from this host (%d)\n", len(profile.BeaconsAsSrc))
+	for _, result := range profile.BeaconsAsSrc {
+		fmt.Fprintf(w, "  %.3f\t-> %s\t%d conns\n", result.Score, result.DstIP, result.Connections)
+	}
+
+	fmt.Fprintf(w, "\nBeacons to this host (%d)\n", len(profile.BeaconsAsDst))
+	for _, result := range profile.BeaconsAsDst {
+		fmt.Fprintf(w, "  %.3f\t<- %s\t%d conns\n", result.Score, result.SrcIP, result.Connections)
+	}
+
+	fmt.Fprintf(w, "\nSNI beacons (%d)\n", len(profile.SNIBeacons))
+	for _, result := range profile.SNIBeacons {
+		fmt.Fprintf(w, "  %.3f\t-> %s\t%d conns\n", result.Score, result.FQDN, result.Connections)
+	}
+
+	fmt.Fprintf(w, "\nProxy beacons (%d)\n", len(profile.ProxyBeacons))
+	for _, result := range profile.ProxyBeacons {
+		fmt.Fprintf(w, "  %.3f\t-> %s via %s\t%d conns\n", result.Score, result.FQDN, result.Proxy.IP, result.Connections)
+	}
+
+	fmt.Fprintf(w, "\nLong connections (%d)\n", len(profile.LongConns))
+	for _, result := range profile.LongConns {
+		fmt.Fprintf(w, "  %.0fs\t%s -> %s\t%s\n", result.MaxDuration, result.SrcIP, result.DstIP, strings.Join(result.Tuples, " "))
+	}
+
+	w.Flush()
+	return out.String()
+}
@@ -0,0 +1,43 @@
+package commands
+
+import (
+	"github.com/activecm/rita/browser"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "browse",
+		Usage:     "Interactively browse the beacons and host profiles of a database",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			netNamesFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			if db == "" {
+				return cli.NewExitError("Specify a database", -1)
+			}
+
+			res := initResources(c)
+
+			dataset, err := browser.LoadDataset(res, db)
+			if err != nil {
+				res.Log.Error(err)
+				return cli.NewExitError(err, -1)
+			}
+
+			if len(dataset.Beacons) == 0 {
+				return cli.NewExitError("No beacons were found for "+db, -1)
+			}
+
+			err = browser.Browse(dataset, c.Bool("network-names"))
+			if err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
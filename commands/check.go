@@ -0,0 +1,94 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/pkg/integrity"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	check := cli.Command{
+		Name:      "check",
+		Usage:     "Check a database for orphaned records and dangling chunk references",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.BoolFlag{
+				Name:  "repair",
+				Usage: "Remove the orphaned records and dangling chunk data which are found",
+			},
+		},
+		Action: checkDatabase,
+	}
+
+	bootstrapCommands(check)
+}
+
+// checkDatabase validates the referential consistency of a database
+func checkDatabase(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	res := initResources(c)
+
+	exists, err := res.MetaDB.DBExists(db)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if !exists {
+		return cli.NewExitError("Database "+db+" is not tracked by RITA", -1)
+	}
+
+	res.DB.SelectDB(db)
+	repo := integrity.NewMongoRepository(res.DB, res.MetaDB, res.Config, res.Log)
+
+	report, err := repo.Check()
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	if report.Consistent() {
+		fmt.Printf("Check successful: %s is consistent.\n", db)
+		return nil
+	}
+
+	printIntegrityReport(report)
+
+	if !c.Bool("repair") {
+		return cli.NewExitError("Check failed: run again with --repair to remove the records listed above.", -1)
+	}
+
+	err = repo.Repair(report)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	// make sure nothing was left behind by the repair
+	report, err = repo.Check()
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if !report.Consistent() {
+		printIntegrityReport(report)
+		return cli.NewExitError("Repair failed: issues remain in "+db, -1)
+	}
+
+	fmt.Printf("Repair successful: %s is consistent.\n", db)
+	return nil
+}
+
+func printIntegrityReport(report integrity.Report) {
+	fmt.Printf("Found the following issues in %s:\n", report.Database)
+	for _, orphans := range report.Orphans {
+		fmt.Printf("\t[-] %d document(s) in %s have no matching record in %s\n",
+			len(orphans.DocumentIDs), orphans.Collection, orphans.ReferenceTable)
+	}
+	for _, dangling := range report.DanglingChunks {
+		fmt.Printf("\t[-] %s references untracked chunk(s) %v\n", dangling.Collection, dangling.CIDs)
+	}
+}
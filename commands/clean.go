@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 
-	"github.com/activecm/rita/resources"
 	"github.com/globalsign/mgo/bson"
 	"github.com/urfave/cli"
 )
@@ -25,10 +24,15 @@ func init() {
 
 // cleanDatabase finds and removes broken databases created by RITA
 func cleanDatabase(c *cli.Context) error {
-	res := resources.InitResources(getConfigFilePath(c))
+	res := initResources(c)
 
 	force := c.Bool("force")
 
+	err := res.DB.Writable()
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
 	ritaAnalysisCollNames := map[string]string{
 		res.Config.T.Structure.UniqueConnTable:      "Unique Connection Analysis",
 		res.Config.T.Structure.HostTable:            "Host Analysis",
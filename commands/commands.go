@@ -14,7 +14,7 @@ var (
 	// ConfigFlag specifies an alternate config file (Capitalized due to being exported)
 	ConfigFlag = cli.StringFlag{
 		Name:  "config, c",
-		Usage: "Use a specific `CONFIG_FILE` when running this command",
+		Usage: "Use a specific `CONFIG_FILE` or http(s):// URL when running this command",
 	}
 
 	// forceFlag allows users to bypass prompts
@@ -99,7 +99,7 @@ var (
 
 	blSortFlag = cli.StringFlag{
 		Name:  "sort, s",
-		Usage: "Sort by conn_count (# of connections), uconn_count (# of unique connections), total_bytes (# of bytes)",
+		Usage: "Sort by conn_count (# of connections), uconn_count (# of unique connections), total_bytes (# of bytes), confidence (weighted confidence of the blacklist feeds)",
 		Value: "conn_count",
 	}
 
@@ -116,11 +116,32 @@ var (
 		Value: ",", //default to comma-separated
 	}
 
+	// templateFlag allows users to render each result through a Go text/template
+	// instead of the built in human-readable or delimited output
+	templateFlag = cli.StringFlag{
+		Name:  "template, T",
+		Usage: "Render each result with the Go text/template `TEMPLATE`, e.g. '{{.SrcIP}} -> {{.DstIP}}'",
+	}
+
 	netNamesFlag = cli.BoolFlag{
 		Name:  "network-names, nn",
 		Usage: "Show network names associated with IP addresses. Helps when private IPs are reused across multiple physical networks.",
 	}
 
+	// ReadOnlyFlag forbids writing to the database. This makes it safe to point RITA
+	// at a read replica. (Capitalized due to being exported)
+	ReadOnlyFlag = cli.BoolFlag{
+		Name:  "read-only",
+		Usage: "Reject any command which writes to the database",
+	}
+
+	// ConnectionFlag selects one of the named MongoDB connections from the config
+	// file. (Capitalized due to being exported)
+	ConnectionFlag = cli.StringFlag{
+		Name:  "connection",
+		Usage: "Connect to the MongoDB cluster registered as `ALIAS` in the MongoDB Connections config section",
+	}
+
 	noBrowserFlag = cli.BoolFlag{
 		Name:  "no-browser, nb",
 		Usage: "Prevent auto-launching of default browser.",
@@ -138,6 +159,49 @@ func SetConfigFilePath(c *cli.Context) error {
 	return nil
 }
 
+// SetReadOnly reads the --read-only flag from the cli context and stores it in app metadata
+// to make it available to all subcommands.
+func SetReadOnly(c *cli.Context) error {
+	if c.Bool("read-only") {
+		c.App.Metadata["read-only"] = true
+	}
+	return nil
+}
+
+// SetConnection reads the --connection flag from the cli context and stores it in app metadata
+// to make it available to all subcommands.
+func SetConnection(c *cli.Context) error {
+	if connection := c.String("connection"); connection != "" {
+		c.App.Metadata["connection"] = connection
+	}
+	return nil
+}
+
+// getConnection returns the MongoDB connection alias from app metadata
+func getConnection(c *cli.Context) string {
+	connection, _ := c.App.Metadata["connection"].(string)
+	return connection
+}
+
+// isReadOnly returns true if RITA was asked not to write to the database
+func isReadOnly(c *cli.Context) bool {
+	readOnly, _ := c.App.Metadata["read-only"].(bool)
+	return readOnly
+}
+
+// initResources initializes the resources for a command, connecting to the
+// cluster selected with --connection and forbidding writes to the database
+// if --read-only was given
+func initResources(c *cli.Context) *resources.Resources {
+	return initResourcesFromFile(getConfigFilePath(c), getConnection(c), isReadOnly(c))
+}
+
+// initResourcesFromFile initializes the resources using the given config file
+// and MongoDB connection alias
+func initResourcesFromFile(configFile string, connection string, readOnly bool) *resources.Resources {
+	return resources.InitConnectionResources(configFile, connection, readOnly)
+}
+
 // getConfigFilePath returns config file path from app metadata
 func getConfigFilePath(c *cli.Context) string {
 	switch cfg := c.App.Metadata["config"].(type) {
@@ -154,7 +218,7 @@ func bootstrapCommands(commands ...cli.Command) {
 		command.Before = func(c *cli.Context) error {
 			//Get access to the logger
 			SetConfigFilePath(c)
-			res := resources.InitResources(getConfigFilePath(c))
+			res := initResources(c)
 			//Display args in logs
 			fields := log.Fields{
 				"Arguments": c.Args(),
@@ -171,6 +235,13 @@ func bootstrapCommands(commands ...cli.Command) {
 				}
 			}
 			res.Log.WithFields(fields).Info("Running Command: " + command.Name)
+
+			// the config only requires a Redaction Key when redaction is enabled there
+			if c.Bool("redact") {
+				if err := res.Config.S.Redaction.ValidateKey(); err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+			}
 			return nil
 		}
 		allCommands = append(allCommands, command)
@@ -35,7 +35,7 @@ func init() {
 
 // deleteDatabase deletes a target database
 func deleteDatabase(c *cli.Context) error {
-	res := resources.InitResources(getConfigFilePath(c))
+	res := initResources(c)
 
 	// Different command flags
 	tgt := c.Args().Get(0)
@@ -135,6 +135,11 @@ func deleteSingleDatabase(res *resources.Resources, db string, dryRun bool) erro
 	mDBExists := util.StringInSlice(db, res.MetaDB.GetDatabases())
 
 	if !dryRun {
+		err = res.DB.Writable()
+		if err != nil {
+			return err
+		}
+
 		// delete database if it exists
 		if dbExists {
 			if res.DB.Session.DB(db).DropDatabase() != nil {
@@ -0,0 +1,174 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "diff-beacons",
+		Usage:     "Print the beacons which changed between two datasets",
+		ArgsUsage: "<before database> <after database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			humanFlag,
+			delimFlag,
+			templateFlag,
+			redactFlag,
+			netNamesFlag,
+			cli.Float64Flag{
+				Name:  "min-score-delta, m",
+				Usage: "Only report score changes of at least `DELTA` (e.g. 0.1)",
+				Value: 0.1,
+			},
+			cli.BoolFlag{
+				Name:  "destinations",
+				Usage: "Also report beacons which only appear in one of the datasets",
+			},
+			cli.BoolFlag{
+				Name:  "ports",
+				Usage: "Also report changes to the port:protocol:service tuples of each beacon",
+			},
+		},
+		Action: diffBeacons,
+	}
+
+	bootstrapCommands(command)
+}
+
+func diffBeacons(c *cli.Context) error {
+	beforeDB := c.Args().Get(0)
+	afterDB := c.Args().Get(1)
+	if beforeDB == "" || afterDB == "" {
+		return cli.NewExitError("Specify the two databases to compare", -1)
+	}
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	opts := beacon.DiffOptions{
+		MinScoreDelta: c.Float64("min-score-delta"),
+		Destinations:  c.Bool("destinations"),
+		Ports:         c.Bool("ports"),
+	}
+	if opts.MinScoreDelta < 0 {
+		return cli.NewExitError("--min-score-delta must not be negative", -1)
+	}
+
+	res := initResources(c)
+
+	before, beforeTuples, err := diffBeaconsData(res, beforeDB, opts.Ports)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err, -1)
+	}
+	after, afterTuples, err := diffBeaconsData(res, afterDB, opts.Ports)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err, -1)
+	}
+
+	data := beacon.DiffResults(before, after, beforeTuples, afterTuples, opts)
+	if !(len(data) > 0) {
+		return cli.NewExitError("No changes were found between "+beforeDB+" and "+afterDB, -1)
+	}
+
+	redactBeaconDiffs(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	showNetNames := c.Bool("network-names")
+	if c.Bool("human-readable") {
+		showBeaconDiffsHuman(data, showNetNames)
+		return nil
+	}
+	showBeaconDiffsDelim(data, c.String("delimiter"), showNetNames)
+	return nil
+}
+
+// diffBeaconsData loads the beacons of a database and, if withTuples is set,
+// the port:protocol:service tuples of each pair of hosts keyed by their MapKey
+func diffBeaconsData(res *resources.Resources, db string, withTuples bool) ([]beacon.Result, map[string][]string, error) {
+	res.DB.SelectDB(db)
+
+	beacons, err := beacon.Results(res, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !withTuples {
+		return beacons, nil, nil
+	}
+
+	// only the tuples of the beacons are needed
+	pairs := make([]data.UniqueIPPair, 0, len(beacons))
+	for _, b := range beacons {
+		pairs = append(pairs, b.UniqueIPPair)
+	}
+	tupleResults, err := uconn.TupleResults(res, pairs)
+	if err != nil {
+		return nil, nil, err
+	}
+	tuples := make(map[string][]string, len(tupleResults))
+	for _, t := range tupleResults {
+		tuples[t.UniqueIPPair.MapKey()] = t.Tuples
+	}
+	return beacons, tuples, nil
+}
+
+// beaconDiffHeader returns the header for the rows created by beaconDiffRows
+func beaconDiffHeader(showNetNames bool) []string {
+	header := []string{
+		"Change", "Source IP", "Destination IP", "Old Score", "New Score", "Score Delta",
+		"Added Tuples", "Removed Tuples",
+	}
+	if showNetNames {
+		header = append([]string{"Source Network", "Destination Network"}, header...)
+	}
+	return header
+}
+
+// beaconDiffRows creates a row for each change to a beacon
+func beaconDiffRows(data []beacon.DiffResult, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		row := []string{
+			d.Change, d.SrcIP, d.DstIP, f(d.OldScore), f(d.NewScore), f(d.ScoreDelta),
+			strings.Join(d.AddedTuples, " "), strings.Join(d.RemovedTuples, " "),
+		}
+		if showNetNames {
+			row = append([]string{d.SrcNetworkName, d.DstNetworkName}, row...)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func showBeaconDiffsHuman(data []beacon.DiffResult, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(beaconDiffHeader(showNetNames))
+	table.AppendBulk(beaconDiffRows(data, showNetNames))
+	table.Render()
+}
+
+func showBeaconDiffsDelim(data []beacon.DiffResult, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(beaconDiffHeader(showNetNames), delim))
+	for _, row := range beaconDiffRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"github.com/activecm/rita/export"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:  "export-beacons",
+		Usage: "Export the beacons and proxy beacons of a database as newline delimited JSON or CSV",
+		UsageText: "rita export-beacons [command-options] <database>\n\n" +
+			"Each beacon scoring above --min-score is written as a line of JSON holding its hosts,\n" +
+			"proxy, score, and timestamp statistics. Beacons are written before proxy beacons.\n" +
+			"The csv format holds a row per beacon. Its dst column holds the FQDN of proxy beacons.",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Write the beacons as `FORMAT`: " + strings.Join(export.BeaconFormats, " or "),
+				Value: "json",
+			},
+			cli.Float64Flag{
+				Name:  "min-score, m",
+				Usage: "Only export beacons scoring above `SCORE`",
+				Value: 0,
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Write the beacons to `FILE` instead of stdout",
+			},
+			redactFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			if db == "" {
+				return cli.NewExitError("Specify a database", -1)
+			}
+
+			format := c.String("format")
+			if format != "json" && format != "csv" {
+				return cli.NewExitError("--format must be one of "+strings.Join(export.BeaconFormats, " or "), -1)
+			}
+
+			minScore := c.Float64("min-score")
+			if minScore < 0 || minScore > 1 {
+				return cli.NewExitError("--min-score must be between 0 and 1", -1)
+			}
+
+			res := initResources(c)
+
+			out := os.Stdout
+			if path := c.String("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			err := export.Beacons(res, db, minScore, format, newRedactor(c, res), out)
+			if err != nil {
+				res.Log.Error(err)
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/activecm/rita/export"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:  "export-graph",
+		Usage: "Export the connections between hosts as a GraphML graph for visualization",
+		UsageText: "rita export-graph [command-options] <database>\n\n" +
+			"Hosts are written as nodes and unique connections as edges weighted by their beacon score.\n" +
+			"By default the unique connections with the most connections are included up to --limit.",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			limitFlag,
+			noLimitFlag,
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Write the graph to `FILE`. Defaults to <database>.graphml",
+			},
+			redactFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			if db == "" {
+				return cli.NewExitError("Specify a database", -1)
+			}
+
+			path := c.String("output")
+			if path == "" {
+				path = db + ".graphml"
+			}
+
+			res := initResources(c)
+
+			f, err := os.Create(path)
+			if err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+
+			err = export.GraphML(res, db, c.Int("limit"), c.Bool("no-limit"), newRedactor(c, res), f)
+			if err != nil {
+				f.Close()
+				res.Log.Error(err)
+				return cli.NewExitError(err.Error(), -1)
+			}
+
+			err = f.Close()
+			if err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+
+			fmt.Println(path)
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
@@ -0,0 +1,62 @@
+package commands
+
+import (
+	"os"
+
+	"github.com/activecm/rita/export"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:  "export-sigma",
+		Usage: "Export a Sigma rule matching the traffic to each beacon destination",
+		UsageText: "rita export-sigma [command-options] <database>\n\n" +
+			"A rule is written for each destination of a beacon scoring above --min-score.\n" +
+			"The rules match Zeek conn records and are separated into YAML documents.",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.Float64Flag{
+				Name:  "min-score, m",
+				Usage: "Only export destinations of beacons scoring above `SCORE`",
+				Value: 0.8,
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Write the rules to `FILE` instead of stdout",
+			},
+			redactFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			if db == "" {
+				return cli.NewExitError("Specify a database", -1)
+			}
+
+			minScore := c.Float64("min-score")
+			if minScore < 0 || minScore > 1 {
+				return cli.NewExitError("--min-score must be between 0 and 1", -1)
+			}
+
+			res := initResources(c)
+
+			out := os.Stdout
+			if path := c.String("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			err := export.Sigma(res, db, minScore, newRedactor(c, res), out)
+			if err != nil {
+				res.Log.Error(err)
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"os"
+	"strings"
+
+	"github.com/activecm/rita/export"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:  "export-timeseries",
+		Usage: "Export the connection timestamps between two hosts as a timeseries for dashboards",
+		UsageText: "rita export-timeseries [command-options] <database> <source> <destination>\n\n" +
+			"Each connection from <source> to <destination> is written as a point of value 1 at its timestamp.\n" +
+			"The csv format holds a time,value row per connection. The json format holds a Grafana series.",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Write the timeseries as `FORMAT`: " + strings.Join(export.TimeseriesFormats, " or "),
+				Value: "csv",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Write the timeseries to `FILE` instead of stdout",
+			},
+			redactFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			src := c.Args().Get(1)
+			dst := c.Args().Get(2)
+			if db == "" || src == "" || dst == "" {
+				return cli.NewExitError("Specify a database, source IP, and destination IP", -1)
+			}
+
+			format := c.String("format")
+			if format != "csv" && format != "json" {
+				return cli.NewExitError("--format must be one of "+strings.Join(export.TimeseriesFormats, " or "), -1)
+			}
+
+			res := initResources(c)
+
+			out := os.Stdout
+			if path := c.String("output"); path != "" {
+				f, err := os.Create(path)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			err := export.Timeseries(res, db, src, dst, format, newRedactor(c, res), out)
+			if err != nil {
+				res.Log.Error(err)
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/export"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:  "export",
+		Usage: "Export the analysis results of a database for ingestion into a data lake",
+		UsageText: "rita export [command-options] <database>\n\n" +
+			"The beacon, DNS, and blacklist results are written to one file per result type.",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.StringFlag{
+				Name:  "format, f",
+				Usage: "Write the results in `FORMAT`. Supported formats: parquet",
+				Value: "parquet",
+			},
+			cli.StringFlag{
+				Name:  "output, o",
+				Usage: "Write the results to `DIR`. Defaults to a directory named after the database",
+			},
+			cli.StringFlag{
+				Name:  "hosts",
+				Usage: "Only export the results involving the IP addresses and CIDR ranges listed in `FILE`, one per line",
+			},
+			redactFlag,
+		},
+		Action: func(c *cli.Context) error {
+			db := c.Args().Get(0)
+			if db == "" {
+				return cli.NewExitError("Specify a database", -1)
+			}
+
+			format := c.String("format")
+			if format != "parquet" {
+				return cli.NewExitError("Unsupported export format "+format+": must be parquet", -1)
+			}
+
+			dir := c.String("output")
+			if dir == "" {
+				dir = db
+			}
+
+			var hosts *export.HostSet
+			if hostsFile := c.String("hosts"); hostsFile != "" {
+				var err error
+				hosts, err = export.LoadHostSet(hostsFile)
+				if err != nil {
+					return cli.NewExitError(err, -1)
+				}
+			}
+
+			res := initResources(c)
+
+			paths, err := export.Parquet(res, db, dir, hosts, newRedactor(c, res))
+			if err != nil {
+				res.Log.Error(err)
+				return cli.NewExitError(err, -1)
+			}
+
+			for _, path := range paths {
+				fmt.Println(path)
+			}
+			return nil
+		},
+	}
+	bootstrapCommands(command)
+}
@@ -1,12 +1,18 @@
 package commands
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/parser"
+	"github.com/activecm/rita/parser/files"
 	"github.com/activecm/rita/pkg/remover"
 	"github.com/activecm/rita/resources"
 	"github.com/activecm/rita/util"
@@ -20,7 +26,8 @@ func init() {
 		Usage: "Import zeek logs into a target database",
 		UsageText: "rita import [command options] <import directory|file> [<import directory|file>...] <database name>\n\n" +
 			"Logs directly in <import directory> will be imported into a database" +
-			" named <database name>.",
+			" named <database name>. Each log within a tar or zip archive is" +
+			" imported as well.",
 		Flags: []cli.Flag{
 			ConfigFlag,
 			threadFlag,
@@ -28,11 +35,19 @@ func init() {
 			rollingFlag,
 			totalChunksFlag,
 			currentChunkFlag,
+			cli.DurationFlag{
+				Name:  "max-runtime",
+				Usage: "Checkpoint beacon analysis once it has run for `DURATION` (e.g. 4h). Importing into the database again resumes the analysis. Only beacon analysis is limited.",
+			},
+			cli.StringFlag{
+				Name:  "baseline-destinations",
+				Usage: "Skip the known-good destinations listed in `FILE` so only newly-seen destinations are analyzed. Each line holds an IP, CIDR range, or FQDN.",
+			},
 		},
 		Action: func(c *cli.Context) error {
 			importer := NewImporter(c)
 			err := importer.run()
-			fmt.Println(updateCheck(getConfigFilePath(c)))
+			fmt.Println(updateCheck(getConfigFilePath(c), getConnection(c), isReadOnly(c)))
 			return err
 		},
 	}
@@ -45,6 +60,8 @@ type (
 	Importer struct {
 		res             *resources.Resources
 		configFile      string
+		connection      string
+		readOnly        bool
 		args            cli.Args
 		importFiles     []string
 		targetDatabase  string
@@ -53,6 +70,8 @@ type (
 		userTotalChunks int
 		userCurrChunk   int
 		threads         int
+		maxRuntime      time.Duration
+		baselineFile    string
 	}
 )
 
@@ -60,12 +79,16 @@ type (
 func NewImporter(c *cli.Context) *Importer {
 	return &Importer{
 		configFile:      getConfigFilePath(c),
+		connection:      getConnection(c),
+		readOnly:        isReadOnly(c),
 		args:            c.Args(),
 		deleteOldData:   c.Bool("delete"),
 		userRolling:     c.Bool("rolling"),
 		userTotalChunks: c.Int("numchunks"),
 		userCurrChunk:   c.Int("chunk"),
 		threads:         util.Max(c.Int("threads")/2, 1),
+		maxRuntime:      c.Duration("max-runtime"),
+		baselineFile:    c.String("baseline-destinations"),
 	}
 }
 
@@ -208,7 +231,14 @@ func (i *Importer) run() error {
 		return err
 	}
 
-	i.res = resources.InitResources(i.configFile)
+	i.res = initResourcesFromFile(i.configFile, i.connection, i.readOnly)
+
+	// fail before touching the MetaDB rather than partway through the import
+	err = i.res.DB.Writable()
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	i.res.OpenSinks()
 
 	// set up target database
 	i.res.DB.SelectDB(i.targetDatabase)
@@ -230,14 +260,32 @@ func (i *Importer) run() error {
 	if err != nil {
 		return cli.NewExitError(err.Error(), -1)
 	}
+	// the retention limit and chunk duration come from the config file rather than the database
+	rollingCfg.MaxChunks = i.res.Config.S.Rolling.MaxChunks
+	rollingCfg.ChunkDuration = i.res.Config.S.Rolling.ChunkDuration
 	i.res.Config.S.Rolling = rollingCfg
 
 	importer := parser.NewFSImporter(i.res)
+	importer.SetMaxRuntime(i.maxRuntime)
 	if len(importer.GetInternalSubnets()) == 0 {
 		return cli.NewExitError("Internal subnets are not defined. Please set the InternalSubnets section of the config file.", -1)
 	}
+	err = importer.SetBaselineDestinations(i.baselineFile)
+	if err != nil {
+		return cli.NewExitError(fmt.Errorf("\n\t[!] %v", err.Error()), -1)
+	}
 
-	indexedFiles := importer.CollectFileDetails(i.importFiles, i.threads)
+	indexedFiles, indexErrs := importer.CollectFileDetails(i.importFiles, i.threads)
+	defer importer.RemoveExtractedArchives()
+	for _, indexErr := range indexErrs {
+		// directories of Zeek logs routinely hold log types RITA doesn't import
+		if errors.Is(indexErr, files.ErrUnknownLogType) {
+			i.res.ImportLog.WithField("error", indexErr).Info("Skipping unsupported log file")
+			continue
+		}
+		i.res.ImportLog.WithField("error", indexErr).Warn("Skipping log file")
+		fmt.Printf("\t[!] Skipping %v\n", indexErr)
+	}
 	// if no compatible files for import were found, exit
 	if len(indexedFiles) == 0 {
 		return cli.NewExitError("No compatible log files found", -1)
@@ -250,12 +298,12 @@ func (i *Importer) run() error {
 		}
 	}
 
-	i.res.Log.Infof("Importing %v\n", i.importFiles)
+	i.res.ImportLog.Infof("Importing %v\n", i.importFiles)
 	fmt.Printf("\n\t[+] Importing %v:\n", i.importFiles)
 
 	// about to import into and convert an existing, non-rolling database
 	if exists && !isRolling && rollingCfg.Rolling {
-		i.res.Log.Infof("Non-rolling database %v will be converted to rolling\n", i.targetDatabase)
+		i.res.ImportLog.Infof("Non-rolling database %v will be converted to rolling\n", i.targetDatabase)
 		fmt.Printf("\t[+] Non-rolling database %v will be converted to rolling\n", i.targetDatabase)
 	}
 
@@ -272,9 +320,18 @@ func (i *Importer) run() error {
 		defer pprof.StopCPUProfile()
 	*/
 
-	importer.Run(indexedFiles, i.threads)
+	// stop the analysis cleanly when the import is interrupted
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	importer.SetContext(ctx)
+
+	err = importer.Run(indexedFiles, i.threads)
+	if err != nil {
+		i.res.ImportLog.Error(err)
+		return cli.NewExitError(fmt.Errorf("\n\t[!] %v", err.Error()), -1)
+	}
 
-	i.res.Log.Infof("Finished importing %v\n", i.importFiles)
+	i.res.ImportLog.Infof("Finished importing %v\n", i.importFiles)
 
 	return nil
 }
@@ -284,7 +341,7 @@ func (i *Importer) handleDeleteOldData() error {
 		fmt.Printf("\t[+] Removing database: %s\n", i.targetDatabase)
 		err := deleteSingleDatabase(i.res, i.targetDatabase, false)
 		if err != nil {
-			i.res.Log.WithFields(log.Fields{
+			i.res.ImportLog.WithFields(log.Fields{
 				"database": i.targetDatabase,
 				"err":      err.Error(),
 			}).Warn("Failed to remove database before import")
@@ -301,7 +358,7 @@ func (i *Importer) handleDeleteOldData() error {
 
 	// Remove the analysis results for the chunk
 	targetChunk := i.res.Config.S.Rolling.CurrentChunk
-	removerRepo := remover.NewMongoRemover(i.res.DB, i.res.Config, i.res.Log)
+	removerRepo := remover.NewMongoRemover(i.res.DB, i.res.Config, i.res.ImportLog)
 	err := removerRepo.Remove(targetChunk)
 	if err != nil {
 		return err
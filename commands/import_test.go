@@ -46,28 +46,28 @@ func TestParseFlags(t *testing.T) {
 		// new database scenarios
 
 		{"rita import (default 12)",
-			!exists, !rolling, 0, 0, !rolling, blank, blank, default12, !delete, cfg{12, !rolling, 0, 1}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: !rolling, CurrentChunk: 0, TotalChunks: 1}, !returnsError},
 
 		{"rita import --rolling (default 12)",
-			!exists, !rolling, 0, 0, rolling, blank, blank, default12, !delete, cfg{12, rolling, 0, 12}, !returnsError},
+			!exists, !rolling, 0, 0, rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling --chunk 0 --numchunks 24 (default 12)",
-			!exists, !rolling, 0, 0, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			!exists, !rolling, 0, 0, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --numchunks 24 (default 12)",
-			!exists, !rolling, 0, 0, !rolling, blank, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, blank, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 5  (default 12)",
-			!exists, !rolling, 0, 0, !rolling, 5, blank, default12, !delete, cfg{12, rolling, 5, 12}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, 5, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --chunk 12 (default 12)",
-			!exists, !rolling, 0, 0, !rolling, 12, blank, default12, !delete, cfg{12, rolling, 12, 12}, returnsError},
+			!exists, !rolling, 0, 0, !rolling, 12, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 12}, returnsError},
 
 		{"rita import --chunk 12 (default 24)",
-			!exists, !rolling, 0, 0, !rolling, 12, blank, default24, !delete, cfg{24, rolling, 12, 24}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, 12, blank, default24, !delete, cfg{DefaultChunks: 24, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 12 --numchunks 24 (default 12)",
-			!exists, !rolling, 0, 0, !rolling, 12, 24, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, 12, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk -2 (default 12)", // error reason: chunk number must be positive
 			!exists, !rolling, 0, 0, !rolling, -2, blank, default12, !delete, cfg{}, returnsError},
@@ -76,16 +76,16 @@ func TestParseFlags(t *testing.T) {
 			!exists, !rolling, 0, 0, !rolling, blank, -2, default12, !delete, cfg{}, returnsError},
 
 		{"rita import --delete (default 12)",
-			!exists, !rolling, 0, 0, !rolling, blank, blank, default12, delete, cfg{12, !rolling, 0, 1}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: !rolling, CurrentChunk: 0, TotalChunks: 1}, !returnsError},
 
 		{"rita import --delete --rolling (default 12)",
-			!exists, !rolling, 0, 0, rolling, blank, blank, default12, delete, cfg{12, rolling, 0, 12}, !returnsError},
+			!exists, !rolling, 0, 0, rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling --chunk 0 --numchunks 24 (default 12)",
-			!exists, !rolling, 0, 0, rolling, 0, 24, default12, delete, cfg{12, rolling, 0, 24}, !returnsError},
+			!exists, !rolling, 0, 0, rolling, 0, 24, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --delete --chunk 5  (default 12)",
-			!exists, !rolling, 0, 0, !rolling, 5, blank, default12, delete, cfg{12, rolling, 5, 12}, !returnsError},
+			!exists, !rolling, 0, 0, !rolling, 5, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		// existing database scenarios
 
@@ -94,25 +94,25 @@ func TestParseFlags(t *testing.T) {
 			exists, !rolling, 0, 1, !rolling, blank, blank, default12, !delete, cfg{}, returnsError},
 
 		{"rita import --rolling",
-			exists, !rolling, 0, 1, rolling, blank, blank, default12, !delete, cfg{12, rolling, 1, 12}, !returnsError},
+			exists, !rolling, 0, 1, rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 1, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling --chunk 0 --numchunks 24",
-			exists, !rolling, 0, 1, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, !rolling, 0, 1, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --numchunks 24",
-			exists, !rolling, 0, 1, !rolling, blank, 24, default12, !delete, cfg{12, rolling, 1, 24}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, blank, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 1, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 5 (default 12)",
-			exists, !rolling, 0, 1, !rolling, 5, blank, default12, !delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, 5, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --chunk 12 (default 12)",
-			exists, !rolling, 0, 1, !rolling, 12, blank, default12, !delete, cfg{12, rolling, 12, 12}, returnsError},
+			exists, !rolling, 0, 1, !rolling, 12, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 12}, returnsError},
 
 		{"rita import --chunk 12 (default 24)",
-			exists, !rolling, 0, 1, !rolling, 12, blank, default24, !delete, cfg{24, rolling, 12, 24}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, 12, blank, default24, !delete, cfg{DefaultChunks: 24, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 12 --numchunks 24",
-			exists, !rolling, 0, 1, !rolling, 12, 24, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, 12, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk -2", // error reason: chunk number must be positive
 			exists, !rolling, 0, 1, !rolling, -2, blank, default12, !delete, cfg{}, returnsError},
@@ -121,32 +121,32 @@ func TestParseFlags(t *testing.T) {
 			exists, !rolling, 0, 1, !rolling, blank, -2, default12, !delete, cfg{}, returnsError},
 
 		{"rita import --delete (default 12)",
-			exists, !rolling, 0, 1, !rolling, blank, blank, default12, delete, cfg{12, !rolling, 0, 1}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: !rolling, CurrentChunk: 0, TotalChunks: 1}, !returnsError},
 
 		{"rita import --delete --rolling (default 12)",
-			exists, !rolling, 0, 1, rolling, blank, blank, default12, delete, cfg{12, rolling, 0, 12}, !returnsError},
+			exists, !rolling, 0, 1, rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --chunk 5 (default 12)",
-			exists, !rolling, 0, 1, !rolling, 5, blank, default12, delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, !rolling, 0, 1, !rolling, 5, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling --chunk 0 --numchunks 24 (default 12)",
-			exists, !rolling, 0, 1, rolling, 0, 24, default12, delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, !rolling, 0, 1, rolling, 0, 24, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		// rolling, current chunk 1, total chunks 12
 		{"rita import",
-			exists, rolling, 1, 12, !rolling, blank, blank, default12, !delete, cfg{12, rolling, 2, 12}, !returnsError},
+			exists, rolling, 1, 12, !rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 2, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling",
-			exists, rolling, 1, 12, rolling, blank, blank, default12, !delete, cfg{12, rolling, 2, 12}, !returnsError},
+			exists, rolling, 1, 12, rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 2, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling --chunk 0 --numchunks 24",
-			exists, rolling, 1, 12, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 1, 12, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --numchunks 24",
-			exists, rolling, 1, 12, !rolling, blank, 24, default12, !delete, cfg{12, rolling, 2, 24}, !returnsError},
+			exists, rolling, 1, 12, !rolling, blank, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 2, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 5 (default 12)",
-			exists, rolling, 1, 12, !rolling, 5, blank, default12, !delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, rolling, 1, 12, !rolling, 5, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --chunk 12 (default 12)", // error reason: chunk must be less than db numchunks
 			exists, rolling, 1, 12, !rolling, 12, blank, default12, !delete, cfg{}, returnsError},
@@ -155,7 +155,7 @@ func TestParseFlags(t *testing.T) {
 			exists, rolling, 1, 12, !rolling, 12, blank, default24, !delete, cfg{}, returnsError},
 
 		{"rita import --chunk 12 --numchunks 24",
-			exists, rolling, 1, 12, !rolling, 12, 24, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, rolling, 1, 12, !rolling, 12, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk -2", // error reason: chunk number must be positive
 			exists, rolling, 1, 12, !rolling, -2, blank, default12, !delete, cfg{}, returnsError},
@@ -164,32 +164,32 @@ func TestParseFlags(t *testing.T) {
 			exists, rolling, 1, 12, !rolling, blank, -2, default12, !delete, cfg{}, returnsError},
 
 		{"rita import --delete (default 12)",
-			exists, rolling, 1, 12, !rolling, blank, blank, default12, delete, cfg{12, rolling, 1, 12}, !returnsError},
+			exists, rolling, 1, 12, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 1, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling (default 12)",
-			exists, rolling, 1, 12, !rolling, blank, blank, default12, delete, cfg{12, rolling, 1, 12}, !returnsError},
+			exists, rolling, 1, 12, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 1, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --chunk 5 (default 12)",
-			exists, rolling, 1, 12, !rolling, 5, blank, default12, delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, rolling, 1, 12, !rolling, 5, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling --chunk 0 --numchunks 24 (default 12)",
-			exists, rolling, 1, 12, rolling, 0, 24, default12, delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 1, 12, rolling, 0, 24, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		// rolling, current chunk 11, total chunks 12
 		{"rita import",
-			exists, rolling, 11, 12, !rolling, blank, blank, default12, !delete, cfg{12, rolling, 0, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling",
-			exists, rolling, 11, 12, rolling, blank, blank, default12, !delete, cfg{12, rolling, 0, 12}, !returnsError},
+			exists, rolling, 11, 12, rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 12}, !returnsError},
 
 		{"rita import --rolling --chunk 0 --numchunks 24",
-			exists, rolling, 11, 12, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 11, 12, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --numchunks 24",
-			exists, rolling, 11, 12, !rolling, blank, 24, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, rolling, 11, 12, !rolling, blank, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --chunk 5 (default 12)",
-			exists, rolling, 11, 12, !rolling, 5, blank, default12, !delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, 5, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --chunk 12 (default 12)", // error reason: chunk must be less than db numchunks
 			exists, rolling, 11, 12, !rolling, 12, blank, default12, !delete, cfg{}, returnsError},
@@ -198,29 +198,29 @@ func TestParseFlags(t *testing.T) {
 			exists, rolling, 11, 12, !rolling, 12, blank, default24, !delete, cfg{}, returnsError},
 
 		{"rita import --chunk 12 --numchunks 24",
-			exists, rolling, 11, 12, !rolling, 12, 24, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, rolling, 11, 12, !rolling, 12, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --delete (default 12)",
-			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{12, rolling, 11, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 11, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling (default 12)",
-			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{12, rolling, 11, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 11, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --chunk 5 (default 12)",
-			exists, rolling, 11, 12, !rolling, 5, blank, default12, delete, cfg{12, rolling, 5, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, 5, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --rolling --chunk 0 --numchunks 24 (default 12)",
-			exists, rolling, 11, 12, rolling, 0, 24, default12, delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 11, 12, rolling, 0, 24, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		// rolling, current chunk 11, total chunks 24
 		{"rita import",
-			exists, rolling, 11, 24, !rolling, blank, blank, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, rolling, 11, 24, !rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --rolling",
-			exists, rolling, 11, 24, rolling, blank, blank, default12, !delete, cfg{12, rolling, 12, 24}, !returnsError},
+			exists, rolling, 11, 24, rolling, blank, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 12, TotalChunks: 24}, !returnsError},
 
 		{"rita import --rolling --chunk 0 --numchunks 24",
-			exists, rolling, 11, 24, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 11, 24, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 
 		{"rita import --numchunks 12", // error reason: cannot reduce the number of chunks
 			exists, rolling, 11, 24, !rolling, blank, 12, default12, !delete, cfg{}, returnsError},
@@ -229,19 +229,19 @@ func TestParseFlags(t *testing.T) {
 			exists, rolling, 11, 24, !rolling, 12, 12, default12, !delete, cfg{}, returnsError},
 
 		{"rita import --chunk 13 (default 12)",
-			exists, rolling, 11, 24, !rolling, 13, blank, default12, !delete, cfg{12, rolling, 13, 24}, !returnsError},
+			exists, rolling, 11, 24, !rolling, 13, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 13, TotalChunks: 24}, !returnsError},
 
 		{"rita import --delete (default 12)",
-			exists, rolling, 11, 24, !rolling, blank, blank, default12, delete, cfg{12, rolling, 11, 24}, !returnsError},
+			exists, rolling, 11, 24, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 11, TotalChunks: 24}, !returnsError},
 
 		{"rita import --delete --rolling (default 12)",
-			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{12, rolling, 11, 12}, !returnsError},
+			exists, rolling, 11, 12, !rolling, blank, blank, default12, delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 11, TotalChunks: 12}, !returnsError},
 
 		{"rita import --delete --chunk 5 (default 12)",
-			exists, rolling, 11, 24, !rolling, 5, blank, default12, !delete, cfg{12, rolling, 5, 24}, !returnsError},
+			exists, rolling, 11, 24, !rolling, 5, blank, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 5, TotalChunks: 24}, !returnsError},
 
 		{"rita import --delete --rolling --chunk 0 --numchunks 24 (default 12)",
-			exists, rolling, 11, 24, rolling, 0, 24, default12, !delete, cfg{12, rolling, 0, 24}, !returnsError},
+			exists, rolling, 11, 24, rolling, 0, 24, default12, !delete, cfg{DefaultChunks: 12, Rolling: rolling, CurrentChunk: 0, TotalChunks: 24}, !returnsError},
 	}
 
 	// runner for the test table above
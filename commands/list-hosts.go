@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/activecm/rita/pkg/hostrole"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "list-hosts",
+		Usage:     "Print each internal host along with the roles its behavior suggests",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			humanFlag,
+			limitFlag,
+			noLimitFlag,
+			delimFlag,
+			templateFlag,
+			redactFlag,
+			netNamesFlag,
+			cli.StringFlag{
+				Name:  "role, r",
+				Usage: "Only list hosts with the `ROLE` server, client, scanner, or beacon-source",
+			},
+		},
+		Action: listHosts,
+	}
+
+	bootstrapCommands(command)
+}
+
+func listHosts(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	role := hostrole.Role(c.String("role"))
+	switch role {
+	case "", hostrole.Server, hostrole.Client, hostrole.Scanner, hostrole.BeaconSource:
+	default:
+		return cli.NewExitError("--role must be one of server, client, scanner, or beacon-source", -1)
+	}
+
+	res := initResources(c)
+	res.DB.SelectDB(db)
+
+	data, err := hostrole.Results(res)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err, -1)
+	}
+
+	if role != "" {
+		var filtered []hostrole.HostProfile
+		for _, d := range data {
+			if d.HasRole(role) {
+				filtered = append(filtered, d)
+			}
+		}
+		data = filtered
+	}
+
+	if !(len(data) > 0) {
+		return cli.NewExitError("No results were found for "+db, -1)
+	}
+
+	if !c.Bool("no-limit") && len(data) > c.Int("limit") {
+		data = data[:c.Int("limit")]
+	}
+
+	redactHostProfiles(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	showNetNames := c.Bool("network-names")
+	if c.Bool("human-readable") {
+		showHostProfilesHuman(data, showNetNames)
+		return nil
+	}
+	showHostProfilesDelim(data, c.String("delimiter"), showNetNames)
+	return nil
+}
+
+// hostProfilesHeader returns the header for the rows created by hostProfilesRows
+func hostProfilesHeader(showNetNames bool) []string {
+	header := []string{
+		"Host", "Roles", "Connections Out", "Connections In", "Destinations", "Max Beacon Score",
+	}
+	if showNetNames {
+		header = append([]string{"Network"}, header...)
+	}
+	return header
+}
+
+// hostProfilesRows creates a row for each host
+func hostProfilesRows(data []hostrole.HostProfile, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		roles := make([]string, 0, len(d.Roles))
+		for _, role := range d.Roles {
+			roles = append(roles, string(role))
+		}
+		row := []string{
+			d.Host.IP,
+			strings.Join(roles, " "),
+			i(d.ConnectionsOut),
+			i(d.ConnectionsIn),
+			strconv.Itoa(d.Destinations),
+			f(d.MaxBeaconScore),
+		}
+		if showNetNames {
+			row = append([]string{d.Host.NetworkName}, row...)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func showHostProfilesHuman(data []hostrole.HostProfile, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hostProfilesHeader(showNetNames))
+	table.AppendBulk(hostProfilesRows(data, showNetNames))
+	table.Render()
+}
+
+func showHostProfilesDelim(data []hostrole.HostProfile, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(hostProfilesHeader(showNetNames), delim))
+	for _, row := range hostProfilesRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
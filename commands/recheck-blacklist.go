@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "recheck-blacklist",
+		Usage:     "Check the hosts and hostnames of an analyzed database against the current blacklists",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+		},
+		Action: recheckBlacklist,
+	}
+
+	bootstrapCommands(command)
+}
+
+// recheckBlacklist updates the blacklist results of a database without
+// analyzing its logs again
+func recheckBlacklist(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	res := initResources(c)
+
+	if !res.Config.S.Blacklisted.Enabled {
+		return cli.NewExitError("Blacklist analysis is disabled in the config", -1)
+	}
+
+	info, err := res.MetaDB.GetDBMetaInfo(db)
+	if err != nil {
+		return cli.NewExitError("Database "+db+" is not tracked by RITA", -1)
+	}
+	if !info.Analyzed {
+		return cli.NewExitError("Database "+db+" has not been analyzed", -1)
+	}
+
+	err = res.DB.Writable()
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	res.DB.SelectDB(db)
+
+	// the peer records of new blacklisted hosts belong to the current chunk
+	res.Config.S.Rolling.CurrentChunk = info.CurrentChunk
+
+	// pull the current indicators from the blacklist sources
+	blacklist.BuildBlacklistedCollections(res.DB, res.Config, res.Log)
+
+	result, err := blacklist.NewMongoRepository(res.DB, res.Config, res.Log).Recheck()
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	fmt.Printf("Recheck successful: %d host(s) and %d hostname(s) newly blacklisted, %d host(s) and %d hostname(s) cleared.\n",
+		result.ListedHosts, result.ListedHostnames, result.ClearedHosts, result.ClearedHostnames)
+	return nil
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/hostrole"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/pkg/rollup"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+	"github.com/urfave/cli"
+)
+
+// redactFlag masks the addresses configured in the Redaction config section
+// so the output can be shared outside of the organization
+var redactFlag = cli.BoolFlag{
+	Name:  "redact",
+	Usage: "Mask the addresses listed in the Redaction section of the config file",
+}
+
+// newRedactor creates the redactor used to mask the results of a show command.
+// Redaction applies if it is enabled in the config file or via --redact.
+func newRedactor(c *cli.Context, res *resources.Resources) *redact.Redactor {
+	return redact.NewRedactor(res.Config, c.Bool("redact"))
+}
+
+// redactBeacons masks the beacon results in place
+func redactBeacons(r *redact.Redactor, results []beacon.Result) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactBeaconCIDRs masks the aggregated beacon results in place. The
+// destination prefixes are not addresses of individual hosts and are kept.
+func redactBeaconCIDRs(r *redact.Redactor, results []beacon.CIDRResult) {
+	for i := range results {
+		results[i].UniqueSrcIP = r.SrcIP(results[i].UniqueSrcIP)
+	}
+}
+
+// redactBeaconJA3s masks the beacons of each JA3 group in place
+func redactBeaconJA3s(r *redact.Redactor, results []beacon.JA3Result) {
+	for i := range results {
+		redactBeacons(r, results[i].Beacons)
+	}
+}
+
+// redactBeaconDiffs masks the beacon changes in place
+func redactBeaconDiffs(r *redact.Redactor, results []beacon.DiffResult) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactStrobes masks the strobe results in place
+func redactStrobes(r *redact.Redactor, results []beacon.StrobeResult) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactBeaconsSNI masks the SNI beacon results in place
+func redactBeaconsSNI(r *redact.Redactor, results []beaconsni.Result) {
+	for i := range results {
+		results[i].UniqueSrcIP = r.SrcIP(results[i].UniqueSrcIP)
+	}
+}
+
+// redactBeaconsProxy masks the proxy beacon results in place. The proxy is
+// treated as the destination of the source's connections.
+func redactBeaconsProxy(r *redact.Redactor, results []beaconproxy.Result) {
+	for i := range results {
+		results[i].SrcIP = r.Src(results[i].SrcIP)
+		results[i].Proxy.IP = r.Dst(results[i].Proxy.IP)
+		for j := range results[i].ProxyList {
+			results[i].ProxyList[j].IP = r.Dst(results[i].ProxyList[j].IP)
+		}
+	}
+}
+
+// redactBeaconProxyFQDNs masks the proxy beacons of each FQDN group in place
+func redactBeaconProxyFQDNs(r *redact.Redactor, results []beaconproxy.FQDNResult) {
+	for i := range results {
+		redactBeaconsProxy(r, results[i].Beacons)
+	}
+}
+
+// redactLongConns masks the long connection results in place
+func redactLongConns(r *redact.Redactor, results []uconn.LongConnResult) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactOpenConns masks the open connection results in place
+func redactOpenConns(r *redact.Redactor, results []uconn.OpenConnResult) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactHostFindings masks the internal hosts of the rolled up findings in
+// place. The internal hosts are treated as the sources of their findings.
+func redactHostFindings(r *redact.Redactor, results []rollup.HostFindings) {
+	for i := range results {
+		results[i].Host.IP = r.Src(results[i].Host.IP)
+	}
+}
+
+// redactHostProfiles masks the internal hosts of the classified hosts in
+// place. The internal hosts are treated as sources.
+func redactHostProfiles(r *redact.Redactor, results []hostrole.HostProfile) {
+	for i := range results {
+		results[i].Host.IP = r.Src(results[i].Host.IP)
+	}
+}
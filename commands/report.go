@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/activecm/rita/pkg/rollup"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "report",
+		Usage:     "Print the findings of each internal host across all analysis modules",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			humanFlag,
+			limitFlag,
+			noLimitFlag,
+			delimFlag,
+			templateFlag,
+			redactFlag,
+			netNamesFlag,
+			cli.Float64Flag{
+				Name:  "min-score, m",
+				Usage: "Only count beacons scoring above `SCORE` (e.g. 0.8)",
+			},
+		},
+		Action: reportHosts,
+	}
+
+	bootstrapCommands(command)
+}
+
+func reportHosts(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	minScore := c.Float64("min-score")
+	if minScore < 0 || minScore > 1 {
+		return cli.NewExitError("--min-score must be between 0 and 1", -1)
+	}
+
+	res := initResources(c)
+	res.DB.SelectDB(db)
+
+	data, err := rollup.Results(res, minScore)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err, -1)
+	}
+
+	if !(len(data) > 0) {
+		return cli.NewExitError("No results were found for "+db, -1)
+	}
+
+	if !c.Bool("no-limit") && len(data) > c.Int("limit") {
+		data = data[:c.Int("limit")]
+	}
+
+	redactHostFindings(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	showNetNames := c.Bool("network-names")
+	if c.Bool("human-readable") {
+		showHostFindingsHuman(data, showNetNames)
+		return nil
+	}
+	showHostFindingsDelim(data, c.String("delimiter"), showNetNames)
+	return nil
+}
+
+// hostFindingsHeader returns the header for the rows created by hostFindingsRows
+func hostFindingsHeader(showNetNames bool) []string {
+	header := []string{
+		"Host", "Beacons", "Proxy Beacons", "SNI Beacons", "Strobes", "Long Connections",
+		"Blacklisted", "Total Findings", "Max Score",
+	}
+	if showNetNames {
+		header = append([]string{"Network"}, header...)
+	}
+	return header
+}
+
+// hostFindingsRows creates a row for the findings of each host
+func hostFindingsRows(data []rollup.HostFindings, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		row := []string{
+			d.Host.IP,
+			strconv.Itoa(d.Beacons),
+			strconv.Itoa(d.BeaconsProxy),
+			strconv.Itoa(d.BeaconsSNI),
+			strconv.Itoa(d.Strobes),
+			strconv.Itoa(d.LongConnections),
+			strconv.Itoa(d.Blacklisted),
+			strconv.Itoa(d.Total()),
+			f(d.MaxScore),
+		}
+		if showNetNames {
+			row = append([]string{d.Host.NetworkName}, row...)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func showHostFindingsHuman(data []rollup.HostFindings, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(hostFindingsHeader(showNetNames))
+	table.AppendBulk(hostFindingsRows(data, showNetNames))
+	table.Render()
+}
+
+func showHostFindingsDelim(data []rollup.HostFindings, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(hostFindingsHeader(showNetNames), delim))
+	for _, row := range hostFindingsRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
@@ -2,7 +2,6 @@ package commands
 
 import (
 	"github.com/activecm/rita/reporting"
-	"github.com/activecm/rita/resources"
 	"github.com/urfave/cli"
 )
 
@@ -19,7 +18,7 @@ func init() {
 			noBrowserFlag,
 		},
 		Action: func(c *cli.Context) error {
-			res := resources.InitResources(getConfigFilePath(c))
+			res := initResources(c)
 			databaseName := c.Args().Get(0)
 			var databases []string
 			if databaseName != "" {
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/sink"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "rescore",
+		Usage:     "Score the beacons and proxy beacons of an analyzed database again with the current config",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.StringFlag{
+				Name:  "dry-run",
+				Usage: "Score the beacons without changing the database, appending the changes which would be made to `FILE` as JSON lines",
+			},
+		},
+		Action: rescoreDatabase,
+	}
+
+	bootstrapCommands(command)
+}
+
+// rescoreDatabase runs the beacon and proxy beacon analyses over the unique
+// connections already stored in a database without parsing its logs again
+func rescoreDatabase(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	res := initResources(c)
+
+	info, err := res.MetaDB.GetDBMetaInfo(db)
+	if err != nil {
+		return cli.NewExitError("Database "+db+" is not tracked by RITA", -1)
+	}
+	if !info.Analyzed {
+		return cli.NewExitError("Database "+db+" has not been analyzed", -1)
+	}
+	if err := info.CheckHashPairKeys(res.Config.S.Beacon.HashPairKeys); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	dryRunFile := c.String("dry-run")
+	if dryRunFile == "" {
+		err = res.DB.Writable()
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+
+	minTimestamp, maxTimestamp, err := res.MetaDB.GetTSRange(db)
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	res.DB.SelectDB(db)
+
+	// the beacons are rescored into the current chunk of the database
+	res.Config.S.Rolling.Rolling = info.Rolling
+	res.Config.S.Rolling.CurrentChunk = info.CurrentChunk
+	res.Config.S.Rolling.TotalChunks = info.TotalChunks
+
+	// a dry run hands the changes to a file sink rather than MongoDB so that
+	// the results of two versions of the analysis can be compared
+	if dryRunFile != "" {
+		dryRun, err := sink.New(config.SinkStaticCfg{Type: "file", Path: dryRunFile}, res.DB.GetSelectedDB)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+
+		fmt.Printf("\t[+] Rescoring %s without changing it:\n", db)
+		if res.Config.S.BeaconProxy.Enabled {
+			fmt.Println("\t[!] Skipping Proxy Beacons: Dry Run")
+		}
+
+		err = beacon.NewDryRunRepository(res.DB, res.Config, res.AnalysisLog, dryRun).Rescore(minTimestamp, maxTimestamp)
+		if err != nil {
+			res.Log.Error(err)
+			return cli.NewExitError(err.Error(), -1)
+		}
+
+		fmt.Printf("\t[-] Done! The changes were written to %s\n", dryRunFile)
+		return nil
+	}
+
+	res.OpenSinks()
+	fmt.Printf("\t[+] Rescoring %s:\n", db)
+
+	if res.Config.S.Beacon.Enabled {
+		err = beacon.NewMongoRepository(res.DB, res.Config, res.AnalysisLog).Rescore(minTimestamp, maxTimestamp)
+		if err != nil {
+			res.Log.Error(err)
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+
+	if res.Config.S.BeaconProxy.Enabled {
+		err = beaconproxy.NewMongoRepository(res.DB, res.Config, res.AnalysisLog).Rescore(minTimestamp, maxTimestamp)
+		if err != nil {
+			res.Log.Error(err)
+			return cli.NewExitError(err.Error(), -1)
+		}
+	}
+
+	// the results now reflect the current analysis settings
+	err = res.MetaDB.SetConfigSnapshot(db, config.NewSnapshot(&res.Config.S))
+	if err != nil {
+		res.Log.Error(err)
+	}
+
+	fmt.Println("\t[-] Done!")
+	return nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/remover"
+	"github.com/urfave/cli"
+)
+
+func init() {
+	rolling := cli.Command{
+		Name:  "rolling",
+		Usage: "Manage the chunks of a rolling database",
+		Subcommands: []cli.Command{
+			{
+				Name:      "evict",
+				Usage:     "Remove the oldest chunk of a rolling database and rescore the beacons and proxy beacons which had connections in it",
+				ArgsUsage: "<database>",
+				Flags: []cli.Flag{
+					ConfigFlag,
+				},
+				Action: evictChunk,
+			},
+		},
+	}
+
+	bootstrapCommands(rolling)
+}
+
+// evictChunk removes the oldest chunk of a rolling database
+func evictChunk(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	res := initResources(c)
+
+	info, err := res.MetaDB.GetDBMetaInfo(db)
+	if err != nil {
+		return cli.NewExitError("Database "+db+" is not tracked by RITA", -1)
+	}
+	if !info.Rolling {
+		return cli.NewExitError("Database "+db+" is not a rolling database", -1)
+	}
+	if err := info.CheckHashPairKeys(res.Config.S.Beacon.HashPairKeys); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	res.DB.SelectDB(db)
+	res.OpenSinks()
+
+	// beacons are rescored into the current chunk of the database
+	res.Config.S.Rolling.Rolling = true
+	res.Config.S.Rolling.CurrentChunk = info.CurrentChunk
+	res.Config.S.Rolling.TotalChunks = info.TotalChunks
+
+	rescore := func(evicted remover.Evicted) error {
+		// the time range of the database no longer covers the removed chunk
+		err := res.MetaDB.AddTSRange(db, evicted.MinTimestamp, evicted.MaxTimestamp)
+		if err != nil {
+			return err
+		}
+
+		if res.Config.S.Beacon.Enabled && len(evicted.UconnMap) > 0 {
+			fmt.Printf("\t[-] Rescoring the beacons of %d unique connections\n", len(evicted.UconnMap))
+			err := beacon.NewMongoRepository(res.DB, res.Config, res.AnalysisLog).Refresh(context.Background(),
+				evicted.UconnMap, evicted.HostMap, evicted.MinTimestamp, evicted.MaxTimestamp)
+			if err != nil {
+				return err
+			}
+		}
+
+		if res.Config.S.BeaconProxy.Enabled && len(evicted.UconnProxyMap) > 0 {
+			fmt.Printf("\t[-] Rescoring the proxy beacons of %d unique proxy connections\n", len(evicted.UconnProxyMap))
+			beaconproxy.NewMongoRepository(res.DB, res.Config, res.AnalysisLog).Refresh(
+				evicted.UconnProxyMap, evicted.ProxyHostMap, evicted.MinTimestamp, evicted.MaxTimestamp)
+		}
+		return nil
+	}
+
+	repo := remover.NewMongoRemover(res.DB, res.Config, res.Log)
+	cid, evicted, err := remover.Evict(repo, info, rescore)
+	// the chunk's data is gone even if its beacons could not be rescored
+	if evicted {
+		if setErr := res.MetaDB.SetChunk(cid, db, false); setErr != nil {
+			return cli.NewExitError(setErr.Error(), -1)
+		}
+	}
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err.Error(), -1)
+	}
+	if !evicted {
+		fmt.Printf("Evict successful: %s only holds its current chunk.\n", db)
+		return nil
+	}
+
+	fmt.Printf("Evict successful: Removed chunk %d from %s.\n", cid, db)
+	return nil
+}
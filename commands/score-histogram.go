@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/olekukonko/tablewriter"
+	"github.com/urfave/cli"
+)
+
+// histogramBarWidth is the number of characters in the bar of the fullest
+// bucket of the human readable histogram
+const histogramBarWidth = 40
+
+func init() {
+	command := cli.Command{
+		Name:      "score-histogram",
+		Usage:     "Print the distribution of beacon scores",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+			humanFlag,
+			delimFlag,
+			cli.IntFlag{
+				Name:  "buckets, b",
+				Usage: "Split the scores into `N` equally sized buckets",
+				Value: 10,
+			},
+			cli.StringFlag{
+				Name:  "boundaries",
+				Usage: "Comma separated `SCORES` marking the edges of the buckets (e.g. 0,0.5,0.8,0.9,1). Overrides --buckets",
+			},
+		},
+		Action: showScoreHistogram,
+	}
+
+	bootstrapCommands(command)
+}
+
+func showScoreHistogram(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	var boundaries []float64
+	var err error
+	if c.String("boundaries") != "" {
+		boundaries, err = parseHistogramBoundaries(c.String("boundaries"))
+	} else {
+		boundaries, err = beacon.HistogramBoundaries(c.Int("buckets"))
+	}
+	if err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	res := initResources(c)
+	res.DB.SelectDB(db)
+
+	histogram, err := beacon.ScoreHistogram(res, boundaries)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err.Error(), -1)
+	}
+
+	if c.Bool("human-readable") {
+		showScoreHistogramHuman(os.Stdout, histogram)
+		return nil
+	}
+	showScoreHistogramDelim(os.Stdout, histogram, c.String("delimiter"))
+	return nil
+}
+
+// parseHistogramBoundaries parses a comma separated list of scores
+func parseHistogramBoundaries(list string) ([]float64, error) {
+	var boundaries []float64
+	for _, field := range strings.Split(list, ",") {
+		boundary, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid histogram boundary %q: must be a number", field)
+		}
+		boundaries = append(boundaries, boundary)
+	}
+	return boundaries, nil
+}
+
+// histogramBar draws a bar for count scaled against the fullest bucket
+func histogramBar(count, maxCount int) string {
+	if maxCount == 0 {
+		return ""
+	}
+	return strings.Repeat("#", count*histogramBarWidth/maxCount)
+}
+
+func showScoreHistogramHuman(w io.Writer, histogram []beacon.HistogramBucket) {
+	maxCount := 0
+	for _, bucket := range histogram {
+		if bucket.Count > maxCount {
+			maxCount = bucket.Count
+		}
+	}
+
+	table := tablewriter.NewWriter(w)
+	table.SetHeader([]string{"Min Score", "Max Score", "Beacons", ""})
+	for _, bucket := range histogram {
+		table.Append([]string{
+			f(bucket.Min), f(bucket.Max), strconv.Itoa(bucket.Count), histogramBar(bucket.Count, maxCount),
+		})
+	}
+	table.Render()
+}
+
+func showScoreHistogramDelim(w io.Writer, histogram []beacon.HistogramBucket, delim string) {
+	fmt.Fprintln(w, strings.Join([]string{"Min Score", "Max Score", "Beacons"}, delim))
+	for _, bucket := range histogram {
+		fmt.Fprintln(w, strings.Join([]string{f(bucket.Min), f(bucket.Max), strconv.Itoa(bucket.Count)}, delim))
+	}
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHistogramBoundaries(t *testing.T) {
+	boundaries, err := parseHistogramBoundaries("0, 0.5,0.8,1")
+	require.Nil(t, err)
+	assert.Equal(t, []float64{0, 0.5, 0.8, 1}, boundaries)
+
+	_, err = parseHistogramBoundaries("0,high,1")
+	assert.NotNil(t, err)
+}
+
+func TestShowScoreHistogramDelim(t *testing.T) {
+	histogram := []beacon.HistogramBucket{
+		{Min: 0, Max: 0.5, Count: 12},
+		{Min: 0.5, Max: 0.8, Count: 0},
+		{Min: 0.8, Max: 1, Count: 3},
+	}
+
+	var out bytes.Buffer
+	showScoreHistogramDelim(&out, histogram, ",")
+	assert.Equal(t, "Min Score,Max Score,Beacons\n0,0.5,12\n0.5,0.8,0\n0.8,1,3\n", out.String())
+}
+
+func TestHistogramBar(t *testing.T) {
+	assert.Equal(t, "", histogramBar(0, 0))
+	assert.Len(t, histogramBar(12, 12), histogramBarWidth)
+	assert.Len(t, histogramBar(3, 12), histogramBarWidth/4)
+	assert.Equal(t, "", histogramBar(0, 12))
+}
@@ -3,10 +3,10 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/activecm/rita/pkg/beaconproxy"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -20,7 +20,13 @@ func init() {
 			ConfigFlag,
 			humanFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
+			cli.BoolFlag{
+				Name:  "by-fqdn, F",
+				Usage: "Group proxy beacons by their destination FQDN and list their sources and scores",
+			},
 		},
 		Action: showBeaconsProxy,
 	}
@@ -33,7 +39,12 @@ func showBeaconsProxy(c *cli.Context) error {
 	if db == "" {
 		return cli.NewExitError("Specify a database", -1)
 	}
-	res := resources.InitResources(c.String("config"))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
 	data, err := beaconproxy.Results(res, 0)
@@ -49,6 +60,36 @@ func showBeaconsProxy(c *cli.Context) error {
 
 	showNetNames := c.Bool("network-names")
 
+	if c.Bool("by-fqdn") {
+		aggregated := beaconproxy.AggregateByFQDN(data)
+		redactBeaconProxyFQDNs(newRedactor(c, res), aggregated)
+
+		if tmpl != nil {
+			err := showTemplate(os.Stdout, tmpl, aggregated)
+			if err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		}
+
+		if c.Bool("human-readable") {
+			showBeaconsProxyFQDNHuman(aggregated, showNetNames)
+			return nil
+		}
+		showBeaconsProxyFQDNDelim(aggregated, c.String("delimiter"), showNetNames)
+		return nil
+	}
+
+	redactBeaconsProxy(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
 	if c.Bool("human-readable") {
 		err := showBeaconsProxyHuman(data, showNetNames)
 		if err != nil {
@@ -154,3 +195,43 @@ func showBeaconsProxyDelim(data []beaconproxy.Result, delim string, showNetNames
 	}
 	return nil
 }
+
+// beaconProxyFQDNHeader returns the header for the rows created by beaconProxyFQDNRows
+func beaconProxyFQDNHeader() []string {
+	return []string{"Score", "Mean Score", "FQDN", "Sources", "Proxies", "Connections", "Beacons"}
+}
+
+// beaconProxyFQDNRows creates a row for each FQDN listing the sources of its
+// beacons with their scores
+func beaconProxyFQDNRows(data []beaconproxy.FQDNResult, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		beacons := make([]string, 0, len(d.Beacons))
+		for _, b := range d.Beacons {
+			src := b.SrcIP
+			if showNetNames {
+				src = b.SrcNetworkName + ":" + src
+			}
+			beacons = append(beacons, src+" ("+f(b.Score)+")")
+		}
+		rows = append(rows, []string{
+			f(d.Score), f(d.MeanScore), d.FQDN, strconv.Itoa(d.SrcCount), strconv.Itoa(d.ProxyCount),
+			i(d.Connections), strings.Join(beacons, " "),
+		})
+	}
+	return rows
+}
+
+func showBeaconsProxyFQDNHuman(data []beaconproxy.FQDNResult, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(beaconProxyFQDNHeader())
+	table.AppendBulk(beaconProxyFQDNRows(data, showNetNames))
+	table.Render()
+}
+
+func showBeaconsProxyFQDNDelim(data []beaconproxy.FQDNResult, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(beaconProxyFQDNHeader(), delim))
+	for _, row := range beaconProxyFQDNRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
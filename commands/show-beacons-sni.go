@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/activecm/rita/pkg/beaconsni"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -20,6 +19,8 @@ func init() {
 			ConfigFlag,
 			humanFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
 		},
 		Action: showBeaconsSNI,
@@ -33,7 +34,12 @@ func showBeaconsSNI(c *cli.Context) error {
 	if db == "" {
 		return cli.NewExitError("Specify a database", -1)
 	}
-	res := resources.InitResources(getConfigFilePath(c))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
 	data, err := beaconsni.Results(res, 0)
@@ -49,6 +55,16 @@ func showBeaconsSNI(c *cli.Context) error {
 
 	showNetNames := c.Bool("network-names")
 
+	redactBeaconsSNI(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
 	if c.Bool("human-readable") {
 		err := showBeaconsSNIHuman(data, showNetNames)
 		if err != nil {
@@ -3,7 +3,9 @@ package commands
 import (
 	"fmt"
 	"os"
+	"strconv"
 	"strings"
+	"text/template"
 
 	"github.com/activecm/rita/pkg/beacon"
 	"github.com/activecm/rita/resources"
@@ -15,12 +17,42 @@ func init() {
 	command := cli.Command{
 		Name:      "show-beacons",
 		Usage:     "Print hosts which show signs of C2 software",
-		ArgsUsage: "<database>",
+		ArgsUsage: "<database> [<source> <destination>]",
 		Flags: []cli.Flag{
 			ConfigFlag,
 			humanFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
+			cli.BoolFlag{
+				Name:  "uids, u",
+				Usage: "List the Zeek connection UIDs stored for the beacon between <source> and <destination>",
+			},
+			cli.IntFlag{
+				Name:  "aggregate-cidr, A",
+				Usage: "Group beacon destinations by `PREFIX` length (e.g. 24) and report aggregate scores",
+			},
+			cli.Float64Flag{
+				Name:  "top-percentile, P",
+				Usage: "Only show the top `PERCENT` (e.g. 1) of beacons by score within the dataset",
+			},
+			cli.BoolFlag{
+				Name:  "by-ja3, J",
+				Usage: "Group beacons by the JA3 fingerprint of the TLS client and list their destinations and scores",
+			},
+			cli.StringFlag{
+				Name:  "cloud",
+				Usage: "Only show beacons to destinations in the ranges of the cloud `PROVIDER` (e.g. aws, gcp, azure)",
+			},
+			cli.BoolFlag{
+				Name:  "no-cloud",
+				Usage: "Hide beacons to destinations in the ranges of any known cloud provider",
+			},
+			cli.BoolFlag{
+				Name:  "no-heartbeats",
+				Usage: "Hide beacons which were marked as likely application heartbeats",
+			},
 		},
 		Action: showBeacons,
 	}
@@ -33,21 +65,133 @@ func showBeacons(c *cli.Context) error {
 	if db == "" {
 		return cli.NewExitError("Specify a database", -1)
 	}
-	res := resources.InitResources(getConfigFilePath(c))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	showUIDs := c.Bool("uids")
+	src := c.Args().Get(1)
+	dst := c.Args().Get(2)
+	if showUIDs && (src == "" || dst == "") {
+		return cli.NewExitError("Specify a source and destination IP to list UIDs for", -1)
+	}
+
+	aggregate := c.IsSet("aggregate-cidr")
+	prefixLen := c.Int("aggregate-cidr")
+	if aggregate && showUIDs {
+		return cli.NewExitError("--aggregate-cidr cannot be combined with --uids", -1)
+	}
+	if aggregate && (prefixLen < 0 || prefixLen > 32) {
+		return cli.NewExitError("--aggregate-cidr must be a prefix length between 0 and 32", -1)
+	}
+
+	topPercentile := c.IsSet("top-percentile")
+	percentile := c.Float64("top-percentile")
+	if topPercentile && showUIDs {
+		return cli.NewExitError("--top-percentile cannot be combined with --uids", -1)
+	}
+	if topPercentile && (percentile <= 0 || percentile > 100) {
+		return cli.NewExitError("--top-percentile must be greater than 0 and at most 100", -1)
+	}
+
+	byJA3 := c.Bool("by-ja3")
+	if byJA3 && (showUIDs || aggregate || topPercentile) {
+		return cli.NewExitError("--by-ja3 cannot be combined with --uids, --aggregate-cidr, or --top-percentile", -1)
+	}
+
+	cloudProvider := c.String("cloud")
+	noCloud := c.Bool("no-cloud")
+	filterCloud := cloudProvider != "" || noCloud
+	if cloudProvider != "" && noCloud {
+		return cli.NewExitError("--cloud cannot be combined with --no-cloud", -1)
+	}
+	if filterCloud && (showUIDs || byJA3) {
+		return cli.NewExitError("--cloud and --no-cloud cannot be combined with --uids or --by-ja3", -1)
+	}
+
+	noHeartbeats := c.Bool("no-heartbeats")
+	if noHeartbeats && (showUIDs || byJA3) {
+		return cli.NewExitError("--no-heartbeats cannot be combined with --uids or --by-ja3", -1)
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
-	data, err := beacon.Results(res, 0)
+	if byJA3 {
+		return showBeaconsByJA3(c, res, db, tmpl)
+	}
+
+	var data []beacon.Result
+	if showUIDs {
+		data, err = beacon.UIDResults(res, src, dst)
+	} else if topPercentile {
+		data, err = beacon.TopPercentileResults(res, percentile)
+	} else {
+		data, err = beacon.Results(res, 0)
+	}
 
 	if err != nil {
 		res.Log.Error(err)
 		return cli.NewExitError(err, -1)
 	}
 
+	if filterCloud {
+		data = beacon.FilterCloudProvider(data, cloudProvider, noCloud)
+	}
+
+	if noHeartbeats {
+		data = beacon.FilterHeartbeats(data)
+	}
+
 	if !(len(data) > 0) {
 		return cli.NewExitError("No results were found for "+db, -1)
 	}
 
 	showNetNames := c.Bool("network-names")
+	redactor := newRedactor(c, res)
+
+	if aggregate {
+		aggregated, err := beacon.AggregateByCIDR(data, prefixLen)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		redactBeaconCIDRs(redactor, aggregated)
+
+		if tmpl != nil {
+			err := showTemplate(os.Stdout, tmpl, aggregated)
+			if err != nil {
+				return cli.NewExitError(err.Error(), -1)
+			}
+			return nil
+		}
+
+		if c.Bool("human-readable") {
+			showBeaconsCIDRHuman(aggregated, showNetNames)
+			return nil
+		}
+		showBeaconsCIDRDelim(aggregated, c.String("delimiter"), showNetNames)
+		return nil
+	}
+
+	redactBeacons(redactor, data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	if showUIDs {
+		if c.Bool("human-readable") {
+			showBeaconUIDsHuman(data, showNetNames)
+			return nil
+		}
+		showBeaconUIDsDelim(data, c.String("delimiter"), showNetNames)
+		return nil
+	}
 
 	if c.Bool("human-readable") {
 		err := showBeaconsHuman(data, showNetNames)
@@ -143,3 +287,153 @@ func showBeaconsDelim(data []beacon.Result, delim string, showNetNames bool) err
 	}
 	return nil
 }
+
+// beaconUIDHeader returns the header for the rows created by beaconUIDRows
+func beaconUIDHeader(showNetNames bool) []string {
+	if showNetNames {
+		return []string{"Source Network", "Destination Network", "Source IP", "Destination IP", "UID"}
+	}
+	return []string{"Source IP", "Destination IP", "UID"}
+}
+
+// beaconUIDRows creates a row for each Zeek connection UID stored with the beacons
+func beaconUIDRows(data []beacon.Result, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		for _, uid := range d.UIDs {
+			if showNetNames {
+				rows = append(rows, []string{d.SrcNetworkName, d.DstNetworkName, d.SrcIP, d.DstIP, uid})
+			} else {
+				rows = append(rows, []string{d.SrcIP, d.DstIP, uid})
+			}
+		}
+	}
+	return rows
+}
+
+func showBeaconUIDsHuman(data []beacon.Result, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(beaconUIDHeader(showNetNames))
+	table.AppendBulk(beaconUIDRows(data, showNetNames))
+	table.Render()
+}
+
+func showBeaconUIDsDelim(data []beacon.Result, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(beaconUIDHeader(showNetNames), delim))
+	for _, row := range beaconUIDRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
+
+// beaconCIDRHeader returns the header for the rows created by beaconCIDRRows
+func beaconCIDRHeader(showNetNames bool) []string {
+	header := []string{
+		"Score", "Mean Score", "Source IP", "Destination CIDR", "Destinations",
+		"Connections", "Avg. Bytes", "Total Bytes", "TS Score", "DS Score", "Top Intvl",
+	}
+	if showNetNames {
+		header = append([]string{"Source Network", "Destination Network"}, header...)
+	}
+	return header
+}
+
+// beaconCIDRRows creates a row for each group of aggregated beacons
+func beaconCIDRRows(data []beacon.CIDRResult, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		row := []string{
+			f(d.Score), f(d.MeanScore), d.SrcIP, d.DstCIDR, strconv.Itoa(d.DstCount),
+			i(d.Connections), f(d.AvgBytes), i(d.TotalBytes), f(d.TsScore), f(d.DsScore),
+			i(d.TopInterval),
+		}
+		if showNetNames {
+			row = append([]string{d.SrcNetworkName, d.DstNetworkName}, row...)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+func showBeaconsCIDRHuman(data []beacon.CIDRResult, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(beaconCIDRHeader(showNetNames))
+	table.AppendBulk(beaconCIDRRows(data, showNetNames))
+	table.Render()
+}
+
+func showBeaconsCIDRDelim(data []beacon.CIDRResult, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(beaconCIDRHeader(showNetNames), delim))
+	for _, row := range beaconCIDRRows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
+
+func showBeaconsByJA3(c *cli.Context, res *resources.Resources, db string, tmpl *template.Template) error {
+	data, err := beacon.JA3Results(res)
+	if err != nil {
+		res.Log.Error(err)
+		return cli.NewExitError(err, -1)
+	}
+
+	if !(len(data) > 0) {
+		return cli.NewExitError("No beacons with a JA3 fingerprint were found for "+db, -1)
+	}
+
+	redactBeaconJA3s(newRedactor(c, res), data)
+
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
+	showNetNames := c.Bool("network-names")
+	if c.Bool("human-readable") {
+		showBeaconsJA3Human(data, showNetNames)
+		return nil
+	}
+	showBeaconsJA3Delim(data, c.String("delimiter"), showNetNames)
+	return nil
+}
+
+// beaconJA3Header returns the header for the rows created by beaconJA3Rows
+func beaconJA3Header() []string {
+	return []string{"Score", "JA3", "Sources", "Destinations", "Connections", "Beacons"}
+}
+
+// beaconJA3Rows creates a row for each JA3 listing its beacons with their scores
+func beaconJA3Rows(data []beacon.JA3Result, showNetNames bool) [][]string {
+	var rows [][]string
+	for _, d := range data {
+		beacons := make([]string, 0, len(d.Beacons))
+		for _, b := range d.Beacons {
+			src, dst := b.SrcIP, b.DstIP
+			if showNetNames {
+				src = b.SrcNetworkName + ":" + src
+				dst = b.DstNetworkName + ":" + dst
+			}
+			beacons = append(beacons, src+" > "+dst+" ("+f(b.Score)+")")
+		}
+		rows = append(rows, []string{
+			f(d.Score), d.JA3, strconv.Itoa(d.SrcCount), strconv.Itoa(d.DstCount),
+			i(d.Connections), strings.Join(beacons, " "),
+		})
+	}
+	return rows
+}
+
+func showBeaconsJA3Human(data []beacon.JA3Result, showNetNames bool) {
+	table := tablewriter.NewWriter(os.Stdout)
+	table.SetHeader(beaconJA3Header())
+	table.AppendBulk(beaconJA3Rows(data, showNetNames))
+	table.Render()
+}
+
+func showBeaconsJA3Delim(data []beacon.JA3Result, delim string, showNetNames bool) {
+	fmt.Println(strings.Join(beaconJA3Header(), delim))
+	for _, row := range beaconJA3Rows(data, showNetNames) {
+		fmt.Println(strings.Join(row, delim))
+	}
+}
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/activecm/rita/pkg/blacklist"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -24,6 +23,7 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
 			netNamesFlag,
 		},
 		Usage:  "Print blacklisted hostnames which received connections",
@@ -40,7 +40,12 @@ func printBLHostnames(c *cli.Context) error {
 		return cli.NewExitError("Specify a database", -1)
 	}
 
-	res := resources.InitResources(getConfigFilePath(c))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
 	data, err := blacklist.HostnameResults(res, "conn_count", c.Int("limit"), c.Bool("no-limit"))
@@ -54,6 +59,14 @@ func printBLHostnames(c *cli.Context) error {
 		return cli.NewExitError("No results were found for "+db, -1)
 	}
 
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
 	if c.Bool("human-readable") {
 		err = showBLHostnamesHuman(data, c.Bool("network-names"))
 		if err != nil {
@@ -8,7 +8,6 @@ import (
 	"strings"
 
 	"github.com/activecm/rita/pkg/blacklist"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -25,6 +24,7 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
 			netNamesFlag,
 		},
 		Usage:  "Print blacklisted IPs which initiated connections",
@@ -42,6 +42,7 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
 			netNamesFlag,
 		},
 		Usage:  "Print blacklisted IPs which received connections",
@@ -60,7 +61,7 @@ func parseBLArgs(c *cli.Context) (string, string, bool, bool, bool, error) {
 	var err error
 	if db == "" {
 		err = cli.NewExitError("Specify a database", -1)
-	} else if sort != "conn_count" && sort != "total_bytes" {
+	} else if sort != "conn_count" && sort != "total_bytes" && sort != "confidence" {
 		err = cli.NewExitError("Invalid option passed to sort flag", -1)
 	}
 	return db, sort, connected, human, showNetNames, err
@@ -71,7 +72,12 @@ func printBLSourceIPs(c *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	res := resources.InitResources(getConfigFilePath(c))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
 	data, err := blacklist.SrcIPResults(res, sort, c.Int("limit"), c.Bool("no-limit"))
@@ -85,6 +91,14 @@ func printBLSourceIPs(c *cli.Context) error {
 		return cli.NewExitError("No results were found for "+db, -1)
 	}
 
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
 	if human {
 		err = showBLIPsHuman(data, connected, showNetNames, true)
 		if err != nil {
@@ -105,7 +119,12 @@ func printBLDestIPs(c *cli.Context) error {
 		return err
 	}
 
-	res := resources.InitResources(getConfigFilePath(c))
+	tmpl, err := parseTemplateFlag(c)
+	if err != nil {
+		return err
+	}
+
+	res := initResources(c)
 	res.DB.SelectDB(db)
 
 	data, err := blacklist.DstIPResults(res, sort, c.Int("limit"), c.Bool("no-limit"))
@@ -119,6 +138,14 @@ func printBLDestIPs(c *cli.Context) error {
 		return cli.NewExitError("No results were found for "+db, -1)
 	}
 
+	if tmpl != nil {
+		err := showTemplate(os.Stdout, tmpl, data)
+		if err != nil {
+			return cli.NewExitError(err.Error(), -1)
+		}
+		return nil
+	}
+
 	if human {
 		err = showBLIPsHuman(data, connected, showNetNames, false)
 		if err != nil {
@@ -0,0 +1,58 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/activecm/rita/config"
+	"github.com/urfave/cli"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func init() {
+	command := cli.Command{
+		Name:      "show-config",
+		Usage:     "Print the analysis settings which produced the results of a database",
+		ArgsUsage: "<database>",
+		Flags: []cli.Flag{
+			ConfigFlag,
+		},
+		Action: showConfig,
+	}
+
+	bootstrapCommands(command)
+}
+
+func showConfig(c *cli.Context) error {
+	db := c.Args().Get(0)
+	if db == "" {
+		return cli.NewExitError("Specify a database", -1)
+	}
+
+	res := initResources(c)
+
+	info, err := res.MetaDB.GetDBMetaInfo(db)
+	if err != nil {
+		return cli.NewExitError("Database "+db+" is not tracked by RITA", -1)
+	}
+
+	if info.ConfigSnapshot == nil {
+		return cli.NewExitError("No config snapshot was stored for "+db+". It was analyzed by an older version of RITA.", -1)
+	}
+
+	if err := writeConfigSnapshot(os.Stdout, *info.ConfigSnapshot); err != nil {
+		return cli.NewExitError(err.Error(), -1)
+	}
+	return nil
+}
+
+// writeConfigSnapshot writes the snapshot in the YAML layout of the config file
+func writeConfigSnapshot(w io.Writer, snapshot config.Snapshot) error {
+	out, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprint(w, string(out))
+	return err
+}
@@ -3,7 +3,6 @@ package commands
 import (
 	"fmt"
 
-	"github.com/activecm/rita/resources"
 	"github.com/urfave/cli"
 )
 
@@ -17,7 +16,7 @@ func init() {
 			ConfigFlag,
 		},
 		Action: func(c *cli.Context) error {
-			res := resources.InitResources(getConfigFilePath(c))
+			res := initResources(c)
 
 			if res != nil {
 				for _, name := range res.MetaDB.GetDatabases() {
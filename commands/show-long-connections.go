@@ -2,12 +2,13 @@ package commands
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/activecm/rita/pkg/uconn"
-	"github.com/activecm/rita/resources"
 	"github.com/activecm/rita/util"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
@@ -25,6 +26,8 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
 		},
 		Action: func(c *cli.Context) error {
@@ -33,7 +36,12 @@ func init() {
 				return cli.NewExitError("Specify a database", -1)
 			}
 
-			res := resources.InitResources(getConfigFilePath(c))
+			tmpl, err := parseTemplateFlag(c)
+			if err != nil {
+				return err
+			}
+
+			res := initResources(c)
 			res.DB.SelectDB(db)
 
 			thresh := 60 // 1 minute
@@ -48,14 +56,24 @@ func init() {
 				return cli.NewExitError("No results were found for "+db, -1)
 			}
 
+			redactLongConns(newRedactor(c, res), data)
+
+			if tmpl != nil {
+				err := showTemplate(os.Stdout, tmpl, data)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				return nil
+			}
+
 			if c.Bool("human-readable") {
-				err := showConnsHuman(data, c.Bool("network-names"))
+				err := showConnsHuman(os.Stdout, data, c.Bool("network-names"))
 				if err != nil {
 					return cli.NewExitError(err.Error(), -1)
 				}
 				return nil
 			}
-			err = showConns(data, c.String("delimiter"), c.Bool("network-names"))
+			err = showConns(os.Stdout, data, c.String("delimiter"), c.Bool("network-names"))
 			if err != nil {
 				return cli.NewExitError(err.Error(), -1)
 			}
@@ -65,17 +83,17 @@ func init() {
 	bootstrapCommands(command)
 }
 
-func showConns(connResults []uconn.LongConnResult, delim string, showNetNames bool) error {
+func showConns(w io.Writer, connResults []uconn.LongConnResult, delim string, showNetNames bool) error {
 
 	var headerFields []string
 	if showNetNames {
-		headerFields = []string{"Source Network", "Destination Network", "Source IP", "Destination IP", "Port:Protocol:Service", "Duration", "State"}
+		headerFields = []string{"Source Network", "Destination Network", "Source IP", "Source Port", "Destination IP", "Destination Port", "Protocol", "Port:Protocol:Service", "Duration", "State"}
 	} else {
-		headerFields = []string{"Source IP", "Destination IP", "Port:Protocol:Service", "Duration", "State"}
+		headerFields = []string{"Source IP", "Source Port", "Destination IP", "Destination Port", "Protocol", "Port:Protocol:Service", "Duration", "State"}
 	}
 
 	// Print the headers and analytic values, separated by a delimiter
-	fmt.Println(strings.Join(headerFields, delim))
+	fmt.Fprintln(w, strings.Join(headerFields, delim))
 	for _, result := range connResults {
 		var row []string
 
@@ -84,13 +102,17 @@ func showConns(connResults []uconn.LongConnResult, delim string, showNetNames bo
 		if result.Open {
 			state = "open"
 		}
+		srcPort, dstPort, proto := connTupleFields(result.ConnTuple)
 
 		if showNetNames {
 			row = []string{
 				result.SrcNetworkName,
 				result.DstNetworkName,
 				result.SrcIP,
+				srcPort,
 				result.DstIP,
+				dstPort,
+				proto,
 				strings.Join(result.Tuples, " "),
 				f(result.MaxDuration),
 				state,
@@ -98,26 +120,29 @@ func showConns(connResults []uconn.LongConnResult, delim string, showNetNames bo
 		} else {
 			row = []string{
 				result.SrcIP,
+				srcPort,
 				result.DstIP,
+				dstPort,
+				proto,
 				strings.Join(result.Tuples, " "),
 				f(result.MaxDuration),
 				state,
 			}
 		}
 
-		fmt.Println(strings.Join(row, delim))
+		fmt.Fprintln(w, strings.Join(row, delim))
 	}
 	return nil
 }
 
-func showConnsHuman(connResults []uconn.LongConnResult, showNetNames bool) error {
-	table := tablewriter.NewWriter(os.Stdout)
+func showConnsHuman(w io.Writer, connResults []uconn.LongConnResult, showNetNames bool) error {
+	table := tablewriter.NewWriter(w)
 
 	var headerFields []string
 	if showNetNames {
-		headerFields = []string{"Source Network", "Destination Network", "Source IP", "Destination IP", "Port:Protocol:Service", "Duration", "State"}
+		headerFields = []string{"Source Network", "Destination Network", "Source IP", "Source Port", "Destination IP", "Destination Port", "Protocol", "Port:Protocol:Service", "Duration", "State"}
 	} else {
-		headerFields = []string{"Source IP", "Destination IP", "Port:Protocol:Service", "Duration", "State"}
+		headerFields = []string{"Source IP", "Source Port", "Destination IP", "Destination Port", "Protocol", "Port:Protocol:Service", "Duration", "State"}
 	}
 
 	table.SetHeader(headerFields)
@@ -129,13 +154,17 @@ func showConnsHuman(connResults []uconn.LongConnResult, showNetNames bool) error
 		if result.Open {
 			state = "open"
 		}
+		srcPort, dstPort, proto := connTupleFields(result.ConnTuple)
 
 		if showNetNames {
 			row = []string{
 				result.SrcNetworkName,
 				result.DstNetworkName,
 				result.SrcIP,
+				srcPort,
 				result.DstIP,
+				dstPort,
+				proto,
 				strings.Join(result.Tuples, " "),
 				util.FormatDuration(time.Duration(int(result.MaxDuration * float64(time.Second)))),
 				state,
@@ -143,7 +172,10 @@ func showConnsHuman(connResults []uconn.LongConnResult, showNetNames bool) error
 		} else {
 			row = []string{
 				result.SrcIP,
+				srcPort,
 				result.DstIP,
+				dstPort,
+				proto,
 				strings.Join(result.Tuples, " "),
 				util.FormatDuration(time.Duration(int(result.MaxDuration * float64(time.Second)))),
 				state,
@@ -155,3 +187,12 @@ func showConnsHuman(connResults []uconn.LongConnResult, showNetNames bool) error
 	table.Render()
 	return nil
 }
+
+// connTupleFields formats the ports and protocol of the longest connection.
+// Long connections imported by older versions of RITA don't have them.
+func connTupleFields(conn uconn.ConnTuple) (string, string, string) {
+	if conn.Proto == "" {
+		return "-", "-", "-"
+	}
+	return strconv.Itoa(conn.SrcPort), strconv.Itoa(conn.DstPort), conn.Proto
+}
@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestLongConns() []uconn.LongConnResult {
+	return []uconn.LongConnResult{
+		{
+			UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "203.0.113.1"}),
+			ConnTuple:    uconn.ConnTuple{SrcPort: 51234, DstPort: 443, Proto: "tcp"},
+			MaxDuration:  7200,
+			Tuples:       []string{"443:tcp:ssl"},
+		},
+		{
+			// imported before the ports and protocol were stored
+			UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.2"}, data.UniqueIP{IP: "203.0.113.2"}),
+			MaxDuration:  3600,
+			Tuples:       []string{"22:tcp:ssh"},
+			Open:         true,
+		},
+	}
+}
+
+func TestShowConns(t *testing.T) {
+	var out bytes.Buffer
+	require.Nil(t, showConns(&out, newTestLongConns(), ",", false))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "Source IP,Source Port,Destination IP,Destination Port,Protocol,Port:Protocol:Service,Duration,State", lines[0])
+	assert.Equal(t, "10.0.0.1,51234,203.0.113.1,443,tcp,443:tcp:ssl,7200,closed", lines[1])
+	assert.Equal(t, "10.0.0.2,-,203.0.113.2,-,-,22:tcp:ssh,3600,open", lines[2])
+}
+
+func TestShowConnsHuman(t *testing.T) {
+	var out bytes.Buffer
+	require.Nil(t, showConnsHuman(&out, newTestLongConns(), true))
+
+	table := out.String()
+	for _, header := range []string{"SOURCE PORT", "DESTINATION PORT", "PROTOCOL"} {
+		assert.Contains(t, table, header)
+	}
+	assert.Regexp(t, `10\.0\.0\.1\s+\|\s+51234\s+\|\s+203\.0\.113\.1\s+\|\s+443\s+\|\s+tcp\s+\|`, table)
+}
@@ -8,7 +8,6 @@ import (
 	"time"
 
 	"github.com/activecm/rita/pkg/uconn"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -25,6 +24,8 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
 		},
 		Action: func(c *cli.Context) error {
@@ -33,7 +34,12 @@ func init() {
 				return cli.NewExitError("Specify a database", -1)
 			}
 
-			res := resources.InitResources(getConfigFilePath(c))
+			tmpl, err := parseTemplateFlag(c)
+			if err != nil {
+				return err
+			}
+
+			res := initResources(c)
 			res.DB.SelectDB(db)
 
 			thresh := 60 // 1 minute
@@ -48,6 +54,16 @@ func init() {
 				return cli.NewExitError("No results were found for "+db, -1)
 			}
 
+			redactOpenConns(newRedactor(c, res), data)
+
+			if tmpl != nil {
+				err := showTemplate(os.Stdout, tmpl, data)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				return nil
+			}
+
 			if c.Bool("human-readable") {
 				err := showOpenConnsHuman(data, c.Bool("network-names"))
 				if err != nil {
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/activecm/rita/pkg/beacon"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -27,6 +26,8 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
+			redactFlag,
 			netNamesFlag,
 		},
 		Action: func(c *cli.Context) error {
@@ -35,7 +36,12 @@ func init() {
 				return cli.NewExitError("Specify a database", -1)
 			}
 
-			res := resources.InitResources(getConfigFilePath(c))
+			tmpl, err := parseTemplateFlag(c)
+			if err != nil {
+				return err
+			}
+
+			res := initResources(c)
 			res.DB.SelectDB(db)
 
 			sortDirection := -1
@@ -54,6 +60,16 @@ func init() {
 				return cli.NewExitError("No results were found for "+db, -1)
 			}
 
+			redactStrobes(newRedactor(c, res), data)
+
+			if tmpl != nil {
+				err := showTemplate(os.Stdout, tmpl, data)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				return nil
+			}
+
 			if c.Bool("human-readable") {
 				err := showStrobesHuman(data, c.Bool("network-names"))
 				if err != nil {
@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	"github.com/activecm/rita/pkg/useragent"
-	"github.com/activecm/rita/resources"
 	"github.com/olekukonko/tablewriter"
 	"github.com/urfave/cli"
 )
@@ -27,6 +26,7 @@ func init() {
 			limitFlag,
 			noLimitFlag,
 			delimFlag,
+			templateFlag,
 		},
 		Action: func(c *cli.Context) error {
 			db := c.Args().Get(0)
@@ -34,7 +34,12 @@ func init() {
 				return cli.NewExitError("Specify a database", -1)
 			}
 
-			res := resources.InitResources(getConfigFilePath(c))
+			tmpl, err := parseTemplateFlag(c)
+			if err != nil {
+				return err
+			}
+
+			res := initResources(c)
 			res.DB.SelectDB(db)
 
 			sortDirection := 1
@@ -53,6 +58,14 @@ func init() {
 				return cli.NewExitError("No results were found for "+db, -1)
 			}
 
+			if tmpl != nil {
+				err := showTemplate(os.Stdout, tmpl, data)
+				if err != nil {
+					return cli.NewExitError(err.Error(), -1)
+				}
+				return nil
+			}
+
 			if c.Bool("human-readable") {
 				err := showAgentsHuman(data)
 				if err != nil {
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"text/template"
+
+	"github.com/urfave/cli"
+)
+
+// parseTemplateFlag compiles the template given via --template. A nil
+// template is returned if the flag was not set.
+func parseTemplateFlag(c *cli.Context) (*template.Template, error) {
+	return parseResultTemplate(c.String("template"))
+}
+
+// parseResultTemplate compiles a user supplied Go text/template which is used
+// to render each result in place of the default output format
+func parseResultTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("result").Parse(text)
+	if err != nil {
+		return nil, cli.NewExitError(fmt.Sprintf("Invalid output template: %s", err.Error()), -1)
+	}
+	return tmpl, nil
+}
+
+// showTemplate renders each element of results (a slice of result structs)
+// through tmpl, writing one rendered result per line
+func showTemplate(w io.Writer, tmpl *template.Template, results interface{}) error {
+	data := reflect.ValueOf(results)
+	if data.Kind() != reflect.Slice {
+		return fmt.Errorf("cannot render %s through an output template", data.Kind())
+	}
+
+	for idx := 0; idx < data.Len(); idx++ {
+		err := tmpl.Execute(w, data.Index(idx).Interface())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, "\n")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseResultTemplate(t *testing.T) {
+	tmpl, err := parseResultTemplate("")
+	assert.Nil(t, err)
+	assert.Nil(t, tmpl, "an empty template should not be compiled")
+
+	_, err = parseResultTemplate("{{.SrcIP")
+	assert.NotNil(t, err, "an invalid template should be rejected before querying")
+
+	tmpl, err = parseResultTemplate("{{.SrcIP}}")
+	assert.Nil(t, err)
+	assert.NotNil(t, tmpl)
+}
+
+func TestShowTemplate(t *testing.T) {
+	results := []beacon.Result{
+		{
+			UniqueIPPair: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0.1"},
+				data.UniqueIP{IP: "8.8.8.8"},
+			),
+			Connections: 100,
+			Ts:          beacon.TSData{Mode: 60},
+			Score:       0.95,
+		},
+		{
+			UniqueIPPair: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0.2"},
+				data.UniqueIP{IP: "1.1.1.1"},
+			),
+			Connections: 5,
+			Ts:          beacon.TSData{Mode: 3600},
+			Score:       0.5,
+		},
+	}
+
+	tmpl, err := parseResultTemplate("{{.SrcIP}} -> {{.DstIP}} conns={{.Connections}} intvl={{.Ts.Mode}} score={{printf \"%.2f\" .Score}}")
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	err = showTemplate(&out, tmpl, results)
+	require.Nil(t, err)
+
+	expected := "10.0.0.1 -> 8.8.8.8 conns=100 intvl=60 score=0.95\n" +
+		"10.0.0.2 -> 1.1.1.1 conns=5 intvl=3600 score=0.50\n"
+	assert.Equal(t, expected, out.String())
+
+	// referencing a field which doesn't exist on the result fails at render time
+	tmpl, err = parseResultTemplate("{{.NotAField}}")
+	require.Nil(t, err)
+	out.Reset()
+	assert.NotNil(t, showTemplate(&out, tmpl, results))
+
+	// only slices of results may be rendered
+	assert.NotNil(t, showTemplate(&out, tmpl, results[0]))
+}
+
+func TestRedactBeacons(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Filtering.InternalSubnets = []string{"10.0.0.0/8"}
+	conf.S.Redaction.Fields = []string{"src", "dst"}
+	conf.S.Redaction.Method = "hash"
+	conf.S.Redaction.InternalOnly = true
+
+	results := []beacon.Result{
+		{
+			UniqueIPPair: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0.1"},
+				data.UniqueIP{IP: "8.8.8.8"},
+			),
+			Connections: 100,
+			Ts:          beacon.TSData{Mode: 60},
+			Score:       0.95,
+		},
+	}
+
+	redactBeacons(redact.NewRedactor(conf, true), results)
+
+	tmpl, err := parseResultTemplate("{{.SrcIP}} -> {{.DstIP}} conns={{.Connections}} intvl={{.Ts.Mode}} score={{printf \"%.2f\" .Score}}")
+	require.Nil(t, err)
+
+	var out bytes.Buffer
+	require.Nil(t, showTemplate(&out, tmpl, results))
+
+	// the internal source is masked while the destination and scores are preserved
+	assert.NotContains(t, out.String(), "10.0.0.1")
+	assert.Contains(t, out.String(), "redacted-")
+	assert.Contains(t, out.String(), "-> 8.8.8.8 conns=100 intvl=60 score=0.95")
+}
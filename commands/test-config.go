@@ -2,51 +2,174 @@ package commands
 
 import (
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
 	"os"
+	"sort"
+	"time"
 
 	"github.com/activecm/rita/config"
-	"github.com/activecm/rita/resources"
+	"github.com/activecm/rita/database"
+	log "github.com/sirupsen/logrus"
 
 	"github.com/urfave/cli"
 	yaml "gopkg.in/yaml.v2"
 )
 
+// feodoBlacklistURL is the feed downloaded for the feodotracker.abuse.ch blacklist
+const feodoBlacklistURL = "https://feodotracker.abuse.ch/downloads/ipblocklist.txt"
+
 func init() {
 	command := cli.Command{
-		Flags:  []cli.Flag{ConfigFlag},
-		Name:   "test-config",
-		Usage:  "Check the configuration file for validity",
-		Before: SetConfigFilePath,
-		Action: testConfiguration,
+		Flags: []cli.Flag{
+			ConfigFlag,
+			cli.BoolFlag{
+				Name:  "connectivity",
+				Usage: "Also check that MongoDB and the blacklist feeds can be reached",
+			},
+		},
+		Name:      "test-config",
+		Usage:     "Check the configuration file for validity",
+		ArgsUsage: "[config file]",
+		Before:    SetConfigFilePath,
+		Action:    testConfiguration,
 	}
 
 	allCommands = append(allCommands, command)
 }
 
-// testConfiguration prints out the result of parsing the config file
+// testConfiguration prints out the result of parsing the config file and
+// exits non-zero if there were any problems
 func testConfiguration(c *cli.Context) error {
+	configPath := c.Args().First()
+	if configPath == "" {
+		configPath = getConfigFilePath(c)
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	if exitCode := checkConfiguration(os.Stdout, configPath, c.Bool("connectivity"), client); exitCode != 0 {
+		return cli.NewExitError("", exitCode)
+	}
+	return nil
+}
+
+// checkConfiguration loads and validates the config file at configPath,
+// reporting any problems to out, and returns the exit code for the result.
+// If connectivity is set, MongoDB and the blacklist feeds must be reachable.
+func checkConfiguration(out io.Writer, configPath string, connectivity bool, client *http.Client) int {
 	// First, print out the config as it was parsed
-	conf, err := config.LoadConfig(getConfigFilePath(c))
+	conf, err := config.LoadConfig(configPath)
 	if err != nil {
-		fmt.Fprintf(os.Stdout, "Failed to config: %s\n", err.Error())
-		os.Exit(-1)
+		fmt.Fprintf(out, "\t[!] Invalid config file: %s\n", err.Error())
+		return 1
 	}
 
 	staticConfig, err := yaml.Marshal(conf.S)
 	if err != nil {
-		return err
+		fmt.Fprintf(out, "\t[!] %s\n", err.Error())
+		return 1
 	}
 
 	tableConfig, err := yaml.Marshal(conf.T)
 	if err != nil {
-		return err
+		fmt.Fprintf(out, "\t[!] %s\n", err.Error())
+		return 1
 	}
 
-	fmt.Fprintf(os.Stdout, "\n%s\n", string(staticConfig))
-	fmt.Fprintf(os.Stdout, "\n%s\n", string(tableConfig))
+	fmt.Fprintf(out, "\n%s\n", string(staticConfig))
+	fmt.Fprintf(out, "\n%s\n", string(tableConfig))
 
-	// Then test initializing external resources like db connection and file handles
-	resources.InitResources(getConfigFilePath(c))
+	// Then test reaching external resources like the db connection and blacklist feeds
+	if connectivity {
+		problems := checkBlacklistFeeds(conf, client)
+		problems = append(problems, checkMongoDBConnections(conf)...)
+		for _, problem := range problems {
+			fmt.Fprintf(out, "\t[!] %s\n", problem.Error())
+		}
+		if len(problems) > 0 {
+			return 1
+		}
+	}
 
-	return nil
+	fmt.Fprintln(out, "\t[+] The config file is valid")
+	return 0
+}
+
+// blacklistFeeds returns the files and URLs the blacklists are read from
+func blacklistFeeds(conf *config.Config) []string {
+	if !conf.S.Blacklisted.Enabled {
+		return nil
+	}
+
+	var feeds []string
+	if conf.S.Blacklisted.UseFeodo {
+		feeds = append(feeds, feodoBlacklistURL)
+	}
+	feeds = append(feeds, conf.S.Blacklisted.IPBlacklists...)
+	feeds = append(feeds, conf.S.Blacklisted.HostnameBlacklists...)
+	return feeds
+}
+
+// checkBlacklistFeeds ensures each blacklist can be read from its file or URL
+func checkBlacklistFeeds(conf *config.Config, client *http.Client) []error {
+	var problems []error
+	for _, feed := range blacklistFeeds(conf) {
+		// blacklists are read from a file before trying a URL
+		if _, err := os.Stat(feed); err == nil {
+			continue
+		}
+
+		feedURL, err := url.Parse(feed)
+		if err != nil || (feedURL.Scheme != "http" && feedURL.Scheme != "https") {
+			problems = append(problems, fmt.Errorf("blacklist %s is not a file or an http(s) URL", feed))
+			continue
+		}
+
+		resp, err := client.Get(feed)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("could not reach blacklist %s: %w", feed, err))
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			problems = append(problems, fmt.Errorf("could not download blacklist %s: %s", feed, resp.Status))
+		}
+	}
+	return problems
+}
+
+// checkMongoDBConnections ensures the default MongoDB connection and each
+// named connection can be dialed
+func checkMongoDBConnections(conf *config.Config) []error {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	aliases := []string{""}
+	for alias := range conf.S.MongoDB.Connections {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+
+	var problems []error
+	for _, alias := range aliases {
+		aliasConf := *conf
+		if err := aliasConf.UseConnection(alias); err != nil {
+			problems = append(problems, err)
+			continue
+		}
+
+		db, err := database.NewDB(&aliasConf, logger)
+		if err != nil {
+			name := "the default MongoDB connection"
+			if alias != "" {
+				name = fmt.Sprintf("MongoDB connection %q", alias)
+			}
+			problems = append(problems, fmt.Errorf("could not connect to %s at %s: %w", name, aliasConf.S.MongoDB.ConnectionString, err))
+			continue
+		}
+		db.Session.Close()
+	}
+	return problems
 }
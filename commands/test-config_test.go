@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestConfig(t *testing.T, contents string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestCheckConfiguration(t *testing.T) {
+	// the version is normally set by the build
+	version := config.Version
+	config.Version = "v0.0.0+testing"
+	defer func() { config.Version = version }()
+
+	feeds := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ips.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("203.0.113.7\n"))
+	}))
+	defer feeds.Close()
+
+	cases := []struct {
+		msg          string
+		contents     string
+		connectivity bool
+		exitCode     int
+	}{
+		{"valid config", "Filtering:\n  InternalSubnets: [\"10.0.0.0/8\", \"192.168.1.1\"]\n", false, 0},
+		{"invalid CIDR", "Filtering:\n  InternalSubnets: [\"10.0.0.0/33\"]\n", false, 1},
+		{"invalid threshold", "Beacon:\n  BlacklistMinScore: 2\n", false, 1},
+		{"invalid yaml", "Beacon: [\n", false, 1},
+		{"reachable feed",
+			"BlackListed:\n  feodotracker.abuse.ch: false\n  CustomIPBlacklists: [\"" + feeds.URL + "/ips.txt\"]\n",
+			true, 0},
+		{"missing feed",
+			"BlackListed:\n  feodotracker.abuse.ch: false\n  CustomIPBlacklists: [\"" + feeds.URL + "/missing.txt\"]\n",
+			true, 1},
+		{"feed which is neither a file nor a URL",
+			"BlackListed:\n  feodotracker.abuse.ch: false\n  CustomIPBlacklists: [\"/no/such/list.txt\"]\n",
+			true, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.msg, func(t *testing.T) {
+			conf, err := config.LoadConfig(writeTestConfig(t, c.contents))
+			if err == nil && c.connectivity {
+				// only the feeds are checked here, not MongoDB
+				problems := checkBlacklistFeeds(conf, feeds.Client())
+				assert.Equal(t, c.exitCode != 0, len(problems) > 0, "%v", problems)
+				return
+			}
+
+			out := new(bytes.Buffer)
+			assert.Equal(t, c.exitCode, checkConfiguration(out, writeTestConfig(t, c.contents), c.connectivity, feeds.Client()), out.String())
+		})
+	}
+
+	out := new(bytes.Buffer)
+	assert.Equal(t, 1, checkConfiguration(out, filepath.Join(t.TempDir(), "missing.yaml"), false, feeds.Client()))
+	assert.Contains(t, out.String(), "Invalid config file")
+}
+
+func TestBlacklistFeeds(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	conf.S.Blacklisted.Enabled = true
+	conf.S.Blacklisted.UseFeodo = true
+	conf.S.Blacklisted.IPBlacklists = []string{"/etc/rita/ips.txt"}
+	conf.S.Blacklisted.HostnameBlacklists = []string{"https://example.com/hosts.txt"}
+	assert.Equal(t, []string{feodoBlacklistURL, "/etc/rita/ips.txt", "https://example.com/hosts.txt"}, blacklistFeeds(conf))
+
+	conf.S.Blacklisted.Enabled = false
+	assert.Empty(t, blacklistFeeds(conf), "no feeds are read when blacklisting is disabled")
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/activecm/rita/config"
-	"github.com/activecm/rita/resources"
 	"github.com/blang/semver"
 	"github.com/google/go-github/github"
 	log "github.com/sirupsen/logrus"
@@ -22,14 +21,14 @@ var versions = []string{"Major", "Minor", "Patch"}
 func GetVersionPrinter() func(*cli.Context) {
 	return func(c *cli.Context) {
 		fmt.Printf("%s version %s\n", c.App.Name, c.App.Version)
-		fmt.Println(updateCheck(getConfigFilePath(c)))
+		fmt.Println(updateCheck(getConfigFilePath(c), getConnection(c), isReadOnly(c)))
 	}
 }
 
 // UpdateCheck Performs a check for the new version of RITA against the git repository and
 //returns a string indicating the new version if available
-func updateCheck(configFile string) string {
-	res := resources.InitResources(configFile)
+func updateCheck(configFile string, connection string, readOnly bool) string {
+	res := initResourcesFromFile(configFile, connection, readOnly)
 	delta := res.Config.S.UserConfig.UpdateCheckFrequency
 	var newVersion semver.Version
 	var err error
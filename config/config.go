@@ -28,7 +28,8 @@ type (
 const defaultConfigPath = "/etc/rita/config.yaml"
 
 // LoadConfig initializes a Config struct with values read
-// from a config file. It takes a string for the path to the file.
+// from a config file. It takes a string for the path to the file,
+// or an HTTP(S) URL the config is fetched from.
 // If the string is empty it uses the default path.
 func LoadConfig(customConfigPath string) (*Config, error) {
 	// Use the default path unless a custom path is given
@@ -49,8 +50,8 @@ func LoadConfig(customConfigPath string) (*Config, error) {
 		return nil, err
 	}
 
-	// Read the contents from the config file
-	contents, err := readStaticConfigFile(configPath)
+	// Read the contents from the config file or URL
+	contents, err := readStaticConfig(configPath)
 	if err != nil {
 		return nil, err
 	}
@@ -60,6 +61,14 @@ func LoadConfig(customConfigPath string) (*Config, error) {
 		return nil, err
 	}
 
+	// Ensure the resulting static config is usable
+	if err := validateStaticConfig(&config.S); err != nil {
+		return nil, err
+	}
+
+	// Only keep fetched configs which are usable
+	cacheStaticConfig(configPath, contents)
+
 	// Use the static config to initialize the running config
 	if err := initRunningConfig(&config.S, &config.R); err != nil {
 		return nil, err
@@ -68,6 +77,13 @@ func LoadConfig(customConfigPath string) (*Config, error) {
 	return config, nil
 }
 
+// Validate ensures the static config holds values RITA knows how to use.
+// LoadConfig validates the config it loads; Validate checks a config which
+// has been changed since.
+func (c *Config) Validate() error {
+	return validateStaticConfig(&c.S)
+}
+
 // expandConfig expands environment variables in config strings
 func expandConfig(reflected reflect.Value) {
 	for i := 0; i < reflected.NumField(); i++ {
@@ -83,6 +99,14 @@ func expandConfig(reflected reflect.Value) {
 				strs[i] = os.ExpandEnv(str)
 			}
 			f.Set(reflect.ValueOf(strs))
+		} else if f.Kind() == reflect.Map && f.Type().Elem().Kind() == reflect.Struct {
+			// map values are not addressable so each one is expanded in a copy
+			for _, key := range f.MapKeys() {
+				elem := reflect.New(f.Type().Elem()).Elem()
+				elem.Set(f.MapIndex(key))
+				expandConfig(elem)
+				f.SetMapIndex(key, elem)
+			}
 		}
 	}
 }
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RemoteConfigAuthEnv names the environment variable holding the value of the
+// Authorization header sent when the config is fetched from a URL. It is read
+// from the environment so the credentials don't show up in the process list.
+const RemoteConfigAuthEnv = "RITA_CONFIG_AUTHORIZATION"
+
+// maxRemoteConfigSize caps the size of a fetched config
+const maxRemoteConfigSize = 10 << 20
+
+// remoteConfigClient fetches configs served over HTTP(S)
+var remoteConfigClient = &http.Client{Timeout: 30 * time.Second}
+
+// remoteConfigCache holds the contents of the configs fetched by this process
+// which passed validation, keyed by URL. Commands load the config more than
+// once, so the central service is only asked for it once per run.
+var remoteConfigCache = struct {
+	sync.Mutex
+	contents map[string][]byte
+}{contents: make(map[string][]byte)}
+
+// isRemoteConfig returns true if cfgPath is an HTTP(S) URL rather than a file path
+func isRemoteConfig(cfgPath string) bool {
+	lower := strings.ToLower(cfgPath)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// readStaticConfig reads the contents of the config at cfgPath, which is
+// either a file path or an HTTP(S) URL
+func readStaticConfig(cfgPath string) ([]byte, error) {
+	if isRemoteConfig(cfgPath) {
+		return fetchStaticConfig(cfgPath)
+	}
+	return readStaticConfigFile(cfgPath)
+}
+
+// fetchStaticConfig downloads the config served at cfgURL, reusing the copy
+// cached by an earlier load. The Authorization header is set from
+// RemoteConfigAuthEnv if it isn't empty.
+func fetchStaticConfig(cfgURL string) ([]byte, error) {
+	remoteConfigCache.Lock()
+	cached, ok := remoteConfigCache.contents[cfgURL]
+	remoteConfigCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	req, err := http.NewRequest(http.MethodGet, cfgURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth := os.Getenv(RemoteConfigAuthEnv); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+
+	resp, err := remoteConfigClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch config from %s: %s", cfgURL, resp.Status)
+	}
+
+	contents, err := ioutil.ReadAll(http.MaxBytesReader(nil, resp.Body, maxRemoteConfigSize))
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch config from %s: %w", cfgURL, err)
+	}
+	return contents, nil
+}
+
+// cacheStaticConfig keeps the contents of a fetched config once it has been
+// validated so that later loads don't fetch it again. File configs aren't cached.
+func cacheStaticConfig(cfgPath string, contents []byte) {
+	if !isRemoteConfig(cfgPath) {
+		return
+	}
+	remoteConfigCache.Lock()
+	remoteConfigCache.contents[cfgPath] = contents
+	remoteConfigCache.Unlock()
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const remoteTestConfig = `
+MongoDB:
+    MetaDB: RemoteMetaDatabase
+Beacon:
+    DefaultConnectionThresh: 42
+`
+
+// setTestVersion sets a valid Version for the duration of the test, since
+// loading a config checks it
+func setTestVersion(t *testing.T) {
+	version := Version
+	Version = "v0.0.0+testing"
+	t.Cleanup(func() { Version = version })
+}
+
+// newConfigServer serves contents with the given status, counting the
+// requests and recording the last Authorization header
+func newConfigServer(t *testing.T, status int, contents string) (*httptest.Server, *int32, *string) {
+	var requests int32
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		auth = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte(contents))
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &auth
+}
+
+func TestLoadConfigURL(t *testing.T) {
+	setTestVersion(t)
+	server, requests, auth := newConfigServer(t, http.StatusOK, remoteTestConfig)
+	t.Setenv(RemoteConfigAuthEnv, "Bearer test-token")
+
+	conf, err := LoadConfig(server.URL + "/rita.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, "RemoteMetaDatabase", conf.S.MongoDB.MetaDB)
+	assert.Equal(t, 42, conf.S.Beacon.DefaultConnectionThresh)
+	assert.Equal(t, "Bearer test-token", *auth)
+
+	// the validated config is cached for the rest of the run
+	conf, err = LoadConfig(server.URL + "/rita.yaml")
+	require.Nil(t, err)
+	assert.Equal(t, 42, conf.S.Beacon.DefaultConnectionThresh)
+	assert.EqualValues(t, 1, atomic.LoadInt32(requests))
+}
+
+func TestLoadConfigURLWithoutAuth(t *testing.T) {
+	setTestVersion(t)
+	server, _, auth := newConfigServer(t, http.StatusOK, remoteTestConfig)
+	t.Setenv(RemoteConfigAuthEnv, "")
+
+	_, err := LoadConfig(server.URL)
+	require.Nil(t, err)
+	assert.Equal(t, "", *auth)
+}
+
+func TestLoadConfigURLInvalid(t *testing.T) {
+	setTestVersion(t)
+	server, requests, _ := newConfigServer(t, http.StatusOK, "Beacon:\n    DatasizeSeries: both\n")
+
+	_, err := LoadConfig(server.URL)
+	assert.NotNil(t, err)
+
+	// invalid configs are not cached so a fixed config is picked up
+	_, err = LoadConfig(server.URL)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 2, atomic.LoadInt32(requests))
+}
+
+func TestLoadConfigURLError(t *testing.T) {
+	setTestVersion(t)
+	server, _, _ := newConfigServer(t, http.StatusUnauthorized, "")
+
+	_, err := LoadConfig(server.URL)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "401")
+}
+
+func TestLoadConfigFileIsNotFetched(t *testing.T) {
+	setTestVersion(t)
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.Nil(t, ioutil.WriteFile(path, []byte(remoteTestConfig), 0644))
+
+	conf, err := LoadConfig(path)
+	require.Nil(t, err)
+	assert.Equal(t, 42, conf.S.Beacon.DefaultConnectionThresh)
+
+	assert.True(t, isRemoteConfig("HTTPS://config.example.com/rita.yaml"))
+	assert.False(t, isRemoteConfig(path))
+	assert.False(t, isRemoteConfig("httpconfig.yaml"))
+}
@@ -7,14 +7,16 @@ import (
 	"io/ioutil"
 
 	"github.com/activecm/mgosec"
+	"github.com/activecm/rita/util"
 	"github.com/blang/semver"
 )
 
 type (
 	//RunningCfg holds configuration options that are parsed at run time
 	RunningCfg struct {
-		MongoDB MongoDBRunningCfg
-		Version semver.Version
+		MongoDB         MongoDBRunningCfg
+		Version         semver.Version
+		AnalysisLimiter *util.Limiter // shared by the analysis workers of every module
 	}
 
 	//MongoDBRunningCfg holds parsed information for connecting to MongoDB
@@ -31,15 +33,32 @@ type (
 func initRunningConfig(static *StaticCfg, running *RunningCfg) error {
 	var err error
 
+	initMongoDBRunningConfig(&static.MongoDB, &running.MongoDB)
+
+	running.AnalysisLimiter = util.NewLimiter(static.Analysis.MaxThreads)
+
+	running.Version, err = semver.ParseTolerant(static.Version)
+	if err != nil {
+		fmt.Println("\t[!] Version error: please ensure that you cloned the git repo and are using make to build.")
+		fmt.Println("\t[!] See the following resources for further information:")
+		fmt.Println("\t[>] https://github.com/activecm/rita/blob/master/Contributing.md#common-issues")
+		fmt.Println("\t[>] https://github.com/activecm/rita/blob/master/docs/Manual%20Installation.md")
+	}
+	return err
+}
+
+// initMongoDBRunningConfig parses the TLS and authentication settings
+// used to dial the MongoDB server described by the static config
+func initMongoDBRunningConfig(static *MongoDBStaticCfg, running *MongoDBRunningCfg) {
 	//parse the tls configuration
-	if static.MongoDB.TLS.Enabled {
+	running.TLS.TLSConfig = nil
+	if static.TLS.Enabled {
 		tlsConf := &tls.Config{}
-		if !static.MongoDB.TLS.VerifyCertificate {
+		if !static.TLS.VerifyCertificate {
 			tlsConf.InsecureSkipVerify = true
 		}
-		if len(static.MongoDB.TLS.CAFile) > 0 {
-			pem, err2 := ioutil.ReadFile(static.MongoDB.TLS.CAFile)
-			err = err2
+		if len(static.TLS.CAFile) > 0 {
+			pem, err := ioutil.ReadFile(static.TLS.CAFile)
 			if err != nil {
 				fmt.Println("[!] Could not read MongoDB CA file")
 			} else {
@@ -47,25 +66,38 @@ func initRunningConfig(static *StaticCfg, running *RunningCfg) error {
 				tlsConf.RootCAs.AppendCertsFromPEM(pem)
 			}
 		}
-		running.MongoDB.TLS.TLSConfig = tlsConf
+		running.TLS.TLSConfig = tlsConf
 	}
 
 	//parse out the mongo authentication mechanism
 	authMechanism, err := mgosec.ParseAuthMechanism(
-		static.MongoDB.AuthMechanism,
+		static.AuthMechanism,
 	)
 	if err != nil {
 		authMechanism = mgosec.None
 		fmt.Println("[!] Could not parse MongoDB authentication mechanism")
 	}
-	running.MongoDB.AuthMechanismParsed = authMechanism
+	running.AuthMechanismParsed = authMechanism
+}
 
-	running.Version, err = semver.ParseTolerant(static.Version)
-	if err != nil {
-		fmt.Println("\t[!] Version error: please ensure that you cloned the git repo and are using make to build.")
-		fmt.Println("\t[!] See the following resources for further information:")
-		fmt.Println("\t[>] https://github.com/activecm/rita/blob/master/Contributing.md#common-issues")
-		fmt.Println("\t[>] https://github.com/activecm/rita/blob/master/docs/Manual%20Installation.md")
+// UseConnection points the MongoDB configuration at the cluster registered
+// under alias in the MongoDB Connections section. The connection string,
+// authentication mechanism, and TLS settings of the alias replace the
+// defaults so every component dials the selected cluster. An empty alias
+// keeps the default connection.
+func (c *Config) UseConnection(alias string) error {
+	if alias == "" {
+		return nil
 	}
-	return err
+
+	conn, ok := c.S.MongoDB.Connections[alias]
+	if !ok {
+		return fmt.Errorf("unknown MongoDB connection alias %q", alias)
+	}
+
+	c.S.MongoDB.ConnectionString = conn.ConnectionString
+	c.S.MongoDB.AuthMechanism = conn.AuthMechanism
+	c.S.MongoDB.TLS = conn.TLS
+	initMongoDBRunningConfig(&c.S.MongoDB, &c.R.MongoDB)
+	return nil
 }
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Snapshot records the analysis settings used to produce the results of a
+// dataset so old results can be traced back to the thresholds and weights
+// which scored them. The filter and blacklist lists can be long, so only a
+// hash of each section is kept to tell whether they have since changed.
+type Snapshot struct {
+	Version        string               `bson:"version" yaml:"Version"`
+	Beacon         BeaconStaticCfg      `bson:"beacon" yaml:"Beacon"`
+	BeaconProxy    BeaconProxyStaticCfg `bson:"beacon_proxy" yaml:"BeaconProxy"`
+	BeaconSNI      BeaconSNIStaticCfg   `bson:"beacon_sni" yaml:"BeaconSNI"`
+	Strobe         StrobeStaticCfg      `bson:"strobe" yaml:"Strobe"`
+	FilteringHash  string               `bson:"filtering_hash" yaml:"FilteringHash"`
+	BlacklistsHash string               `bson:"blacklists_hash" yaml:"BlacklistsHash"`
+}
+
+// NewSnapshot captures the analysis settings of the given config
+func NewSnapshot(config *StaticCfg) Snapshot {
+	// where the blacklisted indicators are held doesn't change the results
+	blacklisted := config.Blacklisted
+	blacklisted.MaxInMemoryIndicators = 0
+
+	return Snapshot{
+		Version:        config.Version,
+		Beacon:         config.Beacon,
+		BeaconProxy:    config.BeaconProxy,
+		BeaconSNI:      config.BeaconSNI,
+		Strobe:         config.Strobe,
+		FilteringHash:  hashSection(config.Filtering),
+		BlacklistsHash: hashSection(blacklisted),
+	}
+}
+
+// hashSection returns the hex encoded SHA-256 hash of the YAML encoding of a
+// config section
+func hashSection(section interface{}) string {
+	// the config sections only hold YAML encodable fields
+	encoded, _ := yaml.Marshal(section)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
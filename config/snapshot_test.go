@@ -0,0 +1,59 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNewSnapshot(t *testing.T) {
+	config := &StaticCfg{}
+	require.Nil(t, parseStaticConfig([]byte(staticConfigParserTestConfig), config))
+	config.Version = "v4.7.0"
+
+	snapshot := NewSnapshot(config)
+	assert.Equal(t, "v4.7.0", snapshot.Version)
+	assert.Equal(t, config.Beacon, snapshot.Beacon)
+	assert.Equal(t, config.BeaconProxy, snapshot.BeaconProxy)
+	assert.Equal(t, config.BeaconSNI, snapshot.BeaconSNI)
+	assert.Equal(t, config.Strobe, snapshot.Strobe)
+	assert.Len(t, snapshot.FilteringHash, 64)
+	assert.Len(t, snapshot.BlacklistsHash, 64)
+
+	// the snapshot is read back from the MetaDB exactly as it was stored
+	encoded, err := bson.Marshal(snapshot)
+	require.Nil(t, err)
+	var decoded Snapshot
+	require.Nil(t, bson.Unmarshal(encoded, &decoded))
+	assert.Equal(t, snapshot, decoded)
+
+	// and printed with the same keys as the config file
+	printed, err := yaml.Marshal(snapshot)
+	require.Nil(t, err)
+	var reparsed Snapshot
+	require.Nil(t, yaml.Unmarshal(printed, &reparsed))
+	assert.Equal(t, snapshot, reparsed)
+}
+
+func TestSnapshotHashes(t *testing.T) {
+	config := &StaticCfg{}
+	require.Nil(t, parseStaticConfig([]byte(staticConfigParserTestConfig), config))
+	snapshot := NewSnapshot(config)
+
+	// the hashes are stable for the same lists
+	assert.Equal(t, snapshot, NewSnapshot(config))
+
+	config.Filtering.NeverInclude = append(config.Filtering.NeverInclude, "192.0.2.0/24")
+	changed := NewSnapshot(config)
+	assert.NotEqual(t, snapshot.FilteringHash, changed.FilteringHash)
+	assert.Equal(t, snapshot.BlacklistsHash, changed.BlacklistsHash)
+
+	config.Blacklisted.MaxInMemoryIndicators = 1000
+	assert.Equal(t, snapshot.BlacklistsHash, NewSnapshot(config).BlacklistsHash)
+
+	config.Blacklisted.IPBlacklists = append(config.Blacklisted.IPBlacklists, "/etc/rita/ips.txt")
+	assert.NotEqual(t, snapshot.BlacklistsHash, NewSnapshot(config).BlacklistsHash)
+}
@@ -1,12 +1,18 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
 	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/creasty/defaults"
 	yaml "gopkg.in/yaml.v2"
 )
 
@@ -22,6 +28,9 @@ import (
 // within ~16777216 bytes
 const maxStrobeConnectionLimit int = 86400
 
+// attackTechniqueRegex matches MITRE ATT&CK technique and sub-technique IDs
+var attackTechniqueRegex = regexp.MustCompile(`^T[0-9]{4}(\.[0-9]{3})?$`)
+
 type (
 	//StaticCfg is the container for other static config sections
 	StaticCfg struct {
@@ -38,17 +47,49 @@ type (
 		Bro          BroStaticCfg         `yaml:"Bro"` // kept in for MetaDB backwards compatibility
 		Filtering    FilteringStaticCfg   `yaml:"Filtering"`
 		Strobe       StrobeStaticCfg      `yaml:"Strobe"`
+		Redaction    RedactionStaticCfg   `yaml:"Redaction"`
+		Analysis     AnalysisStaticCfg    `yaml:"Analysis"`
+		Cloud        CloudStaticCfg       `yaml:"CloudProviders"`
+		Sinks        []SinkStaticCfg      `yaml:"Sinks"`
+		Provenance   ProvenanceStaticCfg  `yaml:"Provenance"`
+		HostRoles    HostRolesStaticCfg   `yaml:"HostRoles"`
+		Attack       AttackStaticCfg      `yaml:"MitreAttack"`
 		Version      string
 		ExactVersion string
 	}
 
 	//MongoDBStaticCfg contains the means for connecting to MongoDB
 	MongoDBStaticCfg struct {
-		ConnectionString string        `yaml:"ConnectionString" default:"mongodb://localhost:27017"`
-		AuthMechanism    string        `yaml:"AuthenticationMechanism" default:""`
-		SocketTimeout    time.Duration `yaml:"SocketTimeout" default:"2"`
-		TLS              TLSStaticCfg  `yaml:"TLS"`
-		MetaDB           string        `yaml:"MetaDB" default:"MetaDatabase"`
+		ConnectionString string                                `yaml:"ConnectionString" default:"mongodb://localhost:27017"`
+		AuthMechanism    string                                `yaml:"AuthenticationMechanism" default:""`
+		SocketTimeout    time.Duration                         `yaml:"SocketTimeout" default:"2"`
+		TLS              TLSStaticCfg                          `yaml:"TLS"`
+		MetaDB           string                                `yaml:"MetaDB" default:"MetaDatabase"`
+		ReadOnly         bool                                  `yaml:"ReadOnly" default:"false"`
+		Connections      map[string]MongoDBConnectionStaticCfg `yaml:"Connections"`
+		Sharding         ShardingStaticCfg                     `yaml:"Sharding"`
+	}
+
+	//ShardingStaticCfg controls how collections are sharded when RITA is
+	//connected to a sharded cluster
+	ShardingStaticCfg struct {
+		Enabled   bool                         `yaml:"Enabled" default:"false"`
+		ShardKeys map[string]ShardKeyStaticCfg `yaml:"ShardKeys"`
+	}
+
+	//ShardKeyStaticCfg contains the shard key of a collection. A hashed
+	//shard key must have exactly one field.
+	ShardKeyStaticCfg struct {
+		Fields []string `yaml:"Fields"`
+		Hashed bool     `yaml:"Hashed" default:"false"`
+	}
+
+	//MongoDBConnectionStaticCfg contains the means for connecting to an additional
+	//MongoDB cluster which is selected by its alias
+	MongoDBConnectionStaticCfg struct {
+		ConnectionString string       `yaml:"ConnectionString"`
+		AuthMechanism    string       `yaml:"AuthenticationMechanism"`
+		TLS              TLSStaticCfg `yaml:"TLS"`
 	}
 
 	//TLSStaticCfg contains the means for connecting to MongoDB over TLS
@@ -60,10 +101,18 @@ type (
 
 	//LogStaticCfg contains the configuration for logging
 	LogStaticCfg struct {
-		LogLevel    int    `yaml:"LogLevel" default:"2"`
-		RitaLogPath string `yaml:"RitaLogPath" default:"/var/lib/rita/logs"`
-		LogToFile   bool   `yaml:"LogToFile" default:"true"`
-		LogToDB     bool   `yaml:"LogToDB" default:"true"`
+		LogLevel    int                `yaml:"LogLevel" default:"2"`
+		LogLevels   LogLevelsStaticCfg `yaml:"LogLevels"`
+		RitaLogPath string             `yaml:"RitaLogPath" default:"/var/lib/rita/logs"`
+		LogToFile   bool               `yaml:"LogToFile" default:"true"`
+		LogToDB     bool               `yaml:"LogToDB" default:"true"`
+	}
+
+	//LogLevelsStaticCfg overrides the LogLevel of each stage of an import.
+	//A level of -1 uses LogLevel.
+	LogLevelsStaticCfg struct {
+		Import   int `yaml:"Import" default:"-1"`
+		Analysis int `yaml:"Analysis" default:"-1"`
 	}
 
 	//BroStaticCfg controls the file parser
@@ -73,10 +122,13 @@ type (
 
 	//RollingStaticCfg controls the rolling database settings
 	RollingStaticCfg struct {
-		DefaultChunks int `yaml:"DefaultChunks" default:"24"`
-		Rolling       bool
-		CurrentChunk  int
-		TotalChunks   int
+		DefaultChunks          int           `yaml:"DefaultChunks" default:"24"`
+		MaxChunks              int           `yaml:"MaxChunks" default:"0"`
+		ChunkDuration          time.Duration `yaml:"ChunkDuration" default:"0"`
+		MergeOverlappingChunks bool          `yaml:"MergeOverlappingChunks" default:"false"`
+		Rolling                bool
+		CurrentChunk           int
+		TotalChunks            int
 	}
 
 	//UserCfgStaticCfg contains
@@ -84,13 +136,18 @@ type (
 		UpdateCheckFrequency int `yaml:"UpdateCheckFrequency" default:"14"`
 	}
 
-	//BlacklistedStaticCfg is used to control the blacklisted analysis module
+	//BlacklistedStaticCfg is used to control the blacklisted analysis module.
+	//FeedWeights maps the name of a blacklist feed to how much it is trusted,
+	//from 0 to 1. Feeds without a weight are trusted DefaultFeedWeight.
 	BlacklistedStaticCfg struct {
-		Enabled            bool     `yaml:"Enabled" default:"true"`
-		UseFeodo           bool     `yaml:"feodotracker.abuse.ch" default:"true"`
-		BlacklistDatabase  string   `yaml:"BlacklistDatabase" default:"rita-bl"`
-		IPBlacklists       []string `yaml:"CustomIPBlacklists" default:"[]"`
-		HostnameBlacklists []string `yaml:"CustomHostnameBlacklists" default:"[]"`
+		Enabled               bool               `yaml:"Enabled" default:"true"`
+		UseFeodo              bool               `yaml:"feodotracker.abuse.ch" default:"true"`
+		BlacklistDatabase     string             `yaml:"BlacklistDatabase" default:"rita-bl"`
+		IPBlacklists          []string           `yaml:"CustomIPBlacklists" default:"[]"`
+		HostnameBlacklists    []string           `yaml:"CustomHostnameBlacklists" default:"[]"`
+		MaxInMemoryIndicators int                `yaml:"MaxInMemoryIndicators" default:"0"`
+		DefaultFeedWeight     float64            `yaml:"DefaultFeedWeight" default:"1"`
+		FeedWeights           map[string]float64 `yaml:"FeedWeights"`
 	}
 
 	//BeaconStaticCfg is used to control the beaconing analysis module
@@ -101,12 +158,107 @@ type (
 		DsWeight                float64 `yaml:"DatasizeScoreWeight" default:"0.25"`
 		DurWeight               float64 `yaml:"DurationScoreWeight" default:"0.25"`
 		HistWeight              float64 `yaml:"HistogramScoreWeight" default:"0.25"`
+		DsSeries                string  `yaml:"DatasizeSeries" default:"orig"`
+		JitterPercent           float64 `yaml:"JitterPercent" default:"0"`
+		MaxIntervalBuckets      int     `yaml:"MaxIntervalBuckets" default:"0"`
+		IntervalBucketScale     string  `yaml:"IntervalBucketScale" default:"log"`
+		MaxStoredIntervals      int     `yaml:"MaxStoredIntervals" default:"0"`
+		MaxExactIntervals       int     `yaml:"MaxExactIntervals" default:"0"`
+		ConnCountMode           string  `yaml:"ConnectionCountNormalization" default:"hourly"`
+		SmallPayloadBytes       int64   `yaml:"SmallPayloadBytes" default:"65535"`
+		UIDSampleSize           int     `yaml:"UIDSampleSize" default:"20"`
+		ConnDurEnabled          bool    `yaml:"ConnectionDurationScoring" default:"false"`
+		ConnDurWeight           float64 `yaml:"ConnectionDurationScoreWeight" default:"0.2"`
+		RespTsEnabled           bool    `yaml:"ResponseTimestampScoring" default:"false"`
+		RespTsWeight            float64 `yaml:"ResponseTimestampScoreWeight" default:"0.25"`
+		DsPeriodicityEnabled    bool    `yaml:"DatasizePeriodicityScoring" default:"false"`
+		DsPeriodicityWeight     float64 `yaml:"DatasizePeriodicityScoreWeight" default:"0.2"`
+		TrendEnabled            bool    `yaml:"IntervalTrendScoring" default:"false"`
+		TrendWeight             float64 `yaml:"IntervalTrendScoreWeight" default:"0.1"`
+		RetransEnabled          bool    `yaml:"RetransmissionDetection" default:"false"`
+		RetransRatio            float64 `yaml:"RetransmissionRatio" default:"0.2"`
+		RetransPenalty          float64 `yaml:"RetransmissionPenalty" default:"0"`
+		MinObservedPeriods      float64 `yaml:"MinObservedPeriods" default:"0"`
+		ShortObsPenalty         float64 `yaml:"ShortObservationPenalty" default:"0.5"`
+		ScoreHistory            bool    `yaml:"ScoreHistory" default:"false"`
+		HashPairKeys            bool    `yaml:"HashPairKeys" default:"false"`
+		MinIntervalSamples      int     `yaml:"MinIntervalSamples" default:"10"`
+		BlacklistMinScore       float64 `yaml:"BlacklistMinScore" default:"0.8"`
+
+		// limits the analysis to connections which used one of these services (empty analyzes every connection)
+		IncludedServices []string `yaml:"IncludedServices" default:"[]"`
+
+		// scores beacons in an external process alongside the built-in scores
+		ExternalScorer ExternalScorerStaticCfg `yaml:"ExternalScorer"`
+
+		// labels beacons which look like benign application heartbeats
+		Heartbeat HeartbeatStaticCfg `yaml:"Heartbeat"`
+
+		// lowers the score of beacons to services which are periodic by design
+		KnownPeriodic KnownPeriodicStaticCfg `yaml:"KnownPeriodic"`
+	}
+
+	//HeartbeatStaticCfg controls which high scoring beacons are labeled as
+	//likely heartbeats. KnownServices entries are "port:protocol" or
+	//"port:protocol:service" and KnownDestinations entries are CIDR ranges or
+	//IP addresses.
+	HeartbeatStaticCfg struct {
+		Enabled           bool     `yaml:"Enabled" default:"true"`
+		MinScore          float64  `yaml:"MinScore" default:"0.8"`
+		MaxAvgBytes       int64    `yaml:"MaxAverageBytes" default:"512"`
+		MinDatasizeScore  float64  `yaml:"MinDatasizeScore" default:"0.9"`
+		KnownServices     []string `yaml:"KnownServices" default:"[\"123:udp\", \"137:udp\", \"161:udp\", \"1900:udp\", \"5353:udp\"]"`
+		KnownDestinations []string `yaml:"KnownDestinations" default:"[]"`
+	}
+
+	//KnownPeriodicStaticCfg lowers the score of beacons to services which are
+	//periodic by design, such as NTP or DNS to internal servers. A beacon is
+	//known periodic if it goes to one of the Destinations of an entry and
+	//only uses the Service of that entry.
+	KnownPeriodicStaticCfg struct {
+		Penalty  float64                `yaml:"Penalty" default:"0.5"`
+		Services []KnownPeriodicService `yaml:"Services"`
+	}
+
+	//KnownPeriodicService is a "port:protocol" or "port:protocol:service"
+	//tuple and the CIDR ranges or IP addresses of the servers providing it
+	KnownPeriodicService struct {
+		Service      string   `yaml:"Service"`
+		Destinations []string `yaml:"Destinations"`
+	}
+
+	//ExternalScorerStaticCfg configures an external process which receives
+	//the timing and data size series of each beacon and returns a score
+	ExternalScorerStaticCfg struct {
+		Enabled bool          `yaml:"Enabled" default:"false"`
+		Command []string      `yaml:"Command"`
+		Weight  float64       `yaml:"ScoreWeight" default:"0.2"`
+		Timeout time.Duration `yaml:"Timeout" default:"10"`
 	}
 
 	//BeaconProxyStaticCfg is used to control the proxy beaconing analysis module
 	BeaconProxyStaticCfg struct {
-		Enabled                 bool `yaml:"Enabled" default:"true"`
-		DefaultConnectionThresh int  `yaml:"DefaultConnectionThresh" default:"20"`
+		Enabled                 bool     `yaml:"Enabled" default:"true"`
+		DefaultConnectionThresh int      `yaml:"DefaultConnectionThresh" default:"20"`
+		TsFreqEnabled           bool     `yaml:"TimestampFrequencyScoring" default:"false"`
+		MinConnBytes            int64    `yaml:"MinConnBytes" default:"0"`
+		CollapseForwardedLegs   bool     `yaml:"CollapseForwardedLegs" default:"false"`
+		ForwardedLegWindow      int64    `yaml:"ForwardedLegWindow" default:"1"`
+		ForwardingProxies       []string `yaml:"ForwardingProxies" default:"[]"`
+		// ConnectionThreshold is checked after DefaultConnectionThresh, against the same
+		// connection count, and only has an effect above DefaultConnectionThresh + 1
+		ConnectionThreshold int64 `yaml:"ConnectionThreshold" default:"0"`
+		IntervalResolution  int64 `yaml:"IntervalResolution" default:"1"`
+
+		// weights the timestamp subscores which are averaged into ts.score
+		Scoring BeaconProxyScoringStaticCfg `yaml:"Scoring"`
+	}
+
+	//BeaconProxyScoringStaticCfg weights the timestamp subscores of proxy beacons
+	BeaconProxyScoringStaticCfg struct {
+		SkewWeight      float64 `yaml:"SkewWeight" default:"1.0"`
+		MadmWeight      float64 `yaml:"MadmWeight" default:"1.0"`
+		ConnCountWeight float64 `yaml:"ConnCountWeight" default:"1.0"`
 	}
 
 	//BeaconSNIStaticCfg is used to control the SNI beaconing analysis module
@@ -117,6 +269,7 @@ type (
 		DsWeight                float64 `yaml:"DatasizeScoreWeight" default:"0.25"`
 		DurWeight               float64 `yaml:"DurationScoreWeight" default:"0.25"`
 		HistWeight              float64 `yaml:"HistogramScoreWeight" default:"0.25"`
+		MaxFQDNsPerHost         int     `yaml:"MaxFQDNsPerHost" default:"0"`
 	}
 
 	//DNSStaticCfg is used to control the DNS analysis module
@@ -133,18 +286,82 @@ type (
 	FilteringStaticCfg struct {
 		AlwaysInclude            []string `yaml:"AlwaysInclude" default:"[]"`
 		NeverInclude             []string `yaml:"NeverInclude" default:"[\"0.0.0.0/32\", \"127.0.0.0/8\", \"169.254.0.0/16\", \"224.0.0.0/4\", \"255.255.255.255/32\", \"::1/128\", \"fe80::/10\", \"ff00::/8\"]"`
+		NeverIncludedSources     []string `yaml:"NeverIncludedSources" default:"[]"`
 		InternalSubnets          []string `yaml:"InternalSubnets" default:"[\"10.0.0.0/8\", \"172.16.0.0/12\", \"192.168.0.0/16\"]"`
 		AlwaysIncludeDomain      []string `yaml:"AlwaysIncludeDomain" default:"[]"`
 		NeverIncludeDomain       []string `yaml:"NeverIncludeDomain" default:"[]"`
 		FilterExternalToInternal bool     `yaml:"FilterExternalToInternal" default:"true"`
+		FilterLocalAddresses     bool     `yaml:"FilterLoopbackAndLinkLocal" default:"true"`
 	}
 
 	//StrobeStaticCfg controls the maximum number of connections between any two given hosts
 	StrobeStaticCfg struct {
 		ConnectionLimit int `yaml:"ConnectionLimit" default:"86400"`
 	}
+
+	//AnalysisStaticCfg controls the resources used by the analysis modules
+	AnalysisStaticCfg struct {
+		MaxThreads int `yaml:"MaxThreads" default:"0"`
+	}
+
+	//CloudStaticCfg controls the annotation of beacon destinations in cloud provider ranges
+	CloudStaticCfg struct {
+		Enabled    bool   `yaml:"Enabled" default:"true"`
+		RangesFile string `yaml:"RangesFile" default:""`
+	}
+
+	//ProvenanceStaticCfg controls recording which log files the connections were imported from
+	ProvenanceStaticCfg struct {
+		Enabled     bool `yaml:"Enabled" default:"false"`
+		LineNumbers bool `yaml:"LineNumbers" default:"false"`
+	}
+
+	//HostRolesStaticCfg sets the thresholds list-hosts uses to classify internal hosts
+	HostRolesStaticCfg struct {
+		ServerMinConnections   int64   `yaml:"ServerMinConnections" default:"1000"`
+		ClientMinConnections   int64   `yaml:"ClientMinConnections" default:"1000"`
+		ScannerMinDestinations int     `yaml:"ScannerMinDestinations" default:"250"`
+		BeaconMinScore         float64 `yaml:"BeaconMinScore" default:"0.8"`
+	}
+
+	//AttackStaticCfg sets the MITRE ATT&CK technique each type of exported finding
+	//is tagged with. An empty technique leaves the findings of that type untagged.
+	AttackStaticCfg struct {
+		Beacon      string `yaml:"Beacon" default:"T1071"`
+		ProxyBeacon string `yaml:"ProxyBeacon" default:"T1071.001"`
+		Blacklisted string `yaml:"BlackListed" default:"T1071"`
+	}
+
+	//SinkStaticCfg configures an additional destination for the analysis results.
+	//Path is used by file sinks, URL by elasticsearch and kafka sinks, Index by
+	//elasticsearch sinks, and Topic by kafka sinks.
+	SinkStaticCfg struct {
+		Type  string `yaml:"Type"`
+		Path  string `yaml:"Path"`
+		URL   string `yaml:"URL"`
+		Index string `yaml:"Index"`
+		Topic string `yaml:"Topic"`
+	}
+
+	//RedactionStaticCfg controls the masking of addresses in the output of the show commands
+	RedactionStaticCfg struct {
+		Enabled      bool     `yaml:"Enabled" default:"false"`
+		Fields       []string `yaml:"Fields" default:"[\"src\"]"`
+		Method       string   `yaml:"Method" default:"hash"`
+		Key          string   `yaml:"Key" default:""`
+		InternalOnly bool     `yaml:"InternalOnly" default:"true"`
+	}
 )
 
+// ValidateKey returns an error if addresses would be hashed without a Key. An
+// unkeyed hash can be reversed by hashing every address.
+func (r RedactionStaticCfg) ValidateKey() error {
+	if r.Method == "hash" && r.Key == "" {
+		return fmt.Errorf("invalid Redaction Key: must be set when Method is hash")
+	}
+	return nil
+}
+
 // readStaticConfigFile attempts to read the contents of the
 // given cfgPath file path (e.g. /etc/rita/config.yaml)
 func readStaticConfigFile(cfgPath string) ([]byte, error) {
@@ -189,8 +406,22 @@ func parseStaticConfig(cfgFile []byte, config *StaticCfg) error {
 	// set the socket time out in hours
 	config.MongoDB.SocketTimeout *= time.Hour
 
+	// set the rolling chunk duration in minutes
+	config.Rolling.ChunkDuration *= time.Minute
+
+	// set the external scorer timeout in seconds
+	config.Beacon.ExternalScorer.Timeout *= time.Second
+
 	// clean all filepaths
 	config.Log.RitaLogPath = filepath.Clean(config.Log.RitaLogPath)
+	if config.Cloud.RangesFile != "" {
+		config.Cloud.RangesFile = filepath.Clean(config.Cloud.RangesFile)
+	}
+	for i := range config.Sinks {
+		if config.Sinks[i].Path != "" {
+			config.Sinks[i].Path = filepath.Clean(config.Sinks[i].Path)
+		}
+	}
 
 	// grab the version constants set by the build process
 	config.Version = Version
@@ -198,3 +429,397 @@ func parseStaticConfig(cfgFile []byte, config *StaticCfg) error {
 
 	return nil
 }
+
+// validateStaticConfig ensures the values in a fully loaded static config
+// (defaults + config file) are ones RITA knows how to use
+func validateStaticConfig(config *StaticCfg) error {
+	// ensure the beacon data size series is one we know how to score
+	switch config.Beacon.DsSeries {
+	case "orig", "resp", "sum":
+	default:
+		return fmt.Errorf("invalid Beacon DatasizeSeries %q: must be one of orig, resp, or sum", config.Beacon.DsSeries)
+	}
+
+	if config.Beacon.SmallPayloadBytes < 1 {
+		return fmt.Errorf("invalid Beacon SmallPayloadBytes %d: must be at least 1", config.Beacon.SmallPayloadBytes)
+	}
+
+	if config.Blacklisted.MaxInMemoryIndicators < 0 {
+		return fmt.Errorf("invalid BlackListed MaxInMemoryIndicators %d: must not be negative", config.Blacklisted.MaxInMemoryIndicators)
+	}
+
+	if config.Blacklisted.DefaultFeedWeight < 0 || config.Blacklisted.DefaultFeedWeight > 1 {
+		return fmt.Errorf("invalid BlackListed DefaultFeedWeight %v: must be between 0 and 1", config.Blacklisted.DefaultFeedWeight)
+	}
+
+	for feed, weight := range config.Blacklisted.FeedWeights {
+		if weight < 0 || weight > 1 {
+			return fmt.Errorf("invalid BlackListed FeedWeights weight %v for %q: must be between 0 and 1", weight, feed)
+		}
+	}
+
+	if config.Beacon.DefaultConnectionThresh < 0 {
+		return fmt.Errorf("invalid Beacon DefaultConnectionThresh %d: must not be negative", config.Beacon.DefaultConnectionThresh)
+	}
+
+	if config.BeaconProxy.DefaultConnectionThresh < 0 {
+		return fmt.Errorf("invalid BeaconProxy DefaultConnectionThresh %d: must not be negative", config.BeaconProxy.DefaultConnectionThresh)
+	}
+
+	if config.BeaconProxy.MinConnBytes < 0 {
+		return fmt.Errorf("invalid BeaconProxy MinConnBytes %d: must not be negative", config.BeaconProxy.MinConnBytes)
+	}
+
+	if config.BeaconProxy.ForwardedLegWindow < 0 {
+		return fmt.Errorf("invalid BeaconProxy ForwardedLegWindow %d: must not be negative", config.BeaconProxy.ForwardedLegWindow)
+	}
+
+	// X-Forwarded-For is only trusted from the proxies which forward requests
+	if config.BeaconProxy.CollapseForwardedLegs && len(config.BeaconProxy.ForwardingProxies) == 0 {
+		return fmt.Errorf("invalid BeaconProxy ForwardingProxies: must be set when CollapseForwardedLegs is enabled")
+	}
+
+	if err := validateSubnets(config.BeaconProxy.ForwardingProxies); err != nil {
+		return fmt.Errorf("invalid BeaconProxy ForwardingProxies entry: %w", err)
+	}
+
+	if config.BeaconProxy.ConnectionThreshold < 0 {
+		return fmt.Errorf("invalid BeaconProxy ConnectionThreshold %d: must not be negative", config.BeaconProxy.ConnectionThreshold)
+	}
+
+	if config.BeaconProxy.IntervalResolution < 1 {
+		return fmt.Errorf("invalid BeaconProxy IntervalResolution %d: must be positive", config.BeaconProxy.IntervalResolution)
+	}
+
+	proxyWeights := config.BeaconProxy.Scoring
+	if proxyWeights.SkewWeight < 0 || proxyWeights.MadmWeight < 0 || proxyWeights.ConnCountWeight < 0 {
+		return fmt.Errorf("invalid BeaconProxy Scoring weights %v, %v, %v: must not be negative",
+			proxyWeights.SkewWeight, proxyWeights.MadmWeight, proxyWeights.ConnCountWeight)
+	}
+
+	if proxyWeights.SkewWeight+proxyWeights.MadmWeight+proxyWeights.ConnCountWeight == 0 {
+		return fmt.Errorf("invalid BeaconProxy Scoring weights: at least one weight must be positive")
+	}
+
+	if config.BeaconSNI.DefaultConnectionThresh < 0 {
+		return fmt.Errorf("invalid BeaconSNI DefaultConnectionThresh %d: must not be negative", config.BeaconSNI.DefaultConnectionThresh)
+	}
+
+	if config.Beacon.JitterPercent < 0 || config.Beacon.JitterPercent >= 100 {
+		return fmt.Errorf("invalid Beacon JitterPercent %v: must be at least 0 and less than 100", config.Beacon.JitterPercent)
+	}
+
+	if config.Beacon.MaxIntervalBuckets < 0 {
+		return fmt.Errorf("invalid Beacon MaxIntervalBuckets %d: must not be negative", config.Beacon.MaxIntervalBuckets)
+	}
+
+	switch config.Beacon.IntervalBucketScale {
+	case "log", "linear":
+	default:
+		return fmt.Errorf("invalid Beacon IntervalBucketScale %q: must be one of log or linear", config.Beacon.IntervalBucketScale)
+	}
+
+	if config.Beacon.MaxStoredIntervals < 0 {
+		return fmt.Errorf("invalid Beacon MaxStoredIntervals %d: must not be negative", config.Beacon.MaxStoredIntervals)
+	}
+
+	if config.Beacon.MaxExactIntervals < 0 {
+		return fmt.Errorf("invalid Beacon MaxExactIntervals %d: must not be negative", config.Beacon.MaxExactIntervals)
+	}
+
+	switch config.Beacon.ConnCountMode {
+	case "hourly", "median":
+	default:
+		return fmt.Errorf("invalid Beacon ConnectionCountNormalization %q: must be one of hourly or median", config.Beacon.ConnCountMode)
+	}
+
+	beaconWeights := config.Beacon
+	if beaconWeights.TsWeight < 0 || beaconWeights.DsWeight < 0 || beaconWeights.DurWeight < 0 || beaconWeights.HistWeight < 0 {
+		return fmt.Errorf("invalid Beacon score weights %v, %v, %v, %v: TimestampScoreWeight, DatasizeScoreWeight, DurationScoreWeight, and HistogramScoreWeight must not be negative",
+			beaconWeights.TsWeight, beaconWeights.DsWeight, beaconWeights.DurWeight, beaconWeights.HistWeight)
+	}
+
+	if beaconWeights.TsWeight+beaconWeights.DsWeight+beaconWeights.DurWeight+beaconWeights.HistWeight == 0 {
+		return fmt.Errorf("invalid Beacon score weights: TimestampScoreWeight, DatasizeScoreWeight, DurationScoreWeight, and HistogramScoreWeight must not all be 0")
+	}
+
+	if config.Beacon.ConnDurWeight < 0 {
+		return fmt.Errorf("invalid Beacon ConnectionDurationScoreWeight %v: must not be negative", config.Beacon.ConnDurWeight)
+	}
+
+	if config.Beacon.RespTsWeight < 0 {
+		return fmt.Errorf("invalid Beacon ResponseTimestampScoreWeight %v: must not be negative", config.Beacon.RespTsWeight)
+	}
+
+	if config.Beacon.DsPeriodicityWeight < 0 {
+		return fmt.Errorf("invalid Beacon DatasizePeriodicityScoreWeight %v: must not be negative", config.Beacon.DsPeriodicityWeight)
+	}
+
+	if config.Beacon.TrendWeight < 0 {
+		return fmt.Errorf("invalid Beacon IntervalTrendScoreWeight %v: must not be negative", config.Beacon.TrendWeight)
+	}
+
+	if config.Beacon.RetransRatio <= 0 || config.Beacon.RetransRatio > 1 {
+		return fmt.Errorf("invalid Beacon RetransmissionRatio %v: must be greater than 0 and at most 1", config.Beacon.RetransRatio)
+	}
+
+	if config.Beacon.RetransPenalty < 0 || config.Beacon.RetransPenalty > 1 {
+		return fmt.Errorf("invalid Beacon RetransmissionPenalty %v: must be between 0 and 1", config.Beacon.RetransPenalty)
+	}
+
+	if config.Beacon.MinObservedPeriods < 0 {
+		return fmt.Errorf("invalid Beacon MinObservedPeriods %v: must not be negative", config.Beacon.MinObservedPeriods)
+	}
+
+	if config.Beacon.ShortObsPenalty < 0 || config.Beacon.ShortObsPenalty > 1 {
+		return fmt.Errorf("invalid Beacon ShortObservationPenalty %v: must be between 0 and 1", config.Beacon.ShortObsPenalty)
+	}
+
+	if scorer := config.Beacon.ExternalScorer; scorer.Enabled {
+		if len(scorer.Command) == 0 || scorer.Command[0] == "" {
+			return fmt.Errorf("invalid Beacon ExternalScorer: Command must be set when the scorer is enabled")
+		}
+		if scorer.Weight < 0 {
+			return fmt.Errorf("invalid Beacon ExternalScorer ScoreWeight %v: must not be negative", scorer.Weight)
+		}
+		if scorer.Timeout <= 0 {
+			return fmt.Errorf("invalid Beacon ExternalScorer Timeout %v: must be positive", scorer.Timeout)
+		}
+	}
+
+	if config.Beacon.UIDSampleSize < 0 {
+		return fmt.Errorf("invalid Beacon UIDSampleSize %d: must not be negative", config.Beacon.UIDSampleSize)
+	}
+
+	if config.Beacon.MinIntervalSamples < 1 {
+		return fmt.Errorf("invalid Beacon MinIntervalSamples %d: must be at least 1", config.Beacon.MinIntervalSamples)
+	}
+
+	if config.Rolling.MaxChunks < 0 {
+		return fmt.Errorf("invalid Rolling MaxChunks %d: must not be negative", config.Rolling.MaxChunks)
+	}
+
+	if config.Rolling.ChunkDuration < 0 {
+		return fmt.Errorf("invalid Rolling ChunkDuration %d: must not be negative", config.Rolling.ChunkDuration)
+	}
+
+	if config.Beacon.BlacklistMinScore < 0 || config.Beacon.BlacklistMinScore > 1 {
+		return fmt.Errorf("invalid Beacon BlacklistMinScore %v: must be between 0 and 1", config.Beacon.BlacklistMinScore)
+	}
+
+	if config.Analysis.MaxThreads < 0 {
+		return fmt.Errorf("invalid Analysis MaxThreads %d: must not be negative", config.Analysis.MaxThreads)
+	}
+
+	if config.BeaconSNI.MaxFQDNsPerHost < 0 {
+		return fmt.Errorf("invalid BeaconSNI MaxFQDNsPerHost %d: must not be negative", config.BeaconSNI.MaxFQDNsPerHost)
+	}
+
+	if config.HostRoles.ServerMinConnections < 1 {
+		return fmt.Errorf("invalid HostRoles ServerMinConnections %d: must be at least 1", config.HostRoles.ServerMinConnections)
+	}
+
+	if config.HostRoles.ClientMinConnections < 1 {
+		return fmt.Errorf("invalid HostRoles ClientMinConnections %d: must be at least 1", config.HostRoles.ClientMinConnections)
+	}
+
+	if config.HostRoles.ScannerMinDestinations < 1 {
+		return fmt.Errorf("invalid HostRoles ScannerMinDestinations %d: must be at least 1", config.HostRoles.ScannerMinDestinations)
+	}
+
+	if config.HostRoles.BeaconMinScore < 0 || config.HostRoles.BeaconMinScore > 1 {
+		return fmt.Errorf("invalid HostRoles BeaconMinScore %v: must be between 0 and 1", config.HostRoles.BeaconMinScore)
+	}
+
+	if config.Log.LogLevels.Import < -1 || config.Log.LogLevels.Import > 3 {
+		return fmt.Errorf("invalid LogConfig LogLevels Import %d: must be between 0 and 3, or -1 to use LogLevel", config.Log.LogLevels.Import)
+	}
+
+	if config.Log.LogLevels.Analysis < -1 || config.Log.LogLevels.Analysis > 3 {
+		return fmt.Errorf("invalid LogConfig LogLevels Analysis %d: must be between 0 and 3, or -1 to use LogLevel", config.Log.LogLevels.Analysis)
+	}
+
+	attackTechniques := []struct {
+		name      string
+		technique string
+	}{
+		{"Beacon", config.Attack.Beacon},
+		{"ProxyBeacon", config.Attack.ProxyBeacon},
+		{"BlackListed", config.Attack.Blacklisted},
+	}
+	for _, t := range attackTechniques {
+		if t.technique != "" && !attackTechniqueRegex.MatchString(t.technique) {
+			return fmt.Errorf("invalid MitreAttack %s %q: must be a technique ID such as T1071 or T1071.001", t.name, t.technique)
+		}
+	}
+
+	if config.Beacon.Heartbeat.MinScore < 0 || config.Beacon.Heartbeat.MinScore > 1 {
+		return fmt.Errorf("invalid Beacon Heartbeat MinScore %v: must be between 0 and 1", config.Beacon.Heartbeat.MinScore)
+	}
+
+	if config.Beacon.Heartbeat.MaxAvgBytes < 0 {
+		return fmt.Errorf("invalid Beacon Heartbeat MaxAverageBytes %d: must not be negative", config.Beacon.Heartbeat.MaxAvgBytes)
+	}
+
+	if config.Beacon.Heartbeat.MinDatasizeScore < 0 || config.Beacon.Heartbeat.MinDatasizeScore > 1 {
+		return fmt.Errorf("invalid Beacon Heartbeat MinDatasizeScore %v: must be between 0 and 1", config.Beacon.Heartbeat.MinDatasizeScore)
+	}
+
+	for _, service := range config.Beacon.IncludedServices {
+		if err := validateService(service); err != nil {
+			return fmt.Errorf("invalid Beacon IncludedServices entry: %w", err)
+		}
+	}
+
+	for _, service := range config.Beacon.Heartbeat.KnownServices {
+		if err := validateService(service); err != nil {
+			return fmt.Errorf("invalid Beacon Heartbeat KnownServices entry: %w", err)
+		}
+	}
+
+	if err := validateSubnets(config.Beacon.Heartbeat.KnownDestinations); err != nil {
+		return fmt.Errorf("invalid Beacon Heartbeat KnownDestinations entry: %w", err)
+	}
+
+	if config.Beacon.KnownPeriodic.Penalty < 0 || config.Beacon.KnownPeriodic.Penalty > 1 {
+		return fmt.Errorf("invalid Beacon KnownPeriodic Penalty %v: must be between 0 and 1", config.Beacon.KnownPeriodic.Penalty)
+	}
+
+	for _, entry := range config.Beacon.KnownPeriodic.Services {
+		if err := validateService(entry.Service); err != nil {
+			return fmt.Errorf("invalid Beacon KnownPeriodic Services entry: %w", err)
+		}
+		if len(entry.Destinations) == 0 {
+			return fmt.Errorf("invalid Beacon KnownPeriodic Services entry %q: Destinations must be set", entry.Service)
+		}
+		if err := validateSubnets(entry.Destinations); err != nil {
+			return fmt.Errorf("invalid Beacon KnownPeriodic Services entry %q: %w", entry.Service, err)
+		}
+	}
+
+	// ensure every named connection can be dialed
+	for alias, conn := range config.MongoDB.Connections {
+		if alias == "" {
+			return fmt.Errorf("invalid MongoDB Connections entry: the alias must not be empty")
+		}
+		if conn.ConnectionString == "" {
+			return fmt.Errorf("invalid MongoDB Connections entry %q: ConnectionString must be set", alias)
+		}
+	}
+
+	// ensure every shard key can be used to shard its collection. MongoDB
+	// requires each unique index of a sharded collection to begin with the
+	// shard key.
+	var tables TableCfg
+	if err := defaults.Set(&tables); err != nil {
+		return err
+	}
+	shardable := uniqueIndexes(&tables, config.Beacon.HashPairKeys)
+	for collection, key := range config.MongoDB.Sharding.ShardKeys {
+		if len(key.Fields) == 0 {
+			return fmt.Errorf("invalid MongoDB Sharding ShardKeys entry %q: Fields must be set", collection)
+		}
+		if key.Hashed && len(key.Fields) != 1 {
+			return fmt.Errorf("invalid MongoDB Sharding ShardKeys entry %q: a hashed shard key must have exactly one field", collection)
+		}
+		indexes, ok := shardable[collection]
+		if !ok {
+			return fmt.Errorf("invalid MongoDB Sharding ShardKeys entry %q: the collection is not sharded by RITA", collection)
+		}
+		for _, index := range indexes {
+			if !isPrefix(key.Fields, index) {
+				return fmt.Errorf("invalid MongoDB Sharding ShardKeys entry %q: Fields %v must begin the unique index %v", collection, key.Fields, index)
+			}
+		}
+	}
+
+	// ensure the filtering subnets can be parsed
+	subnets := []struct {
+		name    string
+		entries []string
+	}{
+		{"AlwaysInclude", config.Filtering.AlwaysInclude},
+		{"NeverInclude", config.Filtering.NeverInclude},
+		{"NeverIncludedSources", config.Filtering.NeverIncludedSources},
+		{"InternalSubnets", config.Filtering.InternalSubnets},
+	}
+	for _, subnet := range subnets {
+		if err := validateSubnets(subnet.entries); err != nil {
+			return fmt.Errorf("invalid Filtering %s entry: %w", subnet.name, err)
+		}
+	}
+
+	// ensure each result sink has the settings its type needs
+	for i, sink := range config.Sinks {
+		switch sink.Type {
+		case "file":
+			if sink.Path == "" {
+				return fmt.Errorf("invalid Sinks entry %d: file sinks must set a Path", i)
+			}
+		case "elasticsearch":
+			if sink.URL == "" || sink.Index == "" {
+				return fmt.Errorf("invalid Sinks entry %d: elasticsearch sinks must set a URL and an Index", i)
+			}
+		case "kafka":
+			if sink.URL == "" || sink.Topic == "" {
+				return fmt.Errorf("invalid Sinks entry %d: kafka sinks must set a URL and a Topic", i)
+			}
+		default:
+			return fmt.Errorf("invalid Sinks entry %d: unknown Type %q: must be one of file, elasticsearch, or kafka", i, sink.Type)
+		}
+	}
+
+	// ensure the redaction settings are ones the redactor knows how to apply
+	switch config.Redaction.Method {
+	case "hash", "zero":
+	default:
+		return fmt.Errorf("invalid Redaction Method %q: must be one of hash or zero", config.Redaction.Method)
+	}
+
+	for _, field := range config.Redaction.Fields {
+		switch field {
+		case "src", "dst":
+		default:
+			return fmt.Errorf("invalid Redaction Field %q: must be one of src or dst", field)
+		}
+	}
+
+	if config.Redaction.Enabled {
+		if err := config.Redaction.ValidateKey(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSubnets ensures each entry is a CIDR range or a single IP address
+func validateSubnets(entries []string) error {
+	for _, entry := range entries {
+		if _, _, err := net.ParseCIDR(entry); err == nil {
+			continue
+		}
+		if net.ParseIP(entry) == nil {
+			return fmt.Errorf("%q is not a CIDR range or IP address", entry)
+		}
+	}
+	return nil
+}
+
+// validateService ensures the entry is a "port:protocol" or
+// "port:protocol:service" tuple
+func validateService(entry string) error {
+	fields := strings.Split(entry, ":")
+	if len(fields) < 2 || len(fields) > 3 {
+		return fmt.Errorf("%q is not a port:protocol or port:protocol:service tuple", entry)
+	}
+	if port, err := strconv.Atoi(fields[0]); err != nil || port < 0 || port > 65535 {
+		return fmt.Errorf("%q does not start with a port number", entry)
+	}
+	for _, field := range fields[1:] {
+		if field == "" {
+			return fmt.Errorf("%q has an empty protocol or service", entry)
+		}
+	}
+	return nil
+}
@@ -4,7 +4,9 @@ import (
 	"testing"
 	"time"
 
+	"github.com/creasty/defaults"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 const staticConfigParserTestConfig = `
@@ -17,11 +19,24 @@ MongoDB:
         VerifyCertificate: false
         CAFile: aaaaa
     MetaDB: MetaDatabase
+    Sharding:
+        Enabled: true
+        ShardKeys:
+            uconn:
+                Fields: [src, dst]
+            host:
+                Fields: [ip]
+                Hashed: true
 LogConfig:
     LogLevel: 2
     RitaLogPath: /var/lib/rita/logs
     LogToFile: true
     LogToDB: true
+Rolling:
+    DefaultChunks: 24
+    MaxChunks: 12
+    ChunkDuration: 60
+    MergeOverlappingChunks: true
 UserConfig:
     UpdateCheckFrequency: 14
 BlackListed:
@@ -29,6 +44,11 @@ BlackListed:
     BlacklistDatabase: "rita-bl"
     CustomIPBlacklists: [test1]
     CustomHostnameBlacklists: [test2]
+    MaxInMemoryIndicators: 5000000
+    DefaultFeedWeight: 0.8
+    FeedWeights:
+        feodo tracker: 0.9
+        test1: 0.5
 DNS:
     Enabled: true
 Beacon:
@@ -38,6 +58,50 @@ Beacon:
     DatasizeScoreWeight: 0.25
     DurationScoreWeight: 0.25
     HistogramScoreWeight: 0.25
+    DatasizeSeries: sum
+    JitterPercent: 2.5
+    MaxIntervalBuckets: 64
+    IntervalBucketScale: linear
+    MaxStoredIntervals: 16
+    MaxExactIntervals: 500000
+    ConnectionCountNormalization: median
+    SmallPayloadBytes: 1500
+    UIDSampleSize: 10
+    ConnectionDurationScoring: true
+    ConnectionDurationScoreWeight: 0.3
+    ResponseTimestampScoring: true
+    ResponseTimestampScoreWeight: 0.2
+    DatasizePeriodicityScoring: true
+    DatasizePeriodicityScoreWeight: 0.15
+    IntervalTrendScoring: true
+    IntervalTrendScoreWeight: 0.05
+    RetransmissionDetection: true
+    RetransmissionRatio: 0.3
+    RetransmissionPenalty: 0.5
+    MinObservedPeriods: 3
+    ShortObservationPenalty: 0.4
+    ScoreHistory: true
+    HashPairKeys: true
+    MinIntervalSamples: 5
+    BlacklistMinScore: 0.6
+    IncludedServices: ["443:tcp", "53:udp:dns"]
+    ExternalScorer:
+        Enabled: true
+        Command: [/usr/local/bin/score-beacon, --model, v2]
+        ScoreWeight: 0.3
+        Timeout: 5
+    Heartbeat:
+        Enabled: true
+        MinScore: 0.7
+        MaxAverageBytes: 256
+        MinDatasizeScore: 0.95
+        KnownServices: ["123:udp:ntp", "8080:tcp"]
+        KnownDestinations: [192.0.2.0/24]
+    KnownPeriodic:
+        Penalty: 0.75
+        Services:
+          - Service: "123:udp"
+            Destinations: [10.0.0.10, 10.0.1.0/24]
 BeaconSNI:
     Enabled: true
     DefaultConnectionThresh: 20
@@ -45,18 +109,61 @@ BeaconSNI:
     DatasizeScoreWeight: 0.25
     DurationScoreWeight: 0.25
     HistogramScoreWeight: 0.25
+    MaxFQDNsPerHost: 500
 BeaconProxy:
     Enabled: true
     DefaultConnectionThresh: 20
+    TimestampFrequencyScoring: true
+    MinConnBytes: 100
+    CollapseForwardedLegs: true
+    ForwardedLegWindow: 2
+    ForwardingProxies: ["10.0.0.1"]
+    ConnectionThreshold: 10
+    IntervalResolution: 5
+    Scoring:
+        SkewWeight: 1.0
+        MadmWeight: 0.5
+        ConnCountWeight: 0.25
 Strobe:
     ConnectionLimit: 250000
 Filtering:
     AlwaysInclude: ["8.8.8.8/32"]
     NeverInclude: ["8.8.4.4/32"]
+    NeverIncludedSources: ["10.0.0.50/32"]
     InternalSubnets: ["10.0.0.0/8","172.16.0.0/12","192.168.0.0/16"]
     AlwaysIncludeDomain: ["bad.com", "google.com", "*.myotherdomain.com"]
     NeverIncludeDomain: ["good.com", "google.com", "*.mydomain.com"]
     FilterExternalToInternal: true
+    FilterLoopbackAndLinkLocal: true
+Redaction:
+    Enabled: true
+    Fields: ["src", "dst"]
+    Method: zero
+    Key: "secret"
+    InternalOnly: false
+Analysis:
+    MaxThreads: 4
+CloudProviders:
+    Enabled: true
+    RangesFile: /etc/rita/cloud-ranges.txt
+Provenance:
+    Enabled: true
+    LineNumbers: true
+HostRoles:
+    ServerMinConnections: 500
+    ClientMinConnections: 2000
+    ScannerMinDestinations: 100
+    BeaconMinScore: 0.7
+MitreAttack:
+    Beacon: T1071.001
+    ProxyBeacon: T1090
+    BlackListed: ""
+Sinks:
+    - Type: file
+      Path: /var/lib/rita/results.jsonl
+    - Type: elasticsearch
+      URL: http://localhost:9200
+      Index: rita
 `
 
 var testConfigFullExp = StaticCfg{
@@ -70,6 +177,13 @@ var testConfigFullExp = StaticCfg{
 			CAFile:            "aaaaa",
 		},
 		MetaDB: "MetaDatabase",
+		Sharding: ShardingStaticCfg{
+			Enabled: true,
+			ShardKeys: map[string]ShardKeyStaticCfg{
+				"uconn": {Fields: []string{"src", "dst"}},
+				"host":  {Fields: []string{"ip"}, Hashed: true},
+			},
+		},
 	},
 	Log: LogStaticCfg{
 		LogLevel:    2,
@@ -77,14 +191,23 @@ var testConfigFullExp = StaticCfg{
 		LogToFile:   true,
 		LogToDB:     true,
 	},
+	Rolling: RollingStaticCfg{
+		DefaultChunks:          24,
+		MaxChunks:              12,
+		ChunkDuration:          60 * time.Minute,
+		MergeOverlappingChunks: true,
+	},
 	UserConfig: UserCfgStaticCfg{
 		UpdateCheckFrequency: 14,
 	},
 	Blacklisted: BlacklistedStaticCfg{
-		UseFeodo:           true,
-		BlacklistDatabase:  "rita-bl",
-		IPBlacklists:       []string{"test1"},
-		HostnameBlacklists: []string{"test2"},
+		UseFeodo:              true,
+		BlacklistDatabase:     "rita-bl",
+		IPBlacklists:          []string{"test1"},
+		HostnameBlacklists:    []string{"test2"},
+		MaxInMemoryIndicators: 5000000,
+		DefaultFeedWeight:     0.8,
+		FeedWeights:           map[string]float64{"feodo tracker": 0.9, "test1": 0.5},
 	},
 	DNS: DNSStaticCfg{
 		Enabled: true,
@@ -96,6 +219,53 @@ var testConfigFullExp = StaticCfg{
 		DsWeight:                0.25,
 		DurWeight:               0.25,
 		HistWeight:              0.25,
+		DsSeries:                "sum",
+		JitterPercent:           2.5,
+		MaxIntervalBuckets:      64,
+		IntervalBucketScale:     "linear",
+		MaxStoredIntervals:      16,
+		MaxExactIntervals:       500000,
+		ConnCountMode:           "median",
+		SmallPayloadBytes:       1500,
+		UIDSampleSize:           10,
+		ConnDurEnabled:          true,
+		ConnDurWeight:           0.3,
+		RespTsEnabled:           true,
+		RespTsWeight:            0.2,
+		DsPeriodicityEnabled:    true,
+		DsPeriodicityWeight:     0.15,
+		TrendEnabled:            true,
+		TrendWeight:             0.05,
+		RetransEnabled:          true,
+		RetransRatio:            0.3,
+		RetransPenalty:          0.5,
+		MinObservedPeriods:      3,
+		ShortObsPenalty:         0.4,
+		ScoreHistory:            true,
+		HashPairKeys:            true,
+		MinIntervalSamples:      5,
+		BlacklistMinScore:       0.6,
+		IncludedServices:        []string{"443:tcp", "53:udp:dns"},
+		ExternalScorer: ExternalScorerStaticCfg{
+			Enabled: true,
+			Command: []string{"/usr/local/bin/score-beacon", "--model", "v2"},
+			Weight:  0.3,
+			Timeout: 5 * time.Second,
+		},
+		Heartbeat: HeartbeatStaticCfg{
+			Enabled:           true,
+			MinScore:          0.7,
+			MaxAvgBytes:       256,
+			MinDatasizeScore:  0.95,
+			KnownServices:     []string{"123:udp:ntp", "8080:tcp"},
+			KnownDestinations: []string{"192.0.2.0/24"},
+		},
+		KnownPeriodic: KnownPeriodicStaticCfg{
+			Penalty: 0.75,
+			Services: []KnownPeriodicService{
+				{Service: "123:udp", Destinations: []string{"10.0.0.10", "10.0.1.0/24"}},
+			},
+		},
 	},
 	BeaconSNI: BeaconSNIStaticCfg{
 		Enabled:                 true,
@@ -104,10 +274,23 @@ var testConfigFullExp = StaticCfg{
 		DsWeight:                0.25,
 		DurWeight:               0.25,
 		HistWeight:              0.25,
+		MaxFQDNsPerHost:         500,
 	},
 	BeaconProxy: BeaconProxyStaticCfg{
 		Enabled:                 true,
 		DefaultConnectionThresh: 20,
+		TsFreqEnabled:           true,
+		MinConnBytes:            100,
+		CollapseForwardedLegs:   true,
+		ForwardedLegWindow:      2,
+		ForwardingProxies:       []string{"10.0.0.1"},
+		ConnectionThreshold:     10,
+		IntervalResolution:      5,
+		Scoring: BeaconProxyScoringStaticCfg{
+			SkewWeight:      1.0,
+			MadmWeight:      0.5,
+			ConnCountWeight: 0.25,
+		},
 	},
 	Strobe: StrobeStaticCfg{
 		ConnectionLimit: maxStrobeConnectionLimit,
@@ -115,10 +298,44 @@ var testConfigFullExp = StaticCfg{
 	Filtering: FilteringStaticCfg{
 		AlwaysInclude:            []string{"8.8.8.8/32"},
 		NeverInclude:             []string{"8.8.4.4/32"},
+		NeverIncludedSources:     []string{"10.0.0.50/32"},
 		InternalSubnets:          []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
 		AlwaysIncludeDomain:      []string{"bad.com", "google.com", "*.myotherdomain.com"},
 		NeverIncludeDomain:       []string{"good.com", "google.com", "*.mydomain.com"},
 		FilterExternalToInternal: true,
+		FilterLocalAddresses:     true,
+	},
+	Redaction: RedactionStaticCfg{
+		Enabled:      true,
+		Fields:       []string{"src", "dst"},
+		Method:       "zero",
+		Key:          "secret",
+		InternalOnly: false,
+	},
+	Analysis: AnalysisStaticCfg{
+		MaxThreads: 4,
+	},
+	Cloud: CloudStaticCfg{
+		Enabled:    true,
+		RangesFile: "/etc/rita/cloud-ranges.txt",
+	},
+	Provenance: ProvenanceStaticCfg{
+		Enabled:     true,
+		LineNumbers: true,
+	},
+	HostRoles: HostRolesStaticCfg{
+		ServerMinConnections:   500,
+		ClientMinConnections:   2000,
+		ScannerMinDestinations: 100,
+		BeaconMinScore:         0.7,
+	},
+	Attack: AttackStaticCfg{
+		Beacon:      "T1071.001",
+		ProxyBeacon: "T1090",
+	},
+	Sinks: []SinkStaticCfg{
+		{Type: "file", Path: "/var/lib/rita/results.jsonl"},
+		{Type: "elasticsearch", URL: "http://localhost:9200", Index: "rita"},
 	},
 }
 
@@ -142,11 +359,16 @@ func TestFilePathCleaning(t *testing.T) {
 	testConfig := `
 LogConfig:
     RitaLogPath: /var/lib/rita/incorrect/./../logs/
+CloudProviders:
+    RangesFile: /etc/rita/./ranges/../cloud-ranges.txt
 `
 	testConfigExp := StaticCfg{
 		Log: LogStaticCfg{
 			RitaLogPath: "/var/lib/rita/logs",
 		},
+		Cloud: CloudStaticCfg{
+			RangesFile: "/etc/rita/cloud-ranges.txt",
+		},
 	}
 	config := &StaticCfg{}
 	err := parseStaticConfig([]byte(testConfig), config)
@@ -158,3 +380,331 @@ LogConfig:
 	assert.Nil(t, err)
 	assert.Equal(t, *config, testConfigExp)
 }
+
+// TestValidateStaticConfig ensures that invalid config values are rejected
+// once the config file has been loaded on top of the defaults.
+func TestValidateStaticConfig(t *testing.T) {
+	config := &StaticCfg{}
+	require.Nil(t, defaults.Set(config))
+	assert.Nil(t, validateStaticConfig(config), "the default config should be valid")
+
+	for _, series := range []string{"orig", "resp", "sum"} {
+		config.Beacon.DsSeries = series
+		assert.Nil(t, validateStaticConfig(config), "DatasizeSeries %s should be valid", series)
+	}
+
+	config.Beacon.DsSeries = "both"
+	assert.NotNil(t, validateStaticConfig(config), "unknown DatasizeSeries should be rejected")
+	config.Beacon.DsSeries = "orig"
+
+	config.Beacon.JitterPercent = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative JitterPercent should be rejected")
+	config.Beacon.JitterPercent = 100
+	assert.NotNil(t, validateStaticConfig(config), "a JitterPercent of 100 would count every interval together")
+	config.Beacon.JitterPercent = 0
+
+	config.Beacon.SmallPayloadBytes = 0
+	assert.NotNil(t, validateStaticConfig(config), "SmallPayloadBytes below 1 should be rejected")
+	config.Beacon.SmallPayloadBytes = 65535
+
+	config.Beacon.HistWeight = -0.25
+	assert.NotNil(t, validateStaticConfig(config), "negative HistogramScoreWeight should be rejected")
+	config.Beacon.TsWeight, config.Beacon.DsWeight, config.Beacon.DurWeight, config.Beacon.HistWeight = 0, 0, 0, 0
+	assert.NotNil(t, validateStaticConfig(config), "base score weights which sum to 0 should be rejected")
+	config.Beacon.TsWeight, config.Beacon.DsWeight, config.Beacon.DurWeight, config.Beacon.HistWeight = 0.25, 0.25, 0.25, 0.25
+
+	config.Beacon.RespTsWeight = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "negative ResponseTimestampScoreWeight should be rejected")
+	config.Beacon.RespTsWeight = 0.25
+
+	config.Beacon.DsPeriodicityWeight = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "negative DatasizePeriodicityScoreWeight should be rejected")
+	config.Beacon.DsPeriodicityWeight = 0.2
+
+	config.Beacon.TrendWeight = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "negative IntervalTrendScoreWeight should be rejected")
+	config.Beacon.TrendWeight = 0.1
+
+	config.Beacon.RetransRatio = 0
+	assert.NotNil(t, validateStaticConfig(config), "a RetransmissionRatio of 0 would flag every pair")
+	config.Beacon.RetransRatio = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a RetransmissionRatio above 1 can never be reached")
+	config.Beacon.RetransRatio = 0.2
+	config.Beacon.RetransPenalty = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "a negative RetransmissionPenalty should be rejected")
+	config.Beacon.RetransPenalty = 1.1
+	assert.NotNil(t, validateStaticConfig(config), "a RetransmissionPenalty above 1 should be rejected")
+	config.Beacon.RetransPenalty = 1
+	assert.Nil(t, validateStaticConfig(config), "a RetransmissionPenalty of 1 zeroes the score")
+	config.Beacon.RetransPenalty = 0
+
+	config.Beacon.MinObservedPeriods = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative MinObservedPeriods should be rejected")
+	config.Beacon.MinObservedPeriods = 0
+	config.Beacon.ShortObsPenalty = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a ShortObservationPenalty above 1 should be rejected")
+	config.Beacon.ShortObsPenalty = -0.5
+	assert.NotNil(t, validateStaticConfig(config), "a negative ShortObservationPenalty should be rejected")
+	config.Beacon.ShortObsPenalty = 0.5
+
+	config.Beacon.ExternalScorer = ExternalScorerStaticCfg{Enabled: true, Command: []string{"score-beacon"}, Weight: 0.2, Timeout: time.Second}
+	assert.Nil(t, validateStaticConfig(config), "an external scorer with a command should be valid")
+	config.Beacon.ExternalScorer.Command = nil
+	assert.NotNil(t, validateStaticConfig(config), "an enabled external scorer without a command should be rejected")
+	config.Beacon.ExternalScorer.Command = []string{"score-beacon"}
+	config.Beacon.ExternalScorer.Timeout = 0
+	assert.NotNil(t, validateStaticConfig(config), "an external scorer without a timeout should be rejected")
+	config.Beacon.ExternalScorer.Timeout = time.Second
+	config.Beacon.ExternalScorer.Weight = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "a negative external scorer ScoreWeight should be rejected")
+	config.Beacon.ExternalScorer = ExternalScorerStaticCfg{}
+
+	config.Beacon.UIDSampleSize = 0
+	assert.Nil(t, validateStaticConfig(config), "UIDSampleSize of 0 disables storing UIDs")
+	config.Beacon.UIDSampleSize = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative UIDSampleSize should be rejected")
+	config.Beacon.UIDSampleSize = 20
+
+	config.Beacon.MinIntervalSamples = 0
+	assert.NotNil(t, validateStaticConfig(config), "MinIntervalSamples below 1 should be rejected")
+	config.Beacon.MinIntervalSamples = 10
+
+	config.Blacklisted.MaxInMemoryIndicators = 0
+	assert.Nil(t, validateStaticConfig(config), "a MaxInMemoryIndicators of 0 keeps every indicator in memory")
+	config.Blacklisted.MaxInMemoryIndicators = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative MaxInMemoryIndicators should be rejected")
+	config.Blacklisted.MaxInMemoryIndicators = 0
+
+	config.Blacklisted.DefaultFeedWeight = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a DefaultFeedWeight above 1 should be rejected")
+	config.Blacklisted.DefaultFeedWeight = 1
+	config.Blacklisted.FeedWeights = map[string]float64{"feodo tracker": 0, "test1": 1}
+	assert.Nil(t, validateStaticConfig(config), "feed weights from 0 to 1 should be accepted")
+	config.Blacklisted.FeedWeights["test1"] = -0.1
+	assert.NotNil(t, validateStaticConfig(config), "negative feed weights should be rejected")
+	config.Blacklisted.FeedWeights = nil
+
+	config.Beacon.BlacklistMinScore = 0
+	assert.Nil(t, validateStaticConfig(config), "a BlacklistMinScore of 0 checks every beacon")
+	config.Beacon.BlacklistMinScore = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "BlacklistMinScore above 1 should be rejected")
+	config.Beacon.BlacklistMinScore = 0.8
+
+	config.Beacon.IncludedServices = []string{"80:tcp", "443:tcp:ssl"}
+	assert.Nil(t, validateStaticConfig(config), "port:protocol and port:protocol:service IncludedServices should be accepted")
+	config.Beacon.IncludedServices = []string{"https"}
+	assert.NotNil(t, validateStaticConfig(config), "an IncludedServices entry without a port should be rejected")
+	config.Beacon.IncludedServices = nil
+
+	config.Analysis.MaxThreads = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative Analysis MaxThreads should be rejected")
+	config.Analysis.MaxThreads = 0
+
+	config.BeaconSNI.MaxFQDNsPerHost = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative BeaconSNI MaxFQDNsPerHost should be rejected")
+	config.BeaconSNI.MaxFQDNsPerHost = 0
+
+	config.Beacon.MaxIntervalBuckets = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative Beacon MaxIntervalBuckets should be rejected")
+	config.Beacon.MaxIntervalBuckets = 0
+	config.Beacon.IntervalBucketScale = "exponential"
+	assert.NotNil(t, validateStaticConfig(config), "an unknown Beacon IntervalBucketScale should be rejected")
+	config.Beacon.IntervalBucketScale = "log"
+	config.Beacon.MaxStoredIntervals = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative Beacon MaxStoredIntervals should be rejected")
+	config.Beacon.MaxStoredIntervals = 0
+	config.Beacon.MaxExactIntervals = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative Beacon MaxExactIntervals should be rejected")
+	config.Beacon.MaxExactIntervals = 0
+
+	config.Beacon.ConnCountMode = "median"
+	assert.Nil(t, validateStaticConfig(config), "median ConnectionCountNormalization should be accepted")
+	config.Beacon.ConnCountMode = "daily"
+	assert.NotNil(t, validateStaticConfig(config), "an unknown Beacon ConnectionCountNormalization should be rejected")
+	config.Beacon.ConnCountMode = "hourly"
+
+	config.Beacon.Heartbeat.MinScore = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a Beacon Heartbeat MinScore above 1 should be rejected")
+	config.Beacon.Heartbeat.MinScore = 0.8
+	config.Beacon.Heartbeat.MaxAvgBytes = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative Beacon Heartbeat MaxAverageBytes should be rejected")
+	config.Beacon.Heartbeat.MaxAvgBytes = 512
+	for _, service := range []string{"ntp", "udp:123", "123", "70000:udp", "123:udp:ntp:extra", "123::ntp"} {
+		config.Beacon.Heartbeat.KnownServices = []string{service}
+		assert.NotNil(t, validateStaticConfig(config), "Beacon Heartbeat KnownServices entry %q should be rejected", service)
+	}
+	config.Beacon.Heartbeat.KnownServices = []string{"123:udp", "443:tcp:ssl"}
+	assert.Nil(t, validateStaticConfig(config))
+	config.Beacon.Heartbeat.KnownDestinations = []string{"time.example.com"}
+	assert.NotNil(t, validateStaticConfig(config), "Beacon Heartbeat KnownDestinations entries must be addresses")
+	config.Beacon.Heartbeat.KnownDestinations = nil
+
+	config.Beacon.KnownPeriodic.Penalty = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a Beacon KnownPeriodic Penalty above 1 should be rejected")
+	config.Beacon.KnownPeriodic.Penalty = 0.5
+	config.Beacon.KnownPeriodic.Services = []KnownPeriodicService{{Service: "53:udp:dns", Destinations: []string{"10.0.0.53"}}}
+	assert.Nil(t, validateStaticConfig(config))
+	config.Beacon.KnownPeriodic.Services = []KnownPeriodicService{{Service: "dns", Destinations: []string{"10.0.0.53"}}}
+	assert.NotNil(t, validateStaticConfig(config), "Beacon KnownPeriodic Services entries must be port:protocol tuples")
+	config.Beacon.KnownPeriodic.Services = []KnownPeriodicService{{Service: "53:udp"}}
+	assert.NotNil(t, validateStaticConfig(config), "Beacon KnownPeriodic Services entries must name their servers")
+	config.Beacon.KnownPeriodic.Services = []KnownPeriodicService{{Service: "53:udp", Destinations: []string{"dns.example.com"}}}
+	assert.NotNil(t, validateStaticConfig(config), "Beacon KnownPeriodic Destinations entries must be addresses")
+	config.Beacon.KnownPeriodic.Services = nil
+
+	config.HostRoles.ServerMinConnections = 0
+	assert.NotNil(t, validateStaticConfig(config), "a HostRoles ServerMinConnections of 0 would make every host a server")
+	config.HostRoles.ServerMinConnections = 1000
+	config.HostRoles.ScannerMinDestinations = 0
+	assert.NotNil(t, validateStaticConfig(config), "a HostRoles ScannerMinDestinations of 0 would make every host a scanner")
+	config.HostRoles.ScannerMinDestinations = 250
+	config.HostRoles.BeaconMinScore = 1.5
+	assert.NotNil(t, validateStaticConfig(config), "a HostRoles BeaconMinScore above 1 should be rejected")
+	config.HostRoles.BeaconMinScore = 0.8
+
+	config.Log.LogLevels.Import = 4
+	assert.NotNil(t, validateStaticConfig(config), "a LogLevels Import above 3 should be rejected")
+	config.Log.LogLevels.Import = -1
+	config.Log.LogLevels.Analysis = -2
+	assert.NotNil(t, validateStaticConfig(config), "a LogLevels Analysis below -1 should be rejected")
+	config.Log.LogLevels.Analysis = -1
+
+	config.Attack.Beacon = "1071"
+	assert.NotNil(t, validateStaticConfig(config), "a MitreAttack technique must be a technique ID")
+	config.Attack.Beacon = "T1071.1"
+	assert.NotNil(t, validateStaticConfig(config), "a MitreAttack sub-technique must have three digits")
+	config.Attack.Beacon = ""
+	assert.Nil(t, validateStaticConfig(config), "an empty MitreAttack technique disables the tag")
+	config.Attack.Beacon = "T1071"
+
+	config.Rolling.MaxChunks = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative Rolling MaxChunks should be rejected")
+	config.Rolling.MaxChunks = 0
+
+	config.Rolling.ChunkDuration = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative Rolling ChunkDuration should be rejected")
+	config.Rolling.ChunkDuration = 0
+
+	config.Beacon.DefaultConnectionThresh = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative Beacon DefaultConnectionThresh should be rejected")
+	config.Beacon.DefaultConnectionThresh = 20
+
+	config.BeaconProxy.MinConnBytes = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative BeaconProxy MinConnBytes should be rejected")
+	config.BeaconProxy.MinConnBytes = 0
+
+	config.BeaconProxy.ForwardedLegWindow = 0
+	assert.Nil(t, validateStaticConfig(config), "a BeaconProxy ForwardedLegWindow of 0 only collapses legs logged in the same second")
+	config.BeaconProxy.ForwardedLegWindow = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative BeaconProxy ForwardedLegWindow should be rejected")
+	config.BeaconProxy.ForwardedLegWindow = 1
+
+	config.BeaconProxy.CollapseForwardedLegs = true
+	assert.NotNil(t, validateStaticConfig(config), "collapsing forwarded legs without BeaconProxy ForwardingProxies should be rejected")
+	config.BeaconProxy.ForwardingProxies = []string{"10.0.0.1", "10.1.0.0/16"}
+	assert.Nil(t, validateStaticConfig(config), "BeaconProxy ForwardingProxies may hold addresses and subnets")
+	config.BeaconProxy.ForwardingProxies = []string{"proxy.example.com"}
+	assert.NotNil(t, validateStaticConfig(config), "an invalid BeaconProxy ForwardingProxies entry should be rejected")
+	config.BeaconProxy.CollapseForwardedLegs = false
+	config.BeaconProxy.ForwardingProxies = nil
+
+	config.BeaconProxy.ConnectionThreshold = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative BeaconProxy ConnectionThreshold should be rejected")
+	config.BeaconProxy.ConnectionThreshold = 0
+
+	config.BeaconProxy.IntervalResolution = 0
+	assert.NotNil(t, validateStaticConfig(config), "a BeaconProxy IntervalResolution of 0 should be rejected")
+	config.BeaconProxy.IntervalResolution = -5
+	assert.NotNil(t, validateStaticConfig(config), "a negative BeaconProxy IntervalResolution should be rejected")
+	config.BeaconProxy.IntervalResolution = 1
+
+	config.BeaconProxy.Scoring.ConnCountWeight = 0
+	assert.Nil(t, validateStaticConfig(config), "a BeaconProxy Scoring weight of 0 removes its subscore")
+	config.BeaconProxy.Scoring.MadmWeight = -1
+	assert.NotNil(t, validateStaticConfig(config), "a negative BeaconProxy Scoring weight should be rejected")
+	config.BeaconProxy.Scoring = BeaconProxyScoringStaticCfg{}
+	assert.NotNil(t, validateStaticConfig(config), "BeaconProxy Scoring weights which are all 0 should be rejected")
+	config.BeaconProxy.Scoring = BeaconProxyScoringStaticCfg{SkewWeight: 1, MadmWeight: 1, ConnCountWeight: 1}
+
+	config.BeaconSNI.DefaultConnectionThresh = -1
+	assert.NotNil(t, validateStaticConfig(config), "negative BeaconSNI DefaultConnectionThresh should be rejected")
+	config.BeaconSNI.DefaultConnectionThresh = 20
+
+	config.Filtering.InternalSubnets = []string{"10.0.0.0/8", "192.168.1.1", "fd00::/8"}
+	assert.Nil(t, validateStaticConfig(config), "CIDR ranges and single addresses should be valid")
+	config.Filtering.InternalSubnets = []string{"10.0.0.0/33"}
+	assert.NotNil(t, validateStaticConfig(config), "an invalid CIDR range should be rejected")
+	config.Filtering.InternalSubnets = []string{"10.0.0.0/8"}
+	config.Filtering.NeverInclude = []string{"not-an-address"}
+	assert.NotNil(t, validateStaticConfig(config), "an invalid NeverInclude entry should be rejected")
+	config.Filtering.NeverInclude = nil
+
+	config.Sinks = []SinkStaticCfg{
+		{Type: "file", Path: "/var/lib/rita/results.jsonl"},
+		{Type: "kafka", URL: "http://localhost:8082", Topic: "rita"},
+	}
+	assert.Nil(t, validateStaticConfig(config), "configured file and kafka sinks should be valid")
+	config.Sinks = []SinkStaticCfg{{Type: "file"}}
+	assert.NotNil(t, validateStaticConfig(config), "a file sink without a Path should be rejected")
+	config.Sinks = []SinkStaticCfg{{Type: "elasticsearch", URL: "http://localhost:9200"}}
+	assert.NotNil(t, validateStaticConfig(config), "an elasticsearch sink without an Index should be rejected")
+	config.Sinks = []SinkStaticCfg{{Type: "syslog"}}
+	assert.NotNil(t, validateStaticConfig(config), "an unknown sink Type should be rejected")
+	config.Sinks = nil
+
+	config.Redaction.Method = "zero"
+	assert.Nil(t, validateStaticConfig(config), "Redaction Method zero should be valid")
+	config.Redaction.Method = "drop"
+	assert.NotNil(t, validateStaticConfig(config), "unknown Redaction Method should be rejected")
+	config.Redaction.Method = "hash"
+
+	config.Redaction.Fields = []string{"src", "dst"}
+	assert.Nil(t, validateStaticConfig(config), "Redaction Fields src and dst should be valid")
+	config.Redaction.Fields = []string{"src", "fqdn"}
+	assert.NotNil(t, validateStaticConfig(config), "unknown Redaction Fields should be rejected")
+	config.Redaction.Fields = []string{"src"}
+
+	config.Redaction.Enabled = true
+	config.Redaction.Key = ""
+	assert.NotNil(t, validateStaticConfig(config), "hashing addresses without a Redaction Key should be rejected")
+	config.Redaction.Method = "zero"
+	assert.Nil(t, validateStaticConfig(config), "zeroing addresses doesn't need a Redaction Key")
+	config.Redaction.Method = "hash"
+	config.Redaction.Key = "secret"
+	assert.Nil(t, validateStaticConfig(config), "hashing addresses with a Redaction Key should be valid")
+	config.Redaction.Enabled = false
+	config.Redaction.Key = ""
+	assert.Nil(t, validateStaticConfig(config), "the Redaction Key is only required when redaction is enabled")
+
+	config.MongoDB.Connections = map[string]MongoDBConnectionStaticCfg{
+		"east": {ConnectionString: "mongodb://mongo-east:27017"},
+	}
+	assert.Nil(t, validateStaticConfig(config), "a named connection with a ConnectionString should be valid")
+	config.MongoDB.Connections["west"] = MongoDBConnectionStaticCfg{}
+	assert.NotNil(t, validateStaticConfig(config), "a named connection without a ConnectionString should be rejected")
+	config.MongoDB.Connections = nil
+
+	config.MongoDB.Sharding.ShardKeys = map[string]ShardKeyStaticCfg{
+		"uconn":     {Fields: []string{"src", "dst"}},
+		"useragent": {Fields: []string{"user_agent"}, Hashed: true},
+	}
+	assert.Nil(t, validateStaticConfig(config), "shard keys with fields should be valid")
+	config.MongoDB.Sharding.ShardKeys["beacon"] = ShardKeyStaticCfg{}
+	assert.NotNil(t, validateStaticConfig(config), "a shard key without fields should be rejected")
+	config.MongoDB.Sharding.ShardKeys["beacon"] = ShardKeyStaticCfg{Fields: []string{"src", "dst"}, Hashed: true}
+	assert.NotNil(t, validateStaticConfig(config), "a hashed shard key with several fields should be rejected")
+
+	// the shard key must begin every unique index of the collection
+	config.MongoDB.Sharding.ShardKeys["beacon"] = ShardKeyStaticCfg{Fields: []string{"src", "dst"}}
+	assert.Nil(t, validateStaticConfig(config), "a shard key which begins the unique index should be valid")
+	config.MongoDB.Sharding.ShardKeys["beacon"] = ShardKeyStaticCfg{Fields: []string{"pair_hash"}}
+	assert.NotNil(t, validateStaticConfig(config), "a shard key which doesn't begin the unique index should be rejected")
+	config.Beacon.HashPairKeys = true
+	assert.NotNil(t, validateStaticConfig(config), "the shard key must begin every unique index")
+	config.MongoDB.Sharding.ShardKeys["beacon"] = ShardKeyStaticCfg{Fields: []string{"src", "dst"}}
+	assert.NotNil(t, validateStaticConfig(config), "the shard key must begin every unique index")
+	config.Beacon.HashPairKeys = false
+	delete(config.MongoDB.Sharding.ShardKeys, "beacon")
+	config.MongoDB.Sharding.ShardKeys["host"] = ShardKeyStaticCfg{Fields: []string{"ip"}}
+	assert.NotNil(t, validateStaticConfig(config), "collections which aren't sharded by RITA should be rejected")
+}
@@ -27,6 +27,7 @@ type (
 		HTTPTable            string `default:"http"`
 		OpenConnTable        string `default:"openconn"`
 		SSLTable             string `default:"ssl"`
+		QUICTable            string `default:"quic"`
 		UniqueConnTable      string `default:"uconn"`
 		UniqueConnProxyTable string `default:"uconnProxy"`
 		SNIConnTable         string `default:"SNIconn"`
@@ -40,7 +41,8 @@ type (
 
 	//BeaconTableCfg is used to control the beaconing analysis module
 	BeaconTableCfg struct {
-		BeaconTable string `default:"beacon"`
+		BeaconTable           string `default:"beacon"`
+		BeaconCheckpointTable string `default:"beaconCheckpoint"`
 	}
 
 	//BeaconSNITableCfg is used to control the SNI beaconing analysis module
@@ -69,3 +71,42 @@ type (
 		DatabasesTable string `default:"databases"`
 	}
 )
+
+// uniqueIndexes returns the fields of the unique indexes of each collection
+// which is sharded as it is created. These must match the indexes created by
+// the analysis packages.
+func uniqueIndexes(tables *TableCfg, hashPairKeys bool) map[string][][]string {
+	pair := []string{"src", "dst", "src_network_uuid", "dst_network_uuid"}
+	fqdn := []string{"src", "fqdn", "src_network_uuid"}
+
+	beacon := [][]string{pair}
+	if hashPairKeys {
+		beacon = append(beacon, []string{"pair_hash"})
+	}
+
+	return map[string][][]string{
+		tables.Structure.UniqueConnTable:      {pair},
+		tables.Structure.UniqueConnProxyTable: {fqdn},
+		tables.Structure.SNIConnTable:         {fqdn},
+		tables.Beacon.BeaconTable:             beacon,
+		tables.BeaconSNI.BeaconSNITable:       {fqdn},
+		tables.BeaconProxy.BeaconProxyTable:   {fqdn},
+		tables.Cert.CertificateTable:          {{"ip", "network_uuid"}},
+		tables.UserAgent.UserAgentTable:       {{"user_agent"}},
+		tables.DNS.ExplodedDNSTable:           {{"domain"}},
+		tables.DNS.HostnamesTable:             {{"host"}},
+	}
+}
+
+// isPrefix reports whether prefix holds the first fields of fields
+func isPrefix(prefix []string, fields []string) bool {
+	if len(prefix) > len(fields) {
+		return false
+	}
+	for i := range prefix {
+		if prefix[i] != fields[i] {
+			return false
+		}
+	}
+	return true
+}
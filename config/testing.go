@@ -79,6 +79,11 @@ func LoadTestingConfig(mongoURI string) (*Config, error) {
 		return nil, err
 	}
 
+	// Ensure the resulting static config is usable
+	if err := validateStaticConfig(&config.S); err != nil {
+		return nil, err
+	}
+
 	config.S.Version = "v0.0.0+testing"
 	config.S.ExactVersion = "v0.0.0+testing"
 
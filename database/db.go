@@ -1,6 +1,7 @@
 package database
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/activecm/mgosec"
@@ -27,11 +28,17 @@ var MaxMongoDBVersion = semver.Version{
 	Patch: 0,
 }
 
+//ErrReadOnly is returned when a write is attempted while RITA is running in read-only mode
+var ErrReadOnly = errors.New("refusing to write to the database: RITA is running in read-only mode")
+
 // DB is the workhorse container for messing with the database
 type DB struct {
 	Session  *mgo.Session
 	log      *log.Logger
 	selected string
+	readOnly bool
+	sinks    []Sink
+	sharding config.ShardingStaticCfg
 }
 
 //NewDB constructs a new DB struct
@@ -49,6 +56,8 @@ func NewDB(conf *config.Config, log *log.Logger) (*DB, error) {
 		Session:  session,
 		log:      log,
 		selected: "",
+		readOnly: conf.S.MongoDB.ReadOnly,
+		sharding: conf.S.MongoDB.Sharding,
 	}, nil
 }
 
@@ -105,6 +114,16 @@ func (d *DB) GetSelectedDB() string {
 	return d.selected
 }
 
+//Writable returns ErrReadOnly if RITA is not allowed to write to the database.
+//Code which writes to the database through the Session directly must check
+//this before writing.
+func (d *DB) Writable() error {
+	if d.readOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
 //CollectionExists returns true if collection exists in the currently
 //selected database
 func (d *DB) CollectionExists(table string) bool {
@@ -126,8 +145,13 @@ func (d *DB) CollectionExists(table string) bool {
 }
 
 //CreateCollection creates a new collection in the currently selected
-//database with the required indexes
+//database with the required indexes. The collection is sharded if a shard
+//key is configured for it and RITA is connected to a sharded cluster.
 func (d *DB) CreateCollection(name string, indexes []mgo.Index) error {
+	if err := d.Writable(); err != nil {
+		return err
+	}
+
 	// Make a copy of the current session
 	session := d.Session.Copy()
 	defer session.Close()
@@ -144,6 +168,11 @@ func (d *DB) CreateCollection(name string, indexes []mgo.Index) error {
 		return err
 	}
 
+	err = d.shardCollection(session, name)
+	if err != nil {
+		return err
+	}
+
 	collection := session.DB(d.selected).C(name)
 	for _, index := range indexes {
 		err := collection.EnsureIndex(index)
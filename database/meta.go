@@ -1,6 +1,8 @@
 package database
 
 import (
+	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -39,17 +41,101 @@ type (
 
 	// DBMetaInfo defines some information about the database
 	DBMetaInfo struct {
-		ID             bson.ObjectId `bson:"_id,omitempty"`   // Ident
-		Name           string        `bson:"name"`            // Top level name of the database
-		Analyzed       bool          `bson:"analyzed"`        // Has this database been analyzed
-		AnalyzeVersion string        `bson:"analyze_version"` // Rita version at analyze
-		Rolling        bool          `bson:"rolling"`
-		TotalChunks    int           `bson:"total_chunks"`
-		CurrentChunk   int           `bson:"current_chunk"`
-		TsRange        Range         `bson:"ts_range"`
+		ID             bson.ObjectId    `bson:"_id,omitempty"`   // Ident
+		Name           string           `bson:"name"`            // Top level name of the database
+		Analyzed       bool             `bson:"analyzed"`        // Has this database been analyzed
+		AnalyzeVersion string           `bson:"analyze_version"` // Rita version at analyze
+		Rolling        bool             `bson:"rolling"`
+		TotalChunks    int              `bson:"total_chunks"`
+		CurrentChunk   int              `bson:"current_chunk"`
+		TsRange        Range            `bson:"ts_range"`
+		CIDList        []ChunkState     `bson:"cid_list,omitempty"`
+		ConfigSnapshot *config.Snapshot `bson:"config_snapshot,omitempty"` // analysis settings of the last import
+		HashPairKeys   bool             `bson:"hash_pair_keys"`            // beacons are selected by their pair hash
+	}
+
+	// ChunkState records whether a chunk of a rolling database holds data.
+	// Period is set when chunks are assigned by Rolling.ChunkDuration and
+	// counts the chunk durations since the epoch of the chunk's records.
+	// TsRange spans the timestamps of the chunk's connections. It is nil for
+	// chunks imported before the ranges were recorded.
+	ChunkState struct {
+		Set     bool   `bson:"set"`
+		Period  int64  `bson:"period,omitempty"`
+		TsRange *Range `bson:"ts_range,omitempty"`
 	}
 )
 
+// SetChunks returns the chunks of a rolling database which hold data, oldest
+// first. Chunk IDs wrap back to 0 after TotalChunks, so the oldest chunk is the
+// first set chunk after CurrentChunk.
+func (d DBMetaInfo) SetChunks() []int {
+	if d.TotalChunks < 1 {
+		return nil
+	}
+
+	var chunks []int
+	for age := d.TotalChunks - 1; age >= 0; age-- {
+		cid := (d.CurrentChunk - age + d.TotalChunks) % d.TotalChunks
+		if cid < len(d.CIDList) && d.CIDList[cid].Set {
+			chunks = append(chunks, cid)
+		}
+	}
+	return chunks
+}
+
+// CheckHashPairKeys returns an error if the dataset was created with a
+// different Beacon HashPairKeys setting than enabled. The beacons of a dataset
+// are either all selected by their pair hash or all selected by their hosts, so
+// the setting can't change once the dataset holds beacons. Datasets created
+// before the setting was recorded don't use pair hashes.
+func (d DBMetaInfo) CheckHashPairKeys(enabled bool) error {
+	if d.HashPairKeys == enabled {
+		return nil
+	}
+	return fmt.Errorf("dataset %s was created with Beacon HashPairKeys set to %v: "+
+		"set HashPairKeys to %v or import into a new dataset", d.Name, d.HashPairKeys, d.HashPairKeys)
+}
+
+// OverlappingChunks groups the set chunks whose time ranges overlap, such as
+// chunks which were imported from the same logs or from sensors which saw the
+// same connections. Each group holds at least two chunks, ordered by the start
+// of their time ranges. Chunks without a time range are skipped.
+func (d DBMetaInfo) OverlappingChunks() [][]int {
+	var chunks []int
+	for cid, state := range d.CIDList {
+		if state.Set && state.TsRange != nil {
+			chunks = append(chunks, cid)
+		}
+	}
+	sort.SliceStable(chunks, func(i, j int) bool {
+		return d.CIDList[chunks[i]].TsRange.Min < d.CIDList[chunks[j]].TsRange.Min
+	})
+
+	var groups [][]int
+	var group []int
+	var groupMax int64
+	for _, cid := range chunks {
+		tsRange := d.CIDList[cid].TsRange
+		if len(group) > 0 && tsRange.Min <= groupMax {
+			group = append(group, cid)
+			if tsRange.Max > groupMax {
+				groupMax = tsRange.Max
+			}
+			continue
+		}
+		if len(group) > 1 {
+			groups = append(groups, group)
+		}
+		group = []int{cid}
+		groupMax = tsRange.Max
+	}
+	if len(group) > 1 {
+		groups = append(groups, group)
+	}
+	return groups
+}
+
 // NewMetaDB instantiates a new handle for the RITA MetaDatabase
 func NewMetaDB(config *config.Config, dbHandle *mgo.Session,
 	log *log.Logger) *MetaDB {
@@ -63,6 +149,14 @@ func NewMetaDB(config *config.Config, dbHandle *mgo.Session,
 	return metaDB
 }
 
+// writable returns ErrReadOnly if RITA is not allowed to write to the database
+func (m *MetaDB) writable() error {
+	if m.config.S.MongoDB.ReadOnly {
+		return ErrReadOnly
+	}
+	return nil
+}
+
 //GetRollingSettings gets the current rolling settings
 func (m *MetaDB) GetRollingSettings(db string) (exists bool, isRolling bool, currChunk int, totalChunks int, err error) {
 	// pull down dataset record from metadatabase
@@ -84,6 +178,10 @@ func (m *MetaDB) GetRollingSettings(db string) (exists bool, isRolling bool, cur
 //SetRollingSettings ensures that a given db is marked as rolling,
 //ensures that total_chunks matches numchunks, and sets the current_chunk to chunk.
 func (m *MetaDB) SetRollingSettings(db string, chunk int, numchunks int) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	// pull down dataset record from metadatabase
 	result, err := m.GetDBMetaInfo(db)
 	if err != nil {
@@ -151,6 +249,10 @@ func (m *MetaDB) LastCheck() (time.Time, semver.Version) {
 // AddNewDB adds a new database to the DBMetaInfo table. All new databases are
 // ready to be rolling databases.
 func (m *MetaDB) AddNewDB(name string, currentChunk, totalChunks int) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	ssn := m.dbHandle.Copy()
@@ -164,6 +266,7 @@ func (m *MetaDB) AddNewDB(name string, currentChunk, totalChunks int) error {
 			Rolling:        false,
 			CurrentChunk:   currentChunk,
 			TotalChunks:    totalChunks,
+			HashPairKeys:   m.config.S.Beacon.HashPairKeys,
 		},
 	)
 	if err != nil {
@@ -176,9 +279,7 @@ func (m *MetaDB) AddNewDB(name string, currentChunk, totalChunks int) error {
 
 	// Used to initialize Mongo array
 	// e.g. [{"set": false}, {"set": false}]
-	cidList := make([]struct {
-		Set bool `bson:"set"`
-	}, totalChunks)
+	cidList := make([]ChunkState, totalChunks)
 
 	_, err = ssn.DB(m.config.S.MongoDB.MetaDB).C(m.config.T.Meta.DatabasesTable).
 		Upsert(
@@ -210,6 +311,10 @@ func (m *MetaDB) DBExists(name string) (bool, error) {
 
 // DeleteDB removes a database managed by RITA
 func (m *MetaDB) DeleteDB(name string) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	_, err := m.GetDBMetaInfo(name)
 	if err != nil {
 		m.log.WithFields(log.Fields{
@@ -289,6 +394,10 @@ func (m *MetaDB) GetTSRange(name string) (int64, int64, error) {
 
 // AddTSRange adds the min and max timestamps found in current dataset
 func (m *MetaDB) AddTSRange(name string, min int64, max int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	dbr, err := m.GetDBMetaInfo(name)
 
 	if err != nil {
@@ -329,6 +438,10 @@ func (m *MetaDB) AddTSRange(name string, min int64, max int64) error {
 
 // MarkDBAnalyzed marks a database as having been analyzed
 func (m *MetaDB) MarkDBAnalyzed(name string, complete bool) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	dbr, err := m.GetDBMetaInfo(name)
 
 	if err != nil {
@@ -372,6 +485,33 @@ func (m *MetaDB) MarkDBAnalyzed(name string, complete bool) error {
 	return nil
 }
 
+// SetConfigSnapshot records the analysis settings used to produce the results
+// of a database, replacing the settings of any earlier import
+func (m *MetaDB) SetConfigSnapshot(name string, snapshot config.Snapshot) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ssn := m.dbHandle.Copy()
+	defer ssn.Close()
+
+	err := ssn.DB(m.config.S.MongoDB.MetaDB).C(m.config.T.Meta.DatabasesTable).
+		Update(bson.M{"name": name}, bson.M{"$set": bson.M{"config_snapshot": snapshot}})
+
+	if err != nil {
+		m.log.WithFields(log.Fields{
+			"metadb_attempted":   m.config.S.MongoDB.MetaDB,
+			"database_requested": name,
+			"error":              err.Error(),
+		}).Error("Could not store the config snapshot for database entry in metadatabase")
+		return err
+	}
+	return nil
+}
+
 // runDBMetaInfoQuery runs a MongoDB query against the MetaDB Databases Table
 // and performs any necessary data migration
 func (m *MetaDB) runDBMetaInfoQuery(queryDoc bson.M) ([]DBMetaInfo, error) {
@@ -392,6 +532,46 @@ func (m *MetaDB) runDBMetaInfoQuery(queryDoc bson.M) ([]DBMetaInfo, error) {
 
 // SetChunk ....
 func (m *MetaDB) SetChunk(cid int, db string, analyzed bool) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ssn := m.dbHandle.Copy()
+	defer ssn.Close()
+
+	update := bson.M{
+		"$set": bson.M{
+			"cid_list." + strconv.Itoa(cid) + ".set": analyzed,
+		},
+	}
+	// a chunk which is emptied no longer covers its time range
+	if !analyzed {
+		update["$unset"] = bson.M{"cid_list." + strconv.Itoa(cid) + ".ts_range": ""}
+	}
+
+	_, err := ssn.DB(m.config.S.MongoDB.MetaDB).C(m.config.T.Meta.DatabasesTable).
+		Upsert(bson.M{"name": db}, update)
+
+	if err != nil {
+		m.log.WithFields(log.Fields{
+			"metadb_attempted":   m.config.S.MongoDB.MetaDB,
+			"database_requested": db,
+			"error":              err.Error(),
+		}).Error("Could not update CID analyzed value for database entry in metadatabase")
+		return err
+	}
+	return nil
+}
+
+// SetChunkPeriod marks a chunk as holding the records of the given period
+func (m *MetaDB) SetChunkPeriod(cid int, db string, period int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 
@@ -403,7 +583,8 @@ func (m *MetaDB) SetChunk(cid int, db string, analyzed bool) error {
 			bson.M{"name": db},
 			bson.M{
 				"$set": bson.M{
-					"cid_list." + strconv.Itoa(cid) + ".set": analyzed,
+					"cid_list." + strconv.Itoa(cid) + ".set":    true,
+					"cid_list." + strconv.Itoa(cid) + ".period": period,
 				}},
 		)
 
@@ -412,12 +593,56 @@ func (m *MetaDB) SetChunk(cid int, db string, analyzed bool) error {
 			"metadb_attempted":   m.config.S.MongoDB.MetaDB,
 			"database_requested": db,
 			"error":              err.Error(),
-		}).Error("Could not update CID analyzed value for database entry in metadatabase")
+		}).Error("Could not update CID period for database entry in metadatabase")
 		return err
 	}
 	return nil
 }
 
+// AddChunkTSRange widens the time range of a chunk to include min and max
+func (m *MetaDB) AddChunkTSRange(cid int, db string, min int64, max int64) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ssn := m.dbHandle.Copy()
+	defer ssn.Close()
+
+	_, err := ssn.DB(m.config.S.MongoDB.MetaDB).C(m.config.T.Meta.DatabasesTable).
+		Upsert(
+			bson.M{"name": db},
+			bson.M{
+				"$min": bson.M{"cid_list." + strconv.Itoa(cid) + ".ts_range.min": min},
+				"$max": bson.M{"cid_list." + strconv.Itoa(cid) + ".ts_range.max": max},
+			},
+		)
+
+	if err != nil {
+		m.log.WithFields(log.Fields{
+			"metadb_attempted":   m.config.S.MongoDB.MetaDB,
+			"database_requested": db,
+			"error":              err.Error(),
+		}).Error("Could not update CID time range for database entry in metadatabase")
+		return err
+	}
+	return nil
+}
+
+// GetChunkState returns the state of a chunk in a rolling database
+func (m *MetaDB) GetChunkState(cid int, db string) (ChunkState, error) {
+	dbr, err := m.GetDBMetaInfo(db)
+	if err != nil {
+		return ChunkState{}, err
+	}
+	if cid < 0 || cid >= len(dbr.CIDList) {
+		return ChunkState{}, fmt.Errorf("chunk %d is not in the CID list of %s", cid, db)
+	}
+	return dbr.CIDList[cid], nil
+}
+
 // IsChunkSet ....
 func (m *MetaDB) IsChunkSet(cid int, db string) (bool, error) {
 	m.lock.Lock()
@@ -545,6 +770,10 @@ func (m *MetaDB) GetFiles(database string) ([]files.IndexedFile, error) {
 
 //AddNewFilesToIndex adds indexed files to the files the metaDB using the bulk API
 func (m *MetaDB) AddNewFilesToIndex(files []*files.IndexedFile) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	if len(files) == 0 {
@@ -611,6 +840,10 @@ func (m *MetaDB) FilterOutPreviouslyIndexedFiles(indexedFiles []*files.IndexedFi
 //RemoveFilesByChunk removes FilesTable entries for a given database chunk.
 //This helps provide the ability to re-import a given chunk.
 func (m *MetaDB) RemoveFilesByChunk(database string, cid int) error {
+	if err := m.writable(); err != nil {
+		return err
+	}
+
 	m.lock.Lock()
 	defer m.lock.Unlock()
 	ssn := m.dbHandle.Copy()
@@ -0,0 +1,77 @@
+package database
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetChunks(t *testing.T) {
+	newInfo := func(currentChunk int, set ...int) DBMetaInfo {
+		info := DBMetaInfo{
+			Rolling:      true,
+			TotalChunks:  4,
+			CurrentChunk: currentChunk,
+			CIDList:      make([]ChunkState, 4),
+		}
+		for _, cid := range set {
+			info.CIDList[cid].Set = true
+		}
+		return info
+	}
+
+	// two chunks imported in order
+	assert.Equal(t, []int{0, 1}, newInfo(1, 0, 1).SetChunks())
+
+	// the chunk after the current chunk is the oldest once the IDs wrap
+	assert.Equal(t, []int{2, 3, 0, 1}, newInfo(1, 0, 1, 2, 3).SetChunks())
+
+	// chunks which were removed are skipped
+	assert.Equal(t, []int{3, 1}, newInfo(1, 1, 3).SetChunks())
+
+	assert.Equal(t, []int{1}, newInfo(1, 1).SetChunks())
+	assert.Nil(t, newInfo(1).SetChunks())
+	assert.Nil(t, DBMetaInfo{}.SetChunks())
+}
+
+func TestOverlappingChunks(t *testing.T) {
+	newInfo := func(ranges ...*Range) DBMetaInfo {
+		info := DBMetaInfo{Rolling: true, TotalChunks: len(ranges), CIDList: make([]ChunkState, len(ranges))}
+		for cid, tsRange := range ranges {
+			info.CIDList[cid] = ChunkState{Set: tsRange != nil, TsRange: tsRange}
+		}
+		return info
+	}
+
+	// hourly chunks imported in order don't overlap
+	assert.Nil(t, newInfo(&Range{0, 3599}, &Range{3600, 7199}, &Range{7200, 10799}).OverlappingChunks())
+
+	// the same hour imported into chunks 0 and 2
+	assert.Equal(t, [][]int{{0, 2}}, newInfo(&Range{0, 3599}, &Range{3600, 7199}, &Range{0, 3599}).OverlappingChunks())
+
+	// a chunk overlapping two others joins them into one group, ordered by start
+	assert.Equal(t, [][]int{{2, 1, 0}},
+		newInfo(&Range{5000, 9000}, &Range{3000, 6000}, &Range{0, 4000}).OverlappingChunks())
+
+	// separate overlaps are reported separately
+	assert.Equal(t, [][]int{{0, 1}, {2, 3}},
+		newInfo(&Range{0, 100}, &Range{50, 150}, &Range{1000, 1100}, &Range{1100, 1200}).OverlappingChunks())
+
+	// chunks without a recorded range or without data are skipped
+	info := newInfo(&Range{0, 3599}, nil, &Range{0, 3599})
+	info.CIDList[1].TsRange = &Range{0, 3599}
+	info.CIDList[2] = ChunkState{Set: true}
+	assert.Nil(t, info.OverlappingChunks())
+	assert.Nil(t, DBMetaInfo{}.OverlappingChunks())
+}
+
+func TestCheckHashPairKeys(t *testing.T) {
+	// datasets created before the setting was recorded select beacons by their hosts
+	legacy := DBMetaInfo{Name: "legacy"}
+	assert.Nil(t, legacy.CheckHashPairKeys(false))
+	assert.NotNil(t, legacy.CheckHashPairKeys(true))
+
+	hashed := DBMetaInfo{Name: "hashed", HashPairKeys: true}
+	assert.Nil(t, hashed.CheckHashPairKeys(true))
+	assert.NotNil(t, hashed.CheckHashPairKeys(false))
+}
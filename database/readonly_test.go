@@ -0,0 +1,79 @@
+package database
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/parser/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newReadOnlyTestDB creates a DB and MetaDB in read-only mode. No session is
+// provided, so any write which gets past the read-only check panics.
+func newReadOnlyTestDB(t *testing.T) (*config.Config, *log.Logger, *DB, *MetaDB) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.MongoDB.ReadOnly = true
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	db := &DB{log: logger, readOnly: conf.S.MongoDB.ReadOnly}
+	return conf, logger, db, NewMetaDB(conf, nil, logger)
+}
+
+func TestReadOnlyDB(t *testing.T) {
+	_, _, db, _ := newReadOnlyTestDB(t)
+
+	assert.Equal(t, ErrReadOnly, db.Writable())
+	assert.Equal(t, ErrReadOnly, db.CreateCollection("uconn", nil))
+
+	assert.Nil(t, (&DB{}).Writable(), "the database is writable by default")
+}
+
+func TestReadOnlyMetaDB(t *testing.T) {
+	_, _, _, metaDB := newReadOnlyTestDB(t)
+
+	assert.Equal(t, ErrReadOnly, metaDB.AddNewDB("test", 0, 1))
+	assert.Equal(t, ErrReadOnly, metaDB.SetRollingSettings("test", 0, 1))
+	assert.Equal(t, ErrReadOnly, metaDB.DeleteDB("test"))
+	assert.Equal(t, ErrReadOnly, metaDB.AddTSRange("test", 0, 1))
+	assert.Equal(t, ErrReadOnly, metaDB.MarkDBAnalyzed("test", true))
+	assert.Equal(t, ErrReadOnly, metaDB.SetConfigSnapshot("test", config.Snapshot{}))
+	assert.Equal(t, ErrReadOnly, metaDB.SetChunk(0, "test", true))
+	assert.Equal(t, ErrReadOnly, metaDB.AddChunkTSRange(0, "test", 0, 1))
+	assert.Equal(t, ErrReadOnly, metaDB.AddNewFilesToIndex([]*files.IndexedFile{{}}))
+	assert.Equal(t, ErrReadOnly, metaDB.RemoveFilesByChunk("test", 0))
+}
+
+func TestReadOnlyBulkWriter(t *testing.T) {
+	conf, logger, db, _ := newReadOnlyTestDB(t)
+
+	writer := NewBulkWriter(db, conf, logger, true, "test")
+	writer.Start()
+
+	// the changes are discarded rather than written
+	writer.Collect(BulkChanges{
+		"uconn": []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Update: map[string]string{}, Upsert: true}},
+	})
+	writer.Close()
+}
+
+func TestReadOnlyReportingWriter(t *testing.T) {
+	conf, logger, db, _ := newReadOnlyTestDB(t)
+
+	// the writer reports that it can't write in addition to logging it
+	var reported []error
+	writer := NewReportingWriter(db, conf, logger, true, "test", func(err error) {
+		reported = append(reported, err)
+	})
+	writer.Start()
+	writer.Collect(BulkChanges{
+		"uconn": []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Update: map[string]string{}, Upsert: true}},
+	})
+	writer.Close()
+	assert.Equal(t, []error{ErrReadOnly}, reported)
+}
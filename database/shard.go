@@ -0,0 +1,92 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/config"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	log "github.com/sirupsen/logrus"
+)
+
+// errCodeAlreadyInitialized is returned by enableSharding when sharding is
+// already enabled for the database
+const errCodeAlreadyInitialized = 23
+
+// commandRunner runs commands against the admin database. It is satisfied
+// by *mgo.Session.
+type commandRunner interface {
+	Run(cmd interface{}, result interface{}) error
+}
+
+// shardCollectionCommand returns the command which shards the collection
+// with the shard key
+func shardCollectionCommand(db string, collection string, key config.ShardKeyStaticCfg) bson.D {
+	keyDoc := bson.D{}
+	for _, field := range key.Fields {
+		if key.Hashed {
+			keyDoc = append(keyDoc, bson.DocElem{Name: field, Value: "hashed"})
+		} else {
+			keyDoc = append(keyDoc, bson.DocElem{Name: field, Value: 1})
+		}
+	}
+	return bson.D{
+		{Name: "shardCollection", Value: db + "." + collection},
+		{Name: "key", Value: keyDoc},
+	}
+}
+
+// isShardedCluster reports whether the server is a mongos router for a
+// sharded cluster
+func isShardedCluster(admin commandRunner) (bool, error) {
+	var isMaster struct {
+		Msg string `bson:"msg"`
+	}
+	err := admin.Run("isMaster", &isMaster)
+	if err != nil {
+		return false, err
+	}
+	return isMaster.Msg == "isdbgrid", nil
+}
+
+// shardCollection shards the collection in the currently selected database
+// if sharding is enabled, a shard key is configured for the collection, and
+// RITA is connected to a sharded cluster. Otherwise the collection is left
+// unsharded.
+func (d *DB) shardCollection(admin commandRunner, name string) error {
+	key, ok := d.sharding.ShardKeys[name]
+	if !d.sharding.Enabled || !ok {
+		return nil
+	}
+
+	sharded, err := isShardedCluster(admin)
+	if err != nil {
+		return fmt.Errorf("could not determine whether MongoDB is a sharded cluster: %w", err)
+	}
+	if !sharded {
+		d.log.WithFields(log.Fields{
+			"collection": name,
+		}).Debug("Not connected to a sharded cluster, creating unsharded collection")
+		return nil
+	}
+
+	err = admin.Run(bson.D{{Name: "enableSharding", Value: d.selected}}, nil)
+	if queryErr, ok := err.(*mgo.QueryError); ok && queryErr.Code == errCodeAlreadyInitialized {
+		err = nil
+	}
+	if err != nil {
+		return fmt.Errorf("could not enable sharding for database %s: %w", d.selected, err)
+	}
+
+	err = admin.Run(shardCollectionCommand(d.selected, name, key), nil)
+	if err != nil {
+		return fmt.Errorf("could not shard collection %s: %w", name, err)
+	}
+
+	d.log.WithFields(log.Fields{
+		"collection": name,
+		"shard_key":  key.Fields,
+		"hashed":     key.Hashed,
+	}).Debug("Sharded collection")
+	return nil
+}
@@ -0,0 +1,117 @@
+package database
+
+import (
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAdmin records the commands it is asked to run and answers isMaster
+// as a mongos router if sharded is set
+type fakeAdmin struct {
+	sharded  bool
+	commands []interface{}
+	errs     map[string]error
+}
+
+func (f *fakeAdmin) Run(cmd interface{}, result interface{}) error {
+	f.commands = append(f.commands, cmd)
+
+	// commands are either named or documents led by their name
+	name, _ := cmd.(string)
+	if doc, ok := cmd.(bson.D); ok {
+		name = doc[0].Name
+	}
+	if err := f.errs[name]; err != nil {
+		return err
+	}
+
+	if name == "isMaster" && f.sharded {
+		reply, _ := bson.Marshal(bson.M{"ismaster": true, "msg": "isdbgrid"})
+		return bson.Unmarshal(reply, result)
+	}
+	return nil
+}
+
+func newShardingTestDB(sharding config.ShardingStaticCfg) *DB {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+	return &DB{log: logger, selected: "dataset", sharding: sharding}
+}
+
+func TestShardCollection(t *testing.T) {
+	sharding := config.ShardingStaticCfg{
+		Enabled: true,
+		ShardKeys: map[string]config.ShardKeyStaticCfg{
+			"uconn":        {Fields: []string{"src", "dst"}},
+			"exploded_dns": {Fields: []string{"domain"}, Hashed: true},
+		},
+	}
+	db := newShardingTestDB(sharding)
+
+	admin := &fakeAdmin{sharded: true}
+	require.Nil(t, db.shardCollection(admin, "uconn"))
+	assert.Equal(t, []interface{}{
+		"isMaster",
+		bson.D{{Name: "enableSharding", Value: "dataset"}},
+		bson.D{
+			{Name: "shardCollection", Value: "dataset.uconn"},
+			{Name: "key", Value: bson.D{{Name: "src", Value: 1}, {Name: "dst", Value: 1}}},
+		},
+	}, admin.commands)
+
+	admin = &fakeAdmin{sharded: true}
+	require.Nil(t, db.shardCollection(admin, "exploded_dns"))
+	require.Len(t, admin.commands, 3)
+	assert.Equal(t, bson.D{
+		{Name: "shardCollection", Value: "dataset.exploded_dns"},
+		{Name: "key", Value: bson.D{{Name: "domain", Value: "hashed"}}},
+	}, admin.commands[2])
+
+	// collections without a shard key are created unsharded
+	admin = &fakeAdmin{sharded: true}
+	require.Nil(t, db.shardCollection(admin, "beacon"))
+	assert.Empty(t, admin.commands)
+
+	// fall back to unsharded collections when not connected to a sharded cluster
+	admin = &fakeAdmin{sharded: false}
+	require.Nil(t, db.shardCollection(admin, "uconn"))
+	assert.Equal(t, []interface{}{"isMaster"}, admin.commands)
+
+	// nothing is sharded unless sharding is enabled
+	sharding.Enabled = false
+	admin = &fakeAdmin{sharded: true}
+	require.Nil(t, newShardingTestDB(sharding).shardCollection(admin, "uconn"))
+	assert.Empty(t, admin.commands)
+}
+
+func TestShardCollectionErrors(t *testing.T) {
+	db := newShardingTestDB(config.ShardingStaticCfg{
+		Enabled:   true,
+		ShardKeys: map[string]config.ShardKeyStaticCfg{"uconn": {Fields: []string{"src", "dst"}}},
+	})
+
+	// sharding which is already enabled for the database is not an error
+	admin := &fakeAdmin{sharded: true, errs: map[string]error{
+		"enableSharding": &mgo.QueryError{Code: errCodeAlreadyInitialized, Message: "sharding already enabled for database dataset"},
+	}}
+	require.Nil(t, db.shardCollection(admin, "uconn"))
+	assert.Len(t, admin.commands, 3)
+
+	cause := errors.New("unauthorized")
+	admin = &fakeAdmin{sharded: true, errs: map[string]error{"shardCollection": cause}}
+	err := db.shardCollection(admin, "uconn")
+	assert.True(t, errors.Is(err, cause))
+
+	admin = &fakeAdmin{sharded: true, errs: map[string]error{"isMaster": cause}}
+	err = db.shardCollection(admin, "uconn")
+	assert.True(t, errors.Is(err, cause))
+	assert.Len(t, admin.commands, 1)
+}
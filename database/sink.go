@@ -0,0 +1,313 @@
+package database
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/activecm/rita/config"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// sinkQueueSize is the number of groups of results held for each sink
+	// registered with the database before further results are dropped
+	sinkQueueSize = 1000
+
+	// sinkBatchSize is the most changes delivered to a sink by each write
+	sinkBatchSize = 500
+
+	// sinkMaxFailures is the number of writes in a row which may fail before
+	// the results for a sink are dropped for sinkCooldown
+	sinkMaxFailures = 5
+	sinkCooldown    = 30 * time.Second
+)
+
+type (
+	// Sink receives a copy of the analysis results in addition to MongoDB.
+	// A Sink may be written to by several writers at once. Each writer closes
+	// the sink when it finishes, so a sink must accept further writes after
+	// it is closed.
+	Sink interface {
+		Name() string                 // identifies the sink in error reporting
+		Write(data BulkChanges) error // delivers a group of results to the sink
+		Close() error                 // releases the resources held by the sink
+	}
+
+	// Writer is a pipeline worker which receives analysis results
+	Writer interface {
+		Collect(data BulkChanges) // sends a group of results to the writer
+		Start()                   // kicks off a new write thread
+		Close()                   // waits for the write threads to finish
+	}
+
+	// SinkWriter is a pipeline worker which delivers analysis results to a Sink.
+	// Failed writes are logged and the remaining results are still delivered.
+	// Collect waits for the sink, so it is meant for sinks which replace
+	// MongoDB, such as the output of a dry run.
+	SinkWriter struct {
+		sink         Sink             // receives the analysis results
+		log          *log.Logger      // main logger for RITA
+		writeChannel chan BulkChanges // holds analyzed data
+		writeWg      *sync.WaitGroup  // wait for writing to finish
+		writerName   string           // used in error reporting
+	}
+
+	// QueuedSinkWriter is a pipeline worker which delivers analysis results to
+	// a Sink without holding up the other writers. Results are queued and sent
+	// in batches. They are dropped if the queue is full or if the sink keeps
+	// failing, in which case the sink is skipped for a while.
+	QueuedSinkWriter struct {
+		sink         Sink             // receives the analysis results
+		log          *log.Logger      // main logger for RITA
+		writeChannel chan BulkChanges // holds analyzed data
+		writeWg      *sync.WaitGroup  // wait for writing to finish
+		writerName   string           // used in error reporting
+		breaker      *circuitBreaker  // skips the sink after repeated failures
+		dropped      int64            // number of changes which were not delivered
+	}
+
+	// circuitBreaker tracks the failed writes to a sink
+	circuitBreaker struct {
+		lock      sync.Mutex
+		failures  int       // failed writes since the last successful write
+		openUntil time.Time // writes are skipped until this time
+		now       func() time.Time
+	}
+
+	// MultiWriter is a pipeline worker which sends each group of analysis
+	// results to every one of its writers
+	MultiWriter struct {
+		writers []Writer
+	}
+)
+
+// NewWriter creates a writer which applies the analysis results to MongoDB and
+// delivers them to each of the sinks registered with the database. The sinks
+// receive the documents left in MongoDB once the changes are applied rather
+// than the changes themselves. They are written to through a QueuedSinkWriter
+// so that MongoDB is never held up.
+func NewWriter(db *DB, conf *config.Config, log *log.Logger, unorderedWritesOK bool, writerName string) *MultiWriter {
+	return NewReportingWriter(db, conf, log, unorderedWritesOK, writerName, nil)
+}
+
+// NewReportingWriter works like NewWriter but also hands the failed MongoDB
+// writes to report, so the analysis can fail rather than only log them. The
+// deliveries to the sinks are still only logged.
+func NewReportingWriter(db *DB, conf *config.Config, log *log.Logger, unorderedWritesOK bool, writerName string, report func(error)) *MultiWriter {
+	bulkWriter := NewBulkWriter(db, conf, log, unorderedWritesOK, writerName)
+	bulkWriter.report = report
+	if len(db.sinks) > 0 {
+		var sinkWriters []Writer
+		for _, sink := range db.sinks {
+			sinkWriters = append(sinkWriters, NewQueuedSinkWriter(sink, log, writerName))
+		}
+		bulkWriter.results = NewMultiWriter(sinkWriters...)
+	}
+	return NewMultiWriter(bulkWriter)
+}
+
+// NewMultiWriter creates a writer which sends each group of results to all of the writers
+func NewMultiWriter(writers ...Writer) *MultiWriter {
+	return &MultiWriter{writers: writers}
+}
+
+// Collect sends a group of results to each of the writers
+func (w *MultiWriter) Collect(data BulkChanges) {
+	for _, writer := range w.writers {
+		writer.Collect(data)
+	}
+}
+
+// Close waits for each of the writers to finish
+func (w *MultiWriter) Close() {
+	for _, writer := range w.writers {
+		writer.Close()
+	}
+}
+
+// Start kicks off a new write thread for each of the writers
+func (w *MultiWriter) Start() {
+	for _, writer := range w.writers {
+		writer.Start()
+	}
+}
+
+// NewSinkWriter creates a new writer object to deliver output data to a sink
+func NewSinkWriter(sink Sink, log *log.Logger, writerName string) *SinkWriter {
+	return &SinkWriter{
+		sink:         sink,
+		log:          log,
+		writeChannel: make(chan BulkChanges),
+		writeWg:      new(sync.WaitGroup),
+		writerName:   writerName,
+	}
+}
+
+// Collect sends a group of results to the writer for delivery to the sink
+func (w *SinkWriter) Collect(data BulkChanges) {
+	w.writeChannel <- data
+}
+
+// Close waits for the write threads to finish and closes the sink
+func (w *SinkWriter) Close() {
+	close(w.writeChannel)
+	w.writeWg.Wait()
+	closeSink(w.sink, w.log, w.writerName)
+}
+
+// Start kicks off a new write thread
+func (w *SinkWriter) Start() {
+	w.writeWg.Add(1)
+	go func() {
+		defer w.writeWg.Done()
+		for data := range w.writeChannel {
+			// a failing sink must not hold up MongoDB or the other sinks
+			if err := w.sink.Write(data); err != nil {
+				w.log.WithFields(log.Fields{
+					"Module": w.writerName,
+					"Sink":   w.sink.Name(),
+				}).Error(err)
+			}
+		}
+	}()
+}
+
+// NewQueuedSinkWriter creates a new writer object to deliver output data to a
+// sink in the background
+func NewQueuedSinkWriter(sink Sink, log *log.Logger, writerName string) *QueuedSinkWriter {
+	return &QueuedSinkWriter{
+		sink:         sink,
+		log:          log,
+		writeChannel: make(chan BulkChanges, sinkQueueSize),
+		writeWg:      new(sync.WaitGroup),
+		writerName:   writerName,
+		breaker:      &circuitBreaker{now: time.Now},
+	}
+}
+
+// Collect queues a group of results for delivery to the sink. The results
+// are dropped if the queue is full.
+func (w *QueuedSinkWriter) Collect(data BulkChanges) {
+	select {
+	case w.writeChannel <- data:
+	default:
+		atomic.AddInt64(&w.dropped, int64(countChanges(data)))
+	}
+}
+
+// Close waits for the queued results to be delivered and closes the sink
+func (w *QueuedSinkWriter) Close() {
+	close(w.writeChannel)
+	w.writeWg.Wait()
+	closeSink(w.sink, w.log, w.writerName)
+
+	if dropped := atomic.LoadInt64(&w.dropped); dropped > 0 {
+		w.log.WithFields(log.Fields{
+			"Module":  w.writerName,
+			"Sink":    w.sink.Name(),
+			"Dropped": dropped,
+		}).Warn("Some results could not be delivered to the sink")
+	}
+}
+
+// Start kicks off a new write thread
+func (w *QueuedSinkWriter) Start() {
+	w.writeWg.Add(1)
+	go func() {
+		defer w.writeWg.Done()
+		for data := range w.writeChannel {
+			batch := BulkChanges{}
+			size := batch.add(data)
+
+			// send the results which are already waiting along with this group
+		fill:
+			for size < sinkBatchSize {
+				select {
+				case more, ok := <-w.writeChannel:
+					if !ok {
+						break fill
+					}
+					size += batch.add(more)
+				default:
+					break fill
+				}
+			}
+
+			w.write(batch, size)
+		}
+	}()
+}
+
+// write delivers a batch of changes to the sink unless it has been failing
+func (w *QueuedSinkWriter) write(batch BulkChanges, size int) {
+	if !w.breaker.allow() {
+		atomic.AddInt64(&w.dropped, int64(size))
+		return
+	}
+
+	err := w.sink.Write(batch)
+	w.breaker.record(err)
+	if err != nil {
+		atomic.AddInt64(&w.dropped, int64(size))
+		w.log.WithFields(log.Fields{
+			"Module": w.writerName,
+			"Sink":   w.sink.Name(),
+		}).Error(err)
+	}
+}
+
+// closeSink closes a sink, logging the failure since the results already reached MongoDB
+func closeSink(sink Sink, logger *log.Logger, writerName string) {
+	if err := sink.Close(); err != nil {
+		logger.WithFields(log.Fields{
+			"Module": writerName,
+			"Sink":   sink.Name(),
+		}).Error(err)
+	}
+}
+
+// add appends the changes in data to the batch and returns the number of changes added
+func (b BulkChanges) add(data BulkChanges) int {
+	for collection, changes := range data {
+		b[collection] = append(b[collection], changes...)
+	}
+	return countChanges(data)
+}
+
+// countChanges returns the number of changes across all of the collections
+func countChanges(data BulkChanges) int {
+	count := 0
+	for _, changes := range data {
+		count += len(changes)
+	}
+	return count
+}
+
+// allow reports whether the sink may be written to
+func (b *circuitBreaker) allow() bool {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return !b.now().Before(b.openUntil)
+}
+
+// record tracks the result of a write. Once sinkMaxFailures writes have failed
+// in a row, writes are skipped for sinkCooldown. A failure after the cooldown
+// skips the sink again until a write succeeds.
+func (b *circuitBreaker) record(err error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err == nil {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= sinkMaxFailures {
+		b.openUntil = b.now().Add(sinkCooldown)
+	}
+}
+
+// AddSink registers a sink which receives the results of each writer created with NewWriter
+func (d *DB) AddSink(sink Sink) {
+	d.sinks = append(d.sinks, sink)
+}
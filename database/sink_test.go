@@ -0,0 +1,206 @@
+package database
+
+import (
+	"errors"
+	"io/ioutil"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/globalsign/mgo/bson"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSink records the results delivered to it and optionally fails each write
+type fakeSink struct {
+	name   string
+	fail   bool
+	lock   sync.Mutex
+	count  int
+	closed int
+}
+
+func (s *fakeSink) Name() string {
+	return s.name
+}
+
+func (s *fakeSink) Write(data BulkChanges) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.fail {
+		return errors.New("sink unavailable")
+	}
+	for _, changes := range data {
+		s.count += len(changes)
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed++
+	return nil
+}
+
+func TestMultiWriterSinks(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	failing := &fakeSink{name: "failing", fail: true}
+	first := &fakeSink{name: "first"}
+	second := &fakeSink{name: "second"}
+
+	writer := NewMultiWriter(
+		NewSinkWriter(first, logger, "test"),
+		NewSinkWriter(failing, logger, "test"),
+		NewSinkWriter(second, logger, "test"),
+	)
+	writer.Start()
+	writer.Start()
+	for i := 0; i < 10; i++ {
+		writer.Collect(BulkChanges{
+			"uconn":  []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Upsert: true}},
+			"beacon": []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Upsert: true}},
+		})
+	}
+	writer.Close()
+
+	assert.Equal(t, 20, first.count, "every result should reach the first sink")
+	assert.Equal(t, 20, second.count, "a failing sink should not keep results from the other sinks")
+	assert.Equal(t, 1, first.closed, "each sink should be closed with its writer")
+	assert.Equal(t, 1, failing.closed)
+	assert.Equal(t, 1, second.closed)
+
+	require.Len(t, hook.AllEntries(), 10, "each failed write should be logged")
+	entry := hook.LastEntry()
+	assert.Equal(t, log.ErrorLevel, entry.Level)
+	assert.Equal(t, "failing", entry.Data["Sink"])
+	assert.Equal(t, "test", entry.Data["Module"])
+}
+
+func TestNewWriterSinks(t *testing.T) {
+	conf, logger, db, _ := newReadOnlyTestDB(t)
+	logger.Out = ioutil.Discard
+
+	sink := &fakeSink{name: "fake"}
+	db.AddSink(sink)
+
+	// the sinks receive the documents left in MongoDB, so nothing reaches
+	// them when the read-only database discards the changes
+	writer := NewWriter(db, conf, logger, true, "test")
+	writer.Start()
+	writer.Collect(BulkChanges{
+		"uconn": []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Update: map[string]string{}, Upsert: true}},
+	})
+	writer.Close()
+
+	assert.Equal(t, 0, sink.count)
+	assert.Equal(t, 1, sink.closed, "the sink should be closed with the writer")
+}
+
+func TestResultChanges(t *testing.T) {
+	selector := bson.M{"src": "10.0.0.1", "dst": "203.0.113.7"}
+
+	// the document left by an update replaces the selected document
+	change := BulkChange{Selector: selector, Update: bson.M{"$set": bson.M{"score": 0.9}}, Upsert: true}
+	doc := bson.M{"_id": bson.NewObjectId(), "src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9}
+	assert.Equal(t, []BulkChange{{
+		Selector: selector,
+		Update:   bson.M{"src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9},
+		Upsert:   true,
+	}}, resultChanges(change, []bson.M{doc}))
+
+	// the documents matched by a SelectAll update are selected by their ID
+	id := bson.NewObjectId()
+	change = BulkChange{Selector: bson.M{"cid": 1}, Update: bson.M{"$set": bson.M{"cid": 2}}, SelectAll: true}
+	assert.Equal(t, []BulkChange{{
+		Selector: bson.M{"_id": id},
+		Update:   bson.M{"cid": 2},
+		Upsert:   true,
+	}}, resultChanges(change, []bson.M{{"_id": id, "cid": 2}}))
+
+	// removals are passed along without their update
+	change = BulkChange{Selector: selector, Remove: true}
+	assert.Equal(t, []BulkChange{{Selector: selector, Remove: true}}, resultChanges(change, nil))
+}
+
+// blockingSink holds up each write until it is released
+type blockingSink struct {
+	started chan struct{}
+	release chan struct{}
+	lock    sync.Mutex
+	writes  []int
+}
+
+func (s *blockingSink) Name() string {
+	return "blocking"
+}
+
+func (s *blockingSink) Write(data BulkChanges) error {
+	s.started <- struct{}{}
+	<-s.release
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.writes = append(s.writes, countChanges(data))
+	return nil
+}
+
+func (s *blockingSink) Close() error {
+	return nil
+}
+
+func TestQueuedSinkWriterDoesNotBlock(t *testing.T) {
+	logger, hook := test.NewNullLogger()
+
+	sink := &blockingSink{started: make(chan struct{}, sinkQueueSize+2), release: make(chan struct{})}
+	writer := NewQueuedSinkWriter(sink, logger, "test")
+	writer.Start()
+
+	change := BulkChanges{"beacon": []BulkChange{{Selector: map[string]string{"src": "10.0.0.1"}, Upsert: true}}}
+	writer.Collect(change)
+	<-sink.started
+
+	// the sink is stuck on the first write, so the queue fills up and the
+	// remaining results are dropped rather than holding up the caller
+	for i := 0; i < sinkQueueSize+10; i++ {
+		writer.Collect(change)
+	}
+	close(sink.release)
+	writer.Close()
+
+	// the queued results are delivered in batches
+	assert.Equal(t, []int{1, sinkBatchSize, sinkBatchSize}, sink.writes)
+	require.Len(t, hook.AllEntries(), 1)
+	assert.Equal(t, log.WarnLevel, hook.LastEntry().Level)
+	assert.Equal(t, int64(10), hook.LastEntry().Data["Dropped"])
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	breaker := &circuitBreaker{now: func() time.Time { return now }}
+	failure := errors.New("sink unavailable")
+
+	for i := 0; i < sinkMaxFailures-1; i++ {
+		breaker.record(failure)
+	}
+	assert.True(t, breaker.allow(), "the sink should be used until it fails repeatedly")
+
+	breaker.record(failure)
+	assert.False(t, breaker.allow(), "a failing sink should be skipped")
+
+	// the sink is tried again after the cooldown but skipped if it still fails
+	now = now.Add(sinkCooldown)
+	assert.True(t, breaker.allow())
+	breaker.record(failure)
+	assert.False(t, breaker.allow())
+
+	now = now.Add(sinkCooldown)
+	breaker.record(nil)
+	for i := 0; i < sinkMaxFailures-1; i++ {
+		breaker.record(failure)
+	}
+	assert.True(t, breaker.allow(), "a successful write should reset the failures")
+}
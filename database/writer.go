@@ -1,6 +1,7 @@
 package database
 
 import (
+	"fmt"
 	"sync"
 
 	"github.com/activecm/rita/config"
@@ -30,6 +31,8 @@ type (
 		writeChannel chan BulkChanges // holds analyzed data
 		writeWg      *sync.WaitGroup  // wait for writing to finish
 		writerName   string           // used in error reporting
+		report       func(error)      // receives the failed writes in addition to the log (nil only logs them)
+		results      Writer           // receives the documents left by the applied changes (nil skips looking them up)
 		unordered    bool             // if the operations can be applied in any order, MongoDB can run the updates in parallel
 		maxBulkCount int              // max number of changes to include in each bulk update
 		maxBulkSize  int              // max total size of BSON documents making up each bulk update
@@ -103,20 +106,46 @@ func (w *MgoBulkWriter) Collect(data BulkChanges) {
 func (w *MgoBulkWriter) Close() {
 	close(w.writeChannel)
 	w.writeWg.Wait()
+	if w.results != nil {
+		w.results.Close()
+	}
+}
+
+// reportError hands a failed write to report if it is set
+func (w *MgoBulkWriter) reportError(err error) {
+	if w.report != nil {
+		w.report(err)
+	}
 }
 
 // start kicks off a new write thread
 func (w *MgoBulkWriter) Start() {
+	if w.results != nil {
+		w.results.Start()
+	}
 	w.writeWg.Add(1)
 	go func() {
+		if err := w.db.Writable(); err != nil {
+			// drain the channel so the analysis pipeline can shut down normally
+			for range w.writeChannel {
+			}
+			w.log.WithFields(log.Fields{
+				"Module": w.writerName,
+			}).Error(err)
+			w.reportError(err)
+			w.writeWg.Done()
+			return
+		}
+
 		ssn := w.db.Session.Copy()
 		defer ssn.Close()
 
-		bulkBuffers := map[string]*mgo.Bulk{} // stores a mgo.Bulk buffer for each collection
-		bulkBufferSizes := map[string]int{}   // stores the size in bytes of the BSON documents in each mgo.Bulk buffer
-		bulkBufferLengths := map[string]int{} // stores the number of changes stored in each mgo.Bulk buffer
-		var sizeBuffer []byte                 // used (and re-used) for BSON serialization in order to calculate the size of each BSON doc
-		var changeSize int                    // holds the total size of each BSON serialized change before being added to bulkBufferSizes
+		bulkBuffers := map[string]*mgo.Bulk{}          // stores a mgo.Bulk buffer for each collection
+		bulkBufferSizes := map[string]int{}            // stores the size in bytes of the BSON documents in each mgo.Bulk buffer
+		bulkBufferLengths := map[string]int{}          // stores the number of changes stored in each mgo.Bulk buffer
+		bulkBufferChanges := map[string][]BulkChange{} // stores the changes in each mgo.Bulk buffer if their results are needed
+		var sizeBuffer []byte                          // used (and re-used) for BSON serialization in order to calculate the size of each BSON doc
+		var changeSize int                             // holds the total size of each BSON serialized change before being added to bulkBufferSizes
 
 		for data := range w.writeChannel { // process data as it streams into the writer
 			for tgtColl, bulkChanges := range data { // loop through each collection that needs updated
@@ -139,41 +168,114 @@ func (w *MgoBulkWriter) Start() {
 					// if the total size of the bulk buffer would exceed the max size after inserting the current change
 					// run the existing bulk buffer against MongoDB
 					if bulkBufferLengths[tgtColl] >= w.maxBulkCount || bulkBufferSizes[tgtColl]+changeSize >= w.maxBulkSize {
-						info, err := bulkBuffer.Run()
-						if err != nil {
-							w.log.WithFields(log.Fields{
-								"Module":     w.writerName,
-								"Collection": tgtColl,
-								"Info":       info,
-							}).Error(err)
-						}
+						w.run(ssn, tgtColl, bulkBuffer, bulkBufferChanges[tgtColl])
 						// make sure to reset the stats we are tracking about the bulk buffer
 						bulkBufferLengths[tgtColl] = 0
 						bulkBufferSizes[tgtColl] = 0
+						bulkBufferChanges[tgtColl] = nil
 					}
 
 					// insert the change into the bulk buffer and update the stats we are tracking about the bulk buffer
 					change.Apply(bulkBuffer)
 					bulkBufferLengths[tgtColl]++
 					bulkBufferSizes[tgtColl] += changeSize
+					if w.results != nil {
+						bulkBufferChanges[tgtColl] = append(bulkBufferChanges[tgtColl], change)
+					}
 				}
 			}
 		}
 
 		// after the writer is done receiving inputs, make sure to drain all of the buffers before exiting
 		for tgtColl, bulkBuffer := range bulkBuffers {
-			info, err := bulkBuffer.Run()
-			if err != nil {
-				w.log.WithFields(log.Fields{
-					"Module":     w.writerName,
-					"Collection": tgtColl,
-					"Info":       info,
-				}).Error(err)
-			}
+			w.run(ssn, tgtColl, bulkBuffer, bulkBufferChanges[tgtColl])
 
 			bulkBufferLengths[tgtColl] = 0
 			bulkBufferSizes[tgtColl] = 0
+			bulkBufferChanges[tgtColl] = nil
 		}
 		w.writeWg.Done()
 	}()
 }
+
+// run applies a bulk buffer to MongoDB. Once the changes are applied, the
+// documents they left are handed to the results writer.
+func (w *MgoBulkWriter) run(ssn *mgo.Session, tgtColl string, bulkBuffer *mgo.Bulk, changes []BulkChange) {
+	info, err := bulkBuffer.Run()
+	if err != nil {
+		w.log.WithFields(log.Fields{
+			"Module":     w.writerName,
+			"Collection": tgtColl,
+			"Info":       info,
+		}).Error(err)
+		w.reportError(fmt.Errorf("could not write to %s: %w", tgtColl, err))
+		return
+	}
+
+	if w.results == nil || len(changes) == 0 {
+		return
+	}
+	results, err := findResults(ssn.DB(w.db.GetSelectedDB()).C(tgtColl), changes)
+	if err != nil {
+		// the results only feed the sinks, so the analysis carries on
+		w.log.WithFields(log.Fields{
+			"Module":     w.writerName,
+			"Collection": tgtColl,
+		}).Error(fmt.Errorf("could not read the results for the sinks: %w", err))
+	}
+	if len(results) > 0 {
+		w.results.Collect(BulkChanges{tgtColl: results})
+	}
+}
+
+// findResults looks up the documents left in a collection by a group of
+// applied changes. Each document is returned as an upsert which replaces
+// the document matching the selector of its change, while removals are
+// returned as they are. Changes which didn't match a document are left out.
+func findResults(coll *mgo.Collection, changes []BulkChange) ([]BulkChange, error) {
+	var results []BulkChange
+	for _, change := range changes {
+		if change.Remove {
+			results = append(results, resultChanges(change, nil)...)
+			continue
+		}
+
+		var docs []bson.M
+		var err error
+		if change.SelectAll {
+			err = coll.Find(change.Selector).All(&docs)
+		} else {
+			var doc bson.M
+			err = coll.Find(change.Selector).One(&doc)
+			docs = append(docs, doc)
+		}
+		if err == mgo.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return results, err
+		}
+		results = append(results, resultChanges(change, docs)...)
+	}
+	return results, nil
+}
+
+// resultChanges converts the documents left by a change into the changes
+// which deliver them. Documents matched by a SelectAll change are selected
+// by their ID since the selector of the change matches all of them.
+func resultChanges(change BulkChange, docs []bson.M) []BulkChange {
+	if change.Remove {
+		return []BulkChange{{Selector: change.Selector, Remove: true, SelectAll: change.SelectAll}}
+	}
+
+	results := make([]BulkChange, 0, len(docs))
+	for _, doc := range docs {
+		selector := change.Selector
+		if change.SelectAll {
+			selector = bson.M{"_id": doc["_id"]}
+		}
+		delete(doc, "_id")
+		results = append(results, BulkChange{Selector: selector, Update: doc, Upsert: true})
+	}
+	return results
+}
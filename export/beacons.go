@@ -0,0 +1,243 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo/bson"
+)
+
+type (
+	// beaconIter iterates over the beacon documents of a query. It is
+	// satisfied by *mgo.Iter.
+	beaconIter interface {
+		Next(result interface{}) bool
+		Close() error
+	}
+
+	// beaconDoc holds the fields of a beacon or proxy beacon document which
+	// are exported
+	beaconDoc struct {
+		Src            string  `bson:"src"`
+		SrcNetworkName string  `bson:"src_network_name"`
+		Dst            string  `bson:"dst"`
+		DstNetworkName string  `bson:"dst_network_name"`
+		FQDN           string  `bson:"fqdn"`
+		Connections    int64   `bson:"connection_count"`
+		Score          float64 `bson:"score"`
+		Proxy          struct {
+			IP string `bson:"ip"`
+		} `bson:"proxy"`
+		Ts bson.M `bson:"ts"`
+	}
+
+	// beaconRecord is a beacon written as a line of JSON. Type is beacon for
+	// beacons between two hosts and proxy for beacons to an FQDN through a
+	// proxy. Technique is the MITRE ATT&CK technique the beacon is tagged with.
+	beaconRecord struct {
+		Type           string                 `json:"type"`
+		Src            string                 `json:"src"`
+		SrcNetworkName string                 `json:"src_network_name"`
+		Dst            string                 `json:"dst,omitempty"`
+		DstNetworkName string                 `json:"dst_network_name,omitempty"`
+		FQDN           string                 `json:"fqdn,omitempty"`
+		Proxy          string                 `json:"proxy,omitempty"`
+		Connections    int64                  `json:"connection_count"`
+		Score          float64                `json:"score"`
+		Technique      string                 `json:"attack_technique,omitempty"`
+		Ts             map[string]interface{} `json:"ts"`
+	}
+
+	// beaconWriter writes beacon records in one of the BeaconFormats
+	beaconWriter interface {
+		write(record beaconRecord) error
+		flush() error
+	}
+
+	// jsonBeaconWriter writes each beacon record as a line of JSON
+	jsonBeaconWriter struct {
+		encoder *json.Encoder
+	}
+
+	// csvBeaconWriter writes each beacon record as a row of beaconCSVHeader
+	csvBeaconWriter struct {
+		writer *csv.Writer
+	}
+)
+
+// BeaconFormats lists the formats which Beacons can write
+var BeaconFormats = []string{"json", "csv"}
+
+// beaconCSVHeader is the header row of the csv format. The ts columns are
+// read from the ts statistics of each record.
+var beaconCSVHeader = []string{
+	"src", "dst", "proxy", "connection_count", "score",
+	"ts.score", "ts.skew", "ts.dispersion", "ts.range", "ts.mode", "ts.mode_count",
+}
+
+// beaconExportFields selects the exported fields of the beacon documents so
+// the connection timestamps and uids aren't read from MongoDB
+var beaconExportFields = bson.M{
+	"src":              1,
+	"src_network_name": 1,
+	"dst":              1,
+	"dst_network_name": 1,
+	"fqdn":             1,
+	"connection_count": 1,
+	"score":            1,
+	"proxy.ip":         1,
+	"ts":               1,
+}
+
+// Beacons writes the beacons and proxy beacons in the given database scoring
+// above minScore to w. format is json for newline delimited JSON or csv for a
+// table with a row per beacon. The beacons are streamed from MongoDB so large
+// result sets are not held in memory. The addresses are masked by r.
+func Beacons(res *resources.Resources, db string, minScore float64, format string, r *redact.Redactor, w io.Writer) error {
+	writer, err := newBeaconWriter(w, format)
+	if err != nil {
+		return err
+	}
+
+	res.DB.SelectDB(db)
+
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	query := bson.M{"score": bson.M{"$gt": minScore}}
+	tables := []struct {
+		kind      string
+		table     string
+		technique string
+	}{
+		{"beacon", res.Config.T.Beacon.BeaconTable, res.Config.S.Attack.Beacon},
+		{"proxy", res.Config.T.BeaconProxy.BeaconProxyTable, res.Config.S.Attack.ProxyBeacon},
+	}
+
+	for _, t := range tables {
+		iter := ssn.DB(res.DB.GetSelectedDB()).C(t.table).Find(query).Select(beaconExportFields).Sort("-score").Iter()
+		if err := writeBeaconRecords(writer, t.kind, t.technique, r, iter); err != nil {
+			return err
+		}
+	}
+	return writer.flush()
+}
+
+// newBeaconWriter creates a beaconWriter for the given format which writes
+// to w
+func newBeaconWriter(w io.Writer, format string) (beaconWriter, error) {
+	switch format {
+	case "json":
+		return jsonBeaconWriter{encoder: json.NewEncoder(w)}, nil
+	case "csv":
+		writer := csv.NewWriter(w)
+		if err := writer.Write(beaconCSVHeader); err != nil {
+			return nil, err
+		}
+		return csvBeaconWriter{writer: writer}, nil
+	}
+	return nil, fmt.Errorf("unknown beacon format %q: must be one of json or csv", format)
+}
+
+// writeBeaconRecords writes a record of the given type, tagged with the given
+// ATT&CK technique and masked by r, for each document of iter and closes iter
+func writeBeaconRecords(writer beaconWriter, kind string, technique string, r *redact.Redactor, iter beaconIter) error {
+	var doc beaconDoc
+	for iter.Next(&doc) {
+		if err := writer.write(newBeaconRecord(kind, technique, r, doc)); err != nil {
+			iter.Close()
+			return err
+		}
+		doc = beaconDoc{}
+	}
+	return iter.Close()
+}
+
+// newBeaconRecord creates the exported record of a beacon document. The
+// interval frequency tables are left out of the timestamp statistics. The
+// proxy is masked as the destination of the source's connections.
+func newBeaconRecord(kind string, technique string, r *redact.Redactor, doc beaconDoc) beaconRecord {
+	ts := make(map[string]interface{}, len(doc.Ts))
+	for key, value := range doc.Ts {
+		if key == "intervals" || key == "interval_counts" {
+			continue
+		}
+		ts[key] = value
+	}
+
+	return beaconRecord{
+		Type:           kind,
+		Src:            r.Src(doc.Src),
+		SrcNetworkName: doc.SrcNetworkName,
+		Dst:            redactOptional(r.Dst, doc.Dst),
+		DstNetworkName: doc.DstNetworkName,
+		FQDN:           doc.FQDN,
+		Proxy:          redactOptional(r.Dst, doc.Proxy.IP),
+		Connections:    doc.Connections,
+		Score:          doc.Score,
+		Technique:      technique,
+		Ts:             ts,
+	}
+}
+
+// redactOptional masks an address which may be missing from the record.
+// Missing addresses are left empty so they are still omitted.
+func redactOptional(mask func(string) string, ip string) string {
+	if ip == "" {
+		return ip
+	}
+	return mask(ip)
+}
+
+func (j jsonBeaconWriter) write(record beaconRecord) error {
+	return j.encoder.Encode(record)
+}
+
+func (j jsonBeaconWriter) flush() error {
+	return nil
+}
+
+// write writes the record as a row. The dst column holds the FQDN of proxy
+// beacons. Statistics missing from the record are left empty.
+func (c csvBeaconWriter) write(record beaconRecord) error {
+	dst := record.Dst
+	if record.FQDN != "" {
+		dst = record.FQDN
+	}
+
+	row := []string{
+		record.Src,
+		dst,
+		record.Proxy,
+		strconv.FormatInt(record.Connections, 10),
+		formatCSVNumber(record.Score),
+	}
+	for _, column := range beaconCSVHeader[len(row):] {
+		row = append(row, formatCSVNumber(record.Ts[column[len("ts."):]]))
+	}
+	return c.writer.Write(row)
+}
+
+func (c csvBeaconWriter) flush() error {
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// formatCSVNumber formats a number without an exponent so spreadsheets
+// read it as written. Values which aren't numbers are left empty.
+func formatCSVNumber(value interface{}) string {
+	switch v := value.(type) {
+	case int:
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testBeaconIter serves seeded documents like a MongoDB query
+type testBeaconIter struct {
+	docs   []bson.M
+	closed bool
+	err    error
+}
+
+func (i *testBeaconIter) Next(result interface{}) bool {
+	if len(i.docs) == 0 {
+		return false
+	}
+	encoded, err := bson.Marshal(i.docs[0])
+	if err != nil {
+		i.err = err
+		return false
+	}
+	i.docs = i.docs[1:]
+	i.err = bson.Unmarshal(encoded, result)
+	return i.err == nil
+}
+
+func (i *testBeaconIter) Close() error {
+	i.closed = true
+	return i.err
+}
+
+// newTestRedactor creates a redactor which zeroes the internal source and
+// destination addresses if enabled is set
+func newTestRedactor(t *testing.T, enabled bool) *redact.Redactor {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Redaction.Fields = []string{"src", "dst"}
+	conf.S.Redaction.Method = "zero"
+	return redact.NewRedactor(conf, enabled)
+}
+
+func TestWriteBeaconRecords(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newBeaconWriter(&buf, "json")
+	require.Nil(t, err)
+
+	beacons := &testBeaconIter{docs: []bson.M{
+		{
+			"src": "10.0.0.1", "src_network_name": "office",
+			"dst": "203.0.113.1", "dst_network_name": "Public",
+			"connection_count": 1440, "score": 0.95,
+			"ts": bson.M{"score": 0.9, "skew": 0.01, "dispersion": 2, "mode": 60, "intervals": []int64{60}, "interval_counts": []int64{1439}},
+		},
+		{"src": "10.0.0.2", "dst": "203.0.113.2", "connection_count": 48, "score": 0.6},
+	}}
+	require.Nil(t, writeBeaconRecords(writer, "beacon", "", newTestRedactor(t, false), beacons))
+	assert.True(t, beacons.closed)
+
+	proxies := &testBeaconIter{docs: []bson.M{{
+		"src": "10.0.0.3", "src_network_name": "office", "fqdn": "c2.example.com",
+		"proxy": bson.M{"ip": "10.0.0.254"}, "connection_count": 96, "score": 0.85,
+		"ts": bson.M{"score": 0.8, "conns_score": 1.0, "freq_score": 0.7},
+	}}}
+	require.Nil(t, writeBeaconRecords(writer, "proxy", "", newTestRedactor(t, false), proxies))
+
+	// each beacon is a line of JSON
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 3)
+	assert.JSONEq(t, `{
+		"type": "beacon", "src": "10.0.0.1", "src_network_name": "office",
+		"dst": "203.0.113.1", "dst_network_name": "Public",
+		"connection_count": 1440, "score": 0.95,
+		"ts": {"score": 0.9, "skew": 0.01, "dispersion": 2, "mode": 60}
+	}`, lines[0])
+	assert.JSONEq(t, `{
+		"type": "beacon", "src": "10.0.0.2", "src_network_name": "",
+		"dst": "203.0.113.2", "connection_count": 48, "score": 0.6, "ts": {}
+	}`, lines[1], "fields from the previous document must not carry over")
+	assert.JSONEq(t, `{
+		"type": "proxy", "src": "10.0.0.3", "src_network_name": "office",
+		"fqdn": "c2.example.com", "proxy": "10.0.0.254",
+		"connection_count": 96, "score": 0.85,
+		"ts": {"score": 0.8, "conns_score": 1.0, "freq_score": 0.7}
+	}`, lines[2])
+}
+
+func TestWriteBeaconRecordsRedacted(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newBeaconWriter(&buf, "json")
+	require.Nil(t, err)
+
+	beacons := &testBeaconIter{docs: []bson.M{{"src": "10.0.0.1", "dst": "203.0.113.1", "score": 0.95}}}
+	require.Nil(t, writeBeaconRecords(writer, "beacon", "", newTestRedactor(t, true), beacons))
+	proxies := &testBeaconIter{docs: []bson.M{{"src": "10.0.0.3", "fqdn": "c2.example.com", "proxy": bson.M{"ip": "10.0.0.254"}, "score": 0.85}}}
+	require.Nil(t, writeBeaconRecords(writer, "proxy", "", newTestRedactor(t, true), proxies))
+
+	// the internal addresses are masked while the external ones are kept
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var records [2]beaconRecord
+	for i := range lines {
+		require.Nil(t, json.Unmarshal([]byte(lines[i]), &records[i]))
+	}
+	assert.Equal(t, "0.0.0.0", records[0].Src)
+	assert.Equal(t, "203.0.113.1", records[0].Dst)
+	assert.Equal(t, "0.0.0.0", records[1].Src)
+	assert.Equal(t, "0.0.0.0", records[1].Proxy)
+	assert.Empty(t, records[1].Dst, "missing addresses stay missing")
+}
+
+func TestWriteBeaconRecordsError(t *testing.T) {
+	// a failed query is reported when the iterator is closed
+	iter := &testBeaconIter{err: errors.New("collection scan failed")}
+	var buf bytes.Buffer
+	assert.EqualError(t, writeBeaconRecords(jsonBeaconWriter{encoder: json.NewEncoder(&buf)}, "beacon", "", newTestRedactor(t, false), iter), "collection scan failed")
+	assert.Empty(t, buf.String())
+}
+
+func TestWriteBeaconRecordsCSV(t *testing.T) {
+	var buf bytes.Buffer
+	writer, err := newBeaconWriter(&buf, "csv")
+	require.Nil(t, err)
+
+	beacons := &testBeaconIter{docs: []bson.M{
+		{
+			"src": "10.0.0.1", "dst": "203.0.113.1", "connection_count": int64(12000000), "score": 0.95,
+			"ts": bson.M{
+				"score": 0.9, "skew": 0.0000001, "dispersion": int64(0), "range": int64(1),
+				"mode": int64(60), "mode_count": int64(11999999), "intervals": []int64{60},
+			},
+		},
+		// imported before the timestamp statistics were stored
+		{"src": "10.0.0.2", "dst": "203.0.113.2", "connection_count": int64(48), "score": 0.6},
+	}}
+	require.Nil(t, writeBeaconRecords(writer, "beacon", "", newTestRedactor(t, false), beacons))
+
+	proxies := &testBeaconIter{docs: []bson.M{{
+		"src": "10.0.0.3", "fqdn": "c2.example.com", "proxy": bson.M{"ip": "10.0.0.254"},
+		"connection_count": int64(96), "score": 0.85,
+		"ts": bson.M{"score": 0.8, "skew": -0.25, "dispersion": int64(3), "range": int64(20), "mode": int64(900)},
+	}}}
+	require.Nil(t, writeBeaconRecords(writer, "proxy", "", newTestRedactor(t, false), proxies))
+	require.Nil(t, writer.flush())
+
+	golden, err := ioutil.ReadFile(filepath.Join("testdata", "beacons.csv"))
+	require.Nil(t, err)
+	assert.Equal(t, string(golden), buf.String())
+}
+
+func TestWriteBeaconRecordsTechnique(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	var buf bytes.Buffer
+	writer, err := newBeaconWriter(&buf, "json")
+	require.Nil(t, err)
+
+	beacons := &testBeaconIter{docs: []bson.M{{"src": "10.0.0.1", "dst": "203.0.113.1", "score": 0.95}}}
+	require.Nil(t, writeBeaconRecords(writer, "beacon", conf.S.Attack.Beacon, newTestRedactor(t, false), beacons))
+	proxies := &testBeaconIter{docs: []bson.M{{"src": "10.0.0.3", "fqdn": "c2.example.com", "score": 0.85}}}
+	require.Nil(t, writeBeaconRecords(writer, "proxy", conf.S.Attack.ProxyBeacon, newTestRedactor(t, false), proxies))
+
+	// each type of beacon is tagged with its default technique
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 2)
+	var records [2]beaconRecord
+	for i := range lines {
+		require.Nil(t, json.Unmarshal([]byte(lines[i]), &records[i]))
+	}
+	assert.Equal(t, "T1071", records[0].Technique, "beacons use Application Layer Protocol")
+	assert.Equal(t, "T1071.001", records[1].Technique, "proxy beacons use Web Protocols")
+}
+
+func TestBeaconFormat(t *testing.T) {
+	var buf bytes.Buffer
+	_, err := newBeaconWriter(&buf, "xml")
+	assert.NotNil(t, err)
+	assert.Empty(t, buf.String())
+}
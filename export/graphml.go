@@ -0,0 +1,263 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"net"
+	"strconv"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+	"github.com/activecm/rita/util"
+)
+
+// graphMLNamespace is the XML namespace of GraphML documents
+const graphMLNamespace = "http://graphml.graphdrawing.org/xmlns"
+
+type (
+	// graph holds the hosts of a dataset as nodes and the unique connections
+	// between them as directed edges
+	graph struct {
+		name  string
+		nodes []*graphNode
+		edges []graphEdge
+	}
+
+	// graphNode is a single host. Connections and TotalBytes are summed over
+	// the edges touching the host. Score is the highest beacon score among them.
+	// Label is the address of the host as written, which may be masked.
+	graphNode struct {
+		id          string
+		host        data.UniqueIP
+		label       string
+		internal    bool
+		source      bool // the host started one of the unique connections
+		target      bool // the host received one of the unique connections
+		connections int64
+		totalBytes  int64
+		score       float64
+	}
+
+	// graphEdge is a single unique connection. Score is the beacon score of
+	// the connection, or 0 if the connection was not analyzed as a beacon.
+	graphEdge struct {
+		id          string
+		source      string
+		target      string
+		connections int64
+		totalBytes  int64
+		score       float64
+	}
+)
+
+// The types below mirror the elements of a GraphML document
+type (
+	graphMLDoc struct {
+		XMLName xml.Name     `xml:"graphml"`
+		XMLNS   string       `xml:"xmlns,attr"`
+		Keys    []graphMLKey `xml:"key"`
+		Graph   graphMLGraph `xml:"graph"`
+	}
+
+	graphMLKey struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+
+	graphMLGraph struct {
+		ID          string        `xml:"id,attr"`
+		EdgeDefault string        `xml:"edgedefault,attr"`
+		Nodes       []graphMLNode `xml:"node"`
+		Edges       []graphMLEdge `xml:"edge"`
+	}
+
+	graphMLNode struct {
+		ID   string        `xml:"id,attr"`
+		Data []graphMLData `xml:"data"`
+	}
+
+	graphMLEdge struct {
+		ID     string        `xml:"id,attr"`
+		Source string        `xml:"source,attr"`
+		Target string        `xml:"target,attr"`
+		Data   []graphMLData `xml:"data"`
+	}
+
+	graphMLData struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+)
+
+// graphMLKeys declares the attributes of the nodes and edges. Gephi uses the
+// edge attribute named weight when laying out the graph, so it holds the
+// beacon score as well.
+var graphMLKeys = []graphMLKey{
+	{ID: "label", For: "node", Name: "label", Type: "string"},
+	{ID: "network_name", For: "node", Name: "network_name", Type: "string"},
+	{ID: "internal", For: "node", Name: "internal", Type: "boolean"},
+	{ID: "node_score", For: "node", Name: "score", Type: "double"},
+	{ID: "node_connection_count", For: "node", Name: "connection_count", Type: "long"},
+	{ID: "node_total_bytes", For: "node", Name: "total_bytes", Type: "long"},
+	{ID: "weight", For: "edge", Name: "weight", Type: "double"},
+	{ID: "edge_score", For: "edge", Name: "score", Type: "double"},
+	{ID: "edge_connection_count", For: "edge", Name: "connection_count", Type: "long"},
+	{ID: "edge_total_bytes", For: "edge", Name: "total_bytes", Type: "long"},
+}
+
+// GraphML writes the hosts of the given database and the unique connections
+// between them to w as a GraphML graph. Hosts within InternalSubnets are
+// marked as internal, and connections are weighted by their beacon score.
+// limit and noLimit control how many unique connections are included,
+// keeping those with the most connections. The host addresses are masked by r.
+func GraphML(res *resources.Resources, db string, limit int, noLimit bool, r *redact.Redactor, w io.Writer) error {
+	res.DB.SelectDB(db)
+
+	conns, err := uconn.ConnResults(res, limit, noLimit)
+	if err != nil {
+		return err
+	}
+
+	var beacons []beacon.Result
+	if res.Config.S.Beacon.Enabled {
+		beacons, err = beacon.Results(res, 0)
+		if err != nil {
+			return err
+		}
+	}
+
+	internal := util.ParseSubnets(res.Config.S.Filtering.InternalSubnets)
+	return writeGraphML(w, newGraph(db, conns, beacons, internal, r))
+}
+
+// newGraph builds the graph of the given unique connections. Beacons between
+// hosts without a unique connection are ignored. The hosts are classified
+// before their addresses are masked by r.
+func newGraph(name string, conns []uconn.ConnResult, beacons []beacon.Result, internal []*net.IPNet, r *redact.Redactor) *graph {
+	g := &graph{name: name}
+
+	scores := make(map[string]float64, len(beacons))
+	for _, result := range beacons {
+		scores[result.MapKey()] = result.Score
+	}
+
+	nodes := make(map[string]*graphNode)
+	addNode := func(host data.UniqueIP) *graphNode {
+		key := host.MapKey()
+		if node, ok := nodes[key]; ok {
+			return node
+		}
+		ip := net.ParseIP(host.IP)
+		node := &graphNode{
+			id:       "n" + strconv.Itoa(len(g.nodes)),
+			host:     host,
+			internal: ip != nil && util.ContainsIP(internal, ip),
+		}
+		nodes[key] = node
+		g.nodes = append(g.nodes, node)
+		return node
+	}
+
+	for _, conn := range conns {
+		src := addNode(conn.UniqueSrcIP.Unpair())
+		dst := addNode(conn.UniqueDstIP.Unpair())
+		src.source = true
+		dst.target = true
+		score := scores[conn.MapKey()]
+
+		for _, node := range []*graphNode{src, dst} {
+			node.connections += conn.Connections
+			node.totalBytes += conn.TotalBytes
+			if score > node.score {
+				node.score = score
+			}
+		}
+
+		g.edges = append(g.edges, graphEdge{
+			id:          "e" + strconv.Itoa(len(g.edges)),
+			source:      src.id,
+			target:      dst.id,
+			connections: conn.Connections,
+			totalBytes:  conn.TotalBytes,
+			score:       score,
+		})
+	}
+
+	// a host is masked if the addresses of any side it was on are redacted
+	for _, node := range g.nodes {
+		node.label = node.host.IP
+		if node.source {
+			node.label = r.Src(node.label)
+		}
+		if node.target && node.label == node.host.IP {
+			node.label = r.Dst(node.label)
+		}
+	}
+	return g
+}
+
+// writeGraphML writes the graph to w as an indented GraphML document
+func writeGraphML(w io.Writer, g *graph) error {
+	doc := graphMLDoc{
+		XMLNS: graphMLNamespace,
+		Keys:  graphMLKeys,
+		Graph: graphMLGraph{
+			ID:          g.name,
+			EdgeDefault: "directed",
+			Nodes:       make([]graphMLNode, 0, len(g.nodes)),
+			Edges:       make([]graphMLEdge, 0, len(g.edges)),
+		},
+	}
+
+	for _, node := range g.nodes {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphMLNode{
+			ID: node.id,
+			Data: []graphMLData{
+				{Key: "label", Value: node.label},
+				{Key: "network_name", Value: node.host.NetworkName},
+				{Key: "internal", Value: strconv.FormatBool(node.internal)},
+				{Key: "node_score", Value: formatGraphMLDouble(node.score)},
+				{Key: "node_connection_count", Value: strconv.FormatInt(node.connections, 10)},
+				{Key: "node_total_bytes", Value: strconv.FormatInt(node.totalBytes, 10)},
+			},
+		})
+	}
+
+	for _, edge := range g.edges {
+		score := formatGraphMLDouble(edge.score)
+		doc.Graph.Edges = append(doc.Graph.Edges, graphMLEdge{
+			ID:     edge.id,
+			Source: edge.source,
+			Target: edge.target,
+			Data: []graphMLData{
+				{Key: "weight", Value: score},
+				{Key: "edge_score", Value: score},
+				{Key: "edge_connection_count", Value: strconv.FormatInt(edge.connections, 10)},
+				{Key: "edge_total_bytes", Value: strconv.FormatInt(edge.totalBytes, 10)},
+			},
+		})
+	}
+
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	err = enc.Encode(doc)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, "\n")
+	return err
+}
+
+func formatGraphMLDouble(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
@@ -0,0 +1,152 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestConn(src, dst string, connections, totalBytes int64) uconn.ConnResult {
+	return uconn.ConnResult{
+		UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: src}, data.UniqueIP{IP: dst}),
+		Connections:  connections,
+		TotalBytes:   totalBytes,
+	}
+}
+
+// writeTestGraph builds a graph of a small dataset, masked by r, and returns its GraphML
+func writeTestGraph(t *testing.T, r *redact.Redactor) []byte {
+	conns := []uconn.ConnResult{
+		newTestConn("10.0.0.1", "8.8.8.8", 1440, 100000),
+		newTestConn("10.0.0.2", "8.8.8.8", 20, 3000),
+		newTestConn("10.0.0.1", "10.0.0.2", 5, 700),
+	}
+	beacons := []beacon.Result{
+		{UniqueIPPair: conns[0].UniqueIPPair, Score: 0.92},
+		{UniqueIPPair: conns[1].UniqueIPPair, Score: 0.31},
+		// beacons without a unique connection are not part of the graph
+		{UniqueIPPair: newTestConn("10.0.0.9", "1.1.1.1", 0, 0).UniqueIPPair, Score: 0.5},
+	}
+	internal := util.ParseSubnets([]string{"10.0.0.0/8"})
+
+	var buf bytes.Buffer
+	require.Nil(t, writeGraphML(&buf, newGraph("dataset", conns, beacons, internal, r)))
+	return buf.Bytes()
+}
+
+// graphMLDataMap returns the data of a node or edge keyed by attribute id
+func graphMLDataMap(attrs []graphMLData) map[string]string {
+	values := make(map[string]string)
+	for _, attr := range attrs {
+		values[attr.Key] = attr.Value
+	}
+	return values
+}
+
+func TestGraphMLWellFormed(t *testing.T) {
+	out := writeTestGraph(t, newTestRedactor(t, false))
+
+	// every token must decode
+	dec := xml.NewDecoder(bytes.NewReader(out))
+	for {
+		_, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		require.Nil(t, err)
+	}
+
+	var doc graphMLDoc
+	require.Nil(t, xml.Unmarshal(out, &doc))
+	assert.Equal(t, graphMLNamespace, doc.XMLName.Space)
+	assert.Equal(t, "directed", doc.Graph.EdgeDefault)
+	assert.Len(t, doc.Graph.Nodes, 3)
+	assert.Len(t, doc.Graph.Edges, 3)
+
+	// every id is unique and every reference resolves
+	keys := make(map[string]string)
+	for _, key := range doc.Keys {
+		require.NotContains(t, keys, key.ID)
+		keys[key.ID] = key.For
+	}
+	nodes := make(map[string]bool)
+	for _, node := range doc.Graph.Nodes {
+		require.False(t, nodes[node.ID])
+		nodes[node.ID] = true
+		for _, attr := range node.Data {
+			assert.Equal(t, "node", keys[attr.Key], "node data %s should use a node key", attr.Key)
+		}
+	}
+	for _, edge := range doc.Graph.Edges {
+		assert.True(t, nodes[edge.Source], "edge %s source %s should be a node", edge.ID, edge.Source)
+		assert.True(t, nodes[edge.Target], "edge %s target %s should be a node", edge.ID, edge.Target)
+		for _, attr := range edge.Data {
+			assert.Equal(t, "edge", keys[attr.Key], "edge data %s should use an edge key", attr.Key)
+		}
+	}
+}
+
+func TestGraphMLAttributes(t *testing.T) {
+	var doc graphMLDoc
+	require.Nil(t, xml.Unmarshal(writeTestGraph(t, newTestRedactor(t, false)), &doc))
+
+	nodes := make(map[string]map[string]string)
+	for _, node := range doc.Graph.Nodes {
+		values := graphMLDataMap(node.Data)
+		nodes[values["label"]] = values
+	}
+	require.Contains(t, nodes, "10.0.0.1")
+	require.Contains(t, nodes, "8.8.8.8")
+
+	assert.Equal(t, "true", nodes["10.0.0.1"]["internal"])
+	assert.Equal(t, "false", nodes["8.8.8.8"]["internal"])
+
+	// host totals are summed over their connections and keep the highest score
+	assert.Equal(t, "1460", nodes["8.8.8.8"]["node_connection_count"])
+	assert.Equal(t, "103000", nodes["8.8.8.8"]["node_total_bytes"])
+	assert.Equal(t, "0.92", nodes["8.8.8.8"]["node_score"])
+	assert.Equal(t, "0.31", nodes["10.0.0.2"]["node_score"])
+
+	beaconEdge := graphMLDataMap(doc.Graph.Edges[0].Data)
+	assert.Equal(t, "0.92", beaconEdge["weight"])
+	assert.Equal(t, "0.92", beaconEdge["edge_score"])
+	assert.Equal(t, "1440", beaconEdge["edge_connection_count"])
+	assert.Equal(t, "100000", beaconEdge["edge_total_bytes"])
+
+	// connections which were not analyzed as beacons carry no weight
+	internalEdge := graphMLDataMap(doc.Graph.Edges[2].Data)
+	assert.Equal(t, "0", internalEdge["weight"])
+	assert.Equal(t, "5", internalEdge["edge_connection_count"])
+}
+
+func TestGraphMLRedacted(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Redaction.Fields = []string{"src"}
+	conf.S.Redaction.Method = "zero"
+
+	var doc graphMLDoc
+	require.Nil(t, xml.Unmarshal(writeTestGraph(t, redact.NewRedactor(conf, true)), &doc))
+
+	// 10.0.0.2 received a connection as well, but it started one too so it
+	// is masked as a source. The hosts are classified before they are masked.
+	labels := make(map[string]int)
+	for _, node := range doc.Graph.Nodes {
+		values := graphMLDataMap(node.Data)
+		labels[values["label"]]++
+		if values["label"] == "0.0.0.0" {
+			assert.Equal(t, "true", values["internal"])
+		}
+	}
+	assert.Equal(t, map[string]int{"0.0.0.0": 2, "8.8.8.8": 1}, labels)
+}
@@ -0,0 +1,140 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+)
+
+// HostSet limits an export to the results involving a set of hosts and
+// networks. A nil HostSet holds every host.
+type HostSet struct {
+	networks []*net.IPNet
+}
+
+// LoadHostSet reads the host list at path
+func LoadHostSet(path string) (*HostSet, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	hosts, err := ParseHostSet(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return hosts, nil
+}
+
+// ParseHostSet reads a host list holding an IP address or CIDR range on each
+// line. Blank lines and lines starting with # are skipped.
+func ParseHostSet(reader io.Reader) (*HostSet, error) {
+	hosts := &HostSet{}
+
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if ip := net.ParseIP(line); ip != nil {
+			bits := 8 * net.IPv6len
+			if ipv4 := ip.To4(); ipv4 != nil {
+				ip = ipv4
+				bits = 8 * net.IPv4len
+			}
+			hosts.networks = append(hosts.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s is not an IP address or CIDR range", lineNum, line)
+		}
+		hosts.networks = append(hosts.networks, network)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return hosts, nil
+}
+
+// Contains reports whether the address is one of the hosts or falls in one
+// of the networks
+func (h *HostSet) Contains(address string) bool {
+	if h == nil {
+		return true
+	}
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return false
+	}
+	return util.ContainsIP(h.networks, ip)
+}
+
+// containsAny reports whether any of the hosts are in the set
+func (h *HostSet) containsAny(hosts []data.UniqueIP) bool {
+	for _, host := range hosts {
+		if h.Contains(host.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// filterBeacons keeps the beacons whose source or destination is in the set
+func (h *HostSet) filterBeacons(results []beacon.Result) []beacon.Result {
+	if h == nil {
+		return results
+	}
+	var filtered []beacon.Result
+	for _, result := range results {
+		if h.Contains(result.SrcIP) || h.Contains(result.DstIP) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// filterBlacklistIPs keeps the blacklisted IPs which are in the set or which
+// connected with a host in the set
+func (h *HostSet) filterBlacklistIPs(results []blacklist.IPResult) []blacklist.IPResult {
+	if h == nil {
+		return results
+	}
+	var filtered []blacklist.IPResult
+	for _, result := range results {
+		if h.Contains(result.Host.IP) || h.containsAny(result.Peers) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
+
+// filterBlacklistHostnames keeps the blacklisted hostnames which a host in the
+// set connected to
+func (h *HostSet) filterBlacklistHostnames(results []blacklist.HostnameResult) []blacklist.HostnameResult {
+	if h == nil {
+		return results
+	}
+	var filtered []blacklist.HostnameResult
+	for _, result := range results {
+		if h.containsAny(result.ConnectedHosts) {
+			filtered = append(filtered, result)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,96 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testHostList = `
+# incident scope
+10.0.1.0/24
+192.168.5.7
+fd00::10
+`
+
+func TestParseHostSet(t *testing.T) {
+	hosts, err := ParseHostSet(strings.NewReader(testHostList))
+	require.Nil(t, err)
+
+	assert.True(t, hosts.Contains("10.0.1.1"))
+	assert.True(t, hosts.Contains("10.0.1.255"))
+	assert.True(t, hosts.Contains("192.168.5.7"))
+	assert.True(t, hosts.Contains("fd00::10"))
+	assert.False(t, hosts.Contains("10.0.2.1"))
+	assert.False(t, hosts.Contains("192.168.5.8"))
+	assert.False(t, hosts.Contains("not-an-address"))
+
+	// a nil set holds every host
+	var all *HostSet
+	assert.True(t, all.Contains("10.0.2.1"))
+
+	_, err = ParseHostSet(strings.NewReader("10.0.1.0/24\nworkstation-7\n"))
+	assert.EqualError(t, err, "line 2: workstation-7 is not an IP address or CIDR range")
+}
+
+func TestHostSetFilters(t *testing.T) {
+	hosts, err := ParseHostSet(strings.NewReader(testHostList))
+	require.Nil(t, err)
+
+	newBeacon := func(src, dst string) beacon.Result {
+		return beacon.Result{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: src}, data.UniqueIP{IP: dst})}
+	}
+	beacons := []beacon.Result{
+		newBeacon("10.0.1.20", "8.8.8.8"),
+		newBeacon("10.0.2.20", "8.8.8.8"),
+		newBeacon("8.8.4.4", "192.168.5.7"),
+	}
+	assert.Equal(t, []beacon.Result{beacons[0], beacons[2]}, hosts.filterBeacons(beacons))
+
+	blIPs := []blacklist.IPResult{
+		{Host: data.UniqueIP{IP: "198.51.100.1"}, Peers: []data.UniqueIP{{IP: "10.0.2.1"}, {IP: "10.0.1.9"}}},
+		{Host: data.UniqueIP{IP: "198.51.100.2"}, Peers: []data.UniqueIP{{IP: "10.0.2.1"}}},
+		{Host: data.UniqueIP{IP: "192.168.5.7"}},
+	}
+	assert.Equal(t, []blacklist.IPResult{blIPs[0], blIPs[2]}, hosts.filterBlacklistIPs(blIPs))
+
+	blHostnames := []blacklist.HostnameResult{
+		{Host: "bad.example.com", ConnectedHosts: []data.UniqueIP{{IP: "fd00::10"}}},
+		{Host: "worse.example.com", ConnectedHosts: []data.UniqueIP{{IP: "10.0.2.1"}}},
+	}
+	assert.Equal(t, blHostnames[:1], hosts.filterBlacklistHostnames(blHostnames))
+
+	// without a host list every result is exported
+	var all *HostSet
+	assert.Equal(t, beacons, all.filterBeacons(beacons))
+	assert.Equal(t, blIPs, all.filterBlacklistIPs(blIPs))
+	assert.Equal(t, blHostnames, all.filterBlacklistHostnames(blHostnames))
+}
+
+func TestHostScopedBeaconRows(t *testing.T) {
+	hosts, err := ParseHostSet(strings.NewReader("10.0.1.0/24\n"))
+	require.Nil(t, err)
+
+	results := []beacon.Result{
+		{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.1.20"}, data.UniqueIP{IP: "8.8.8.8"}), Score: 0.9},
+		{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.2.20"}, data.UniqueIP{IP: "1.1.1.1"}), Score: 0.8},
+	}
+
+	// only the beacon of the listed host is written
+	rows := newBeaconRows(hosts.filterBeacons(results), "")
+	out := make([]beaconRow, len(rows))
+	roundTrip(t, new(beaconRow), rows, &out)
+	require.Len(t, out, 1)
+	assert.Equal(t, "10.0.1.20", out[0].Src)
+	assert.Equal(t, "8.8.8.8", out[0].Dst)
+
+	// the DNS results can't be tied to a host
+	for _, table := range tables {
+		assert.Equal(t, table.name != "exploded-dns", table.perHost, table.name)
+	}
+}
@@ -0,0 +1,169 @@
+package export
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/explodeddns"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/resources"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+)
+
+// parquetParallelism is the number of goroutines used to marshal each file
+const parquetParallelism = 4
+
+// table is a set of results which is exported as a single file
+type table struct {
+	name    string
+	enabled func(res *resources.Resources) bool
+	schema  interface{}
+	rows    func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error)
+	perHost bool // whether the results can be limited to a HostSet
+	// technique selects the MITRE ATT&CK technique the rows are tagged with.
+	// Tables without one are not tagged.
+	technique func(res *resources.Resources) string
+}
+
+// tables lists the results which are exported from each database
+var tables = []table{
+	{
+		name:    "beacons",
+		enabled: func(res *resources.Resources) bool { return res.Config.S.Beacon.Enabled },
+		schema:  new(beaconRow),
+		rows: func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error) {
+			results, err := beacon.Results(res, 0)
+			results = hosts.filterBeacons(results)
+			redactBeacons(r, results)
+			return newBeaconRows(results, technique), err
+		},
+		perHost:   true,
+		technique: func(res *resources.Resources) string { return res.Config.S.Attack.Beacon },
+	},
+	{
+		name:    "exploded-dns",
+		enabled: func(res *resources.Resources) bool { return res.Config.S.DNS.Enabled },
+		schema:  new(explodedDNSRow),
+		rows: func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error) {
+			results, err := explodeddns.Results(res, 0, true)
+			return newExplodedDNSRows(results), err
+		},
+	},
+	{
+		name:    "bl-source-ips",
+		enabled: func(res *resources.Resources) bool { return res.Config.S.Blacklisted.Enabled },
+		schema:  new(blacklistIPRow),
+		rows: func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error) {
+			results, err := blacklist.SrcIPResults(res, "conn_count", 0, true)
+			results = hosts.filterBlacklistIPs(results)
+			redactBlacklistIPs(r.Src, results)
+			return newBlacklistIPRows(results, technique), err
+		},
+		perHost:   true,
+		technique: func(res *resources.Resources) string { return res.Config.S.Attack.Blacklisted },
+	},
+	{
+		name:    "bl-dest-ips",
+		enabled: func(res *resources.Resources) bool { return res.Config.S.Blacklisted.Enabled },
+		schema:  new(blacklistIPRow),
+		rows: func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error) {
+			results, err := blacklist.DstIPResults(res, "conn_count", 0, true)
+			results = hosts.filterBlacklistIPs(results)
+			redactBlacklistIPs(r.Dst, results)
+			return newBlacklistIPRows(results, technique), err
+		},
+		perHost:   true,
+		technique: func(res *resources.Resources) string { return res.Config.S.Attack.Blacklisted },
+	},
+	{
+		name:    "bl-hostnames",
+		enabled: func(res *resources.Resources) bool { return res.Config.S.Blacklisted.Enabled },
+		schema:  new(blacklistHostnameRow),
+		rows: func(res *resources.Resources, hosts *HostSet, technique string, r *redact.Redactor) ([]interface{}, error) {
+			results, err := blacklist.HostnameResults(res, "conn_count", 0, true)
+			return newBlacklistHostnameRows(hosts.filterBlacklistHostnames(results), technique), err
+		},
+		perHost:   true,
+		technique: func(res *resources.Resources) string { return res.Config.S.Attack.Blacklisted },
+	},
+}
+
+// Parquet writes the beacon, DNS, and blacklist results of the given database
+// to Parquet files in dir, one file per result type. The results of disabled
+// modules are skipped. If hosts is not nil, only the results involving the
+// hosts are written and the DNS results, which can't be tied to a host, are
+// skipped. The addresses are masked by r after the results are limited to the
+// hosts. The paths of the written files are returned.
+func Parquet(res *resources.Resources, db string, dir string, hosts *HostSet, r *redact.Redactor) ([]string, error) {
+	res.DB.SelectDB(db)
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, t := range tables {
+		if !t.enabled(res) || (hosts != nil && !t.perHost) {
+			continue
+		}
+
+		rows, err := t.rows(res, hosts, t.attackTechnique(res), r)
+		if err != nil {
+			return paths, err
+		}
+
+		path := filepath.Join(dir, t.name+".parquet")
+		err = writeParquetFile(path, t.schema, rows)
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
+
+// attackTechnique returns the ATT&CK technique the rows of the table are
+// tagged with, or an empty string if they aren't tagged
+func (t table) attackTechnique(res *resources.Resources) string {
+	if t.technique == nil {
+		return ""
+	}
+	return t.technique(res)
+}
+
+func writeParquetFile(path string, schema interface{}, rows []interface{}) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = writeParquet(f, schema, rows)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// writeParquet writes the rows to w as a Snappy compressed Parquet file.
+// schema must be a pointer to the row type.
+func writeParquet(w io.Writer, schema interface{}, rows []interface{}) error {
+	pw, err := writer.NewParquetWriterFromWriter(w, schema, parquetParallelism)
+	if err != nil {
+		return err
+	}
+	pw.CompressionType = parquet.CompressionCodec_SNAPPY
+
+	for _, row := range rows {
+		err = pw.Write(row)
+		if err != nil {
+			return err
+		}
+	}
+	return pw.WriteStop()
+}
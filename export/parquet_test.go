@@ -0,0 +1,202 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/explodeddns"
+	"github.com/activecm/rita/resources"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/reader"
+	"github.com/xitongsys/parquet-go/schema"
+	"github.com/xitongsys/parquet-go/source"
+)
+
+// memFile is a read only source.ParquetFile over an in memory Parquet file
+type memFile struct {
+	*bytes.Reader
+	buf []byte
+}
+
+func newMemFile(buf []byte) *memFile {
+	return &memFile{Reader: bytes.NewReader(buf), buf: buf}
+}
+
+func (f *memFile) Open(string) (source.ParquetFile, error)   { return newMemFile(f.buf), nil }
+func (f *memFile) Create(string) (source.ParquetFile, error) { return nil, assert.AnError }
+func (f *memFile) Write([]byte) (int, error)                 { return 0, assert.AnError }
+func (f *memFile) Close() error                              { return nil }
+
+// roundTrip writes the rows as Parquet and reads them back into out, which must
+// be a pointer to a slice of the row type. The reader fills as many rows as the
+// slice holds, so it must be sized to the number of rows written. The schema of
+// the file is returned.
+func roundTrip(t *testing.T, obj interface{}, rows []interface{}, out interface{}) *schema.SchemaHandler {
+	var buf bytes.Buffer
+	require.Nil(t, writeParquet(&buf, obj, rows))
+
+	pr, err := reader.NewParquetReader(newMemFile(buf.Bytes()), obj, parquetParallelism)
+	require.Nil(t, err)
+	defer pr.ReadStop()
+
+	require.Equal(t, int64(len(rows)), pr.GetNumRows())
+	require.Nil(t, pr.Read(out))
+	return pr.SchemaHandler
+}
+
+// columnTypes maps the name of each column in the file to its physical type
+func columnTypes(sh *schema.SchemaHandler) map[string]parquet.Type {
+	types := make(map[string]parquet.Type)
+	// the first element is the root of the schema
+	for i := 1; i < len(sh.SchemaElements); i++ {
+		types[sh.Infos[i].ExName] = sh.SchemaElements[i].GetType()
+	}
+	return types
+}
+
+func TestBeaconsParquetRoundTrip(t *testing.T) {
+	results := []beacon.Result{
+		{
+			UniqueIPPair: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0.1", NetworkName: "lan"},
+				data.UniqueIP{IP: "8.8.8.8", NetworkName: "internet"},
+			),
+			Connections: 1440,
+			AvgBytes:    512.5,
+			TotalBytes:  738000,
+			Ts:          beacon.TSData{Score: 0.99, Range: 60, Mode: 60, ModeCount: 1400, Skew: 0.01, Dispersion: 1},
+			Ds:          beacon.DSData{Score: 0.98, Range: 12, Mode: 512, ModeCount: 1300, Skew: 0.02, Dispersion: 3},
+			DurScore:    1,
+			HistScore:   0.95,
+			Score:       0.981,
+		},
+		{
+			UniqueIPPair: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0.2"},
+				data.UniqueIP{IP: "1.1.1.1"},
+			),
+			Connections: 24,
+			Score:       0.4,
+		},
+	}
+
+	rows := newBeaconRows(results, "T1071")
+	out := make([]beaconRow, len(rows))
+	sh := roundTrip(t, new(beaconRow), rows, &out)
+
+	for i := range rows {
+		assert.Equal(t, rows[i], out[i])
+	}
+	assert.Equal(t, "10.0.0.1", out[0].Src)
+	assert.Equal(t, "internet", out[0].DstNetworkName)
+	assert.Equal(t, int64(1400), out[0].TsModeCount)
+	assert.Equal(t, "T1071", out[1].Technique)
+
+	types := columnTypes(sh)
+	assert.Equal(t, parquet.Type_DOUBLE, types["score"])
+	assert.Equal(t, parquet.Type_DOUBLE, types["ts_score"])
+	assert.Equal(t, parquet.Type_INT64, types["connection_count"])
+	assert.Equal(t, parquet.Type_INT64, types["total_bytes"])
+	assert.Equal(t, parquet.Type_BYTE_ARRAY, types["src"])
+	assert.Equal(t, parquet.Type_BYTE_ARRAY, types["attack_technique"])
+}
+
+func TestExplodedDNSParquetRoundTrip(t *testing.T) {
+	rows := newExplodedDNSRows([]explodeddns.Result{
+		{Domain: "example.com", SubdomainCount: 3, Visited: 120},
+		{Domain: "a.example.com", SubdomainCount: 1, Visited: 40},
+	})
+
+	out := make([]explodedDNSRow, len(rows))
+	sh := roundTrip(t, new(explodedDNSRow), rows, &out)
+
+	for i := range rows {
+		assert.Equal(t, rows[i], out[i])
+	}
+
+	types := columnTypes(sh)
+	assert.Equal(t, parquet.Type_INT64, types["subdomain_count"])
+	assert.Equal(t, parquet.Type_INT64, types["visited"])
+}
+
+func TestBlacklistParquetRoundTrip(t *testing.T) {
+	ipRows := newBlacklistIPRows([]blacklist.IPResult{
+		{
+			Host:              data.UniqueIP{IP: "203.0.113.7", NetworkName: "internet"},
+			Connections:       30,
+			UniqueConnections: 2,
+			TotalBytes:        4096,
+		},
+	}, "T1071")
+
+	ipOut := make([]blacklistIPRow, len(ipRows))
+	sh := roundTrip(t, new(blacklistIPRow), ipRows, &ipOut)
+	assert.Equal(t, ipRows[0], ipOut[0])
+	assert.Equal(t, "T1071", ipOut[0].Technique)
+	assert.Equal(t, parquet.Type_INT64, columnTypes(sh)["conn_count"])
+
+	hostnameRows := newBlacklistHostnameRows([]blacklist.HostnameResult{
+		{Host: "bad.example.com", Connections: 12, UniqueConnections: 3, TotalBytes: 900},
+	}, "")
+
+	hostnameOut := make([]blacklistHostnameRow, len(hostnameRows))
+	roundTrip(t, new(blacklistHostnameRow), hostnameRows, &hostnameOut)
+	assert.Equal(t, hostnameRows[0], hostnameOut[0])
+	assert.Empty(t, hostnameOut[0].Technique, "an empty technique leaves the finding untagged")
+}
+
+func TestRedactParquetRows(t *testing.T) {
+	r := newTestRedactor(t, true)
+
+	beacons := []beacon.Result{{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "203.0.113.7"})}}
+	redactBeacons(r, beacons)
+	rows := newBeaconRows(beacons, "")
+	assert.Equal(t, "0.0.0.0", rows[0].(beaconRow).Src)
+	assert.Equal(t, "203.0.113.7", rows[0].(beaconRow).Dst, "external addresses are kept")
+
+	// blacklisted addresses are masked as the side of the connection they were on
+	ips := []blacklist.IPResult{{Host: data.UniqueIP{IP: "10.0.0.2"}}}
+	redactBlacklistIPs(r.Dst, ips)
+	assert.Equal(t, "0.0.0.0", ips[0].Host.IP)
+}
+
+func TestParquetEmptyTable(t *testing.T) {
+	// a dataset without results still produces a readable file
+	var out []explodedDNSRow
+	sh := roundTrip(t, new(explodedDNSRow), newExplodedDNSRows(nil), &out)
+	assert.Len(t, out, 0)
+	assert.Contains(t, columnTypes(sh), "domain")
+}
+
+func TestParquetTechniques(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	res := &resources.Resources{Config: conf}
+
+	// each table is tagged with the default technique of its type of finding
+	expected := map[string]string{
+		"beacons":       "T1071",
+		"exploded-dns":  "",
+		"bl-source-ips": "T1071",
+		"bl-dest-ips":   "T1071",
+		"bl-hostnames":  "T1071",
+	}
+	for _, table := range tables {
+		assert.Equal(t, expected[table.name], table.attackTechnique(res), table.name)
+	}
+
+	// the techniques can be overridden
+	conf.S.Attack.Blacklisted = "T1102"
+	for _, table := range tables {
+		if strings.HasPrefix(table.name, "bl-") {
+			assert.Equal(t, "T1102", table.attackTechnique(res), table.name)
+		}
+	}
+}
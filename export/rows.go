@@ -0,0 +1,156 @@
+package export
+
+import (
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/explodeddns"
+	"github.com/activecm/rita/pkg/redact"
+)
+
+// The row types below define the Parquet schema of each exported table.
+// Scores are written as DOUBLE and counts as INT64 so the files can be
+// queried without casting. The attack_technique column holds the MITRE ATT&CK
+// technique the finding is tagged with.
+type (
+	// beaconRow is a single beacon in beacons.parquet
+	beaconRow struct {
+		Src            string  `parquet:"name=src, type=BYTE_ARRAY, convertedtype=UTF8"`
+		SrcNetworkName string  `parquet:"name=src_network_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Dst            string  `parquet:"name=dst, type=BYTE_ARRAY, convertedtype=UTF8"`
+		DstNetworkName string  `parquet:"name=dst_network_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Connections    int64   `parquet:"name=connection_count, type=INT64"`
+		AvgBytes       float64 `parquet:"name=avg_bytes, type=DOUBLE"`
+		TotalBytes     int64   `parquet:"name=total_bytes, type=INT64"`
+		TsScore        float64 `parquet:"name=ts_score, type=DOUBLE"`
+		TsRange        int64   `parquet:"name=ts_range, type=INT64"`
+		TsMode         int64   `parquet:"name=ts_mode, type=INT64"`
+		TsModeCount    int64   `parquet:"name=ts_mode_count, type=INT64"`
+		TsSkew         float64 `parquet:"name=ts_skew, type=DOUBLE"`
+		TsDispersion   int64   `parquet:"name=ts_dispersion, type=INT64"`
+		DsScore        float64 `parquet:"name=ds_score, type=DOUBLE"`
+		DsRange        int64   `parquet:"name=ds_range, type=INT64"`
+		DsMode         int64   `parquet:"name=ds_mode, type=INT64"`
+		DsModeCount    int64   `parquet:"name=ds_mode_count, type=INT64"`
+		DsSkew         float64 `parquet:"name=ds_skew, type=DOUBLE"`
+		DsDispersion   int64   `parquet:"name=ds_dispersion, type=INT64"`
+		DurScore       float64 `parquet:"name=duration_score, type=DOUBLE"`
+		HistScore      float64 `parquet:"name=hist_score, type=DOUBLE"`
+		Score          float64 `parquet:"name=score, type=DOUBLE"`
+		Technique      string  `parquet:"name=attack_technique, type=BYTE_ARRAY, convertedtype=UTF8"`
+	}
+
+	// explodedDNSRow is a single domain in exploded-dns.parquet
+	explodedDNSRow struct {
+		Domain         string `parquet:"name=domain, type=BYTE_ARRAY, convertedtype=UTF8"`
+		SubdomainCount int64  `parquet:"name=subdomain_count, type=INT64"`
+		Visited        int64  `parquet:"name=visited, type=INT64"`
+	}
+
+	// blacklistIPRow is a single blacklisted IP in bl-source-ips.parquet
+	// and bl-dest-ips.parquet
+	blacklistIPRow struct {
+		IP                string `parquet:"name=ip, type=BYTE_ARRAY, convertedtype=UTF8"`
+		NetworkName       string `parquet:"name=network_name, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Connections       int64  `parquet:"name=conn_count, type=INT64"`
+		UniqueConnections int64  `parquet:"name=uconn_count, type=INT64"`
+		TotalBytes        int64  `parquet:"name=total_bytes, type=INT64"`
+		Technique         string `parquet:"name=attack_technique, type=BYTE_ARRAY, convertedtype=UTF8"`
+	}
+
+	// blacklistHostnameRow is a single blacklisted hostname in bl-hostnames.parquet
+	blacklistHostnameRow struct {
+		Host              string `parquet:"name=host, type=BYTE_ARRAY, convertedtype=UTF8"`
+		Connections       int64  `parquet:"name=conn_count, type=INT64"`
+		UniqueConnections int64  `parquet:"name=uconn_count, type=INT64"`
+		TotalBytes        int64  `parquet:"name=total_bytes, type=INT64"`
+		Technique         string `parquet:"name=attack_technique, type=BYTE_ARRAY, convertedtype=UTF8"`
+	}
+)
+
+// redactBeacons masks the addresses of the beacon results in place
+func redactBeacons(r *redact.Redactor, results []beacon.Result) {
+	for i := range results {
+		results[i].UniqueIPPair = r.Pair(results[i].UniqueIPPair)
+	}
+}
+
+// redactBlacklistIPs masks the blacklisted addresses in place. mask is the
+// Redactor method for the side of the connections the addresses were on.
+func redactBlacklistIPs(mask func(string) string, results []blacklist.IPResult) {
+	for i := range results {
+		results[i].Host.IP = mask(results[i].Host.IP)
+	}
+}
+
+func newBeaconRows(results []beacon.Result, technique string) []interface{} {
+	rows := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, beaconRow{
+			Src:            result.SrcIP,
+			SrcNetworkName: result.SrcNetworkName,
+			Dst:            result.DstIP,
+			DstNetworkName: result.DstNetworkName,
+			Connections:    result.Connections,
+			AvgBytes:       result.AvgBytes,
+			TotalBytes:     result.TotalBytes,
+			TsScore:        result.Ts.Score,
+			TsRange:        result.Ts.Range,
+			TsMode:         result.Ts.Mode,
+			TsModeCount:    result.Ts.ModeCount,
+			TsSkew:         result.Ts.Skew,
+			TsDispersion:   result.Ts.Dispersion,
+			DsScore:        result.Ds.Score,
+			DsRange:        result.Ds.Range,
+			DsMode:         result.Ds.Mode,
+			DsModeCount:    result.Ds.ModeCount,
+			DsSkew:         result.Ds.Skew,
+			DsDispersion:   result.Ds.Dispersion,
+			DurScore:       result.DurScore,
+			HistScore:      result.HistScore,
+			Score:          result.Score,
+			Technique:      technique,
+		})
+	}
+	return rows
+}
+
+func newExplodedDNSRows(results []explodeddns.Result) []interface{} {
+	rows := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, explodedDNSRow{
+			Domain:         result.Domain,
+			SubdomainCount: result.SubdomainCount,
+			Visited:        result.Visited,
+		})
+	}
+	return rows
+}
+
+func newBlacklistIPRows(results []blacklist.IPResult, technique string) []interface{} {
+	rows := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, blacklistIPRow{
+			IP:                result.Host.IP,
+			NetworkName:       result.Host.NetworkName,
+			Connections:       int64(result.Connections),
+			UniqueConnections: int64(result.UniqueConnections),
+			TotalBytes:        int64(result.TotalBytes),
+			Technique:         technique,
+		})
+	}
+	return rows
+}
+
+func newBlacklistHostnameRows(results []blacklist.HostnameResult, technique string) []interface{} {
+	rows := make([]interface{}, 0, len(results))
+	for _, result := range results {
+		rows = append(rows, blacklistHostnameRow{
+			Host:              result.Host,
+			Connections:       int64(result.Connections),
+			UniqueConnections: int64(result.UniqueConnections),
+			TotalBytes:        int64(result.TotalBytes),
+			Technique:         technique,
+		})
+	}
+	return rows
+}
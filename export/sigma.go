@@ -0,0 +1,160 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/resources"
+	"github.com/google/uuid"
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// sigmaHighScore is the beacon score at which rules are given a high level
+	sigmaHighScore = 0.9
+
+	// sigmaMaxSources limits how many beaconing sources are named in the
+	// description of a rule
+	sigmaMaxSources = 10
+)
+
+// sigmaNamespace namespaces the IDs of the generated rules so that the rule
+// for a destination keeps the same ID each time it is exported
+var sigmaNamespace = uuid.NewSHA1(uuid.NameSpaceURL, []byte("https://github.com/activecm/rita/sigma"))
+
+type (
+	// sigmaRule is a Sigma detection rule. The fields are written in the
+	// order recommended by the Sigma specification.
+	sigmaRule struct {
+		Title          string         `yaml:"title"`
+		ID             string         `yaml:"id"`
+		Status         string         `yaml:"status"`
+		Description    string         `yaml:"description"`
+		Author         string         `yaml:"author"`
+		Date           string         `yaml:"date"`
+		Tags           []string       `yaml:"tags"`
+		LogSource      sigmaLogSource `yaml:"logsource"`
+		Detection      yaml.MapSlice  `yaml:"detection"`
+		Fields         []string       `yaml:"fields"`
+		FalsePositives []string       `yaml:"falsepositives"`
+		Level          string         `yaml:"level"`
+	}
+
+	sigmaLogSource struct {
+		Product string `yaml:"product"`
+		Service string `yaml:"service"`
+	}
+
+	// beaconDestination gathers the beacons to a single destination
+	beaconDestination struct {
+		host     data.UniqueIP
+		sources  []data.UniqueIP
+		maxScore float64
+	}
+)
+
+// Sigma writes a Sigma rule to w for each destination of the beacons in the
+// given database scoring above minScore. Each rule matches Zeek conn records
+// of traffic to the destination and is preceded by a comment holding the
+// highest score of the beacons to it. The addresses are masked by r.
+func Sigma(res *resources.Resources, db string, minScore float64, r *redact.Redactor, w io.Writer) error {
+	res.DB.SelectDB(db)
+
+	beacons, err := beacon.Results(res, minScore)
+	if err != nil {
+		return err
+	}
+	if len(beacons) == 0 {
+		return fmt.Errorf("no beacons scoring above %v were found in %s", minScore, db)
+	}
+	redactBeacons(r, beacons)
+	return writeSigma(w, db, groupBeaconDestinations(beacons), time.Now())
+}
+
+// groupBeaconDestinations groups the beacons by their destination, ordered by
+// the highest score of the beacons to each destination
+func groupBeaconDestinations(beacons []beacon.Result) []*beaconDestination {
+	var dsts []*beaconDestination
+	byKey := make(map[string]*beaconDestination)
+	for _, result := range beacons {
+		host := result.UniqueDstIP.Unpair()
+		dst, ok := byKey[host.MapKey()]
+		if !ok {
+			dst = &beaconDestination{host: host}
+			byKey[host.MapKey()] = dst
+			dsts = append(dsts, dst)
+		}
+		dst.sources = append(dst.sources, result.UniqueSrcIP.Unpair())
+		if result.Score > dst.maxScore {
+			dst.maxScore = result.Score
+		}
+	}
+
+	sort.SliceStable(dsts, func(i, j int) bool {
+		if dsts[i].maxScore != dsts[j].maxScore {
+			return dsts[i].maxScore > dsts[j].maxScore
+		}
+		return dsts[i].host.IP < dsts[j].host.IP
+	})
+	return dsts
+}
+
+// newSigmaRule creates the rule matching traffic to the destination
+func newSigmaRule(db string, dst *beaconDestination, date time.Time) sigmaRule {
+	level := "medium"
+	if dst.maxScore >= sigmaHighScore {
+		level = "high"
+	}
+
+	var sources []string
+	for _, src := range dst.sources {
+		sources = append(sources, src.IP)
+	}
+	sort.Strings(sources)
+	if len(sources) > sigmaMaxSources {
+		sources = append(sources[:sigmaMaxSources], fmt.Sprintf("and %d more", len(sources)-sigmaMaxSources))
+	}
+
+	return sigmaRule{
+		Title:  "RITA beacon destination " + dst.host.IP,
+		ID:     uuid.NewSHA1(sigmaNamespace, []byte(db+"/"+dst.host.MapKey())).String(),
+		Status: "experimental",
+		Description: fmt.Sprintf("RITA found beaconing to %s in dataset %s from %s",
+			dst.host.IP, db, strings.Join(sources, ", ")),
+		Author: "RITA",
+		Date:   date.Format("2006-01-02"),
+		Tags:   []string{"attack.command_and_control", "attack.t1071"},
+		LogSource: sigmaLogSource{
+			Product: "zeek",
+			Service: "conn",
+		},
+		Detection: yaml.MapSlice{
+			{Key: "selection", Value: yaml.MapSlice{{Key: "id.resp_h", Value: dst.host.IP}}},
+			{Key: "condition", Value: "selection"},
+		},
+		Fields:         []string{"id.orig_h", "id.resp_h", "id.resp_p", "proto", "service"},
+		FalsePositives: []string{"Software update checks and telemetry which connect on a schedule"},
+		Level:          level,
+	}
+}
+
+// writeSigma writes a YAML document to w for the rule of each destination
+func writeSigma(w io.Writer, db string, dsts []*beaconDestination, date time.Time) error {
+	for _, dst := range dsts {
+		out, err := yaml.Marshal(newSigmaRule(db, dst, date))
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "---\n# RITA beacon score: %.3f (%d sources)\n%s", dst.maxScore, len(dst.sources), out)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package export
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func testSigmaBeacon(src, dst string, score float64) beacon.Result {
+	srcIP := data.UniqueIP{IP: src, NetworkUUID: util.UnknownPrivateNetworkUUID, NetworkName: util.UnknownPrivateNetworkName}
+	dstIP := data.UniqueIP{IP: dst, NetworkUUID: util.PublicNetworkUUID, NetworkName: util.PublicNetworkName}
+	return beacon.Result{UniqueIPPair: data.NewUniqueIPPair(srcIP, dstIP), Score: score}
+}
+
+func TestGroupBeaconDestinations(t *testing.T) {
+	dsts := groupBeaconDestinations([]beacon.Result{
+		testSigmaBeacon("10.0.0.1", "203.0.113.2", 0.85),
+		testSigmaBeacon("10.0.0.1", "203.0.113.1", 0.82),
+		testSigmaBeacon("10.0.0.2", "203.0.113.1", 0.97),
+	})
+
+	// ordered by the highest score of the beacons to each destination
+	require.Len(t, dsts, 2)
+	assert.Equal(t, "203.0.113.1", dsts[0].host.IP)
+	assert.Equal(t, 0.97, dsts[0].maxScore)
+	assert.Len(t, dsts[0].sources, 2)
+	assert.Equal(t, "203.0.113.2", dsts[1].host.IP)
+	assert.Equal(t, 0.85, dsts[1].maxScore)
+}
+
+func TestWriteSigma(t *testing.T) {
+	date := time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC)
+	dsts := groupBeaconDestinations([]beacon.Result{
+		testSigmaBeacon("10.0.0.1", "203.0.113.1", 0.97),
+		testSigmaBeacon("10.0.0.2", "203.0.113.1", 0.9),
+		testSigmaBeacon("10.0.0.1", "198.51.100.7", 0.81),
+	})
+
+	var buf bytes.Buffer
+	require.Nil(t, writeSigma(&buf, "dataset", dsts, date))
+
+	docs := strings.Split(buf.String(), "---\n")[1:]
+	require.Len(t, docs, 2)
+
+	// each rule is preceded by a comment holding the score
+	assert.True(t, strings.HasPrefix(docs[0], "# RITA beacon score: 0.970 (2 sources)\n"))
+	assert.True(t, strings.HasPrefix(docs[1], "# RITA beacon score: 0.810 (1 sources)\n"))
+
+	var rule map[string]interface{}
+	require.Nil(t, yaml.Unmarshal([]byte(docs[0]), &rule))
+
+	for _, field := range []string{"title", "id", "status", "description", "author", "date",
+		"tags", "logsource", "detection", "fields", "falsepositives", "level"} {
+		assert.Contains(t, rule, field)
+	}
+	assert.Equal(t, "RITA beacon destination 203.0.113.1", rule["title"])
+	assert.Equal(t, "2022-09-01", rule["date"])
+	assert.Equal(t, "high", rule["level"])
+	assert.Contains(t, rule["description"], "10.0.0.1, 10.0.0.2")
+	assert.Equal(t, map[interface{}]interface{}{"product": "zeek", "service": "conn"}, rule["logsource"])
+	assert.Equal(t, map[interface{}]interface{}{
+		"selection": map[interface{}]interface{}{"id.resp_h": "203.0.113.1"},
+		"condition": "selection",
+	}, rule["detection"])
+
+	var lower map[string]interface{}
+	require.Nil(t, yaml.Unmarshal([]byte(docs[1]), &lower))
+	assert.Equal(t, "medium", lower["level"])
+	assert.NotEqual(t, rule["id"], lower["id"])
+}
+
+func TestSigmaRuleID(t *testing.T) {
+	dst := &beaconDestination{
+		host:     data.UniqueIP{IP: "203.0.113.1", NetworkUUID: util.PublicNetworkUUID},
+		maxScore: 0.9,
+	}
+	first := newSigmaRule("dataset", dst, time.Now())
+	second := newSigmaRule("dataset", dst, time.Now().Add(24*time.Hour))
+
+	// a destination keeps its rule ID across exports of the same dataset
+	assert.Equal(t, first.ID, second.ID)
+	assert.NotEqual(t, first.ID, newSigmaRule("other", dst, time.Now()).ID)
+}
+
+func TestSigmaRuleSourceLimit(t *testing.T) {
+	var beacons []beacon.Result
+	for i := 0; i < sigmaMaxSources+3; i++ {
+		beacons = append(beacons, testSigmaBeacon("10.0.0."+strconv.Itoa(i+1), "203.0.113.1", 0.9))
+	}
+	rule := newSigmaRule("dataset", groupBeaconDestinations(beacons)[0], time.Now())
+	assert.True(t, strings.HasSuffix(rule.Description, "and 3 more"))
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/activecm/rita/pkg/redact"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+)
+
+type (
+	// timeseries holds the connections between two hosts as points in time
+	timeseries struct {
+		target     string
+		timestamps []int64 // sorted connection timestamps in seconds
+	}
+
+	// grafanaSeries mirrors a series in the format read by the Grafana JSON
+	// and Infinity data sources. Each datapoint is a value and a timestamp
+	// in milliseconds.
+	grafanaSeries struct {
+		Target     string     `json:"target"`
+		Datapoints [][2]int64 `json:"datapoints"`
+	}
+)
+
+// TimeseriesFormats lists the formats which Timeseries can write
+var TimeseriesFormats = []string{"csv", "json"}
+
+// Timeseries writes the connections from src to dst in the given database to w
+// as a timeseries with a point of value 1 for each connection. format is csv
+// for a time,value table or json for a Grafana series. The hosts are masked
+// by r in the series name.
+func Timeseries(res *resources.Resources, db, src, dst, format string, r *redact.Redactor, w io.Writer) error {
+	res.DB.SelectDB(db)
+
+	timestamps, err := uconn.TimestampResults(res, src, dst)
+	if err != nil {
+		return err
+	}
+	if len(timestamps) == 0 {
+		return fmt.Errorf("no connection timestamps were found from %s to %s in %s", src, dst, db)
+	}
+
+	return writeTimeseries(w, newTimeseries(r.Src(src)+" -> "+r.Dst(dst), timestamps), format)
+}
+
+// newTimeseries creates a timeseries of the given connection timestamps
+func newTimeseries(target string, timestamps []int64) timeseries {
+	sorted := make([]int64, len(timestamps))
+	copy(sorted, timestamps)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return timeseries{target: target, timestamps: sorted}
+}
+
+// writeTimeseries writes the timeseries to w in the given format
+func writeTimeseries(w io.Writer, series timeseries, format string) error {
+	switch format {
+	case "csv":
+		return writeTimeseriesCSV(w, series)
+	case "json":
+		return writeTimeseriesJSON(w, series)
+	}
+	return fmt.Errorf("unknown timeseries format %q: must be one of csv or json", format)
+}
+
+// writeTimeseriesCSV writes a row holding the RFC 3339 time and a value of 1
+// for each connection
+func writeTimeseriesCSV(w io.Writer, series timeseries) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"time", "value"}); err != nil {
+		return err
+	}
+	for _, ts := range series.timestamps {
+		row := []string{time.Unix(ts, 0).UTC().Format(time.RFC3339), strconv.Itoa(1)}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeTimeseriesJSON writes the connections as a list holding a single
+// Grafana series
+func writeTimeseriesJSON(w io.Writer, series timeseries) error {
+	out := grafanaSeries{Target: series.target, Datapoints: make([][2]int64, 0, len(series.timestamps))}
+	for _, ts := range series.timestamps {
+		out.Datapoints = append(out.Datapoints, [2]int64{1, ts * 1000})
+	}
+	return json.NewEncoder(w).Encode([]grafanaSeries{out})
+}
@@ -0,0 +1,49 @@
+package export
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeseriesCSV(t *testing.T) {
+	timestamps := []int64{1600000120, 1600000000, 1600000060}
+	series := newTimeseries("10.0.0.1 -> 203.0.113.7", timestamps)
+	assert.Equal(t, []int64{1600000120, 1600000000, 1600000060}, timestamps, "the input must not be reordered")
+
+	var buf bytes.Buffer
+	require.Nil(t, writeTimeseries(&buf, series, "csv"))
+	assert.Equal(t, "time,value\n"+
+		"2020-09-13T12:26:40Z,1\n"+
+		"2020-09-13T12:27:40Z,1\n"+
+		"2020-09-13T12:28:40Z,1\n", buf.String())
+}
+
+func TestTimeseriesJSON(t *testing.T) {
+	// connections which share a timestamp are each a point
+	series := newTimeseries("10.0.0.1 -> 203.0.113.7", []int64{1600000060, 1600000000, 1600000060})
+
+	var buf bytes.Buffer
+	require.Nil(t, writeTimeseries(&buf, series, "json"))
+	assert.JSONEq(t, `[{
+		"target": "10.0.0.1 -> 203.0.113.7",
+		"datapoints": [[1, 1600000000000], [1, 1600000060000], [1, 1600000060000]]
+	}]`, buf.String())
+
+	var decoded []grafanaSeries
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	require.Len(t, decoded, 1)
+	assert.Len(t, decoded[0].Datapoints, 3)
+}
+
+func TestTimeseriesFormat(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NotNil(t, writeTimeseries(&buf, newTimeseries("a -> b", []int64{1}), "xml"))
+
+	// an empty series is still a valid document
+	require.Nil(t, writeTimeseries(&buf, newTimeseries("a -> b", nil), "json"))
+	assert.JSONEq(t, `[{"target": "a -> b", "datapoints": []}]`, buf.String())
+}
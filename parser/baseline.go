@@ -0,0 +1,97 @@
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/activecm/rita/util"
+)
+
+// SetBaselineDestinations reads a baseline of known-good destinations from the
+// file at path. Connections to the baseline destinations are skipped during the
+// import so that only the newly-seen destinations are analyzed and scored.
+// An empty path clears the baseline.
+func (fs *FSImporter) SetBaselineDestinations(path string) error {
+	if path == "" {
+		fs.baselineSubnets = nil
+		fs.baselineDomains = nil
+		return nil
+	}
+
+	subnets, domains, err := readBaselineDestinations(path)
+	if err != nil {
+		return err
+	}
+	fs.baselineSubnets = subnets
+	fs.baselineDomains = domains
+	return nil
+}
+
+// readBaselineDestinations parses a baseline file. Each line holds an IP
+// address, a CIDR range, or an FQDN, which may start with a "*." wildcard.
+// Blank lines and lines starting with # are ignored.
+func readBaselineDestinations(path string) ([]*net.IPNet, []string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("could not read baseline destinations: %w", err)
+	}
+	defer file.Close()
+
+	var subnets []*net.IPNet
+	var domains []string
+
+	scanner := bufio.NewScanner(file)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		entry := strings.TrimSpace(scanner.Text())
+		if entry == "" || strings.HasPrefix(entry, "#") {
+			continue
+		}
+
+		if strings.Contains(entry, "/") {
+			_, block, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid baseline destination %q on line %d of %s: %w", entry, lineNum, path, err)
+			}
+			subnets = append(subnets, block)
+			continue
+		}
+
+		if ip := net.ParseIP(entry); ip != nil {
+			bits := 128
+			if ip.To4() != nil {
+				ip = ip.To4()
+				bits = 32
+			}
+			subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		// domains are matched against the lower case hostnames found in the logs
+		domains = append(domains, strings.TrimSuffix(strings.ToLower(entry), "."))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, fmt.Errorf("could not read baseline destinations: %w", err)
+	}
+	return subnets, domains, nil
+}
+
+// isBaselineDestination returns true if the IP is in the baseline of known-good destinations
+func (fs *filter) isBaselineDestination(IP net.IP) bool {
+	return util.ContainsIP(fs.baselineSubnets, IP)
+}
+
+// filterBaselineDomain returns true if a destination hostname is in the baseline of
+// known-good destinations and is not on the AlwaysIncludeDomain list. Hostnames
+// which are IP addresses are checked against the baseline IPs and CIDR ranges.
+func (fs *filter) filterBaselineDomain(domain string) bool {
+	if util.ContainsDomain(fs.alwaysIncludedDomain, domain) {
+		return false
+	}
+	if ip := net.ParseIP(domain); ip != nil {
+		return !util.ContainsIP(fs.alwaysIncluded, ip) && fs.isBaselineDestination(ip)
+	}
+	return util.ContainsDomain(fs.baselineDomains, strings.ToLower(domain))
+}
@@ -0,0 +1,135 @@
+package parser
+
+import (
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/activecm/rita/parser/parsetypes"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testBaseline = `
+# known-good destinations
+198.51.100.7
+203.0.113.0/24
+2001:db8::1
+Updates.Example.com.
+*.cdn.example.net
+`
+
+// newBaselineFilter returns a filter whose baseline is read from contents
+func newBaselineFilter(t *testing.T, contents string) filter {
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	subnets, domains, err := readBaselineDestinations(path)
+	require.Nil(t, err)
+	return filter{
+		internal:        util.ParseSubnets([]string{"10.0.0.0/8"}),
+		baselineSubnets: subnets,
+		baselineDomains: domains,
+	}
+}
+
+func TestReadBaselineDestinations(t *testing.T) {
+	fsTest := newBaselineFilter(t, testBaseline)
+
+	assert.Len(t, fsTest.baselineSubnets, 3)
+	assert.Equal(t, []string{"updates.example.com", "*.cdn.example.net"}, fsTest.baselineDomains)
+
+	ipCases := []testCaseSingleIP{
+		{"198.51.100.7", true, "a listed IP is in the baseline"},
+		{"198.51.100.8", false, "an unlisted IP is not in the baseline"},
+		{"203.0.113.99", true, "an IP in a listed range is in the baseline"},
+		{"2001:db8::1", true, "a listed IPv6 address is in the baseline"},
+		{"2001:db8::2", false, "an unlisted IPv6 address is not in the baseline"},
+	}
+	for _, test := range ipCases {
+		assert.Equal(t, test.out, fsTest.isBaselineDestination(net.ParseIP(test.ip)), test.msg)
+	}
+
+	domainCases := []testCaseDomain{
+		{"updates.example.com", true, "a listed FQDN is in the baseline"},
+		{"UPDATES.example.com", true, "FQDNs are matched regardless of case"},
+		{"example.com", false, "the parent of a listed FQDN is not in the baseline"},
+		{"a.cdn.example.net", true, "a wildcard matches subdomains"},
+		{"cdn.example.net", true, "a wildcard matches its top domain"},
+		{"203.0.113.5", true, "a hostname in a listed range is in the baseline"},
+		{"novel.example.org", false, "a novel FQDN is not in the baseline"},
+		{"", false, "an empty hostname is not in the baseline"},
+	}
+	for _, test := range domainCases {
+		assert.Equal(t, test.out, fsTest.filterBaselineDomain(test.domain), test.msg)
+	}
+}
+
+func TestReadBaselineDestinationsErrors(t *testing.T) {
+	_, _, err := readBaselineDestinations(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.NotNil(t, err)
+
+	path := filepath.Join(t.TempDir(), "baseline.txt")
+	require.Nil(t, ioutil.WriteFile(path, []byte("198.51.100.7\n203.0.113.0/33\n"), 0644))
+	_, _, err = readBaselineDestinations(path)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+}
+
+func TestFilterConnPairBaseline(t *testing.T) {
+	fsTest := newBaselineFilter(t, testBaseline)
+	fsTest.alwaysIncluded = util.ParseSubnets([]string{"198.51.100.7/32"})
+
+	testCases := []testCase{
+		{"10.0.0.1", "203.0.113.5", true, "a baseline destination should be filtered"},
+		{"10.0.0.1", "192.0.2.1", false, "a novel destination should not be filtered"},
+		{"203.0.113.5", "10.0.0.1", false, "a baseline host is only skipped as a destination"},
+		{"10.0.0.1", "198.51.100.7", false, "AlwaysInclude should override the baseline"},
+	}
+	for _, test := range testCases {
+		assert.Equal(t, test.out, fsTest.filterConnPair(net.ParseIP(test.src), net.ParseIP(test.dst)), test.msg)
+	}
+}
+
+func TestParseEntriesBaseline(t *testing.T) {
+	fsTest := newBaselineFilter(t, testBaseline)
+	retVals := newParseResults()
+
+	newConn := func(dst string) *parsetypes.Conn {
+		return &parsetypes.Conn{
+			TimeStamp:       1600000000,
+			Source:          "10.0.0.1",
+			SourcePort:      50000,
+			Destination:     dst,
+			DestinationPort: 443,
+			Proto:           "tcp",
+		}
+	}
+	newHTTP := func(dst, host string) *parsetypes.HTTP {
+		return &parsetypes.HTTP{
+			TimeStamp:   1600000000,
+			Source:      "10.0.0.1",
+			Destination: dst,
+			Method:      "GET",
+			Host:        host,
+		}
+	}
+
+	parseConnEntry(newConn("203.0.113.5"), fsTest, retVals)
+	parseConnEntry(newConn("192.0.2.1"), fsTest, retVals)
+	parseHTTPEntry(newHTTP("192.0.2.2", "updates.example.com"), fsTest, retVals)
+	parseHTTPEntry(newHTTP("192.0.2.3", "novel.example.org"), fsTest, retVals)
+
+	// only the novel destinations are collected for analysis
+	require.Len(t, retVals.UniqueConnMap, 1)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Equal(t, "192.0.2.1", input.Hosts.DstIP)
+	}
+
+	require.Len(t, retVals.HTTPConnMap, 1)
+	for _, input := range retVals.HTTPConnMap {
+		assert.Equal(t, "novel.example.org", input.Hosts.FQDN)
+	}
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/activecm/rita/util"
 )
 
-func parseConnEntry(parseConn *parsetypes.Conn, filter filter, retVals ParseResults) {
+func parseConnEntry(parseConn *parsetypes.Conn, filter filter, retVals ParseResults) bool {
 	// get source destination pair for connection record
 	src := parseConn.Source
 	dst := parseConn.Destination
@@ -26,7 +26,7 @@ func parseConnEntry(parseConn *parsetypes.Conn, filter filter, retVals ParseResu
 
 	// If connection pair is not subject to filtering, process
 	if ignore {
-		return
+		return false
 	}
 
 	// disambiguate addresses which are not publicly routable
@@ -61,6 +61,7 @@ func parseConnEntry(parseConn *parsetypes.Conn, filter filter, retVals ParseResu
 	updateCertificatesByConn(dstKey, tuple, retVals)
 
 	updateZeekUIDRecordsByConn(parseConn.UID, parseConn.OrigIPBytes, parseConn.RespBytes, roundedDuration, retVals)
+	return true
 }
 
 func updateUniqueConnectionsByConn(srcIP, dstIP net.IP, srcDstPair data.UniqueIPPair, srcDstKey string,
@@ -124,20 +125,46 @@ func updateUniqueConnectionsByConn(srcIP, dstIP net.IP, srcDstPair data.UniqueIP
 	// ///// INCREMENT THE CONNECTION COUNT FOR THE UNIQUE CONNECTION /////
 	retVals.UniqueConnMap[srcDstKey].ConnectionCount++
 
-	// ///// APPEND TIMESTAMP TO UNIQUE CONNECTION TIMESTAMP LIST /////
-	retVals.UniqueConnMap[srcDstKey].TsList = append(
-		retVals.UniqueConnMap[srcDstKey].TsList, parseConn.TimeStamp,
-	)
+	// ///// APPEND THE CONNECTION TO THE SERIES SCORED BY BEACON ANALYSIS /////
+	// Connections which didn't use one of the Beacon IncludedServices are
+	// counted for the unique connection, but left out of the beacon counts
+	// and series so they never become beacon candidates
+	if filter.beaconServices.Included(tuple) {
+		appendBeaconSeries(retVals.UniqueConnMap[srcDstKey], roundedDuration, twoWayIPBytes, parseConn, filter)
+	}
 
-	// ///// APPEND IP BYTES TO UNIQUE CONNECTION BYTES LIST /////
-	retVals.UniqueConnMap[srcDstKey].OrigBytesList = append(
-		retVals.UniqueConnMap[srcDstKey].OrigBytesList, parseConn.OrigIPBytes,
-	)
+	// ///// RECORD THE LOG FILE AND LINE OF THE CONNECTION /////
+	// Only set when Provenance is enabled, for tracing findings back to their source data
+	if len(parseConn.LogPath) > 0 {
+		if retVals.UniqueConnMap[srcDstKey].LogPaths == nil {
+			retVals.UniqueConnMap[srcDstKey].LogPaths = make(data.StringSet)
+		}
+		retVals.UniqueConnMap[srcDstKey].LogPaths.Insert(parseConn.LogPath)
+
+		// only the most recent references are stored, so the older ones are
+		// dropped as they pile up rather than holding one for every connection
+		if parseConn.LogLine > 0 {
+			logRefs := append(
+				retVals.UniqueConnMap[srcDstKey].LogRefs,
+				parseConn.LogPath+":"+strconv.FormatInt(parseConn.LogLine, 10),
+			)
+			if len(logRefs) > 2*filter.sampleSize {
+				logRefs = util.SampleStrings(logRefs, filter.sampleSize)
+			}
+			retVals.UniqueConnMap[srcDstKey].LogRefs = logRefs
+		}
+	}
 
 	// ///// ADD ORIG BYTES AND RESP BYTES TO UNIQUE CONNECTION TOTAL BYTES COUNTER /////
 	// Calculate and store the total number of bytes exchanged by the uconn pair
 	retVals.UniqueConnMap[srcDstKey].TotalBytes += twoWayIPBytes
 
+	// ///// ADD ORIG PACKET AND BYTE COUNTS TO UNIQUE CONNECTION COUNTERS /////
+	// Comparing the IP bytes to the payload bytes reveals retransmissions
+	retVals.UniqueConnMap[srcDstKey].OrigIPBytes += parseConn.OrigIPBytes
+	retVals.UniqueConnMap[srcDstKey].OrigPayloadBytes += parseConn.OrigBytes
+	retVals.UniqueConnMap[srcDstKey].OrigPkts += parseConn.OrigPkts
+
 	// ///// ADD CONNECTION DURATION TO UNIQUE CONNECTION'S TOTAL DURATION COUNTER /////
 	retVals.UniqueConnMap[srcDstKey].TotalDuration += roundedDuration
 
@@ -145,11 +172,55 @@ func updateUniqueConnectionsByConn(srcIP, dstIP net.IP, srcDstPair data.UniqueIP
 	// Replace existing duration if current duration is higher
 	if roundedDuration > retVals.UniqueConnMap[srcDstKey].MaxDuration {
 		retVals.UniqueConnMap[srcDstKey].MaxDuration = roundedDuration
+		retVals.UniqueConnMap[srcDstKey].MaxDurationConn = uconn.ConnTuple{
+			SrcPort: parseConn.SourcePort,
+			DstPort: parseConn.DestinationPort,
+			Proto:   parseConn.Proto,
+		}
 	}
 
 	return
 }
 
+// appendBeaconSeries adds a connection to the beacon counts of its unique
+// connection and appends its timestamp, sizes, duration, and uid to the
+// series scored by beacon analysis
+func appendBeaconSeries(input *uconn.Input, roundedDuration float64, twoWayIPBytes int64, parseConn *parsetypes.Conn, filter filter) {
+	// ///// INCREMENT THE BEACON CONNECTION COUNT AND BYTES /////
+	// These decide whether the unique connection is a beacon or a strobe
+	input.BeaconCount++
+	input.BeaconBytes += twoWayIPBytes
+
+	// ///// APPEND TIMESTAMP TO UNIQUE CONNECTION TIMESTAMP LIST /////
+	input.TsList = append(input.TsList, parseConn.TimeStamp)
+
+	// ///// APPEND LAST ACTIVITY TO UNIQUE CONNECTION RESPONSE TIMESTAMP LIST /////
+	// Servers which push data on a schedule beacon on the response side
+	if filter.collectRespTs {
+		input.RespTsList = append(input.RespTsList, parseConn.TimeStamp+int64(math.Round(parseConn.Duration)))
+	}
+
+	// ///// APPEND IP BYTES TO UNIQUE CONNECTION BYTES LIST /////
+	input.OrigBytesList = append(input.OrigBytesList, parseConn.OrigIPBytes)
+	input.RespBytesList = append(input.RespBytesList, parseConn.RespIPBytes)
+
+	// ///// APPEND CONNECTION DURATION TO UNIQUE CONNECTION DURATION LIST /////
+	if filter.collectDurations {
+		input.DurationList = append(input.DurationList, roundedDuration)
+	}
+
+	// ///// APPEND ZEEK RECORD UID TO UNIQUE CONNECTION UID LIST /////
+	// This allows analysts to pivot from a beacon back to the connections
+	// which make it up. Like the log references, only the most recent uids
+	// are stored, so the older ones are dropped as they pile up.
+	if len(parseConn.UID) > 0 {
+		input.UIDs = append(input.UIDs, parseConn.UID)
+		if len(input.UIDs) > 2*filter.sampleSize {
+			input.UIDs = util.SampleStrings(input.UIDs, filter.sampleSize)
+		}
+	}
+}
+
 func updateHostsByConn(srcIP, dstIP net.IP, srcUniqIP, dstUniqIP data.UniqueIP, srcKey, dstKey string,
 	newUniqueConnection, setUPPSFlag bool, roundedDuration float64, twoWayIPBytes int64, tuple string,
 	parseConn *parsetypes.Conn, filter filter, retVals ParseResults) {
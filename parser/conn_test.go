@@ -0,0 +1,209 @@
+package parser
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/activecm/rita/parser/parsetypes"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseConnEntryLongestConnection(t *testing.T) {
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"})}
+	retVals := newParseResults()
+
+	newConn := func(uid string, srcPort int, dstPort int, proto string, duration float64) *parsetypes.Conn {
+		return &parsetypes.Conn{
+			TimeStamp:       1600000000,
+			UID:             uid,
+			Source:          "10.0.0.1",
+			SourcePort:      srcPort,
+			Destination:     "203.0.113.1",
+			DestinationPort: dstPort,
+			Proto:           proto,
+			Duration:        duration,
+		}
+	}
+
+	parseConnEntry(newConn("C1", 50000, 53, "udp", 0.5), testFilter, retVals)
+	parseConnEntry(newConn("C2", 51234, 443, "tcp", 7200), testFilter, retVals)
+	parseConnEntry(newConn("C3", 52000, 80, "tcp", 60), testFilter, retVals)
+
+	// the ports and protocol of the longest connection are kept
+	require.Len(t, retVals.UniqueConnMap, 1)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Equal(t, 7200.0, input.MaxDuration)
+		assert.Equal(t, uconn.ConnTuple{SrcPort: 51234, DstPort: 443, Proto: "tcp"}, input.MaxDurationConn)
+	}
+}
+
+func TestParseConnEntryReportsFiltered(t *testing.T) {
+	testFilter := filter{
+		internal:      util.ParseSubnets([]string{"10.0.0.0/8"}),
+		neverIncluded: util.ParseSubnets([]string{"203.0.113.2/32"}),
+	}
+	retVals := newParseResults()
+
+	newConn := func(dst string) *parsetypes.Conn {
+		return &parsetypes.Conn{
+			TimeStamp:       1600000000,
+			UID:             "C1",
+			Source:          "10.0.0.1",
+			SourcePort:      50000,
+			Destination:     dst,
+			DestinationPort: 443,
+			Proto:           "tcp",
+		}
+	}
+
+	// only the entries which pass the filter count as written
+	assert.True(t, parseConnEntry(newConn("203.0.113.1"), testFilter, retVals))
+	assert.False(t, parseConnEntry(newConn("203.0.113.2"), testFilter, retVals))
+	assert.Len(t, retVals.UniqueConnMap, 1)
+}
+
+func TestParseConnEntryResponseTimestamps(t *testing.T) {
+	conn := &parsetypes.Conn{
+		TimeStamp:       1600000000,
+		UID:             "C1",
+		Source:          "10.0.0.1",
+		SourcePort:      50000,
+		Destination:     "203.0.113.1",
+		DestinationPort: 443,
+		Proto:           "tcp",
+		Duration:        30,
+	}
+
+	// response timestamps are only collected for ResponseTimestampScoring
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"})}
+	retVals := newParseResults()
+	parseConnEntry(conn, testFilter, retVals)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Nil(t, input.RespTsList)
+	}
+
+	testFilter.collectRespTs = true
+	retVals = newParseResults()
+	parseConnEntry(conn, testFilter, retVals)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Equal(t, []int64{1600000030}, input.RespTsList)
+	}
+}
+
+func TestParseConnEntryDurations(t *testing.T) {
+	conn := &parsetypes.Conn{
+		TimeStamp:       1600000000,
+		UID:             "C1",
+		Source:          "10.0.0.1",
+		SourcePort:      50000,
+		Destination:     "203.0.113.1",
+		DestinationPort: 443,
+		Proto:           "tcp",
+		Duration:        30,
+	}
+
+	// durations are only collected for ConnectionDurationScoring
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"})}
+	retVals := newParseResults()
+	parseConnEntry(conn, testFilter, retVals)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Nil(t, input.DurationList)
+		assert.Equal(t, 30.0, input.TotalDuration)
+	}
+
+	testFilter.collectDurations = true
+	retVals = newParseResults()
+	parseConnEntry(conn, testFilter, retVals)
+	for _, input := range retVals.UniqueConnMap {
+		assert.Equal(t, []float64{30}, input.DurationList)
+	}
+}
+
+func TestParseConnEntryCapsSamples(t *testing.T) {
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"}), sampleSize: 2}
+	retVals := newParseResults()
+
+	for line := int64(1); line <= 10; line++ {
+		parseConnEntry(&parsetypes.Conn{
+			TimeStamp:       1600000000,
+			UID:             "C" + strconv.FormatInt(line, 10),
+			Source:          "10.0.0.1",
+			SourcePort:      50000,
+			Destination:     "203.0.113.1",
+			DestinationPort: 443,
+			Proto:           "tcp",
+			LogPath:         "/logs/conn.log",
+			LogLine:         line,
+		}, testFilter, retVals)
+	}
+
+	// the uids and references are trimmed as they are collected, keeping the most recent ones
+	require.Len(t, retVals.UniqueConnMap, 1)
+	for _, input := range retVals.UniqueConnMap {
+		assert.LessOrEqual(t, len(input.UIDs), 2*testFilter.sampleSize)
+		assert.Equal(t, []string{"C9", "C10"}, util.SampleStrings(input.UIDs, testFilter.sampleSize))
+		assert.LessOrEqual(t, len(input.LogRefs), 2*testFilter.sampleSize)
+		assert.Equal(t, []string{"/logs/conn.log:9", "/logs/conn.log:10"}, util.SampleStrings(input.LogRefs, testFilter.sampleSize))
+	}
+}
+
+func TestParseConnEntryIncludedServices(t *testing.T) {
+	testFilter := filter{
+		internal:         util.ParseSubnets([]string{"10.0.0.0/8"}),
+		beaconServices:   util.NewServiceFilter([]string{"443:tcp", "53:udp:dns"}),
+		collectDurations: true,
+		sampleSize:       10,
+	}
+	retVals := newParseResults()
+
+	newConn := func(uid, dst string, ts int64, dstPort int, proto, service string) *parsetypes.Conn {
+		return &parsetypes.Conn{
+			TimeStamp:       ts,
+			UID:             uid,
+			Source:          "10.0.0.1",
+			SourcePort:      50000,
+			Destination:     dst,
+			DestinationPort: dstPort,
+			Proto:           proto,
+			Service:         service,
+			OrigIPBytes:     100,
+			RespIPBytes:     200,
+		}
+	}
+
+	// a pair which mixes an included service with an excluded one
+	parseConnEntry(newConn("C1", "203.0.113.1", 1600000000, 443, "tcp", "ssl"), testFilter, retVals)
+	parseConnEntry(newConn("C2", "203.0.113.1", 1600000060, 123, "udp", "ntp"), testFilter, retVals)
+	parseConnEntry(newConn("C3", "203.0.113.1", 1600000120, 443, "tcp", "ssl"), testFilter, retVals)
+	// a pair which only used an excluded service
+	parseConnEntry(newConn("C4", "203.0.113.2", 1600000000, 123, "udp", "ntp"), testFilter, retVals)
+	parseConnEntry(newConn("C5", "203.0.113.2", 1600000060, 53, "udp", ""), testFilter, retVals)
+
+	require.Len(t, retVals.UniqueConnMap, 2)
+	for _, input := range retVals.UniqueConnMap {
+		switch input.Hosts.DstIP {
+		case "203.0.113.1":
+			// only the included connections are scored
+			assert.Equal(t, int64(3), input.ConnectionCount)
+			assert.Equal(t, int64(900), input.TotalBytes)
+			assert.Equal(t, int64(2), input.BeaconCount)
+			assert.Equal(t, int64(600), input.BeaconBytes)
+			assert.Equal(t, []int64{1600000000, 1600000120}, input.TsList)
+			assert.Len(t, input.OrigBytesList, 2)
+			assert.Len(t, input.DurationList, 2)
+			assert.Equal(t, []string{"C1", "C3"}, input.UIDs)
+		case "203.0.113.2":
+			// the excluded connections are counted but produce no beacon candidate
+			assert.Equal(t, int64(2), input.ConnectionCount)
+			assert.Equal(t, int64(600), input.TotalBytes)
+			assert.Zero(t, input.BeaconCount)
+			assert.Zero(t, input.BeaconBytes)
+			assert.Empty(t, input.TsList)
+			assert.Empty(t, input.OrigBytesList)
+			assert.Empty(t, input.UIDs)
+		}
+	}
+}
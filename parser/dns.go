@@ -8,7 +8,7 @@ import (
 	"github.com/activecm/rita/pkg/hostname"
 )
 
-func parseDNSEntry(parseDNS *parsetypes.DNS, filter filter, retVals ParseResults) {
+func parseDNSEntry(parseDNS *parsetypes.DNS, filter filter, retVals ParseResults) bool {
 
 	// extract and store the dns client ip address
 	src := parseDNS.Source
@@ -19,17 +19,18 @@ func parseDNSEntry(parseDNS *parsetypes.DNS, filter filter, retVals ParseResults
 	// section since a c2 channel running over dns could have an
 	// internal ip to internal ip connection and not having that ip
 	// in the host table is limiting
-	ignore := (filter.filterDomain(parseDNS.Query) || filter.filterSingleIP(srcIP))
+	ignore := (filter.filterDomain(parseDNS.Query) || filter.filterSourceIP(srcIP))
 
 	// If domain is not subject to filtering, process
 	if ignore {
-		return
+		return false
 	}
 
 	srcUniqIP := data.NewUniqueIP(srcIP, parseDNS.AgentUUID, parseDNS.AgentHostname)
 
 	updateExplodedDNSbyDNS(parseDNS, retVals)
 	updateHostnamesByDNS(srcUniqIP, parseDNS, retVals)
+	return true
 }
 
 func updateExplodedDNSbyDNS(parseDNS *parsetypes.DNS, retVals ParseResults) {
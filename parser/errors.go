@@ -0,0 +1,27 @@
+package parser
+
+import "errors"
+
+// ErrDatabaseWrite is returned when the importer fails to write to MongoDB
+var ErrDatabaseWrite = errors.New("database write failed")
+
+// DatabaseWriteError records the write which stopped an import
+type DatabaseWriteError struct {
+	Database string
+	Op       string // what was being written
+	Err      error
+}
+
+func (e *DatabaseWriteError) Error() string {
+	return ErrDatabaseWrite.Error() + ": " + e.Op + " in " + e.Database + ": " + e.Err.Error()
+}
+
+// Is reports ErrDatabaseWrite as the kind of error
+func (e *DatabaseWriteError) Is(target error) bool {
+	return target == ErrDatabaseWrite
+}
+
+// Unwrap returns the error reported by the database
+func (e *DatabaseWriteError) Unwrap() error {
+	return e.Err
+}
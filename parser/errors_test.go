@@ -0,0 +1,24 @@
+package parser
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDatabaseWriteError(t *testing.T) {
+	cause := errors.New("connection refused")
+	var err error = &DatabaseWriteError{Database: "dataset", Op: "removing outdated data", Err: cause}
+
+	assert.True(t, errors.Is(err, ErrDatabaseWrite))
+	assert.True(t, errors.Is(err, cause), "the error from the database should be unwrapped")
+
+	var writeErr *DatabaseWriteError
+	require.True(t, errors.As(err, &writeErr))
+	assert.Equal(t, "dataset", writeErr.Database)
+	assert.Equal(t, "database write failed: removing outdated data in dataset: connection refused", err.Error())
+
+	assert.False(t, errors.Is(cause, ErrDatabaseWrite))
+}
@@ -0,0 +1,146 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// archiveExts lists the extensions of the archives of log files which can be read
+var archiveExts = []string{".tar", ".tar.gz", ".tgz", ".tar.zst", ".zip"}
+
+// IsArchive reports whether the file name is a tar or zip archive which may
+// hold log files
+func IsArchive(name string) bool {
+	for _, ext := range archiveExts {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchivedLog reports whether an archive entry is a log file. Archives
+// nested inside of an archive are not extracted.
+func isArchivedLog(name string) bool {
+	return isLogFile(name) && !IsArchive(name)
+}
+
+// ExtractArchive writes the log files held by a tar or zip archive into dir
+// and returns their paths. The directory structure of the archive is kept so
+// that logs with the same name in different directories don't collide. Other
+// files are skipped. Compressed logs inside of the archive are left compressed
+// and are decompressed when they are read.
+func ExtractArchive(archivePath string, dir string) ([]string, error) {
+	if strings.HasSuffix(archivePath, ".zip") {
+		return extractZip(archivePath, dir)
+	}
+	return extractTar(archivePath, dir)
+}
+
+// extractTar writes the log files of a tar archive, which may be compressed
+// with gzip or zstd, into dir
+func extractTar(archivePath string, dir string) ([]string, error) {
+	fileHandle, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader io.Reader
+	closer := fileHandle.Close
+	switch {
+	case strings.HasSuffix(archivePath, ".gz"), strings.HasSuffix(archivePath, ".tgz"):
+		reader, closer, err = newGzipReader(fileHandle)
+	case strings.HasSuffix(archivePath, ".zst"):
+		reader, closer, err = newZstdReader(fileHandle)
+	default:
+		reader = fileHandle
+	}
+	if err != nil {
+		closer()
+		return nil, err
+	}
+	defer closer()
+
+	var extracted []string
+	tarReader := tar.NewReader(reader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return extracted, fmt.Errorf("could not read %s: %w", archivePath, err)
+		}
+		if header.Typeflag != tar.TypeReg || !isArchivedLog(header.Name) {
+			continue
+		}
+
+		extractedPath, err := extractEntry(dir, header.Name, tarReader)
+		if err != nil {
+			return extracted, err
+		}
+		extracted = append(extracted, extractedPath)
+	}
+	return extracted, nil
+}
+
+// extractZip writes the log files of a zip archive into dir
+func extractZip(archivePath string, dir string) ([]string, error) {
+	zipReader, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer zipReader.Close()
+
+	var extracted []string
+	for _, entry := range zipReader.File {
+		if !entry.Mode().IsRegular() || !isArchivedLog(entry.Name) {
+			continue
+		}
+
+		entryReader, err := entry.Open()
+		if err != nil {
+			return extracted, fmt.Errorf("could not read %s from %s: %w", entry.Name, archivePath, err)
+		}
+		extractedPath, err := extractEntry(dir, entry.Name, entryReader)
+		entryReader.Close()
+		if err != nil {
+			return extracted, err
+		}
+		extracted = append(extracted, extractedPath)
+	}
+	return extracted, nil
+}
+
+// extractEntry copies an archive entry to its path under dir. Entries which
+// would be written outside of dir are rejected.
+func extractEntry(dir string, name string, reader io.Reader) (string, error) {
+	cleanName := path.Clean("/" + name)[1:]
+	if cleanName == "" || cleanName != strings.TrimPrefix(name, "./") {
+		return "", fmt.Errorf("refusing to extract archive entry %q: the path is not relative to the archive", name)
+	}
+
+	extractedPath := filepath.Join(dir, filepath.FromSlash(cleanName))
+	if err := os.MkdirAll(filepath.Dir(extractedPath), 0755); err != nil {
+		return "", err
+	}
+
+	file, err := os.OpenFile(extractedPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", err
+	}
+	_, err = io.Copy(file, reader)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not extract archive entry %q: %w", name, err)
+	}
+	return extractedPath, nil
+}
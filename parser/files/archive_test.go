@@ -0,0 +1,146 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testArchiveEntry struct {
+	name     string
+	contents string
+}
+
+// writeTestTarGz writes a gzip compressed tar archive holding the entries
+func writeTestTarGz(t *testing.T, path string, entries []testArchiveEntry) {
+	file, err := os.Create(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	require.Nil(t, tarWriter.WriteHeader(&tar.Header{Name: "logs/", Typeflag: tar.TypeDir, Mode: 0755}))
+	for _, entry := range entries {
+		require.Nil(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     entry.name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(entry.contents)),
+		}))
+		_, err = tarWriter.Write([]byte(entry.contents))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tarWriter.Close())
+	require.Nil(t, gzipWriter.Close())
+}
+
+// writeTestZip writes a zip archive holding the entries
+func writeTestZip(t *testing.T, path string, entries []testArchiveEntry) {
+	file, err := os.Create(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	zipWriter := zip.NewWriter(file)
+	for _, entry := range entries {
+		entryWriter, err := zipWriter.Create(entry.name)
+		require.Nil(t, err)
+		_, err = entryWriter.Write([]byte(entry.contents))
+		require.Nil(t, err)
+	}
+	require.Nil(t, zipWriter.Close())
+}
+
+func TestIsArchive(t *testing.T) {
+	for _, name := range []string{"logs.tar", "logs.tar.gz", "logs.tgz", "logs.tar.zst", "logs.zip"} {
+		assert.True(t, IsArchive(name), name)
+	}
+	for _, name := range []string{"conn.log", "conn.log.gz", "conn.json.zst", "logs.tar.bz2"} {
+		assert.False(t, IsArchive(name), name)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	connLog, err := ioutil.ReadFile(filepath.Join("testdata", "compressed", "conn.log"))
+	require.Nil(t, err)
+	connLogGz, err := ioutil.ReadFile(filepath.Join("testdata", "compressed", "conn.log.gz"))
+	require.Nil(t, err)
+
+	entries := []testArchiveEntry{
+		{"logs/conn.log", string(connLog)},
+		{"logs/2020-09-13/conn.log.gz", string(connLogGz)},
+		{"logs/README.txt", "not a log"},
+		{"logs/nested.tar.gz", "archives within archives are skipped"},
+	}
+
+	archives := map[string]func(*testing.T, string, []testArchiveEntry){
+		"logs.tar.gz": writeTestTarGz,
+		"logs.zip":    writeTestZip,
+	}
+	for name, writeArchive := range archives {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), name)
+			writeArchive(t, archivePath, entries)
+
+			dir := t.TempDir()
+			extracted, err := ExtractArchive(archivePath, dir)
+			require.Nil(t, err)
+			assert.ElementsMatch(t, []string{
+				filepath.Join(dir, "logs", "conn.log"),
+				filepath.Join(dir, "logs", "2020-09-13", "conn.log.gz"),
+			}, extracted)
+
+			contents, err := ioutil.ReadFile(filepath.Join(dir, "logs", "conn.log"))
+			require.Nil(t, err)
+			assert.Equal(t, connLog, contents)
+
+			// compressed logs are left as they were in the archive
+			contents, err = ioutil.ReadFile(filepath.Join(dir, "logs", "2020-09-13", "conn.log.gz"))
+			require.Nil(t, err)
+			assert.Equal(t, connLogGz, contents)
+		})
+	}
+}
+
+func TestExtractArchiveOutsideDir(t *testing.T) {
+	for _, name := range []string{"../conn.log", "logs/../../conn.log", "/tmp/conn.log"} {
+		t.Run(name, func(t *testing.T) {
+			archivePath := filepath.Join(t.TempDir(), "logs.zip")
+			writeTestZip(t, archivePath, []testArchiveEntry{{name, "escaped"}})
+
+			parent := t.TempDir()
+			dir := filepath.Join(parent, "extracted")
+			require.Nil(t, os.Mkdir(dir, 0755))
+
+			extracted, err := ExtractArchive(archivePath, dir)
+			assert.NotNil(t, err)
+			assert.Empty(t, extracted)
+
+			_, err = os.Stat(filepath.Join(parent, "conn.log"))
+			assert.True(t, os.IsNotExist(err), "the entry should not be written outside of the directory")
+		})
+	}
+}
+
+func TestGatherLogFilesArchives(t *testing.T) {
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	dir := t.TempDir()
+	for _, name := range []string{"logs.tar.gz", "logs.zip", "conn.log", "notes.txt"} {
+		require.Nil(t, ioutil.WriteFile(filepath.Join(dir, name), nil, 0644))
+	}
+
+	assert.ElementsMatch(t, []string{
+		filepath.Join(dir, "logs.tar.gz"),
+		filepath.Join(dir, "logs.zip"),
+		filepath.Join(dir, "conn.log"),
+	}, GatherLogFiles([]string{dir}, logger))
+}
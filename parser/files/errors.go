@@ -0,0 +1,27 @@
+package files
+
+import "errors"
+
+var (
+	// ErrMalformedLog is returned when the contents of a log file cannot be parsed
+	ErrMalformedLog = errors.New("malformed log")
+
+	// ErrUnknownLogType is returned when a log file does not match a supported Zeek log
+	ErrUnknownLogType = errors.New("unknown log type")
+)
+
+//LogError records the log file which could not be indexed. Use errors.Is with
+//ErrMalformedLog or ErrUnknownLogType to find out why.
+type LogError struct {
+	Path string
+	Err  error
+}
+
+func (e *LogError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+//Unwrap returns the reason the log file could not be indexed
+func (e *LogError) Unwrap() error {
+	return e.Err
+}
@@ -3,7 +3,6 @@ package files
 import (
 	"crypto/md5"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -18,11 +17,17 @@ import (
 )
 
 //newIndexedFile takes in a file path and the current resource bundle and opens up the
-//file path and parses out some metadata
+//file path and parses out some metadata. Errors are returned as a *LogError.
 func newIndexedFile(filePath string, targetDB string, targetCID int,
-	logger *log.Logger, conf *config.Config) (*IndexedFile, error) {
+	logger *log.Logger, conf *config.Config) (toReturn *IndexedFile, err error) {
 
-	toReturn := new(IndexedFile)
+	defer func() {
+		if err != nil {
+			err = &LogError{Path: filePath, Err: err}
+		}
+	}()
+
+	toReturn = new(IndexedFile)
 	toReturn.Path = filePath
 
 	fileHandle, err := os.Open(filePath)
@@ -78,7 +83,7 @@ func newIndexedFile(filePath string, targetDB string, targetCID int,
 		}
 	}
 	if broDataFactory == nil {
-		return toReturn, errors.New("could not map file header to parse type")
+		return toReturn, fmt.Errorf("%w: could not map file header to parse type", ErrUnknownLogType)
 	}
 	toReturn.SetBroDataFactory(broDataFactory)
 
@@ -101,12 +106,12 @@ func newIndexedFile(filePath string, targetDB string, targetCID int,
 	}
 
 	if line == nil {
-		return toReturn, errors.New("could not parse first line of file")
+		return toReturn, fmt.Errorf("%w: could not parse first line of file", ErrMalformedLog)
 	}
 
 	toReturn.TargetCollection = line.TargetCollection(&conf.T.Structure)
 	if toReturn.TargetCollection == "" {
-		return toReturn, errors.New("could not find a target collection for file")
+		return toReturn, fmt.Errorf("%w: could not find a target collection for file", ErrUnknownLogType)
 	}
 
 	toReturn.TargetDatabase = targetDB
@@ -135,17 +140,18 @@ func getFileHash(fileHandle *os.File, fInfo os.FileInfo) (string, error) {
 }
 
 //IndexFiles takes in a list of Zeek files, a number of threads, the target database, and target chunk ID and parses
-//some metadata out of the files
+//some metadata out of the files. The files which could not be indexed are skipped and their *LogError is returned.
 func IndexFiles(files []string, indexingThreads int, targetDB string, targetCID int,
-	logger *log.Logger, conf *config.Config) []*IndexedFile {
+	logger *log.Logger, conf *config.Config) ([]*IndexedFile, []error) {
 	n := len(files)
 	output := make([]*IndexedFile, n)
+	errs := make([]error, n)
 	indexingWG := new(sync.WaitGroup)
 
 	for i := 0; i < indexingThreads; i++ {
 		indexingWG.Add(1)
 
-		go func(files []string, indexedFiles []*IndexedFile, targetDB string, targetCID int,
+		go func(files []string, indexedFiles []*IndexedFile, errs []error, targetDB string, targetCID int,
 			logger *log.Logger, conf *config.Config, wg *sync.WaitGroup,
 			start int, jump int, length int) {
 
@@ -157,12 +163,13 @@ func IndexFiles(files []string, indexingThreads int, targetDB string, targetCID
 						"file":  files[j],
 						"error": err.Error(),
 					}).Debug("An error was encountered while indexing a file.")
+					errs[j] = err
 					continue
 				}
 				indexedFiles[j] = indexedFile
 			}
 			wg.Done()
-		}(files, output, targetDB, targetCID, logger, conf, indexingWG, i, indexingThreads, n)
+		}(files, output, errs, targetDB, targetCID, logger, conf, indexingWG, i, indexingThreads, n)
 	}
 
 	indexingWG.Wait()
@@ -170,11 +177,13 @@ func IndexFiles(files []string, indexingThreads int, targetDB string, targetCID
 	// remove all nil values from the slice
 	errCount := 0
 	indexedFiles := make([]*IndexedFile, 0, len(output))
-	for _, file := range output {
+	var indexErrs []error
+	for i, file := range output {
 		if file != nil {
 			indexedFiles = append(indexedFiles, file)
 		} else {
 			errCount++
+			indexErrs = append(indexErrs, errs[i])
 		}
 	}
 	if errCount == len(output) {
@@ -182,5 +191,5 @@ func IndexFiles(files []string, indexingThreads int, targetDB string, targetCID
 		fmt.Println("\t[-] Exiting...")
 		os.Exit(0)
 	}
-	return indexedFiles
+	return indexedFiles, indexErrs
 }
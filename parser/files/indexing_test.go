@@ -0,0 +1,118 @@
+package files
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testConnRecord = "1600000000.123456\tC1\t10.0.0.5\t50000\t142.250.0.10\t443\ttcp\n"
+
+func writeTestLog(t *testing.T, name string, header ...string) string {
+	path := filepath.Join(t.TempDir(), name)
+	contents := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n" +
+		strings.Join(header, "\n") + "\n" + testConnRecord
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+	return path
+}
+
+func TestNewIndexedFileErrors(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	const (
+		connFields = "#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto"
+		connTypes  = "#types\ttime\tstring\taddr\tport\taddr\tport\tenum"
+	)
+
+	cases := []struct {
+		name     string
+		file     string
+		header   []string
+		expected error
+	}{
+		{
+			name:     "fields without types",
+			file:     "conn.log",
+			header:   []string{"#path\tconn", connFields, "#types\ttime\tstring"},
+			expected: ErrMalformedLog,
+		},
+		{
+			name:     "field type mismatch",
+			file:     "conn.log",
+			header:   []string{"#path\tconn", connFields, strings.Replace(connTypes, "time", "string", 1)},
+			expected: ErrMalformedLog,
+		},
+		{
+			name:     "unsupported path",
+			file:     "conn.log",
+			header:   []string{"#path\tnot_a_zeek_log", connFields, connTypes},
+			expected: ErrUnknownLogType,
+		},
+		{
+			name:     "unsupported file extension",
+			file:     "conn.txt",
+			header:   []string{"#path\tconn", connFields, connTypes},
+			expected: ErrUnknownLogType,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := writeTestLog(t, c.file, c.header...)
+			_, err := newIndexedFile(path, "test", 0, logger, conf)
+			require.NotNil(t, err)
+
+			assert.True(t, errors.Is(err, c.expected), "expected %v, got %v", c.expected, err)
+
+			var logErr *LogError
+			require.True(t, errors.As(err, &logErr))
+			assert.Equal(t, path, logErr.Path)
+		})
+	}
+
+	// a well formed log is indexed without error
+	path := writeTestLog(t, "conn.log", "#path\tconn", connFields, connTypes)
+	indexed, err := newIndexedFile(path, "test", 0, logger, conf)
+	require.Nil(t, err)
+	assert.Equal(t, conf.T.Structure.ConnTable, indexed.TargetCollection)
+
+	// errors opening the file are passed along
+	_, err = newIndexedFile(filepath.Join(t.TempDir(), "missing.log"), "test", 0, logger, conf)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
+
+func TestIndexFilesReturnsLogErrors(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	connFields := "#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto"
+	connTypes := "#types\ttime\tstring\taddr\tport\taddr\tport\tenum"
+	good := writeTestLog(t, "conn.log", "#path\tconn", connFields, connTypes)
+	bad := writeTestLog(t, "conn.log", "#path\tconn", connFields, "#types\ttime\tstring")
+
+	indexed, errs := IndexFiles([]string{good, bad}, 2, "test", 0, logger, conf)
+	require.Len(t, indexed, 1)
+	assert.Equal(t, good, indexed[0].Path)
+
+	// the file which could not be indexed is reported rather than dropped
+	require.Len(t, errs, 1)
+	var logErr *LogError
+	require.True(t, errors.As(errs[0], &logErr))
+	assert.Equal(t, bad, logErr.Path)
+	assert.True(t, errors.Is(errs[0], ErrMalformedLog))
+}
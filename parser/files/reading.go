@@ -21,30 +21,54 @@ import (
 	"github.com/activecm/rita/util"
 
 	jsoniter "github.com/json-iterator/go"
+	"github.com/klauspost/compress/zstd"
 	log "github.com/sirupsen/logrus"
 )
 
-// GatherLogFiles reads the files and directories looking for log and gz files
+// compressionExts lists the extensions of the compressed log files which can be read
+var compressionExts = []string{".gz", ".zst"}
+
+// GatherLogFiles reads the files and directories looking for log files, which
+// may be compressed, and tar or zip archives of log files. Archives are
+// returned as is and must be extracted with ExtractArchive before indexing.
 func GatherLogFiles(paths []string, logger *log.Logger) []string {
 	var toReturn []string
 
 	for _, path := range paths {
 		if util.IsDir(path) {
 			toReturn = append(toReturn, gatherDir(path, logger)...)
-		} else if strings.HasSuffix(path, ".gz") ||
-			strings.HasSuffix(path, ".log") {
+		} else if isLogFile(path) || IsArchive(path) {
 			toReturn = append(toReturn, path)
 		} else {
 			logger.WithFields(log.Fields{
 				"path": path,
-			}).Warn("Ignoring non .log or .gz file")
+			}).Warn("Ignoring non .log, .json, .gz, .zst, .tar, or .zip file")
 		}
 	}
 
 	return toReturn
 }
 
-// gatherDir reads the directory looking for log and .gz files
+// compressionExt returns the extension of a compressed log file or an empty
+// string if the file is not compressed
+func compressionExt(name string) string {
+	for _, ext := range compressionExts {
+		if strings.HasSuffix(name, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// isLogFile reports whether the file name is a .log or .json file or is compressed.
+// The format of compressed files is detected from their contents.
+func isLogFile(name string) bool {
+	return compressionExt(name) != "" ||
+		strings.HasSuffix(name, ".log") ||
+		strings.HasSuffix(name, ".json")
+}
+
+// gatherDir reads the directory looking for log files and archives
 func gatherDir(cpath string, logger *log.Logger) []string {
 	var toReturn []string
 	files, err := ioutil.ReadDir(cpath)
@@ -62,8 +86,7 @@ func gatherDir(cpath string, logger *log.Logger) []string {
 		// if file.IsDir() && file.Mode() != os.ModeSymlink {
 		// 	toReturn = append(toReturn, readDir(path.Join(cpath, file.Name()), logger)...)
 		// }
-		if !file.IsDir() && strings.HasSuffix(file.Name(), ".gz") ||
-			strings.HasSuffix(file.Name(), ".log") {
+		if !file.IsDir() && (isLogFile(file.Name()) || IsArchive(file.Name())) {
 			toReturn = append(toReturn, path.Join(cpath, file.Name()))
 		}
 	}
@@ -72,26 +95,28 @@ func gatherDir(cpath string, logger *log.Logger) []string {
 
 // GetFileScanner returns a buffered file scanner for a bro log file, a function to close the
 // underlying stream and any associated processors, as well as any error that may occur while
-// creating the scanner
+// creating the scanner. Compressed files are decompressed before they are scanned.
 func GetFileScanner(fileHandle *os.File) (scanner *bufio.Scanner, closer func() error, err error) {
 	// by default just close out the underlying file handle
 	closer = fileHandle.Close
 
-	ftype := fileHandle.Name()[len(fileHandle.Name())-3:]
-	if ftype != ".gz" && ftype != "log" {
-		return nil, closer, errors.New("filetype not recognized")
+	if !isLogFile(fileHandle.Name()) {
+		return nil, closer, fmt.Errorf("%w: filetype not recognized", ErrUnknownLogType)
 	}
 
-	if ftype == ".gz" {
-		var gzipReader io.Reader
-		gzipReader, closer, err = newGzipReader(fileHandle)
-		if err != nil {
-			return nil, closer, err
-		}
-		scanner = bufio.NewScanner(gzipReader)
-	} else {
-		scanner = bufio.NewScanner(fileHandle)
+	var reader io.Reader
+	switch compressionExt(fileHandle.Name()) {
+	case ".gz":
+		reader, closer, err = newGzipReader(fileHandle)
+	case ".zst":
+		reader, closer, err = newZstdReader(fileHandle)
+	default:
+		reader = fileHandle
+	}
+	if err != nil {
+		return nil, closer, err
 	}
+	scanner = bufio.NewScanner(reader)
 
 	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
 	return scanner, closer, nil
@@ -170,6 +195,22 @@ func newGzipReader(fileHandle io.ReadCloser) (reader io.Reader, closer func() er
 	return pipeR, closer, nil
 }
 
+//newZstdReader returns an uncompressed byte stream given a zstd compressed byte stream,
+//a function to close the decoder and the underlying stream, and any err that may occur
+//when opening the stream.
+func newZstdReader(fileHandle io.ReadCloser) (reader io.Reader, closer func() error, err error) {
+	decoder, err := zstd.NewReader(fileHandle)
+	if err != nil {
+		return nil, fileHandle.Close, err
+	}
+
+	closer = func() error {
+		decoder.Close()
+		return fileHandle.Close()
+	}
+	return decoder, closer, nil
+}
+
 // scanHeader scans the comment lines out of a bro file and returns a
 // BroHeader object containing the information. NOTE: This has the side
 // effect of advancing the fileScanner so that fileScanner.Text() will
@@ -191,7 +232,7 @@ func scanTSVHeader(fileScanner *bufio.Scanner) (*BroHeader, error) {
 				var err error
 				toReturn.Separator, err = strconv.Unquote("\"" + line[1] + "\"")
 				if err != nil {
-					return toReturn, err
+					return toReturn, fmt.Errorf("%w: invalid separator: %v", ErrMalformedLog, err)
 				}
 			case "set_separator":
 				toReturn.SetSep = line[1]
@@ -213,7 +254,7 @@ func scanTSVHeader(fileScanner *bufio.Scanner) (*BroHeader, error) {
 	}
 
 	if len(toReturn.Names) != len(toReturn.Types) {
-		return toReturn, errors.New("name / type mismatch")
+		return toReturn, fmt.Errorf("%w: name / type mismatch", ErrMalformedLog)
 	}
 	return toReturn, nil
 }
@@ -273,7 +314,7 @@ func mapZeekHeaderToParseType(header *BroHeader, broDataFactory func() pt.BroDat
 		}
 
 		if header.Types[index] != fieldInfo.zeekType {
-			err := errors.New("type mismatch found in log")
+			err := fmt.Errorf("%w: type mismatch found in log", ErrMalformedLog)
 			logger.WithFields(log.Fields{
 				"error":         err,
 				"type_in_log":   header.Types[index],
@@ -0,0 +1,102 @@
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	pt "github.com/activecm/rita/parser/parsetypes"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readTestConns indexes the conn log and parses each of its records
+func readTestConns(t *testing.T, path string, logger *log.Logger, conf *config.Config) (*IndexedFile, []*pt.Conn) {
+	indexed, err := newIndexedFile(path, "test", 0, logger, conf)
+	require.Nil(t, err)
+	require.Equal(t, conf.T.Structure.ConnTable, indexed.TargetCollection)
+
+	fileHandle, err := os.Open(path)
+	require.Nil(t, err)
+	scanner, closeScanner, err := GetFileScanner(fileHandle)
+	require.Nil(t, err)
+	defer closeScanner()
+
+	var conns []*pt.Conn
+	for scanner.Scan() {
+		if len(scanner.Bytes()) < 1 {
+			continue
+		}
+		var datum pt.BroData
+		if indexed.IsJSON() {
+			datum = ParseJSONLine(scanner.Bytes(), indexed.GetBroDataFactory(), logger)
+		} else {
+			datum = ParseTSVLine(scanner.Text(), indexed.GetHeader(), indexed.GetFieldMap(), indexed.GetBroDataFactory(), logger)
+		}
+		if datum != nil {
+			conns = append(conns, datum.(*pt.Conn))
+		}
+	}
+	require.Nil(t, scanner.Err())
+	return indexed, conns
+}
+
+func TestCompressedLogFormats(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	dir := filepath.Join("testdata", "compressed")
+	cases := []struct {
+		file string
+		json bool
+	}{
+		{"conn.log", false},
+		{"conn.log.gz", false},
+		{"conn.log.zst", false},
+		{"conn.json", true},
+		{"conn.json.gz", true},
+		{"conn.json.zst", true},
+	}
+
+	var expectedFiles []string
+	for _, c := range cases {
+		expectedFiles = append(expectedFiles, filepath.Join(dir, c.file))
+	}
+	assert.ElementsMatch(t, expectedFiles, GatherLogFiles([]string{dir}, logger))
+
+	for _, c := range cases {
+		t.Run(c.file, func(t *testing.T) {
+			indexed, conns := readTestConns(t, filepath.Join(dir, c.file), logger, conf)
+			assert.Equal(t, c.json, indexed.IsJSON(), "the format should be detected after decompressing")
+
+			require.Len(t, conns, 3)
+			for i, conn := range conns {
+				assert.Equal(t, int64(1600000000+i*60), conn.TimeStamp)
+				assert.Equal(t, "10.0.0.5", conn.Source)
+				assert.Equal(t, "203.0.113.7", conn.Destination)
+				assert.Equal(t, 443, conn.DestinationPort)
+				assert.Equal(t, int64(100+i), conn.OrigBytes)
+			}
+			assert.Equal(t, "CConn00", conns[0].UID)
+		})
+	}
+}
+
+func TestIsLogFile(t *testing.T) {
+	for _, name := range []string{"conn.log", "conn.log.gz", "conn.log.zst", "conn.json", "conn.json.gz", "conn.json.zst"} {
+		assert.True(t, isLogFile(name), name)
+	}
+	for _, name := range []string{"conn.txt", "conn.json.bz2", "conn.zip", "gz"} {
+		assert.False(t, isLogFile(name), name)
+	}
+
+	assert.Equal(t, ".gz", compressionExt("conn.json.gz"))
+	assert.Equal(t, ".zst", compressionExt("conn.log.zst"))
+	assert.Equal(t, "", compressionExt("conn.json"))
+}
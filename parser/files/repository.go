@@ -41,12 +41,26 @@ type IndexedFile struct {
 	broDataFactory   func() pt.BroData
 	fieldMap         ZeekHeaderIndexMap
 	json             bool
+	location         string // where the file is read from if it isn't at Path, e.g. extracted from an archive
 }
 
 //The following functions are for interacting with the private data in
 //IndexedFile as if it were public. The fields are private so they don't get
 //marshalled into MongoDB
 
+//SetLocation records that the file is read from location rather than from Path
+func (i *IndexedFile) SetLocation(location string) {
+	i.location = location
+}
+
+//Location returns the path the file is read from
+func (i *IndexedFile) Location() string {
+	if i.location != "" {
+		return i.location
+	}
+	return i.Path
+}
+
 //IsJSON returns whether the file is a json file
 func (i *IndexedFile) IsJSON() bool {
 	return i.json
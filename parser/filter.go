@@ -13,11 +13,35 @@ type filter struct {
 	internal       []*net.IPNet
 	alwaysIncluded []*net.IPNet
 	neverIncluded  []*net.IPNet
+	// neverIncludedSources is only checked against the source of a connection
+	neverIncludedSources []*net.IPNet
 
 	alwaysIncludedDomain []string
 	neverIncludedDomain  []string
 
+	// baselineSubnets and baselineDomains hold the known-good destinations
+	// which are skipped so only newly-seen destinations are analyzed
+	baselineSubnets []*net.IPNet
+	baselineDomains []string
+
 	filterExternalToInternal bool
+	filterLocalAddresses     bool
+
+	// collapseForwardedLegs credits proxied requests forwarded by a proxy to the client named by X-Forwarded-For
+	collapseForwardedLegs bool
+	// forwardingProxies are the only sources whose X-Forwarded-For headers are trusted
+	forwardingProxies []*net.IPNet
+
+	// collectRespTs keeps the response timestamps of connections for ResponseTimestampScoring
+	collectRespTs bool
+	// collectDurations keeps the durations of connections for ConnectionDurationScoring
+	collectDurations bool
+
+	// beaconServices limits the connections whose series are kept for beacon analysis (nil keeps every connection)
+	beaconServices *util.ServiceFilter
+
+	// sampleSize is the number of uids and log references kept for each unique connection
+	sampleSize int
 }
 
 func newFilter(conf *config.Config) filter {
@@ -25,9 +49,17 @@ func newFilter(conf *config.Config) filter {
 		internal:                 util.ParseSubnets(conf.S.Filtering.InternalSubnets),
 		alwaysIncluded:           util.ParseSubnets(conf.S.Filtering.AlwaysInclude),
 		neverIncluded:            util.ParseSubnets(conf.S.Filtering.NeverInclude),
+		neverIncludedSources:     util.ParseSubnets(conf.S.Filtering.NeverIncludedSources),
 		alwaysIncludedDomain:     conf.S.Filtering.AlwaysIncludeDomain,
 		neverIncludedDomain:      conf.S.Filtering.NeverIncludeDomain,
 		filterExternalToInternal: conf.S.Filtering.FilterExternalToInternal,
+		filterLocalAddresses:     conf.S.Filtering.FilterLocalAddresses,
+		collapseForwardedLegs:    conf.S.BeaconProxy.CollapseForwardedLegs,
+		forwardingProxies:        util.ParseSubnets(conf.S.BeaconProxy.ForwardingProxies),
+		collectRespTs:            conf.S.Beacon.RespTsEnabled,
+		collectDurations:         conf.S.Beacon.ConnDurEnabled,
+		beaconServices:           util.NewServiceFilter(conf.S.Beacon.IncludedServices),
+		sampleSize:               conf.S.Beacon.UIDSampleSize,
 	}
 }
 
@@ -35,9 +67,12 @@ func newFilter(conf *config.Config) filter {
 // This is determined by the following rules, in order:
 //   1. Not filtered if either IP is on the AlwaysInclude list
 //   2. Filtered if either IP is on the NeverInclude list
-//   3. Not filtered if InternalSubnets is empty
-//   4. Filtered if both IPs are internal or both are external
-//   5. Not filtered in all other cases
+//   3. Filtered if the source IP is on the NeverIncludedSources list
+//   4. Filtered if the destination IP is in the baseline destinations
+//   5. Filtered if either IP is a loopback or link local address (if enabled)
+//   6. Not filtered if InternalSubnets is empty
+//   7. Filtered if both IPs are internal or both are external
+//   8. Not filtered in all other cases
 func (fs *filter) filterConnPair(srcIP net.IP, dstIP net.IP) bool {
 	// check if on always included list
 	isSrcIncluded := util.ContainsIP(fs.alwaysIncluded, srcIP)
@@ -57,6 +92,21 @@ func (fs *filter) filterConnPair(srcIP net.IP, dstIP net.IP) bool {
 		return true
 	}
 
+	// if the source IP is on the NeverIncludedSources list, filter applies
+	if util.ContainsIP(fs.neverIncludedSources, srcIP) {
+		return true
+	}
+
+	// if the destination IP is a known-good baseline destination, filter applies
+	if fs.isBaselineDestination(dstIP) {
+		return true
+	}
+
+	// if either IP is a loopback or link local address, filter applies
+	if fs.isFilteredLocalAddress(srcIP) || fs.isFilteredLocalAddress(dstIP) {
+		return true
+	}
+
 	// if no internal subnets are defined, filter does not apply
 	// this is was the default behavior before InternalSubnets was added
 	if len(fs.internal) == 0 {
@@ -90,7 +140,8 @@ func (fs *filter) filterConnPair(srcIP net.IP, dstIP net.IP) bool {
 // This is determined by the following rules, in order:
 //   1. Not filtered IP is on the AlwaysInclude list
 //   2. Filtered IP is on the NeverInclude list
-//   3. Not filtered in all other cases
+//   3. Filtered IP is a loopback or link local address (if enabled)
+//   4. Not filtered in all other cases
 func (fs *filter) filterSingleIP(IP net.IP) bool {
 	// check if on always included list
 	if util.ContainsIP(fs.alwaysIncluded, IP) {
@@ -102,10 +153,34 @@ func (fs *filter) filterSingleIP(IP net.IP) bool {
 		return true
 	}
 
+	// check if the IP is a loopback or link local address
+	if fs.isFilteredLocalAddress(IP) {
+		return true
+	}
+
 	// default to not filter the IP address
 	return false
 }
 
+// filterSourceIP returns true if the IP originating a request is filtered/excluded.
+// This is determined by the following rules, in order:
+//   1. Not filtered IP is on the AlwaysInclude list
+//   2. Filtered IP is on the NeverIncludedSources list
+//   3. Filtered in any case filterSingleIP filters the IP
+func (fs *filter) filterSourceIP(IP net.IP) bool {
+	// check if on always included list
+	if util.ContainsIP(fs.alwaysIncluded, IP) {
+		return false
+	}
+
+	// check if on never included sources list
+	if util.ContainsIP(fs.neverIncludedSources, IP) {
+		return true
+	}
+
+	return fs.filterSingleIP(IP)
+}
+
 // filterDomain returns true if a domain is filtered/excluded.
 // This is determined by the following rules, in order:
 //   1. Not filtered if domain is on the AlwaysInclude list
@@ -132,6 +207,13 @@ func (fs *filter) filterDomain(domain string) bool {
 	return false
 }
 
+// isFilteredLocalAddress returns true if loopback and link local filtering is
+// enabled and the IP is a loopback (127.0.0.0/8, ::1) or link local unicast
+// (169.254.0.0/16, fe80::/10) address
+func (fs *filter) isFilteredLocalAddress(IP net.IP) bool {
+	return fs.filterLocalAddresses && (IP.IsLoopback() || IP.IsLinkLocalUnicast())
+}
+
 func (fs *filter) checkIfInternal(host net.IP) bool {
 	return util.ContainsIP(fs.internal, host)
 }
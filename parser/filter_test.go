@@ -207,3 +207,109 @@ func TestFilterSingleIP(t *testing.T) {
 		assert.Equal(t, test.out, output, test.msg)
 	}
 }
+
+func TestFilterLoopbackAndLinkLocal(t *testing.T) {
+
+	fsTest := &filter{
+		internal:             util.ParseSubnets([]string{"10.0.0.0/8"}),
+		alwaysIncluded:       util.ParseSubnets([]string{"169.254.10.10/32"}),
+		filterLocalAddresses: true,
+	}
+
+	internal := "10.0.0.1"
+	external := "1.1.1.1"
+
+	testCases := []testCase{
+		{internal, "127.0.0.1", true, "IPv4 loopback destination should be filtered"},
+		{"127.10.20.30", external, true, "IPv4 loopback source anywhere in 127.0.0.0/8 should be filtered"},
+		{internal, "169.254.169.254", true, "IPv4 link local destination should be filtered"},
+		{"169.254.1.1", external, true, "IPv4 link local source should be filtered"},
+		{"::1", "2001:4860:4860::8888", true, "IPv6 loopback source should be filtered"},
+		{"2001:4860:4860::8888", "::1", true, "IPv6 loopback destination should be filtered"},
+		{"fe80::1", "2001:4860:4860::8888", true, "IPv6 link local source should be filtered"},
+		{"2001:4860:4860::8888", "febf::1", true, "IPv6 link local destination at the end of fe80::/10 should be filtered"},
+		{internal, "169.254.10.10", false, "AlwaysInclude should override the loopback and link local filter"},
+		{internal, "169.255.0.1", false, "addresses just outside of 169.254.0.0/16 should not be filtered"},
+		{internal, "fec0::1", false, "addresses just outside of fe80::/10 should not be filtered"},
+		{internal, external, false, "internal to external should not be filtered"},
+	}
+
+	for _, test := range testCases {
+		output := fsTest.filterConnPair(net.ParseIP(test.src), net.ParseIP(test.dst))
+		assert.Equal(t, test.out, output, test.msg)
+	}
+
+	singleIPCases := []testCaseSingleIP{
+		{"127.0.0.1", true, "IPv4 loopback should be filtered"},
+		{"169.254.0.1", true, "IPv4 link local should be filtered"},
+		{"::1", true, "IPv6 loopback should be filtered"},
+		{"fe80::abcd", true, "IPv6 link local should be filtered"},
+		{"169.254.10.10", false, "AlwaysInclude should override the loopback and link local filter"},
+		{"8.8.8.8", false, "public addresses should not be filtered"},
+	}
+
+	for _, test := range singleIPCases {
+		output := fsTest.filterSingleIP(net.ParseIP(test.ip))
+		assert.Equal(t, test.out, output, test.msg)
+	}
+
+	// the filter can be turned off
+	fsTest.filterLocalAddresses = false
+	assert.False(t, fsTest.filterConnPair(net.ParseIP(internal), net.ParseIP("127.0.0.1")),
+		"loopback should not be filtered when the filter is disabled")
+	assert.False(t, fsTest.filterConnPair(net.ParseIP("fe80::1"), net.ParseIP(internal)),
+		"link local should not be filtered when the filter is disabled")
+	assert.False(t, fsTest.filterSingleIP(net.ParseIP("169.254.0.1")),
+		"link local should not be filtered when the filter is disabled")
+}
+
+func TestFilterNeverIncludedSources(t *testing.T) {
+
+	fsTest := &filter{
+		internal:             util.ParseSubnets([]string{"10.0.0.0/8", "fd00::/8"}),
+		alwaysIncluded:       util.ParseSubnets([]string{"10.0.0.60/32"}),
+		neverIncludedSources: util.ParseSubnets([]string{"10.0.0.50/32", "10.1.0.0/16", "fd00::50/128", "10.0.0.60/32"}),
+	}
+
+	scanner := "10.0.0.50"
+	scannerSubnet := "10.1.2.3"
+	scannerIPv6 := "fd00::50"
+	internal := "10.0.0.1"
+	external := "1.1.1.1"
+	externalIPv6 := "2001:4860:4860::8888"
+
+	testCases := []testCase{
+		{scanner, external, true, "connections from an excluded source should be filtered"},
+		{scannerSubnet, external, true, "connections from an excluded source subnet should be filtered"},
+		{scannerIPv6, externalIPv6, true, "connections from an excluded IPv6 source should be filtered"},
+		{external, scanner, false, "connections to an excluded source should not be filtered"},
+		{external, scannerIPv6, false, "connections to an excluded IPv6 source should not be filtered"},
+		{internal, external, false, "connections from other sources should not be filtered"},
+		{"10.0.0.60", external, false, "AlwaysInclude should override NeverIncludedSources"},
+	}
+
+	for _, test := range testCases {
+		output := fsTest.filterConnPair(net.ParseIP(test.src), net.ParseIP(test.dst))
+		assert.Equal(t, test.out, output, test.msg)
+	}
+
+	singleIPCases := []testCaseSingleIP{
+		{scanner, true, "DNS queries from an excluded source should be filtered"},
+		{scannerSubnet, true, "DNS queries from an excluded source subnet should be filtered"},
+		{scannerIPv6, true, "DNS queries from an excluded IPv6 source should be filtered"},
+		{internal, false, "DNS queries from other sources should not be filtered"},
+		{"10.0.0.60", false, "AlwaysInclude should override NeverIncludedSources"},
+	}
+
+	for _, test := range singleIPCases {
+		output := fsTest.filterSourceIP(net.ParseIP(test.ip))
+		assert.Equal(t, test.out, output, test.msg)
+	}
+
+	// the list only applies to sources, so it does not affect filterSingleIP
+	assert.False(t, fsTest.filterSingleIP(net.ParseIP(scanner)))
+
+	// filterSourceIP still applies the rules shared by every IP
+	fsTest.neverIncluded = util.ParseSubnets([]string{"10.0.0.2/32"})
+	assert.True(t, fsTest.filterSourceIP(net.ParseIP("10.0.0.2")))
+}
@@ -1,9 +1,13 @@
 package parser
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net"
 	"os"
+	"path/filepath"
 	"sort"
 	"sync"
 	"time"
@@ -29,7 +33,7 @@ import (
 	"github.com/activecm/rita/resources"
 	"github.com/activecm/rita/util"
 
-	"github.com/globalsign/mgo/bson"
+	"github.com/globalsign/mgo"
 	"github.com/pbnjay/memory"
 	log "github.com/sirupsen/logrus"
 )
@@ -39,12 +43,21 @@ type (
 	FSImporter struct {
 		filter
 
-		log      *log.Logger
-		config   *config.Config
-		database *database.DB
-		metaDB   *database.MetaDB
+		log         *log.Logger // logs the reading and parsing of the log files
+		analysisLog *log.Logger // logs the analysis of the parsed records
+		config      *config.Config
+		database    *database.DB
+		metaDB      *database.MetaDB
 
 		batchSizeBytes int64
+		progress       *ImportProgress
+
+		ctx            context.Context // stops the import when cancelled
+		deadline       time.Time       // beacon analysis is checkpointed after the deadline (zero for no deadline)
+		beaconsPending bool            // set if beacon analysis was checkpointed during this import
+
+		archiveDirs    []string          // temporary directories holding the logs extracted from archives
+		archiveSources map[string]string // maps each extracted log to its archive path plus member name
 	}
 
 	trustedAppTiplet struct {
@@ -60,11 +73,14 @@ func NewFSImporter(res *resources.Resources) *FSImporter {
 	batchSize := int64(util.MaxUint64(4*(1<<30), (memory.TotalMemory() / 2)))
 	return &FSImporter{
 		filter:         newFilter(res.Config),
-		log:            res.Log,
+		log:            res.ImportLog,
+		analysisLog:    res.AnalysisLog,
 		config:         res.Config,
 		database:       res.DB,
 		metaDB:         res.MetaDB,
 		batchSizeBytes: batchSize,
+		progress:       new(ImportProgress),
+		ctx:            context.Background(),
 	}
 }
 
@@ -73,24 +89,131 @@ var trustedAppReferenceList = [...]trustedAppTiplet{
 	{"tcp", 443, "ssl"},
 }
 
+// SetMaxRuntime limits how long beacon analysis may run before the remaining
+// unique connections are checkpointed. The analysis is resumed by the next import
+// into the same dataset. A maxRuntime of 0 removes the limit. The other analyses
+// are not limited and always run to completion.
+func (fs *FSImporter) SetMaxRuntime(maxRuntime time.Duration) {
+	if maxRuntime <= 0 {
+		fs.deadline = time.Time{}
+		return
+	}
+	fs.deadline = time.Now().Add(maxRuntime)
+}
+
+// SetContext sets the context which interrupts the import. Once ctx is cancelled,
+// beacon analysis stops, no further batches are imported, and Run returns the context's error.
+// The files of the interrupted batch are not recorded as imported.
+func (fs *FSImporter) SetContext(ctx context.Context) {
+	fs.ctx = ctx
+}
+
 // GetInternalSubnets returns the internal subnets from the config file
 func (fs *FSImporter) GetInternalSubnets() []*net.IPNet {
 	return fs.internal
 }
 
-// CollectFileDetails reads and hashes the files
-func (fs *FSImporter) CollectFileDetails(importFiles []string, threads int) []*files.IndexedFile {
+// Progress returns the running counts for the import. The counts are safe
+// to read while the import is running.
+func (fs *FSImporter) Progress() *ImportProgress {
+	return fs.progress
+}
+
+// CollectFileDetails reads and hashes the files. The logs held by tar and zip
+// archives are extracted to temporary directories, which are removed by
+// RemoveExtractedArchives once the import is finished. The files which could
+// not be indexed are returned as *files.LogError values.
+func (fs *FSImporter) CollectFileDetails(importFiles []string, threads int) ([]*files.IndexedFile, []error) {
 	// find all of the potential bro log paths
-	logFiles := files.GatherLogFiles(importFiles, fs.log)
+	logFiles := fs.extractArchives(files.GatherLogFiles(importFiles, fs.log))
 
 	// hash the files and get their stats
-	return files.IndexFiles(
+	indexedFiles, indexErrs := files.IndexFiles(
 		logFiles, threads, fs.database.GetSelectedDB(), fs.config.S.Rolling.CurrentChunk, fs.log, fs.config,
 	)
+
+	// the extracted logs are removed after the import, so the archive they
+	// came from is recorded instead
+	for _, indexedFile := range indexedFiles {
+		if source, ok := fs.archiveSources[indexedFile.Path]; ok {
+			indexedFile.SetLocation(indexedFile.Path)
+			indexedFile.Path = source
+		}
+	}
+	for _, indexErr := range indexErrs {
+		var logErr *files.LogError
+		if errors.As(indexErr, &logErr) {
+			if source, ok := fs.archiveSources[logErr.Path]; ok {
+				logErr.Path = source
+			}
+		}
+	}
+	return indexedFiles, indexErrs
+}
+
+// extractArchives replaces the archives in logFiles with the log files they
+// hold. Archives which cannot be extracted are skipped.
+func (fs *FSImporter) extractArchives(logFiles []string) []string {
+	var toReturn []string
+	for _, logFile := range logFiles {
+		if !files.IsArchive(logFile) {
+			toReturn = append(toReturn, logFile)
+			continue
+		}
+
+		dir, err := ioutil.TempDir("", "rita-archive-")
+		if err != nil {
+			fs.log.WithFields(log.Fields{
+				"path":  logFile,
+				"error": err.Error(),
+			}).Error("Could not create a directory to extract archive into")
+			continue
+		}
+		fs.archiveDirs = append(fs.archiveDirs, dir)
+
+		extracted, err := files.ExtractArchive(logFile, dir)
+		if err != nil {
+			fs.log.WithFields(log.Fields{
+				"path":  logFile,
+				"error": err.Error(),
+			}).Error("Could not extract archive")
+			fmt.Printf("\t[!] Skipping archive %s: %v\n", logFile, err)
+			continue
+		}
+		toReturn = append(toReturn, extracted...)
+
+		if fs.archiveSources == nil {
+			fs.archiveSources = make(map[string]string)
+		}
+		for _, extractedPath := range extracted {
+			member, err := filepath.Rel(dir, extractedPath)
+			if err != nil {
+				continue
+			}
+			fs.archiveSources[extractedPath] = filepath.Join(logFile, member)
+		}
+	}
+	return toReturn
+}
+
+// RemoveExtractedArchives deletes the logs extracted from archives by
+// CollectFileDetails
+func (fs *FSImporter) RemoveExtractedArchives() {
+	for _, dir := range fs.archiveDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			fs.log.WithFields(log.Fields{
+				"path":  dir,
+				"error": err.Error(),
+			}).Warn("Could not remove extracted archive")
+		}
+	}
+	fs.archiveDirs = nil
+	fs.archiveSources = nil
 }
 
-// Run starts the importing
-func (fs *FSImporter) Run(indexedFiles []*files.IndexedFile, threads int) {
+// Run starts the importing. Errors which stop the import are returned, failed
+// database writes as a *DatabaseWriteError.
+func (fs *FSImporter) Run(indexedFiles []*files.IndexedFile, threads int) error {
 	start := time.Now()
 
 	fmt.Println("\t[-] Verifying log files have not been previously parsed into the target dataset ... ")
@@ -98,66 +221,84 @@ func (fs *FSImporter) Run(indexedFiles []*files.IndexedFile, threads int) {
 	// won't be imported into the same database twice.
 	indexedFiles = fs.metaDB.FilterOutPreviouslyIndexedFiles(indexedFiles, fs.database.GetSelectedDB())
 
+	// the beacons of an existing dataset must be selected the way they were created
+	if err := fs.checkHashPairKeys(); err != nil {
+		return err
+	}
+
+	// finish any beacon analysis which a previous import stopped at its maximum runtime.
+	// This runs before any outdated chunk data is removed below.
+	resumed, err := fs.resumeBeacons()
+	if ctxErr := fs.ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		return err
+	}
+
 	// if all files were removed because they've already been imported, handle error
 	if !(len(indexedFiles) > 0) {
+		if resumed {
+			fs.markAnalyzed()
+			return fs.pruneChunks()
+		}
 		if fs.config.S.Rolling.Rolling {
 			fmt.Println("\t[!] All files pertaining to the current chunk entry have already been parsed into database: ", fs.database.GetSelectedDB())
 		} else {
 			fmt.Println("\t[!] All files in this directory have already been parsed into database: ", fs.database.GetSelectedDB())
 		}
-		return
+		return nil
 	}
 
 	// Add new metadatabase record for db if doesn't already exist
 	dbExists, err := fs.metaDB.DBExists(fs.database.GetSelectedDB())
 	if err != nil {
-		fs.log.WithFields(log.Fields{
-			"err":      err,
-			"database": fs.database.GetSelectedDB(),
-		}).Error("Could not check if metadatabase record exists for target database")
-		fmt.Printf("\t[!] %v", err.Error())
+		return fmt.Errorf("could not check if metadatabase record exists for target database: %w", err)
 	}
 
 	if !dbExists {
 		err := fs.metaDB.AddNewDB(fs.database.GetSelectedDB(), fs.config.S.Rolling.CurrentChunk, fs.config.S.Rolling.TotalChunks)
 		if err != nil {
-			fs.log.WithFields(log.Fields{
-				"err":      err,
-				"database": fs.database.GetSelectedDB(),
-			}).Error("Could not add metadatabase record for new database")
-			fmt.Printf("\t[!] %v", err.Error())
+			return &DatabaseWriteError{
+				Database: fs.database.GetSelectedDB(),
+				Op:       "adding metadatabase record for new database",
+				Err:      err,
+			}
 		}
 	}
 
-	if fs.config.S.Rolling.Rolling {
+	// chunks assigned by record timestamps are checked for outdated data as they are built
+	if fs.config.S.Rolling.Rolling && fs.chunkDuration() == 0 {
 		err := fs.metaDB.SetRollingSettings(fs.database.GetSelectedDB(), fs.config.S.Rolling.CurrentChunk, fs.config.S.Rolling.TotalChunks)
 		if err != nil {
-			fs.log.WithFields(log.Fields{
-				"err":      err,
-				"database": fs.database.GetSelectedDB(),
-			}).Error("Could not update rolling database settings for database")
-			fmt.Printf("\t[!] %v", err.Error())
+			return &DatabaseWriteError{
+				Database: fs.database.GetSelectedDB(),
+				Op:       "updating rolling database settings",
+				Err:      err,
+			}
 		}
 
 		chunkSet, err := fs.metaDB.IsChunkSet(fs.config.S.Rolling.CurrentChunk, fs.database.GetSelectedDB())
 		if err != nil {
-			fmt.Println("\t[!] Could not find CID List entry in metadatabase")
-			return
+			return fmt.Errorf("could not find CID List entry in metadatabase: %w", err)
 		}
 
 		if chunkSet {
 			fmt.Println("\t[-] Removing outdated data from rolling dataset ... ")
 			err := fs.removeAnalysisChunk(fs.config.S.Rolling.CurrentChunk)
 			if err != nil {
-				fmt.Println("\t[!] Failed to remove outdata data from rolling dataset")
-				return
+				return &DatabaseWriteError{
+					Database: fs.database.GetSelectedDB(),
+					Op:       "removing outdated data from rolling dataset",
+					Err:      err,
+				}
 			}
 		}
 	}
 
 	// create blacklisted reference Collection if blacklisted module is enabled
 	if fs.config.S.Blacklisted.Enabled {
-		blacklist.BuildBlacklistedCollections(fs.database, fs.config, fs.log)
+		blacklist.BuildBlacklistedCollections(fs.database, fs.config, fs.analysisLog)
 	}
 
 	// batch up the indexed files so as not to read too much in at one time
@@ -166,63 +307,58 @@ func (fs *FSImporter) Run(indexedFiles []*files.IndexedFile, threads int) {
 	for i, indexedFileBatch := range batchedIndexedFiles {
 		fmt.Printf("\t[-] Processing batch %d of %d\n", i+1, len(batchedIndexedFiles))
 
-		// parse in those files!
-		retVals := fs.parseFiles(indexedFileBatch, threads, fs.log)
-		// Set chunk before we continue so if process dies, we still verify with a delete if
-		// any data was written out.
-		fs.metaDB.SetChunk(fs.config.S.Rolling.CurrentChunk, fs.database.GetSelectedDB(), true)
-
-		// build Hosts table.
-		fs.buildHosts(retVals.HostMap)
-
-		// build Uconns table. Must go before beacons.
-		fs.buildUconns(retVals.UniqueConnMap, retVals.HostMap)
-
-		// build uconnsProxy table. Must go before proxy beacons
-		fs.buildUconnsProxy(retVals.ProxyUniqueConnMap)
-
-		// build SNIconns table. Must go before SNI beacons
-		fs.buildSNIConns(retVals.TLSConnMap, retVals.HTTPConnMap, retVals.ZeekUIDMap, retVals.HostMap)
-
-		// update ts range for dataset (needs to be run before beacons)
-		minTimestamp, maxTimestamp := fs.updateTimestampRange()
-
-		// build or update the exploded DNS table. Must go before hostnames
-		fs.buildExplodedDNS(retVals.ExplodedDNSMap)
-
-		// build or update the exploded DNS table
-		fs.buildHostnames(retVals.HostnameMap)
-
-		// build or update Beacons table
-		fs.buildBeacons(retVals.UniqueConnMap, retVals.HostMap, minTimestamp, maxTimestamp)
-
-		// build or update the Proxy Beacons Table
-		fs.buildProxyBeacons(retVals.ProxyUniqueConnMap, retVals.HostMap, minTimestamp, maxTimestamp)
-
-		// build or update SNI Beacons Table
-		fs.buildSNIBeacons(retVals.TLSConnMap, retVals.HTTPConnMap, retVals.HostMap, minTimestamp, maxTimestamp)
-
-		// build or update UserAgent table
-		fs.buildUserAgent(retVals.UseragentMap)
+		if fs.chunkDuration() > 0 {
+			// split the records into chunks by their timestamps
+			err := fs.analyzeChunks(fs.parseFilesByChunk(indexedFileBatch, threads, fs.log))
+			if err != nil {
+				return err
+			}
+		} else {
+			// parse in those files!
+			retVals := fs.parseFiles(indexedFileBatch, threads, fs.log)
+			// Set chunk before we continue so if process dies, we still verify with a delete if
+			// any data was written out.
+			err := fs.metaDB.SetChunk(fs.config.S.Rolling.CurrentChunk, fs.database.GetSelectedDB(), true)
+			if err != nil {
+				return &DatabaseWriteError{
+					Database: fs.database.GetSelectedDB(),
+					Op:       "marking chunk as imported",
+					Err:      err,
+				}
+			}
 
-		// build or update Certificate table
-		fs.buildCertificates(retVals.CertificateMap)
+			// a failed analysis leaves the files to be imported again
+			err = fs.analyze(retVals)
+			if err != nil && fs.ctx.Err() == nil {
+				return err
+			}
+		}
 
-		// update blacklisted peers in hosts collection
-		fs.markBlacklistedPeers(retVals.HostMap)
+		// the analysis of an interrupted batch is incomplete, so its files are left to be imported again
+		if err := fs.ctx.Err(); err != nil {
+			return err
+		}
 
 		// record file+database name hash in metadabase to prevent duplicate content
 		fmt.Println("\t[-] Indexing log entries ... ")
 		err := fs.metaDB.AddNewFilesToIndex(indexedFileBatch)
 		if err != nil {
-			fs.log.Error("Could not update the list of parsed files")
+			return &DatabaseWriteError{
+				Database: fs.database.GetSelectedDB(),
+				Op:       "updating the list of parsed files",
+				Err:      err,
+			}
 		}
 
 	}
 
-	// mark results as imported and analyzed
-	fmt.Println("\t[-] Updating metadatabase ... ")
-	fs.metaDB.MarkDBAnalyzed(fs.database.GetSelectedDB(), true)
+	fs.markAnalyzed()
+
+	// remove the chunks which have fallen out of the rolling window
+	err = fs.pruneChunks()
+	if err != nil {
+		return err
+	}
 
 	progTime := time.Now()
 	fs.log.WithFields(
@@ -241,6 +377,77 @@ func (fs *FSImporter) Run(indexedFiles []*files.IndexedFile, threads int) {
 	).Info("Finished importing log files")
 
 	fmt.Println("\t[-] Done!")
+	return nil
+}
+
+// analyze builds the analysis results for the parsed records into the current chunk.
+// Returns an error if the beacon analysis failed, in which case the remaining
+// results are not built.
+func (fs *FSImporter) analyze(retVals ParseResults) error {
+	// record the time range of the chunk so overlapping chunks can be found
+	if min, max, ok := connTimestampRange(retVals.UniqueConnMap); ok {
+		fs.metaDB.AddChunkTSRange(fs.config.S.Rolling.CurrentChunk, fs.database.GetSelectedDB(), min, max)
+	}
+
+	// build Hosts table.
+	fs.buildHosts(retVals.HostMap)
+
+	// build Uconns table. Must go before beacons.
+	fs.buildUconns(retVals.UniqueConnMap, retVals.HostMap)
+
+	// collapse the legs of proxied requests which were logged twice. Must go before uconnsProxy.
+	if fs.config.S.BeaconProxy.CollapseForwardedLegs {
+		for _, input := range retVals.ProxyUniqueConnMap {
+			collapseForwardedLegs(input, fs.config.S.BeaconProxy.ForwardedLegWindow)
+		}
+	}
+
+	// build uconnsProxy table. Must go before proxy beacons
+	fs.buildUconnsProxy(retVals.ProxyUniqueConnMap)
+
+	// build SNIconns table. Must go before SNI beacons
+	fs.buildSNIConns(retVals.TLSConnMap, retVals.HTTPConnMap, retVals.ZeekUIDMap, retVals.HostMap)
+
+	// update ts range for dataset (needs to be run before beacons)
+	minTimestamp, maxTimestamp := fs.updateTimestampRange()
+
+	// build or update the exploded DNS table. Must go before hostnames
+	fs.buildExplodedDNS(retVals.ExplodedDNSMap)
+
+	// build or update the exploded DNS table
+	fs.buildHostnames(retVals.HostnameMap)
+
+	// build or update Beacons table
+	if err := fs.buildBeacons(retVals.UniqueConnMap, retVals.HostMap, minTimestamp, maxTimestamp); err != nil {
+		return err
+	}
+
+	// build or update the Proxy Beacons Table
+	fs.buildProxyBeacons(retVals.ProxyUniqueConnMap, retVals.HostMap, minTimestamp, maxTimestamp)
+
+	// build or update SNI Beacons Table
+	fs.buildSNIBeacons(retVals.TLSConnMap, retVals.HTTPConnMap, retVals.HostMap, minTimestamp, maxTimestamp)
+
+	// build or update UserAgent table
+	fs.buildUserAgent(retVals.UseragentMap)
+
+	// build or update Certificate table
+	fs.buildCertificates(retVals.CertificateMap)
+
+	// update blacklisted peers in hosts collection
+	fs.markBlacklistedPeers(retVals.HostMap)
+	return nil
+}
+
+// markAnalyzed marks the results as imported and analyzed unless beacon analysis
+// was checkpointed and still needs to be resumed
+func (fs *FSImporter) markAnalyzed() {
+	fmt.Println("\t[-] Updating metadatabase ... ")
+	fs.metaDB.MarkDBAnalyzed(fs.database.GetSelectedDB(), !fs.beaconsPending)
+	fs.metaDB.SetConfigSnapshot(fs.database.GetSelectedDB(), config.NewSnapshot(&fs.config.S))
+	if fs.beaconsPending {
+		fmt.Println("\t[!] Beacon analysis reached the maximum runtime. Run the import again to resume it.")
+	}
 }
 
 // batchFilesBySize takes in an slice of indexedFiles and splits the array into
@@ -308,11 +515,33 @@ func batchFilesBySize(indexedFiles []*files.IndexedFile, size int64) [][]*files.
 // a MongoDB datastore object to store the bro data in, and a logger to report
 // errors and parses the bro files line by line into the database.
 func (fs *FSImporter) parseFiles(indexedFiles []*files.IndexedFile, parsingThreads int, logger *log.Logger) ParseResults {
+	retVals := newParseResults()
+	fs.parseFilesInto(indexedFiles, parsingThreads, logger, func(parsetypes.BroData) (ParseResults, bool) {
+		return retVals, true
+	})
+	return retVals
+}
+
+// setProvenance records the log file and line a connection was read from
+// if the Provenance config section asks for it
+func (fs *FSImporter) setProvenance(conn *parsetypes.Conn, path string, line int64) {
+	if !fs.config.S.Provenance.Enabled {
+		return
+	}
+	conn.LogPath = path
+	if fs.config.S.Provenance.LineNumbers {
+		conn.LogLine = line
+	}
+}
+
+// parseFilesInto parses the bro files line by line, adding each record to the
+// results returned by resultsFor. Records are skipped if resultsFor returns false.
+func (fs *FSImporter) parseFilesInto(indexedFiles []*files.IndexedFile, parsingThreads int, logger *log.Logger,
+	resultsFor func(parsetypes.BroData) (ParseResults, bool)) {
 
 	fmt.Println("\t[-] Parsing logs to: " + fs.database.GetSelectedDB() + " ... ")
 
 	parseStartTime := time.Now()
-	retVals := newParseResults()
 
 	//set up parallel parsing
 	n := len(indexedFiles)
@@ -327,12 +556,13 @@ func (fs *FSImporter) parseFiles(indexedFiles []*files.IndexedFile, parsingThrea
 			for j := start; j < length; j += jump {
 
 				// open the file
-				fileHandle, err := os.Open(indexedFiles[j].Path)
+				fileHandle, err := os.Open(indexedFiles[j].Location())
 				if err != nil {
 					logger.WithFields(log.Fields{
 						"file":  indexedFiles[j].Path,
 						"error": err.Error(),
 					}).Error("Could not open file for parsing")
+					fs.progress.addError()
 				}
 
 				// read the file
@@ -342,13 +572,18 @@ func (fs *FSImporter) parseFiles(indexedFiles []*files.IndexedFile, parsingThrea
 						"file":  indexedFiles[j].Path,
 						"error": err.Error(),
 					}).Error("Could not read from the file")
+					fs.progress.addError()
 				}
 				fmt.Println("\t[-] Parsing " + indexedFiles[j].Path + " -> " + indexedFiles[j].TargetDatabase)
 
 				// This loops through every line of the file
+				var lineNum int64
 				for fileScanner.Scan() {
+					lineNum++
+
 					// go to next line if there was an issue
 					if fileScanner.Err() != nil {
+						fs.progress.addError()
 						break
 					}
 
@@ -368,21 +603,38 @@ func (fs *FSImporter) parseFiles(indexedFiles []*files.IndexedFile, parsingThrea
 					if entry == nil {
 						continue
 					}
+					fs.progress.addRecordParsed()
+
+					retVals, ok := resultsFor(entry)
+					if !ok {
+						continue
+					}
 
+					// only entries which pass the filters are stored for analysis
+					var stored bool
 					switch typedEntry := entry.(type) {
 					case *parsetypes.Conn:
-						parseConnEntry(typedEntry, fs.filter, retVals)
+						fs.setProvenance(typedEntry, indexedFiles[j].Path, lineNum)
+						stored = parseConnEntry(typedEntry, fs.filter, retVals)
 					case *parsetypes.DNS:
-						parseDNSEntry(typedEntry, fs.filter, retVals)
+						stored = parseDNSEntry(typedEntry, fs.filter, retVals)
 					case *parsetypes.HTTP:
-						parseHTTPEntry(typedEntry, fs.filter, retVals)
+						stored = parseHTTPEntry(typedEntry, fs.filter, retVals)
 					case *parsetypes.OpenConn:
-						parseOpenConnEntry(typedEntry, fs.filter, retVals)
+						stored = parseOpenConnEntry(typedEntry, fs.filter, retVals)
 					case *parsetypes.SSL:
-						parseSSLEntry(typedEntry, fs.filter, retVals)
+						stored = parseSSLEntry(typedEntry, fs.filter, retVals)
+					case *parsetypes.QUIC:
+						stored = parseQUICEntry(typedEntry, fs.filter, retVals)
+					default:
+						continue
+					}
+					if stored {
+						fs.progress.addRecordWritten()
 					}
 				}
 				indexedFiles[j].ParseTime = time.Now()
+				fs.progress.addFileDone()
 				closeScanner() // handles closing the underlying fileHandle
 				logger.WithFields(log.Fields{
 					"path": indexedFiles[j].Path,
@@ -405,8 +657,6 @@ func (fs *FSImporter) parseFiles(indexedFiles []*files.IndexedFile, parsingThrea
 			log.Fatal("could not write memory profile: ", err)
 		}
 	*/
-
-	return retVals
 }
 
 // buildExplodedDNS .....
@@ -415,10 +665,10 @@ func (fs *FSImporter) buildExplodedDNS(domainMap map[string]int) {
 	if fs.config.S.DNS.Enabled {
 		if len(domainMap) > 0 {
 			// Set up the database
-			explodedDNSRepo := explodeddns.NewMongoRepository(fs.database, fs.config, fs.log)
+			explodedDNSRepo := explodeddns.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 			err := explodedDNSRepo.CreateIndexes()
 			if err != nil {
-				fs.log.Error(err)
+				fs.analysisLog.Error(err)
 			}
 			explodedDNSRepo.Upsert(domainMap)
 		} else {
@@ -432,10 +682,10 @@ func (fs *FSImporter) buildCertificates(certMap map[string]*certificate.Input) {
 
 	if len(certMap) > 0 {
 		// Set up the database
-		certificateRepo := certificate.NewMongoRepository(fs.database, fs.config, fs.log)
+		certificateRepo := certificate.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 		err := certificateRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 		certificateRepo.Upsert(certMap)
 	} else {
@@ -466,10 +716,10 @@ func (fs *FSImporter) buildHostnames(hostnameMap map[string]*hostname.Input) {
 	// non-optional module
 	if len(hostnameMap) > 0 {
 		// Set up the database
-		hostnameRepo := hostname.NewMongoRepository(fs.database, fs.config, fs.log)
+		hostnameRepo := hostname.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 		err := hostnameRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 		hostnameRepo.Upsert(hostnameMap)
 	} else {
@@ -481,11 +731,11 @@ func (fs *FSImporter) buildHostnames(hostnameMap map[string]*hostname.Input) {
 func (fs *FSImporter) buildSNIConns(tlsMap map[string]*sniconn.TLSInput, httpMap map[string]*sniconn.HTTPInput,
 	zeekUIDMap map[string]*data.ZeekUIDRecord, hostMap map[string]*host.Input) {
 	if len(tlsMap) != 0 || len(httpMap) != 0 {
-		sniconnRepo := sniconn.NewMongoRepository(fs.database, fs.config, fs.log)
+		sniconnRepo := sniconn.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 		err := sniconnRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 
 		sniconnRepo.Upsert(tlsMap, httpMap, zeekUIDMap, hostMap)
@@ -498,11 +748,11 @@ func (fs *FSImporter) buildUconnsProxy(uconnProxyMap map[string]*uconnproxy.Inpu
 	// non-optional module
 	if len(uconnProxyMap) > 0 {
 		// Set up the database
-		uconnProxyRepo := uconnproxy.NewMongoRepository(fs.database, fs.config, fs.log)
+		uconnProxyRepo := uconnproxy.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 		err := uconnProxyRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 
 		// send uconnProxyMap to uconnProxy analysis
@@ -516,11 +766,11 @@ func (fs *FSImporter) buildUconns(uconnMap map[string]*uconn.Input, hostMap map[
 	// non-optional module
 	if len(uconnMap) > 0 {
 		// Set up the database
-		uconnRepo := uconn.NewMongoRepository(fs.database, fs.config, fs.log)
+		uconnRepo := uconn.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 		err := uconnRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 
 		// send uconns to uconn analysis
@@ -535,11 +785,11 @@ func (fs *FSImporter) buildUconns(uconnMap map[string]*uconn.Input, hostMap map[
 func (fs *FSImporter) buildHosts(hostMap map[string]*host.Input) {
 	// non-optional module
 	if len(hostMap) > 0 {
-		hostRepo := host.NewMongoRepository(fs.database, fs.config, fs.log)
+		hostRepo := host.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 		err := hostRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 
 		// add the hosts to the database
@@ -554,11 +804,11 @@ func (fs *FSImporter) buildHosts(hostMap map[string]*host.Input) {
 func (fs *FSImporter) markBlacklistedPeers(hostMap map[string]*host.Input) {
 	// non-optional module
 	if len(hostMap) > 0 {
-		blacklistRepo := blacklist.NewMongoRepository(fs.database, fs.config, fs.log)
+		blacklistRepo := blacklist.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 		err := blacklistRepo.CreateIndexes()
 		if err != nil {
-			fs.log.Error(err)
+			fs.analysisLog.Error(err)
 		}
 
 		// send the hosts out for threat intel analysis
@@ -566,33 +816,96 @@ func (fs *FSImporter) markBlacklistedPeers(hostMap map[string]*host.Input) {
 	}
 }
 
-func (fs *FSImporter) buildBeacons(uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
+// buildBeacons analyzes the unique connections for beacons. Returns an error if
+// any unique connection could not be analyzed or written, or the analysis could
+// not be checkpointed.
+func (fs *FSImporter) buildBeacons(uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) error {
 	if fs.config.S.Beacon.Enabled {
 		if len(uconnMap) > 0 {
-			beaconRepo := beacon.NewMongoRepository(fs.database, fs.config, fs.log)
+			beaconRepo := beacon.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 			err := beaconRepo.CreateIndexes()
 			if err != nil {
-				fs.log.Error(err)
+				fs.analysisLog.Error(err)
 			}
 
 			// send uconns to beacon analysis
-			beaconRepo.Upsert(uconnMap, hostMap, minTimestamp, maxTimestamp)
+			if fs.deadline.IsZero() {
+				return beaconRepo.Upsert(fs.ctx, uconnMap, hostMap, minTimestamp, maxTimestamp)
+			}
+
+			// once the deadline has passed, every remaining batch is checkpointed
+			finished, err := beaconRepo.UpsertUntil(fs.ctx, uconnMap, hostMap, minTimestamp, maxTimestamp, fs.deadline)
+			if !finished {
+				fs.beaconsPending = true
+			}
+			if err != nil && fs.ctx.Err() == nil {
+				fs.analysisLog.WithFields(log.Fields{
+					"err":      err,
+					"database": fs.database.GetSelectedDB(),
+				}).Error("Beacon analysis failed")
+				return err
+			}
 		} else {
 			fmt.Println("\t[!] No Beacon data to analyze")
 		}
 	}
+	return nil
+}
+
+// checkHashPairKeys returns an error if the target dataset was created with a
+// different Beacon HashPairKeys setting. New datasets record the current setting.
+func (fs *FSImporter) checkHashPairKeys() error {
+	info, err := fs.metaDB.GetDBMetaInfo(fs.database.GetSelectedDB())
+	if err == mgo.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return info.CheckHashPairKeys(fs.config.S.Beacon.HashPairKeys)
+}
+
+// resumeBeacons finishes beacon analysis which was checkpointed by a previous
+// import. Returns true if a checkpoint was found, and an error if the resumed
+// analysis failed.
+func (fs *FSImporter) resumeBeacons() (bool, error) {
+	if !fs.config.S.Beacon.Enabled {
+		return false, nil
+	}
+
+	beaconRepo := beacon.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
+
+	exists, err := beaconRepo.HasCheckpoint()
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, nil
+	}
 
+	finished, err := beaconRepo.Resume(fs.ctx, fs.deadline)
+	if !finished {
+		fs.beaconsPending = true
+	}
+	if err != nil && fs.ctx.Err() == nil {
+		fs.analysisLog.WithFields(log.Fields{
+			"err":      err,
+			"database": fs.database.GetSelectedDB(),
+		}).Error("Could not resume beacon analysis")
+		return true, err
+	}
+	return true, nil
 }
 
 func (fs *FSImporter) buildProxyBeacons(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
 	if fs.config.S.BeaconProxy.Enabled {
 		if len(uconnProxyMap) > 0 {
-			beaconProxyRepo := beaconproxy.NewMongoRepository(fs.database, fs.config, fs.log)
+			beaconProxyRepo := beaconproxy.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 			err := beaconProxyRepo.CreateIndexes()
 			if err != nil {
-				fs.log.Error(err)
+				fs.analysisLog.Error(err)
 			}
 
 			// send proxy uconns to beacon analysis
@@ -607,11 +920,11 @@ func (fs *FSImporter) buildProxyBeacons(uconnProxyMap map[string]*uconnproxy.Inp
 func (fs *FSImporter) buildSNIBeacons(tlsMap map[string]*sniconn.TLSInput, httpMap map[string]*sniconn.HTTPInput, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
 	if fs.config.S.BeaconSNI.Enabled {
 		if len(tlsMap) > 0 || len(httpMap) > 0 {
-			beaconSNIRepo := beaconsni.NewMongoRepository(fs.database, fs.config, fs.log)
+			beaconSNIRepo := beaconsni.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 			err := beaconSNIRepo.CreateIndexes()
 			if err != nil {
-				fs.log.Error(err)
+				fs.analysisLog.Error(err)
 			}
 
 			// send SNI conns to beacon analysis
@@ -628,11 +941,11 @@ func (fs *FSImporter) buildUserAgent(useragentMap map[string]*useragent.Input) {
 	if fs.config.S.UserAgent.Enabled {
 		if len(useragentMap) > 0 {
 			// Set up the database
-			useragentRepo := useragent.NewMongoRepository(fs.database, fs.config, fs.log)
+			useragentRepo := useragent.NewMongoRepository(fs.database, fs.config, fs.analysisLog)
 
 			err := useragentRepo.CreateIndexes()
 			if err != nil {
-				fs.log.Error(err)
+				fs.analysisLog.Error(err)
 			}
 			useragentRepo.Upsert(useragentMap)
 		} else {
@@ -664,79 +977,21 @@ func (fs *FSImporter) updateTimestampRange() (int64, int64) {
 		return 0, 0
 	}
 
-	// Build query for aggregation
-	timestampMinQuery := []bson.M{
-		{"$project": bson.M{
-			"_id":     0,
-			"ts":      "$dat.ts",
-			"open_ts": bson.M{"$ifNull": []interface{}{"$open_ts", []interface{}{}}},
-		}},
-		{"$unwind": "$ts"},
-		{"$project": bson.M{"_id": 0, "ts": bson.M{"$concatArrays": []interface{}{"$ts", "$open_ts"}}}},
-		{"$unwind": "$ts"}, // Not an error, must unwind it twice
-		{"$sort": bson.M{"ts": 1}},
-		{"$limit": 1},
-	}
-
-	var resultMin struct {
-		Timestamp int64 `bson:"ts"`
-	}
-
-	// get iminimum timestamp
-	// sort by the timestamp, limit it to 1 (only returns first result)
-	err := session.DB(fs.database.GetSelectedDB()).C(collectionName).Pipe(timestampMinQuery).AllowDiskUse().One(&resultMin)
-
+	minTimestamp, maxTimestamp, err := uconn.TimestampRange(fs.database, fs.config)
 	if err != nil {
-		fs.log.WithFields(log.Fields{
+		fs.analysisLog.WithFields(log.Fields{
 			"error": err.Error(),
-		}).Error("Could not retrieve minimum timestamp:", err)
+		}).Error("Could not retrieve timestamp range:", err)
 		return 0, 0
 	}
 
-	// Build query for aggregation
-	timestampMaxQuery := []bson.M{
-		{"$project": bson.M{
-			"_id":     0,
-			"ts":      "$dat.ts",
-			"open_ts": bson.M{"$ifNull": []interface{}{"$open_ts", []interface{}{}}},
-		}},
-		{"$unwind": "$ts"},
-		{"$project": bson.M{"_id": 0, "ts": bson.M{"$concatArrays": []interface{}{"$ts", "$open_ts"}}}},
-		{"$unwind": "$ts"}, // Not an error, must unwind it twice
-		{"$sort": bson.M{"ts": -1}},
-		{"$limit": 1},
-	}
-
-	var resultMax struct {
-		Timestamp int64 `bson:"ts"`
-	}
-
-	// get max timestamp
-	// sort by the timestamp, limit it to 1 (only returns first result)
-	err = session.DB(fs.database.GetSelectedDB()).C(collectionName).Pipe(timestampMaxQuery).AllowDiskUse().One(&resultMax)
-
-	if err != nil {
-		fs.log.WithFields(log.Fields{
-			"error": err.Error(),
-		}).Error("Could not retrieve maximum timestamp:", err)
-		return 0, 0
-	}
-
-	// since zeek records connections when they close, some connections that started before the ingested
-	// observation period can skew the ts range. We need to cap observation period to the last 24 hours
-	// for accurate beaconing analysis
-	tsMinCapped := resultMax.Timestamp - 24*60*60
-	if tsMinCapped > resultMin.Timestamp {
-		resultMin.Timestamp = tsMinCapped
-	}
-
 	// set range in metadatabase
-	err = fs.metaDB.AddTSRange(fs.database.GetSelectedDB(), resultMin.Timestamp, resultMax.Timestamp)
+	err = fs.metaDB.AddTSRange(fs.database.GetSelectedDB(), minTimestamp, maxTimestamp)
 	if err != nil {
-		fs.log.WithFields(log.Fields{
+		fs.analysisLog.WithFields(log.Fields{
 			"error": err.Error(),
 		}).Error("Could not set ts range in metadatabase: ", err)
 		return 0, 0
 	}
-	return resultMin.Timestamp, resultMax.Timestamp
+	return minTimestamp, maxTimestamp
 }
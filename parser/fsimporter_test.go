@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/parser/files"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// parseProvenanceTestLog parses a conn log holding two connections between the
+// same hosts and returns their unique connection and the path of the log
+func parseProvenanceTestLog(t *testing.T, provenance config.ProvenanceStaticCfg) (*uconn.Input, string) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Provenance = provenance
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	fs := &FSImporter{
+		filter: filter{
+			internal:   util.ParseSubnets([]string{"10.0.0.0/8"}),
+			sampleSize: conf.S.Beacon.UIDSampleSize,
+		},
+		log:      logger,
+		config:   conf,
+		database: &database.DB{},
+		progress: new(ImportProgress),
+	}
+
+	path := filepath.Join(t.TempDir(), "conn.log")
+	contents := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+		"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n" +
+		"1600000000.000000\tC0\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600000060.000000\tC1\t10.0.0.5\t50001\t203.0.113.7\t443\ttcp\n"
+	require.Nil(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+	indexedFiles, _ := files.IndexFiles([]string{path}, 1, "test", 0, logger, conf)
+	require.Len(t, indexedFiles, 1)
+
+	retVals := fs.parseFiles(indexedFiles, 1, logger)
+	require.Len(t, retVals.UniqueConnMap, 1)
+	for _, uconnInput := range retVals.UniqueConnMap {
+		return uconnInput, indexedFiles[0].Path
+	}
+	return nil, ""
+}
+
+func TestParseFilesProvenance(t *testing.T) {
+	// disabled by default so nothing extra is stored
+	uconnInput, _ := parseProvenanceTestLog(t, config.ProvenanceStaticCfg{})
+	assert.Empty(t, uconnInput.LogPaths)
+	assert.Empty(t, uconnInput.LogRefs)
+
+	uconnInput, path := parseProvenanceTestLog(t, config.ProvenanceStaticCfg{Enabled: true})
+	assert.Equal(t, []string{path}, uconnInput.LogPaths.Items())
+	assert.Empty(t, uconnInput.LogRefs, "line numbers are only recorded if requested")
+
+	// the records follow the 7 header lines
+	uconnInput, path = parseProvenanceTestLog(t, config.ProvenanceStaticCfg{Enabled: true, LineNumbers: true})
+	assert.Equal(t, []string{path}, uconnInput.LogPaths.Items())
+	assert.Equal(t, []string{path + ":8", path + ":9"}, uconnInput.LogRefs)
+}
+
+func TestCollectFileDetailsArchive(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	fs := &FSImporter{
+		filter: filter{
+			internal: util.ParseSubnets([]string{"10.0.0.0/8"}),
+		},
+		log:      logger,
+		config:   conf,
+		database: &database.DB{},
+		progress: new(ImportProgress),
+	}
+
+	const tsvHeader = "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n"
+	logs := map[string]string{
+		"zeek/conn.log": tsvHeader + "#path\tconn\n" +
+			"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+			"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n" +
+			"1600000000.000000\tC0\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n",
+		"zeek/dns.log": tsvHeader + "#path\tdns\n" +
+			"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\ttrans_id\tquery\n" +
+			"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\tcount\tstring\n" +
+			"1600000000.000000\tD0\t10.0.0.5\t50001\t10.0.0.53\t53\tudp\t1\twww.example.com\n",
+	}
+
+	archivePath := filepath.Join(t.TempDir(), "zeek.tar.gz")
+	archiveFile, err := os.Create(archivePath)
+	require.Nil(t, err)
+	gzipWriter := gzip.NewWriter(archiveFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	for name, contents := range logs {
+		require.Nil(t, tarWriter.WriteHeader(&tar.Header{
+			Name: name, Typeflag: tar.TypeReg, Mode: 0644, Size: int64(len(contents)),
+		}))
+		_, err = tarWriter.Write([]byte(contents))
+		require.Nil(t, err)
+	}
+	require.Nil(t, tarWriter.Close())
+	require.Nil(t, gzipWriter.Close())
+	require.Nil(t, archiveFile.Close())
+
+	indexedFiles, indexErrs := fs.CollectFileDetails([]string{archivePath}, 1)
+	assert.Empty(t, indexErrs)
+	require.Len(t, indexedFiles, 2)
+	require.Len(t, fs.archiveDirs, 1)
+
+	targets := make([]string, 0, len(indexedFiles))
+	paths := make([]string, 0, len(indexedFiles))
+	for _, indexedFile := range indexedFiles {
+		targets = append(targets, indexedFile.TargetCollection)
+		paths = append(paths, indexedFile.Path)
+		assert.True(t, strings.HasPrefix(indexedFile.Location(), fs.archiveDirs[0]), "the logs should be read from the extracted copy")
+	}
+	assert.ElementsMatch(t, []string{conf.T.Structure.ConnTable, conf.T.Structure.DNSTable}, targets)
+
+	// the archive and the member are recorded rather than the temporary directory
+	assert.ElementsMatch(t, []string{
+		filepath.Join(archivePath, "zeek", "conn.log"), filepath.Join(archivePath, "zeek", "dns.log"),
+	}, paths)
+
+	retVals := fs.parseFiles(indexedFiles, 1, logger)
+	assert.Len(t, retVals.UniqueConnMap, 1)
+	assert.Equal(t, 1, retVals.ExplodedDNSMap["www.example.com"])
+
+	// the extracted logs are removed once the import is finished
+	archiveDir := fs.archiveDirs[0]
+	fs.RemoveExtractedArchives()
+	_, err = os.Stat(archiveDir)
+	assert.True(t, os.IsNotExist(err))
+	assert.Empty(t, fs.archiveDirs)
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"net"
+	"sort"
 	"strings"
 
 	"github.com/activecm/rita/parser/parsetypes"
@@ -9,16 +10,17 @@ import (
 	"github.com/activecm/rita/pkg/sniconn"
 	"github.com/activecm/rita/pkg/uconnproxy"
 	"github.com/activecm/rita/pkg/useragent"
+	"github.com/activecm/rita/util"
 )
 
-func parseHTTPEntry(parseHTTP *parsetypes.HTTP, filter filter, retVals ParseResults) {
+func parseHTTPEntry(parseHTTP *parsetypes.HTTP, filter filter, retVals ParseResults) bool {
 	// get source destination pair for connection record
 	src := parseHTTP.Source
 	dst := parseHTTP.Destination
 
 	// parse addresses into binary format
-	srcIP := net.ParseIP(src)
-	dstIP := net.ParseIP(dst)
+	srcIP := data.ParseIP(src)
+	dstIP := data.ParseIP(dst)
 
 	// parse host
 	fqdn := parseHTTP.Host
@@ -61,6 +63,19 @@ func parseHTTPEntry(parseHTTP *parsetypes.HTTP, filter filter, retVals ParseResu
 	// check if destination is a proxy server based on HTTP method
 	dstIsProxy := (method == "CONNECT")
 
+	// if the proxy's own leg of a proxied request was logged, the proxy
+	// appears as the source and the client is named by X-Forwarded-For.
+	// The leg is credited to the client so it can be collapsed with the
+	// client's leg of the request. Any host can send the header, so it is
+	// only trusted from the configured forwarding proxies.
+	forwarded := false
+	if dstIsProxy && filter.collapseForwardedLegs && util.ContainsIP(filter.forwardingProxies, srcIP) {
+		if clientIP := forwardedClient(parseHTTP.Proxied); clientIP != nil {
+			srcIP = clientIP
+			forwarded = true
+		}
+	}
+
 	// if the HTTP method is CONNECT, then the srcIP is communicating
 	// to an FQDN through the dstIP proxy. We need to handle that
 	// as a special case here so that we don't filter internal->internal
@@ -75,15 +90,15 @@ func parseHTTPEntry(parseHTTP *parsetypes.HTTP, filter filter, retVals ParseResu
 	// appearing as a destination, while still allowing for processing that
 	// data for the proxy modules
 	if dstIsProxy {
-		if filter.filterDomain(fqdn) || filter.filterSingleIP(srcIP) {
-			return
+		if filter.filterDomain(fqdn) || filter.filterBaselineDomain(fqdn) || filter.filterSourceIP(srcIP) {
+			return false
 		}
 		fqdnAsIPAddress := net.ParseIP(fqdn)
 		if fqdnAsIPAddress != nil && filter.checkIfInternal(dstIP) && filter.filterConnPair(srcIP, fqdnAsIPAddress) {
-			return
+			return false
 		}
-	} else if filter.filterDomain(fqdn) || filter.filterConnPair(srcIP, dstIP) {
-		return
+	} else if filter.filterDomain(fqdn) || filter.filterBaselineDomain(fqdn) || filter.filterConnPair(srcIP, dstIP) {
+		return false
 	}
 
 	// disambiguate addresses which are not publicly routable
@@ -93,15 +108,19 @@ func parseHTTPEntry(parseHTTP *parsetypes.HTTP, filter filter, retVals ParseResu
 
 	srcFQDNKey := srcFQDNPair.MapKey()
 
-	updateUseragentsByHTTP(srcUniqIP, parseHTTP, retVals)
+	// the user agent of a forwarded leg was already seen in the client's leg
+	if !forwarded {
+		updateUseragentsByHTTP(srcUniqIP, parseHTTP, retVals)
+	}
 
 	// check if internal IP is requesting a connection through a proxy
 	if dstIsProxy {
-		updateProxiedUniqueConnectionsByHTTP(srcFQDNPair, dstUniqIP, parseHTTP, retVals)
-		return
+		updateProxiedUniqueConnectionsByHTTP(srcFQDNPair, dstUniqIP, parseHTTP, forwarded, retVals)
+		return true
 	}
 
 	updateHTTPConnectionsByHTTP(srcIP, dstUniqIP, srcFQDNPair, srcFQDNKey, parseHTTP, filter, retVals)
+	return true
 }
 
 func updateUseragentsByHTTP(srcUniqIP data.UniqueIP, parseHTTP *parsetypes.HTTP, retVals ParseResults) {
@@ -132,8 +151,73 @@ func updateUseragentsByHTTP(srcUniqIP data.UniqueIP, parseHTTP *parsetypes.HTTP,
 	retVals.UseragentMap[parseHTTP.UserAgent].Requests.Insert(parseHTTP.Host)
 }
 
+// forwardedClient returns the client named by the X-Forwarded-For header of a
+// proxied HTTP request or nil if there isn't one. Zeek records the header in the
+// proxied field as "X-FORWARDED-FOR -> <addresses>", where the first address is
+// the client which made the request.
+func forwardedClient(proxied []string) net.IP {
+	for _, header := range proxied {
+		parts := strings.SplitN(header, "->", 2)
+		if len(parts) != 2 || !strings.EqualFold(strings.TrimSpace(parts[0]), "X-FORWARDED-FOR") {
+			continue
+		}
+		client := strings.TrimSpace(strings.Split(parts[1], ",")[0])
+		if clientIP := net.ParseIP(client); clientIP != nil {
+			return clientIP
+		}
+	}
+	return nil
+}
+
+// collapseForwardedLegs merges the connections a proxy forwarded on behalf of the
+// source of a proxied unique connection into the source's own connections. A
+// forwarded connection within window seconds of a connection of the source is the
+// second leg of the same request and is dropped. The rest are counted as
+// connections of the source since their first leg wasn't logged.
+func collapseForwardedLegs(input *uconnproxy.Input, window int64) {
+	if len(input.ForwardedTsList) == 0 {
+		return
+	}
+
+	clientTs := make([]int64, len(input.TsList))
+	copy(clientTs, input.TsList)
+	sort.Slice(clientTs, func(i, j int) bool { return clientTs[i] < clientTs[j] })
+
+	// visit the forwarded connections in order, keeping their sizes with them
+	order := make([]int, len(input.ForwardedTsList))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return input.ForwardedTsList[order[i]] < input.ForwardedTsList[order[j]]
+	})
+
+	// pair each forwarded connection with the earliest unpaired connection
+	// of the source within the window
+	next := 0
+	for _, i := range order {
+		ts := input.ForwardedTsList[i]
+		for next < len(clientTs) && clientTs[next] < ts-window {
+			next++
+		}
+		if next < len(clientTs) && clientTs[next] <= ts+window {
+			next++
+			continue
+		}
+
+		input.ConnectionCount++
+		input.TsList = append(input.TsList, ts)
+		if i < len(input.ForwardedBytesList) {
+			input.BytesList = append(input.BytesList, input.ForwardedBytesList[i])
+		}
+	}
+
+	input.ForwardedTsList = nil
+	input.ForwardedBytesList = nil
+}
+
 func updateProxiedUniqueConnectionsByHTTP(srcFQDNPair data.UniqueSrcFQDNPair, dstUniqIP data.UniqueIP,
-	parseHTTP *parsetypes.HTTP, retVals ParseResults) {
+	parseHTTP *parsetypes.HTTP, forwarded bool, retVals ParseResults) {
 
 	retVals.ProxyUniqueConnLock.Lock()
 	defer retVals.ProxyUniqueConnLock.Unlock()
@@ -144,20 +228,41 @@ func updateProxiedUniqueConnectionsByHTTP(srcFQDNPair data.UniqueSrcFQDNPair, ds
 	if _, ok := retVals.ProxyUniqueConnMap[srcFQDNKey]; !ok {
 		// create new host record with src and dst
 		retVals.ProxyUniqueConnMap[srcFQDNKey] = &uconnproxy.Input{
-			Hosts: srcFQDNPair,
-			Proxy: dstUniqIP,
+			Hosts:   srcFQDNPair,
+			Proxy:   dstUniqIP,
+			Proxies: make(data.UniqueIPSet),
 		}
 	}
 
+	// ///// ADD THE PROXY TO THE PROXIES SERVICING THE CONNECTION /////
+	retVals.ProxyUniqueConnMap[srcFQDNKey].Proxies.Insert(dstUniqIP)
+
+	ts := parseHTTP.TimeStamp
+
+	// ///// SET ASIDE FORWARDED LEGS UNTIL THEY ARE COLLAPSED INTO THE CLIENT'S LEGS /////
+	if forwarded {
+		retVals.ProxyUniqueConnMap[srcFQDNKey].ForwardedTsList = append(
+			retVals.ProxyUniqueConnMap[srcFQDNKey].ForwardedTsList, ts,
+		)
+		retVals.ProxyUniqueConnMap[srcFQDNKey].ForwardedBytesList = append(
+			retVals.ProxyUniqueConnMap[srcFQDNKey].ForwardedBytesList, parseHTTP.ReqLen+parseHTTP.RespLen,
+		)
+		return
+	}
+
 	// ///// INCREMENT THE CONNECTION COUNT FOR THE PROXIED UNIQUE CONNECTION /////
 	retVals.ProxyUniqueConnMap[srcFQDNKey].ConnectionCount++
 
 	// ///// APPEND TIMESTAMP TO PROXIED UNIQUE CONNECTION TIMESTAMP LIST /////
-	ts := parseHTTP.TimeStamp
 
 	retVals.ProxyUniqueConnMap[srcFQDNKey].TsList = append(
 		retVals.ProxyUniqueConnMap[srcFQDNKey].TsList, ts,
 	)
+
+	// ///// APPEND BODY BYTES TO PROXIED UNIQUE CONNECTION BYTES LIST /////
+	retVals.ProxyUniqueConnMap[srcFQDNKey].BytesList = append(
+		retVals.ProxyUniqueConnMap[srcFQDNKey].BytesList, parseHTTP.ReqLen+parseHTTP.RespLen,
+	)
 }
 
 func updateHTTPConnectionsByHTTP(srcIP net.IP, dstUniqIP data.UniqueIP, srcFQDNPair data.UniqueSrcFQDNPair, srcFQDNKey string,
@@ -0,0 +1,162 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/parser/parsetypes"
+	"github.com/activecm/rita/pkg/uconnproxy"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestConnect returns a CONNECT request to example.com through the given proxy
+func newTestConnect(src, proxy string, ts int64, proxied ...string) *parsetypes.HTTP {
+	return &parsetypes.HTTP{
+		TimeStamp:   ts,
+		Source:      src,
+		Destination: proxy,
+		Method:      "CONNECT",
+		Host:        "example.com",
+		ReqLen:      100,
+		RespLen:     400,
+		Proxied:     proxied,
+	}
+}
+
+func TestForwardedClient(t *testing.T) {
+	assert.Equal(t, "10.0.0.5", forwardedClient([]string{"X-FORWARDED-FOR -> 10.0.0.5"}).String())
+	assert.Equal(t, "10.0.0.5", forwardedClient([]string{"VIA -> 1.1 squid", "x-forwarded-for -> 10.0.0.5, 10.0.0.1"}).String())
+	assert.Nil(t, forwardedClient([]string{"VIA -> 1.1 squid"}))
+	assert.Nil(t, forwardedClient([]string{"X-FORWARDED-FOR -> unknown"}))
+	assert.Nil(t, forwardedClient(nil))
+}
+
+func TestParseHTTPEntryForwardedLegs(t *testing.T) {
+	testFilter := filter{
+		internal:              util.ParseSubnets([]string{"10.0.0.0/8"}),
+		collapseForwardedLegs: true,
+		forwardingProxies:     util.ParseSubnets([]string{"10.0.0.1"}),
+	}
+	retVals := newParseResults()
+
+	// the client and the proxy both log each of four requests, and the
+	// proxy logs one more whose client leg was lost
+	xff := "X-FORWARDED-FOR -> 10.0.0.5"
+	for _, ts := range []int64{1600000000, 1600000060, 1600000120, 1600000180} {
+		parseHTTPEntry(newTestConnect("10.0.0.5", "10.0.0.1", ts), testFilter, retVals)
+		parseHTTPEntry(newTestConnect("10.0.0.1", "10.0.0.2", ts+1, xff), testFilter, retVals)
+	}
+	parseHTTPEntry(newTestConnect("10.0.0.1", "10.0.0.2", 1600000240, xff), testFilter, retVals)
+
+	// the forwarded legs are credited to the client rather than the proxy
+	require.Len(t, retVals.ProxyUniqueConnMap, 1)
+	var input *uconnproxy.Input
+	for _, entry := range retVals.ProxyUniqueConnMap {
+		input = entry
+	}
+	assert.Equal(t, "10.0.0.5", input.Hosts.SrcIP)
+	assert.Equal(t, "10.0.0.1", input.Proxy.IP)
+	assert.Equal(t, int64(4), input.ConnectionCount)
+	assert.Len(t, input.ForwardedTsList, 5)
+
+	// each pair of legs is a single connection
+	collapseForwardedLegs(input, 1)
+	assert.Equal(t, int64(5), input.ConnectionCount)
+	assert.Equal(t, []int64{1600000000, 1600000060, 1600000120, 1600000180, 1600000240}, input.TsList)
+	assert.Equal(t, []int64{500, 500, 500, 500, 500}, input.BytesList)
+	assert.Empty(t, input.ForwardedTsList)
+	assert.Empty(t, input.ForwardedBytesList)
+
+	// the user agent is only counted once per request
+	assert.Equal(t, int64(4), retVals.UseragentMap["Empty user agent string"].Seen)
+}
+
+func TestParseHTTPEntrySpoofedForwardedFor(t *testing.T) {
+	testFilter := filter{
+		internal:              util.ParseSubnets([]string{"10.0.0.0/8"}),
+		collapseForwardedLegs: true,
+		forwardingProxies:     util.ParseSubnets([]string{"10.0.0.1"}),
+	}
+	retVals := newParseResults()
+
+	// a host which isn't a forwarding proxy names another client in its header
+	parseHTTPEntry(newTestConnect("10.0.0.6", "10.0.0.2", 1600000000, "X-FORWARDED-FOR -> 10.0.0.5"), testFilter, retVals)
+
+	// the header is ignored and the request stays with the host which sent it
+	require.Len(t, retVals.ProxyUniqueConnMap, 1)
+	for _, input := range retVals.ProxyUniqueConnMap {
+		assert.Equal(t, "10.0.0.6", input.Hosts.SrcIP)
+		assert.Equal(t, int64(1), input.ConnectionCount)
+		assert.Empty(t, input.ForwardedTsList)
+	}
+	assert.Equal(t, int64(1), retVals.UseragentMap["Empty user agent string"].Seen)
+}
+
+func TestParseHTTPEntryProxies(t *testing.T) {
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"})}
+	retVals := newParseResults()
+
+	// one client always uses the same proxy while the other is balanced
+	// across two
+	parseHTTPEntry(newTestConnect("10.0.0.5", "10.0.0.1", 1600000000), testFilter, retVals)
+	parseHTTPEntry(newTestConnect("10.0.0.5", "10.0.0.1", 1600000060), testFilter, retVals)
+	parseHTTPEntry(newTestConnect("10.0.0.6", "10.0.0.2", 1600000000), testFilter, retVals)
+	parseHTTPEntry(newTestConnect("10.0.0.6", "10.0.0.1", 1600000060), testFilter, retVals)
+	parseHTTPEntry(newTestConnect("10.0.0.6", "10.0.0.2", 1600000120), testFilter, retVals)
+
+	require.Len(t, retVals.ProxyUniqueConnMap, 2)
+	for _, input := range retVals.ProxyUniqueConnMap {
+		switch input.Hosts.SrcIP {
+		case "10.0.0.5":
+			assert.Equal(t, "10.0.0.1", input.Proxy.IP)
+			assert.Len(t, input.Proxies, 1)
+			assert.Nil(t, input.ProxyList())
+		case "10.0.0.6":
+			// the first proxy seen stays the primary proxy
+			assert.Equal(t, "10.0.0.2", input.Proxy.IP)
+			proxies := input.ProxyList()
+			require.Len(t, proxies, 2)
+			assert.Equal(t, "10.0.0.1", proxies[0].IP)
+			assert.Equal(t, "10.0.0.2", proxies[1].IP)
+		}
+	}
+}
+
+func TestParseHTTPEntryForwardedLegsDisabled(t *testing.T) {
+	testFilter := filter{internal: util.ParseSubnets([]string{"10.0.0.0/8"})}
+	retVals := newParseResults()
+
+	parseHTTPEntry(newTestConnect("10.0.0.5", "10.0.0.1", 1600000000), testFilter, retVals)
+	parseHTTPEntry(newTestConnect("10.0.0.1", "10.0.0.2", 1600000000, "X-FORWARDED-FOR -> 10.0.0.5"), testFilter, retVals)
+
+	// the proxy's leg is its own proxied connection
+	require.Len(t, retVals.ProxyUniqueConnMap, 2)
+	for _, input := range retVals.ProxyUniqueConnMap {
+		assert.Equal(t, int64(1), input.ConnectionCount)
+		assert.Empty(t, input.ForwardedTsList)
+	}
+}
+
+func TestCollapseForwardedLegs(t *testing.T) {
+	input := &uconnproxy.Input{
+		ConnectionCount: 3,
+		TsList:          []int64{1600000120, 1600000000, 1600000060},
+		BytesList:       []int64{30, 10, 20},
+		// unordered, one forwarded leg lies outside the window of every
+		// client leg, and two fall within the window of the same client leg
+		ForwardedTsList:    []int64{1600000062, 1600000001, 1600000090, 1600000002},
+		ForwardedBytesList: []int64{21, 11, 99, 12},
+	}
+
+	collapseForwardedLegs(input, 2)
+
+	// the closest legs pair up and the rest are counted as connections
+	assert.Equal(t, int64(5), input.ConnectionCount)
+	assert.Equal(t, []int64{1600000120, 1600000000, 1600000060, 1600000002, 1600000090}, input.TsList)
+	assert.Equal(t, []int64{30, 10, 20, 12, 99}, input.BytesList)
+
+	// nothing changes without forwarded legs
+	collapseForwardedLegs(input, 2)
+	assert.Equal(t, int64(5), input.ConnectionCount)
+}
@@ -12,7 +12,7 @@ import (
 	"github.com/activecm/rita/util"
 )
 
-func parseOpenConnEntry(parseConn *parsetypes.OpenConn, filter filter, retVals ParseResults) {
+func parseOpenConnEntry(parseConn *parsetypes.OpenConn, filter filter, retVals ParseResults) bool {
 	// get source destination pair for connection record
 	src := parseConn.Source
 	dst := parseConn.Destination
@@ -26,7 +26,7 @@ func parseOpenConnEntry(parseConn *parsetypes.OpenConn, filter filter, retVals P
 
 	// If connection pair is not subject to filtering, process
 	if ignore {
-		return
+		return false
 	}
 
 	// disambiConnguate addresses which are not publicly routable
@@ -60,6 +60,7 @@ func parseOpenConnEntry(parseConn *parsetypes.OpenConn, filter filter, retVals P
 
 	updateCertificatesByOpenConn(dstKey, tuple, retVals)
 
+	return true
 }
 
 func updateUniqueConnectionsByOpenConn(srcIP, dstIP net.IP, srcDstPair data.UniqueIPPair, srcDstKey string,
@@ -145,6 +146,11 @@ func updateUniqueConnectionsByOpenConn(srcIP, dstIP net.IP, srcDstPair data.Uniq
 	// Replace existing duration if current duration is higher
 	if roundedDuration > retVals.UniqueConnMap[srcDstKey].MaxDuration {
 		retVals.UniqueConnMap[srcDstKey].MaxDuration = roundedDuration
+		retVals.UniqueConnMap[srcDstKey].MaxDurationConn = uconn.ConnTuple{
+			SrcPort: parseConn.SourcePort,
+			DstPort: parseConn.DestinationPort,
+			Proto:   parseConn.Proto,
+		}
 	}
 
 	// NOTE: We are not incrementing uconn.ConnectionCount until the
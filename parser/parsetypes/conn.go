@@ -57,6 +57,10 @@ type Conn struct {
 	AgentHostname string `bson:"agent_hostname" bro:"agent_hostname" brotype:"string" json:"agent_hostname"`
 	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
 	AgentUUID string `bson:"agent_uuid" bro:"agent_uuid" brotype:"string" json:"agent_uuid"`
+	// LogPath is the log file this connection was read from. Only set when Provenance is enabled.
+	LogPath string `bson:"-" json:"-"`
+	// LogLine is the line of the log file holding this connection. Only set when Provenance LineNumbers is enabled.
+	LogLine int64 `bson:"-" json:"-"`
 }
 
 //TargetCollection returns the mongo collection this entry should be inserted
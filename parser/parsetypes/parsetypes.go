@@ -41,6 +41,10 @@ func NewBroDataFactory(fileType string) func() BroData {
 		return func() BroData {
 			return &SSL{}
 		}
+	} else if strings.HasPrefix(fileType, "quic") {
+		return func() BroData {
+			return &QUIC{}
+		}
 	}
 	return nil
 }
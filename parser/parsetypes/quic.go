@@ -0,0 +1,53 @@
+package parsetypes
+
+import (
+	"github.com/activecm/rita/config"
+)
+
+// QUIC provides a data structure for zeek's QUIC data
+type QUIC struct {
+	// TimeStamp of this connection
+	TimeStamp int64 `bson:"ts" bro:"ts" brotype:"time" json:"-"`
+	// TimeStampGeneric is used when reading from json files
+	TimeStampGeneric interface{} `bson:"-" json:"ts"`
+	// UID is the Unique Id for this connection (generated by Bro)
+	UID string `bson:"uid" bro:"uid" brotype:"string" json:"uid"`
+	// Source is the source address for this connection
+	Source string `bson:"id_orig_h" bro:"id.orig_h" brotype:"addr" json:"id.orig_h"`
+	// SourcePort is the source port of this connection
+	SourcePort int `bson:"id_orig_p" bro:"id.orig_p" brotype:"port" json:"id.orig_p"`
+	// Destination is the destination of the connection
+	Destination string `bson:"id_resp_h" bro:"id.resp_h" brotype:"addr" json:"id.resp_h"`
+	// DestinationPort is the port at the destination host
+	DestinationPort int `bson:"id_resp_p" bro:"id.resp_p" brotype:"port" json:"id.resp_p"`
+	// Version : QUIC version found in the first long header packet processed
+	Version string `bson:"version" bro:"version" brotype:"string" json:"version"`
+	// ClientInitialDCID : first Destination Connection ID used by client
+	ClientInitialDCID string `bson:"client_initial_dcid" bro:"client_initial_dcid" brotype:"string" json:"client_initial_dcid"`
+	// ClientSCID : first Source Connection ID used by client
+	ClientSCID string `bson:"client_scid" bro:"client_scid" brotype:"string" json:"client_scid"`
+	// ServerSCID : first Source Connection ID used by server
+	ServerSCID string `bson:"server_scid" bro:"server_scid" brotype:"string" json:"server_scid"`
+	// ServerName : Server name extracted from the SNI extension in the
+	// CRYPTO frame of the client's first INITIAL packet
+	ServerName string `bson:"server_name" bro:"server_name" brotype:"string" json:"server_name"`
+	// ClientProtocol : First protocol extracted from the ALPN extension in the
+	// CRYPTO frame of the client's first INITIAL packet
+	ClientProtocol string `bson:"client_protocol" bro:"client_protocol" brotype:"string" json:"client_protocol"`
+	// History : QUIC history of the connection
+	History string `bson:"history" bro:"history" brotype:"string" json:"history"`
+	// AgentHostname names which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentHostname string `bson:"agent_hostname" bro:"agent_hostname" brotype:"string" json:"agent_hostname"`
+	// AgentUUID identifies which sensor recorded this event. Only set when combining logs from multiple sensors.
+	AgentUUID string `bson:"agent_uuid" bro:"agent_uuid" brotype:"string" json:"agent_uuid"`
+}
+
+//TargetCollection returns the mongo collection this entry should be inserted
+func (line *QUIC) TargetCollection(config *config.StructureTableCfg) string {
+	return config.QUICTable
+}
+
+//ConvertFromJSON performs any extra conversions necessary when reading from JSON
+func (line *QUIC) ConvertFromJSON() {
+	line.TimeStamp = convertTimestamp(line.TimeStampGeneric)
+}
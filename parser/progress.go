@@ -0,0 +1,54 @@
+package parser
+
+import "sync/atomic"
+
+type (
+	//ImportProgress holds running counts for an import. The counts are updated
+	//atomically by the parsing threads so they may be read from a reporter
+	//goroutine at any time without locking.
+	ImportProgress struct {
+		filesDone      int64
+		recordsParsed  int64
+		recordsWritten int64
+		errors         int64
+	}
+
+	//ImportProgressSnapshot is a point in time copy of an ImportProgress
+	ImportProgressSnapshot struct {
+		FilesDone      int64 // number of log files which have been fully parsed
+		RecordsParsed  int64 // number of log entries parsed out of the log files
+		RecordsWritten int64 // number of parsed log entries which passed the filters and were stored for analysis
+		Errors         int64 // number of files which could not be opened or read
+	}
+)
+
+//addFileDone records that a log file has been fully parsed
+func (p *ImportProgress) addFileDone() {
+	atomic.AddInt64(&p.filesDone, 1)
+}
+
+//addRecordParsed records that a log entry has been parsed
+func (p *ImportProgress) addRecordParsed() {
+	atomic.AddInt64(&p.recordsParsed, 1)
+}
+
+//addRecordWritten records that a parsed log entry has been stored for analysis
+func (p *ImportProgress) addRecordWritten() {
+	atomic.AddInt64(&p.recordsWritten, 1)
+}
+
+//addError records that a log file could not be opened or read
+func (p *ImportProgress) addError() {
+	atomic.AddInt64(&p.errors, 1)
+}
+
+//Snapshot returns a copy of the current counts. Each count is read
+//atomically, but the counts may be updated in between individual reads.
+func (p *ImportProgress) Snapshot() ImportProgressSnapshot {
+	return ImportProgressSnapshot{
+		FilesDone:      atomic.LoadInt64(&p.filesDone),
+		RecordsParsed:  atomic.LoadInt64(&p.recordsParsed),
+		RecordsWritten: atomic.LoadInt64(&p.recordsWritten),
+		Errors:         atomic.LoadInt64(&p.errors),
+	}
+}
@@ -0,0 +1,65 @@
+package parser
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestImportProgressConcurrent hammers the progress counters from several
+// goroutines while another goroutine takes snapshots. Run with -race to
+// verify the counters are safe to read during an import.
+func TestImportProgressConcurrent(t *testing.T) {
+	const workers = 8
+	const iterations = 10000
+
+	progress := new(ImportProgress)
+
+	done := make(chan struct{})
+	reporterWG := new(sync.WaitGroup)
+	reporterWG.Add(1)
+	go func() {
+		defer reporterWG.Done()
+		var last ImportProgressSnapshot
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			snap := progress.Snapshot()
+			// counts only ever increase
+			assert.True(t, snap.RecordsParsed >= last.RecordsParsed)
+			assert.True(t, snap.FilesDone >= last.FilesDone)
+			last = snap
+		}
+	}()
+
+	workerWG := new(sync.WaitGroup)
+	for w := 0; w < workers; w++ {
+		workerWG.Add(1)
+		go func() {
+			defer workerWG.Done()
+			for j := 0; j < iterations; j++ {
+				progress.addRecordParsed()
+				if j%2 == 0 {
+					progress.addRecordWritten()
+				}
+				if j%100 == 0 {
+					progress.addError()
+				}
+			}
+			progress.addFileDone()
+		}()
+	}
+	workerWG.Wait()
+	close(done)
+	reporterWG.Wait()
+
+	snap := progress.Snapshot()
+	assert.Equal(t, int64(workers), snap.FilesDone)
+	assert.Equal(t, int64(workers*iterations), snap.RecordsParsed)
+	assert.Equal(t, int64(workers*iterations/2), snap.RecordsWritten)
+	assert.Equal(t, int64(workers*iterations/100), snap.Errors)
+}
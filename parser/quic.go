@@ -0,0 +1,132 @@
+package parser
+
+import (
+	"net"
+	"strconv"
+
+	"github.com/activecm/rita/parser/parsetypes"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/sniconn"
+	"github.com/activecm/rita/pkg/uconn"
+)
+
+func parseQUICEntry(parseQUIC *parsetypes.QUIC, filter filter, retVals ParseResults) bool {
+	src := parseQUIC.Source
+	dst := parseQUIC.Destination
+
+	srcIP := net.ParseIP(src)
+	dstIP := net.ParseIP(dst)
+
+	fqdn := parseQUIC.ServerName
+
+	// Run conn pair through filter to filter out certain connections
+	ignore := filter.filterConnPair(srcIP, dstIP) || filter.filterBaselineDomain(fqdn)
+	if ignore {
+		return false
+	}
+
+	srcUniqIP := data.NewUniqueIP(srcIP, parseQUIC.AgentUUID, parseQUIC.AgentHostname)
+	dstUniqIP := data.NewUniqueIP(dstIP, parseQUIC.AgentUUID, parseQUIC.AgentHostname)
+	srcDstPair := data.NewUniqueIPPair(srcUniqIP, dstUniqIP)
+
+	srcFQDNPair := data.NewUniqueSrcFQDNPair(srcUniqIP, fqdn)
+
+	srcDstKey := srcDstPair.MapKey()
+	srcKey := srcUniqIP.MapKey()
+	dstKey := dstUniqIP.MapKey()
+
+	srcFQDNKey := srcFQDNPair.MapKey()
+
+	// QUIC always runs over UDP. The timestamps and data sizes for the
+	// unique connection come from the matching conn record.
+	tuple := strconv.Itoa(parseQUIC.DestinationPort) + ":udp:quic"
+
+	newUniqueConnection := updateUniqueConnectionsByQUIC(srcIP, dstIP, srcDstPair, srcDstKey, tuple, filter, retVals)
+
+	updateTLSConnectionsByQUIC(srcIP, dstUniqIP, srcFQDNPair, srcFQDNKey, parseQUIC, filter, retVals)
+
+	// host records are handled the same as for SSL records since neither
+	// carry any connection details
+	updateHostsBySSL(srcIP, dstIP, srcUniqIP, dstUniqIP, srcKey, dstKey, newUniqueConnection, filter, retVals)
+	return true
+}
+
+func updateUniqueConnectionsByQUIC(srcIP, dstIP net.IP, srcDstPair data.UniqueIPPair, srcDstKey string,
+	tuple string, filter filter, retVals ParseResults) (newEntry bool) {
+
+	retVals.UniqueConnLock.Lock()
+	defer retVals.UniqueConnLock.Unlock()
+
+	newEntry = false
+
+	// Check if uconn map value is set, because this record could
+	// come before a relevant uconns record
+	if _, ok := retVals.UniqueConnMap[srcDstKey]; !ok {
+		newEntry = true
+
+		// create new uconn record if it does not exist
+		retVals.UniqueConnMap[srcDstKey] = &uconn.Input{
+			Hosts:      srcDstPair,
+			IsLocalSrc: filter.checkIfInternal(srcIP),
+			IsLocalDst: filter.checkIfInternal(dstIP),
+			Tuples:     make(data.StringSet),
+		}
+	}
+
+	// ///// UNION QUIC (PORT PROTOCOL SERVICE) TUPLE INTO SET FOR UNIQUE CONNECTION /////
+	// this tags the unique connection as QUIC even when the conn record
+	// was produced by a version of Zeek which does not identify the service
+	retVals.UniqueConnMap[srcDstKey].Tuples.Insert(tuple)
+	return
+}
+
+func updateTLSConnectionsByQUIC(srcIP net.IP, dstUniqIP data.UniqueIP, srcFQDNPair data.UniqueSrcFQDNPair, srcFQDNKey string,
+	parseQUIC *parsetypes.QUIC, filter filter, retVals ParseResults) {
+
+	if len(srcFQDNPair.FQDN) == 0 {
+		return // don't record QUIC SNI connections when the SNI is missing
+	}
+
+	retVals.TLSConnLock.Lock()
+	defer retVals.TLSConnLock.Unlock()
+
+	// QUIC negotiates TLS 1.3 inside the connection, so QUIC SNI connections
+	// are tracked alongside the TLS SNI connections
+	if _, ok := retVals.TLSConnMap[srcFQDNKey]; !ok {
+		retVals.TLSConnMap[srcFQDNKey] = &sniconn.TLSInput{
+			Hosts:           srcFQDNPair,
+			IsLocalSrc:      filter.checkIfInternal(srcIP),
+			Timestamps:      []int64{},
+			RespondingIPs:   make(data.UniqueIPSet),
+			RespondingPorts: make(data.IntSet),
+
+			Subjects: make(data.StringSet),
+			JA3s:     make(data.StringSet),
+			JA3Ss:    make(data.StringSet),
+		}
+	}
+
+	// ///// INCREMENT THE CONNECTION COUNT FOR THE QUIC SNI CONNECTION /////
+	retVals.TLSConnMap[srcFQDNKey].ConnectionCount++
+
+	// ///// APPEND TIMESTAMP TO TLS TIMESTAMP LIST /////
+	retVals.TLSConnMap[srcFQDNKey].Timestamps = append(
+		retVals.TLSConnMap[srcFQDNKey].Timestamps, parseQUIC.TimeStamp,
+	)
+
+	// ///// UNION DESTINATION HOST INTO TLS RESPONDING HOSTS /////
+	retVals.TLSConnMap[srcFQDNKey].RespondingIPs.Insert(dstUniqIP)
+
+	// ///// UNION DESTINATION PORT INTO TLS RESPONDING PORTS /////
+	retVals.TLSConnMap[srcFQDNKey].RespondingPorts.Insert(parseQUIC.DestinationPort)
+
+	// ///// APPEND ZEEK RECORD UID INTO TLS UID SET /////
+	// This allows us to link conn record information to this
+	// ip -> fqdn record such as data sizes.
+	if len(parseQUIC.UID) > 0 {
+		retVals.TLSConnMap[srcFQDNKey].ZeekUIDs = append(
+			retVals.TLSConnMap[srcFQDNKey].ZeekUIDs,
+			parseQUIC.UID,
+		)
+	}
+}
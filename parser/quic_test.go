@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/parser/files"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQUICIngestion parses a conn log and a quic log describing the same
+// UDP/443 connections and verifies the resulting unique connection is tagged
+// as QUIC and has enough data to be considered for beacon analysis.
+func TestQUICIngestion(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	fs := &FSImporter{
+		filter: filter{
+			internal: util.ParseSubnets([]string{"10.0.0.0/8"}),
+		},
+		log:      logger,
+		config:   conf,
+		database: &database.DB{},
+		progress: new(ImportProgress),
+	}
+
+	logFiles := files.GatherLogFiles([]string{filepath.Join("testdata", "quic")}, logger)
+	indexedFiles, _ := files.IndexFiles(logFiles, 1, "test", 0, logger, conf)
+	require.Len(t, indexedFiles, 2)
+
+	retVals := fs.parseFiles(indexedFiles, 2, logger)
+
+	src := data.UniqueIP{IP: "10.0.0.5", NetworkUUID: util.UnknownPrivateNetworkUUID, NetworkName: util.UnknownPrivateNetworkName}
+	dst := data.UniqueIP{IP: "142.250.0.10", NetworkUUID: util.PublicNetworkUUID, NetworkName: util.PublicNetworkName}
+	uconnKey := data.NewUniqueIPPair(src, dst).MapKey()
+
+	require.Contains(t, retVals.UniqueConnMap, uconnKey)
+	uconnInput := retVals.UniqueConnMap[uconnKey]
+
+	// the conn log did not identify the service, the quic log tags it
+	assert.True(t, uconnInput.Tuples.Contains("443:udp:quic"), "unique connection should be tagged as QUIC")
+	assert.True(t, uconnInput.Tuples.Contains("443:udp:-"))
+
+	// the timestamps and sizes come from the conn records
+	assert.Equal(t, int64(24), uconnInput.ConnectionCount)
+	assert.Len(t, uconnInput.TsList, 24)
+	assert.Len(t, uconnInput.OrigBytesList, 24)
+	assert.True(t, uconnInput.ConnectionCount > int64(conf.S.Beacon.DefaultConnectionThresh),
+		"QUIC connections should be eligible for beacon analysis")
+
+	// the QUIC SNI is tracked along with the TLS SNI connections
+	sniKey := data.NewUniqueSrcFQDNPair(src, "beacon.example.com").MapKey()
+	require.Contains(t, retVals.TLSConnMap, sniKey)
+	assert.Equal(t, int64(24), retVals.TLSConnMap[sniKey].ConnectionCount)
+	assert.Len(t, retVals.TLSConnMap[sniKey].ZeekUIDs, 24)
+
+	// only a single unique connection should be recorded for both hosts
+	assert.Equal(t, 1, retVals.HostMap[src.MapKey()].CountSrc)
+	assert.Equal(t, 1, retVals.HostMap[dst.MapKey()].CountDst)
+
+	assert.Equal(t, int64(2), fs.Progress().Snapshot().FilesDone)
+}
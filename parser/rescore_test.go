@@ -0,0 +1,166 @@
+// +build integration
+
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRescoreConnLog holds a beacon every minute whose sizes vary so that
+// the timestamp and data size scores differ
+func testRescoreConnLog() string {
+	var builder strings.Builder
+	builder.WriteString("#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n")
+	builder.WriteString("#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\tservice\tduration\torig_bytes\tresp_bytes\tconn_state\torig_pkts\torig_ip_bytes\tresp_pkts\tresp_ip_bytes\n")
+	builder.WriteString("#types\ttime\tstring\taddr\tport\taddr\tport\tenum\tstring\tinterval\tcount\tcount\tstring\tcount\tcount\tcount\tcount\n")
+	for i := 0; i < 40; i++ {
+		origBytes := 200 + (i*37)%900
+		fmt.Fprintf(&builder, "%d.000000\tCRescore%02d\t10.0.0.5\t%d\t203.0.113.10\t443\ttcp\tssl\t0.500000\t%d\t1200\tSF\t5\t%d\t5\t1400\n",
+			1600000000+i*60, i, 50000+i, origBytes, origBytes+200)
+	}
+	return builder.String()
+}
+
+func TestRescore(t *testing.T) {
+	res := resources.InitIntegrationTestingResources(t)
+
+	db := "tmp_test_rescore"
+	res.DB.SelectDB(db)
+	res.Config.S.Rolling = config.RollingStaticCfg{TotalChunks: 1}
+	defer func() {
+		res.MetaDB.DeleteDB(db)
+		res.DB.Session.DB(db).DropDatabase()
+	}()
+
+	dir := t.TempDir()
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conn.log"), []byte(testRescoreConnLog()), 0644))
+
+	importer := NewFSImporter(res)
+	indexedFiles, indexErrs := importer.CollectFileDetails([]string{dir}, 1)
+	require.Empty(t, indexErrs)
+	require.Nil(t, importer.Run(indexedFiles, 1))
+
+	pair := bson.M{"src": "10.0.0.5", "dst": "203.0.113.10"}
+	readResults := func() (beacon.Result, []bson.M) {
+		var result beacon.Result
+		require.Nil(t, res.DB.Session.DB(db).C(res.Config.T.Beacon.BeaconTable).Find(pair).One(&result))
+		var uconnDoc struct {
+			Dat []bson.M `bson:"dat"`
+		}
+		require.Nil(t, res.DB.Session.DB(db).C(res.Config.T.Structure.UniqueConnTable).Find(pair).One(&uconnDoc))
+		return result, uconnDoc.Dat
+	}
+	before, beforeDat := readResults()
+	require.EqualValues(t, 40, before.Connections)
+
+	// favor the regular timestamps over the varying sizes
+	res.Config.S.Beacon.TsWeight = 0.7
+	res.Config.S.Beacon.DsWeight = 0.1
+	res.Config.S.Beacon.DurWeight = 0.1
+	res.Config.S.Beacon.HistWeight = 0.1
+
+	minTimestamp, maxTimestamp, err := res.MetaDB.GetTSRange(db)
+	require.Nil(t, err)
+	require.Nil(t, beacon.NewMongoRepository(res.DB, res.Config, res.Log).Rescore(minTimestamp, maxTimestamp))
+
+	after, afterDat := readResults()
+	assert.NotEqual(t, before.Score, after.Score)
+
+	// the stored connections are read but left as they were
+	assert.Equal(t, before.Connections, after.Connections)
+	assert.Equal(t, before.Ts, after.Ts)
+	assert.Equal(t, beforeDat, afterDat)
+}
+
+// recordingSink keeps the changes it receives
+type recordingSink struct {
+	lock    sync.Mutex
+	changes []database.BulkChanges
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Write(data database.BulkChanges) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.changes = append(s.changes, data)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestRescoreDryRun(t *testing.T) {
+	res := resources.InitIntegrationTestingResources(t)
+
+	db := "tmp_test_rescore_dry_run"
+	res.DB.SelectDB(db)
+	res.Config.S.Rolling = config.RollingStaticCfg{TotalChunks: 1}
+	defer func() {
+		res.MetaDB.DeleteDB(db)
+		res.DB.Session.DB(db).DropDatabase()
+	}()
+
+	dir := t.TempDir()
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conn.log"), []byte(testRescoreConnLog()), 0644))
+
+	importer := NewFSImporter(res)
+	indexedFiles, indexErrs := importer.CollectFileDetails([]string{dir}, 1)
+	require.Empty(t, indexErrs)
+	require.Nil(t, importer.Run(indexedFiles, 1))
+
+	pair := bson.M{"src": "10.0.0.5", "dst": "203.0.113.10"}
+	readDocs := func(collection string) []bson.M {
+		var docs []bson.M
+		require.Nil(t, res.DB.Session.DB(db).C(collection).Find(nil).Select(bson.M{"_id": 0}).All(&docs))
+		return docs
+	}
+	readScore := func() float64 {
+		var result beacon.Result
+		require.Nil(t, res.DB.Session.DB(db).C(res.Config.T.Beacon.BeaconTable).Find(pair).One(&result))
+		return result.Score
+	}
+	beforeBeacons := readDocs(res.Config.T.Beacon.BeaconTable)
+	beforeUconns := readDocs(res.Config.T.Structure.UniqueConnTable)
+	beforeScore := readScore()
+
+	// favor the regular timestamps over the varying sizes
+	res.Config.S.Beacon.TsWeight = 0.7
+	res.Config.S.Beacon.DsWeight = 0.1
+	res.Config.S.Beacon.DurWeight = 0.1
+	res.Config.S.Beacon.HistWeight = 0.1
+
+	minTimestamp, maxTimestamp, err := res.MetaDB.GetTSRange(db)
+	require.Nil(t, err)
+	dryRun := &recordingSink{}
+	require.Nil(t, beacon.NewDryRunRepository(res.DB, res.Config, res.Log, dryRun).Rescore(minTimestamp, maxTimestamp))
+
+	// nothing was written to MongoDB
+	assert.Equal(t, beforeBeacons, readDocs(res.Config.T.Beacon.BeaconTable))
+	assert.Equal(t, beforeUconns, readDocs(res.Config.T.Structure.UniqueConnTable))
+
+	// the sink received the new score, which matches a real rescore
+	var dryRunScore interface{}
+	for _, changes := range dryRun.changes {
+		for _, change := range changes[res.Config.T.Beacon.BeaconTable] {
+			dryRunScore = change.Update.(bson.M)["$set"].(bson.M)["score"]
+		}
+	}
+	require.NotNil(t, dryRunScore)
+	assert.NotEqual(t, beforeScore, dryRunScore)
+
+	require.Nil(t, beacon.NewMongoRepository(res.DB, res.Config, res.Log).Rescore(minTimestamp, maxTimestamp))
+	assert.Equal(t, readScore(), dryRunScore)
+}
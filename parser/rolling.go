@@ -0,0 +1,248 @@
+package parser
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/activecm/rita/parser/files"
+	"github.com/activecm/rita/parser/parsetypes"
+	"github.com/activecm/rita/pkg/uconn"
+	log "github.com/sirupsen/logrus"
+)
+
+// chunksToPrune returns the chunks of a rolling database which are older than
+// the maxChunks most recent chunks, newest first. Chunk IDs wrap back to 0
+// after totalChunks, so the chunk before 0 is totalChunks-1. A maxChunks of 0
+// keeps every chunk.
+func chunksToPrune(currentChunk, totalChunks, maxChunks int) []int {
+	if maxChunks <= 0 || maxChunks >= totalChunks {
+		return nil
+	}
+
+	var chunks []int
+	for age := maxChunks; age < totalChunks; age++ {
+		chunks = append(chunks, (currentChunk-age+totalChunks)%totalChunks)
+	}
+	return chunks
+}
+
+// pruneChunks removes the analysis results of the chunks which are older than
+// the Rolling MaxChunks most recent chunks. The file records of the pruned
+// chunks are kept so that their logs are not imported again. Pruning waits
+// until the beacon analysis of the current chunk has finished.
+func (fs *FSImporter) pruneChunks() error {
+	rolling := fs.config.S.Rolling
+	if !rolling.Rolling || fs.beaconsPending {
+		return nil
+	}
+
+	for _, cid := range chunksToPrune(rolling.CurrentChunk, rolling.TotalChunks, rolling.MaxChunks) {
+		chunkSet, err := fs.metaDB.IsChunkSet(cid, fs.database.GetSelectedDB())
+		if err != nil {
+			return fmt.Errorf("could not find CID List entry in metadatabase: %w", err)
+		}
+		if !chunkSet {
+			continue
+		}
+
+		fmt.Printf("\t[-] Removing chunk %d from rolling dataset ... \n", cid)
+		err = fs.removeAnalysisChunk(cid)
+		if err != nil {
+			return &DatabaseWriteError{
+				Database: fs.database.GetSelectedDB(),
+				Op:       fmt.Sprintf("removing chunk %d from rolling dataset", cid),
+				Err:      err,
+			}
+		}
+	}
+	return nil
+}
+
+// chunkDuration returns the length of time covered by each chunk when chunks
+// are assigned by record timestamps, or 0 if chunks are assigned per import
+func (fs *FSImporter) chunkDuration() time.Duration {
+	if !fs.config.S.Rolling.Rolling || fs.config.S.Rolling.TotalChunks < 1 {
+		return 0
+	}
+	return fs.config.S.Rolling.ChunkDuration
+}
+
+// chunkPeriod returns the number of whole chunk durations between the epoch
+// and the timestamp ts in seconds
+func chunkPeriod(ts int64, chunkDuration time.Duration) int64 {
+	seconds := int64(chunkDuration / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	period := ts / seconds
+	// round down for timestamps before the epoch
+	if ts%seconds < 0 {
+		period--
+	}
+	return period
+}
+
+// chunkForPeriod returns the chunk which holds the records of a period.
+// Periods wrap back to chunk 0 after totalChunks.
+func chunkForPeriod(period int64, totalChunks int) int {
+	cid := int(period % int64(totalChunks))
+	if cid < 0 {
+		cid += totalChunks
+	}
+	return cid
+}
+
+// chunkForTimestamp returns the chunk which holds records with the timestamp ts
+func chunkForTimestamp(ts int64, chunkDuration time.Duration, totalChunks int) int {
+	return chunkForPeriod(chunkPeriod(ts, chunkDuration), totalChunks)
+}
+
+// entryTimestamp returns the timestamp of a parsed record
+func entryTimestamp(entry parsetypes.BroData) (int64, bool) {
+	switch typedEntry := entry.(type) {
+	case *parsetypes.Conn:
+		return typedEntry.TimeStamp, true
+	case *parsetypes.DNS:
+		return typedEntry.TimeStamp, true
+	case *parsetypes.HTTP:
+		return typedEntry.TimeStamp, true
+	case *parsetypes.OpenConn:
+		return typedEntry.TimeStamp, true
+	case *parsetypes.SSL:
+		return typedEntry.TimeStamp, true
+	case *parsetypes.QUIC:
+		return typedEntry.TimeStamp, true
+	}
+	return 0, false
+}
+
+// connTimestampRange returns the earliest and latest timestamps of the
+// connections in uconnMap. ok is false if there are no timestamps.
+func connTimestampRange(uconnMap map[string]*uconn.Input) (min int64, max int64, ok bool) {
+	for _, input := range uconnMap {
+		for _, ts := range input.TsList {
+			if !ok || ts < min {
+				min = ts
+			}
+			if !ok || ts > max {
+				max = ts
+			}
+			ok = true
+		}
+	}
+	return min, max, ok
+}
+
+// parseFilesByChunk parses the bro files and groups the records by the
+// chunk period containing their timestamps
+func (fs *FSImporter) parseFilesByChunk(indexedFiles []*files.IndexedFile, parsingThreads int, logger *log.Logger) map[int64]ParseResults {
+	chunkDuration := fs.chunkDuration()
+	periods := make(map[int64]ParseResults)
+	periodsLock := new(sync.Mutex)
+
+	fs.parseFilesInto(indexedFiles, parsingThreads, logger, func(entry parsetypes.BroData) (ParseResults, bool) {
+		ts, ok := entryTimestamp(entry)
+		if !ok || ts < 0 {
+			return ParseResults{}, false
+		}
+		period := chunkPeriod(ts, chunkDuration)
+
+		periodsLock.Lock()
+		defer periodsLock.Unlock()
+		retVals, ok := periods[period]
+		if !ok {
+			retVals = newParseResults()
+			periods[period] = retVals
+		}
+		return retVals, true
+	})
+	return periods
+}
+
+// analyzeChunks builds the analysis results for each chunk period, oldest
+// first. A chunk holding the records of an older period is cleared before it
+// is reused. Records older than the period already held by their chunk have
+// fallen out of the rolling dataset and are skipped. Afterwards the chunk of
+// the newest period becomes the current chunk.
+func (fs *FSImporter) analyzeChunks(periods map[int64]ParseResults) error {
+	rolling := &fs.config.S.Rolling
+	db := fs.database.GetSelectedDB()
+
+	sortedPeriods := make([]int64, 0, len(periods))
+	for period := range periods {
+		sortedPeriods = append(sortedPeriods, period)
+	}
+	sort.Slice(sortedPeriods, func(i, j int) bool { return sortedPeriods[i] < sortedPeriods[j] })
+
+	newestChunk, newestPeriod := rolling.CurrentChunk, int64(-1)
+	currentState, err := fs.metaDB.GetChunkState(rolling.CurrentChunk, db)
+	if err != nil {
+		return fmt.Errorf("could not find CID List entry in metadatabase: %w", err)
+	}
+	if currentState.Set {
+		newestPeriod = currentState.Period
+	}
+
+	for _, period := range sortedPeriods {
+		cid := chunkForPeriod(period, rolling.TotalChunks)
+		state, err := fs.metaDB.GetChunkState(cid, db)
+		if err != nil {
+			return fmt.Errorf("could not find CID List entry in metadatabase: %w", err)
+		}
+
+		if state.Set && state.Period > period {
+			fs.log.WithFields(log.Fields{
+				"chunk":        cid,
+				"period":       period,
+				"chunk_period": state.Period,
+			}).Warn("Skipping records older than the rolling dataset")
+			fmt.Printf("\t[!] Skipping records older than the data in chunk %d\n", cid)
+			continue
+		}
+
+		if state.Set && state.Period < period {
+			fmt.Printf("\t[-] Removing outdated data from chunk %d of rolling dataset ... \n", cid)
+			err := fs.removeAnalysisChunk(cid)
+			if err != nil {
+				return &DatabaseWriteError{
+					Database: db,
+					Op:       fmt.Sprintf("removing outdated data from chunk %d of rolling dataset", cid),
+					Err:      err,
+				}
+			}
+		}
+
+		fmt.Printf("\t[-] Importing records from %s into chunk %d\n",
+			time.Unix(period*int64(rolling.ChunkDuration/time.Second), 0).UTC().Format(time.RFC3339), cid)
+
+		// Set chunk before we continue so if process dies, we still verify with a delete if
+		// any data was written out.
+		rolling.CurrentChunk = cid
+		fs.metaDB.SetChunkPeriod(cid, db, period)
+
+		err = fs.analyze(periods[period])
+		if ctxErr := fs.ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		if err != nil {
+			return err
+		}
+
+		if period > newestPeriod {
+			newestChunk, newestPeriod = cid, period
+		}
+	}
+
+	rolling.CurrentChunk = newestChunk
+	err = fs.metaDB.SetRollingSettings(db, rolling.CurrentChunk, rolling.TotalChunks)
+	if err != nil {
+		fs.log.WithFields(log.Fields{
+			"err":      err,
+			"database": db,
+		}).Error("Could not update rolling database settings for database")
+		fmt.Printf("\t[!] %v", err.Error())
+	}
+	return nil
+}
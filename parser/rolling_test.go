@@ -0,0 +1,170 @@
+package parser
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/parser/files"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChunksToPrune(t *testing.T) {
+	assert.Equal(t, []int{1, 0, 9, 8, 7, 6}, chunksToPrune(5, 10, 4))
+	assert.Equal(t, []int{1}, chunksToPrune(0, 10, 9), "the chunk after the current chunk should be the oldest")
+
+	assert.Empty(t, chunksToPrune(5, 10, 0), "a MaxChunks of 0 keeps every chunk")
+	assert.Empty(t, chunksToPrune(5, 10, 10))
+	assert.Empty(t, chunksToPrune(5, 10, 24), "every chunk fits within a MaxChunks above the total")
+}
+
+// TestChunksToPruneRollingCycles imports a chunk at a time through several
+// trips around the rolling database and checks that only the most recent
+// chunks are kept after each import
+func TestChunksToPruneRollingCycles(t *testing.T) {
+	const totalChunks = 6
+	const maxChunks = 3
+
+	set := make(map[int]bool)
+	var imported []int
+
+	for i := 0; i < totalChunks*4; i++ {
+		currentChunk := i % totalChunks
+		set[currentChunk] = true
+		imported = append(imported, currentChunk)
+
+		for _, cid := range chunksToPrune(currentChunk, totalChunks, maxChunks) {
+			delete(set, cid)
+		}
+
+		expected := imported
+		if len(imported) > maxChunks {
+			expected = imported[len(imported)-maxChunks:]
+		}
+		var kept []int
+		for cid := range set {
+			kept = append(kept, cid)
+		}
+		assert.ElementsMatch(t, expected, kept, "after importing chunk %d of cycle %d", currentChunk, i/totalChunks)
+	}
+
+	// the chunks which were kept are the newest
+	var kept []int
+	for cid := range set {
+		kept = append(kept, cid)
+	}
+	sort.Ints(kept)
+	assert.Equal(t, []int{3, 4, 5}, kept)
+}
+
+func TestChunkForTimestamp(t *testing.T) {
+	const midnight = 1599955200 // 2020-09-13T00:00:00Z
+
+	// records on either side of a boundary go to neighbouring chunks
+	assert.Equal(t, 0, chunkForTimestamp(midnight, time.Hour, 24))
+	assert.Equal(t, 0, chunkForTimestamp(midnight+3599, time.Hour, 24))
+	assert.Equal(t, 1, chunkForTimestamp(midnight+3600, time.Hour, 24))
+	assert.Equal(t, 23, chunkForTimestamp(midnight-1, time.Hour, 24))
+
+	// the chunks wrap around after the total number of chunks
+	assert.Equal(t, 0, chunkForTimestamp(midnight+24*3600, time.Hour, 24))
+	assert.Equal(t, 2, chunkForTimestamp(midnight+26*3600+1800, time.Hour, 24))
+
+	// shorter durations split the hour further
+	assert.Equal(t, 0, chunkForTimestamp(midnight+899, 15*time.Minute, 4))
+	assert.Equal(t, 1, chunkForTimestamp(midnight+900, 15*time.Minute, 4))
+	assert.Equal(t, 3, chunkForTimestamp(midnight+2700, 15*time.Minute, 4))
+	assert.Equal(t, 0, chunkForTimestamp(midnight+3600, 15*time.Minute, 4))
+
+	// periods before the epoch round down
+	assert.Equal(t, int64(-1), chunkPeriod(-1, time.Hour))
+	assert.Equal(t, 23, chunkForTimestamp(-1, time.Hour, 24))
+	assert.Equal(t, int64(0), chunkPeriod(0, time.Hour))
+}
+
+func TestConnTimestampRange(t *testing.T) {
+	uconnMap := map[string]*uconn.Input{
+		"a": {TsList: []int64{1600003600, 1600000100, 1600007000}},
+		"b": {TsList: []int64{1600000050}},
+		"c": {},
+	}
+	min, max, ok := connTimestampRange(uconnMap)
+	assert.True(t, ok)
+	assert.Equal(t, int64(1600000050), min)
+	assert.Equal(t, int64(1600007000), max)
+
+	// a chunk without connection timestamps has no range
+	_, _, ok = connTimestampRange(map[string]*uconn.Input{"c": {}})
+	assert.False(t, ok)
+}
+
+// TestParseFilesByChunk parses a conn log spanning several chunk boundaries
+// and checks each record is grouped into the period holding its timestamp
+func TestParseFilesByChunk(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Rolling.Rolling = true
+	conf.S.Rolling.TotalChunks = 4
+	conf.S.Rolling.ChunkDuration = time.Hour
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	fs := &FSImporter{
+		filter: filter{
+			internal: util.ParseSubnets([]string{"10.0.0.0/8"}),
+		},
+		log:      logger,
+		config:   conf,
+		database: &database.DB{},
+		progress: new(ImportProgress),
+	}
+
+	// 1599998400 is 2020-09-13T12:00:00Z
+	dir := t.TempDir()
+	contents := "#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n" +
+		"#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\n" +
+		"#types\ttime\tstring\taddr\tport\taddr\tport\tenum\n" +
+		"1599998400.000000\tC0\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600001999.900000\tC1\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600002000.000000\tC2\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600002060.000000\tC3\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600005600.000000\tC4\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n" +
+		"1600013000.000000\tC5\t10.0.0.5\t50000\t203.0.113.7\t443\ttcp\n"
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conn.log"), []byte(contents), 0644))
+
+	indexedFiles, _ := files.IndexFiles([]string{filepath.Join(dir, "conn.log")}, 1, "test", 0, logger, conf)
+	require.Len(t, indexedFiles, 1)
+
+	periods := fs.parseFilesByChunk(indexedFiles, 2, logger)
+
+	const firstPeriod = 1599998400 / 3600
+	expected := map[int64][]int64{
+		firstPeriod:     {1599998400, 1600001999},
+		firstPeriod + 1: {1600002000, 1600002060},
+		firstPeriod + 2: {1600005600},
+		firstPeriod + 4: {1600013000},
+	}
+	require.Len(t, periods, len(expected))
+	for period, tsList := range expected {
+		require.Contains(t, periods, period)
+		require.Len(t, periods[period].UniqueConnMap, 1)
+		for _, uconnInput := range periods[period].UniqueConnMap {
+			assert.ElementsMatch(t, tsList, uconnInput.TsList, "period %d", period)
+		}
+	}
+
+	// the fifth period wraps back around to the chunk of the first
+	assert.Equal(t, chunkForPeriod(firstPeriod, 4), chunkForPeriod(firstPeriod+4, 4))
+	assert.Equal(t, 0, chunkForPeriod(firstPeriod, 4))
+	assert.Equal(t, 1, chunkForPeriod(firstPeriod+1, 4))
+	assert.Equal(t, 2, chunkForPeriod(firstPeriod+2, 4))
+}
@@ -0,0 +1,97 @@
+// +build integration
+
+package parser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServicesConnLog holds a beacon every minute over HTTPS which is mixed
+// with NTP polls to the same server, and a second server which only answers
+// NTP polls. Either server sees more connections than the strobe limit.
+func testServicesConnLog(strobeLimit int) string {
+	var builder strings.Builder
+	builder.WriteString("#separator \\x09\n#set_separator\t,\n#empty_field\t(empty)\n#unset_field\t-\n#path\tconn\n")
+	builder.WriteString("#fields\tts\tuid\tid.orig_h\tid.orig_p\tid.resp_h\tid.resp_p\tproto\tservice\tduration\torig_bytes\tresp_bytes\tconn_state\torig_pkts\torig_ip_bytes\tresp_pkts\tresp_ip_bytes\n")
+	builder.WriteString("#types\ttime\tstring\taddr\tport\taddr\tport\tenum\tstring\tinterval\tcount\tcount\tstring\tcount\tcount\tcount\tcount\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&builder, "%d.000000\tCHttps%03d\t10.0.0.5\t%d\t203.0.113.10\t443\ttcp\tssl\t0.500000\t200\t1200\tSF\t5\t400\t5\t1400\n",
+			1600000000+i*60, i, 50000+i)
+	}
+	for i := 0; i < strobeLimit+10; i++ {
+		fmt.Fprintf(&builder, "%d.000000\tCMixedNtp%03d\t10.0.0.5\t%d\t203.0.113.10\t123\tudp\tntp\t0.100000\t48\t48\tSF\t1\t76\t1\t76\n",
+			1600000030+i*30, i, 40000+i)
+		fmt.Fprintf(&builder, "%d.000000\tCNtp%03d\t10.0.0.5\t%d\t203.0.113.20\t123\tudp\tntp\t0.100000\t48\t48\tSF\t1\t76\t1\t76\n",
+			1600000000+i*30, i, 30000+i)
+	}
+	return builder.String()
+}
+
+func TestIncludedServices(t *testing.T) {
+	res := resources.InitIntegrationTestingResources(t)
+
+	db := "tmp_test_included_services"
+	res.DB.SelectDB(db)
+	res.Config.S.Rolling = config.RollingStaticCfg{TotalChunks: 1}
+	res.Config.S.Strobe.ConnectionLimit = 50
+	res.Config.S.Beacon.IncludedServices = []string{"443:tcp"}
+	defer func() {
+		res.MetaDB.DeleteDB(db)
+		res.DB.Session.DB(db).DropDatabase()
+	}()
+
+	dir := t.TempDir()
+	connLog := testServicesConnLog(res.Config.S.Strobe.ConnectionLimit)
+	require.Nil(t, ioutil.WriteFile(filepath.Join(dir, "conn.log"), []byte(connLog), 0644))
+
+	importer := NewFSImporter(res)
+	indexedFiles, indexErrs := importer.CollectFileDetails([]string{dir}, 1)
+	require.Empty(t, indexErrs)
+	require.Nil(t, importer.Run(indexedFiles, 1))
+
+	beacons := res.DB.Session.DB(db).C(res.Config.T.Beacon.BeaconTable)
+
+	// the mixed pair is scored on its HTTPS connections alone, so the NTP
+	// polls neither make it a strobe nor add to its counts
+	var mixed beacon.Result
+	require.Nil(t, beacons.Find(bson.M{"src": "10.0.0.5", "dst": "203.0.113.10"}).One(&mixed))
+	assert.EqualValues(t, 40, mixed.Connections)
+	assert.EqualValues(t, 40*1800, mixed.TotalBytes)
+	assert.Equal(t, 1800.0, mixed.AvgBytes)
+	for _, uid := range mixed.UIDs {
+		assert.True(t, strings.HasPrefix(uid, "CHttps"), uid)
+	}
+
+	// the NTP pair exceeds the connection threshold and the strobe limit,
+	// but none of its connections are kept for beacon analysis
+	err := beacons.Find(bson.M{"src": "10.0.0.5", "dst": "203.0.113.20"}).One(&beacon.Result{})
+	assert.Equal(t, mgo.ErrNotFound, err)
+
+	strobes, err := beacon.StrobeResults(res, -1, 0, true)
+	require.Nil(t, err)
+	assert.Empty(t, strobes)
+
+	// the unique connections still count every connection
+	var uconnDoc struct {
+		Strobe bool     `bson:"strobe"`
+		Dat    []bson.M `bson:"dat"`
+	}
+	require.Nil(t, res.DB.Session.DB(db).C(res.Config.T.Structure.UniqueConnTable).
+		Find(bson.M{"src": "10.0.0.5", "dst": "203.0.113.20"}).One(&uconnDoc))
+	assert.False(t, uconnDoc.Strobe)
+	require.Len(t, uconnDoc.Dat, 1)
+	assert.EqualValues(t, res.Config.S.Strobe.ConnectionLimit+10, uconnDoc.Dat[0]["count"])
+	assert.EqualValues(t, 0, uconnDoc.Dat[0]["bcount"])
+}
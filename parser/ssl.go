@@ -13,7 +13,7 @@ import (
 	"github.com/activecm/rita/util"
 )
 
-func parseSSLEntry(parseSSL *parsetypes.SSL, filter filter, retVals ParseResults) {
+func parseSSLEntry(parseSSL *parsetypes.SSL, filter filter, retVals ParseResults) bool {
 	src := parseSSL.Source
 	dst := parseSSL.Destination
 	certStatus := parseSSL.ValidationStatus
@@ -39,9 +39,9 @@ func parseSSLEntry(parseSSL *parsetypes.SSL, filter filter, retVals ParseResults
 
 	// create uconn and cert records
 	// Run conn pair through filter to filter out certain connections
-	ignore := filter.filterConnPair(srcIP, dstIP)
+	ignore := filter.filterConnPair(srcIP, dstIP) || filter.filterBaselineDomain(fqdn)
 	if ignore {
-		return
+		return false
 	}
 
 	certificateIsInvalid := certStatus != "ok" && certStatus != "-" && certStatus != "" && certStatus != " "
@@ -57,6 +57,7 @@ func parseSSLEntry(parseSSL *parsetypes.SSL, filter filter, retVals ParseResults
 		// the unique connection record may have been created before the certificate record was seen
 		copyServiceTuplesFromUconnToCerts(dstKey, srcDstKey, retVals)
 	}
+	return true
 }
 
 func updateUseragentsBySSL(srcUniqIP data.UniqueIP, parseSSL *parsetypes.SSL, retVals ParseResults) {
@@ -0,0 +1,109 @@
+package beacon
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/activecm/rita/pkg/data"
+)
+
+// CIDRResult represents the beacons from a source to the destinations within
+// a network prefix. Beacons to many addresses in the same cloud or CDN subnet
+// often belong to a single logical destination.
+type CIDRResult struct {
+	data.UniqueSrcIP `bson:",inline"`
+	DstCIDR          string  `bson:"dst_cidr"`
+	DstNetworkName   string  `bson:"dst_network_name"`
+	DstCount         int     `bson:"dst_count"`
+	Connections      int64   `bson:"connection_count"`
+	AvgBytes         float64 `bson:"avg_bytes"`
+	TotalBytes       int64   `bson:"total_bytes"`
+	TsScore          float64 `bson:"ts_score"`
+	DsScore          float64 `bson:"ds_score"`
+	TopInterval      int64   `bson:"top_interval"`
+	MeanScore        float64 `bson:"mean_score"`
+	Score            float64 `bson:"score"`
+}
+
+// AggregateByCIDR groups beacons by their source and the network prefix of
+// their destination. IPv4 destinations are grouped using prefixLen. IPv6
+// destinations are not aggregated. The timing, data size, and overall scores
+// of each group are averaged by connection count, while Score holds the highest
+// score in the group. The groups are returned sorted by Score.
+func AggregateByCIDR(beacons []Result, prefixLen int) ([]CIDRResult, error) {
+	if prefixLen < 0 || prefixLen > 32 {
+		return nil, fmt.Errorf("invalid prefix length %d: must be between 0 and 32", prefixLen)
+	}
+
+	ipv4Mask := net.CIDRMask(prefixLen, 32)
+
+	// topConns tracks the connection count of the beacon the top interval
+	// of each group was taken from
+	var groups []*CIDRResult
+	var topConns []int64
+	index := make(map[string]int)
+
+	for _, beacon := range beacons {
+		cidr := beacon.DstIP
+		if ip := net.ParseIP(beacon.DstIP); ip != nil {
+			if ipv4 := ip.To4(); ipv4 != nil {
+				cidr = ipv4.Mask(ipv4Mask).String() + "/" + strconv.Itoa(prefixLen)
+			} else {
+				cidr = ip.String() + "/128"
+			}
+		}
+
+		key := beacon.SrcIP + string(beacon.SrcNetworkUUID.Data) + cidr + string(beacon.DstNetworkUUID.Data)
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, &CIDRResult{
+				UniqueSrcIP:    beacon.UniqueSrcIP,
+				DstCIDR:        cidr,
+				DstNetworkName: beacon.DstNetworkName,
+			})
+			topConns = append(topConns, -1)
+		}
+
+		group := groups[i]
+		group.DstCount++
+		group.Connections += beacon.Connections
+		group.TotalBytes += beacon.TotalBytes
+
+		// sum the weighted scores here and divide them out once every
+		// beacon has been grouped
+		weight := float64(beacon.Connections)
+		group.TsScore += beacon.Ts.Score * weight
+		group.DsScore += beacon.Ds.Score * weight
+		group.MeanScore += beacon.Score * weight
+
+		if beacon.Score > group.Score {
+			group.Score = beacon.Score
+		}
+		if beacon.Connections > topConns[i] {
+			topConns[i] = beacon.Connections
+			group.TopInterval = beacon.Ts.Mode
+		}
+	}
+
+	results := make([]CIDRResult, 0, len(groups))
+	for _, group := range groups {
+		if group.Connections > 0 {
+			conns := float64(group.Connections)
+			group.TsScore /= conns
+			group.DsScore /= conns
+			group.MeanScore /= conns
+			group.AvgBytes = float64(group.TotalBytes) / conns
+		}
+		results = append(results, *group)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
@@ -0,0 +1,75 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCIDRBeacon(src, dst string, conns, totalBytes, mode int64, tsScore, score float64) Result {
+	return Result{
+		UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: src}, data.UniqueIP{IP: dst}),
+		Connections:  conns,
+		TotalBytes:   totalBytes,
+		Ts:           TSData{Score: tsScore, Mode: mode},
+		Ds:           DSData{Score: tsScore},
+		Score:        score,
+	}
+}
+
+func TestAggregateByCIDR(t *testing.T) {
+	beacons := []Result{
+		newTestCIDRBeacon("10.0.0.1", "52.10.4.7", 100, 1000, 60, 0.9, 0.8),
+		newTestCIDRBeacon("10.0.0.1", "52.10.4.200", 300, 3000, 300, 0.5, 0.6),
+		newTestCIDRBeacon("10.0.0.1", "52.10.5.1", 50, 500, 30, 0.7, 0.7),
+		newTestCIDRBeacon("10.0.0.2", "52.10.4.7", 40, 400, 10, 0.4, 0.9),
+		newTestCIDRBeacon("10.0.0.1", "2001:db8::1", 20, 200, 5, 0.3, 0.3),
+	}
+
+	results, err := AggregateByCIDR(beacons, 24)
+	require.Nil(t, err)
+	require.Len(t, results, 4)
+
+	// sorted by the highest score in each group
+	assert.Equal(t, "10.0.0.2", results[0].SrcIP)
+	assert.Equal(t, "52.10.4.0/24", results[0].DstCIDR)
+	assert.Equal(t, 1, results[0].DstCount)
+
+	// the two destinations in 52.10.4.0/24 collapse into one group
+	group := results[1]
+	assert.Equal(t, "10.0.0.1", group.SrcIP)
+	assert.Equal(t, "52.10.4.0/24", group.DstCIDR)
+	assert.Equal(t, 2, group.DstCount)
+	assert.Equal(t, int64(400), group.Connections)
+	assert.Equal(t, int64(4000), group.TotalBytes)
+	assert.InDelta(t, 10.0, group.AvgBytes, 0.0001)
+	assert.InDelta(t, (0.9*100+0.5*300)/400, group.TsScore, 0.0001)
+	assert.InDelta(t, (0.8*100+0.6*300)/400, group.MeanScore, 0.0001)
+	assert.Equal(t, 0.8, group.Score)
+	// the top interval comes from the destination with the most connections
+	assert.Equal(t, int64(300), group.TopInterval)
+
+	// the adjacent /24 is kept separate
+	assert.Equal(t, "52.10.5.0/24", results[2].DstCIDR)
+	assert.Equal(t, 1, results[2].DstCount)
+
+	// IPv6 destinations are not aggregated
+	assert.Equal(t, "2001:db8::1/128", results[3].DstCIDR)
+
+	// a shorter prefix merges the adjacent /24s
+	results, err = AggregateByCIDR(beacons, 16)
+	require.Nil(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, "52.10.0.0/16", results[1].DstCIDR)
+	assert.Equal(t, 3, results[1].DstCount)
+	assert.Equal(t, int64(450), results[1].Connections)
+}
+
+func TestAggregateByCIDRInvalidPrefix(t *testing.T) {
+	_, err := AggregateByCIDR(nil, 33)
+	assert.NotNil(t, err)
+	_, err = AggregateByCIDR(nil, -1)
+	assert.NotNil(t, err)
+}
@@ -1,12 +1,17 @@
 package beacon
 
 import (
+	"context"
+	"fmt"
 	"math"
 	"sort"
 	"sync"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/cloud"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/scorer"
 	"github.com/activecm/rita/pkg/uconn"
 	"github.com/activecm/rita/util"
 
@@ -14,259 +19,698 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// analyzerErrorBuffer is the number of errors an analyzer holds for its reader.
+// Errors reported while the buffer is full are only logged.
+const analyzerErrorBuffer = 100
+
 type (
 	//analyzer handles calculating statistical measures of the distributions of the
 	//timestamps and data sizes between pairs of hosts
 	analyzer struct {
-		tsMin            int64                      // min timestamp for the whole dataset
-		tsMax            int64                      // max timestamp for the whole dataset
-		chunk            int                        // current chunk (0 if not on rolling analysis)
-		db               *database.DB               // provides access to MongoDB
-		conf             *config.Config             // contains details needed to access MongoDB
-		log              *log.Logger                // main logger for RITA
-		analyzedCallback func(database.BulkChanges) // analysis results are sent to this callback as MongoDB bulk actions
-		closedCallback   func()                     // called when .close() is called and no more calls to analyzedCallback will be made
-		analysisChannel  chan *uconn.Input          // holds unanalyzed unique connection data
-		analysisWg       sync.WaitGroup             // wait for analysis to finish
+		ctx              context.Context                   // stops the analysis early when cancelled
+		tsMin            int64                             // min timestamp for the whole dataset
+		tsMax            int64                             // max timestamp for the whole dataset
+		chunk            int                               // current chunk (0 if not on rolling analysis)
+		db               *database.DB                      // provides access to MongoDB
+		conf             *config.Config                    // contains details needed to access MongoDB
+		log              *log.Logger                       // main logger for RITA
+		blacklisted      func(data.UniqueIP) (bool, error) // reports whether a host is blacklisted (nil skips the blacklist checks)
+		cloudRanges      *cloud.Ranges                     // cloud provider ranges for annotating destinations (nil skips the annotation)
+		scorer           scorer.Scorer                     // external scorer which adds to the built-in scores (nil uses the built-in scores only)
+		heartbeat        *heartbeatClassifier              // labels likely application heartbeats (nil skips the classification)
+		knownPeriodic    *knownPeriodicClassifier          // recognizes beacons to periodic services (nil skips the classification)
+		analyzedCallback func(database.BulkChanges)        // analysis results are sent to this callback as MongoDB bulk actions
+		closedCallback   func()                            // called when .close() is called and no more calls to analyzedCallback will be made
+		analysisChannel  chan *uconn.Input                 // holds unanalyzed unique connection data
+		analysisWg       sync.WaitGroup                    // wait for analysis to finish
+		summary          *analysisSummary                  // outcome of the analysis which is logged by .close()
+		errs             chan error                        // recoverable analysis errors, closed by .close()
 	}
 )
 
-// newAnalyzer creates a new analyzer for calculating the beacon statistics of unique connections
-func newAnalyzer(min int64, max int64, chunk int, db *database.DB, conf *config.Config, log *log.Logger,
+// newAnalyzer creates a new analyzer for calculating the beacon statistics of unique connections.
+// Once ctx is cancelled, the analyzer stops scoring and drops the unique connections it is given.
+func newAnalyzer(ctx context.Context, min int64, max int64, chunk int, db *database.DB, conf *config.Config, log *log.Logger,
+	blacklisted func(data.UniqueIP) (bool, error), cloudRanges *cloud.Ranges, extScorer scorer.Scorer,
 	analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
+	var heartbeat *heartbeatClassifier
+	if conf.S.Beacon.Heartbeat.Enabled {
+		heartbeat = newHeartbeatClassifier(conf.S.Beacon.Heartbeat)
+	}
+
+	var knownPeriodic *knownPeriodicClassifier
+	if len(conf.S.Beacon.KnownPeriodic.Services) > 0 {
+		knownPeriodic = newKnownPeriodicClassifier(conf.S.Beacon.KnownPeriodic)
+	}
+
 	return &analyzer{
+		ctx:              ctx,
 		tsMin:            min,
 		tsMax:            max,
 		chunk:            chunk,
 		db:               db,
 		conf:             conf,
 		log:              log,
+		blacklisted:      blacklisted,
+		cloudRanges:      cloudRanges,
+		scorer:           extScorer,
+		heartbeat:        heartbeat,
+		knownPeriodic:    knownPeriodic,
 		analyzedCallback: analyzedCallback,
 		closedCallback:   closedCallback,
 		analysisChannel:  make(chan *uconn.Input),
+		summary:          newAnalysisSummary(),
+		errs:             make(chan error, analyzerErrorBuffer),
 	}
 }
 
-// collect gathers sorted unique connection data for analysis
+// collect gathers sorted unique connection data for analysis. The data is
+// dropped if the analysis was cancelled so the caller doesn't block forever.
 func (a *analyzer) collect(data *uconn.Input) {
-	a.analysisChannel <- data
+	select {
+	case a.analysisChannel <- data:
+	case <-a.ctx.Done():
+		a.summary.addSkipped()
+	}
+}
+
+// next returns the next unique connection to analyze. It returns false once
+// the analysis channel is closed or the analysis was cancelled.
+func (a *analyzer) next() (*uconn.Input, bool) {
+	// prefer stopping over analyzing when both are possible
+	if a.ctx.Err() != nil {
+		return nil, false
+	}
+
+	select {
+	case res, ok := <-a.analysisChannel:
+		return res, ok
+	case <-a.ctx.Done():
+		return nil, false
+	}
+}
+
+// errors returns the recoverable errors found during the analysis, such as
+// malformed unique connections, failures in analyzedCallback, or the failed
+// writes handed to reportError by the writer. The affected unique connections
+// are skipped. The channel is closed by .close() once the analysis finishes.
+func (a *analyzer) errors() <-chan error {
+	return a.errs
+}
+
+// reportError hands an error to the reader of .errors() without blocking
+// the analysis if the reader falls behind
+func (a *analyzer) reportError(err error) {
+	select {
+	case a.errs <- err:
+	default:
+	}
 }
 
-// close waits for the analyzer to finish
+// close waits for the analyzer to finish and logs a summary of the analysis.
+// The errors channel is closed after closedCallback returns, so the writer
+// receiving the results may still report its failures through the analyzer.
 func (a *analyzer) close() {
 	close(a.analysisChannel)
 	a.analysisWg.Wait()
+	if a.log != nil {
+		fields := a.summary.fields()
+		fields["Module"] = "beacon"
+		fields["chunk"] = a.chunk
+		a.log.WithFields(fields).Info("Finished beacon analysis")
+	}
 	a.closedCallback()
+	close(a.errs)
 }
 
 // start kicks off a new analysis thread
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
-		for res := range a.analysisChannel {
-
-			//store the diffFull slice length since we use it a lot
-			//for timestamps this is one less then the data slice length
-			//since we are calculating the times in between readings
-			tsLength := len(res.TsList) - 1
-			dsLength := len(res.OrigBytesList)
-
-			//find the delta times between the timestamps and sort
-			diffFull := make([]int64, tsLength)
-			for i := 0; i < tsLength; i++ {
-				interval := res.TsList[i+1] - res.TsList[i]
-				diffFull[i] = interval
-			}
-			sort.Sort(util.SortableInt64(diffFull))
-
-			// We are excluding delta zero for scoring calculations
-			// but using a separate array that includes it for making
-			// the user/ graph reference variables returned by createCountMap.
-
-			// Search for the section of diffFull without any 0's in it
-			// The dissector guarantees that there are at least three unique timestamps in res.TsList
-			// as a result, we are guaranteed to find at least two non-zero intervals in diffFull
-			diffNonZeroIdx := 0
-			for i := 0; i < len(diffFull); i++ {
-				if diffFull[i] > 0 {
-					diffNonZeroIdx = i
-					break
-				}
-			}
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
 
-			diff := diffFull[diffNonZeroIdx:] // select the part of diffFull without any 0's
-
-			//store the diff slice length
-			diffLength := len(diff)
-
-			//perfect beacons should have symmetric delta time and size distributions
-			//Bowley's measure of skew is used to check symmetry
-			tsSkew := float64(0)
-			dsSkew := float64(0)
-
-			//diffLength-1 is used since diff is a zero based slice
-			tsLow := diff[util.Round(.25*float64(diffLength-1))]
-			tsMid := diff[util.Round(.5*float64(diffLength-1))]
-			tsHigh := diff[util.Round(.75*float64(diffLength-1))]
-			tsBowleyNum := tsLow + tsHigh - 2*tsMid
-			tsBowleyDen := tsHigh - tsLow
-
-			//we do the same for datasizes
-			dsLow := res.OrigBytesList[util.Round(.25*float64(dsLength-1))]
-			dsMid := res.OrigBytesList[util.Round(.5*float64(dsLength-1))]
-			dsHigh := res.OrigBytesList[util.Round(.75*float64(dsLength-1))]
-			dsBowleyNum := dsLow + dsHigh - 2*dsMid
-			dsBowleyDen := dsHigh - dsLow
-
-			//tsSkew should equal zero if the denominator equals zero
-			//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
-			if tsBowleyDen != 0 && tsMid != tsLow && tsMid != tsHigh {
-				tsSkew = float64(tsBowleyNum) / float64(tsBowleyDen)
+		for {
+			res, ok := a.next()
+			if !ok {
+				break
 			}
+			a.analyzeRecovered(res)
+		}
 
-			if dsBowleyDen != 0 && dsMid != dsLow && dsMid != dsHigh {
-				dsSkew = float64(dsBowleyNum) / float64(dsBowleyDen)
-			}
+		a.analysisWg.Done()
+	}()
+}
 
-			//perfect beacons should have very low dispersion around the
-			//median of their delta times
-			//Median Absolute Deviation About the Median
-			//is used to check dispersion
-			devs := make([]int64, diffLength)
-			for i := 0; i < diffLength; i++ {
-				devs[i] = util.Abs(diff[i] - tsMid)
+// analyzeRecovered analyzes a unique connection. A panic while analyzing it,
+// such as a failure in analyzedCallback, is reported as an error so the
+// remaining unique connections are still analyzed.
+func (a *analyzer) analyzeRecovered(res *uconn.Input) {
+	defer func() {
+		if r := recover(); r != nil {
+			err := fmt.Errorf("beacon analysis of %s to %s failed: %v", res.Hosts.SrcIP, res.Hosts.DstIP, r)
+			if a.log != nil {
+				a.log.WithFields(log.Fields{"Module": "beacon"}).Error(err)
 			}
+			a.summary.addSkipped()
+			a.reportError(err)
+		}
+	}()
+	a.analyze(res)
+}
 
-			dsDevs := make([]int64, dsLength)
-			for i := 0; i < dsLength; i++ {
-				dsDevs[i] = util.Abs(res.OrigBytesList[i] - dsMid)
-			}
+// analyze scores a unique connection and hands the results to analyzedCallback
+func (a *analyzer) analyze(res *uconn.Input) {
+	//store the diffFull slice length since we use it a lot
+	//for timestamps this is one less then the data slice length
+	//since we are calculating the times in between readings
+	tsLength := len(res.TsList) - 1
+
+	//select the byte series used for data size scoring and sort it
+	//to compute quantiles. The series must be selected before sorting
+	//since summing the series pairs up the bytes of each connection.
+	dsList := getDatasizeSeries(a.conf.S.Beacon.DsSeries, res.OrigBytesList, res.RespBytesList)
+
+	//the periodicity of the data sizes depends on the order of the
+	//connections, so it must be measured before sorting
+	var dsPeriod int
+	var dsPeriodicity float64
+	if a.conf.S.Beacon.DsPeriodicityEnabled {
+		dsPeriod, dsPeriodicity = getDsPeriodicityScore(dsList)
+	}
+	//the sizes are paired with the intervals which precede them, so the
+	//correlation must also be measured before sorting
+	durSizeCorrelation := IntervalSizeCorrelation(res.TsList, dsList)
+	sort.Sort(util.SortableInt64(dsList))
+	dsLength := len(dsList)
+
+	//pairs with too many intervals to score exactly are scored from
+	//estimates streamed from the timestamps, so their intervals are
+	//never collected or sorted
+	maxExactIntervals := a.conf.S.Beacon.MaxExactIntervals
+	approximate := maxExactIntervals > 0 && tsLength > maxExactIntervals
+
+	//find the delta times between the timestamps, including zero
+	//intervals. The trend and the external scorer need them in
+	//chronological order, so only an approximately scored pair which
+	//uses neither skips them.
+	var intervalSeries []int64
+	if !approximate || a.conf.S.Beacon.TrendEnabled || a.scorer != nil {
+		intervalSeries = make([]int64, tsLength)
+		for i := 0; i < tsLength; i++ {
+			intervalSeries[i] = res.TsList[i+1] - res.TsList[i]
+		}
+	}
 
-			sort.Sort(util.SortableInt64(devs))
-			sort.Sort(util.SortableInt64(dsDevs))
+	//a steadily shrinking or growing interval is measured before
+	//the intervals are sorted
+	var trend float64
+	if intervalSeries != nil {
+		trend = IntervalTrend(intervalSeries)
+	}
 
-			tsMadm := devs[util.Round(.5*float64(diffLength-1))]
-			dsMadm := dsDevs[util.Round(.5*float64(dsLength-1))]
+	//sort the intervals for the user/ graph reference variables
+	//returned by createCountMap. The external scorer keeps the
+	//chronological order.
+	var diffFull []int64
+	if !approximate {
+		diffFull = intervalSeries
+		if a.scorer != nil {
+			diffFull = make([]int64, tsLength)
+			copy(diffFull, intervalSeries)
+		}
+		sort.Sort(util.SortableInt64(diffFull))
+	}
 
-			//Store the range for human analysis
-			tsIntervalRange := diff[diffLength-1] - diff[0]
-			dsRange := res.OrigBytesList[dsLength-1] - res.OrigBytesList[0]
+	//score the regularity of the intervals between the timestamps.
+	//The dissector guarantees that there are at least three unique
+	//timestamps in res.TsList, so this should never fail.
+	scoreTimestamps := ScoreTimestampsWithMode
+	if approximate {
+		scoreTimestamps = ApproximateScoreTimestamps
+	}
+	ts, err := scoreTimestamps(res.TsList, res.ConnectionCount, a.tsMin, a.tsMax, a.conf.S.Beacon.ConnCountMode)
+	if err != nil {
+		if a.log != nil {
+			a.log.WithFields(log.Fields{
+				"Module": "beacon",
+				"src":    res.Hosts.SrcIP,
+				"dst":    res.Hosts.DstIP,
+			}).Error(err)
+		}
+		a.summary.addSkipped()
+		a.reportError(fmt.Errorf("could not score the beacon from %s to %s: %w", res.Hosts.SrcIP, res.Hosts.DstIP, err))
+		return
+	}
 
-			//get a list of the intervals found in the data,
-			//the number of times the interval was found,
-			//and the most occurring interval
-			intervals, intervalCounts, tsMode, tsModeCount := createCountMap(diffFull)
-			dsSizes, dsCounts, dsMode, dsModeCount := createCountMap(res.OrigBytesList)
+	//perfect beacons should have symmetric data size distributions
+	//Bowley's measure of skew is used to check symmetry
+	dsSkew := float64(0)
 
-			//more skewed distributions receive a lower score
-			//less skewed distributions receive a higher score
-			tsSkewScore := 1.0 - math.Abs(tsSkew) //smush tsSkew
-			dsSkewScore := 1.0 - math.Abs(dsSkew) //smush dsSkew
+	dsLow := dsList[util.Round(.25*float64(dsLength-1))]
+	dsMid := dsList[util.Round(.5*float64(dsLength-1))]
+	dsHigh := dsList[util.Round(.75*float64(dsLength-1))]
+	dsBowleyNum := dsLow + dsHigh - 2*dsMid
+	dsBowleyDen := dsHigh - dsLow
 
-			//lower dispersion is better
-			tsMadmScore := 1.0
-			if tsMid >= 1 {
-				tsMadmScore = 1.0 - float64(tsMadm)/float64(tsMid)
-			}
-			if tsMadmScore < 0 {
-				tsMadmScore = 0
-			}
+	//dsSkew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if dsBowleyDen != 0 && dsMid != dsLow && dsMid != dsHigh {
+		dsSkew = float64(dsBowleyNum) / float64(dsBowleyDen)
+	}
 
-			//lower dispersion is better
-			dsMadmScore := 0.0
-			if dsMid >= 1 {
-				dsMadmScore = 1.0 - float64(dsMadm)/float64(dsMid)
-			}
-			if dsMadmScore < 0 {
-				dsMadmScore = 0
-			}
+	//perfect beacons should have very low dispersion around the
+	//median of their data sizes
+	//Median Absolute Deviation About the Median
+	//is used to check dispersion
+	dsDevs := make([]int64, dsLength)
+	for i := 0; i < dsLength; i++ {
+		dsDevs[i] = util.Abs(dsList[i] - dsMid)
+	}
+	sort.Sort(util.SortableInt64(dsDevs))
+	dsMadm := dsDevs[util.Round(.5*float64(dsLength-1))]
+
+	//Store the range for human analysis
+	dsRange := dsList[dsLength-1] - dsList[0]
+
+	//get a list of the intervals found in the data,
+	//the number of times the interval was found,
+	//and the most occurring interval
+	var intervals, intervalCounts, intervalBuckets []int64
+	var tsMode, tsModeCount int64
+	maxIntervalBuckets := a.conf.S.Beacon.MaxIntervalBuckets
+	if approximate {
+		//the frequency table is skipped and the intervals are streamed
+		//from the timestamps straight into bins
+		tsMode, tsModeCount = streamIntervalMode(res.TsList, approxModeCandidates)
+		approxBuckets := defaultApproxIntervalBuckets
+		if maxIntervalBuckets > 0 {
+			approxBuckets = maxIntervalBuckets
+		}
+		intervalBuckets, intervalCounts = streamIntervalBuckets(res.TsList, approxBuckets,
+			a.conf.S.Beacon.IntervalBucketScale == "log")
+		intervals = []int64{}
+	} else {
+		intervals, intervalCounts, tsMode, tsModeCount = createCountMap(diffFull, a.conf.S.Beacon.JitterPercent)
+
+		//collapse the interval frequency table into bins if it holds
+		//too many intervals to store
+		if maxIntervalBuckets > 0 && len(intervals) > maxIntervalBuckets {
+			intervalBuckets, intervalCounts = bucketCountMap(intervals, intervalCounts, maxIntervalBuckets,
+				a.conf.S.Beacon.IntervalBucketScale == "log")
+			intervals = []int64{}
+		}
+	}
+	dsSizes, dsCounts, dsMode, dsModeCount := createCountMap(dsList, 0)
+
+	//otherwise only keep the most frequent intervals if asked to.
+	//The mode was already found among all of the intervals.
+	var intervalOtherCount int64
+	maxStoredIntervals := a.conf.S.Beacon.MaxStoredIntervals
+	if maxStoredIntervals > 0 && intervalBuckets == nil {
+		intervals, intervalCounts, intervalOtherCount = topCountMap(intervals, intervalCounts, maxStoredIntervals)
+	}
 
-			//smaller data sizes receive a higher score
-			dsSmallnessScore := 1.0 - float64(dsMode)/65535.0
-			if dsSmallnessScore < 0 {
-				dsSmallnessScore = 0
-			}
+	//more skewed distributions receive a lower score
+	//less skewed distributions receive a higher score
+	dsSkewScore := 1.0 - math.Abs(dsSkew) //smush dsSkew
 
-			// connection count scoring
-			tsConnDiv := (float64(a.tsMax) - float64(a.tsMin)) / 3600
-			tsConnCountScore := float64(res.ConnectionCount) / tsConnDiv
-			if tsConnCountScore > 1.0 {
-				tsConnCountScore = 1.0
-			}
+	//lower dispersion is better
+	dsMadmScore := 0.0
+	if dsMid >= 1 {
+		dsMadmScore = 1.0 - float64(dsMadm)/float64(dsMid)
+	}
+	if dsMadmScore < 0 {
+		dsMadmScore = 0
+	}
 
-			// calculate final ts and ds scores
-			tsScore := math.Ceil(((tsSkewScore+tsMadmScore+tsConnCountScore)/3.0)*1000) / 1000
-			dsScore := math.Ceil(((dsSkewScore+dsMadmScore+dsSmallnessScore)/3.0)*1000) / 1000
+	//smaller data sizes receive a higher score
+	dsSmallnessScore := getDsSmallnessScore(dsMode, a.conf.S.Beacon.SmallPayloadBytes)
 
-			// calculate duration score
-			duration := math.Ceil((float64(res.TsList[tsLength]-res.TsList[0])/(float64(a.tsMax)-float64(a.tsMin)))*1000) / 1000
-			if duration > 1.0 {
-				duration = 1.0
-			}
+	// calculate final ts and ds scores
+	tsScore := ts.Score
+	dsScore := math.Ceil(((dsSkewScore+dsMadmScore+dsSmallnessScore)/3.0)*1000) / 1000
 
-			// calculate histogram score
-			bucketDivs, freqList, freqCount, histScore := getTsHistogramScore(a.tsMin, a.tsMax, res.TsList)
-
-			// calculate overall beacon score
-			score := math.Ceil(((tsScore*a.conf.S.Beacon.TsWeight)+
-				(dsScore*a.conf.S.Beacon.DsWeight)+
-				(duration*a.conf.S.Beacon.DurWeight)+
-				(histScore*a.conf.S.Beacon.HistWeight))*1000) / 1000
-
-			// copy variables to be used by bulk callback to prevent capturing by reference
-			pairSelector := res.Hosts.BSONKey()
-			beaconQuery := bson.M{
-				"$set": bson.M{
-					"connection_count":   res.ConnectionCount,
-					"avg_bytes":          res.TotalBytes / res.ConnectionCount,
-					"total_bytes":        res.TotalBytes,
-					"ts.range":           tsIntervalRange,
-					"ts.mode":            tsMode,
-					"ts.mode_count":      tsModeCount,
-					"ts.intervals":       intervals,
-					"ts.interval_counts": intervalCounts,
-					"ts.dispersion":      tsMadm,
-					"ts.skew":            tsSkew,
-					"ts.conns_score":     tsConnCountScore,
-					"ts.score":           tsScore,
-					"ds.range":           dsRange,
-					"ds.mode":            dsMode,
-					"ds.mode_count":      dsModeCount,
-					"ds.sizes":           dsSizes,
-					"ds.counts":          dsCounts,
-					"ds.dispersion":      dsMadm,
-					"ds.skew":            dsSkew,
-					"ds.score":           dsScore,
-					"duration_score":     duration,
-					"bucket_divs":        bucketDivs,
-					"freq_list":          freqList,
-					"freq_count":         freqCount,
-					"hist_score":         histScore,
-					"score":              score,
-					"cid":                a.chunk,
-					"src_network_name":   res.Hosts.SrcNetworkName,
-					"dst_network_name":   res.Hosts.DstNetworkName,
-				},
-			}
+	// calculate duration score
+	// a dataset without a time range can't show persistence
+	duration := 0.0
+	if a.tsMax > a.tsMin {
+		duration = math.Ceil((float64(res.TsList[tsLength]-res.TsList[0])/(float64(a.tsMax)-float64(a.tsMin)))*1000) / 1000
+	}
+	if duration > 1.0 {
+		duration = 1.0
+	}
+
+	// calculate histogram score
+	bucketDivs, freqList, freqCount, histScore := getTsHistogramScore(a.tsMin, a.tsMax, res.TsList)
+
+	// calculate overall beacon score
+	var overall weightedMean
+	overall.add(tsScore, a.conf.S.Beacon.TsWeight)
+	overall.add(dsScore, a.conf.S.Beacon.DsWeight)
+	overall.add(duration, a.conf.S.Beacon.DurWeight)
+	overall.add(histScore, a.conf.S.Beacon.HistWeight)
+
+	// optionally fold in how consistent the connection durations are
+	var connDurSkew, connDurMadm, connDurScore float64
+	if a.conf.S.Beacon.ConnDurEnabled {
+		connDurSkew, connDurMadm, connDurScore = getConnDurationScore(res.DurationList)
+		overall.add(connDurScore, a.conf.S.Beacon.ConnDurWeight)
+	}
+
+	// optionally fold in the timing of the connections' last activity
+	var respTsMode, respTsMadm int64
+	var respTsSkew, respTsScore float64
+	if a.conf.S.Beacon.RespTsEnabled {
+		respTsMode, respTsSkew, respTsMadm, respTsScore = getRespTsScore(res.RespTsList, len(res.TsList), ts.ConnCountScore)
+		overall.add(respTsScore, a.conf.S.Beacon.RespTsWeight)
+	}
+
+	// optionally fold in how rhythmic the data sizes are
+	if a.conf.S.Beacon.DsPeriodicityEnabled {
+		overall.add(dsPeriodicity, a.conf.S.Beacon.DsPeriodicityWeight)
+	}
 
-			update := database.BulkChanges{
-				a.conf.T.Beacon.BeaconTable: []database.BulkChange{
-					{Selector: pairSelector, Update: beaconQuery, Upsert: true},
-				},
+	// optionally fold in how steadily the interval shrinks or grows
+	var trendScore float64
+	if a.conf.S.Beacon.TrendEnabled {
+		trendScore = math.Abs(trend)
+		overall.add(trendScore, a.conf.S.Beacon.TrendWeight)
+	}
+
+	// optionally fold in the score of the external scorer
+	var external *scorer.Response
+	if a.scorer != nil {
+		external = a.externalScore(res, intervalSeries, map[string]float64{
+			"ts":       tsScore,
+			"ds":       dsScore,
+			"duration": duration,
+			"hist":     histScore,
+			"score":    overall.value(),
+		})
+		if external != nil {
+			overall.add(external.Score, a.conf.S.Beacon.ExternalScorer.Weight)
+		}
+	}
+	weightedScore := overall.value()
+
+	// optionally flag pairs whose timing and sizes are distorted by
+	// retransmissions on a lossy link, lowering their score
+	var retransRatio float64
+	var retransFlagged bool
+	if a.conf.S.Beacon.RetransEnabled {
+		retransRatio = getRetransmissionRatio(res.OrigIPBytes, res.OrigPayloadBytes, res.OrigPkts)
+		retransFlagged = retransRatio >= a.conf.S.Beacon.RetransRatio
+		if retransFlagged {
+			weightedScore *= 1 - a.conf.S.Beacon.RetransPenalty
+		}
+	}
+
+	// optionally lower the score of beacons whose period the dataset
+	// only covers a few times, since their regularity is barely tested
+	var observedPeriods float64
+	var shortObservation bool
+	if a.conf.S.Beacon.MinObservedPeriods > 0 {
+		observedPeriods = getObservedPeriods(a.tsMin, a.tsMax, tsMode)
+		shortObservation = observedPeriods < a.conf.S.Beacon.MinObservedPeriods
+		if shortObservation {
+			weightedScore *= 1 - a.conf.S.Beacon.ShortObsPenalty
+		}
+	}
+
+	// lower the score of beacons to services which are periodic by
+	// design, such as NTP to the internal time servers
+	var knownPeriodic bool
+	if a.knownPeriodic != nil {
+		knownPeriodic = a.knownPeriodic.knownPeriodic(res.Hosts.DstIP, res.Tuples.Items())
+		if knownPeriodic {
+			weightedScore *= 1 - a.conf.S.Beacon.KnownPeriodic.Penalty
+		}
+	}
+
+	score := math.Ceil(weightedScore*1000) / 1000
+
+	// rate how much the timing measurements can be trusted
+	confidence := getConfidence(ts.IntervalSampleSize, res.ConnectionCount, a.conf.S.Beacon.MinIntervalSamples)
+
+	// copy variables to be used by bulk callback to prevent capturing by reference
+	pairSelector := getPairSelector(a.conf, res.Hosts)
+	beaconQuery := bson.M{
+		"$set": bson.M{
+			"connection_count":        res.ConnectionCount,
+			"avg_bytes":               res.TotalBytes / res.ConnectionCount,
+			"total_bytes":             res.TotalBytes,
+			"ts.range":                ts.Range,
+			"ts.mode":                 tsMode,
+			"ts.mode_count":           tsModeCount,
+			"ts.intervals":            intervals,
+			"ts.interval_counts":      intervalCounts,
+			"ts.dispersion":           ts.Dispersion,
+			"ts.skew":                 ts.Skew,
+			"ts.conns_score":          ts.ConnCountScore,
+			"ts.conns_mode":           ts.ConnCountMode,
+			"ts.interval_sample_size": ts.IntervalSampleSize,
+			"ts.score":                tsScore,
+			"ds.range":                dsRange,
+			"ds.mode":                 dsMode,
+			"ds.mode_count":           dsModeCount,
+			"ds.sizes":                dsSizes,
+			"ds.counts":               dsCounts,
+			"ds.dispersion":           dsMadm,
+			"ds.skew":                 dsSkew,
+			"ds.score":                dsScore,
+			"dur_size.correlation":    durSizeCorrelation,
+			"duration_score":          duration,
+			"bucket_divs":             bucketDivs,
+			"freq_list":               freqList,
+			"freq_count":              freqCount,
+			"hist_score":              histScore,
+			"score":                   score,
+			"confidence":              confidence,
+			"uids":                    util.SampleStrings(res.UIDs, a.conf.S.Beacon.UIDSampleSize),
+			"cid":                     a.chunk,
+			"src_network_name":        res.Hosts.SrcNetworkName,
+			"dst_network_name":        res.Hosts.DstNetworkName,
+		},
+	}
+
+	update := database.BulkChanges{
+		a.conf.T.Beacon.BeaconTable: []database.BulkChange{
+			{Selector: pairSelector, Update: beaconQuery, Upsert: true},
+		},
+	}
+
+	// beacons below the BlacklistMinScore are not checked, so a stale result
+	// from an earlier import is removed rather than reported as clean
+	if a.blacklisted != nil && score >= a.conf.S.Beacon.BlacklistMinScore {
+		beaconQuery["$set"].(bson.M)["blacklisted"] = a.blacklistedBeacon(res.Hosts)
+	} else if a.blacklisted != nil {
+		unsetField(beaconQuery, "blacklisted")
+	}
+
+	if a.cloudRanges != nil {
+		beaconQuery["$set"].(bson.M)["cloud_provider"] = a.cloudRanges.Provider(res.Hosts.DstIP)
+	}
+
+	// bins stored by an earlier import are removed once the exact
+	// intervals fit again
+	if intervalBuckets != nil {
+		beaconQuery["$set"].(bson.M)["ts.interval_buckets"] = intervalBuckets
+	} else if maxIntervalBuckets > 0 {
+		unsetField(beaconQuery, "ts.interval_buckets")
+	}
+
+	// the count stored by an earlier import is removed once every interval
+	// is stored again
+	if maxStoredIntervals > 0 {
+		beaconQuery["$set"].(bson.M)["ts.interval_other_count"] = intervalOtherCount
+	} else {
+		unsetField(beaconQuery, "ts.interval_other_count")
+	}
+
+	// the flag stored by an earlier import is removed once every pair is
+	// scored exactly again
+	if maxExactIntervals > 0 {
+		beaconQuery["$set"].(bson.M)["ts.approximate"] = approximate
+	} else {
+		unsetField(beaconQuery, "ts.approximate")
+	}
+
+	// the trend isn't measured on approximately scored pairs unless it's
+	// scored or handed to the external scorer
+	if intervalSeries != nil {
+		beaconQuery["$set"].(bson.M)["ts.trend"] = trend
+	} else {
+		unsetField(beaconQuery, "ts.trend")
+	}
+
+	if a.heartbeat != nil {
+		beaconQuery["$set"].(bson.M)["likely_heartbeat"] = a.heartbeat.likelyHeartbeat(
+			res.Hosts.DstIP, res.Tuples.Items(), res.TotalBytes/res.ConnectionCount, dsScore, score,
+		)
+	}
+
+	if a.conf.S.Beacon.ConnDurEnabled {
+		beaconQuery["$set"].(bson.M)["conn_dur.skew"] = connDurSkew
+		beaconQuery["$set"].(bson.M)["conn_dur.dispersion"] = connDurMadm
+		beaconQuery["$set"].(bson.M)["conn_dur.score"] = connDurScore
+	}
+
+	if a.conf.S.Beacon.RespTsEnabled {
+		beaconQuery["$set"].(bson.M)["resp_ts.mode"] = respTsMode
+		beaconQuery["$set"].(bson.M)["resp_ts.skew"] = respTsSkew
+		beaconQuery["$set"].(bson.M)["resp_ts.dispersion"] = respTsMadm
+		beaconQuery["$set"].(bson.M)["resp_ts.score"] = respTsScore
+	}
+
+	if external != nil {
+		for field, value := range external.Fields {
+			beaconQuery["$set"].(bson.M)["external."+field] = value
+		}
+		beaconQuery["$set"].(bson.M)["external.score"] = external.Score
+	}
+
+	if a.conf.S.Beacon.RetransEnabled {
+		beaconQuery["$set"].(bson.M)["retrans.ratio"] = retransRatio
+		beaconQuery["$set"].(bson.M)["retrans.flagged"] = retransFlagged
+	}
+
+	if a.conf.S.Beacon.TrendEnabled {
+		beaconQuery["$set"].(bson.M)["ts.trend_score"] = trendScore
+	}
+
+	if a.conf.S.Beacon.MinObservedPeriods > 0 {
+		beaconQuery["$set"].(bson.M)["observed_periods"] = observedPeriods
+		beaconQuery["$set"].(bson.M)["short_observation"] = shortObservation
+	}
+
+	if a.knownPeriodic != nil {
+		beaconQuery["$set"].(bson.M)["known_periodic"] = knownPeriodic
+	}
+
+	if a.conf.S.Beacon.DsPeriodicityEnabled {
+		beaconQuery["$set"].(bson.M)["ds.period"] = dsPeriod
+		beaconQuery["$set"].(bson.M)["ds.periodicity"] = dsPeriodicity
+	}
+
+	// the pair fields are not part of a hashed selector so they must
+	// be written out when the beacon is created
+	if a.conf.S.Beacon.HashPairKeys {
+		beaconQuery["$setOnInsert"] = res.Hosts.BSONKey()
+	}
+
+	// keep the score of each chunk rather than only the latest score
+	if a.conf.S.Beacon.ScoreHistory {
+		beaconQuery["$push"] = bson.M{
+			"score_history": bson.M{"cid": a.chunk, "score": score},
+		}
+	}
+
+	a.analyzedCallback(update)
+	a.summary.addScore(score)
+}
+
+// blacklistedBeacon reports whether either host of a beacon is blacklisted.
+func (a *analyzer) blacklistedBeacon(hosts data.UniqueIPPair) bool {
+	for _, host := range []data.UniqueIP{hosts.UniqueSrcIP.Unpair(), hosts.UniqueDstIP.Unpair()} {
+		blacklisted, err := a.blacklisted(host)
+		if err != nil {
+			if a.log != nil {
+				a.log.WithFields(log.Fields{
+					"Module": "beacon",
+					"Data":   host,
+				}).Error(err)
 			}
+			continue
+		}
+		if blacklisted {
+			return true
+		}
+	}
+	return false
+}
 
-			a.analyzedCallback(update)
+// unsetField adds field to the $unset operator of the update query
+func unsetField(query bson.M, field string) {
+	if _, ok := query["$unset"]; !ok {
+		query["$unset"] = bson.M{}
+	}
+	query["$unset"].(bson.M)[field] = ""
+}
+
+// getPairSelector returns the selector used to find the beacon document of the
+// given pair. The pair's fixed size hash is used if HashPairKeys is enabled.
+func getPairSelector(conf *config.Config, hosts data.UniqueIPPair) bson.M {
+	if conf.S.Beacon.HashPairKeys {
+		return hosts.BSONHashKey()
+	}
+	return hosts.BSONKey()
+}
+
+// externalScore sends the series of a beacon and its built-in scores to the
+// external scorer. The returned score is limited to between 0 and 1. Returns
+// nil if the beacon could not be scored, in which case only the built-in
+// scores are used.
+func (a *analyzer) externalScore(res *uconn.Input, intervals []int64, scores map[string]float64) *scorer.Response {
+	resp, err := a.scorer.Score(scorer.Request{
+		Src:            res.Hosts.SrcIP,
+		SrcNetworkName: res.Hosts.SrcNetworkName,
+		Dst:            res.Hosts.DstIP,
+		DstNetworkName: res.Hosts.DstNetworkName,
+		Timestamps:     res.TsList,
+		Intervals:      intervals,
+		OrigBytes:      res.OrigBytesList,
+		RespBytes:      res.RespBytesList,
+		Scores:         scores,
+	})
+	if err != nil {
+		// a stopped scorer was already reported when it failed
+		if err != scorer.ErrClosed && a.log != nil {
+			a.log.WithFields(log.Fields{
+				"Module": "beacon",
+				"src":    res.Hosts.SrcIP,
+				"dst":    res.Hosts.DstIP,
+			}).Error(err)
 		}
+		return nil
+	}
+	resp.Score = math.Max(0, math.Min(resp.Score, 1))
+	return &resp
+}
 
-		a.analysisWg.Done()
-	}()
+// getDatasizeSeries returns a new slice holding the byte series selected for
+// data size scoring. "orig" selects the bytes sent by the source, "resp" the
+// bytes sent by the destination, and "sum" the total bytes of each connection.
+// The originator series is used if the responder series is incomplete, such
+// as when the connections were imported by an older version of RITA.
+func getDatasizeSeries(series string, origBytes []int64, respBytes []int64) []int64 {
+	dsList := make([]int64, len(origBytes))
+	if len(respBytes) != len(origBytes) {
+		series = "orig"
+	}
+
+	switch series {
+	case "resp":
+		copy(dsList, respBytes)
+	case "sum":
+		for i := range origBytes {
+			dsList[i] = origBytes[i] + respBytes[i]
+		}
+	default:
+		copy(dsList, origBytes)
+	}
+	return dsList
 }
 
 // createCountMap returns a distinct data array, data count array, the mode,
-// and the number of times the mode occurred
-func createCountMap(sortedIn []int64) ([]int64, []int64, int64, int64) {
+// and the number of times the mode occurred. If jitterPercent is positive,
+// values within jitterPercent percent of each other are counted together.
+func createCountMap(sortedIn []int64, jitterPercent float64) ([]int64, []int64, int64, int64) {
 	//Since the data is already sorted, we can call this without fear
 	distinct, countsMap := countAndRemoveConsecutiveDuplicates(sortedIn)
+	if jitterPercent > 0 {
+		distinct, countsMap = clusterByJitter(distinct, countsMap, jitterPercent)
+	}
 	countsArr := make([]int64, len(distinct))
 	mode := distinct[0]
 	max := countsMap[mode]
@@ -281,6 +725,166 @@ func createCountMap(sortedIn []int64) ([]int64, []int64, int64, int64) {
 	return distinct, countsArr, mode, max
 }
 
+// topCountMap orders the distinct values and counts returned by createCountMap
+// from the most to the least frequent, breaking ties with the smaller value, and
+// keeps at most maxValues of them. Returns the kept values, their counts, and the
+// summed count of the values which were left out.
+func topCountMap(distinct []int64, counts []int64, maxValues int) ([]int64, []int64, int64) {
+	order := make([]int, len(distinct))
+	for i := range order {
+		order[i] = i
+	}
+	//the distinct values are sorted, so a stable sort breaks ties with the smaller value
+	sort.SliceStable(order, func(i, j int) bool {
+		return counts[order[i]] > counts[order[j]]
+	})
+
+	kept := len(order)
+	if kept > maxValues {
+		kept = maxValues
+	}
+
+	topValues := make([]int64, kept)
+	topCounts := make([]int64, kept)
+	for i := 0; i < kept; i++ {
+		topValues[i] = distinct[order[i]]
+		topCounts[i] = counts[order[i]]
+	}
+
+	var other int64
+	for _, i := range order[kept:] {
+		other += counts[i]
+	}
+	return topValues, topCounts, other
+}
+
+// bucketCountMap collapses the distinct values and counts returned by
+// createCountMap into at most maxBuckets bins spanning the smallest to the
+// largest value. The bins are evenly spaced unless logScale is set, in which
+// case they grow with the values. Returns the edges of the bins and the count
+// of each bin. Bin i holds the values from edges[i] up to but not including
+// edges[i+1], except for the last bin, which also holds the largest value.
+// Fewer bins are returned if the rounded edges of neighboring bins coincide.
+func bucketCountMap(distinct []int64, counts []int64, maxBuckets int, logScale bool) ([]int64, []int64) {
+	edges := bucketEdges(distinct[0], distinct[len(distinct)-1], maxBuckets, logScale)
+
+	bucketCounts := make([]int64, len(edges)-1)
+	for i, datum := range distinct {
+		bucket := sort.Search(len(edges), func(j int) bool { return edges[j] > datum }) - 1
+		if bucket >= len(bucketCounts) {
+			bucket = len(bucketCounts) - 1
+		}
+		bucketCounts[bucket] += counts[i]
+	}
+	return edges, bucketCounts
+}
+
+// defaultApproxIntervalBuckets is the number of bins the intervals of an
+// approximately scored beacon are counted into if MaxIntervalBuckets isn't set
+const defaultApproxIntervalBuckets = 64
+
+// streamIntervalBuckets counts the intervals between the sorted timestamps
+// into bins like bucketCountMap without collecting or sorting them first
+func streamIntervalBuckets(tsList []int64, maxBuckets int, logScale bool) ([]int64, []int64) {
+	shortest, longest := int64(math.MaxInt64), int64(0)
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if interval < shortest {
+			shortest = interval
+		}
+		if interval > longest {
+			longest = interval
+		}
+	}
+	edges := bucketEdges(shortest, longest, maxBuckets, logScale)
+
+	bucketCounts := make([]int64, len(edges)-1)
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		bucket := sort.Search(len(edges), func(j int) bool { return edges[j] > interval }) - 1
+		if bucket >= len(bucketCounts) {
+			bucket = len(bucketCounts) - 1
+		}
+		bucketCounts[bucket]++
+	}
+	return edges, bucketCounts
+}
+
+// bucketEdges returns the edges of at most maxBuckets bins spanning min to max.
+// The bins are evenly spaced unless logScale is set. Edges which round to the
+// same value are merged.
+func bucketEdges(min int64, max int64, maxBuckets int, logScale bool) []int64 {
+	//shift the values by one for the log scale so intervals of 0 can be binned
+	logMin := math.Log(float64(min) + 1)
+	logMax := math.Log(float64(max) + 1)
+
+	edges := make([]int64, 0, maxBuckets+1)
+	edges = append(edges, min)
+	for i := 1; i < maxBuckets; i++ {
+		var edge int64
+		if logScale {
+			edge = int64(math.Round(math.Exp(logMin+(logMax-logMin)*float64(i)/float64(maxBuckets)) - 1))
+		} else {
+			edge = min + int64(math.Round(float64(max-min)*float64(i)/float64(maxBuckets)))
+		}
+		if edge > edges[len(edges)-1] && edge < max {
+			edges = append(edges, edge)
+		}
+	}
+	edges = append(edges, max)
+	return edges
+}
+
+// approxModeCandidates is the number of intervals tracked while streaming the
+// mode of an approximately scored beacon
+const approxModeCandidates = 64
+
+// streamIntervalMode returns the most occurring interval between the sorted
+// timestamps and the number of times it occurred, preferring the shorter
+// interval like createCountMap, without counting every distinct interval. At
+// most candidates intervals are tracked by a Misra-Gries summary, and the
+// survivors are then counted exactly. The mode is exact whenever it makes up
+// more than 1/(candidates+1) of the intervals; otherwise the most occurring
+// survivor is returned.
+func streamIntervalMode(tsList []int64, candidates int) (int64, int64) {
+	counts := make(map[int64]int64, candidates)
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if _, ok := counts[interval]; ok || len(counts) < candidates {
+			counts[interval]++
+			continue
+		}
+		for candidate := range counts {
+			counts[candidate]--
+			if counts[candidate] == 0 {
+				delete(counts, candidate)
+			}
+		}
+	}
+
+	//the summary may run dry if no interval stands out
+	if len(counts) == 0 {
+		counts[tsList[1]-tsList[0]] = 0
+	}
+	for candidate := range counts {
+		counts[candidate] = 0
+	}
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if _, ok := counts[interval]; ok {
+			counts[interval]++
+		}
+	}
+
+	mode, max := int64(0), int64(-1)
+	for candidate, count := range counts {
+		if count > max || (count == max && candidate < mode) {
+			mode, max = candidate, count
+		}
+	}
+	return mode, max
+}
+
 // countAndRemoveConsecutiveDuplicates removes consecutive
 // duplicates in an array of integers and counts how many
 // instances of each number exist in the array.
@@ -305,6 +909,31 @@ func countAndRemoveConsecutiveDuplicates(numberList []int64) ([]int64, map[int64
 	return result, counts
 }
 
+// clusterByJitter groups the sorted distinct values into clusters whose
+// values are within jitterPercent percent of the smallest value of the
+// cluster. Each cluster is represented by its most common value, or its
+// smallest value if there is a tie, and counts all of its values. Measuring
+// the distance relative to the values lets long intervals vary by more
+// seconds than short intervals.
+func clusterByJitter(distinct []int64, counts map[int64]int64, jitterPercent float64) ([]int64, map[int64]int64) {
+	clustered := make([]int64, 0, len(distinct))
+	clusteredCounts := make(map[int64]int64)
+
+	for i := 0; i < len(distinct); {
+		limit := float64(distinct[i]) * (1 + jitterPercent/100)
+		common, total := distinct[i], int64(0)
+		for ; i < len(distinct) && float64(distinct[i]) <= limit; i++ {
+			if counts[distinct[i]] > counts[common] {
+				common = distinct[i]
+			}
+			total += counts[distinct[i]]
+		}
+		clustered = append(clustered, common)
+		clusteredCounts[common] = total
+	}
+	return clustered, clusteredCounts
+}
+
 // getTsHistogramScore calculates two potential scores based on the histogram of connections for the
 // host pair and takes the max of the two scores.
 func getTsHistogramScore(min int64, max int64, tsList []int64) ([]int64, []int, map[int]int, float64) {
@@ -444,3 +1073,231 @@ func createHistogram(bucketDivs []int64, tsList []int64) ([]int, map[int]int, fl
 	return freqList, freqCount, cv, totalBars
 
 }
+
+// getConnDurationScore measures how consistent the durations of the connections
+// between a pair of hosts are using Bowley's measure of skew and the median
+// absolute deviation about the median, the same measures used for the timestamp
+// and data size scores. Returns a score of 0 if there are too few durations to measure.
+func getConnDurationScore(durations []float64) (skew float64, madm float64, score float64) {
+	length := len(durations)
+	if length < 3 {
+		return 0, 0, 0
+	}
+
+	sorted := make([]float64, length)
+	copy(sorted, durations)
+	sort.Float64s(sorted)
+
+	low := sorted[util.Round(.25*float64(length-1))]
+	mid := sorted[util.Round(.5*float64(length-1))]
+	high := sorted[util.Round(.75*float64(length-1))]
+
+	//skew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if high-low != 0 && mid != low && mid != high {
+		skew = (low + high - 2*mid) / (high - low)
+	}
+
+	devs := make([]float64, length)
+	for i := 0; i < length; i++ {
+		devs[i] = math.Abs(sorted[i] - mid)
+	}
+	sort.Float64s(devs)
+	madm = devs[util.Round(.5*float64(length-1))]
+
+	//more skewed distributions receive a lower score
+	skewScore := 1.0 - math.Abs(skew)
+
+	//lower dispersion is better. Durations which never vary are perfectly
+	//consistent, even if they are all zero.
+	madmScore := 1.0
+	if madm > 0 {
+		madmScore = 0.0
+		if mid > 0 {
+			madmScore = 1.0 - madm/mid
+		}
+		if madmScore < 0 {
+			madmScore = 0
+		}
+	}
+
+	score = math.Ceil(((skewScore+madmScore)/2.0)*1000) / 1000
+	return skew, madm, score
+}
+
+// getDsSmallnessScore scores how small the most common payload of a beacon
+// is, from 1 for empty payloads down to 0 for payloads of smallPayloadBytes
+// bytes or larger
+func getDsSmallnessScore(dsMode int64, smallPayloadBytes int64) float64 {
+	if smallPayloadBytes < 1 {
+		return 0
+	}
+	score := 1.0 - float64(dsMode)/float64(smallPayloadBytes)
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// packetHeaderBytes is the most IP and TCP header bytes expected of each
+// packet. It covers IPv6 headers and TCP headers carrying the usual options so
+// that only resent payloads count as retransmissions.
+const packetHeaderBytes = 80
+
+// getRetransmissionRatio estimates the fraction of the IP bytes sent by the
+// source which were retransmissions. Zeek measures the payload bytes from the
+// TCP sequence numbers, so any IP bytes beyond the payload and the packet
+// headers were resent. Returns 0 if no packet counts were recorded.
+func getRetransmissionRatio(ipBytes, payloadBytes, pkts int64) float64 {
+	if pkts < 1 || ipBytes < 1 {
+		return 0
+	}
+	resent := ipBytes - payloadBytes - pkts*packetHeaderBytes
+	if resent <= 0 {
+		return 0
+	}
+	ratio := float64(resent) / float64(ipBytes)
+	if ratio > 1 {
+		ratio = 1
+	}
+	return math.Ceil(ratio*1000) / 1000
+}
+
+// getObservedPeriods returns how many times the period of a beacon fits into
+// the time range of the dataset, rounded to two places. Returns 0 if the beacon
+// has no period or the dataset has no time range.
+func getObservedPeriods(tsMin, tsMax, period int64) float64 {
+	if period < 1 || tsMax <= tsMin {
+		return 0
+	}
+	return math.Floor(float64(tsMax-tsMin)/float64(period)*100) / 100
+}
+
+// periodicityTolerance absorbs rounding errors when comparing the
+// correlations of different shifts in getDsPeriodicityScore
+const periodicityTolerance = 1e-9
+
+// getDsPeriodicityScore measures how rhythmic the data sizes of the
+// connections are, in the order the connections were recorded, using the
+// autocorrelation of the sizes. Beacons which send a repeating pattern of
+// sizes correlate strongly with themselves when shifted by the length of the
+// pattern. Returns the shift with the strongest correlation, up to half the
+// number of sizes, along with the correlation as the score. Sizes which never
+// vary repeat with a period of 1 and a score of 1. Returns a score of 0 if
+// there are fewer than four sizes.
+func getDsPeriodicityScore(sizes []int64) (period int, score float64) {
+	length := len(sizes)
+	if length < 4 {
+		return 0, 0
+	}
+
+	mean := 0.0
+	for _, size := range sizes {
+		mean += float64(size)
+	}
+	mean /= float64(length)
+
+	variance := 0.0
+	for _, size := range sizes {
+		variance += (float64(size) - mean) * (float64(size) - mean)
+	}
+	if variance == 0 {
+		return 1, 1
+	}
+
+	for lag := 1; lag <= length/2; lag++ {
+		covariance := 0.0
+		for i := 0; i+lag < length; i++ {
+			covariance += (float64(sizes[i]) - mean) * (float64(sizes[i+lag]) - mean)
+		}
+		//scale up by the number of overlapping sizes so longer shifts
+		//aren't penalized for comparing fewer sizes
+		correlation := covariance / variance * float64(length) / float64(length-lag)
+		//multiples of the period correlate as well as the period itself,
+		//keep the shortest shift unless a longer one is clearly stronger
+		if correlation > score+periodicityTolerance {
+			period, score = lag, correlation
+		}
+	}
+
+	if score > 1 {
+		score = 1
+	}
+	score = math.Ceil(score*1000) / 1000
+	return period, score
+}
+
+// getRespTsScore measures how regular the intervals between the last activity
+// of each connection are, for servers which push data on a schedule. The
+// intervals are scored with the skew and dispersion measures used for the
+// connection timestamps along with the connection count score. The sorted
+// response timestamps must cover all connections. Returns a score of 0 if
+// they don't, such as for connections imported by older versions of RITA, or
+// if there are fewer than two non-zero intervals.
+func getRespTsScore(sortedRespTs []int64, connCount int, connCountScore float64) (mode int64, skew float64, madm int64, score float64) {
+	if len(sortedRespTs) != connCount || connCount < 3 {
+		return 0, 0, 0, 0
+	}
+
+	diff := make([]int64, 0, len(sortedRespTs)-1)
+	for i := 0; i < len(sortedRespTs)-1; i++ {
+		if interval := sortedRespTs[i+1] - sortedRespTs[i]; interval > 0 {
+			diff = append(diff, interval)
+		}
+	}
+	length := len(diff)
+	if length < 2 {
+		return 0, 0, 0, 0
+	}
+	sort.Sort(util.SortableInt64(diff))
+
+	low := diff[util.Round(.25*float64(length-1))]
+	mid := diff[util.Round(.5*float64(length-1))]
+	high := diff[util.Round(.75*float64(length-1))]
+
+	//skew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if high-low != 0 && mid != low && mid != high {
+		skew = float64(low+high-2*mid) / float64(high-low)
+	}
+
+	devs := make([]int64, length)
+	for i := 0; i < length; i++ {
+		devs[i] = util.Abs(diff[i] - mid)
+	}
+	sort.Sort(util.SortableInt64(devs))
+	madm = devs[util.Round(.5*float64(length-1))]
+
+	_, _, mode, _ = createCountMap(diff, 0)
+
+	//more skewed distributions receive a lower score
+	skewScore := 1.0 - math.Abs(skew)
+
+	//lower dispersion is better
+	madmScore := 1.0
+	if mid >= 1 {
+		madmScore = 1.0 - float64(madm)/float64(mid)
+	}
+	if madmScore < 0 {
+		madmScore = 0
+	}
+
+	score = math.Ceil(((skewScore+madmScore+connCountScore)/3.0)*1000) / 1000
+	return mode, skew, madm, score
+}
+
+// getConfidence rates how well the intervals between the connections of a beacon
+// were sampled, from 0 to 1. Beacons with fewer than minSamples non-zero intervals
+// are scaled down since a handful of matching intervals may be coincidental.
+// The result is also scaled by the fraction of connections which produced a
+// non-zero interval, since connections sharing a timestamp say nothing about timing.
+func getConfidence(intervalSamples int, connectionCount int64, minSamples int) float64 {
+	if intervalSamples <= 0 || connectionCount < 2 {
+		return 0
+	}
+
+	sampling := math.Min(float64(intervalSamples)/float64(minSamples), 1)
+	coverage := math.Min(float64(intervalSamples)/float64(connectionCount-1), 1)
+
+	return math.Ceil(sampling*coverage*1000) / 1000
+}
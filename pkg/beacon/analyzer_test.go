@@ -0,0 +1,1641 @@
+package beacon
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/cloud"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/scorer"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo/bson"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// analyzeTestInputs runs the given inputs through an analyzer and returns the
+// fields which would have been $set on each beacon document
+func analyzeTestInputs(t *testing.T, conf *config.Config, tsMin, tsMax int64, inputs ...*uconn.Input) []bson.M {
+	var results []bson.M
+	for _, update := range analyzeTestUpdates(t, conf, tsMin, tsMax, 0, inputs...) {
+		results = append(results, update["$set"].(bson.M))
+	}
+	return results
+}
+
+// analyzeTestUpdates runs the given inputs through an analyzer for the given
+// chunk and returns the update which would have been applied to each beacon document
+func analyzeTestUpdates(t *testing.T, conf *config.Config, tsMin, tsMax int64, chunk int, inputs ...*uconn.Input) []bson.M {
+	var results []bson.M
+	a := newAnalyzer(context.Background(), tsMin, tsMax, chunk, nil, conf, nil, nil, nil, nil,
+		func(changes database.BulkChanges) {
+			for _, change := range changes[conf.T.Beacon.BeaconTable] {
+				results = append(results, change.Update.(bson.M))
+			}
+		},
+		func() {},
+	)
+	a.start()
+	for _, input := range inputs {
+		a.collect(input)
+	}
+	a.close()
+	require.Len(t, results, len(inputs))
+	return results
+}
+
+// newTestBeaconInput creates a sorted uconn input with a connection every
+// interval seconds starting at tsMin
+func newTestBeaconInput(tsMin, interval int64, count int, origBytes, respBytes []int64) *uconn.Input {
+	input := &uconn.Input{
+		Hosts: data.NewUniqueIPPair(
+			data.UniqueIP{IP: "10.0.0.1"},
+			data.UniqueIP{IP: "8.8.8.8"},
+		),
+		ConnectionCount: int64(count),
+		OrigBytesList:   origBytes,
+		RespBytesList:   respBytes,
+	}
+	for i := 0; i < count; i++ {
+		input.TsList = append(input.TsList, tsMin+int64(i)*interval)
+		input.TotalBytes += origBytes[i] + respBytes[i]
+	}
+	input.UniqueTsListLength = int64(count)
+	return input
+}
+
+func TestGetDatasizeSeries(t *testing.T) {
+	orig := []int64{100, 100, 200}
+	resp := []int64{5000, 20, 300}
+
+	assert.Equal(t, []int64{100, 100, 200}, getDatasizeSeries("orig", orig, resp))
+	assert.Equal(t, []int64{5000, 20, 300}, getDatasizeSeries("resp", orig, resp))
+	assert.Equal(t, []int64{5100, 120, 500}, getDatasizeSeries("sum", orig, resp))
+
+	// an incomplete responder series falls back to the originator series
+	assert.Equal(t, []int64{100, 100, 200}, getDatasizeSeries("resp", orig, nil))
+	assert.Equal(t, []int64{100, 100, 200}, getDatasizeSeries("sum", orig, resp[:2]))
+
+	// the returned series must not alias the inputs since the analyzer sorts it
+	series := getDatasizeSeries("orig", orig, resp)
+	series[0] = 0
+	assert.Equal(t, int64(100), orig[0])
+}
+
+func TestAnalyzerDatasizeSeries(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// requests are a constant size while the responses vary wildly
+	count := 48
+	orig := make([]int64, count)
+	resp := make([]int64, count)
+	for i := 0; i < count; i++ {
+		orig[i] = 120
+		resp[i] = int64(1000 + (i%4)*7000)
+	}
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	cases := []struct {
+		series string
+		mode   int64
+		rng    int64
+	}{
+		{"orig", 120, 0},
+		{"resp", 1000, 21000},
+		{"sum", 1120, 21000},
+	}
+
+	var dsScores []float64
+	for _, c := range cases {
+		conf.S.Beacon.DsSeries = c.series
+		input := newTestBeaconInput(tsMin, 1800, count, orig, resp)
+		result := analyzeTestInputs(t, conf, tsMin, tsMax, input)[0]
+
+		assert.Equal(t, c.mode, result["ds.mode"], "ds.mode for series %s", c.series)
+		assert.Equal(t, c.rng, result["ds.range"], "ds.range for series %s", c.series)
+		dsScores = append(dsScores, result["ds.score"].(float64))
+	}
+
+	// the constant request sizes should score better than the noisy responses
+	assert.True(t, dsScores[0] > dsScores[1], "orig ds score should beat resp ds score")
+	assert.True(t, dsScores[0] > dsScores[2], "orig ds score should beat sum ds score")
+}
+
+func TestCreateCountMapJitterPercent(t *testing.T) {
+	// a five minute beacon whose intervals vary by about 1%, along with a
+	// few hour long gaps and a short retry
+	sorted := []int64{12, 298, 299, 300, 300, 300, 302, 303, 3570, 3600, 3620}
+
+	intervals, counts, mode, modeCount := createCountMap(sorted, 0)
+	assert.Equal(t, []int64{12, 298, 299, 300, 302, 303, 3570, 3600, 3620}, intervals)
+	assert.Equal(t, []int64{1, 1, 1, 3, 1, 1, 1, 1, 1}, counts)
+	assert.Equal(t, int64(300), mode)
+	assert.Equal(t, int64(3), modeCount)
+
+	// the five minute intervals count together and so do the hour long ones,
+	// even though the hour long ones are spread over more seconds
+	intervals, counts, mode, modeCount = createCountMap(sorted, 2)
+	assert.Equal(t, []int64{12, 300, 3570}, intervals)
+	assert.Equal(t, []int64{1, 7, 3}, counts)
+	assert.Equal(t, int64(300), mode)
+	assert.Equal(t, int64(7), modeCount)
+
+	// the distance is relative to the shortest interval of each group, so a
+	// chain of small steps doesn't join everything together
+	intervals, counts, _, _ = createCountMap([]int64{100, 101, 102, 103, 104, 105}, 2)
+	assert.Equal(t, []int64{100, 103}, intervals)
+	assert.Equal(t, []int64{3, 3}, counts)
+}
+
+func TestAnalyzerJitterPercent(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// a five minute beacon with up to 1% of jitter
+	count := 48
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	input := newTestBeaconInput(tsMin, 300, count, sizes, sizes)
+	for i := 1; i < count; i++ {
+		input.TsList[i] = input.TsList[i-1] + 300 + []int64{0, 0, 1, 2, 3}[i%5]
+	}
+
+	result := analyzeTestInputs(t, conf, tsMin, tsMax, input)[0]
+	assert.Equal(t, int64(19), result["ts.mode_count"], "each jittered interval is counted separately")
+
+	conf.S.Beacon.JitterPercent = 1
+	result = analyzeTestInputs(t, conf, tsMin, tsMax, input)[0]
+	assert.Equal(t, []int64{300}, result["ts.intervals"])
+	assert.Equal(t, int64(47), result["ts.mode_count"])
+}
+
+func TestBucketCountMap(t *testing.T) {
+	distinct := []int64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}
+	counts := []int64{1, 2, 1, 1, 1, 1, 1, 1, 1, 5}
+
+	// the largest value falls in the last bucket
+	edges, bucketCounts := bucketCountMap(distinct, counts, 3, false)
+	assert.Equal(t, []int64{0, 3, 6, 9}, edges)
+	assert.Equal(t, []int64{4, 3, 8}, bucketCounts)
+
+	// log scaled buckets widen with the intervals
+	distinct = []int64{0, 1, 3, 7, 15, 31, 63, 127, 255}
+	counts = []int64{1, 1, 1, 1, 1, 1, 1, 1, 1}
+	edges, bucketCounts = bucketCountMap(distinct, counts, 4, true)
+	assert.Equal(t, []int64{0, 3, 15, 63, 255}, edges)
+	assert.Equal(t, []int64{2, 2, 2, 3}, bucketCounts)
+
+	// edges which round to the same value are merged
+	edges, bucketCounts = bucketCountMap([]int64{0, 1, 2, 3, 4, 5}, []int64{1, 1, 1, 1, 1, 1}, 5, true)
+	assert.Equal(t, []int64{0, 1, 2, 3, 5}, edges)
+	assert.Equal(t, []int64{1, 1, 1, 3}, bucketCounts)
+}
+
+func TestAnalyzerMaxIntervalBuckets(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// a beacon whose 47 intervals are all distinct
+	count := 48
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	input := newTestBeaconInput(tsMin, 300, count, sizes, sizes)
+	for i := 1; i < count; i++ {
+		input.TsList[i] = input.TsList[i-1] + 300 + int64(i)
+	}
+
+	// the exact intervals are stored by default
+	update := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	assert.Len(t, update["$set"].(bson.M)["ts.intervals"], 47)
+	assert.NotContains(t, update["$set"], "ts.interval_buckets")
+	assert.NotContains(t, update["$unset"], "ts.interval_buckets")
+
+	// the exact intervals are kept while they are under the limit, and any
+	// buckets from an earlier import are removed
+	conf.S.Beacon.MaxIntervalBuckets = 47
+	update = analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	assert.Len(t, update["$set"].(bson.M)["ts.intervals"], 47)
+	assert.NotContains(t, update["$set"], "ts.interval_buckets")
+	assert.Contains(t, update["$unset"], "ts.interval_buckets")
+
+	// over the limit the intervals are counted into buckets
+	conf.S.Beacon.MaxIntervalBuckets = 4
+	conf.S.Beacon.IntervalBucketScale = "linear"
+	update = analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	result := update["$set"].(bson.M)
+	assert.Equal(t, []int64{}, result["ts.intervals"])
+	assert.Equal(t, []int64{301, 313, 324, 336, 347}, result["ts.interval_buckets"])
+	assert.Equal(t, []int64{12, 11, 12, 12}, result["ts.interval_counts"])
+	assert.NotContains(t, update["$unset"], "ts.interval_buckets")
+
+	// the statistics are still measured on the exact intervals
+	assert.Equal(t, int64(46), result["ts.range"])
+}
+
+func TestStreamIntervalBuckets(t *testing.T) {
+	// the bins match those of the frequency table of the sorted intervals
+	tsList := []int64{100, 100, 109, 110, 111, 113, 116, 120, 125, 131, 138, 146, 155, 164, 173}
+	sorted := make([]int64, len(tsList)-1)
+	for i := range sorted {
+		sorted[i] = tsList[i+1] - tsList[i]
+	}
+	sort.Sort(util.SortableInt64(sorted))
+	distinct, counts, _, _ := createCountMap(sorted, 0)
+	for _, logScale := range []bool{false, true} {
+		edges, bucketCounts := streamIntervalBuckets(tsList, 3, logScale)
+		expectedEdges, expectedCounts := bucketCountMap(distinct, counts, 3, logScale)
+		assert.Equal(t, expectedEdges, edges)
+		assert.Equal(t, expectedCounts, bucketCounts)
+	}
+}
+
+func TestStreamIntervalMode(t *testing.T) {
+	// ties go to the shorter interval like createCountMap
+	mode, count := streamIntervalMode([]int64{0, 1, 3, 5, 8, 11, 15}, 4)
+	assert.Equal(t, int64(2), mode)
+	assert.Equal(t, int64(2), count)
+
+	// a mode which stands out is found exactly among more distinct
+	// intervals than there are candidates
+	tsList := []int64{0}
+	for i := 1; i <= 1000; i++ {
+		interval := int64(300)
+		if i%3 != 0 {
+			interval = int64(500 + i)
+		}
+		tsList = append(tsList, tsList[i-1]+interval)
+	}
+	mode, count = streamIntervalMode(tsList, 4)
+	assert.Equal(t, int64(300), mode)
+	assert.Equal(t, int64(333), count)
+
+	// the exact count of a survivor is returned when no interval stands out
+	mode, count = streamIntervalMode([]int64{0, 1, 3, 6, 10, 15}, 2)
+	assert.Equal(t, int64(1), count)
+	assert.Contains(t, []int64{1, 2, 3, 4, 5}, mode)
+}
+
+func TestAnalyzerMaxExactIntervals(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// a five minute beacon which mostly checks in on time, with up to a
+	// minute of jitter either way otherwise
+	count := 288
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	input := newTestBeaconInput(tsMin, 300, count, sizes, sizes)
+	for i := 1; i < count; i++ {
+		interval := int64(300)
+		if i%2 == 0 {
+			interval = 240 + int64(i*37%121)
+		}
+		input.TsList[i] = input.TsList[i-1] + interval
+	}
+
+	// the intervals are counted exactly by default and a stale flag is removed
+	exactUpdate := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	exact := exactUpdate["$set"].(bson.M)
+	assert.NotContains(t, exact, "ts.approximate")
+	assert.Contains(t, exactUpdate["$unset"], "ts.approximate")
+
+	// up to the limit the intervals are still counted exactly
+	conf.S.Beacon.MaxExactIntervals = count - 1
+	update := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]["$set"].(bson.M)
+	assert.Equal(t, false, update["ts.approximate"])
+	assert.Equal(t, exact["ts.intervals"], update["ts.intervals"])
+
+	// over the limit the scores are estimated and the intervals are binned
+	conf.S.Beacon.MaxExactIntervals = count - 2
+	conf.S.Beacon.IntervalBucketScale = "linear"
+	approxUpdate := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	update = approxUpdate["$set"].(bson.M)
+	assert.Equal(t, true, update["ts.approximate"])
+	assert.Equal(t, []int64{}, update["ts.intervals"])
+	assert.Len(t, update["ts.interval_buckets"], defaultApproxIntervalBuckets+1)
+	var binned int64
+	for _, bucketCount := range update["ts.interval_counts"].([]int64) {
+		binned += bucketCount
+	}
+	assert.Equal(t, int64(count-1), binned)
+
+	assert.Equal(t, exact["ts.mode"], update["ts.mode"])
+	assert.Equal(t, exact["ts.mode_count"], update["ts.mode_count"])
+	assert.Equal(t, exact["ts.range"], update["ts.range"])
+	assert.InDelta(t, exact["ts.score"], update["ts.score"], 0.01)
+	assert.InDelta(t, exact["score"], update["score"], 0.01)
+
+	// the intervals aren't collected for the trend unless it's scored
+	assert.NotContains(t, update, "ts.trend")
+	assert.Contains(t, approxUpdate["$unset"], "ts.trend")
+	conf.S.Beacon.TrendEnabled = true
+	update = analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]["$set"].(bson.M)
+	assert.Equal(t, exact["ts.trend"], update["ts.trend"])
+
+	// MaxIntervalBuckets sets the number of bins
+	conf.S.Beacon.MaxIntervalBuckets = 8
+	update = analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]["$set"].(bson.M)
+	assert.Len(t, update["ts.interval_buckets"], 9)
+}
+
+func TestTopCountMap(t *testing.T) {
+	distinct := []int64{12, 298, 300, 302, 3570, 3600}
+	counts := []int64{1, 4, 9, 4, 2, 1}
+
+	// the most frequent intervals are kept, with ties going to the shorter interval
+	intervals, intervalCounts, other := topCountMap(distinct, counts, 3)
+	assert.Equal(t, []int64{300, 298, 302}, intervals)
+	assert.Equal(t, []int64{9, 4, 4}, intervalCounts)
+	assert.Equal(t, int64(4), other)
+
+	// the kept and residual counts account for every interval
+	intervals, intervalCounts, other = topCountMap(distinct, counts, 1)
+	assert.Equal(t, []int64{300}, intervals)
+	assert.Equal(t, []int64{9}, intervalCounts)
+	assert.Equal(t, int64(12), other)
+
+	// every interval is kept under the limit, ordered by frequency
+	intervals, intervalCounts, other = topCountMap(distinct, counts, 10)
+	assert.Equal(t, []int64{300, 298, 302, 3570, 12, 3600}, intervals)
+	assert.Equal(t, []int64{9, 4, 4, 2, 1, 1}, intervalCounts)
+	assert.Equal(t, int64(0), other)
+
+	// the frequency table from createCountMap is left untouched
+	assert.Equal(t, []int64{12, 298, 300, 302, 3570, 3600}, distinct)
+	assert.Equal(t, []int64{1, 4, 9, 4, 2, 1}, counts)
+}
+
+func TestAnalyzerMaxStoredIntervals(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// a five minute beacon with a few longer intervals
+	count := 48
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	input := newTestBeaconInput(tsMin, 300, count, sizes, sizes)
+	for i := 1; i < count; i++ {
+		input.TsList[i] = input.TsList[i-1] + 300 + []int64{0, 0, 0, 1, 2, 30, 60}[i%7]
+	}
+
+	// every interval is stored in order by default
+	update := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	result := update["$set"].(bson.M)
+	assert.Equal(t, []int64{300, 301, 302, 330, 360}, result["ts.intervals"])
+	assert.Equal(t, []int64{20, 7, 7, 7, 6}, result["ts.interval_counts"])
+	assert.NotContains(t, result, "ts.interval_other_count")
+	// and a residual count left by an earlier import is removed
+	assert.Contains(t, update["$unset"], "ts.interval_other_count")
+
+	// only the most frequent intervals are stored, and the rest are counted
+	// in the residual
+	conf.S.Beacon.MaxStoredIntervals = 2
+	update = analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	result = update["$set"].(bson.M)
+	assert.Equal(t, []int64{300, 301}, result["ts.intervals"])
+	assert.Equal(t, []int64{20, 7}, result["ts.interval_counts"])
+	assert.Equal(t, int64(20), result["ts.interval_other_count"])
+	assert.NotContains(t, update["$unset"], "ts.interval_other_count")
+
+	// the statistics are still measured on every interval
+	assert.Equal(t, int64(300), result["ts.mode"])
+	assert.Equal(t, int64(20), result["ts.mode_count"])
+	assert.Equal(t, int64(60), result["ts.range"])
+}
+
+func TestGetDsSmallnessScore(t *testing.T) {
+	assert.Equal(t, 1.0, getDsSmallnessScore(0, 1500), "empty payloads are the smallest")
+	assert.Equal(t, 0.5, getDsSmallnessScore(750, 1500))
+
+	// payloads at or above the threshold aren't small
+	assert.InDelta(t, 1.0/1500, getDsSmallnessScore(1499, 1500), 1e-9)
+	assert.Equal(t, 0.0, getDsSmallnessScore(1500, 1500))
+	assert.Equal(t, 0.0, getDsSmallnessScore(1501, 1500))
+
+	// the default threshold matches the previously fixed 65535 bytes
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	assert.Equal(t, int64(65535), conf.S.Beacon.SmallPayloadBytes)
+	assert.Equal(t, 0.0, getDsSmallnessScore(65535, conf.S.Beacon.SmallPayloadBytes))
+}
+
+func TestAnalyzerSmallPayloadBytes(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(payload int64) *uconn.Input {
+		sizes := make([]int64, 48)
+		for i := range sizes {
+			sizes[i] = payload
+		}
+		return newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes)
+	}
+
+	// constant payloads have perfect skew and dispersion scores, so only the
+	// smallness of the payloads changes the data size score
+	conf.S.Beacon.SmallPayloadBytes = 120
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(119), newInput(120), newInput(121))
+	assert.Equal(t, 0.67, results[0]["ds.score"], "payloads just below the threshold are small")
+	assert.Equal(t, 0.667, results[1]["ds.score"], "payloads at the threshold aren't small")
+	assert.Equal(t, 0.667, results[2]["ds.score"], "payloads above the threshold aren't small")
+
+	// the same payloads are small under a larger threshold
+	conf.S.Beacon.SmallPayloadBytes = 1200
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, newInput(120))
+	assert.Equal(t, 0.967, results[0]["ds.score"])
+}
+
+func TestAnalyzerUIDSample(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Beacon.UIDSampleSize = 5
+
+	count := 30
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+
+	input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+	for i := 0; i < count; i++ {
+		input.UIDs = append(input.UIDs, "C"+strconv.Itoa(i))
+	}
+
+	result := analyzeTestInputs(t, conf, tsMin, tsMin+86400, input)[0]
+
+	// only the most recently imported uids are kept
+	assert.Equal(t, []string{"C25", "C26", "C27", "C28", "C29"}, result["uids"])
+
+	// a sample size of 0 disables storing uids
+	conf.S.Beacon.UIDSampleSize = 0
+	result = analyzeTestInputs(t, conf, tsMin, tsMin+86400, input)[0]
+	assert.Equal(t, []string{}, result["uids"])
+}
+
+func TestGetConnDurationScore(t *testing.T) {
+	// durations which never vary are perfectly consistent
+	skew, madm, score := getConnDurationScore([]float64{2.5, 2.5, 2.5, 2.5, 2.5})
+	assert.Equal(t, 0.0, skew)
+	assert.Equal(t, 0.0, madm)
+	assert.Equal(t, 1.0, score)
+
+	// so are connections which never last any time at all
+	_, _, score = getConnDurationScore([]float64{0, 0, 0, 0})
+	assert.Equal(t, 1.0, score)
+
+	// slight jitter around a consistent duration still scores well
+	_, _, jitterScore := getConnDurationScore([]float64{2.4, 2.5, 2.5, 2.6, 2.5, 2.5})
+	assert.True(t, jitterScore > 0.9, "jittered durations scored %v", jitterScore)
+
+	// wildly varying durations score poorly
+	skew, madm, variableScore := getConnDurationScore([]float64{0.1, 30, 2, 600, 5, 0.5, 90})
+	assert.True(t, madm > 0)
+	assert.True(t, skew > 0, "long tailed durations should be right skewed")
+	assert.True(t, variableScore < 0.5, "variable durations scored %v", variableScore)
+
+	// the input must not be reordered
+	durations := []float64{3, 1, 2}
+	getConnDurationScore(durations)
+	assert.Equal(t, []float64{3, 1, 2}, durations)
+
+	// too few durations can't be measured
+	_, _, score = getConnDurationScore([]float64{1, 1})
+	assert.Equal(t, 0.0, score)
+}
+
+func TestAnalyzerConnDurationScoring(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	count := 48
+	sizes := make([]int64, count)
+	for i := range sizes {
+		sizes[i] = 120
+	}
+	consistent := make([]float64, count)
+	variable := make([]float64, count)
+	for i := 0; i < count; i++ {
+		consistent[i] = 1.5
+		variable[i] = float64((i%6)*(i%6)) * 10
+	}
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(durations []float64) *uconn.Input {
+		input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+		input.DurationList = durations
+		return input
+	}
+
+	// disabled by default: the score is unaffected and nothing extra is stored
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(consistent), newInput(variable))
+	assert.Equal(t, results[0]["score"], results[1]["score"])
+	assert.NotContains(t, results[0], "conn_dur.score")
+
+	conf.S.Beacon.ConnDurEnabled = true
+	conf.S.Beacon.TsWeight = 0.2
+	conf.S.Beacon.DsWeight = 0.2
+	conf.S.Beacon.DurWeight = 0.2
+	conf.S.Beacon.HistWeight = 0.2
+	conf.S.Beacon.ConnDurWeight = 0.2
+
+	consistentResult := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(consistent))[0]
+	variableResult := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(variable))[0]
+
+	assert.Equal(t, 1.0, consistentResult["conn_dur.score"])
+	assert.True(t, variableResult["conn_dur.score"].(float64) < 1.0)
+	assert.True(t, consistentResult["score"].(float64) > variableResult["score"].(float64),
+		"consistent durations should raise the beacon score")
+}
+
+func TestAnalyzerScoreWeightsNormalized(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	count := 48
+	sizes := make([]int64, count)
+	durations := make([]float64, count)
+	for i := range sizes {
+		sizes[i] = 120
+		durations[i] = 1.5
+	}
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400 - 1800
+
+	newInput := func() *uconn.Input {
+		input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+		input.DurationList = durations
+		return input
+	}
+
+	baseline := analyzeTestInputs(t, conf, tsMin, tsMax, newInput())[0]
+
+	// the optional weight is added on top of the default weights, which
+	// already sum to 1, without lowering them
+	conf.S.Beacon.ConnDurEnabled = true
+	result := analyzeTestInputs(t, conf, tsMin, tsMax, newInput())[0]
+	assert.Equal(t, 1.0, result["conn_dur.score"])
+	expected := (baseline["score"].(float64) + conf.S.Beacon.ConnDurWeight) / (1 + conf.S.Beacon.ConnDurWeight)
+	assert.InDelta(t, expected, result["score"].(float64), 0.0011)
+
+	// the score stays within 0 and 1 however many optional scores are enabled
+	conf.S.Beacon.RespTsEnabled = true
+	conf.S.Beacon.DsPeriodicityEnabled = true
+	conf.S.Beacon.TrendEnabled = true
+	result = analyzeTestInputs(t, conf, tsMin, tsMax, newInput())[0]
+	assert.LessOrEqual(t, result["score"].(float64), 1.0)
+	assert.GreaterOrEqual(t, result["score"].(float64), 0.0)
+}
+
+func TestGetRespTsScore(t *testing.T) {
+	respTs := []int64{1000, 1600, 2200, 2800, 3400, 4000}
+
+	mode, skew, madm, score := getRespTsScore(respTs, len(respTs), 1.0)
+	assert.Equal(t, int64(600), mode)
+	assert.Equal(t, 0.0, skew)
+	assert.Equal(t, int64(0), madm)
+	assert.Equal(t, 1.0, score)
+
+	// the connection count score is part of the timing score
+	_, _, _, score = getRespTsScore(respTs, len(respTs), 0.4)
+	assert.Equal(t, 0.8, score)
+
+	// response timestamps which don't cover every connection are not scored
+	_, _, _, score = getRespTsScore(respTs[:4], len(respTs), 1.0)
+	assert.Equal(t, 0.0, score)
+	_, _, _, score = getRespTsScore(nil, len(respTs), 1.0)
+	assert.Equal(t, 0.0, score)
+
+	// a single non-zero interval can't be measured
+	_, _, _, score = getRespTsScore([]int64{1000, 1000, 1000, 1600}, 4, 1.0)
+	assert.Equal(t, 0.0, score)
+}
+
+// TestAnalyzerRespTsScoring analyzes a server push style beacon where the
+// client reconnects at irregular times after each response, while the server
+// answers on a fixed schedule
+func TestAnalyzerRespTsScoring(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	count := 48
+	sizes := make([]int64, count)
+	for i := range sizes {
+		sizes[i] = 120
+	}
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	// the server pushes every 30 minutes, the connections open up to 25 minutes earlier
+	newInput := func(regularPushes bool) *uconn.Input {
+		input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+		for i := 0; i < count; i++ {
+			push := tsMin + 1800 + int64(i)*1800
+			wait := int64((i*i*37)%1500) + 1
+			input.TsList[i] = push - wait
+			input.DurationList = append(input.DurationList, float64(wait))
+			if !regularPushes {
+				// the responses come back as soon as the connection opens
+				push = input.TsList[i] + 1
+			}
+			input.RespTsList = append(input.RespTsList, push)
+		}
+		return input
+	}
+
+	// disabled by default: the score is unaffected and nothing extra is stored
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(true), newInput(false))
+	assert.Equal(t, results[0]["score"], results[1]["score"])
+	assert.NotContains(t, results[0], "resp_ts.score")
+
+	conf.S.Beacon.RespTsEnabled = true
+	conf.S.Beacon.TsWeight = 0.2
+	conf.S.Beacon.DsWeight = 0.2
+	conf.S.Beacon.DurWeight = 0.2
+	conf.S.Beacon.HistWeight = 0.2
+	conf.S.Beacon.RespTsWeight = 0.2
+
+	pushResult := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(true))[0]
+	irregularResult := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(false))[0]
+
+	assert.Equal(t, int64(1800), pushResult["resp_ts.mode"])
+	assert.Equal(t, int64(0), pushResult["resp_ts.dispersion"])
+	assert.True(t, pushResult["resp_ts.score"].(float64) > pushResult["ts.score"].(float64),
+		"the pushes should be more regular than the connection starts")
+	assert.True(t, irregularResult["resp_ts.score"].(float64) < pushResult["resp_ts.score"].(float64))
+	assert.True(t, pushResult["score"].(float64) > irregularResult["score"].(float64),
+		"regular pushes should raise the beacon score")
+}
+
+func TestGetDsPeriodicityScore(t *testing.T) {
+	// a repeating pattern of sizes correlates perfectly at the pattern length
+	var rhythmic []int64
+	for i := 0; i < 16; i++ {
+		rhythmic = append(rhythmic, 100, 100, 900)
+	}
+	period, score := getDsPeriodicityScore(rhythmic)
+	assert.Equal(t, 3, period)
+	assert.Equal(t, 1.0, score)
+
+	period, score = getDsPeriodicityScore([]int64{40, 800, 40, 800, 40, 800, 40, 800})
+	assert.Equal(t, 2, period)
+	assert.Equal(t, 1.0, score)
+
+	// sizes which never vary repeat every connection
+	period, score = getDsPeriodicityScore([]int64{120, 120, 120, 120, 120})
+	assert.Equal(t, 1, period)
+	assert.Equal(t, 1.0, score)
+
+	// the same sizes in a random order have little rhythm
+	random := make([]int64, len(rhythmic))
+	copy(random, rhythmic)
+	rand.New(rand.NewSource(1)).Shuffle(len(random), func(i, j int) {
+		random[i], random[j] = random[j], random[i]
+	})
+	_, randomScore := getDsPeriodicityScore(random)
+	assert.True(t, randomScore < 0.6, "random sizes scored %v", randomScore)
+
+	noise := make([]int64, 48)
+	rng := rand.New(rand.NewSource(2))
+	for i := range noise {
+		noise[i] = rng.Int63n(1000)
+	}
+	_, noiseScore := getDsPeriodicityScore(noise)
+	assert.True(t, noiseScore < 0.6, "random sizes scored %v", noiseScore)
+
+	// too few sizes to measure
+	period, score = getDsPeriodicityScore([]int64{100, 900, 100})
+	assert.Equal(t, 0, period)
+	assert.Equal(t, 0.0, score)
+}
+
+// TestAnalyzerDsPeriodicityScoring analyzes two beacons which send the same
+// sizes, one in a repeating pattern and the other in a random order, so only
+// the rhythm of the sizes differs
+func TestAnalyzerDsPeriodicityScoring(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	var rhythmic []int64
+	for i := 0; i < 16; i++ {
+		rhythmic = append(rhythmic, 100, 100, 900)
+	}
+	random := make([]int64, len(rhythmic))
+	copy(random, rhythmic)
+	rand.New(rand.NewSource(1)).Shuffle(len(random), func(i, j int) {
+		random[i], random[j] = random[j], random[i]
+	})
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	newInput := func(sizes []int64) *uconn.Input {
+		return newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes)
+	}
+
+	// disabled by default: the score is unaffected and nothing extra is stored
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(rhythmic), newInput(random))
+	assert.Equal(t, results[0]["score"], results[1]["score"])
+	assert.Equal(t, results[0]["ds.score"], results[1]["ds.score"])
+	assert.NotContains(t, results[0], "ds.periodicity")
+
+	conf.S.Beacon.DsPeriodicityEnabled = true
+	conf.S.Beacon.TsWeight = 0.2
+	conf.S.Beacon.DsWeight = 0.2
+	conf.S.Beacon.DurWeight = 0.2
+	conf.S.Beacon.HistWeight = 0.2
+	conf.S.Beacon.DsPeriodicityWeight = 0.2
+
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, newInput(rhythmic), newInput(random))
+	assert.Equal(t, 3, results[0]["ds.period"])
+	assert.Equal(t, 1.0, results[0]["ds.periodicity"])
+	assert.True(t, results[1]["ds.periodicity"].(float64) < results[0]["ds.periodicity"].(float64))
+	assert.Equal(t, results[0]["ds.score"], results[1]["ds.score"], "the size dispersion should be unaffected")
+	assert.True(t, results[0]["score"].(float64) > results[1]["score"].(float64),
+		"rhythmic sizes should raise the beacon score")
+}
+
+// TestAnalyzerIntervalTrend analyzes a beacon whose interval shrinks over the
+// day and one with the same intervals in a random order
+func TestAnalyzerIntervalTrend(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	intervals := make([]int64, 47)
+	for i := range intervals {
+		intervals[i] = int64(3000 - 50*i)
+	}
+	shuffled := make([]int64, len(intervals))
+	copy(shuffled, intervals)
+	rand.New(rand.NewSource(1)).Shuffle(len(shuffled), func(i, j int) {
+		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+	})
+
+	sizes := make([]int64, len(intervals)+1)
+	for i := range sizes {
+		sizes[i] = 100
+	}
+	newInput := func(intervals []int64) *uconn.Input {
+		input := newTestBeaconInput(tsMin, 0, len(sizes), sizes, sizes)
+		for i, interval := range intervals {
+			input.TsList[i+1] = input.TsList[i] + interval
+		}
+		return input
+	}
+
+	// the trend is always stored but doesn't change the score by default
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(intervals), newInput(shuffled))
+	assert.Equal(t, -1.0, results[0]["ts.trend"])
+	assert.InDelta(t, 0, results[1]["ts.trend"], 0.3)
+	assert.Equal(t, results[0]["ts.score"], results[1]["ts.score"])
+	assert.NotContains(t, results[0], "ts.trend_score")
+	untrendedScore := results[0]["score"].(float64)
+
+	conf.S.Beacon.TrendEnabled = true
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, newInput(intervals), newInput(shuffled))
+	assert.Equal(t, 1.0, results[0]["ts.trend_score"])
+	assert.InDelta(t, (untrendedScore+conf.S.Beacon.TrendWeight)/(1+conf.S.Beacon.TrendWeight), results[0]["score"], 0.0011)
+	assert.True(t, results[0]["score"].(float64) > results[1]["score"].(float64),
+		"a shrinking interval should raise the beacon score")
+}
+
+func TestAnalyzerIntervalSizeCorrelation(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	// each connection uploads more the longer it waited to check in
+	count := 48
+	sizes := make([]int64, count)
+	input := newTestBeaconInput(tsMin, 0, count, sizes, sizes)
+	rng := rand.New(rand.NewSource(1))
+	for i := 1; i < count; i++ {
+		interval := 1500 + rng.Int63n(600)
+		input.TsList[i] = input.TsList[i-1] + interval
+		sizes[i] = interval * 10
+	}
+
+	// the connections reach the analyzer in the order they were gathered
+	// in, so the sorter pairs the sizes back up with the timestamps
+	rng.Shuffle(count, func(i, j int) {
+		input.TsList[i], input.TsList[j] = input.TsList[j], input.TsList[i]
+		sizes[i], sizes[j] = sizes[j], sizes[i]
+	})
+	sortByTimestamp(input)
+
+	perfect := make([]int64, count)
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, input,
+		newTestBeaconInput(tsMin, 1800, count, perfect, perfect))
+	assert.Equal(t, 1.0, results[0]["dur_size.correlation"])
+	assert.Equal(t, 0.0, results[1]["dur_size.correlation"])
+}
+
+func TestGetObservedPeriods(t *testing.T) {
+	tsMin := int64(1600000000)
+
+	// 8 hours of data holds one and a third 6 hour periods
+	assert.Equal(t, 1.33, getObservedPeriods(tsMin, tsMin+8*3600, 6*3600))
+	assert.Equal(t, 48.0, getObservedPeriods(tsMin, tsMin+86400, 1800))
+
+	// beacons without a period and datasets without a time range aren't measured
+	assert.Equal(t, 0.0, getObservedPeriods(tsMin, tsMin+86400, 0))
+	assert.Equal(t, 0.0, getObservedPeriods(tsMin, tsMin, 1800))
+}
+
+// TestAnalyzerShortObservation analyzes a beacon which the 8 hour dataset
+// covers fewer than three times and one which it covers many times
+func TestAnalyzerShortObservation(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 8*3600
+	newInput := func(interval int64) *uconn.Input {
+		count := int((tsMax-tsMin)/interval) + 1
+		sizes := make([]int64, count)
+		for i := range sizes {
+			sizes[i] = 100
+		}
+		return newTestBeaconInput(tsMin, interval, count, sizes, sizes)
+	}
+	under := newInput(3 * 3600)
+	well := newInput(1800)
+
+	// disabled by default
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, under, well)
+	assert.NotContains(t, results[0], "observed_periods")
+	unpenalized := []float64{results[0]["score"].(float64), results[1]["score"].(float64)}
+
+	conf.S.Beacon.MinObservedPeriods = 3
+	conf.S.Beacon.ShortObsPenalty = 0.5
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, under, well)
+
+	// the under-observed beacon has its score halved
+	assert.Equal(t, 2.66, results[0]["observed_periods"])
+	assert.Equal(t, true, results[0]["short_observation"])
+	assert.InDelta(t, unpenalized[0]/2, results[0]["score"], 0.0011)
+
+	// the well-observed beacon keeps its score
+	assert.Equal(t, 16.0, results[1]["observed_periods"])
+	assert.Equal(t, false, results[1]["short_observation"])
+	assert.Equal(t, unpenalized[1], results[1]["score"])
+
+	// the confidence is left alone
+	conf.S.Beacon.MinObservedPeriods = 0
+	assert.Equal(t, analyzeTestInputs(t, conf, tsMin, tsMax, under)[0]["confidence"], results[0]["confidence"])
+}
+
+func TestGetRetransmissionRatio(t *testing.T) {
+	// 10 packets with 52 byte headers carrying 5000 payload bytes
+	assert.Equal(t, 0.0, getRetransmissionRatio(5520, 5000, 10), "headers are not retransmissions")
+
+	// half of the 10000 IP bytes were resent
+	assert.Equal(t, 0.5, getRetransmissionRatio(10000, 4200, 10))
+
+	// missing packet counts are not measured
+	assert.Equal(t, 0.0, getRetransmissionRatio(10000, 0, 0))
+	assert.Equal(t, 0.0, getRetransmissionRatio(0, 0, 10))
+}
+
+// TestAnalyzerRetransmission analyzes a pair on a clean link and a pair on a
+// lossy link which resends a quarter of its bytes
+func TestAnalyzerRetransmission(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	count := 48
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(resentBytes int64) *uconn.Input {
+		sizes := make([]int64, count)
+		input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+		for i := 0; i < count; i++ {
+			// 4 packets with 52 byte headers carrying 1000 payload bytes,
+			// along with any resent bytes
+			input.OrigBytesList[i] = 1000 + 4*52 + resentBytes
+			input.OrigIPBytes += input.OrigBytesList[i]
+			input.OrigPayloadBytes += 1000
+			input.OrigPkts += 4
+		}
+		return input
+	}
+	clean, lossy := newInput(0), newInput(552)
+
+	// disabled by default: nothing extra is stored
+	unchecked := analyzeTestInputs(t, conf, tsMin, tsMax, clean, lossy)
+	assert.NotContains(t, unchecked[1], "retrans.flagged")
+
+	conf.S.Beacon.RetransEnabled = true
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, clean, lossy)
+	assert.Equal(t, 0.0, results[0]["retrans.ratio"])
+	assert.Equal(t, false, results[0]["retrans.flagged"])
+	assert.Equal(t, 0.25, results[1]["retrans.ratio"])
+	assert.Equal(t, true, results[1]["retrans.flagged"], "the lossy pair should be flagged")
+	assert.Equal(t, unchecked[1]["score"], results[1]["score"], "flagged pairs keep their score without a penalty")
+
+	conf.S.Beacon.RetransPenalty = 0.5
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, clean, lossy)
+	assert.Equal(t, unchecked[0]["score"], results[0]["score"], "unflagged pairs are not penalized")
+	assert.InDelta(t, unchecked[1]["score"].(float64)*0.5, results[1]["score"].(float64), 0.001)
+
+	// a higher threshold tolerates the lossy link
+	conf.S.Beacon.RetransRatio = 0.5
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, lossy)
+	assert.Equal(t, false, results[0]["retrans.flagged"])
+}
+
+func TestAnalyzerScoreHistory(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(count int) *uconn.Input {
+		sizes := make([]int64, count)
+		for i := range sizes {
+			sizes[i] = 120
+		}
+		return newTestBeaconInput(tsMin, 86400/int64(count), count, sizes, sizes)
+	}
+
+	// disabled by default: the score is only overwritten
+	update := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, newInput(24))[0]
+	assert.NotContains(t, update, "$push")
+
+	conf.S.Beacon.ScoreHistory = true
+
+	// simulate the pair being re-analyzed as each chunk of a rolling dataset
+	// is imported, applying the $push to the stored beacon document
+	var history []bson.M
+	var scores []float64
+	for chunk, count := range []int{24, 48, 96} {
+		update := analyzeTestUpdates(t, conf, tsMin, tsMax, chunk, newInput(count))[0]
+		require.Contains(t, update, "$push")
+
+		entry := update["$push"].(bson.M)["score_history"].(bson.M)
+		history = append(history, entry)
+		scores = append(scores, update["$set"].(bson.M)["score"].(float64))
+	}
+
+	require.Len(t, history, 3)
+	for chunk, entry := range history {
+		assert.Equal(t, chunk, entry["cid"], "history entry %d should record its chunk", chunk)
+		assert.Equal(t, scores[chunk], entry["score"], "history entry %d should record the chunk's score", chunk)
+	}
+	assert.NotEqual(t, history[0]["score"], history[2]["score"], "the score should evolve across chunks")
+}
+
+func TestAnalyzerHashPairKeys(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	sizes := make([]int64, 24)
+	input := newTestBeaconInput(tsMin, 3600, 24, sizes, sizes)
+
+	// by default beacons are selected by their pair fields
+	assert.Equal(t, input.Hosts.BSONKey(), getPairSelector(conf, input.Hosts))
+	update := analyzeTestUpdates(t, conf, tsMin, tsMin+86400, 0, input)[0]
+	assert.NotContains(t, update, "$setOnInsert")
+
+	conf.S.Beacon.HashPairKeys = true
+
+	// the hash replaces the pair fields in the selector
+	selector := getPairSelector(conf, input.Hosts)
+	assert.Equal(t, input.Hosts.BSONHashKey(), selector)
+	assert.NotContains(t, selector, "src")
+
+	// the pair fields are still written for display
+	update = analyzeTestUpdates(t, conf, tsMin, tsMin+86400, 0, input)[0]
+	require.Contains(t, update, "$setOnInsert")
+	assert.Equal(t, input.Hosts.BSONKey(), update["$setOnInsert"])
+}
+
+func TestAnalyzerZeroWidthWindow(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// every connection in the dataset happened at the same instant
+	ts := int64(1600000000)
+	sizes := make([]int64, 24)
+	for i := range sizes {
+		sizes[i] = 120
+	}
+	input := newTestBeaconInput(ts, 0, 24, sizes, sizes)
+
+	result := analyzeTestInputs(t, conf, ts, ts, input)[0]
+	for field, value := range result {
+		if score, ok := value.(float64); ok {
+			assert.False(t, math.IsNaN(score) || math.IsInf(score, 0), "%s should be finite but is %v", field, score)
+		}
+	}
+	assert.Equal(t, 1.0, result["ts.conns_score"])
+}
+
+func TestGetConfidence(t *testing.T) {
+	// enough intervals from every connection are fully trusted
+	assert.Equal(t, 1.0, getConfidence(47, 48, 10))
+	assert.Equal(t, 1.0, getConfidence(10, 11, 10))
+
+	// too few intervals scale the confidence down
+	assert.Equal(t, 0.2, getConfidence(2, 3, 10))
+
+	// so do connections which share timestamps and produce no interval
+	assert.Equal(t, 0.5, getConfidence(20, 41, 10))
+
+	// nothing can be said without any intervals
+	assert.Equal(t, 0.0, getConfidence(0, 1, 10))
+	assert.Equal(t, 0.0, getConfidence(0, 20, 10))
+}
+
+func TestGetConfidenceMonotonic(t *testing.T) {
+	minSamples := 10
+	for _, gaps := range []int64{0, 1, 5} {
+		previous := 0.0
+		for samples := 1; samples <= 100; samples++ {
+			// every connection after the first produces a sample, except for the gaps
+			confidence := getConfidence(samples, int64(samples)+1+gaps, minSamples)
+			assert.GreaterOrEqual(t, confidence, previous,
+				"confidence fell at %d samples with %d gaps", samples, gaps)
+			assert.LessOrEqual(t, confidence, 1.0)
+			if gaps == 0 && samples >= minSamples {
+				assert.Equal(t, 1.0, confidence, "%d samples should be fully trusted", samples)
+			}
+			previous = confidence
+		}
+	}
+}
+
+func TestAnalyzerConfidence(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(interval int64, count int) *uconn.Input {
+		sizes := make([]int64, count)
+		for i := range sizes {
+			sizes[i] = 120
+		}
+		return newTestBeaconInput(tsMin, interval, count, sizes, sizes)
+	}
+
+	// a beacon observed all day
+	sampled := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(1800, 48))[0]
+	assert.Equal(t, 47, sampled["ts.interval_sample_size"])
+	assert.Equal(t, 1.0, sampled["confidence"])
+
+	// three connections which happen to be an hour apart look just as periodic
+	coincidental := analyzeTestInputs(t, conf, tsMin, tsMax, newInput(3600, 3))[0]
+	assert.Equal(t, 2, coincidental["ts.interval_sample_size"])
+	assert.Equal(t, int64(2), coincidental["ts.mode_count"], "both intervals should match")
+	assert.True(t, coincidental["confidence"].(float64) < 0.5,
+		"a coincidental beacon scored a confidence of %v", coincidental["confidence"])
+
+	// connections sharing timestamps produce fewer intervals
+	burst := newInput(1800, 48)
+	for i := range burst.TsList {
+		burst.TsList[i] = tsMin + int64(i/2)*3600
+	}
+	bursty := analyzeTestInputs(t, conf, tsMin, tsMax, burst)[0]
+	assert.Equal(t, 23, bursty["ts.interval_sample_size"])
+	assert.True(t, bursty["confidence"].(float64) < sampled["confidence"].(float64))
+
+	// a lower minimum trusts fewer samples
+	conf.S.Beacon.MinIntervalSamples = 2
+	coincidental = analyzeTestInputs(t, conf, tsMin, tsMax, newInput(3600, 3))[0]
+	assert.Equal(t, 1.0, coincidental["confidence"])
+}
+
+func TestAnalyzerBlacklistMinScore(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	newInput := func(dst string, interval int64, count int) *uconn.Input {
+		sizes := make([]int64, count)
+		for i := range sizes {
+			sizes[i] = 120
+		}
+		input := newTestBeaconInput(tsMin, interval, count, sizes, sizes)
+		input.Hosts = data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst})
+		return input
+	}
+
+	// a beacon seen all day and a few connections which barely qualify
+	steady := newInput("203.0.113.7", 1800, 48)
+	tail := newInput("198.51.100.1", 3600, 3)
+
+	scores := analyzeTestInputs(t, conf, tsMin, tsMax, steady, tail)
+	steadyScore, tailScore := scores[0]["score"].(float64), scores[1]["score"].(float64)
+	require.True(t, steadyScore > tailScore)
+	_, ok := scores[0]["blacklisted"]
+	assert.False(t, ok, "beacons should not be marked without a blacklist lookup")
+
+	conf.S.Beacon.BlacklistMinScore = (steadyScore + tailScore) / 2
+
+	// every host is blacklisted so that only the score decides which beacons are marked
+	var lookups []string
+	blacklisted := func(host data.UniqueIP) (bool, error) {
+		lookups = append(lookups, host.IP)
+		return true, nil
+	}
+
+	var results []bson.M
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, blacklisted, nil, nil,
+		func(changes database.BulkChanges) {
+			for _, change := range changes[conf.T.Beacon.BeaconTable] {
+				results = append(results, change.Update.(bson.M))
+			}
+		},
+		func() {},
+	)
+	a.start()
+	a.collect(steady)
+	a.collect(tail)
+	a.close()
+	require.Len(t, results, 2)
+
+	assert.Equal(t, true, results[0]["$set"].(bson.M)["blacklisted"])
+	_, ok = results[1]["$set"].(bson.M)["blacklisted"]
+	assert.False(t, ok, "beacons below BlacklistMinScore should not be marked")
+	assert.Contains(t, results[1]["$unset"], "blacklisted", "results from earlier checks should be removed")
+	assert.Equal(t, []string{"10.0.0.1"}, lookups, "only beacons above BlacklistMinScore should be looked up")
+
+	// a failed lookup leaves the beacon unmarked, even without a logger
+	failing := func(data.UniqueIP) (bool, error) {
+		return false, errors.New("lookup failed")
+	}
+	results = nil
+	a = newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, failing, nil, nil,
+		func(changes database.BulkChanges) {
+			for _, change := range changes[conf.T.Beacon.BeaconTable] {
+				results = append(results, change.Update.(bson.M))
+			}
+		},
+		func() {},
+	)
+	a.start()
+	a.collect(steady)
+	a.close()
+	require.Len(t, results, 1)
+	assert.Equal(t, false, results[0]["$set"].(bson.M)["blacklisted"])
+}
+
+func TestAnalyzerCloudProvider(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+
+	newInput := func(dst string) *uconn.Input {
+		input := newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes)
+		input.Hosts = data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst})
+		return input
+	}
+
+	// the annotation is skipped without cloud provider ranges
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput("35.190.247.1"))
+	assert.NotContains(t, results[0], "cloud_provider")
+
+	ranges, err := cloud.LoadRanges(conf)
+	require.Nil(t, err)
+
+	var annotated []bson.M
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, ranges, nil,
+		func(changes database.BulkChanges) {
+			for _, change := range changes[conf.T.Beacon.BeaconTable] {
+				annotated = append(annotated, change.Update.(bson.M)["$set"].(bson.M))
+			}
+		},
+		func() {},
+	)
+	a.start()
+	a.collect(newInput("35.190.247.1"))
+	a.collect(newInput("203.0.113.7"))
+	a.close()
+	require.Len(t, annotated, 2)
+
+	assert.Equal(t, "gcp", annotated[0]["cloud_provider"])
+	assert.Equal(t, "", annotated[1]["cloud_provider"], "destinations outside the ranges should be cleared")
+}
+
+func TestAnalyzerSharedLimiter(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.R.AnalysisLimiter = util.NewLimiter(2)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+
+	var lock sync.Mutex
+	var active, maxActive, analyzed int
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil,
+		func(changes database.BulkChanges) {
+			lock.Lock()
+			active++
+			analyzed++
+			if active > maxActive {
+				maxActive = active
+			}
+			lock.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			lock.Lock()
+			active--
+			lock.Unlock()
+		},
+		func() {},
+	)
+
+	// more workers are started than the limit allows to run
+	for i := 0; i < 6; i++ {
+		a.start()
+	}
+	for i := 0; i < 12; i++ {
+		a.collect(newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes))
+	}
+	a.close()
+
+	assert.Equal(t, 12, analyzed)
+	assert.LessOrEqual(t, maxActive, 2, "no more workers than the limit should analyze at once")
+}
+
+// mockScorer is an in process scorer.Scorer which records the requests it
+// receives and answers with a fixed response
+type mockScorer struct {
+	requests []scorer.Request
+	resp     scorer.Response
+	err      error
+}
+
+func (m *mockScorer) Score(req scorer.Request) (scorer.Response, error) {
+	m.requests = append(m.requests, req)
+	return m.resp, m.err
+}
+
+func (m *mockScorer) Close() error { return nil }
+
+func TestAnalyzerExternalScorer(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Beacon.ExternalScorer.Weight = 0.5
+
+	logger := log.New()
+	logger.Out = ioutil.Discard
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+	for i := range sizes {
+		sizes[i] = int64(100 + i%2)
+	}
+
+	analyze := func(s scorer.Scorer) bson.M {
+		input := newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes)
+		// the sorter would have ordered the timestamps, the last interval is longer
+		input.TsList[len(sizes)-1] += 600
+
+		var results []bson.M
+		a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, logger, nil, nil, s,
+			func(changes database.BulkChanges) {
+				for _, change := range changes[conf.T.Beacon.BeaconTable] {
+					results = append(results, change.Update.(bson.M)["$set"].(bson.M))
+				}
+			},
+			func() {},
+		)
+		a.start()
+		a.collect(input)
+		a.close()
+		require.Len(t, results, 1)
+		return results[0]
+	}
+
+	builtIn := analyze(nil)
+	assert.NotContains(t, builtIn, "external.score")
+
+	mock := &mockScorer{resp: scorer.Response{Score: 0.4, Fields: map[string]interface{}{"model": "v2"}}}
+	result := analyze(mock)
+
+	require.Len(t, mock.requests, 1)
+	req := mock.requests[0]
+	assert.Equal(t, "10.0.0.1", req.Src)
+	assert.Equal(t, "8.8.8.8", req.Dst)
+	assert.Len(t, req.Timestamps, len(sizes))
+	require.Len(t, req.Intervals, len(sizes)-1)
+	assert.Equal(t, int64(2400), req.Intervals[len(sizes)-2], "the intervals should be sent in chronological order")
+	assert.Equal(t, sizes, req.OrigBytes)
+	assert.Equal(t, builtIn["ts.score"], req.Scores["ts"])
+	assert.Equal(t, builtIn["ds.score"], req.Scores["ds"])
+
+	assert.Equal(t, 0.4, result["external.score"])
+	assert.Equal(t, "v2", result["external.model"])
+	// the external score is averaged in with the built-in scores, whose weights sum to 1
+	assert.InDelta(t, (builtIn["score"].(float64)+0.4*0.5)/1.5, result["score"].(float64), 0.0011)
+
+	// out of range scores are limited
+	mock = &mockScorer{resp: scorer.Response{Score: 7}}
+	assert.Equal(t, 1.0, analyze(mock)["external.score"])
+
+	// the built-in scores are kept if the beacon can't be scored externally
+	mock = &mockScorer{err: errors.New("scorer crashed")}
+	result = analyze(mock)
+	assert.NotContains(t, result, "external.score")
+	assert.Equal(t, builtIn["score"], result["score"])
+
+	mock = &mockScorer{err: scorer.ErrClosed}
+	assert.Equal(t, builtIn["score"], analyze(mock)["score"])
+
+	// a failing scorer is only logged if there is a logger
+	logger = nil
+	mock = &mockScorer{err: errors.New("scorer crashed")}
+	result = analyze(mock)
+	assert.NotContains(t, result, "external.score")
+	assert.Equal(t, builtIn["score"], result["score"])
+}
+
+func TestAnalyzerCancel(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+	for i := range sizes {
+		sizes[i] = 100
+	}
+
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	analyzed := 0
+	closed := 0
+	a := newAnalyzer(ctx, tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil,
+		func(database.BulkChanges) {
+			mu.Lock()
+			defer mu.Unlock()
+			analyzed++
+			// interrupt the run once analysis is underway
+			if analyzed == 10 {
+				cancel()
+			}
+		},
+		func() {
+			mu.Lock()
+			defer mu.Unlock()
+			closed++
+		},
+	)
+	for i := 0; i < 4; i++ {
+		a.start()
+	}
+
+	// the producer isn't blocked by the cancelled analyzer
+	const inputs = 10000
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < inputs; i++ {
+			a.collect(newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes))
+		}
+		a.close()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the analyzer did not stop after being cancelled")
+	}
+
+	mu.Lock()
+	assert.Less(t, analyzed, inputs, "the remaining entries should be dropped")
+	assert.Equal(t, 1, closed)
+	mu.Unlock()
+
+	// the analysis goroutines exit
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines, "the analysis goroutines should exit")
+}
+
+func TestAnalyzerSummary(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	logger, hook := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+
+	inputs := []*uconn.Input{
+		newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes),
+		newTestBeaconInput(tsMin, 3600, 24, sizes[:24], sizes[:24]),
+		// two timestamps are too few to score, so the pair is skipped
+		newTestBeaconInput(tsMin, 1800, 2, sizes[:2], sizes[:2]),
+	}
+
+	var mu sync.Mutex
+	var scores []float64
+	closed := false
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 3, nil, conf, logger, nil, nil, nil,
+		func(changes database.BulkChanges) {
+			mu.Lock()
+			defer mu.Unlock()
+			for _, change := range changes[conf.T.Beacon.BeaconTable] {
+				scores = append(scores, change.Update.(bson.M)["$set"].(bson.M)["score"].(float64))
+			}
+		},
+		func() {
+			// the summary is logged before the analysis is reported as closed
+			entry := hook.LastEntry()
+			require.NotNil(t, entry)
+			assert.Equal(t, "Finished beacon analysis", entry.Message)
+			closed = true
+		},
+	)
+	for i := 0; i < 2; i++ {
+		a.start()
+	}
+	for _, input := range inputs {
+		a.collect(input)
+	}
+	a.close()
+	require.True(t, closed)
+	require.Len(t, scores, 2)
+
+	entry := hook.LastEntry()
+	assert.Equal(t, log.InfoLevel, entry.Level)
+	assert.Equal(t, "beacon", entry.Data["Module"])
+	assert.Equal(t, 3, entry.Data["chunk"])
+	assert.Equal(t, int64(2), entry.Data["analyzed"])
+	assert.Equal(t, int64(1), entry.Data["skipped"])
+	assert.Equal(t, math.Min(scores[0], scores[1]), entry.Data["min_score"])
+	assert.Equal(t, math.Max(scores[0], scores[1]), entry.Data["max_score"])
+	assert.InDelta(t, (scores[0]+scores[1])/2, entry.Data["mean_score"], 1e-9)
+	_, err = time.ParseDuration(entry.Data["duration"].(string))
+	assert.Nil(t, err)
+}
+
+func TestAnalyzerSummaryEmpty(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	logger, hook := test.NewNullLogger()
+
+	a := newAnalyzer(context.Background(), 0, 0, 0, nil, conf, logger, nil, nil, nil,
+		func(database.BulkChanges) {}, func() {})
+	a.start()
+	a.close()
+
+	// a chunk without pairs still reports that it produced nothing
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, int64(0), entry.Data["analyzed"])
+	assert.Equal(t, 0.0, entry.Data["min_score"])
+	assert.Equal(t, 0.0, entry.Data["max_score"])
+	assert.Equal(t, 0.0, entry.Data["mean_score"])
+}
+
+func TestAnalyzerErrors(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+
+	written := 0
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil,
+		func(changes database.BulkChanges) {
+			// the first write fails
+			written++
+			if written == 1 {
+				panic("write failed")
+			}
+		},
+		func() {},
+	)
+	a.start()
+	a.collect(newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes))
+	a.collect(newTestBeaconInput(tsMin, 3600, 24, sizes[:24], sizes[:24]))
+
+	// nothing reads the errors during the analysis, so the malformed pairs
+	// overflow the buffer without blocking the analysis
+	for i := 0; i < analyzerErrorBuffer; i++ {
+		a.collect(newTestBeaconInput(tsMin, 1800, 2, sizes[:2], sizes[:2]))
+	}
+	a.close()
+
+	var errs []error
+	for err := range a.errors() {
+		errs = append(errs, err)
+	}
+	require.Len(t, errs, analyzerErrorBuffer)
+	assert.Contains(t, errs[0].Error(), "write failed")
+	assert.Contains(t, errs[1].Error(), "could not score the beacon from 10.0.0.1 to 8.8.8.8")
+
+	// the pair after the failed write is still analyzed
+	assert.Equal(t, 2, written)
+	assert.Equal(t, int64(1), a.summary.analyzed)
+	assert.Equal(t, int64(analyzerErrorBuffer+1), a.summary.skipped)
+}
+
+func TestAnalyzerWriterErrors(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	sizes := make([]int64, 48)
+
+	// the writer only fails once it drains its buffers on close
+	var a *analyzer
+	a = newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil,
+		func(database.BulkChanges) {},
+		func() { a.reportError(errors.New("could not write to beacon")) },
+	)
+	a.start()
+	a.collect(newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes))
+	a.close()
+
+	var failures analysisErrors
+	for err := range a.errors() {
+		failures.add(err)
+	}
+	require.NotNil(t, failures.err())
+	assert.Equal(t, "beacon analysis errors: 1, first error: could not write to beacon", failures.err().Error())
+}
+
+// BenchmarkAnalyzerMaxExactIntervals compares the memory used by the analyzer
+// to score a high volume pair exactly and approximately
+func BenchmarkAnalyzerMaxExactIntervals(b *testing.B) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(b, err)
+
+	tsMin := int64(1600000000)
+	tsList := jitteredTimestamps(rand.New(rand.NewSource(1)), tsMin, 100000, 60, 3)
+	tsMax := tsList[len(tsList)-1]
+	sizes := make([]int64, len(tsList))
+	input := newTestBeaconInput(tsMin, 60, len(tsList), sizes, sizes)
+	input.TsList = tsList
+
+	for _, bench := range []struct {
+		name              string
+		maxExactIntervals int
+	}{
+		{"exact", 0},
+		{"approximate", 1000},
+	} {
+		conf.S.Beacon.MaxExactIntervals = bench.maxExactIntervals
+		a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil,
+			func(database.BulkChanges) {},
+			func() {},
+		)
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				a.analyze(input)
+			}
+		})
+	}
+}
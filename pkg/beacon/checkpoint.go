@@ -0,0 +1,141 @@
+package beacon
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/globalsign/mgo/bson"
+)
+
+type (
+	// checkpointEntry is stored for each unique connection which had not been
+	// analyzed when beacon analysis reached its deadline. The timestamp range
+	// is stored so the resumed analysis scores the connections the same way.
+	checkpointEntry struct {
+		data.UniqueIPPair `bson:",inline"`
+		IsLocalSrc        bool  `bson:"local_src"`
+		IsLocalDst        bool  `bson:"local_dst"`
+		Chunk             int   `bson:"cid"`
+		MinTimestamp      int64 `bson:"min_ts"`
+		MaxTimestamp      int64 `bson:"max_ts"`
+	}
+
+	// checkpointGroup holds the unique connections restored from the checkpoint
+	// records of a single chunk
+	checkpointGroup struct {
+		chunk        int
+		uconnMap     map[string]*uconn.Input
+		hostMap      map[string]*host.Input
+		minTimestamp int64
+		maxTimestamp int64
+	}
+)
+
+// collectUntil sends each unique connection to collect until the deadline passes or
+// ctx is cancelled and returns the unique connections which were not sent. A zero
+// deadline never passes.
+func collectUntil(ctx context.Context, uconnMap map[string]*uconn.Input, deadline time.Time, now func() time.Time,
+	collect func(*uconn.Input)) map[string]*uconn.Input {
+
+	pending := make(map[string]*uconn.Input)
+	for key, entry := range uconnMap {
+		if ctx.Err() != nil || (!deadline.IsZero() && !now().Before(deadline)) {
+			pending[key] = entry
+			continue
+		}
+		collect(entry)
+	}
+	return pending
+}
+
+// newCheckpointEntries creates the records stored for the pending unique connections
+func newCheckpointEntries(pending map[string]*uconn.Input, chunk int, minTimestamp, maxTimestamp int64) []interface{} {
+	entries := make([]interface{}, 0, len(pending))
+	for _, entry := range pending {
+		entries = append(entries, checkpointEntry{
+			UniqueIPPair: entry.Hosts,
+			IsLocalSrc:   entry.IsLocalSrc,
+			IsLocalDst:   entry.IsLocalDst,
+			Chunk:        chunk,
+			MinTimestamp: minTimestamp,
+			MaxTimestamp: maxTimestamp,
+		})
+	}
+	return entries
+}
+
+// restoreCheckpoint rebuilds the unique connections which still need to be
+// analyzed from the stored checkpoint records. The unique connections are
+// grouped by the chunk they were imported in so their beacons are written to
+// the same chunk. The local hosts of those unique connections are returned as
+// well since their beacon summaries need updated. A unique connection may be
+// recorded by several imports as the dataset's timestamp range grows, so the
+// widest timestamp range recorded for a chunk is returned. The groups are
+// ordered by chunk.
+func restoreCheckpoint(entries []checkpointEntry) []checkpointGroup {
+	groups := make(map[int]*checkpointGroup)
+
+	for _, entry := range entries {
+		group, ok := groups[entry.Chunk]
+		if !ok {
+			group = &checkpointGroup{
+				chunk:        entry.Chunk,
+				uconnMap:     make(map[string]*uconn.Input),
+				hostMap:      make(map[string]*host.Input),
+				minTimestamp: entry.MinTimestamp,
+				maxTimestamp: entry.MaxTimestamp,
+			}
+			groups[entry.Chunk] = group
+		}
+
+		if entry.MinTimestamp < group.minTimestamp {
+			group.minTimestamp = entry.MinTimestamp
+		}
+		if entry.MaxTimestamp > group.maxTimestamp {
+			group.maxTimestamp = entry.MaxTimestamp
+		}
+
+		group.uconnMap[entry.MapKey()] = &uconn.Input{
+			Hosts:      entry.UniqueIPPair,
+			IsLocalSrc: entry.IsLocalSrc,
+			IsLocalDst: entry.IsLocalDst,
+		}
+		if entry.IsLocalSrc {
+			group.addLocalHost(entry.UniqueSrcIP.Unpair())
+		}
+		if entry.IsLocalDst {
+			group.addLocalHost(entry.UniqueDstIP.Unpair())
+		}
+	}
+
+	ordered := make([]checkpointGroup, 0, len(groups))
+	for _, group := range groups {
+		ordered = append(ordered, *group)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].chunk < ordered[j].chunk })
+	return ordered
+}
+
+// addLocalHost adds a local host whose beacon summary needs updated
+func (g *checkpointGroup) addLocalHost(ip data.UniqueIP) {
+	g.hostMap[ip.MapKey()] = &host.Input{Host: ip, IsLocal: true}
+}
+
+// analyzedSelectors returns the selectors of the checkpoint records of the
+// unique connections in the group which are not pending
+func (g *checkpointGroup) analyzedSelectors(pending map[string]*uconn.Input) []bson.M {
+	var selectors []bson.M
+	for key, entry := range g.uconnMap {
+		if _, ok := pending[key]; ok {
+			continue
+		}
+		selector := entry.Hosts.BSONKey()
+		selector["cid"] = g.chunk
+		selectors = append(selectors, selector)
+	}
+	return selectors
+}
@@ -0,0 +1,185 @@
+package beacon
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCheckpointUconns(count int) map[string]*uconn.Input {
+	uconnMap := make(map[string]*uconn.Input)
+	for i := 0; i < count; i++ {
+		input := &uconn.Input{
+			Hosts: data.NewUniqueIPPair(
+				data.UniqueIP{IP: "10.0.0." + strconv.Itoa(i)},
+				data.UniqueIP{IP: "8.8.8.8"},
+			),
+			IsLocalSrc: true,
+		}
+		uconnMap[input.Hosts.MapKey()] = input
+	}
+	return uconnMap
+}
+
+// fakeClock returns a clock which advances by a second each time it is read
+func fakeClock(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		current := now
+		now = now.Add(time.Second)
+		return current
+	}
+}
+
+func TestCollectUntilDeadline(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(10)
+	start := time.Unix(1600000000, 0)
+
+	// the deadline passes after the first 4 unique connections are collected
+	analyzed := make(map[string]bool)
+	pending := collectUntil(context.Background(), uconnMap, start.Add(4*time.Second), fakeClock(start), func(entry *uconn.Input) {
+		analyzed[entry.Hosts.MapKey()] = true
+	})
+
+	assert.Len(t, analyzed, 4)
+	assert.Len(t, pending, 6)
+	for key := range pending {
+		assert.False(t, analyzed[key], "pending unique connections must not have been analyzed")
+	}
+
+	// resume the analysis from the checkpoint records without a deadline
+	var entries []checkpointEntry
+	for _, entry := range newCheckpointEntries(pending, 2, 100, 200) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+	groups := restoreCheckpoint(entries)
+	require.Len(t, groups, 1)
+	assert.Equal(t, 2, groups[0].chunk)
+	assert.Equal(t, int64(100), groups[0].minTimestamp)
+	assert.Equal(t, int64(200), groups[0].maxTimestamp)
+	assert.Len(t, groups[0].hostMap, 6, "the local sources of the pending unique connections need summarized")
+
+	pending = collectUntil(context.Background(), groups[0].uconnMap, time.Time{}, fakeClock(start), func(entry *uconn.Input) {
+		key := entry.Hosts.MapKey()
+		assert.False(t, analyzed[key], "unique connections must only be analyzed once")
+		analyzed[key] = true
+	})
+
+	assert.Empty(t, pending)
+	require.Len(t, analyzed, 10)
+	for key := range uconnMap {
+		assert.True(t, analyzed[key])
+	}
+}
+
+func TestCollectUntilPassedDeadline(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(3)
+	start := time.Unix(1600000000, 0)
+
+	// later batches of the same import are checkpointed entirely
+	pending := collectUntil(context.Background(), uconnMap, start, fakeClock(start), func(entry *uconn.Input) {
+		t.Fatal("no unique connections should be analyzed after the deadline")
+	})
+	assert.Len(t, pending, 3)
+}
+
+func TestCollectUntilCancelled(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(10)
+	start := time.Unix(1600000000, 0)
+
+	// the import is interrupted while the third unique connection is analyzed
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	analyzed := 0
+	pending := collectUntil(ctx, uconnMap, time.Time{}, fakeClock(start), func(entry *uconn.Input) {
+		analyzed++
+		if analyzed == 3 {
+			cancel()
+		}
+	})
+
+	assert.Equal(t, 3, analyzed)
+	assert.Len(t, pending, 7)
+}
+
+func TestRestoreCheckpointMergesImports(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(2)
+
+	// the same unique connections were checkpointed by two imports as the
+	// dataset's timestamp range grew
+	var entries []checkpointEntry
+	for _, entry := range newCheckpointEntries(uconnMap, 0, 100, 200) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+	for _, entry := range newCheckpointEntries(uconnMap, 0, 100, 300) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+
+	groups := restoreCheckpoint(entries)
+	require.Len(t, groups, 1)
+	assert.Len(t, groups[0].uconnMap, 2)
+	assert.Len(t, groups[0].hostMap, 2)
+	assert.Equal(t, int64(100), groups[0].minTimestamp)
+	assert.Equal(t, int64(300), groups[0].maxTimestamp)
+
+	for key, entry := range groups[0].uconnMap {
+		assert.Equal(t, uconnMap[key].Hosts, entry.Hosts)
+		assert.True(t, entry.IsLocalSrc)
+		assert.False(t, entry.IsLocalDst)
+	}
+}
+
+func TestRestoreCheckpointKeepsChunks(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(3)
+
+	// a rolling dataset checkpointed chunk 4 and then chunk 1
+	var entries []checkpointEntry
+	for _, entry := range newCheckpointEntries(uconnMap, 4, 400, 500) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+	for _, entry := range newCheckpointEntries(newTestCheckpointUconns(1), 1, 100, 200) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+
+	// the beacons are written to the chunk their connections were imported in
+	groups := restoreCheckpoint(entries)
+	require.Len(t, groups, 2)
+	assert.Equal(t, 1, groups[0].chunk)
+	assert.Len(t, groups[0].uconnMap, 1)
+	assert.Equal(t, int64(100), groups[0].minTimestamp)
+	assert.Equal(t, int64(200), groups[0].maxTimestamp)
+	assert.Equal(t, 4, groups[1].chunk)
+	assert.Len(t, groups[1].uconnMap, 3)
+	assert.Equal(t, int64(400), groups[1].minTimestamp)
+	assert.Equal(t, int64(500), groups[1].maxTimestamp)
+}
+
+func TestCheckpointAnalyzedSelectors(t *testing.T) {
+	uconnMap := newTestCheckpointUconns(4)
+	var entries []checkpointEntry
+	for _, entry := range newCheckpointEntries(uconnMap, 3, 100, 200) {
+		entries = append(entries, entry.(checkpointEntry))
+	}
+	group := restoreCheckpoint(entries)[0]
+
+	// the deadline passed again with one unique connection left
+	start := time.Unix(1600000000, 0)
+	pending := collectUntil(context.Background(), group.uconnMap, start.Add(3*time.Second), fakeClock(start), func(*uconn.Input) {})
+	require.Len(t, pending, 1)
+
+	// only the records of the analyzed unique connections are removed
+	selectors := group.analyzedSelectors(pending)
+	assert.Len(t, selectors, 3)
+	for _, selector := range selectors {
+		assert.Equal(t, 3, selector["cid"])
+		for _, entry := range pending {
+			assert.NotEqual(t, entry.Hosts.SrcIP, selector["src"], "pending records must be kept")
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package beacon
+
+import "strings"
+
+// FilterCloudProvider returns the beacons whose destinations were annotated
+// with the given cloud provider. If noCloud is set, it instead returns the
+// beacons whose destinations are not in any cloud provider's ranges.
+func FilterCloudProvider(beacons []Result, provider string, noCloud bool) []Result {
+	provider = strings.ToLower(provider)
+
+	var filtered []Result
+	for _, b := range beacons {
+		if noCloud {
+			if b.CloudProvider == "" {
+				filtered = append(filtered, b)
+			}
+			continue
+		}
+		if b.CloudProvider == provider {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
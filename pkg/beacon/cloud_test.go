@@ -0,0 +1,30 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterCloudProvider(t *testing.T) {
+	newResult := func(dst string, provider string) Result {
+		return Result{
+			UniqueIPPair:  data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst}),
+			CloudProvider: provider,
+		}
+	}
+	beacons := []Result{
+		newResult("52.94.0.1", "aws"),
+		newResult("35.190.0.1", "gcp"),
+		newResult("203.0.113.7", ""),
+		newResult("54.64.0.1", "aws"),
+	}
+
+	aws := FilterCloudProvider(beacons, "AWS", false)
+	assert.Equal(t, []Result{beacons[0], beacons[3]}, aws)
+
+	assert.Empty(t, FilterCloudProvider(beacons, "azure", false))
+
+	assert.Equal(t, []Result{beacons[2]}, FilterCloudProvider(beacons, "", true))
+}
@@ -0,0 +1,138 @@
+package beacon
+
+import (
+	"math"
+	"sort"
+
+	"github.com/activecm/rita/pkg/data"
+)
+
+const (
+	// DiffScoreChanged marks a beacon whose score shifted by at least the minimum delta
+	DiffScoreChanged = "score"
+	// DiffAdded marks a beacon which only exists in the newer dataset
+	DiffAdded = "added"
+	// DiffRemoved marks a beacon which only exists in the older dataset
+	DiffRemoved = "removed"
+	// DiffPortsChanged marks a beacon whose destination port:protocol:service tuples changed
+	DiffPortsChanged = "ports"
+)
+
+type (
+	// DiffOptions controls which changes between two sets of beacons are reported
+	DiffOptions struct {
+		MinScoreDelta float64 // smallest absolute score change which is reported
+		Destinations  bool    // report beacons which were added or removed
+		Ports         bool    // report changes to the destination tuples of a beacon
+	}
+
+	// DiffResult represents the change to a beacon between two datasets
+	DiffResult struct {
+		data.UniqueIPPair `bson:",inline"`
+		Change            string   `bson:"change"`
+		OldScore          float64  `bson:"old_score"`
+		NewScore          float64  `bson:"new_score"`
+		ScoreDelta        float64  `bson:"score_delta"`
+		AddedTuples       []string `bson:"added_tuples"`
+		RemovedTuples     []string `bson:"removed_tuples"`
+	}
+)
+
+// DiffResults compares the beacons of an older and a newer dataset. The
+// tuples maps hold the destination port:protocol:service tuples of each
+// beacon keyed by the MapKey of its hosts and are only used if opts.Ports is
+// set. A beacon whose score changed by at least opts.MinScoreDelta and whose
+// tuples changed is reported once for each change. The changes are returned
+// sorted by the size of the score change, largest first.
+func DiffResults(before, after []Result, beforeTuples, afterTuples map[string][]string, opts DiffOptions) []DiffResult {
+	beforeMap := make(map[string]Result, len(before))
+	for _, b := range before {
+		beforeMap[b.UniqueIPPair.MapKey()] = b
+	}
+
+	var diffs []DiffResult
+	for _, a := range after {
+		key := a.UniqueIPPair.MapKey()
+		b, ok := beforeMap[key]
+		if !ok {
+			if opts.Destinations {
+				diffs = append(diffs, DiffResult{
+					UniqueIPPair: a.UniqueIPPair,
+					Change:       DiffAdded,
+					NewScore:     a.Score,
+					ScoreDelta:   a.Score,
+				})
+			}
+			continue
+		}
+		delete(beforeMap, key)
+
+		delta := a.Score - b.Score
+		if math.Abs(delta) >= opts.MinScoreDelta && delta != 0 {
+			diffs = append(diffs, DiffResult{
+				UniqueIPPair: a.UniqueIPPair,
+				Change:       DiffScoreChanged,
+				OldScore:     b.Score,
+				NewScore:     a.Score,
+				ScoreDelta:   delta,
+			})
+		}
+
+		if opts.Ports {
+			added, removed := diffTuples(beforeTuples[key], afterTuples[key])
+			if len(added) > 0 || len(removed) > 0 {
+				diffs = append(diffs, DiffResult{
+					UniqueIPPair:  a.UniqueIPPair,
+					Change:        DiffPortsChanged,
+					OldScore:      b.Score,
+					NewScore:      a.Score,
+					ScoreDelta:    delta,
+					AddedTuples:   added,
+					RemovedTuples: removed,
+				})
+			}
+		}
+	}
+
+	if opts.Destinations {
+		for _, b := range before {
+			if _, ok := beforeMap[b.UniqueIPPair.MapKey()]; !ok {
+				continue
+			}
+			diffs = append(diffs, DiffResult{
+				UniqueIPPair: b.UniqueIPPair,
+				Change:       DiffRemoved,
+				OldScore:     b.Score,
+				ScoreDelta:   -b.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(diffs, func(i, j int) bool {
+		return math.Abs(diffs[i].ScoreDelta) > math.Abs(diffs[j].ScoreDelta)
+	})
+	return diffs
+}
+
+// diffTuples returns the tuples which are only in after and only in before, sorted
+func diffTuples(before, after []string) (added []string, removed []string) {
+	beforeSet := make(map[string]bool, len(before))
+	for _, tuple := range before {
+		beforeSet[tuple] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, tuple := range after {
+		afterSet[tuple] = true
+		if !beforeSet[tuple] {
+			added = append(added, tuple)
+		}
+	}
+	for _, tuple := range before {
+		if !afterSet[tuple] {
+			removed = append(removed, tuple)
+		}
+	}
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
@@ -0,0 +1,93 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newDiffTestResult(dst string, score float64) Result {
+	return Result{
+		UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst}),
+		Score:        score,
+	}
+}
+
+func TestDiffResultsScoreDelta(t *testing.T) {
+	before := []Result{
+		newDiffTestResult("203.0.113.1", 0.5),
+		newDiffTestResult("203.0.113.2", 0.5),
+		newDiffTestResult("203.0.113.3", 0.9),
+		newDiffTestResult("203.0.113.4", 0.7),
+	}
+	after := []Result{
+		newDiffTestResult("203.0.113.1", 0.55), // below the threshold
+		newDiffTestResult("203.0.113.2", 0.8),  // above the threshold
+		newDiffTestResult("203.0.113.3", 0.6),  // above the threshold, decreasing
+		newDiffTestResult("203.0.113.4", 0.7),  // unchanged
+	}
+
+	diffs := DiffResults(before, after, nil, nil, DiffOptions{MinScoreDelta: 0.1})
+	require.Len(t, diffs, 2)
+
+	// the largest shifts are listed first
+	assert.Equal(t, "203.0.113.2", diffs[0].DstIP)
+	assert.Equal(t, DiffScoreChanged, diffs[0].Change)
+	assert.Equal(t, 0.5, diffs[0].OldScore)
+	assert.Equal(t, 0.8, diffs[0].NewScore)
+	assert.InDelta(t, 0.3, diffs[0].ScoreDelta, 1e-9)
+	assert.Equal(t, "203.0.113.3", diffs[1].DstIP)
+	assert.InDelta(t, -0.3, diffs[1].ScoreDelta, 1e-9)
+
+	// a higher threshold drops the smaller shifts
+	assert.Empty(t, DiffResults(before, after, nil, nil, DiffOptions{MinScoreDelta: 0.5}))
+
+	// a threshold of 0 reports every change but not unchanged beacons
+	assert.Len(t, DiffResults(before, after, nil, nil, DiffOptions{}), 3)
+}
+
+func TestDiffResultsDestinations(t *testing.T) {
+	before := []Result{
+		newDiffTestResult("203.0.113.1", 0.5),
+		newDiffTestResult("203.0.113.2", 0.4),
+	}
+	after := []Result{
+		newDiffTestResult("203.0.113.1", 0.5),
+		newDiffTestResult("203.0.113.3", 0.9),
+	}
+
+	assert.Empty(t, DiffResults(before, after, nil, nil, DiffOptions{MinScoreDelta: 0.1}),
+		"added and removed beacons should only be reported if requested")
+
+	diffs := DiffResults(before, after, nil, nil, DiffOptions{MinScoreDelta: 0.1, Destinations: true})
+	require.Len(t, diffs, 2)
+	assert.Equal(t, DiffAdded, diffs[0].Change)
+	assert.Equal(t, "203.0.113.3", diffs[0].DstIP)
+	assert.Equal(t, 0.9, diffs[0].NewScore)
+	assert.Equal(t, DiffRemoved, diffs[1].Change)
+	assert.Equal(t, "203.0.113.2", diffs[1].DstIP)
+	assert.Equal(t, 0.4, diffs[1].OldScore)
+}
+
+func TestDiffResultsPorts(t *testing.T) {
+	before := []Result{newDiffTestResult("203.0.113.1", 0.5)}
+	after := []Result{newDiffTestResult("203.0.113.1", 0.5)}
+	key := before[0].UniqueIPPair.MapKey()
+
+	beforeTuples := map[string][]string{key: {"443:tcp:ssl", "80:tcp:http"}}
+	afterTuples := map[string][]string{key: {"443:tcp:ssl", "8443:tcp:ssl"}}
+
+	assert.Empty(t, DiffResults(before, after, beforeTuples, afterTuples, DiffOptions{MinScoreDelta: 0.1}),
+		"tuple changes should only be reported if requested")
+
+	diffs := DiffResults(before, after, beforeTuples, afterTuples, DiffOptions{MinScoreDelta: 0.1, Ports: true})
+	require.Len(t, diffs, 1)
+	assert.Equal(t, DiffPortsChanged, diffs[0].Change)
+	assert.Equal(t, []string{"8443:tcp:ssl"}, diffs[0].AddedTuples)
+	assert.Equal(t, []string{"80:tcp:http"}, diffs[0].RemovedTuples)
+
+	// matching tuples are not a change
+	assert.Empty(t, DiffResults(before, after, beforeTuples, beforeTuples, DiffOptions{MinScoreDelta: 0.1, Ports: true}))
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/pkg/uconn"
 	"github.com/globalsign/mgo/bson"
 )
@@ -12,24 +13,29 @@ import (
 type (
 	//dissector gathers all of the unique connection details between pairs of hosts
 	dissector struct {
-		connLimit         int64              // limit for strobe classification
-		chunk             int                // current chunk (0 if not on rolling analysis)
-		db                *database.DB       // provides access to MongoDB
-		conf              *config.Config     // contains details needed to access MongoDB
-		dissectedCallback func(*uconn.Input) // gathered unique connection details are sent to this callback
-		closedCallback    func()             // called when .close() is called and no more calls to dissectedCallback will be made
-		dissectChannel    chan *uconn.Input  // holds data to be processed
-		dissectWg         sync.WaitGroup     // wait for dissector to finish
+		connLimit         int64                      // limit for strobe classification
+		chunk             int                        // current chunk (0 if not on rolling analysis)
+		db                *database.DB               // provides access to MongoDB
+		conf              *config.Config             // contains details needed to access MongoDB
+		overlapping       map[int]int                // overlap group of each chunk whose repeated connections are dropped (nil keeps every connection)
+		staleCallback     func(database.BulkChanges) // removes the beacons of pairs which are dropped (nil keeps them)
+		dissectedCallback func(*uconn.Input)         // gathered unique connection details are sent to this callback
+		closedCallback    func()                     // called when .close() is called and no more calls to dissectedCallback will be made
+		dissectChannel    chan *uconn.Input          // holds data to be processed
+		dissectWg         sync.WaitGroup             // wait for dissector to finish
 	}
 )
 
 // newDissector creates a new dissector for gathering data
-func newDissector(connLimit int64, chunk int, db *database.DB, conf *config.Config, dissectedCallback func(*uconn.Input), closedCallback func()) *dissector {
+func newDissector(connLimit int64, chunk int, db *database.DB, conf *config.Config, overlapping map[int]int,
+	staleCallback func(database.BulkChanges), dissectedCallback func(*uconn.Input), closedCallback func()) *dissector {
 	return &dissector{
 		connLimit:         connLimit,
 		chunk:             chunk,
 		db:                db,
 		conf:              conf,
+		overlapping:       overlapping,
+		staleCallback:     staleCallback,
 		dissectedCallback: dissectedCallback,
 		closedCallback:    closedCallback,
 		dissectChannel:    make(chan *uconn.Input),
@@ -78,27 +84,68 @@ func (d *dissector) start() {
 				{"$match": matchNoStrobeKey},
 				{"$limit": 1},
 				{"$project": bson.M{
-					"ts":     "$dat.ts",
-					"bytes":  "$dat.bytes",
-					"count":  "$dat.count",
-					"tbytes": "$dat.tbytes",
+					"ts":       "$dat.ts",
+					"rts":      "$dat.rts",
+					"bytes":    "$dat.bytes",
+					"rbytes":   "$dat.rbytes",
+					"durs":     "$dat.durs",
+					"uids":     "$dat.uids",
+					"oipbytes": "$dat.oipbytes",
+					"obytes":   "$dat.obytes",
+					"opkts":    "$dat.opkts",
+					"tuples":   "$dat.tuples",
+					"cids":     "$dat.cid",
+					// only the connections kept for beacon analysis are counted.
+					// Chunks imported before these were stored kept every connection.
+					"count": bson.M{"$map": bson.M{
+						"input": "$dat",
+						"in":    bson.M{"$ifNull": []interface{}{"$$this.bcount", "$$this.count"}},
+					}},
+					"tbytes": bson.M{"$map": bson.M{
+						"input": "$dat",
+						"in":    bson.M{"$ifNull": []interface{}{"$$this.btbytes", "$$this.tbytes"}},
+					}},
+					"ts_lens": bson.M{"$map": bson.M{
+						"input": "$dat.ts",
+						"in":    bson.M{"$size": bson.M{"$ifNull": []interface{}{"$$this", []interface{}{}}}},
+					}},
 				}},
 				{"$unwind": "$count"},
 				{"$group": bson.M{
-					"_id":    "$_id",
-					"ts":     bson.M{"$first": "$ts"},
-					"bytes":  bson.M{"$first": "$bytes"},
-					"count":  bson.M{"$sum": "$count"},
-					"tbytes": bson.M{"$first": "$tbytes"},
+					"_id":      "$_id",
+					"ts":       bson.M{"$first": "$ts"},
+					"rts":      bson.M{"$first": "$rts"},
+					"bytes":    bson.M{"$first": "$bytes"},
+					"rbytes":   bson.M{"$first": "$rbytes"},
+					"durs":     bson.M{"$first": "$durs"},
+					"uids":     bson.M{"$first": "$uids"},
+					"count":    bson.M{"$sum": "$count"},
+					"tbytes":   bson.M{"$first": "$tbytes"},
+					"oipbytes": bson.M{"$first": "$oipbytes"},
+					"obytes":   bson.M{"$first": "$obytes"},
+					"opkts":    bson.M{"$first": "$opkts"},
+					"tuples":   bson.M{"$first": "$tuples"},
+					"cids":     bson.M{"$first": "$cids"},
+					"ts_lens":  bson.M{"$first": "$ts_lens"},
 				}},
 				{"$match": bson.M{"count": bson.M{"$gt": d.conf.S.Beacon.DefaultConnectionThresh}}},
 				{"$unwind": "$tbytes"},
 				{"$group": bson.M{
-					"_id":    "$_id",
-					"ts":     bson.M{"$first": "$ts"},
-					"bytes":  bson.M{"$first": "$bytes"},
-					"count":  bson.M{"$first": "$count"},
-					"tbytes": bson.M{"$sum": "$tbytes"},
+					"_id":      "$_id",
+					"ts":       bson.M{"$first": "$ts"},
+					"rts":      bson.M{"$first": "$rts"},
+					"bytes":    bson.M{"$first": "$bytes"},
+					"rbytes":   bson.M{"$first": "$rbytes"},
+					"durs":     bson.M{"$first": "$durs"},
+					"uids":     bson.M{"$first": "$uids"},
+					"count":    bson.M{"$first": "$count"},
+					"tbytes":   bson.M{"$sum": "$tbytes"},
+					"oipbytes": bson.M{"$first": "$oipbytes"},
+					"obytes":   bson.M{"$first": "$obytes"},
+					"opkts":    bson.M{"$first": "$opkts"},
+					"tuples":   bson.M{"$first": "$tuples"},
+					"cids":     bson.M{"$first": "$cids"},
+					"ts_lens":  bson.M{"$first": "$ts_lens"},
 				}},
 				{"$unwind": "$ts"},
 				{"$unwind": "$ts"},
@@ -106,9 +153,19 @@ func (d *dissector) start() {
 					"_id":       "$_id",
 					"ts_unique": bson.M{"$addToSet": "$ts"},
 					"ts":        bson.M{"$push": "$ts"},
+					"rts":       bson.M{"$first": "$rts"},
 					"bytes":     bson.M{"$first": "$bytes"},
+					"rbytes":    bson.M{"$first": "$rbytes"},
+					"durs":      bson.M{"$first": "$durs"},
+					"uids":      bson.M{"$first": "$uids"},
 					"count":     bson.M{"$first": "$count"},
 					"tbytes":    bson.M{"$first": "$tbytes"},
+					"oipbytes":  bson.M{"$first": "$oipbytes"},
+					"obytes":    bson.M{"$first": "$obytes"},
+					"opkts":     bson.M{"$first": "$opkts"},
+					"tuples":    bson.M{"$first": "$tuples"},
+					"cids":      bson.M{"$first": "$cids"},
+					"ts_lens":   bson.M{"$first": "$ts_lens"},
 				}},
 				{"$unwind": "$bytes"},
 				{"$unwind": "$bytes"},
@@ -116,32 +173,56 @@ func (d *dissector) start() {
 					"_id":       "$_id",
 					"ts_unique": bson.M{"$first": "$ts_unique"},
 					"ts":        bson.M{"$first": "$ts"},
+					"rts":       bson.M{"$first": "$rts"},
 					"bytes":     bson.M{"$push": "$bytes"},
+					"rbytes":    bson.M{"$first": "$rbytes"},
+					"durs":      bson.M{"$first": "$durs"},
+					"uids":      bson.M{"$first": "$uids"},
 					"count":     bson.M{"$first": "$count"},
 					"tbytes":    bson.M{"$first": "$tbytes"},
+					"oipbytes":  bson.M{"$first": "$oipbytes"},
+					"obytes":    bson.M{"$first": "$obytes"},
+					"opkts":     bson.M{"$first": "$opkts"},
+					"tuples":    bson.M{"$first": "$tuples"},
+					"cids":      bson.M{"$first": "$cids"},
+					"ts_lens":   bson.M{"$first": "$ts_lens"},
 				}},
 				{"$project": bson.M{
 					"_id":           "$_id",
 					"ts_unique_len": bson.M{"$size": "$ts_unique"},
 					"ts":            1,
+					"rts":           1,
 					"bytes":         1,
+					"rbytes":        1,
+					"durs":          1,
+					"uids":          1,
 					"count":         1,
 					"tbytes":        1,
+					"oipbytes":      1,
+					"obytes":        1,
+					"opkts":         1,
+					"tuples":        1,
+					"cids":          1,
+					"ts_lens":       1,
 				}},
 			}
 
-			var res struct {
-				Count       int64   `bson:"count"`
-				TsUniqueLen int64   `bson:"ts_unique_len"`
-				Ts          []int64 `bson:"ts"`
-				Bytes       []int64 `bson:"bytes"`
-				TBytes      int64   `bson:"tbytes"`
-			}
-
+			var res uconnDat
 			_ = ssn.DB(d.db.GetSelectedDB()).C(d.conf.T.Structure.UniqueConnTable).Pipe(uconnFindQuery).AllowDiskUse().One(&res)
 
+			// overlapping chunks may have imported the same connections. The
+			// pipeline compared the count with the connection threshold before
+			// they were dropped, so the merged pair has to meet it again.
+			if d.overlapping != nil {
+				res.mergeOverlappingChunks(d.overlapping)
+				if res.Count <= int64(d.conf.S.Beacon.DefaultConnectionThresh) {
+					res.Count = 0
+				}
+			}
+
 			// Check for errors and parse results
 			// this is here because it will still return an empty document even if there are no results
+			dissected := false
 			if res.Count > 0 {
 
 				connection := &uconn.Input{
@@ -152,18 +233,64 @@ func (d *dissector) start() {
 				// avoid passing unnecessary data if conn is a strobe
 				if res.Count > d.connLimit {
 					d.dissectedCallback(connection)
+					dissected = true
 				} else {
 					// the analysis worker requires that we have over UNIQUE 3 timestamps
 					// we drop the input here since it is the earliest place in the pipeline to do so
 					if res.TsUniqueLen > 3 {
+						// res.Ts is built up chunk by chunk, so it is only in chronological
+						// order if the chunks were imported in order. The sorter orders the
+						// timestamps before the analyzer computes the intervals between them.
 						connection.TotalBytes = res.TBytes
 						connection.TsList = res.Ts
 						connection.UniqueTsListLength = res.TsUniqueLen
 						connection.OrigBytesList = res.Bytes
+						for _, respTs := range res.RespTs {
+							connection.RespTsList = append(connection.RespTsList, respTs...)
+						}
+						for _, respBytes := range res.RespBytes {
+							connection.RespBytesList = append(connection.RespBytesList, respBytes...)
+						}
+						for _, durations := range res.Durations {
+							connection.DurationList = append(connection.DurationList, durations...)
+						}
+						for _, uids := range res.UIDs {
+							connection.UIDs = append(connection.UIDs, uids...)
+						}
+						connection.Tuples = make(data.StringSet)
+						for _, tuples := range res.Tuples {
+							for _, tuple := range tuples {
+								connection.Tuples.Insert(tuple)
+							}
+						}
+						// chunks imported before packet counts were recorded
+						// are missing all three counts, so the totals add up
+						// the same chunks
+						for _, pkts := range res.OrigPkts {
+							connection.OrigPkts += pkts
+						}
+						for _, ipBytes := range res.OrigIPBytes {
+							connection.OrigIPBytes += ipBytes
+						}
+						for _, payloadBytes := range res.OrigBytes {
+							connection.OrigPayloadBytes += payloadBytes
+						}
 						d.dissectedCallback(connection)
+						dissected = true
 					}
 				}
 			}
+
+			// once connections are removed from a pair, its beacon is left
+			// over from data which is gone if the pair no longer qualifies
+			if !dissected && d.staleCallback != nil {
+				d.staleCallback(database.BulkChanges{
+					d.conf.T.Beacon.BeaconTable: []database.BulkChange{{
+						Selector: getPairSelector(d.conf, datum.Hosts),
+						Remove:   true,
+					}},
+				})
+			}
 		}
 
 		d.dissectWg.Done()
@@ -0,0 +1,71 @@
+package beacon
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/globalsign/mgo/bson"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingSink keeps the changes it receives
+type recordingSink struct {
+	lock    sync.Mutex
+	changes []database.BulkChanges
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Write(data database.BulkChanges) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.changes = append(s.changes, data)
+	return nil
+}
+
+func (s *recordingSink) Close() error { return nil }
+
+func TestDryRunRepositorySkipsWrites(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// the repository has no database, so any write would panic
+	dryRun := &recordingSink{}
+	r := NewDryRunRepository(nil, conf, logrus.New(), dryRun).(*repo)
+	assert.Nil(t, r.CreateIndexes())
+	assert.Nil(t, r.saveCheckpoint(nil, 0, 0))
+	assert.IsType(t, &database.SinkWriter{}, r.newWriter(nil))
+}
+
+func TestDryRunRepositoryHandsChangesToSink(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	count := 48
+	sizes := make([]int64, count)
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	input := newTestBeaconInput(tsMin, 1800, count, sizes, sizes)
+
+	dryRun := &recordingSink{}
+	writer := NewDryRunRepository(nil, conf, logrus.New(), dryRun).(*repo).newWriter(nil)
+	a := newAnalyzer(context.Background(), tsMin, tsMax, 0, nil, conf, nil, nil, nil, nil, writer.Collect, writer.Close)
+	writer.Start()
+	a.start()
+	a.collect(input)
+	a.close()
+
+	// the sink receives the same changes the analyzer would write to MongoDB
+	expected := analyzeTestUpdates(t, conf, tsMin, tsMax, 0, input)[0]
+	require.Len(t, dryRun.changes, 1)
+	changes := dryRun.changes[0][conf.T.Beacon.BeaconTable]
+	require.Len(t, changes, 1)
+	assert.True(t, changes[0].Upsert)
+	assert.Equal(t, expected, changes[0].Update)
+	assert.Greater(t, changes[0].Update.(bson.M)["$set"].(bson.M)["score"], 0.0)
+}
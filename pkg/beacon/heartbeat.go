@@ -0,0 +1,118 @@
+package beacon
+
+import (
+	"net"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/util"
+)
+
+// heartbeatClassifier labels high scoring beacons which look like benign
+// application heartbeats. Heartbeats such as NTP polls and health checks are
+// as regular as command and control beacons, but they send small payloads of
+// a constant size to well known services.
+type heartbeatClassifier struct {
+	minScore         float64
+	maxAvgBytes      int64
+	minDatasizeScore float64
+	services         [][]string // port, protocol, and optionally service of each known service
+	destinations     []*net.IPNet
+}
+
+// newHeartbeatClassifier creates a heartbeatClassifier from the Beacon
+// Heartbeat config section. The entries are checked when the config is
+// loaded, so entries which can't be parsed are skipped.
+func newHeartbeatClassifier(conf config.HeartbeatStaticCfg) *heartbeatClassifier {
+	h := &heartbeatClassifier{
+		minScore:         conf.MinScore,
+		maxAvgBytes:      conf.MaxAvgBytes,
+		minDatasizeScore: conf.MinDatasizeScore,
+	}
+	for _, service := range conf.KnownServices {
+		h.services = append(h.services, util.ParseService(service))
+	}
+	h.destinations = parseDestinations(conf.KnownDestinations)
+	return h
+}
+
+// likelyHeartbeat reports whether a beacon to dst over the given
+// port:protocol:service tuples is likely an application heartbeat. The beacon
+// must score at least the minimum score, its connections must be small and
+// of a consistent size, and it must either go to a known destination or use
+// known services exclusively.
+func (h *heartbeatClassifier) likelyHeartbeat(dst string, tuples []string, avgBytes int64, dsScore, score float64) bool {
+	if score < h.minScore || avgBytes > h.maxAvgBytes || dsScore < h.minDatasizeScore {
+		return false
+	}
+
+	if containsDestination(h.destinations, dst) {
+		return true
+	}
+
+	if len(tuples) == 0 {
+		return false
+	}
+	for _, tuple := range tuples {
+		if !h.knownService(tuple) {
+			return false
+		}
+	}
+	return true
+}
+
+// knownService reports whether a port:protocol:service tuple matches one of
+// the known services. Known services without a service name match the port
+// and protocol regardless of the service Zeek identified.
+func (h *heartbeatClassifier) knownService(tuple string) bool {
+	for _, service := range h.services {
+		if util.MatchService(service, tuple) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseDestinations parses CIDR ranges and IP addresses into subnets. Single
+// addresses become subnets holding only that address. Entries which can't be
+// parsed are skipped.
+func parseDestinations(entries []string) []*net.IPNet {
+	var subnets []*net.IPNet
+	for _, dst := range entries {
+		if _, subnet, err := net.ParseCIDR(dst); err == nil {
+			subnets = append(subnets, subnet)
+		} else if ip := net.ParseIP(dst); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			subnets = append(subnets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+		}
+	}
+	return subnets
+}
+
+// containsDestination reports whether the address dst is in one of subnets
+func containsDestination(subnets []*net.IPNet, dst string) bool {
+	ip := net.ParseIP(dst)
+	if ip == nil {
+		return false
+	}
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterHeartbeats returns the beacons which were not marked as likely
+// application heartbeats
+func FilterHeartbeats(beacons []Result) []Result {
+	var filtered []Result
+	for _, b := range beacons {
+		if !b.LikelyHeartbeat {
+			filtered = append(filtered, b)
+		}
+	}
+	return filtered
+}
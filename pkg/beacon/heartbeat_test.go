@@ -0,0 +1,96 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testHeartbeatConf = config.HeartbeatStaticCfg{
+	Enabled:           true,
+	MinScore:          0.8,
+	MaxAvgBytes:       512,
+	MinDatasizeScore:  0.9,
+	KnownServices:     []string{"123:udp", "8443:tcp:http"},
+	KnownDestinations: []string{"10.10.0.0/16", "192.0.2.7"},
+}
+
+func TestLikelyHeartbeat(t *testing.T) {
+	h := newHeartbeatClassifier(testHeartbeatConf)
+
+	testCases := []struct {
+		msg       string
+		dst       string
+		tuples    []string
+		avgBytes  int64
+		dsScore   float64
+		score     float64
+		heartbeat bool
+	}{
+		{"ntp poll", "203.0.113.1", []string{"123:udp:ntp"}, 90, 1, 0.95, true},
+		{"ntp without an identified service", "203.0.113.1", []string{"123:udp:-"}, 90, 1, 0.95, true},
+		{"c2 over tls", "203.0.113.1", []string{"443:tcp:ssl"}, 300, 1, 0.95, false},
+		{"known service alongside an unknown one", "203.0.113.1", []string{"123:udp:ntp", "443:tcp:ssl"}, 90, 1, 0.95, false},
+		{"service name must match when listed", "203.0.113.1", []string{"8443:tcp:ssl"}, 90, 1, 0.95, false},
+		{"service name matching is case insensitive", "203.0.113.1", []string{"8443:TCP:HTTP"}, 90, 1, 0.95, true},
+		{"health check to a known subnet", "10.10.4.2", []string{"443:tcp:ssl"}, 200, 0.95, 0.9, true},
+		{"health check to a known address", "192.0.2.7", []string{"443:tcp:ssl"}, 200, 0.95, 0.9, true},
+		{"large payloads", "203.0.113.1", []string{"123:udp:ntp"}, 4096, 1, 0.95, false},
+		{"varying payloads", "203.0.113.1", []string{"123:udp:ntp"}, 90, 0.6, 0.95, false},
+		{"low score", "10.10.4.2", []string{"123:udp:ntp"}, 90, 1, 0.5, false},
+		{"no tuples", "203.0.113.1", nil, 90, 1, 0.95, false},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.heartbeat, h.likelyHeartbeat(test.dst, test.tuples, test.avgBytes, test.dsScore, test.score), test.msg)
+	}
+}
+
+func TestAnalyzerLikelyHeartbeat(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Beacon.Heartbeat = testHeartbeatConf
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	// both beacons connect every 30 minutes with 48 byte requests and
+	// responses, but only one of them goes to NTP
+	newInput := func(dst string, tuple string) *uconn.Input {
+		sizes := make([]int64, 48)
+		for i := range sizes {
+			sizes[i] = 48
+		}
+		input := newTestBeaconInput(tsMin, 1800, len(sizes), sizes, sizes)
+		input.Hosts = data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst})
+		input.Tuples = data.StringSet{tuple: struct{}{}}
+		return input
+	}
+
+	results := analyzeTestInputs(t, conf, tsMin, tsMax,
+		newInput("203.0.113.1", "123:udp:ntp"),
+		newInput("203.0.113.2", "443:tcp:ssl"),
+	)
+
+	require.Greater(t, results[0]["score"], 0.8)
+	require.Greater(t, results[1]["score"], 0.8)
+	assert.Equal(t, true, results[0]["likely_heartbeat"])
+	assert.Equal(t, false, results[1]["likely_heartbeat"], "a c2-like beacon over tls should not be a heartbeat")
+
+	// the classification is skipped when disabled
+	conf.S.Beacon.Heartbeat.Enabled = false
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, newInput("203.0.113.1", "123:udp:ntp"))
+	assert.NotContains(t, results[0], "likely_heartbeat")
+}
+
+func TestFilterHeartbeats(t *testing.T) {
+	beacons := []Result{
+		{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "203.0.113.1"}), LikelyHeartbeat: true},
+		{UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "203.0.113.2"})},
+	}
+	assert.Equal(t, []Result{beacons[1]}, FilterHeartbeats(beacons))
+}
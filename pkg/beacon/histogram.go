@@ -0,0 +1,115 @@
+package beacon
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo/bson"
+)
+
+// HistogramBucket counts the beacons scoring at least Min and below Max. The
+// last bucket of a histogram is open-ended and also counts the beacons scoring
+// Max or above.
+type HistogramBucket struct {
+	Min   float64
+	Max   float64
+	Count int
+}
+
+// histogramCount is the number of beacons MongoDB placed in the bucket
+// starting at ID
+type histogramCount struct {
+	ID    float64 `bson:"_id"`
+	Count int     `bson:"count"`
+}
+
+// HistogramBoundaries splits the scores from 0 to 1 into the given number of
+// equally sized buckets and returns the edges of the buckets
+func HistogramBoundaries(buckets int) ([]float64, error) {
+	if buckets < 1 {
+		return nil, fmt.Errorf("invalid bucket count %d: must be at least 1", buckets)
+	}
+
+	boundaries := make([]float64, buckets+1)
+	for i := range boundaries {
+		boundaries[i] = float64(i) / float64(buckets)
+	}
+	return boundaries, nil
+}
+
+// ScoreHistogram counts the beacons scoring within each of the buckets marked
+// by the given boundaries. Beacons scoring below the first boundary are not
+// counted, while those scoring above the last boundary are counted in the last
+// bucket so that no beacon above the first boundary goes missing.
+func ScoreHistogram(res *resources.Resources, boundaries []float64) ([]HistogramBucket, error) {
+	if err := validateHistogramBoundaries(boundaries); err != nil {
+		return nil, err
+	}
+
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var counts []histogramCount
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Beacon.BeaconTable).
+		Pipe(scoreHistogramQuery(boundaries)).AllowDiskUse().All(&counts)
+	if err != nil {
+		return nil, err
+	}
+
+	return newHistogram(boundaries, counts), nil
+}
+
+// validateHistogramBoundaries checks that the boundaries mark at least one
+// bucket of scores between 0 and 1
+func validateHistogramBoundaries(boundaries []float64) error {
+	if len(boundaries) < 2 {
+		return fmt.Errorf("invalid histogram boundaries %v: must mark at least one bucket", boundaries)
+	}
+	for i, boundary := range boundaries {
+		if boundary < 0 || boundary > 1 {
+			return fmt.Errorf("invalid histogram boundary %v: must be between 0 and 1", boundary)
+		}
+		if i > 0 && boundary <= boundaries[i-1] {
+			return fmt.Errorf("invalid histogram boundaries %v: must be increasing", boundaries)
+		}
+	}
+	return nil
+}
+
+// scoreHistogramQuery counts the beacons in each bucket. MongoDB excludes the
+// upper boundary from the last bucket, so the beacons scoring the upper boundary
+// or above are counted under the default bucket of that boundary instead.
+func scoreHistogramQuery(boundaries []float64) []bson.M {
+	upper := boundaries[len(boundaries)-1]
+	return []bson.M{
+		{"$match": bson.M{"score": bson.M{"$gte": boundaries[0]}}},
+		{"$bucket": bson.M{
+			"groupBy":    "$score",
+			"boundaries": boundaries,
+			"default":    upper,
+			"output":     bson.M{"count": bson.M{"$sum": 1}},
+		}},
+	}
+}
+
+// newHistogram creates a bucket for each pair of boundaries holding the counts
+// MongoDB returned. Buckets without beacons are left out by MongoDB and are
+// given a count of 0.
+func newHistogram(boundaries []float64, counts []histogramCount) []HistogramBucket {
+	buckets := make([]HistogramBucket, len(boundaries)-1)
+	for i := range buckets {
+		buckets[i] = HistogramBucket{Min: boundaries[i], Max: boundaries[i+1]}
+	}
+
+	for _, count := range counts {
+		for i := range buckets {
+			last := i == len(buckets)-1
+			if count.ID == buckets[i].Min || (last && count.ID == buckets[i].Max) {
+				buckets[i].Count += count.Count
+				break
+			}
+		}
+	}
+	return buckets
+}
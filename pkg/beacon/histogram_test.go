@@ -0,0 +1,152 @@
+package beacon
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runHistogramQuery applies the histogram query to the given scores the same
+// way MongoDB would and returns the counts of the buckets holding beacons
+func runHistogramQuery(t *testing.T, scores []float64, boundaries []float64) []histogramCount {
+	query := scoreHistogramQuery(boundaries)
+	require.Len(t, query, 2)
+	match := query[0]["$match"].(bson.M)["score"].(bson.M)
+	bucket := query[1]["$bucket"].(bson.M)
+	edges := bucket["boundaries"].([]float64)
+	def := bucket["default"].(float64)
+
+	counts := make(map[float64]int)
+	for _, score := range scores {
+		if score < match["$gte"].(float64) {
+			continue
+		}
+		id := def
+		for i := 0; i < len(edges)-1; i++ {
+			if score >= edges[i] && score < edges[i+1] {
+				id = edges[i]
+				break
+			}
+		}
+		counts[id]++
+	}
+
+	var results []histogramCount
+	for id, count := range counts {
+		results = append(results, histogramCount{ID: id, Count: count})
+	}
+	return results
+}
+
+func bucketCounts(buckets []HistogramBucket) []int {
+	counts := make([]int, len(buckets))
+	for i, bucket := range buckets {
+		counts[i] = bucket.Count
+	}
+	return counts
+}
+
+func TestHistogramBoundaries(t *testing.T) {
+	boundaries, err := HistogramBoundaries(4)
+	require.Nil(t, err)
+	assert.Equal(t, []float64{0, 0.25, 0.5, 0.75, 1}, boundaries)
+
+	boundaries, err = HistogramBoundaries(10)
+	require.Nil(t, err)
+	assert.Len(t, boundaries, 11)
+	assert.Equal(t, 1.0, boundaries[10])
+
+	_, err = HistogramBoundaries(0)
+	assert.NotNil(t, err)
+}
+
+func TestValidateHistogramBoundaries(t *testing.T) {
+	assert.Nil(t, validateHistogramBoundaries([]float64{0, 1}))
+	assert.Nil(t, validateHistogramBoundaries([]float64{0.5, 0.8, 0.9, 1}))
+
+	assert.NotNil(t, validateHistogramBoundaries([]float64{0.5}))
+	assert.NotNil(t, validateHistogramBoundaries([]float64{0, 0.5, 0.5, 1}))
+	assert.NotNil(t, validateHistogramBoundaries([]float64{0, 0.8, 0.5}))
+	assert.NotNil(t, validateHistogramBoundaries([]float64{-0.1, 1}))
+	assert.NotNil(t, validateHistogramBoundaries([]float64{0, 1.5}))
+}
+
+func TestScoreHistogram(t *testing.T) {
+	scores := []float64{
+		0, 0.05, 0.099, // first bucket
+		0.1, // boundaries start the next bucket
+		0.55, 0.59,
+		0.91, 0.95, 0.999, 1, // the last bucket holds the top score
+	}
+	boundaries, err := HistogramBoundaries(10)
+	require.Nil(t, err)
+
+	histogram := newHistogram(boundaries, runHistogramQuery(t, scores, boundaries))
+	require.Len(t, histogram, 10)
+	assert.Equal(t, []int{3, 1, 0, 0, 0, 2, 0, 0, 0, 4}, bucketCounts(histogram))
+	assert.Equal(t, HistogramBucket{Min: 0.9, Max: 1, Count: 4}, histogram[9])
+}
+
+func TestScoreHistogramCustomBoundaries(t *testing.T) {
+	scores := []float64{0.1, 0.4, 0.5, 0.75, 0.8, 0.85, 0.9, 0.97, 1}
+
+	// scores below the lowest boundary are not counted
+	boundaries := []float64{0.5, 0.8, 0.9, 1}
+	histogram := newHistogram(boundaries, runHistogramQuery(t, scores, boundaries))
+	assert.Equal(t, []int{2, 2, 3}, bucketCounts(histogram))
+
+	// while the last bucket also holds the scores above the highest boundary
+	boundaries = []float64{0, 0.5, 0.9}
+	histogram = newHistogram(boundaries, runHistogramQuery(t, scores, boundaries))
+	assert.Equal(t, []int{2, 7}, bucketCounts(histogram))
+}
+
+func TestScoreHistogramAboveOne(t *testing.T) {
+	// scores above 1 land in the last bucket rather than going missing
+	scores := []float64{0.2, 0.95, 1, 1.2}
+	boundaries, err := HistogramBoundaries(2)
+	require.Nil(t, err)
+
+	histogram := newHistogram(boundaries, runHistogramQuery(t, scores, boundaries))
+	assert.Equal(t, []int{1, 3}, bucketCounts(histogram))
+}
+
+func TestScoreHistogramSeeded(t *testing.T) {
+	// most beacons score low with a cluster of high scoring beacons
+	rng := rand.New(rand.NewSource(265))
+	var scores []float64
+	for i := 0; i < 900; i++ {
+		scores = append(scores, rng.Float64()*0.5)
+	}
+	for i := 0; i < 100; i++ {
+		scores = append(scores, 0.9+rng.Float64()*0.1)
+	}
+
+	boundaries, err := HistogramBoundaries(10)
+	require.Nil(t, err)
+	histogram := newHistogram(boundaries, runHistogramQuery(t, scores, boundaries))
+
+	expected := make([]int, 10)
+	for _, score := range scores {
+		index := int(score * 10)
+		if index == 10 {
+			index = 9
+		}
+		expected[index]++
+	}
+	assert.Equal(t, expected, bucketCounts(histogram))
+
+	// the natural cutoff shows up as empty buckets between the clusters
+	counts := bucketCounts(histogram)
+	assert.Equal(t, 900, counts[0]+counts[1]+counts[2]+counts[3]+counts[4])
+	assert.Equal(t, []int{0, 0, 0, 0}, counts[5:9])
+	assert.Equal(t, 100, counts[9])
+}
+
+func TestNewHistogramEmpty(t *testing.T) {
+	histogram := newHistogram([]float64{0, 0.5, 1}, nil)
+	assert.Equal(t, []HistogramBucket{{Min: 0, Max: 0.5}, {Min: 0.5, Max: 1}}, histogram)
+}
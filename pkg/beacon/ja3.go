@@ -0,0 +1,154 @@
+package beacon
+
+import (
+	"sort"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo/bson"
+)
+
+// missingJA3 is recorded by the parser when Zeek did not generate a JA3 hash
+const missingJA3 = "No JA3 hash generated"
+
+type (
+	// JA3Result groups the beacons made by TLS clients sharing a JA3 fingerprint.
+	// One fingerprint beaconing to many destinations may be a single malware
+	// family using several command and control servers.
+	JA3Result struct {
+		JA3         string
+		Beacons     []Result // sorted by score
+		SrcCount    int
+		DstCount    int
+		Connections int64
+		Score       float64 // highest score among the beacons
+	}
+
+	// pairJA3s holds the client JA3 hashes seen in the TLS sessions between a pair of hosts
+	pairJA3s struct {
+		data.UniqueIPPair `bson:",inline"`
+		JA3s              []string `bson:"ja3"`
+	}
+)
+
+// JA3Results groups the beacons in the database by the client JA3 hashes
+// recorded for the TLS sessions between their hosts. Beacons without a JA3
+// are left out.
+func JA3Results(res *resources.Resources) ([]JA3Result, error) {
+	beacons, err := Results(res, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs, err := pairJA3Results(res)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupByJA3(beacons, pairs), nil
+}
+
+// pairJA3Results gathers the client JA3 hashes seen between each source and
+// each IP address which responded to it over TLS
+func pairJA3Results(res *resources.Resources) ([]pairJA3s, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var pairs []pairJA3s
+
+	pairQuery := []bson.M{
+		{"$match": bson.M{"dat.tls.ja3": bson.M{"$exists": true}}},
+		{"$project": bson.M{
+			"src":              1,
+			"src_network_uuid": 1,
+			"tls":              "$dat.tls",
+		}},
+		{"$unwind": "$tls"},
+		{"$unwind": "$tls.dst_ips"},
+		{"$unwind": "$tls.ja3"},
+		{"$group": bson.M{
+			"_id": bson.M{
+				"src":              "$src",
+				"src_network_uuid": "$src_network_uuid",
+				"dst":              "$tls.dst_ips.ip",
+				"dst_network_uuid": "$tls.dst_ips.network_uuid",
+			},
+			"ja3": bson.M{"$addToSet": "$tls.ja3"},
+		}},
+		{"$project": bson.M{
+			"_id":              0,
+			"src":              "$_id.src",
+			"src_network_uuid": "$_id.src_network_uuid",
+			"dst":              "$_id.dst",
+			"dst_network_uuid": "$_id.dst_network_uuid",
+			"ja3":              1,
+		}},
+	}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.SNIConnTable).Pipe(pairQuery).AllowDiskUse().All(&pairs)
+
+	return pairs, err
+}
+
+// groupByJA3 groups the beacons by the JA3 hashes seen between their hosts. A
+// beacon is listed under each JA3 seen between its hosts. The groups are
+// returned sorted by the number of destinations, then by Score.
+func groupByJA3(beacons []Result, pairs []pairJA3s) []JA3Result {
+	ja3sByPair := make(map[string][]string, len(pairs))
+	for _, pair := range pairs {
+		key := pair.MapKey()
+		ja3sByPair[key] = append(ja3sByPair[key], pair.JA3s...)
+	}
+
+	var groups []*JA3Result
+	index := make(map[string]int)
+	srcs := make(map[string]map[string]bool)
+	dsts := make(map[string]map[string]bool)
+
+	for _, beacon := range beacons {
+		// a JA3 may be listed by several chunks
+		seen := make(map[string]bool)
+		for _, ja3 := range ja3sByPair[beacon.MapKey()] {
+			if ja3 == "" || ja3 == missingJA3 || seen[ja3] {
+				continue
+			}
+			seen[ja3] = true
+
+			i, ok := index[ja3]
+			if !ok {
+				i = len(groups)
+				index[ja3] = i
+				groups = append(groups, &JA3Result{JA3: ja3})
+				srcs[ja3] = make(map[string]bool)
+				dsts[ja3] = make(map[string]bool)
+			}
+
+			group := groups[i]
+			group.Beacons = append(group.Beacons, beacon)
+			group.Connections += beacon.Connections
+			if beacon.Score > group.Score {
+				group.Score = beacon.Score
+			}
+			srcs[ja3][beacon.UniqueSrcIP.Unpair().MapKey()] = true
+			dsts[ja3][beacon.UniqueDstIP.Unpair().MapKey()] = true
+		}
+	}
+
+	results := make([]JA3Result, 0, len(groups))
+	for _, group := range groups {
+		group.SrcCount = len(srcs[group.JA3])
+		group.DstCount = len(dsts[group.JA3])
+		sort.SliceStable(group.Beacons, func(i, j int) bool {
+			return group.Beacons[i].Score > group.Beacons[j].Score
+		})
+		results = append(results, *group)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].DstCount != results[j].DstCount {
+			return results[i].DstCount > results[j].DstCount
+		}
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
@@ -0,0 +1,81 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPairJA3s(src, dst string, ja3s ...string) pairJA3s {
+	return pairJA3s{
+		UniqueIPPair: data.NewUniqueIPPair(data.UniqueIP{IP: src}, data.UniqueIP{IP: dst}),
+		JA3s:         ja3s,
+	}
+}
+
+func TestGroupByJA3(t *testing.T) {
+	beacons := []Result{
+		newTestCIDRBeacon("10.0.0.1", "52.10.4.7", 100, 1000, 60, 0.9, 0.8),
+		newTestCIDRBeacon("10.0.0.2", "198.51.100.3", 300, 3000, 300, 0.5, 0.95),
+		newTestCIDRBeacon("10.0.0.1", "203.0.113.9", 50, 500, 30, 0.7, 0.7),
+		newTestCIDRBeacon("10.0.0.3", "52.10.4.7", 40, 400, 10, 0.4, 0.6),
+		// no TLS sessions were seen between these hosts
+		newTestCIDRBeacon("10.0.0.4", "192.0.2.1", 20, 200, 5, 0.3, 0.99),
+	}
+
+	pairs := []pairJA3s{
+		newTestPairJA3s("10.0.0.1", "52.10.4.7", "malware"),
+		newTestPairJA3s("10.0.0.2", "198.51.100.3", "malware", "browser"),
+		newTestPairJA3s("10.0.0.1", "203.0.113.9", "malware", missingJA3),
+		newTestPairJA3s("10.0.0.3", "52.10.4.7", "browser", "browser"),
+		// pairs without a beacon are ignored
+		newTestPairJA3s("10.0.0.9", "52.10.4.7", "malware"),
+	}
+
+	results := groupByJA3(beacons, pairs)
+	require.Len(t, results, 2)
+
+	// the fingerprint beaconing to the most destinations comes first
+	malware := results[0]
+	assert.Equal(t, "malware", malware.JA3)
+	assert.Equal(t, 3, malware.DstCount)
+	assert.Equal(t, 2, malware.SrcCount)
+	assert.Equal(t, int64(450), malware.Connections)
+	assert.Equal(t, 0.95, malware.Score)
+	require.Len(t, malware.Beacons, 3)
+	assert.Equal(t, "198.51.100.3", malware.Beacons[0].DstIP, "beacons should be sorted by score")
+	assert.Equal(t, "52.10.4.7", malware.Beacons[1].DstIP)
+	assert.Equal(t, "203.0.113.9", malware.Beacons[2].DstIP)
+
+	// a beacon is listed under every JA3 seen between its hosts, once per JA3
+	browser := results[1]
+	assert.Equal(t, "browser", browser.JA3)
+	assert.Equal(t, 2, browser.DstCount)
+	assert.Equal(t, 2, browser.SrcCount)
+	require.Len(t, browser.Beacons, 2)
+	assert.Equal(t, 0.95, browser.Score)
+}
+
+func TestGroupByJA3MultipleChunks(t *testing.T) {
+	beacons := []Result{
+		newTestCIDRBeacon("10.0.0.1", "52.10.4.7", 100, 1000, 60, 0.9, 0.8),
+	}
+
+	// the same pair may be reported more than once
+	pairs := []pairJA3s{
+		newTestPairJA3s("10.0.0.1", "52.10.4.7", "malware"),
+		newTestPairJA3s("10.0.0.1", "52.10.4.7", "malware", "other"),
+	}
+
+	results := groupByJA3(beacons, pairs)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		assert.Len(t, result.Beacons, 1, "JA3 %s should list the beacon once", result.JA3)
+		assert.Equal(t, int64(100), result.Connections)
+	}
+
+	// nothing is grouped without JA3 hashes
+	assert.Empty(t, groupByJA3(beacons, nil))
+}
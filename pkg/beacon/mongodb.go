@@ -1,17 +1,22 @@
 package beacon
 
 import (
+	"context"
 	"fmt"
 	"runtime"
+	"time"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/cloud"
 	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/scorer"
 	"github.com/activecm/rita/pkg/uconn"
 	"github.com/activecm/rita/util"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/vbauerster/mpb"
 	"github.com/vbauerster/mpb/decor"
 
@@ -22,6 +27,7 @@ type repo struct {
 	database *database.DB
 	config   *config.Config
 	log      *log.Logger
+	dryRun   database.Sink // receives the changes instead of MongoDB if set
 }
 
 // NewMongoRepository create new repository
@@ -33,7 +39,34 @@ func NewMongoRepository(db *database.DB, conf *config.Config, logger *log.Logger
 	}
 }
 
+// NewDryRunRepository creates a repository which runs the same beacon analysis as
+// the MongoDB repository but hands the changes it would make to sink instead of
+// writing them. The unique connections are still read from MongoDB. Indexes,
+// checkpoints, and the summaries of the local hosts are skipped since they
+// depend on the changes being written.
+func NewDryRunRepository(db *database.DB, conf *config.Config, logger *log.Logger, sink database.Sink) Repository {
+	return &repo{
+		database: db,
+		config:   conf,
+		log:      logger,
+		dryRun:   sink,
+	}
+}
+
+// newWriter creates the writer which receives the changes of the analysis.
+// The failed MongoDB writes are handed to report.
+func (r *repo) newWriter(report func(error)) database.Writer {
+	if r.dryRun != nil {
+		return database.NewSinkWriter(r.dryRun, r.log, "beacon")
+	}
+	return database.NewReportingWriter(r.database, r.config, r.log, true, "beacon", report)
+}
+
 func (r *repo) CreateIndexes() error {
+	if r.dryRun != nil {
+		return nil
+	}
+
 	session := r.database.Session.Copy()
 	defer session.Close()
 
@@ -59,6 +92,12 @@ func (r *repo) CreateIndexes() error {
 		{Key: []string{"-connection_count"}},
 	}
 
+	// beacons are only selected by their pair hash if it is enabled. The index
+	// is sparse so that documents without a hash never collide on null.
+	if r.config.S.Beacon.HashPairKeys {
+		indexes = append(indexes, mgo.Index{Key: []string{"pair_hash"}, Unique: true, Sparse: true})
+	}
+
 	// create collection
 	err := r.database.CreateCollection(collectionName, indexes)
 	if err != nil {
@@ -69,29 +108,253 @@ func (r *repo) CreateIndexes() error {
 }
 
 // Upsert derives beacon statistics from the given unique connections and creates summaries
-// for the given local hosts. The results are pushed to MongoDB.
-func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
+// for the given local hosts. The results are pushed to MongoDB. The analysis stops early
+// if ctx is cancelled. Returns an error if any unique connection could not be analyzed
+// or any result could not be written. The remaining results are still written.
+func (r *repo) Upsert(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) error {
+	_, err := r.analyze(ctx, uconnMap, hostMap, minTimestamp, maxTimestamp, r.config.S.Rolling.CurrentChunk, time.Time{}, false)
+	return err
+}
 
-	//Create the workers
-	writerWorker := database.NewBulkWriter(
-		r.database,
-		r.config,
-		r.log,
-		true,
-		"beacon",
-	)
+// Refresh works like Upsert for unique connections whose data was partly removed,
+// such as when a chunk is evicted from a rolling database. The beacons of pairs
+// which no longer qualify for the analysis are removed.
+func (r *repo) Refresh(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) error {
+	_, err := r.analyze(ctx, uconnMap, hostMap, minTimestamp, maxTimestamp, r.config.S.Rolling.CurrentChunk, time.Time{}, true)
+	return err
+}
+
+// UpsertUntil works like Upsert but stops sending unique connections to analysis once the
+// deadline passes. The unique connections which were not analyzed are saved to a checkpoint
+// so that Resume can finish the analysis later. Returns true if every unique connection was analyzed.
+// If ctx is cancelled, nothing is checkpointed and the context's error is returned. Like Upsert,
+// an error is also returned if any unique connection could not be analyzed or written.
+func (r *repo) UpsertUntil(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input,
+	minTimestamp, maxTimestamp int64, deadline time.Time) (bool, error) {
+
+	pending, analysisErr := r.analyze(ctx, uconnMap, hostMap, minTimestamp, maxTimestamp, r.config.S.Rolling.CurrentChunk, deadline, false)
+	// the analyzer drops the unique connections it was given once ctx is cancelled,
+	// so the pending unique connections are not all that is left to analyze
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	if len(pending) == 0 {
+		return true, analysisErr
+	}
+
+	fmt.Printf("\t[!] Beacon analysis reached the maximum runtime with %d unique connections remaining\n", len(pending))
+	if err := r.saveCheckpoint(pending, minTimestamp, maxTimestamp); err != nil {
+		return false, err
+	}
+	return false, analysisErr
+}
+
+// HasCheckpoint returns true if a previous beacon analysis stopped before it finished
+func (r *repo) HasCheckpoint() (bool, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	count, err := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Beacon.BeaconCheckpointTable).Count()
+	return count > 0, err
+}
+
+// Resume analyzes the unique connections saved to the checkpoint by UpsertUntil, stopping
+// again if the deadline passes. The beacons are written to the chunk each unique connection
+// was imported in. Each checkpoint record is only removed once the analysis of its unique
+// connection has been written, so the records of unique connections which are still pending
+// are kept for the next resume. Returns true if no unique connections remain to be analyzed.
+// If ctx is cancelled, the checkpoint is kept as it was and the context's error is returned.
+// If a unique connection could not be analyzed or written, the records of its group are kept
+// and the error is returned.
+func (r *repo) Resume(ctx context.Context, deadline time.Time) (bool, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	checkpoint := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Beacon.BeaconCheckpointTable)
+
+	var entries []checkpointEntry
+	err := checkpoint.Find(nil).All(&entries)
+	if err != nil {
+		return false, err
+	}
+	if len(entries) == 0 {
+		return true, nil
+	}
+
+	fmt.Printf("\t[-] Resuming beacon analysis of %d unique connections\n", len(entries))
+
+	finished := true
+	for _, group := range restoreCheckpoint(entries) {
+		if !deadline.IsZero() && !time.Now().Before(deadline) {
+			finished = false
+			break
+		}
+
+		// analyze only returns once the writer has written every change
+		pending, analysisErr := r.analyze(ctx, group.uconnMap, group.hostMap, group.minTimestamp, group.maxTimestamp, group.chunk, deadline, false)
+		if err := ctx.Err(); err != nil {
+			return false, err
+		}
+		if analysisErr != nil {
+			return false, analysisErr
+		}
+		if len(pending) > 0 {
+			finished = false
+		}
+
+		selectors := group.analyzedSelectors(pending)
+		if r.dryRun != nil || len(selectors) == 0 {
+			continue
+		}
+		bulk := checkpoint.Bulk()
+		bulk.Unordered()
+		for _, selector := range selectors {
+			bulk.RemoveAll(selector)
+		}
+		if _, err := bulk.Run(); err != nil {
+			return false, err
+		}
+	}
+
+	if !finished {
+		fmt.Println("\t[!] Beacon analysis reached the maximum runtime again, the rest of the checkpoint is kept")
+	}
+	return finished, nil
+}
 
-	analyzerWorker := newAnalyzer(
+// Rescore analyzes every unique connection stored in the dataset again and
+// overwrites the results of the existing beacons. The unique connections are
+// read from MongoDB rather than from parsed logs, so scoring changes can be
+// applied without importing the logs again.
+func (r *repo) Rescore(minTimestamp, maxTimestamp int64) error {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	uconnMap := make(map[string]*uconn.Input)
+	hostMap := make(map[string]*host.Input)
+	internal := util.ParseSubnets(r.config.S.Filtering.InternalSubnets)
+
+	addLocalHost := func(ip data.UniqueIP) {
+		hostMap[ip.MapKey()] = &host.Input{Host: ip, IsLocal: true}
+	}
+
+	// strobes are skipped by the dissector, so there's no need to read them
+	var stored uconn.StoredConnection
+	iter := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.UniqueConnTable).
+		Find(bson.M{"strobe": bson.M{"$ne": true}}).Select(uconn.StoredConnectionFields).Iter()
+
+	for iter.Next(&stored) {
+		input := stored.Input(internal)
+		uconnMap[input.Hosts.MapKey()] = input
+		if input.IsLocalSrc {
+			addLocalHost(input.Hosts.UniqueSrcIP.Unpair())
+		}
+		if input.IsLocalDst {
+			addLocalHost(input.Hosts.UniqueDstIP.Unpair())
+		}
+		stored = uconn.StoredConnection{}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	// the new scores replace the score history of the current chunk
+	if r.config.S.Beacon.ScoreHistory && r.dryRun == nil {
+		chunk := r.config.S.Rolling.CurrentChunk
+		_, err := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Beacon.BeaconTable).UpdateAll(
+			bson.M{"score_history.cid": chunk},
+			bson.M{"$pull": bson.M{"score_history": bson.M{"cid": chunk}}},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := r.analyze(context.Background(), uconnMap, hostMap, minTimestamp, maxTimestamp, r.config.S.Rolling.CurrentChunk, time.Time{}, false)
+	return err
+}
+
+// saveCheckpoint records the unique connections which still need to be analyzed
+func (r *repo) saveCheckpoint(pending map[string]*uconn.Input, minTimestamp, maxTimestamp int64) error {
+	if r.dryRun != nil {
+		return nil
+	}
+
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	entries := newCheckpointEntries(pending, r.config.S.Rolling.CurrentChunk, minTimestamp, maxTimestamp)
+
+	bulk := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Beacon.BeaconCheckpointTable).Bulk()
+	bulk.Unordered()
+	bulk.Insert(entries...)
+	_, err := bulk.Run()
+	return err
+}
+
+// analyze runs the beacon analysis pipeline over the unique connections until the
+// deadline passes or ctx is cancelled and returns the unique connections which were
+// not analyzed. The beacons are written to the given chunk. If prune is set, the
+// beacons of the unique connections which don't qualify for the analysis are removed.
+// The errors of the analysis and of the writes to MongoDB are returned as a single error.
+func (r *repo) analyze(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input,
+	minTimestamp, maxTimestamp int64, chunk int, deadline time.Time, prune bool) (map[string]*uconn.Input, error) {
+
+	var failures analysisErrors
+
+	//Create the workers. The failed writes are passed on to the analyzer,
+	//which is created below but before anything is written.
+	var analyzerWorker *analyzer
+	writerWorker := r.newWriter(func(err error) { analyzerWorker.reportError(err) })
+
+	// only mark beacons as blacklisted once the blacklist analysis has marked the hosts
+	var blacklisted func(data.UniqueIP) (bool, error)
+	if r.config.S.Blacklisted.Enabled {
+		blacklisted = r.hostBlacklisted
+	}
+
+	cloudRanges, err := cloud.LoadRanges(r.config)
+	if err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "beacon",
+		}).Error("Could not load the cloud provider ranges: ", err)
+		cloudRanges = nil
+	}
+
+	extScorer, err := scorer.Start(r.config)
+	if err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "beacon",
+		}).Error("Could not start the external scorer, using the built-in scores only: ", err)
+		extScorer = nil
+	}
+
+	analyzerWorker = newAnalyzer(
+		ctx,
 		minTimestamp,
 		maxTimestamp,
-		r.config.S.Rolling.CurrentChunk,
+		chunk,
 		r.database,
 		r.config,
 		r.log,
+		blacklisted,
+		cloudRanges,
+		extScorer,
 		writerWorker.Collect,
 		writerWorker.Close,
 	)
 
+	// the errors were logged where they occurred, so they are only tallied here
+	analysisErrors := 0
+	errorsDone := make(chan struct{})
+	go func() {
+		for err := range analyzerWorker.errors() {
+			failures.add(err)
+			analysisErrors++
+		}
+		close(errorsDone)
+	}()
+
 	sorterWorker := newSorter(
 		r.database,
 		r.config,
@@ -101,7 +364,7 @@ func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host
 
 	siphonWorker := newSiphon(
 		int64(r.config.S.Strobe.ConnectionLimit),
-		r.config.S.Rolling.CurrentChunk,
+		chunk,
 		r.database,
 		r.config,
 		r.log,
@@ -110,11 +373,18 @@ func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host
 		sorterWorker.close,
 	)
 
+	var staleCallback func(database.BulkChanges)
+	if prune {
+		staleCallback = writerWorker.Collect
+	}
+
 	dissectorWorker := newDissector(
 		int64(r.config.S.Strobe.ConnectionLimit),
-		r.config.S.Rolling.CurrentChunk,
+		chunk,
 		r.database,
 		r.config,
+		r.overlappingChunks(),
+		staleCallback,
 		siphonWorker.collect,
 		siphonWorker.close,
 	)
@@ -137,16 +407,32 @@ func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host
 		),
 		mpb.AppendDecorators(decor.Percentage()),
 	)
-	// loop over map entries
-	for _, entry := range uconnMap {
+	// loop over map entries, stopping early if the deadline passes
+	pending := collectUntil(ctx, uconnMap, deadline, time.Now, func(entry *uconn.Input) {
 		dissectorWorker.collect(entry)
 		bar.IncrBy(1)
-	}
+	})
+	// the bar only completes when every entry was collected
+	bar.SetTotal(int64(len(uconnMap)-len(pending)), true)
 	p.Wait()
 
 	// start the closing cascade (this will also close the other channels)
 	dissectorWorker.close()
 
+	<-errorsDone
+	if analysisErrors > 0 {
+		fmt.Printf("\t[!] %d beacons could not be analyzed or written, see the log for details\n", analysisErrors)
+	}
+
+	// the analyzer is finished with the external scorer once the cascade completes
+	if extScorer != nil {
+		if err := extScorer.Close(); err != nil {
+			r.log.WithFields(log.Fields{
+				"Module": "beacon",
+			}).Error("External scorer did not exit cleanly: ", err)
+		}
+	}
+
 	// Phase 2: Summary
 
 	// grab the local hosts we have seen during the current analysis period
@@ -158,16 +444,22 @@ func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host
 		}
 	}
 
+	// the summaries are built from the stored beacons, which a dry run leaves as they were
+	if r.dryRun != nil {
+		fmt.Println("\t[!] Skipping Beacon Aggregation: Dry Run")
+		return pending, failures.err()
+	}
+
 	// skip the summarize phase if there are no local hosts to summarize
 	if len(localHosts) == 0 {
 		fmt.Println("\t[!] Skipping Beacon Aggregation: No Internal Hosts")
-		return
+		return pending, failures.err()
 	}
 
 	// initialize a new writer for the summarizer
-	writerWorker = database.NewBulkWriter(r.database, r.config, r.log, true, "beacon")
+	writerWorker = database.NewReportingWriter(r.database, r.config, r.log, true, "beacon", failures.add)
 	summarizerWorker := newSummarizer(
-		r.config.S.Rolling.CurrentChunk,
+		chunk,
 		r.database,
 		r.config,
 		r.log,
@@ -201,4 +493,18 @@ func (r *repo) Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host
 
 	// start the closing cascade (this will also close the other channels)
 	summarizerWorker.close()
+
+	return pending, failures.err()
+}
+
+// hostBlacklisted reports whether the host was marked as blacklisted when the hosts were built
+func (r *repo) hostBlacklisted(host data.UniqueIP) (bool, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	query := host.BSONKey()
+	query["blacklisted"] = true
+
+	count, err := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.HostTable).Find(query).Count()
+	return count > 0, err
 }
@@ -0,0 +1,161 @@
+package beacon
+
+import (
+	"fmt"
+
+	"github.com/activecm/rita/database"
+	log "github.com/sirupsen/logrus"
+)
+
+// uconnDat holds the chunks of a unique connection gathered by the dissector.
+// Ts and Bytes are the series of every chunk laid end to end, while the
+// other series are kept per chunk. CIDs and TsLens hold the ID and the number
+// of timestamps of each chunk.
+type uconnDat struct {
+	Count       int64       `bson:"count"`
+	TsUniqueLen int64       `bson:"ts_unique_len"`
+	Ts          []int64     `bson:"ts"`
+	RespTs      [][]int64   `bson:"rts"`
+	Bytes       []int64     `bson:"bytes"`
+	RespBytes   [][]int64   `bson:"rbytes"`
+	Durations   [][]float64 `bson:"durs"`
+	UIDs        [][]string  `bson:"uids"`
+	TBytes      int64       `bson:"tbytes"`
+	OrigIPBytes []int64     `bson:"oipbytes"`
+	OrigBytes   []int64     `bson:"obytes"`
+	OrigPkts    []int64     `bson:"opkts"`
+	Tuples      [][]string  `bson:"tuples"`
+	CIDs        []int       `bson:"cids"`
+	TsLens      []int       `bson:"ts_lens"`
+}
+
+// overlappingChunks reports the chunks of the selected dataset whose time
+// ranges overlap, since they may count the same connections twice. If the
+// Rolling MergeOverlappingChunks setting is enabled, the overlap group of
+// each of those chunks is returned so their repeated connections can be
+// dropped. Otherwise nil is returned.
+func (r *repo) overlappingChunks() map[int]int {
+	db := r.database.GetSelectedDB()
+	info, err := database.NewMetaDB(r.config, r.database.Session, r.log).GetDBMetaInfo(db)
+	if err != nil {
+		return nil
+	}
+
+	groups := info.OverlappingChunks()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	merge := r.config.S.Rolling.MergeOverlappingChunks
+	r.log.WithFields(log.Fields{
+		"Module":   "beacon",
+		"database": db,
+		"chunks":   groups,
+		"merge":    merge,
+	}).Warn("Chunks cover overlapping time ranges")
+	if !merge {
+		fmt.Printf("\t[!] Chunks %v cover overlapping time ranges, their connections may be counted twice\n", groups)
+		return nil
+	}
+	fmt.Printf("\t[!] Merging the connections of chunks %v which cover overlapping time ranges\n", groups)
+	return chunkGroups(groups)
+}
+
+// chunkGroups maps each chunk in groups to the index of its group
+func chunkGroups(groups [][]int) map[int]int {
+	chunkGroup := make(map[int]int)
+	for i, group := range groups {
+		for _, cid := range group {
+			chunkGroup[cid] = i
+		}
+	}
+	return chunkGroup
+}
+
+// mergeOverlappingChunks drops the connections which a chunk repeats from
+// an earlier chunk in the same overlap group, so each connection is only
+// counted once. A connection is repeated if the other chunk holds a
+// connection with the same timestamp and data size. The per chunk series
+// are only filtered if they hold a value for each connection. Nothing is
+// dropped if the chunks don't line up with the timestamps, such as chunks
+// imported before the data sizes were stored.
+func (res *uconnDat) mergeOverlappingChunks(overlapping map[int]int) {
+	if len(res.CIDs) != len(res.TsLens) || len(res.Ts) != len(res.Bytes) {
+		return
+	}
+	total := 0
+	for _, n := range res.TsLens {
+		total += n
+	}
+	if total != len(res.Ts) {
+		return
+	}
+
+	type connKey struct {
+		group int
+		ts    int64
+		bytes int64
+	}
+	kept := make(map[connKey]int) // most copies of each connection in a single chunk
+
+	ts := make([]int64, 0, len(res.Ts))
+	bytes := make([]int64, 0, len(res.Bytes))
+	offset := 0
+	for i, cid := range res.CIDs {
+		n := res.TsLens[i]
+		chunkTs, chunkBytes := res.Ts[offset:offset+n], res.Bytes[offset:offset+n]
+		offset += n
+
+		group, overlaps := overlapping[cid]
+		keep := make([]bool, n)
+		seen := make(map[connKey]int)
+		for j := range chunkTs {
+			key := connKey{group, chunkTs[j], chunkBytes[j]}
+			seen[key]++
+			if !overlaps || seen[key] > kept[key] {
+				keep[j] = true
+				if overlaps {
+					kept[key]++
+				}
+				ts = append(ts, chunkTs[j])
+				bytes = append(bytes, chunkBytes[j])
+				continue
+			}
+			res.Count--
+			res.TBytes -= chunkBytes[j]
+		}
+
+		if i < len(res.RespBytes) && len(res.RespBytes[i]) == n {
+			for j, respBytes := range res.RespBytes[i] {
+				if !keep[j] {
+					res.TBytes -= respBytes
+				}
+			}
+			res.RespBytes[i] = filterKept(res.RespBytes[i], keep)
+		}
+		if i < len(res.RespTs) && len(res.RespTs[i]) == n {
+			res.RespTs[i] = filterKept(res.RespTs[i], keep)
+		}
+		if i < len(res.Durations) && len(res.Durations[i]) == n {
+			durations := make([]float64, 0, n)
+			for j, duration := range res.Durations[i] {
+				if keep[j] {
+					durations = append(durations, duration)
+				}
+			}
+			res.Durations[i] = durations
+		}
+	}
+	res.Ts, res.Bytes = ts, bytes
+}
+
+// filterKept returns the values whose entry in keep is true
+func filterKept(values []int64, keep []bool) []int64 {
+	filtered := make([]int64, 0, len(values))
+	for j, value := range values {
+		if keep[j] {
+			filtered = append(filtered, value)
+		}
+	}
+	return filtered
+}
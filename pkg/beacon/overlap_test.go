@@ -0,0 +1,68 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunkGroups(t *testing.T) {
+	assert.Equal(t, map[int]int{0: 0, 2: 0, 3: 1, 5: 1}, chunkGroups([][]int{{0, 2}, {5, 3}}))
+	assert.Empty(t, chunkGroups(nil))
+}
+
+func TestMergeOverlappingChunks(t *testing.T) {
+	// chunks 0 and 2 imported the same hour, chunk 1 the hour after
+	newDat := func() uconnDat {
+		return uconnDat{
+			Count:     7,
+			TBytes:    7*100 + 7*10,
+			CIDs:      []int{0, 1, 2},
+			TsLens:    []int{3, 2, 2},
+			Ts:        []int64{0, 1800, 1800, 3600, 5400, 1800, 3000},
+			Bytes:     []int64{100, 100, 100, 100, 100, 100, 100},
+			RespBytes: [][]int64{{10, 10, 10}, {10, 10}, {10, 10}},
+			RespTs:    [][]int64{{1, 1801, 1801}, {3601, 5401}, {1801, 3001}},
+			Durations: [][]float64{{1, 1, 1}, {1, 1}, {1, 2}},
+		}
+	}
+
+	res := newDat()
+	res.mergeOverlappingChunks(chunkGroups([][]int{{0, 2}}))
+
+	// the connection at 1800 is repeated by chunk 2 but the one at 3000 is new
+	assert.Equal(t, []int64{0, 1800, 1800, 3600, 5400, 3000}, res.Ts)
+	assert.Equal(t, []int64{100, 100, 100, 100, 100, 100}, res.Bytes)
+	assert.Equal(t, int64(6), res.Count)
+	assert.Equal(t, int64(6*110), res.TBytes)
+	assert.Equal(t, [][]int64{{10, 10, 10}, {10, 10}, {10}}, res.RespBytes)
+	assert.Equal(t, [][]int64{{1, 1801, 1801}, {3601, 5401}, {3001}}, res.RespTs)
+	assert.Equal(t, [][]float64{{1, 1, 1}, {1, 1}, {2}}, res.Durations)
+
+	// the same connections in chunks which don't overlap are different connections
+	res = newDat()
+	res.mergeOverlappingChunks(chunkGroups([][]int{{0, 1}}))
+	assert.Equal(t, newDat(), res)
+
+	// chunks which don't line up with the timestamps are left alone
+	res = newDat()
+	res.TsLens = []int{3, 2, 1}
+	res.mergeOverlappingChunks(chunkGroups([][]int{{0, 2}}))
+	assert.Equal(t, int64(7), res.Count)
+	assert.Len(t, res.Ts, 7)
+}
+
+func TestMergeOverlappingChunksCopies(t *testing.T) {
+	// two connections in the same second are both kept, and the chunk
+	// repeating them adds a third
+	res := uconnDat{
+		Count:  5,
+		CIDs:   []int{0, 1},
+		TsLens: []int{2, 3},
+		Ts:     []int64{60, 60, 60, 60, 60},
+		Bytes:  []int64{5, 5, 5, 5, 5},
+	}
+	res.mergeOverlappingChunks(chunkGroups([][]int{{0, 1}}))
+	assert.Equal(t, []int64{60, 60, 60}, res.Ts)
+	assert.Equal(t, int64(3), res.Count)
+}
@@ -0,0 +1,58 @@
+package beacon
+
+import (
+	"net"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/util"
+)
+
+// knownPeriodicClassifier recognizes beacons to services which are periodic
+// by design, such as NTP or DNS to internal servers. Unlike heartbeats, these
+// are recognized by the service and server alone regardless of their score
+// or payloads, so that only the listed servers are exempted.
+type knownPeriodicClassifier struct {
+	services []knownPeriodicService
+}
+
+// knownPeriodicService is a parsed Beacon KnownPeriodic Services entry
+type knownPeriodicService struct {
+	service      []string // port, protocol, and optionally service
+	destinations []*net.IPNet
+}
+
+// newKnownPeriodicClassifier creates a knownPeriodicClassifier from the
+// Beacon KnownPeriodic config section. The entries are checked when the
+// config is loaded.
+func newKnownPeriodicClassifier(conf config.KnownPeriodicStaticCfg) *knownPeriodicClassifier {
+	k := &knownPeriodicClassifier{}
+	for _, entry := range conf.Services {
+		k.services = append(k.services, knownPeriodicService{
+			service:      util.ParseService(entry.Service),
+			destinations: parseDestinations(entry.Destinations),
+		})
+	}
+	return k
+}
+
+// knownPeriodic reports whether a beacon to dst over the given
+// port:protocol:service tuples only uses a service listed for dst
+func (k *knownPeriodicClassifier) knownPeriodic(dst string, tuples []string) bool {
+	if len(tuples) == 0 {
+		return false
+	}
+
+	for _, tuple := range tuples {
+		matched := false
+		for _, entry := range k.services {
+			if util.MatchService(entry.service, tuple) && containsDestination(entry.destinations, dst) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
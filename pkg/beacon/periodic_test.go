@@ -0,0 +1,77 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testKnownPeriodicConf = config.KnownPeriodicStaticCfg{
+	Penalty: 0.5,
+	Services: []config.KnownPeriodicService{
+		{Service: "123:udp", Destinations: []string{"10.0.0.10"}},
+		{Service: "53:udp:dns", Destinations: []string{"10.0.1.0/24"}},
+	},
+}
+
+func TestKnownPeriodic(t *testing.T) {
+	k := newKnownPeriodicClassifier(testKnownPeriodicConf)
+
+	testCases := []struct {
+		msg      string
+		dst      string
+		tuples   []string
+		periodic bool
+	}{
+		{"ntp to the known time server", "10.0.0.10", []string{"123:udp:ntp"}, true},
+		{"ntp to an unknown server", "203.0.113.1", []string{"123:udp:ntp"}, false},
+		{"dns to a known resolver subnet", "10.0.1.53", []string{"53:udp:dns"}, true},
+		{"service must match the destination's entry", "10.0.0.10", []string{"53:udp:dns"}, false},
+		{"service name must match when listed", "10.0.1.53", []string{"53:udp:-"}, false},
+		{"known service alongside an unknown one", "10.0.0.10", []string{"123:udp:ntp", "443:tcp:ssl"}, false},
+		{"no tuples", "10.0.0.10", nil, false},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.periodic, k.knownPeriodic(test.dst, test.tuples), test.msg)
+	}
+}
+
+func TestAnalyzerKnownPeriodic(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Beacon.Heartbeat.Enabled = false
+	conf.S.Beacon.KnownPeriodic = testKnownPeriodicConf
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	// both hosts poll NTP every 15 minutes, but only one goes to the
+	// internal time server
+	newInput := func(dst string) *uconn.Input {
+		sizes := make([]int64, 96)
+		for i := range sizes {
+			sizes[i] = 76
+		}
+		input := newTestBeaconInput(tsMin, 900, len(sizes), sizes, sizes)
+		input.Hosts = data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: dst})
+		input.Tuples = data.StringSet{"123:udp:ntp": struct{}{}}
+		return input
+	}
+
+	results := analyzeTestInputs(t, conf, tsMin, tsMax, newInput("10.0.0.10"), newInput("203.0.113.1"))
+
+	assert.Equal(t, true, results[0]["known_periodic"])
+	assert.Equal(t, false, results[1]["known_periodic"], "ntp to an unknown server is still scored")
+	require.Greater(t, results[1]["score"], 0.8)
+	assert.InDelta(t, results[1]["score"].(float64)*0.5, results[0]["score"], 0.001)
+
+	// the classification is skipped when no services are listed
+	conf.S.Beacon.KnownPeriodic.Services = nil
+	results = analyzeTestInputs(t, conf, tsMin, tsMax, newInput("10.0.0.10"))
+	assert.NotContains(t, results[0], "known_periodic")
+}
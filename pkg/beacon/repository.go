@@ -1,6 +1,9 @@
 package beacon
 
 import (
+	"context"
+	"time"
+
 	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/pkg/host"
 	"github.com/activecm/rita/pkg/uconn"
@@ -9,41 +12,87 @@ import (
 // Repository for beacon collection
 type Repository interface {
 	CreateIndexes() error
-	Upsert(uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64)
+	Upsert(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) error
+	Refresh(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) error
+	UpsertUntil(ctx context.Context, uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64, deadline time.Time) (bool, error)
+	HasCheckpoint() (bool, error)
+	Resume(ctx context.Context, deadline time.Time) (bool, error)
+	Rescore(minTimestamp, maxTimestamp int64) error
 }
 
 // TSData ...
 type TSData struct {
-	Score      float64 `bson:"score"`
-	Range      int64   `bson:"range"`
-	Mode       int64   `bson:"mode"`
-	ModeCount  int64   `bson:"mode_count"`
-	Skew       float64 `bson:"skew"`
-	Dispersion int64   `bson:"dispersion"`
+	Score              float64 `bson:"score"`
+	Range              int64   `bson:"range"`
+	Mode               int64   `bson:"mode"`
+	ModeCount          int64   `bson:"mode_count"`
+	Skew               float64 `bson:"skew"`
+	Dispersion         int64   `bson:"dispersion"`
+	IntervalSampleSize int64   `bson:"interval_sample_size"`
+	Trend              float64 `bson:"trend"`
 }
 
 // DSData ...
 type DSData struct {
+	Score       float64 `bson:"score"`
+	Skew        float64 `bson:"skew"`
+	Dispersion  int64   `bson:"dispersion"`
+	Range       int64   `bson:"range"`
+	Mode        int64   `bson:"mode"`
+	ModeCount   int64   `bson:"mode_count"`
+	Period      int     `bson:"period"`
+	Periodicity float64 `bson:"periodicity"`
+}
+
+// ConnDurData ...
+type ConnDurData struct {
 	Score      float64 `bson:"score"`
 	Skew       float64 `bson:"skew"`
-	Dispersion int64   `bson:"dispersion"`
-	Range      int64   `bson:"range"`
+	Dispersion float64 `bson:"dispersion"`
+}
+
+// RespTsData describes the intervals between the last activity of the connections
+type RespTsData struct {
+	Score      float64 `bson:"score"`
 	Mode       int64   `bson:"mode"`
-	ModeCount  int64   `bson:"mode_count"`
+	Skew       float64 `bson:"skew"`
+	Dispersion int64   `bson:"dispersion"`
+}
+
+// DurSizeData describes how the intervals between the connections relate to
+// the data sizes of the connections
+type DurSizeData struct {
+	Correlation float64 `bson:"correlation"`
+}
+
+// ScoreHistoryEntry records the score a beacon received when a chunk was analyzed
+type ScoreHistoryEntry struct {
+	CID   int     `bson:"cid"`
+	Score float64 `bson:"score"`
 }
 
 // Result represents a beacon between two hosts. Contains information
 // on connection delta times and the amount of data transferred
 type Result struct {
 	data.UniqueIPPair `bson:",inline"`
-	Connections       int64   `bson:"connection_count"`
-	AvgBytes          float64 `bson:"avg_bytes"`
-	TotalBytes        int64   `bson:"total_bytes"`
-	Ts                TSData  `bson:"ts"`
-	Ds                DSData  `bson:"ds"`
-	DurScore          float64 `bson:"duration_score"`
-	HistScore         float64 `bson:"hist_score"`
-	Score             float64 `bson:"score"`
+	Connections       int64               `bson:"connection_count"`
+	AvgBytes          float64             `bson:"avg_bytes"`
+	TotalBytes        int64               `bson:"total_bytes"`
+	Ts                TSData              `bson:"ts"`
+	Ds                DSData              `bson:"ds"`
+	DurScore          float64             `bson:"duration_score"`
+	ConnDur           ConnDurData         `bson:"conn_dur"`
+	RespTs            RespTsData          `bson:"resp_ts"`
+	DurSize           DurSizeData         `bson:"dur_size"`
+	HistScore         float64             `bson:"hist_score"`
+	Score             float64             `bson:"score"`
+	Confidence        float64             `bson:"confidence"`
+	Blacklisted       bool                `bson:"blacklisted"`
+	CloudProvider     string              `bson:"cloud_provider"`
+	LikelyHeartbeat   bool                `bson:"likely_heartbeat"`
+	KnownPeriodic     bool                `bson:"known_periodic"`
+	UIDs              []string            `bson:"uids"`
+	ScoreHistory      []ScoreHistoryEntry `bson:"score_history"`
 }
 
 // StrobeResult represents a unique connection with a large amount
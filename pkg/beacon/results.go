@@ -1,7 +1,11 @@
 package beacon
 
 import (
+	"fmt"
+	"math"
+
 	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 )
 
@@ -19,6 +23,84 @@ func Results(res *resources.Resources, cutoffScore float64) ([]Result, error) {
 	return beacons, err
 }
 
+//TopPercentileResults finds the beacons scoring within the top percentile (0-100]
+//of the beacons in the database. The cutoff score is found with an aggregation so
+//it adapts to each dataset's score distribution. Beacons which tie the cutoff score
+//are included, so slightly more than the requested fraction may be returned.
+func TopPercentileResults(res *resources.Resources, percentile float64) ([]Result, error) {
+	if percentile <= 0 || percentile > 100 {
+		return nil, fmt.Errorf("invalid percentile %v: must be greater than 0 and at most 100", percentile)
+	}
+
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	beaconColl := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Beacon.BeaconTable)
+
+	total, err := beaconColl.Find(bson.M{"score": bson.M{"$gt": 0}}).Count()
+	if err != nil || total == 0 {
+		return nil, err
+	}
+
+	var cutoff struct {
+		Score float64 `bson:"score"`
+	}
+	err = beaconColl.Pipe(percentileCutoffQuery(percentileCount(total, percentile))).AllowDiskUse().One(&cutoff)
+	if err == mgo.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var beacons []Result
+
+	beaconQuery := bson.M{"score": bson.M{"$gte": cutoff.Score}}
+
+	err = beaconColl.Find(beaconQuery).Sort("-score").All(&beacons)
+
+	return beacons, err
+}
+
+//percentileCount returns how many of total results fall within the top percentile.
+//At least one result is always selected.
+func percentileCount(total int, percentile float64) int {
+	count := int(math.Ceil(float64(total) * percentile / 100))
+	if count < 1 {
+		count = 1
+	}
+	if count > total {
+		count = total
+	}
+	return count
+}
+
+//percentileCutoffQuery finds the score of the count-th highest scoring beacon
+func percentileCutoffQuery(count int) []bson.M {
+	return []bson.M{
+		{"$match": bson.M{"score": bson.M{"$gt": 0}}},
+		{"$sort": bson.M{"score": -1}},
+		{"$skip": count - 1},
+		{"$limit": 1},
+		{"$project": bson.M{"_id": 0, "score": 1}},
+	}
+}
+
+//UIDResults finds the beacons between the given source and destination IPs. The
+//capped sample of Zeek connection UIDs behind each beacon is stored in Result.UIDs.
+func UIDResults(res *resources.Resources, src, dst string) ([]Result, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var beacons []Result
+
+	beaconQuery := bson.M{"src": src, "dst": dst}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Beacon.BeaconTable).Find(beaconQuery).Sort("-score").All(&beacons)
+
+	return beacons, err
+}
+
 //StrobeResults finds strobes (beacons with an immense number of connections) in the database.
 //The results will be sorted by connection count ordered by sortDir (-1 or 1).
 //limit and noLimit control how many results are returned.
@@ -0,0 +1,74 @@
+package beacon
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPercentileCount(t *testing.T) {
+	assert.Equal(t, 10, percentileCount(1000, 1))
+	assert.Equal(t, 5, percentileCount(1000, 0.5))
+	assert.Equal(t, 250, percentileCount(1000, 25))
+	assert.Equal(t, 1000, percentileCount(1000, 100))
+
+	// partial results round up so the top result is never dropped
+	assert.Equal(t, 1, percentileCount(50, 1))
+	assert.Equal(t, 2, percentileCount(150, 1))
+	assert.Equal(t, 1, percentileCount(1, 0.01))
+}
+
+// selectTopPercentile applies the cutoff query to the given scores the same
+// way MongoDB would and returns the scores which meet the cutoff
+func selectTopPercentile(t *testing.T, scores []float64, percentile float64) []float64 {
+	var positive []float64
+	for _, score := range scores {
+		if score > 0 {
+			positive = append(positive, score)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(positive)))
+
+	query := percentileCutoffQuery(percentileCount(len(positive), percentile))
+	require.Len(t, query, 5)
+	assert.Equal(t, bson.M{"score": -1}, query[1]["$sort"])
+	skip := query[2]["$skip"].(int)
+	require.True(t, skip >= 0 && skip < len(positive))
+	cutoff := positive[skip]
+
+	var selected []float64
+	for _, score := range scores {
+		if score > 0 && score >= cutoff {
+			selected = append(selected, score)
+		}
+	}
+	return selected
+}
+
+func TestPercentileCutoffQuery(t *testing.T) {
+	// 1000 distinct scores between 0.001 and 1
+	scores := make([]float64, 1000)
+	for i := range scores {
+		scores[i] = float64(i+1) / 1000
+	}
+
+	top := selectTopPercentile(t, scores, 1)
+	assert.Len(t, top, 10, "the top 1%% of 1000 beacons should be 10 beacons")
+	for _, score := range top {
+		assert.True(t, score > 0.99, "score %v is not in the top 1%%", score)
+	}
+
+	assert.Len(t, selectTopPercentile(t, scores, 10), 100)
+	assert.Len(t, selectTopPercentile(t, scores, 100), 1000)
+
+	// beacons without a score are not part of the distribution
+	withZeros := append([]float64{0, 0, 0, 0, 0}, scores[:100]...)
+	assert.Len(t, selectTopPercentile(t, withZeros, 10), 10)
+
+	// ties with the cutoff score are included
+	tied := []float64{0.9, 0.8, 0.8, 0.8, 0.5, 0.4, 0.3, 0.2, 0.1, 0.1}
+	assert.Equal(t, []float64{0.9, 0.8, 0.8, 0.8}, selectTopPercentile(t, tied, 20))
+}
@@ -0,0 +1,369 @@
+package beacon
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/activecm/rita/util"
+)
+
+var (
+	// ErrTooFewTimestamps is returned by ScoreTimestamps when fewer than three
+	// timestamps are given
+	ErrTooFewTimestamps = errors.New("at least three timestamps are needed to score a beacon")
+
+	// ErrUnsortedTimestamps is returned by ScoreTimestamps when the timestamps
+	// are not sorted in ascending order
+	ErrUnsortedTimestamps = errors.New("timestamps must be sorted in ascending order")
+)
+
+const (
+	// ConnCountHourly scores the connections per hour of the dataset
+	ConnCountHourly = "hourly"
+
+	// ConnCountMedian scores the connections against the number a perfect
+	// beacon repeating at the median interval would make over the dataset
+	ConnCountMedian = "median"
+)
+
+// BeaconScore holds the measurements of the intervals between the connections
+// of a beacon along with the sub-scores derived from them and the combined
+// timestamp score. Each score ranges from 0 to 1. Intervals of zero are
+// excluded from the measurements unless every interval is zero.
+type BeaconScore struct {
+	Skew               float64 // Bowley's measure of skew of the intervals
+	Dispersion         int64   // median absolute deviation about the median of the intervals
+	Range              int64   // difference between the longest and shortest interval
+	IntervalSampleSize int     // number of intervals measured
+	SkewScore          float64 // higher for more symmetric intervals
+	DispersionScore    float64 // higher for intervals which vary less
+	ConnCountScore     float64 // higher for more connections over the dataset
+	ConnCountMode      string  // how the connection count was normalized, ConnCountHourly or ConnCountMedian
+	Approximate        bool    // set if the quantiles of the intervals were estimated by ApproximateScoreTimestamps
+	Score              float64 // average of the sub-scores
+}
+
+// ScoreTimestamps scores how regular the intervals between the connection
+// timestamps of a beacon are. tsList must be sorted in ascending order and
+// hold at least three timestamps. connCount is the number of connections the
+// timestamps were drawn from and tsMin and tsMax bound the timestamps of the
+// whole dataset. The connection count is normalized per hour of the dataset.
+func ScoreTimestamps(tsList []int64, connCount int64, tsMin, tsMax int64) (BeaconScore, error) {
+	return ScoreTimestampsWithMode(tsList, connCount, tsMin, tsMax, ConnCountHourly)
+}
+
+// ScoreTimestampsWithMode works like ScoreTimestamps but normalizes the
+// connection count as selected by connCountMode. It falls back to
+// ConnCountHourly if connCountMode isn't ConnCountMedian.
+func ScoreTimestampsWithMode(tsList []int64, connCount int64, tsMin, tsMax int64, connCountMode string) (BeaconScore, error) {
+	if len(tsList) < 3 {
+		return BeaconScore{}, ErrTooFewTimestamps
+	}
+
+	//find the delta times between the timestamps and sort
+	diffFull := make([]int64, len(tsList)-1)
+	for i := 0; i < len(tsList)-1; i++ {
+		diffFull[i] = tsList[i+1] - tsList[i]
+		if diffFull[i] < 0 {
+			return BeaconScore{}, ErrUnsortedTimestamps
+		}
+	}
+	sort.Sort(util.SortableInt64(diffFull))
+
+	// We are excluding delta zero for scoring calculations.
+	// Search for the section of diffFull without any 0's in it. If every
+	// timestamp is the same, the zero intervals are scored instead.
+	diffNonZeroIdx := 0
+	for i := 0; i < len(diffFull); i++ {
+		if diffFull[i] > 0 {
+			diffNonZeroIdx = i
+			break
+		}
+	}
+
+	diff := diffFull[diffNonZeroIdx:] // select the part of diffFull without any 0's
+	diffLength := len(diff)
+
+	var score BeaconScore
+	score.IntervalSampleSize = diffLength
+
+	//perfect beacons should have symmetric delta time distributions
+	//Bowley's measure of skew is used to check symmetry
+	//diffLength-1 is used since diff is a zero based slice
+	tsLow := diff[util.Round(.25*float64(diffLength-1))]
+	tsMid := diff[util.Round(.5*float64(diffLength-1))]
+	tsHigh := diff[util.Round(.75*float64(diffLength-1))]
+
+	//perfect beacons should have very low dispersion around the
+	//median of their delta times
+	//Median Absolute Deviation About the Median
+	//is used to check dispersion
+	devs := make([]int64, diffLength)
+	for i := 0; i < diffLength; i++ {
+		devs[i] = util.Abs(diff[i] - tsMid)
+	}
+	sort.Sort(util.SortableInt64(devs))
+	score.Dispersion = devs[util.Round(.5*float64(diffLength-1))]
+
+	//Store the range for human analysis
+	score.Range = diff[diffLength-1] - diff[0]
+
+	score.scoreQuartiles(tsLow, tsMid, tsHigh, connCount, tsMin, tsMax, connCountMode)
+	return score, nil
+}
+
+// approxQuartileTolerance is the largest relative error of the interval
+// quartiles estimated by ApproximateScoreTimestamps
+const approxQuartileTolerance = 0.01
+
+// ApproximateScoreTimestamps scores the timestamps like ScoreTimestampsWithMode but
+// estimates the quartiles and the dispersion of the intervals with t-digests
+// rather than sorting them. The intervals are streamed from tsList twice, so
+// the memory used doesn't grow with the number of timestamps. The estimates
+// are rounded to whole seconds like the intervals themselves. The estimated
+// quartiles are within approxQuartileTolerance of the exact quartiles, or one
+// second for short intervals, and the estimated dispersion is within one
+// second of the exact dispersion.
+func ApproximateScoreTimestamps(tsList []int64, connCount int64, tsMin, tsMax int64, connCountMode string) (BeaconScore, error) {
+	if len(tsList) < 3 {
+		return BeaconScore{}, ErrTooFewTimestamps
+	}
+
+	// zero intervals are excluded unless every interval is zero
+	nonZero := 0
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if interval < 0 {
+			return BeaconScore{}, ErrUnsortedTimestamps
+		}
+		if interval > 0 {
+			nonZero++
+		}
+	}
+	skipZero := nonZero > 0
+
+	var score BeaconScore
+	score.Approximate = true
+
+	intervals := newTDigest(tDigestCompression)
+	shortest, longest := int64(math.MaxInt64), int64(0)
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if interval == 0 && skipZero {
+			continue
+		}
+		intervals.add(float64(interval))
+		if interval < shortest {
+			shortest = interval
+		}
+		if interval > longest {
+			longest = interval
+		}
+		score.IntervalSampleSize++
+	}
+
+	tsLow := int64(math.Round(intervals.quantile(.25)))
+	tsMid := int64(math.Round(intervals.quantile(.5)))
+	tsHigh := int64(math.Round(intervals.quantile(.75)))
+
+	//the deviations from the estimated median are streamed into another digest
+	devs := newTDigest(tDigestCompression)
+	for i := 0; i < len(tsList)-1; i++ {
+		interval := tsList[i+1] - tsList[i]
+		if interval == 0 && skipZero {
+			continue
+		}
+		devs.add(float64(util.Abs(interval - tsMid)))
+	}
+	score.Dispersion = int64(math.Round(devs.quantile(.5)))
+
+	score.Range = longest - shortest
+
+	score.scoreQuartiles(tsLow, tsMid, tsHigh, connCount, tsMin, tsMax, connCountMode)
+	return score, nil
+}
+
+// scoreQuartiles derives the skew and the sub-scores from the quartiles of the
+// intervals, the dispersion stored in score, and the connection count, and
+// combines them into the timestamp score
+func (score *BeaconScore) scoreQuartiles(tsLow, tsMid, tsHigh int64, connCount int64, tsMin, tsMax int64, connCountMode string) {
+	tsBowleyNum := tsLow + tsHigh - 2*tsMid
+	tsBowleyDen := tsHigh - tsLow
+
+	//skew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if tsBowleyDen != 0 && tsMid != tsLow && tsMid != tsHigh {
+		score.Skew = float64(tsBowleyNum) / float64(tsBowleyDen)
+	}
+
+	//more skewed distributions receive a lower score
+	//less skewed distributions receive a higher score
+	score.SkewScore = 1.0 - math.Abs(score.Skew) //smush tsSkew
+
+	//lower dispersion is better
+	score.DispersionScore = 1.0
+	if tsMid >= 1 {
+		score.DispersionScore = 1.0 - float64(score.Dispersion)/float64(tsMid)
+	}
+	if score.DispersionScore < 0 {
+		score.DispersionScore = 0
+	}
+
+	// connection count scoring
+	if connCountMode == ConnCountMedian {
+		// compare against the connections a perfect beacon at the median
+		// interval would make over the dataset. Both grow with the length of
+		// the capture, so the score doesn't depend on it. Every connection is
+		// expected at once if the median interval is zero.
+		score.ConnCountMode = ConnCountMedian
+		tsExpected := 1.0
+		if tsMid >= 1 {
+			tsExpected = (float64(tsMax)-float64(tsMin))/float64(tsMid) + 1
+		}
+		score.ConnCountScore = float64(connCount) / tsExpected
+	} else {
+		// count connections over at least an hour so a dataset whose
+		// timestamps fall within a single instant doesn't divide by zero
+		score.ConnCountMode = ConnCountHourly
+		tsConnDiv := math.Max((float64(tsMax)-float64(tsMin))/3600, 1)
+		score.ConnCountScore = float64(connCount) / tsConnDiv
+	}
+	if score.ConnCountScore > 1.0 {
+		score.ConnCountScore = 1.0
+	}
+
+	score.Score = math.Ceil(((score.SkewScore+score.DispersionScore+score.ConnCountScore)/3.0)*1000) / 1000
+}
+
+// IntervalTrend measures how steadily the intervals between the connections of
+// a beacon grow or shrink over time, such as an implant which checks in more
+// often as the capture goes on. intervals must be in the order the connections
+// were made. The Spearman rank correlation between the position of each
+// interval and its length is returned, rounded to three places: 1 for intervals
+// which only grow, -1 for intervals which only shrink, and near 0 for intervals
+// without a trend. Intervals of zero are skipped. Fewer than three intervals,
+// or intervals which never change, have a trend of 0.
+func IntervalTrend(intervals []int64) float64 {
+	var series []int64
+	for _, interval := range intervals {
+		if interval > 0 {
+			series = append(series, interval)
+		}
+	}
+	n := len(series)
+	if n < 3 {
+		return 0
+	}
+
+	// correlate the lengths of the intervals with their positions
+	positions := make([]float64, n)
+	lengths := make([]float64, n)
+	for i, interval := range series {
+		positions[i] = float64(i + 1)
+		lengths[i] = float64(interval)
+	}
+	return math.Round(SpearmanCorrelation(positions, lengths)*1000) / 1000
+}
+
+// IntervalSizeCorrelation measures whether the timing and the data sizes of a
+// beacon move together, which suggests that a single automated process is
+// behind both. tsList must be sorted in ascending order and sizes must hold
+// the data size of each connection in the same order. Each interval is paired
+// with the size of the connection which ends it, and the Spearman rank
+// correlation of the pairs is returned, rounded to three places: 1 for sizes
+// which grow with the interval, -1 for sizes which shrink as the interval
+// grows, and near 0 for sizes which are independent of the interval. Intervals
+// of zero are skipped. Fewer than three pairs, series which never change, or
+// sizes which don't line up with the timestamps have a correlation of 0.
+func IntervalSizeCorrelation(tsList []int64, sizes []int64) float64 {
+	if len(sizes) != len(tsList) || len(tsList) == 0 {
+		return 0
+	}
+
+	intervals := make([]float64, 0, len(tsList)-1)
+	following := make([]float64, 0, len(tsList)-1)
+	for i := 1; i < len(tsList); i++ {
+		interval := tsList[i] - tsList[i-1]
+		if interval > 0 {
+			intervals = append(intervals, float64(interval))
+			following = append(following, float64(sizes[i]))
+		}
+	}
+	return math.Round(SpearmanCorrelation(intervals, following)*1000) / 1000
+}
+
+// SpearmanCorrelation returns the Spearman rank correlation between two series
+// of paired values, from -1 to 1. Tied values share the mean of the ranks they
+// span. Series of different lengths, with fewer than three pairs, or in which
+// every value is the same have a correlation of 0.
+func SpearmanCorrelation(x, y []float64) float64 {
+	n := len(x)
+	if n != len(y) || n < 3 {
+		return 0
+	}
+
+	// both series are ranked 1 through n and share the same mean rank
+	xRanks, yRanks := rankValues(x), rankValues(y)
+	meanRank := float64(n+1) / 2
+	var cov, xVar, yVar float64
+	for i := range xRanks {
+		xDev := xRanks[i] - meanRank
+		yDev := yRanks[i] - meanRank
+		cov += xDev * yDev
+		xVar += xDev * xDev
+		yVar += yDev * yDev
+	}
+	if xVar == 0 || yVar == 0 {
+		return 0
+	}
+
+	return cov / math.Sqrt(xVar*yVar)
+}
+
+// rankValues ranks the values from 1 through len(values), giving tied values
+// the mean of the ranks they span
+func rankValues(values []float64) []float64 {
+	n := len(values)
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return values[order[i]] < values[order[j]] })
+
+	ranks := make([]float64, n)
+	for start := 0; start < n; {
+		end := start
+		for end+1 < n && values[order[end+1]] == values[order[start]] {
+			end++
+		}
+		meanRank := float64(start+end)/2 + 1
+		for i := start; i <= end; i++ {
+			ranks[order[i]] = meanRank
+		}
+		start = end + 1
+	}
+	return ranks
+}
+
+// weightedMean averages subscores by their weights. Dividing by the sum of the
+// weights of the subscores which were added keeps the overall score between 0
+// and 1 no matter which optional subscores are enabled.
+type weightedMean struct {
+	sum    float64
+	weight float64
+}
+
+// add adds a subscore with the given weight
+func (m *weightedMean) add(score, weight float64) {
+	m.sum += score * weight
+	m.weight += weight
+}
+
+// value returns the weighted mean of the subscores, or 0 if their weights sum to 0
+func (m *weightedMean) value() float64 {
+	if m.weight == 0 {
+		return 0
+	}
+	return m.sum / m.weight
+}
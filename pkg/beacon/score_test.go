@@ -0,0 +1,346 @@
+package beacon
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/activecm/rita/util"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScoreTimestampsTooFew(t *testing.T) {
+	for _, tsList := range [][]int64{nil, {1600000000}, {1600000000, 1600000060}} {
+		_, err := ScoreTimestamps(tsList, int64(len(tsList)), 1600000000, 1600086400)
+		assert.Equal(t, ErrTooFewTimestamps, err, "%d timestamps should be rejected", len(tsList))
+	}
+}
+
+func TestScoreTimestampsUnsorted(t *testing.T) {
+	_, err := ScoreTimestamps([]int64{1600000120, 1600000000, 1600000060}, 3, 1600000000, 1600086400)
+	assert.Equal(t, ErrUnsortedTimestamps, err)
+}
+
+func TestScoreTimestampsIdenticalIntervals(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	// a connection every minute for a day is a perfect beacon
+	tsList := make([]int64, 1440)
+	for i := range tsList {
+		tsList[i] = tsMin + int64(i)*60
+	}
+
+	score, err := ScoreTimestamps(tsList, int64(len(tsList)), tsMin, tsMax)
+	require.Nil(t, err)
+	assert.Equal(t, BeaconScore{
+		IntervalSampleSize: 1439,
+		SkewScore:          1,
+		DispersionScore:    1,
+		ConnCountScore:     1,
+		ConnCountMode:      ConnCountHourly,
+		Score:              1,
+	}, score)
+}
+
+func TestScoreTimestampsIdenticalTimestamps(t *testing.T) {
+	// every connection happened at the same instant, so only the zero
+	// intervals can be measured
+	ts := int64(1600000000)
+	score, err := ScoreTimestamps([]int64{ts, ts, ts, ts}, 4, ts, ts)
+	require.Nil(t, err)
+	assert.Equal(t, 3, score.IntervalSampleSize)
+	assert.Equal(t, int64(0), score.Range)
+	assert.Equal(t, 1.0, score.SkewScore)
+	assert.Equal(t, 1.0, score.DispersionScore)
+	assert.Equal(t, 1.0, score.ConnCountScore)
+	assert.Equal(t, 1.0, score.Score)
+}
+
+func TestScoreTimestampsExcludesZeroIntervals(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 3600
+
+	// duplicated timestamps don't count as intervals
+	tsList := []int64{tsMin, tsMin, tsMin + 60, tsMin + 120, tsMin + 120, tsMin + 180}
+	score, err := ScoreTimestamps(tsList, int64(len(tsList)), tsMin, tsMax)
+	require.Nil(t, err)
+	assert.Equal(t, 3, score.IntervalSampleSize)
+	assert.Equal(t, int64(0), score.Dispersion)
+	assert.Equal(t, 1.0, score.DispersionScore)
+}
+
+func TestScoreTimestampsIrregular(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	tsList := []int64{tsMin, tsMin + 10, tsMin + 20, tsMin + 100, tsMin + 1000, tsMin + 1010, tsMin + 5000}
+	score, err := ScoreTimestamps(tsList, int64(len(tsList)), tsMin, tsMax)
+	require.Nil(t, err)
+
+	// intervals of 10, 10, 10, 80, 900, and 3990 seconds
+	assert.Equal(t, 6, score.IntervalSampleSize)
+	assert.Equal(t, int64(3980), score.Range)
+	assert.Less(t, score.DispersionScore, 1.0)
+	assert.Less(t, score.ConnCountScore, 1.0)
+	assert.Less(t, score.Score, 0.9)
+	assert.GreaterOrEqual(t, score.Score, 0.0)
+}
+
+func TestApproximateScoreTimestampsErrors(t *testing.T) {
+	_, err := ApproximateScoreTimestamps([]int64{1600000000, 1600000060}, 2, 1600000000, 1600086400, ConnCountHourly)
+	assert.Equal(t, ErrTooFewTimestamps, err)
+	_, err = ApproximateScoreTimestamps([]int64{1600000120, 1600000000, 1600000060}, 3, 1600000000, 1600086400, ConnCountHourly)
+	assert.Equal(t, ErrUnsortedTimestamps, err)
+}
+
+// jitteredTimestamps returns count connections starting at tsMin whose
+// intervals are interval seconds plus up to jitter seconds either way, with
+// zero intervals from duplicated connections mixed in
+func jitteredTimestamps(rng *rand.Rand, tsMin int64, count int, interval int64, jitter int64) []int64 {
+	tsList := make([]int64, count)
+	tsList[0] = tsMin
+	for i := 1; i < count; i++ {
+		tsList[i] = tsList[i-1]
+		if rng.Intn(20) > 0 {
+			tsList[i] += interval + rng.Int63n(2*jitter+1) - jitter
+		}
+	}
+	return tsList
+}
+
+func TestApproximateScoreTimestampsMatchesExact(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tsMin := int64(1600000000)
+
+	testCases := []struct {
+		msg       string
+		tsList    []int64
+		mode      string
+		tolerance float64
+	}{
+		{"a lightly jittered beacon", jitteredTimestamps(rng, tsMin, 50000, 60, 3), ConnCountHourly, 0.005},
+		{"a heavily jittered beacon", jitteredTimestamps(rng, tsMin, 50000, 600, 300), ConnCountMedian, 0.005},
+		{"a skewed beacon", jitteredTimestamps(rng, tsMin, 20000, 30, 29), ConnCountHourly, 0.005},
+		{"a perfect beacon", cadenceTimestamps(tsMin, 24*30, 10, 0), ConnCountMedian, 0},
+	}
+
+	for _, test := range testCases {
+		tsMax := test.tsList[len(test.tsList)-1]
+		connCount := int64(len(test.tsList))
+
+		exact, err := ScoreTimestampsWithMode(test.tsList, connCount, tsMin, tsMax, test.mode)
+		require.Nil(t, err)
+		approx, err := ApproximateScoreTimestamps(test.tsList, connCount, tsMin, tsMax, test.mode)
+		require.Nil(t, err)
+
+		assert.True(t, approx.Approximate, test.msg)
+		assert.False(t, exact.Approximate, test.msg)
+		assert.Equal(t, exact.IntervalSampleSize, approx.IntervalSampleSize, test.msg)
+		assert.Equal(t, exact.Range, approx.Range, test.msg)
+		assert.Equal(t, exact.ConnCountMode, approx.ConnCountMode, test.msg)
+		assert.InDelta(t, exact.Dispersion, approx.Dispersion, 1, test.msg)
+		assert.InDelta(t, exact.Score, approx.Score, test.tolerance, test.msg)
+	}
+}
+
+func TestApproximateQuartilesWithinTolerance(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tsMin := int64(1600000000)
+
+	for _, tsList := range [][]int64{
+		jitteredTimestamps(rng, tsMin, 50000, 60, 3),
+		jitteredTimestamps(rng, tsMin, 50000, 600, 300),
+		jitteredTimestamps(rng, tsMin, 20000, 30, 29),
+		jitteredTimestamps(rng, tsMin, 200000, 3600, 1800),
+	} {
+		// the approximate scoring streams the non-zero intervals into a t-digest
+		var intervals []int64
+		digest := newTDigest(tDigestCompression)
+		for i := 0; i < len(tsList)-1; i++ {
+			if interval := tsList[i+1] - tsList[i]; interval > 0 {
+				intervals = append(intervals, interval)
+				digest.add(float64(interval))
+			}
+		}
+		sort.Sort(util.SortableInt64(intervals))
+
+		for _, q := range []float64{.25, .5, .75} {
+			exact := float64(intervals[util.Round(q*float64(len(intervals)-1))])
+			estimate := math.Round(digest.quantile(q))
+			tolerance := math.Max(1, exact*approxQuartileTolerance)
+			assert.InDelta(t, exact, estimate, tolerance, "quantile %v of %d intervals", q, len(intervals))
+		}
+	}
+}
+
+// BenchmarkScoreTimestamps compares the memory used by the exact and the
+// approximate scoring of a high volume pair
+func BenchmarkScoreTimestamps(b *testing.B) {
+	tsMin := int64(1600000000)
+	tsList := jitteredTimestamps(rand.New(rand.NewSource(1)), tsMin, 100000, 60, 3)
+	tsMax := tsList[len(tsList)-1]
+	connCount := int64(len(tsList))
+
+	for _, bench := range []struct {
+		name  string
+		score func([]int64, int64, int64, int64, string) (BeaconScore, error)
+	}{
+		{"exact", ScoreTimestampsWithMode},
+		{"approximate", ApproximateScoreTimestamps},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bench.score(tsList, connCount, tsMin, tsMax, ConnCountHourly); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// cadenceTimestamps returns a connection every interval seconds over hours of
+// capture, skipping every missEvery-th check in if missEvery is above 0
+func cadenceTimestamps(tsMin int64, hours int64, interval int64, missEvery int) []int64 {
+	var tsList []int64
+	for i := 0; int64(i)*interval <= hours*3600; i++ {
+		if missEvery > 0 && i%missEvery == missEvery-1 {
+			continue
+		}
+		tsList = append(tsList, tsMin+int64(i)*interval)
+	}
+	return tsList
+}
+
+func TestScoreTimestampsMedianConnCountCaptureLength(t *testing.T) {
+	tsMin := int64(1600000000)
+
+	for _, c := range []struct {
+		name      string
+		interval  int64
+		missEvery int
+		expected  float64
+	}{
+		{"every two hours", 7200, 0, 1},
+		{"every 90 minutes missing a quarter of the check ins", 5400, 4, 0.75},
+	} {
+		var scores []float64
+		for _, hours := range []int64{24, 96, 168} {
+			tsList := cadenceTimestamps(tsMin, hours, c.interval, c.missEvery)
+			score, err := ScoreTimestampsWithMode(tsList, int64(len(tsList)), tsMin, tsMin+hours*3600, ConnCountMedian)
+			require.Nil(t, err)
+			assert.Equal(t, ConnCountMedian, score.ConnCountMode)
+			scores = append(scores, score.ConnCountScore)
+		}
+
+		// the same cadence scores the same however long the capture is
+		for _, score := range scores {
+			assert.InDelta(t, c.expected, score, 0.05, c.name)
+			assert.InDelta(t, scores[0], score, 0.05, c.name)
+		}
+	}
+}
+
+func TestScoreTimestampsHourlyConnCountCaptureLength(t *testing.T) {
+	tsMin := int64(1600000000)
+
+	// the hourly mode favors the short capture of the same beacon
+	short := cadenceTimestamps(tsMin, 6, 7200, 0)
+	shortScore, err := ScoreTimestampsWithMode(short, int64(len(short)), tsMin, tsMin+6*3600, ConnCountHourly)
+	require.Nil(t, err)
+	long := cadenceTimestamps(tsMin, 168, 7200, 0)
+	longScore, err := ScoreTimestampsWithMode(long, int64(len(long)), tsMin, tsMin+168*3600, ConnCountHourly)
+	require.Nil(t, err)
+
+	assert.Equal(t, ConnCountHourly, shortScore.ConnCountMode)
+	assert.Greater(t, shortScore.ConnCountScore-longScore.ConnCountScore, 0.1)
+}
+
+func TestScoreTimestampsMedianConnCountIdenticalTimestamps(t *testing.T) {
+	// a median interval of zero expects every connection at once
+	ts := int64(1600000000)
+	score, err := ScoreTimestampsWithMode([]int64{ts, ts, ts, ts}, 4, ts, ts+3600, ConnCountMedian)
+	require.Nil(t, err)
+	assert.Equal(t, 1.0, score.ConnCountScore)
+}
+
+func TestIntervalTrend(t *testing.T) {
+	// an implant which checks in more often as the capture goes on
+	shrinking := make([]int64, 48)
+	for i := range shrinking {
+		shrinking[i] = int64(3600 - 60*i)
+	}
+	assert.Equal(t, -1.0, IntervalTrend(shrinking))
+
+	growing := make([]int64, len(shrinking))
+	for i := range growing {
+		growing[i] = shrinking[len(shrinking)-1-i]
+	}
+	assert.Equal(t, 1.0, IntervalTrend(growing))
+
+	// a jittered but steady beacon has little trend
+	jittered := []int64{300, 310, 290, 305, 295, 300, 315, 285, 300, 302, 298, 300}
+	assert.InDelta(t, 0, IntervalTrend(jittered), 0.2)
+
+	// a perfect beacon has no trend
+	assert.Equal(t, 0.0, IntervalTrend([]int64{60, 60, 60, 60, 60}))
+
+	// a noisy ramp still trends, and tied lengths share a rank
+	assert.InDelta(t, -0.9, IntervalTrend([]int64{600, 620, 540, 540, 480, 500, 420, 360, 380, 300}), 0.1)
+
+	// zero intervals are skipped and too few intervals have no trend
+	assert.Equal(t, 1.0, IntervalTrend([]int64{60, 0, 120, 0, 180}))
+	assert.Equal(t, 0.0, IntervalTrend([]int64{60, 0, 120}))
+	assert.Equal(t, 0.0, IntervalTrend(nil))
+}
+
+func TestSpearmanCorrelation(t *testing.T) {
+	x := []float64{1, 2, 3, 4, 5}
+	assert.Equal(t, 1.0, SpearmanCorrelation(x, []float64{10, 20, 40, 80, 160}))
+	assert.Equal(t, -1.0, SpearmanCorrelation(x, []float64{5, 4, 3, 2, 1}))
+
+	// tied values share a rank
+	assert.InDelta(t, 0.9, SpearmanCorrelation(x, []float64{1, 2, 2, 3, 4}), 0.1)
+
+	// constant, short, or mismatched series have no correlation
+	assert.Equal(t, 0.0, SpearmanCorrelation(x, []float64{7, 7, 7, 7, 7}))
+	assert.Equal(t, 0.0, SpearmanCorrelation(x[:2], []float64{1, 2}))
+	assert.Equal(t, 0.0, SpearmanCorrelation(x, []float64{1, 2, 3}))
+}
+
+func TestIntervalSizeCorrelation(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	tsList := make([]int64, 200)
+	for i := 1; i < len(tsList); i++ {
+		tsList[i] = tsList[i-1] + 60 + rng.Int63n(600)
+	}
+
+	// an implant which uploads more the longer it waited to check in
+	correlated := make([]int64, len(tsList))
+	anticorrelated := make([]int64, len(tsList))
+	uncorrelated := make([]int64, len(tsList))
+	for i := 1; i < len(tsList); i++ {
+		interval := tsList[i] - tsList[i-1]
+		correlated[i] = interval * 10
+		anticorrelated[i] = 100000 - interval*10
+		uncorrelated[i] = rng.Int63n(10000)
+	}
+	assert.Equal(t, 1.0, IntervalSizeCorrelation(tsList, correlated))
+	assert.Equal(t, -1.0, IntervalSizeCorrelation(tsList, anticorrelated))
+	assert.InDelta(t, 0, IntervalSizeCorrelation(tsList, uncorrelated), 0.2)
+
+	// the size of the first connection doesn't follow an interval
+	correlated[0] = 1000000
+	assert.Equal(t, 1.0, IntervalSizeCorrelation(tsList, correlated))
+
+	// zero intervals are skipped
+	assert.Equal(t, 1.0, IntervalSizeCorrelation([]int64{0, 60, 60, 180, 480}, []int64{0, 1, 9, 2, 3}))
+
+	// sizes which don't line up with the timestamps have no correlation
+	assert.Equal(t, 0.0, IntervalSizeCorrelation(tsList, correlated[1:]))
+	assert.Equal(t, 0.0, IntervalSizeCorrelation(nil, nil))
+}
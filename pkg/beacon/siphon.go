@@ -96,7 +96,7 @@ func (s *siphon) start() {
 
 					// remove the uconn from the beacon table as its now a strobe
 					s.conf.T.Beacon.BeaconTable: []database.BulkChange{{
-						Selector: data.Hosts.BSONKey(),
+						Selector: getPairSelector(s.conf, data.Hosts),
 						Remove:   true,
 					}},
 				}
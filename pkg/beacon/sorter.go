@@ -11,9 +11,10 @@ import (
 )
 
 type (
-	//sorter handles sorting the timestamp deltas and data sizes
-	//of pairs of hosts in order to prepare the data for quantile based
-	//statistical analysis
+	//sorter handles sorting the timestamps of pairs of hosts in order to
+	//prepare the data for quantile based statistical analysis. The data sizes
+	//and durations are kept in the order of the timestamps, and the data sizes
+	//are sorted by the analyzer once the configured byte series is selected.
 	sorter struct {
 		db             *database.DB       // provides access to MongoDB
 		conf           *config.Config     // contains details needed to access MongoDB
@@ -55,12 +56,54 @@ func (s *sorter) start() {
 
 		for data := range s.sortChannel {
 			if (data.TsList) != nil {
-				//sort the size and timestamps to compute quantiles in the analyzer
-				sort.Sort(util.SortableInt64(data.TsList))
-				sort.Sort(util.SortableInt64(data.OrigBytesList))
+				//sort the timestamps to compute quantiles in the analyzer
+				sortByTimestamp(data)
+			}
+			if (data.RespTsList) != nil {
+				sort.Sort(util.SortableInt64(data.RespTsList))
 			}
 			s.sortedCallback(data)
 		}
 		s.sortWg.Done()
 	}()
 }
+
+// sortByTimestamp sorts the timestamps of a unique connection in ascending
+// order. The data sizes and durations of each connection are moved along with
+// its timestamp so that the series stay paired up in chronological order.
+// Series which don't hold a value for each timestamp are left as they are.
+func sortByTimestamp(data *uconn.Input) {
+	if sort.IsSorted(util.SortableInt64(data.TsList)) {
+		return
+	}
+
+	order := make([]int, len(data.TsList))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return data.TsList[order[i]] < data.TsList[order[j]] })
+
+	data.TsList = permuteInt64(data.TsList, order)
+	if len(data.OrigBytesList) == len(order) {
+		data.OrigBytesList = permuteInt64(data.OrigBytesList, order)
+	}
+	if len(data.RespBytesList) == len(order) {
+		data.RespBytesList = permuteInt64(data.RespBytesList, order)
+	}
+	if len(data.DurationList) == len(order) {
+		durations := make([]float64, len(order))
+		for i, index := range order {
+			durations[i] = data.DurationList[index]
+		}
+		data.DurationList = durations
+	}
+}
+
+// permuteInt64 returns a new slice holding values[order[i]] at each index i
+func permuteInt64(values []int64, order []int) []int64 {
+	permuted := make([]int64, len(order))
+	for i, index := range order {
+		permuted[i] = values[index]
+	}
+	return permuted
+}
@@ -0,0 +1,60 @@
+package beacon
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSorterOutOfOrderTimestamps feeds the sorter a timestamp list shaped like
+// one gathered by the dissector from out of order imports (each chunk's
+// timestamps are concatenated in chunk order, not chronological order) and
+// verifies the analyzer receives a list which yields non-negative intervals.
+func TestSorterOutOfOrderTimestamps(t *testing.T) {
+	input := &uconn.Input{
+		// chunk 1 was imported before chunk 0
+		TsList: []int64{500, 600, 700, 800, 100, 200, 300, 400},
+	}
+
+	var sorted *uconn.Input
+	s := newSorter(nil, nil, func(data *uconn.Input) { sorted = data }, func() {})
+	s.start()
+	s.collect(input)
+	s.close()
+
+	assert.Equal(t, []int64{100, 200, 300, 400, 500, 600, 700, 800}, sorted.TsList)
+
+	for i := 0; i < len(sorted.TsList)-1; i++ {
+		interval := sorted.TsList[i+1] - sorted.TsList[i]
+		assert.Equal(t, int64(100), interval, "interval %d should be positive and regular", i)
+	}
+}
+
+func TestSorterKeepsSeriesPaired(t *testing.T) {
+	input := &uconn.Input{
+		TsList:        []int64{300, 100, 200, 100},
+		OrigBytesList: []int64{3, 1, 2, 4},
+		RespBytesList: []int64{30, 10, 20, 40},
+		DurationList:  []float64{0.3, 0.1, 0.2, 0.4},
+	}
+
+	sortByTimestamp(input)
+
+	// tied timestamps keep the order the connections were gathered in
+	assert.Equal(t, []int64{100, 100, 200, 300}, input.TsList)
+	assert.Equal(t, []int64{1, 4, 2, 3}, input.OrigBytesList)
+	assert.Equal(t, []int64{10, 40, 20, 30}, input.RespBytesList)
+	assert.Equal(t, []float64{0.1, 0.4, 0.2, 0.3}, input.DurationList)
+
+	// series which don't line up with the timestamps are left alone
+	partial := &uconn.Input{
+		TsList:        []int64{200, 100, 300},
+		OrigBytesList: []int64{2, 1, 3},
+		RespBytesList: []int64{20},
+	}
+	sortByTimestamp(partial)
+	assert.Equal(t, []int64{100, 200, 300}, partial.TsList)
+	assert.Equal(t, []int64{1, 2, 3}, partial.OrigBytesList)
+	assert.Equal(t, []int64{20}, partial.RespBytesList)
+}
@@ -0,0 +1,97 @@
+package beacon
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// analysisSummary accumulates the outcome of an analysis pass across the
+// analysis threads so it can be logged once the pass is done
+type analysisSummary struct {
+	lock     sync.Mutex
+	started  time.Time
+	analyzed int64
+	skipped  int64
+	minScore float64
+	maxScore float64
+	scoreSum float64
+}
+
+// newAnalysisSummary creates an empty summary of a pass starting now
+func newAnalysisSummary() *analysisSummary {
+	return &analysisSummary{
+		started:  time.Now(),
+		minScore: math.Inf(1),
+		maxScore: math.Inf(-1),
+	}
+}
+
+// addScore records a pair which was analyzed and given score
+func (s *analysisSummary) addScore(score float64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.analyzed++
+	s.scoreSum += score
+	s.minScore = math.Min(s.minScore, score)
+	s.maxScore = math.Max(s.maxScore, score)
+}
+
+// addSkipped records a pair which could not be analyzed
+func (s *analysisSummary) addSkipped() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.skipped++
+}
+
+// fields returns the summary as log fields. The scores are 0 if no pair was analyzed.
+func (s *analysisSummary) fields() log.Fields {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var minScore, maxScore, meanScore float64
+	if s.analyzed > 0 {
+		minScore, maxScore = s.minScore, s.maxScore
+		meanScore = s.scoreSum / float64(s.analyzed)
+	}
+	return log.Fields{
+		"analyzed":   s.analyzed,
+		"skipped":    s.skipped,
+		"min_score":  minScore,
+		"max_score":  maxScore,
+		"mean_score": meanScore,
+		"duration":   time.Since(s.started).String(),
+	}
+}
+
+// analysisErrors tallies the errors of an analysis pass, which were logged
+// where they occurred, so they can be returned as a single error
+type analysisErrors struct {
+	lock  sync.Mutex
+	count int
+	first error
+}
+
+// add records an error
+func (e *analysisErrors) add(err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.first == nil {
+		e.first = err
+	}
+	e.count++
+}
+
+// err returns an error wrapping the first error which was recorded, or nil
+// if there were none
+func (e *analysisErrors) err() error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if e.count == 0 {
+		return nil
+	}
+	return fmt.Errorf("beacon analysis errors: %d, first error: %w", e.count, e.first)
+}
@@ -0,0 +1,139 @@
+package beacon
+
+import (
+	"math"
+	"sort"
+)
+
+// tDigestCompression bounds the number of centroids a tDigest keeps. Larger
+// values trade memory for more accurate quantiles.
+const tDigestCompression = 100
+
+type (
+	// tDigest is a merging t-digest (Dunning and Ertl) which estimates the
+	// quantiles of a stream of values in memory bounded by its compression.
+	// Values are buffered and merged into centroids whose size shrinks toward
+	// the tails, so the extreme quantiles stay accurate.
+	tDigest struct {
+		compression float64
+		centroids   []centroid      // merged centroids ordered by mean
+		buffer      []centroid      // values added since the last merge
+		scratch     centroidsByMean // reused by merge so that merging doesn't allocate
+		count       float64
+		min         float64
+		max         float64
+	}
+
+	// centroid summarizes weight values by their mean
+	centroid struct {
+		mean   float64
+		weight float64
+	}
+
+	// centroidsByMean sorts centroids in ascending order of their means. It
+	// is sorted through a pointer so that sorting doesn't allocate.
+	centroidsByMean struct {
+		centroids []centroid
+	}
+)
+
+func (c *centroidsByMean) Len() int { return len(c.centroids) }
+func (c *centroidsByMean) Swap(i, j int) {
+	c.centroids[i], c.centroids[j] = c.centroids[j], c.centroids[i]
+}
+func (c *centroidsByMean) Less(i, j int) bool { return c.centroids[i].mean < c.centroids[j].mean }
+
+// newTDigest creates an empty tDigest with the given compression
+func newTDigest(compression float64) *tDigest {
+	return &tDigest{
+		compression: compression,
+		buffer:      make([]centroid, 0, int(compression)*5),
+		min:         math.Inf(1),
+		max:         math.Inf(-1),
+	}
+}
+
+// add adds a value to the digest
+func (d *tDigest) add(x float64) {
+	d.buffer = append(d.buffer, centroid{mean: x, weight: 1})
+	d.count++
+	d.min = math.Min(d.min, x)
+	d.max = math.Max(d.max, x)
+	if len(d.buffer) == cap(d.buffer) {
+		d.merge()
+	}
+}
+
+// merge folds the buffered values into the centroids. Neighboring centroids
+// are combined as long as they span at most one unit of the k1 scale function.
+// The centroids are gathered in the scratch space and merged back into the
+// centroids slice, so a digest stops allocating once it reaches its size.
+func (d *tDigest) merge() {
+	if len(d.buffer) == 0 {
+		return
+	}
+	d.scratch.centroids = append(append(d.scratch.centroids[:0], d.centroids...), d.buffer...)
+	sort.Sort(&d.scratch)
+	all := d.scratch.centroids
+
+	merged := d.centroids[:0]
+	current := all[0]
+	weightSoFar := 0.0
+	for _, next := range all[1:] {
+		proposed := current.weight + next.weight
+		if d.scale((weightSoFar+proposed)/d.count)-d.scale(weightSoFar/d.count) <= 1 {
+			current.mean += (next.mean - current.mean) * next.weight / proposed
+			current.weight = proposed
+			continue
+		}
+		merged = append(merged, current)
+		weightSoFar += current.weight
+		current = next
+	}
+	d.centroids = append(merged, current)
+	d.buffer = d.buffer[:0]
+}
+
+// scale is the k1 scale function, which maps the quantile q to the index of
+// the centroid holding it
+func (d *tDigest) scale(q float64) float64 {
+	return d.compression / (2 * math.Pi) * math.Asin(2*math.Min(q, 1)-1)
+}
+
+// quantile estimates the value at quantile q, from 0 to 1, by interpolating
+// between the centroids. The estimate lines up with rank q*(count-1) of the
+// sorted values, so it is exact for whole ranks while each value has its own
+// centroid. Returns NaN for an empty digest.
+func (d *tDigest) quantile(q float64) float64 {
+	d.merge()
+	if len(d.centroids) == 0 {
+		return math.NaN()
+	}
+	if len(d.centroids) == 1 {
+		return d.centroids[0].mean
+	}
+
+	// the center of a centroid sits at the middle of the weight it holds, and
+	// the smallest and largest values sit half a unit in from the ends
+	index := q*(d.count-1) + 0.5
+
+	first := d.centroids[0]
+	if index < first.weight/2 {
+		return d.min + (first.mean-d.min)*(index-0.5)/(first.weight/2-0.5)
+	}
+
+	cumulative := 0.0
+	for i := 0; i < len(d.centroids)-1; i++ {
+		left, right := d.centroids[i], d.centroids[i+1]
+		leftCenter := cumulative + left.weight/2
+		rightCenter := cumulative + left.weight + right.weight/2
+		if index <= rightCenter {
+			return left.mean + (right.mean-left.mean)*(index-leftCenter)/(rightCenter-leftCenter)
+		}
+		cumulative += left.weight
+	}
+
+	last := d.centroids[len(d.centroids)-1]
+	lastCenter := d.count - last.weight/2
+	return last.mean + (d.max-last.mean)*math.Min((index-lastCenter)/(last.weight/2-0.5), 1)
+}
@@ -0,0 +1,52 @@
+package beacon
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTDigestSmallIsExact(t *testing.T) {
+	// every value keeps its own centroid while there are few of them, and
+	// the quartiles of nine values fall on whole ranks
+	values := []float64{12, 300, 298, 303, 3600, 301, 299, 300, 60}
+	d := newTDigest(tDigestCompression)
+	for _, value := range values {
+		d.add(value)
+	}
+	sort.Float64s(values)
+
+	for _, q := range []float64{0, .25, .5, .75, 1} {
+		expected := values[int(math.Round(q*float64(len(values)-1)))]
+		assert.Equal(t, expected, d.quantile(q), "quantile %v", q)
+	}
+}
+
+func TestTDigestQuantiles(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := make([]float64, 200000)
+	d := newTDigest(tDigestCompression)
+	for i := range values {
+		values[i] = 300 + rng.NormFloat64()*30
+		d.add(values[i])
+	}
+	sort.Float64s(values)
+
+	// the centroids stay bounded by the compression
+	d.merge()
+	assert.Less(t, len(d.centroids), 2*tDigestCompression)
+
+	for _, q := range []float64{.01, .25, .5, .75, .99} {
+		expected := values[int(math.Round(q*float64(len(values)-1)))]
+		assert.InDelta(t, expected, d.quantile(q), 0.5, "quantile %v", q)
+	}
+	assert.Equal(t, values[0], d.quantile(0))
+	assert.Equal(t, values[len(values)-1], d.quantile(1))
+}
+
+func TestTDigestEmpty(t *testing.T) {
+	assert.True(t, math.IsNaN(newTDigest(tDigestCompression).quantile(.5)))
+}
@@ -0,0 +1,78 @@
+package beaconproxy
+
+import (
+	"sort"
+	"strings"
+)
+
+// FQDNResult groups the proxy beacons to a single destination FQDN. Proxy
+// beacons are keyed by their source and FQDN, so many clients beaconing to the
+// same server through one or more proxies appear as separate beacons.
+type FQDNResult struct {
+	FQDN        string
+	Beacons     []Result // sorted by score
+	SrcCount    int
+	ProxyCount  int
+	Connections int64
+	MeanScore   float64 // average score weighted by connection count
+	Score       float64 // highest score among the beacons
+}
+
+// AggregateByFQDN groups proxy beacons by their destination FQDN regardless of
+// the client which made the connections or the proxy which serviced them.
+// FQDNs are compared case insensitively. The groups are returned sorted by
+// Score.
+func AggregateByFQDN(beacons []Result) []FQDNResult {
+	var groups []*FQDNResult
+	index := make(map[string]int)
+	srcs := make(map[string]map[string]struct{})
+	proxies := make(map[string]map[string]struct{})
+
+	for _, beacon := range beacons {
+		fqdn := strings.ToLower(beacon.FQDN)
+		i, ok := index[fqdn]
+		if !ok {
+			i = len(groups)
+			index[fqdn] = i
+			groups = append(groups, &FQDNResult{FQDN: fqdn})
+			srcs[fqdn] = make(map[string]struct{})
+			proxies[fqdn] = make(map[string]struct{})
+		}
+
+		group := groups[i]
+		group.Beacons = append(group.Beacons, beacon)
+		group.Connections += beacon.Connections
+
+		// sum the weighted scores here and divide them out once every
+		// beacon has been grouped
+		group.MeanScore += beacon.Score * float64(beacon.Connections)
+
+		if beacon.Score > group.Score {
+			group.Score = beacon.Score
+		}
+
+		srcs[fqdn][beacon.SrcIP+string(beacon.SrcNetworkUUID.Data)] = struct{}{}
+		for _, proxy := range beacon.Proxies() {
+			proxies[fqdn][proxy.IP+string(proxy.NetworkUUID.Data)] = struct{}{}
+		}
+	}
+
+	results := make([]FQDNResult, 0, len(groups))
+	for _, group := range groups {
+		if group.Connections > 0 {
+			group.MeanScore /= float64(group.Connections)
+		}
+		group.SrcCount = len(srcs[group.FQDN])
+		group.ProxyCount = len(proxies[group.FQDN])
+		sort.SliceStable(group.Beacons, func(i, j int) bool {
+			return group.Beacons[i].Score > group.Beacons[j].Score
+		})
+		results = append(results, *group)
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results
+}
@@ -0,0 +1,70 @@
+package beaconproxy
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestFQDNBeacon(src, fqdn, proxy string, conns int64, score float64) Result {
+	return Result{
+		FQDN:        fqdn,
+		SrcIP:       src,
+		Connections: conns,
+		Score:       score,
+		Proxy:       data.UniqueIP{IP: proxy},
+	}
+}
+
+func TestAggregateByFQDN(t *testing.T) {
+	beacons := []Result{
+		newTestFQDNBeacon("10.0.0.1", "c2.example.com", "10.0.0.254", 100, 0.6),
+		newTestFQDNBeacon("10.0.0.2", "c2.example.com", "10.0.0.254", 300, 0.9),
+		newTestFQDNBeacon("10.0.0.3", "C2.Example.com", "10.0.1.254", 100, 0.7),
+		newTestFQDNBeacon("10.0.0.1", "updates.example.com", "10.0.0.254", 50, 0.95),
+		newTestFQDNBeacon("10.0.0.4", "cdn.example.net", "10.0.0.254", 10, 0.5),
+	}
+
+	results := AggregateByFQDN(beacons)
+	require.Len(t, results, 3)
+
+	// sorted by the highest score in each group
+	assert.Equal(t, "updates.example.com", results[0].FQDN)
+	assert.Equal(t, 1, results[0].SrcCount)
+	assert.Equal(t, 0.95, results[0].MeanScore)
+
+	// the clients of c2.example.com collapse into one group across proxies
+	c2 := results[1]
+	assert.Equal(t, "c2.example.com", c2.FQDN)
+	assert.Equal(t, 3, c2.SrcCount)
+	assert.Equal(t, 2, c2.ProxyCount)
+	assert.Equal(t, int64(500), c2.Connections)
+	assert.Equal(t, 0.9, c2.Score)
+	assert.InDelta(t, (0.6*100+0.9*300+0.7*100)/500, c2.MeanScore, 1e-9)
+	require.Len(t, c2.Beacons, 3)
+	assert.Equal(t, "10.0.0.2", c2.Beacons[0].SrcIP)
+	assert.Equal(t, "10.0.0.3", c2.Beacons[1].SrcIP)
+	assert.Equal(t, "10.0.0.1", c2.Beacons[2].SrcIP)
+
+	assert.Equal(t, "cdn.example.net", results[2].FQDN)
+}
+
+func TestAggregateByFQDNSourceNetworks(t *testing.T) {
+	// hosts sharing a private address on separate networks are separate sources
+	office := newTestFQDNBeacon("10.0.0.1", "c2.example.com", "10.0.0.254", 10, 0.8)
+	office.SrcNetworkUUID = bson.Binary{Kind: bson.BinaryUUID, Data: []byte("office")}
+	lab := newTestFQDNBeacon("10.0.0.1", "c2.example.com", "10.0.0.254", 10, 0.8)
+	lab.SrcNetworkUUID = bson.Binary{Kind: bson.BinaryUUID, Data: []byte("lab")}
+
+	results := AggregateByFQDN([]Result{office, lab})
+	require.Len(t, results, 1)
+	assert.Equal(t, 2, results[0].SrcCount)
+	assert.Equal(t, 1, results[0].ProxyCount)
+}
+
+func TestAggregateByFQDNEmpty(t *testing.T) {
+	assert.Empty(t, AggregateByFQDN(nil))
+}
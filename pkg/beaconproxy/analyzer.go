@@ -4,6 +4,7 @@ import (
 	"math"
 	"sort"
 	"sync"
+	"sync/atomic"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
@@ -14,6 +15,11 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// minIntervals is the fewest intervals between the timestamps of a proxied
+// unique connection which must stay above 0 once they are rounded to the
+// interval resolution for the intervals to be scored
+const minIntervals = 2
+
 type (
 	//analyzer handles calculating statistical measures of the distribution of timestamps
 	//between pairs of proxied hosts
@@ -24,23 +30,68 @@ type (
 		db               *database.DB               // provides access to MongoDB
 		conf             *config.Config             // contains details needed to access MongoDB
 		log              *log.Logger                // main logger for RITA
-		analyzedCallback func(database.BulkChanges) // called on each analyzed result
+		workers          int                        // number of goroutines consuming analysisChannel
+		weights          scoreWeights               // weights of the timestamp subscores
+		analyzedCallback func(database.BulkChanges) // called on each analyzed result (from every worker at once)
 		closedCallback   func()                     // called when .close() is called and no more calls to analyzedCallback will be made
 		analysisChannel  chan *uconnproxy.Input     // holds unanalyzed data
 		analysisWg       sync.WaitGroup             // wait for analysis to finish
+		dropped          int64                      // number of entries skipped for having too few intervals (accessed atomically)
+		belowThreshold   int64                      // number of entries skipped for having too few connections (accessed atomically)
+	}
+
+	//scoreWeights weights the timestamp subscores in the timestamp score
+	scoreWeights struct {
+		skew      float64
+		madm      float64
+		connCount float64
+	}
+
+	//result holds the timestamp and data size statistics and scores of a proxied unique connection
+	result struct {
+		tsIntervalRange  int64   // range between the shortest and longest non-zero interval
+		tsMode           int64   // most common interval
+		tsModeCount      int64   // number of times the most common interval occurred
+		intervals        []int64 // distinct intervals
+		intervalCounts   []int64 // number of times each distinct interval occurred
+		tsDispersion     int64   // median absolute deviation about the median interval
+		tsSkew           float64 // Bowley skew of the intervals
+		tsSkewScore      float64 // 1 - |tsSkew|
+		tsMadmScore      float64 // 1 - tsDispersion / median interval
+		tsConnCountScore float64 // connections per hour of the dataset, up to 1
+		tsFreqScore      float64 // strength of the dominant frequency of the timestamps
+		tsFreqScored     bool    // whether tsFreqScore is one of the timestamp subscores
+		tsScore          float64 // weighted mean of the timestamp subscores
+		dsRange          int64   // range between the smallest and largest size
+		dsMode           int64   // most common size
+		dsModeCount      int64   // number of times the most common size occurred
+		dsDispersion     int64   // median absolute deviation about the median size
+		dsSkew           float64 // Bowley skew of the sizes
+		dsSkewScore      float64 // 1 - |dsSkew|
+		dsMadmScore      float64 // 1 - dsDispersion / median size
+		dsScore          float64 // mean of the data size subscores
+		dsScored         bool    // whether the data sizes were scored
+		score            float64 // overall proxy beacon score
 	}
 )
 
-// newAnalyzer creates a new analyzer for calculating the beacon statistics of proxied unique connections
-func newAnalyzer(min int64, max int64, chunk int, db *database.DB, conf *config.Config, log *log.Logger,
+// newAnalyzer creates a new analyzer for calculating the beacon statistics of proxied unique connections.
+// start launches workers goroutines, so analyzedCallback must be safe to call concurrently.
+func newAnalyzer(min int64, max int64, chunk int, db *database.DB, conf *config.Config, log *log.Logger, workers int,
 	analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
 	return &analyzer{
-		tsMin:            min,
-		tsMax:            max,
-		chunk:            chunk,
-		db:               db,
-		conf:             conf,
-		log:              log,
+		tsMin:   min,
+		tsMax:   max,
+		chunk:   chunk,
+		db:      db,
+		conf:    conf,
+		log:     log,
+		workers: util.Max(1, workers),
+		weights: scoreWeights{
+			skew:      conf.S.BeaconProxy.Scoring.SkewWeight,
+			madm:      conf.S.BeaconProxy.Scoring.MadmWeight,
+			connCount: conf.S.BeaconProxy.Scoring.ConnCountWeight,
+		},
 		analyzedCallback: analyzedCallback,
 		closedCallback:   closedCallback,
 		analysisChannel:  make(chan *uconnproxy.Input),
@@ -56,148 +107,345 @@ func (a *analyzer) collect(data *uconnproxy.Input) {
 func (a *analyzer) close() {
 	close(a.analysisChannel)
 	a.analysisWg.Wait()
+
+	if dropped := a.droppedCount(); dropped > 0 {
+		a.log.WithFields(log.Fields{
+			"Module":  "beaconproxy",
+			"Dropped": dropped,
+		}).Warn("Skipped proxied connections with too few intervals to score")
+	}
+
+	if belowThreshold := a.belowThresholdCount(); belowThreshold > 0 {
+		a.log.WithFields(log.Fields{
+			"Module":    "beaconproxy",
+			"Skipped":   belowThreshold,
+			"Threshold": a.conf.S.BeaconProxy.ConnectionThreshold,
+		}).Info("Skipped proxied connections with fewer connections than the ConnectionThreshold")
+	}
+
 	a.closedCallback()
 }
 
-// start kicks off a new analysis thread
+// droppedCount returns the number of entries skipped for having too few intervals
+func (a *analyzer) droppedCount() int64 {
+	return atomic.LoadInt64(&a.dropped)
+}
+
+// belowThresholdCount returns the number of entries skipped for having fewer
+// connections than the ConnectionThreshold
+func (a *analyzer) belowThresholdCount() int64 {
+	return atomic.LoadInt64(&a.belowThreshold)
+}
+
+// start kicks off the analysis threads
 func (a *analyzer) start() {
-	a.analysisWg.Add(1)
-	go func() {
-
-		for entry := range a.analysisChannel {
-
-			//store the diffFull slice length since we use it a lot
-			//for timestamps this is one less then the data slice length
-			//since we are calculating the times in between readings
-			tsLength := len(entry.TsList) - 1
-
-			//find the delta times between the timestamps and sort
-			diffFull := make([]int64, tsLength)
-			for i := 0; i < tsLength; i++ {
-				interval := entry.TsList[i+1] - entry.TsList[i]
-				diffFull[i] = interval
-			}
-			sort.Sort(util.SortableInt64(diffFull))
-
-			// We are excluding delta zero for scoring calculations
-			// but using a separate array that includes it for making
-			// the user/ graph reference variables returned by createCountMap.
-
-			// Search for the section of diffFull without any 0's in it
-			// The dissector guarantees that there are at least three unique timestamps in res.TsList
-			// as a result, we are guaranteed to find at least two non-zero intervals in diffFull
-			diffNonZeroIdx := 0
-			for i := 0; i < len(diffFull); i++ {
-				if diffFull[i] > 0 {
-					diffNonZeroIdx = i
-					break
-				}
-			}
-
-			diff := diffFull[diffNonZeroIdx:] // select the part of diffFull without any 0's
-
-			//store the diff slice length
-			diffLength := len(diff)
-
-			//perfect beacons should have symmetric delta time and size distributions
-			//Bowley's measure of skew is used to check symmetry
-			tsSkew := float64(0)
-
-			//diffLength-1 is used since diff is a zero based slice
-			tsLow := diff[util.Round(.25*float64(diffLength-1))]
-			tsMid := diff[util.Round(.5*float64(diffLength-1))]
-			tsHigh := diff[util.Round(.75*float64(diffLength-1))]
-			tsBowleyNum := tsLow + tsHigh - 2*tsMid
-			tsBowleyDen := tsHigh - tsLow
-
-			//tsSkew should equal zero if the denominator equals zero
-			//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
-			if tsBowleyDen != 0 && tsMid != tsLow && tsMid != tsHigh {
-				tsSkew = float64(tsBowleyNum) / float64(tsBowleyDen)
-			}
-
-			//perfect beacons should have very low dispersion around the
-			//median of their delta times
-			//Median Absolute Deviation About the Median
-			//is used to check dispersion
-			devs := make([]int64, diffLength)
-			for i := 0; i < diffLength; i++ {
-				devs[i] = util.Abs(diff[i] - tsMid)
-			}
-
-			sort.Sort(util.SortableInt64(devs))
-
-			tsMadm := devs[util.Round(.5*float64(diffLength-1))]
-
-			//Store the range for human analysis
-			tsIntervalRange := diff[diffLength-1] - diff[0]
-
-			//get a list of the intervals found in the data,
-			//the number of times the interval was found,
-			//and the most occurring interval
-			intervals, intervalCounts, tsMode, tsModeCount := createCountMap(diffFull)
-
-			//more skewed distributions receive a lower score
-			//less skewed distributions receive a higher score
-			tsSkewScore := 1.0 - math.Abs(tsSkew) //smush tsSkew
-
-			//lower dispersion is better
-			tsMadmScore := 1.0
-			if tsMid >= 1 {
-				tsMadmScore = 1.0 - float64(tsMadm)/float64(tsMid)
-			}
-			if tsMadmScore < 0 {
-				tsMadmScore = 0
-			}
-
-			// connection count scoring
-			tsConnDiv := (float64(a.tsMax) - float64(a.tsMin)) / 3600
-			tsConnCountScore := float64(entry.ConnectionCount) / tsConnDiv
-			if tsConnCountScore > 1.0 {
-				tsConnCountScore = 1.0
-			}
-
-			//score numerators
-			tsSum := tsSkewScore + tsMadmScore + tsConnCountScore
-
-			//score averages
-			tsScore := math.Ceil((tsSum/3.0)*1000) / 1000
-			score := math.Ceil((tsSum/3.0)*1000) / 1000
-
-			// copy variables to be used by bulk callback to prevent capturing by reference
-			pairSelector := entry.Hosts.BSONKey()
-			proxyBeaconQuery := bson.M{
-				"$set": bson.M{
-					"connection_count":   entry.ConnectionCount,
-					"proxy":              entry.Proxy,
-					"src_network_name":   entry.Hosts.SrcNetworkName,
-					"ts.range":           tsIntervalRange,
-					"ts.mode":            tsMode,
-					"ts.mode_count":      tsModeCount,
-					"ts.intervals":       intervals,
-					"ts.interval_counts": intervalCounts,
-					"ts.dispersion":      tsMadm,
-					"ts.skew":            tsSkew,
-					"ts.conns_score":     tsConnCountScore,
-					"ts.score":           tsScore,
-					"score":              score,
-					"cid":                a.chunk,
-				},
-			}
-
-			update := database.BulkChanges{
-				a.conf.T.BeaconProxy.BeaconProxyTable: []database.BulkChange{{
-					Selector: pairSelector,
-					Update:   proxyBeaconQuery,
-					Upsert:   true,
-				}},
-			}
-
-			a.analyzedCallback(update)
+	for i := 0; i < a.workers; i++ {
+		a.analysisWg.Add(1)
+		go a.analyze()
+	}
+}
+
+// analyze scores the entries of the analysis channel until it is closed
+func (a *analyzer) analyze() {
+	a.conf.R.AnalysisLimiter.Acquire()
+	defer a.conf.R.AnalysisLimiter.Release()
+
+	for entry := range a.analysisChannel {
+		// pairs with only a few connections produce noisy scores, so they
+		// are skipped before any scoring and no proxy beacon is stored
+		if entry.ConnectionCount < a.conf.S.BeaconProxy.ConnectionThreshold {
+			atomic.AddInt64(&a.belowThreshold, 1)
+			continue
+		}
+
+		// the dissector only passes along entries with enough unique
+		// timestamps, but bursts whose intervals are shorter than half of the
+		// IntervalResolution round to 0. Their intervals would look perfectly
+		// regular, and scoring fewer than two would index past the intervals
+		// and stop this goroutine.
+		if nonZeroIntervals(entry.TsList, a.conf.S.BeaconProxy.IntervalResolution) < minIntervals {
+			atomic.AddInt64(&a.dropped, 1)
+			a.log.WithFields(log.Fields{
+				"Module":     "beaconproxy",
+				"src":        entry.Hosts.SrcIP,
+				"fqdn":       entry.Hosts.FQDN,
+				"timestamps": len(entry.TsList),
+			}).Debug("Skipped a proxied connection with too few intervals to score")
+			continue
 		}
 
-		a.analysisWg.Done()
-	}()
+		res := scoreTimestamps(entry.TsList, entry.ConnectionCount, a.tsMin, a.tsMax, a.conf.S.BeaconProxy.IntervalResolution, a.weights, a.conf.S.BeaconProxy.TsFreqEnabled)
+		res.scoreDatasizes(entry.BytesList)
+
+		// copy variables to be used by bulk callback to prevent capturing by reference
+		pairSelector := entry.Hosts.Canonical().BSONKey()
+		proxyBeaconQuery := res.update(entry, a.chunk)
+
+		update := database.BulkChanges{
+			a.conf.T.BeaconProxy.BeaconProxyTable: []database.BulkChange{{
+				Selector: pairSelector,
+				Update:   proxyBeaconQuery,
+				Upsert:   true,
+			}},
+		}
+
+		a.analyzedCallback(update)
+	}
+
+	a.analysisWg.Done()
+}
+
+// scoreTimestamps calculates the beacon statistics and scores of the sorted
+// connection timestamps of a proxied unique connection. tsMin and tsMax bound
+// the timestamps of the whole dataset. Each interval is rounded to the nearest
+// multiple of resolution seconds so that jitter no beacon controls doesn't
+// count against it. The timestamp score is the mean of the
+// subscores weighted by weights. If freqScoring is set, the strength of the
+// dominant frequency of the timestamps is averaged into the timestamp score
+// as well with a weight of 1.
+func scoreTimestamps(tsList []int64, connectionCount int64, tsMin, tsMax int64, resolution int64, weights scoreWeights, freqScoring bool) result {
+	//store the diffFull slice length since we use it a lot
+	//for timestamps this is one less then the data slice length
+	//since we are calculating the times in between readings
+	tsLength := len(tsList) - 1
+
+	//find the delta times between the timestamps, round them
+	//to the interval resolution, and sort
+	diffFull := make([]int64, tsLength)
+	for i := 0; i < tsLength; i++ {
+		interval := tsList[i+1] - tsList[i]
+		diffFull[i] = roundInterval(interval, resolution)
+	}
+	sort.Sort(util.SortableInt64(diffFull))
+
+	// We are excluding delta zero for scoring calculations
+	// but using a separate array that includes it for making
+	// the user/ graph reference variables returned by createCountMap.
+
+	// Search for the section of diffFull without any 0's in it
+	// The analyzer only scores timestamps with at least two intervals which
+	// don't round to 0, so at least two non-zero intervals are found in diffFull
+	diffNonZeroIdx := 0
+	for i := 0; i < len(diffFull); i++ {
+		if diffFull[i] > 0 {
+			diffNonZeroIdx = i
+			break
+		}
+	}
+
+	diff := diffFull[diffNonZeroIdx:] // select the part of diffFull without any 0's
+
+	//store the diff slice length
+	diffLength := len(diff)
+
+	//perfect beacons should have symmetric delta time and size distributions
+	//Bowley's measure of skew is used to check symmetry
+	tsSkew := float64(0)
+
+	//diffLength-1 is used since diff is a zero based slice
+	tsLow := diff[util.Round(.25*float64(diffLength-1))]
+	tsMid := diff[util.Round(.5*float64(diffLength-1))]
+	tsHigh := diff[util.Round(.75*float64(diffLength-1))]
+	tsBowleyNum := tsLow + tsHigh - 2*tsMid
+	tsBowleyDen := tsHigh - tsLow
+
+	//tsSkew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if tsBowleyDen != 0 && tsMid != tsLow && tsMid != tsHigh {
+		tsSkew = float64(tsBowleyNum) / float64(tsBowleyDen)
+	}
+
+	//perfect beacons should have very low dispersion around the
+	//median of their delta times
+	//Median Absolute Deviation About the Median
+	//is used to check dispersion
+	devs := make([]int64, diffLength)
+	for i := 0; i < diffLength; i++ {
+		devs[i] = util.Abs(diff[i] - tsMid)
+	}
+
+	sort.Sort(util.SortableInt64(devs))
+
+	tsMadm := devs[util.Round(.5*float64(diffLength-1))]
+
+	//Store the range for human analysis
+	tsIntervalRange := diff[diffLength-1] - diff[0]
+
+	//get a list of the intervals found in the data,
+	//the number of times the interval was found,
+	//and the most occurring interval
+	intervals, intervalCounts, tsMode, tsModeCount := createCountMap(diffFull)
+
+	//more skewed distributions receive a lower score
+	//less skewed distributions receive a higher score
+	tsSkewScore := 1.0 - math.Abs(tsSkew) //smush tsSkew
+
+	//lower dispersion is better
+	tsMadmScore := 1.0
+	if tsMid >= 1 {
+		tsMadmScore = 1.0 - float64(tsMadm)/float64(tsMid)
+	}
+	if tsMadmScore < 0 {
+		tsMadmScore = 0
+	}
+
+	// connection count scoring
+	// count connections over at least an hour so a dataset whose
+	// timestamps fall within a single instant doesn't divide by zero
+	tsConnDiv := math.Max((float64(tsMax)-float64(tsMin))/3600, 1)
+	tsConnCountScore := float64(connectionCount) / tsConnDiv
+	if tsConnCountScore > 1.0 {
+		tsConnCountScore = 1.0
+	}
+
+	//score numerators
+	tsSum := weights.skew*tsSkewScore + weights.madm*tsMadmScore + weights.connCount*tsConnCountScore
+	tsWeights := weights.skew + weights.madm + weights.connCount
+
+	// strongly periodic connections may still have multimodal intervals,
+	// such as a jitter which flips between two multiples of the period
+	tsFreqScore := 0.0
+	if freqScoring {
+		tsFreqScore = getTsFreqScore(tsList)
+		tsSum += tsFreqScore
+		tsWeights++
+	}
+
+	//score averages
+	tsScore := math.Ceil((tsSum/tsWeights)*1000) / 1000
+	score := math.Ceil((tsSum/tsWeights)*1000) / 1000
+
+	return result{
+		tsIntervalRange:  tsIntervalRange,
+		tsMode:           tsMode,
+		tsModeCount:      tsModeCount,
+		intervals:        intervals,
+		intervalCounts:   intervalCounts,
+		tsDispersion:     tsMadm,
+		tsSkew:           tsSkew,
+		tsSkewScore:      tsSkewScore,
+		tsMadmScore:      tsMadmScore,
+		tsConnCountScore: tsConnCountScore,
+		tsFreqScore:      tsFreqScore,
+		tsFreqScored:     freqScoring,
+		tsScore:          tsScore,
+		score:            score,
+	}
+}
+
+// scoreDatasizes calculates the data size statistics and scores of the sizes
+// of the connections of a proxied unique connection using the skew and
+// dispersion measures used for the timestamps. The overall score becomes the
+// higher of the timestamp and data size scores so that connections which
+// beacon in either timing or size are flagged. Connections imported by older
+// versions of RITA have no sizes and are only scored by their timestamps.
+func (r *result) scoreDatasizes(sizes []int64) {
+	dsLength := len(sizes)
+	if dsLength == 0 {
+		return
+	}
+
+	dsList := make([]int64, dsLength)
+	copy(dsList, sizes)
+	sort.Sort(util.SortableInt64(dsList))
+
+	//perfect beacons should have symmetric data size distributions
+	//Bowley's measure of skew is used to check symmetry
+	dsSkew := float64(0)
+
+	//dsLength-1 is used since dsList is a zero based slice
+	dsLow := dsList[util.Round(.25*float64(dsLength-1))]
+	dsMid := dsList[util.Round(.5*float64(dsLength-1))]
+	dsHigh := dsList[util.Round(.75*float64(dsLength-1))]
+	dsBowleyNum := dsLow + dsHigh - 2*dsMid
+	dsBowleyDen := dsHigh - dsLow
+
+	//dsSkew should equal zero if the denominator equals zero
+	//bowley skew is unreliable if Q2 = Q1 or Q2 = Q3
+	if dsBowleyDen != 0 && dsMid != dsLow && dsMid != dsHigh {
+		dsSkew = float64(dsBowleyNum) / float64(dsBowleyDen)
+	}
+
+	//perfect beacons should have very low dispersion around the
+	//median of their data sizes
+	//Median Absolute Deviation About the Median
+	//is used to check dispersion
+	dsDevs := make([]int64, dsLength)
+	for i := 0; i < dsLength; i++ {
+		dsDevs[i] = util.Abs(dsList[i] - dsMid)
+	}
+	sort.Sort(util.SortableInt64(dsDevs))
+	dsMadm := dsDevs[util.Round(.5*float64(dsLength-1))]
+
+	_, _, dsMode, dsModeCount := createCountMap(dsList)
+
+	//more skewed distributions receive a lower score
+	dsSkewScore := 1.0 - math.Abs(dsSkew)
+
+	//lower dispersion is better
+	dsMadmScore := 0.0
+	if dsMid >= 1 {
+		dsMadmScore = 1.0 - float64(dsMadm)/float64(dsMid)
+	}
+	if dsMadmScore < 0 {
+		dsMadmScore = 0
+	}
+
+	r.dsRange = dsList[dsLength-1] - dsList[0]
+	r.dsMode = dsMode
+	r.dsModeCount = dsModeCount
+	r.dsDispersion = dsMadm
+	r.dsSkew = dsSkew
+	r.dsSkewScore = dsSkewScore
+	r.dsMadmScore = dsMadmScore
+	r.dsScore = math.Ceil(((dsSkewScore+dsMadmScore)/2.0)*1000) / 1000
+	r.dsScored = true
+
+	r.score = math.Max(r.tsScore, r.dsScore)
+}
+
+// update translates the result into the update for the proxy beacon document of the entry
+func (r result) update(entry *uconnproxy.Input, chunk int) bson.M {
+	query := bson.M{
+		"$set": bson.M{
+			"connection_count":   entry.ConnectionCount,
+			"proxy":              entry.Proxy,
+			"src_network_name":   entry.Hosts.SrcNetworkName,
+			"ts.range":           r.tsIntervalRange,
+			"ts.mode":            r.tsMode,
+			"ts.mode_count":      r.tsModeCount,
+			"ts.intervals":       r.intervals,
+			"ts.interval_counts": r.intervalCounts,
+			"ts.dispersion":      r.tsDispersion,
+			"ts.skew":            r.tsSkew,
+			"ts.conns_score":     r.tsConnCountScore,
+			"ts.score":           r.tsScore,
+			"score":              r.score,
+			"cid":                chunk,
+		},
+	}
+
+	if r.tsFreqScored {
+		query["$set"].(bson.M)["ts.freq_score"] = r.tsFreqScore
+	}
+
+	if r.dsScored {
+		query["$set"].(bson.M)["ds.range"] = r.dsRange
+		query["$set"].(bson.M)["ds.mode"] = r.dsMode
+		query["$set"].(bson.M)["ds.mode_count"] = r.dsModeCount
+		query["$set"].(bson.M)["ds.dispersion"] = r.dsDispersion
+		query["$set"].(bson.M)["ds.skew"] = r.dsSkew
+		query["$set"].(bson.M)["ds.score"] = r.dsScore
+	}
+
+	// the proxies of every chunk were gathered by the dissector, so the list
+	// is removed once the connections of all but one proxy have been removed
+	if proxies := entry.ProxyList(); proxies != nil {
+		query["$set"].(bson.M)["proxy_list"] = proxies
+	} else {
+		query["$unset"] = bson.M{"proxy_list": ""}
+	}
+	return query
 }
 
 // createCountMap returns a distinct data array, data count array, the mode,
@@ -242,3 +490,25 @@ func countAndRemoveConsecutiveDuplicates(numberList []int64) ([]int64, map[int64
 	}
 	return result, counts
 }
+
+// nonZeroIntervals counts the intervals between the sorted timestamps which
+// don't round to 0 at the given resolution
+func nonZeroIntervals(tsList []int64, resolution int64) int {
+	count := 0
+	for i := 1; i < len(tsList); i++ {
+		if roundInterval(tsList[i]-tsList[i-1], resolution) > 0 {
+			count++
+		}
+	}
+	return count
+}
+
+// roundInterval rounds an interval to the nearest multiple of resolution
+// seconds, rounding halfway intervals up. Resolutions below 2 leave the
+// interval as it is.
+func roundInterval(interval, resolution int64) int64 {
+	if resolution < 2 {
+		return interval
+	}
+	return (interval + resolution/2) / resolution * resolution
+}
@@ -0,0 +1,567 @@
+package beaconproxy
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconnproxy"
+	"github.com/globalsign/mgo/bson"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTimestamps returns the timestamps of connections separated by the given intervals
+func newTestTimestamps(start int64, intervals ...int64) []int64 {
+	tsList := []int64{start}
+	for _, interval := range intervals {
+		tsList = append(tsList, tsList[len(tsList)-1]+interval)
+	}
+	return tsList
+}
+
+// equalWeights weights each timestamp subscore equally, as in the default config
+var equalWeights = scoreWeights{skew: 1, madm: 1, connCount: 1}
+
+func TestScoreTimestampsPerfectBeacon(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	intervals := make([]int64, 47)
+	for i := range intervals {
+		intervals[i] = 60
+	}
+	res := scoreTimestamps(newTestTimestamps(tsMin, intervals...), 48, tsMin, tsMax, 1, equalWeights, false)
+
+	assert.Equal(t, []int64{60}, res.intervals)
+	assert.Equal(t, []int64{47}, res.intervalCounts)
+	assert.Equal(t, int64(60), res.tsMode)
+	assert.Equal(t, int64(47), res.tsModeCount)
+	assert.Equal(t, int64(0), res.tsIntervalRange)
+	assert.Equal(t, int64(0), res.tsDispersion)
+	assert.Equal(t, 0.0, res.tsSkew)
+
+	assert.Equal(t, 1.0, res.tsSkewScore)
+	assert.Equal(t, 1.0, res.tsMadmScore)
+	assert.Equal(t, 1.0, res.tsConnCountScore, "48 connections in 24 hours should be capped at 1")
+	assert.Equal(t, 1.0, res.tsScore)
+	assert.Equal(t, 1.0, res.score)
+}
+
+func TestScoreTimestampsSkewedIntervals(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 10*3600
+
+	// the sorted intervals have quartiles of 20, 30, and 70
+	res := scoreTimestamps(newTestTimestamps(tsMin, 30, 100, 10, 70, 20), 6, tsMin, tsMax, 1, equalWeights, false)
+
+	assert.Equal(t, []int64{10, 20, 30, 70, 100}, res.intervals)
+	assert.Equal(t, []int64{1, 1, 1, 1, 1}, res.intervalCounts)
+	assert.Equal(t, int64(10), res.tsMode, "the shortest interval is the mode when every interval is unique")
+	assert.Equal(t, int64(90), res.tsIntervalRange)
+
+	// (20 + 70 - 2*30) / (70 - 20)
+	assert.InDelta(t, 0.6, res.tsSkew, 1e-9)
+	assert.InDelta(t, 0.4, res.tsSkewScore, 1e-9)
+
+	// the deviations from 30 are 0, 10, 20, 40, and 70
+	assert.Equal(t, int64(20), res.tsDispersion)
+	assert.InDelta(t, 1-20.0/30.0, res.tsMadmScore, 1e-9)
+
+	// 6 connections over 10 hours
+	assert.InDelta(t, 0.6, res.tsConnCountScore, 1e-9)
+
+	// the mean of the subscores rounded up to 3 decimal places
+	assert.Equal(t, 0.445, res.tsScore)
+	assert.Equal(t, res.tsScore, res.score)
+}
+
+func TestScoreTimestampsRepeatedTimestamps(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 3600
+
+	// connections sharing a timestamp are counted but not scored
+	res := scoreTimestamps(newTestTimestamps(tsMin, 0, 60, 60, 60), 5, tsMin, tsMax, 1, equalWeights, false)
+
+	assert.Equal(t, []int64{0, 60}, res.intervals)
+	assert.Equal(t, []int64{1, 3}, res.intervalCounts)
+	assert.Equal(t, int64(60), res.tsMode)
+	assert.Equal(t, int64(3), res.tsModeCount)
+	assert.Equal(t, int64(0), res.tsIntervalRange)
+	assert.Equal(t, 1.0, res.tsMadmScore)
+	assert.Equal(t, 1.0, res.tsConnCountScore)
+}
+
+// TestScoreTimestampsFrequency scores a beacon on a 30 second schedule which
+// skips up to two beats at a time, which spreads out its intervals
+func TestScoreTimestampsFrequency(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 3*3600
+
+	intervals := make([]int64, 119)
+	for i := range intervals {
+		intervals[i] = 30 * int64(1+i%3)
+	}
+	tsList := newTestTimestamps(tsMin, intervals...)
+
+	res := scoreTimestamps(tsList, 120, tsMin, tsMax, 1, equalWeights, false)
+	assert.Equal(t, 0.0, res.tsFreqScore)
+	assert.False(t, res.tsFreqScored)
+
+	freqRes := scoreTimestamps(tsList, 120, tsMin, tsMax, 1, equalWeights, true)
+	assert.Equal(t, 1.0, freqRes.tsFreqScore)
+	assert.True(t, freqRes.tsFreqScored)
+	assert.Equal(t, math.Ceil((res.tsSkewScore+res.tsMadmScore+res.tsConnCountScore+1.0)/4*1000)/1000, freqRes.tsScore,
+		"the frequency score should be averaged in with the other subscores")
+	assert.True(t, freqRes.tsScore > res.tsScore, "the schedule should raise the score of the beacon")
+	assert.Equal(t, freqRes.tsScore, freqRes.score)
+}
+
+func TestScoreTimestampsWeights(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 10*3600
+	tsList := newTestTimestamps(tsMin, 30, 100, 10, 70, 20)
+
+	equal := scoreTimestamps(tsList, 6, tsMin, tsMax, 1, equalWeights, false)
+
+	// zeroing a weight removes its subscore from ts.score
+	noConnCount := scoreTimestamps(tsList, 6, tsMin, tsMax, 1, scoreWeights{skew: 1, madm: 1}, false)
+	assert.Equal(t, math.Ceil((equal.tsSkewScore+equal.tsMadmScore)/2*1000)/1000, noConnCount.tsScore)
+	assert.Equal(t, noConnCount.tsScore, noConnCount.score)
+
+	skewOnly := scoreTimestamps(tsList, 6, tsMin, tsMax, 1, scoreWeights{skew: 1}, false)
+	assert.Equal(t, math.Ceil(equal.tsSkewScore*1000)/1000, skewOnly.tsScore)
+
+	// the subscores themselves are unaffected by the weights
+	assert.Equal(t, equal.tsConnCountScore, noConnCount.tsConnCountScore)
+
+	// weights are normalized by their sum
+	doubled := scoreTimestamps(tsList, 6, tsMin, tsMax, 1, scoreWeights{skew: 2, madm: 2, connCount: 2}, false)
+	assert.Equal(t, equal.tsScore, doubled.tsScore)
+
+	weighted := scoreTimestamps(tsList, 6, tsMin, tsMax, 1, scoreWeights{skew: 1, madm: 1, connCount: 2}, false)
+	assert.Equal(t, math.Ceil((equal.tsSkewScore+equal.tsMadmScore+2*equal.tsConnCountScore)/4*1000)/1000, weighted.tsScore)
+}
+
+// newJitteredTimestamps returns the timestamps of a perfect beacon whose
+// connections jitter by a few hundred microseconds around each period. The
+// periods fall on second boundaries, so truncating the timestamps to whole
+// seconds as the parser does leaves intervals a second longer or shorter than
+// the period.
+func newJitteredTimestamps(start int64, period int64, count int) []int64 {
+	jitters := []float64{-0.0003, 0.0002, 0.0004}
+	tsList := make([]int64, count)
+	for i := range tsList {
+		ts := float64(start) + float64(int64(i)*period) + jitters[i%len(jitters)]
+		tsList[i] = int64(math.Floor(ts))
+	}
+	return tsList
+}
+
+func TestScoreTimestampsIntervalResolution(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	tsList := newJitteredTimestamps(tsMin, 60, 200)
+
+	exact := scoreTimestamps(tsList, 200, tsMin, tsMax, 1, equalWeights, false)
+	require.Equal(t, []int64{59, 60, 61}, exact.intervals, "the jitter should split the period")
+	assert.Less(t, exact.tsModeCount, int64(199))
+	assert.Equal(t, int64(1), exact.tsDispersion)
+
+	// rounding to 5 seconds collapses the jitter into the beacon's period
+	rounded := scoreTimestamps(tsList, 200, tsMin, tsMax, 5, equalWeights, false)
+	assert.Equal(t, []int64{60}, rounded.intervals)
+	assert.Equal(t, []int64{199}, rounded.intervalCounts)
+	assert.Equal(t, int64(60), rounded.tsMode)
+	assert.Equal(t, int64(0), rounded.tsDispersion)
+	assert.Equal(t, 1.0, rounded.tsSkewScore)
+	assert.Equal(t, 1.0, rounded.tsMadmScore)
+	assert.Greater(t, rounded.tsScore, exact.tsScore)
+	assert.Greater(t, rounded.score, exact.score)
+}
+
+func TestRoundInterval(t *testing.T) {
+	assert.Equal(t, int64(59), roundInterval(59, 1), "a resolution of 1 keeps the exact interval")
+	assert.Equal(t, int64(59), roundInterval(59, 0))
+	assert.Equal(t, int64(60), roundInterval(59, 5))
+	assert.Equal(t, int64(60), roundInterval(62, 5))
+	assert.Equal(t, int64(65), roundInterval(63, 5), "halfway intervals round up")
+	assert.Equal(t, int64(0), roundInterval(2, 5))
+	assert.Equal(t, int64(0), roundInterval(0, 5))
+}
+
+func TestNonZeroIntervals(t *testing.T) {
+	tsList := newTestTimestamps(1600000000, 0, 2, 3, 60)
+	assert.Equal(t, 3, nonZeroIntervals(tsList, 1))
+	assert.Equal(t, 2, nonZeroIntervals(tsList, 5))
+	assert.Equal(t, 1, nonZeroIntervals(tsList, 60))
+	assert.Equal(t, 0, nonZeroIntervals(nil, 60))
+}
+
+func TestAnalyzerResolutionAboveIntervals(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.BeaconProxy.IntervalResolution = 60
+	logger, _ := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	newInput := func(fqdn string, intervals ...int64) *uconnproxy.Input {
+		return &uconnproxy.Input{
+			Hosts:           data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: "10.0.0.1"}, fqdn),
+			ConnectionCount: int64(len(intervals) + 1),
+			TsList:          newTestTimestamps(tsMin, intervals...),
+		}
+	}
+
+	var results []database.BulkChanges
+	a := newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, 1,
+		func(changes database.BulkChanges) { results = append(results, changes) },
+		func() {},
+	)
+	a.start()
+	// a burst whose intervals all round to 0 would otherwise score as a perfect beacon
+	a.collect(newInput("burst.example.com", 1, 2, 3, 1, 2, 3, 1, 2))
+	// a single interval survives the rounding, which is too few to score
+	a.collect(newInput("once.example.com", 1, 2, 3, 300))
+	a.collect(newInput("beacon.example.com", 300, 290, 310, 300))
+	a.close()
+
+	require.Len(t, results, 1)
+	changes := results[0][conf.T.BeaconProxy.BeaconProxyTable]
+	require.Len(t, changes, 1)
+	assert.Equal(t, "beacon.example.com", changes[0].Selector.(bson.M)["fqdn"])
+	assert.Equal(t, int64(2), a.droppedCount())
+}
+
+func TestScoreDatasizesConstant(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 10*3600
+
+	// irregular timing, but every request and response is the same size
+	res := scoreTimestamps(newTestTimestamps(tsMin, 30, 100, 10, 70, 20), 6, tsMin, tsMax, 1, equalWeights, false)
+	res.scoreDatasizes([]int64{512, 512, 512, 512, 512, 512})
+
+	assert.True(t, res.dsScored)
+	assert.Equal(t, int64(0), res.dsRange)
+	assert.Equal(t, int64(512), res.dsMode)
+	assert.Equal(t, int64(6), res.dsModeCount)
+	assert.Equal(t, int64(0), res.dsDispersion)
+	assert.Equal(t, 0.0, res.dsSkew)
+	assert.Equal(t, 1.0, res.dsScore)
+
+	// the data sizes beacon more strongly than the timestamps
+	assert.Less(t, res.tsScore, 1.0)
+	assert.Equal(t, 1.0, res.score)
+}
+
+func TestScoreDatasizesRandom(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+
+	intervals := make([]int64, 47)
+	for i := range intervals {
+		intervals[i] = 60
+	}
+	res := scoreTimestamps(newTestTimestamps(tsMin, intervals...), 48, tsMin, tsMax, 1, equalWeights, false)
+
+	// sizes are given unsorted and the caller's slice is left untouched
+	sizes := []int64{90000, 120, 4000, 35, 15000, 610, 2, 48000}
+	res.scoreDatasizes(sizes)
+	assert.Equal(t, int64(90000), sizes[0])
+
+	assert.True(t, res.dsScored)
+	assert.Equal(t, int64(89998), res.dsRange)
+	assert.Equal(t, int64(2), res.dsMode, "the smallest size is the mode when every size is unique")
+	assert.Equal(t, int64(1), res.dsModeCount)
+	assert.Less(t, res.dsScore, 0.7)
+
+	// the timestamps beacon more strongly than the data sizes
+	assert.Equal(t, 1.0, res.tsScore)
+	assert.Equal(t, 1.0, res.score)
+}
+
+func TestScoreDatasizesMissing(t *testing.T) {
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 10*3600
+
+	// connections imported without sizes are only scored by their timestamps
+	res := scoreTimestamps(newTestTimestamps(tsMin, 30, 100, 10, 70, 20), 6, tsMin, tsMax, 1, equalWeights, false)
+	res.scoreDatasizes(nil)
+
+	assert.False(t, res.dsScored)
+	assert.Equal(t, res.tsScore, res.score)
+}
+
+func TestResultUpdate(t *testing.T) {
+	entry := &uconnproxy.Input{
+		Hosts:           data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: "10.0.0.1", NetworkName: "office"}, "proxied.example.com"),
+		Proxy:           data.UniqueIP{IP: "10.0.0.254"},
+		ConnectionCount: 6,
+	}
+	res := result{
+		tsIntervalRange:  90,
+		tsMode:           10,
+		tsModeCount:      1,
+		intervals:        []int64{10, 100},
+		intervalCounts:   []int64{1, 1},
+		tsDispersion:     20,
+		tsSkew:           0.6,
+		tsSkewScore:      0.4,
+		tsMadmScore:      0.333,
+		tsConnCountScore: 0.6,
+		tsScore:          0.445,
+		score:            0.445,
+	}
+
+	assert.Equal(t, bson.M{
+		"$set": bson.M{
+			"connection_count":   int64(6),
+			"proxy":              entry.Proxy,
+			"src_network_name":   "office",
+			"ts.range":           int64(90),
+			"ts.mode":            int64(10),
+			"ts.mode_count":      int64(1),
+			"ts.intervals":       []int64{10, 100},
+			"ts.interval_counts": []int64{1, 1},
+			"ts.dispersion":      int64(20),
+			"ts.skew":            0.6,
+			"ts.conns_score":     0.6,
+			"ts.score":           0.445,
+			"score":              0.445,
+			"cid":                3,
+		},
+		"$unset": bson.M{"proxy_list": ""},
+	}, res.update(entry, 3))
+
+	// the frequency score is only stored when it is part of the score
+	res.tsFreqScore = 0.9
+	res.tsFreqScored = true
+	assert.Equal(t, 0.9, res.update(entry, 3)["$set"].(bson.M)["ts.freq_score"])
+
+	// as are the data size statistics
+	assert.NotContains(t, res.update(entry, 3)["$set"], "ds.score")
+	res.dsRange = 100
+	res.dsMode = 512
+	res.dsModeCount = 4
+	res.dsDispersion = 0
+	res.dsSkew = 0
+	res.dsScore = 1
+	res.dsScored = true
+	update := res.update(entry, 3)["$set"].(bson.M)
+	assert.Equal(t, int64(100), update["ds.range"])
+	assert.Equal(t, int64(512), update["ds.mode"])
+	assert.Equal(t, int64(4), update["ds.mode_count"])
+	assert.Equal(t, int64(0), update["ds.dispersion"])
+	assert.Equal(t, 0.0, update["ds.skew"])
+	assert.Equal(t, 1.0, update["ds.score"])
+	assert.NotContains(t, update, "proxy_list", "a single proxy is only stored as the proxy")
+	assert.Equal(t, bson.M{"proxy_list": ""}, res.update(entry, 3)["$unset"], "a list left by an earlier chunk is removed")
+
+	// connections relayed through several proxies record all of them
+	entry.Proxies = data.UniqueIPSet{}
+	entry.Proxies.Insert(data.UniqueIP{IP: "10.0.1.254"})
+	entry.Proxies.Insert(data.UniqueIP{IP: "10.0.0.254"})
+	update = res.update(entry, 3)["$set"].(bson.M)
+	assert.Equal(t, entry.Proxy, update["proxy"])
+	assert.Equal(t, []data.UniqueIP{{IP: "10.0.0.254"}, {IP: "10.0.1.254"}}, update["proxy_list"])
+	assert.NotContains(t, res.update(entry, 3), "$unset")
+}
+
+func TestAnalyzerTooFewTimestamps(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	logger, hook := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	hosts := data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: "10.0.0.1"}, "proxied.example.com")
+
+	var results []database.BulkChanges
+	a := newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, 1,
+		func(changes database.BulkChanges) { results = append(results, changes) },
+		func() {},
+	)
+	a.start()
+
+	// entries with too few timestamps are skipped without stopping the analyzer
+	a.collect(&uconnproxy.Input{Hosts: hosts, ConnectionCount: 1, TsList: []int64{tsMin}})
+	a.collect(&uconnproxy.Input{Hosts: hosts, ConnectionCount: 0})
+	a.collect(&uconnproxy.Input{Hosts: hosts, ConnectionCount: 4, TsList: newTestTimestamps(tsMin, 60, 60, 60)})
+	a.close()
+
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(2), a.droppedCount())
+
+	// the number of skipped entries is reported once the analyzer closes
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, log.WarnLevel, hook.LastEntry().Level)
+	assert.Equal(t, int64(2), hook.LastEntry().Data["Dropped"])
+}
+
+func TestAnalyzerConnectionThreshold(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.BeaconProxy.ConnectionThreshold = 5
+	logger, hook := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	newInput := func(src string, count int64) *uconnproxy.Input {
+		return &uconnproxy.Input{
+			Hosts:           data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: src}, "proxied.example.com"),
+			ConnectionCount: count,
+			TsList:          newTestTimestamps(tsMin, 60, 60, 60),
+		}
+	}
+
+	var results []database.BulkChanges
+	a := newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, 1,
+		func(changes database.BulkChanges) { results = append(results, changes) },
+		func() {},
+	)
+	a.start()
+	a.collect(newInput("10.0.0.1", 4))
+	a.collect(newInput("10.0.0.2", 5))
+	a.collect(newInput("10.0.0.3", 6))
+	a.close()
+
+	// no proxy beacon is written for the pair below the threshold
+	require.Len(t, results, 2)
+	for i, src := range []string{"10.0.0.2", "10.0.0.3"} {
+		changes := results[i][conf.T.BeaconProxy.BeaconProxyTable]
+		require.Len(t, changes, 1)
+		assert.Equal(t, src, changes[0].Selector.(bson.M)["src"])
+	}
+	assert.Equal(t, int64(1), a.belowThresholdCount())
+	assert.Equal(t, int64(0), a.droppedCount())
+
+	// the number of skipped pairs is reported once the analyzer closes
+	require.NotNil(t, hook.LastEntry())
+	assert.Equal(t, log.InfoLevel, hook.LastEntry().Level)
+	assert.Equal(t, int64(1), hook.LastEntry().Data["Skipped"])
+
+	// nothing is skipped when the threshold is disabled
+	conf.S.BeaconProxy.ConnectionThreshold = 0
+	results = nil
+	a = newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, 1,
+		func(changes database.BulkChanges) { results = append(results, changes) },
+		func() {},
+	)
+	a.start()
+	a.collect(newInput("10.0.0.1", 4))
+	a.close()
+	assert.Len(t, results, 1)
+	assert.Equal(t, int64(0), a.belowThresholdCount())
+}
+
+// newTestAnalyzerInputs returns count proxied unique connections which beacon
+// every minute for a day, every tenth of which has too few timestamps to score
+func newTestAnalyzerInputs(tsMin int64, count int) []*uconnproxy.Input {
+	intervals := make([]int64, 1439)
+	for i := range intervals {
+		intervals[i] = 60
+	}
+	tsList := newTestTimestamps(tsMin, intervals...)
+
+	inputs := make([]*uconnproxy.Input, count)
+	for i := range inputs {
+		input := &uconnproxy.Input{
+			Hosts:           data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: "10.0.0.1"}, fmt.Sprintf("host%d.example.com", i)),
+			ConnectionCount: int64(len(tsList)),
+			TsList:          tsList,
+		}
+		if i%10 == 0 {
+			input.ConnectionCount = 1
+			input.TsList = tsList[:1]
+		}
+		inputs[i] = input
+	}
+	return inputs
+}
+
+func TestAnalyzerWorkers(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	logger, _ := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	inputs := newTestAnalyzerInputs(tsMin, 200)
+
+	for _, workers := range []int{0, 1, 2, 8} {
+		var mu sync.Mutex
+		analyzed := make(map[string]int)
+		closed := false
+
+		a := newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, workers,
+			func(changes database.BulkChanges) {
+				mu.Lock()
+				defer mu.Unlock()
+				for _, change := range changes[conf.T.BeaconProxy.BeaconProxyTable] {
+					analyzed[change.Selector.(bson.M)["fqdn"].(string)]++
+				}
+			},
+			func() { closed = true },
+		)
+		a.start()
+		for _, input := range inputs {
+			a.collect(input)
+		}
+		a.close()
+
+		// every input is either analyzed exactly once or dropped
+		assert.True(t, closed, "workers: %d", workers)
+		assert.Equal(t, int64(20), a.droppedCount(), "workers: %d", workers)
+		require.Len(t, analyzed, 180, "workers: %d", workers)
+		for i, input := range inputs {
+			if i%10 == 0 {
+				assert.NotContains(t, analyzed, input.Hosts.FQDN, "workers: %d", workers)
+				continue
+			}
+			assert.Equal(t, 1, analyzed[input.Hosts.FQDN], "workers: %d", workers)
+		}
+	}
+}
+
+func TestAnalyzerWorkersLimit(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	assert.GreaterOrEqual(t, analyzerWorkers(conf), 1)
+
+	// there's no use starting more workers than may work at once
+	conf.S.Analysis.MaxThreads = 1
+	assert.Equal(t, 1, analyzerWorkers(conf))
+}
+
+func BenchmarkAnalyzer(b *testing.B) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(b, err)
+	logger, _ := test.NewNullLogger()
+
+	tsMin := int64(1600000000)
+	tsMax := tsMin + 86400
+	inputs := newTestAnalyzerInputs(tsMin, 1000)
+
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				a := newAnalyzer(tsMin, tsMax, 0, nil, conf, logger, workers,
+					func(database.BulkChanges) {},
+					func() {},
+				)
+				a.start()
+				for _, input := range inputs {
+					a.collect(input)
+				}
+				a.close()
+			}
+		})
+	}
+}
@@ -5,30 +5,33 @@ import (
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/pkg/uconnproxy"
 	"github.com/globalsign/mgo/bson"
 )
 
 type (
 	dissector struct {
-		connLimit         int64                   // limit for strobe classification
-		chunk             int                     // current chunk (0 if not on rolling analysis)
-		db                *database.DB            // provides access to MongoDB
-		conf              *config.Config          // contains details needed to access MongoDB
-		dissectedCallback func(*uconnproxy.Input) // called on each analyzed result
-		closedCallback    func()                  // called when .close() is called and no more calls to analyzedCallback will be made
-		dissectChannel    chan *uconnproxy.Input  // holds unanalyzed data
-		dissectWg         sync.WaitGroup          // wait for analysis to finish
+		connLimit         int64                      // limit for strobe classification
+		chunk             int                        // current chunk (0 if not on rolling analysis)
+		db                *database.DB               // provides access to MongoDB
+		conf              *config.Config             // contains details needed to access MongoDB
+		staleCallback     func(database.BulkChanges) // removes the proxy beacons of pairs which are dropped (nil keeps them)
+		dissectedCallback func(*uconnproxy.Input)    // called on each analyzed result
+		closedCallback    func()                     // called when .close() is called and no more calls to analyzedCallback will be made
+		dissectChannel    chan *uconnproxy.Input     // holds unanalyzed data
+		dissectWg         sync.WaitGroup             // wait for analysis to finish
 	}
 )
 
 // newdissector creates a new collector for gathering data
-func newDissector(connLimit int64, chunk int, db *database.DB, conf *config.Config, dissectedCallback func(*uconnproxy.Input), closedCallback func()) *dissector {
+func newDissector(connLimit int64, chunk int, db *database.DB, conf *config.Config, staleCallback func(database.BulkChanges), dissectedCallback func(*uconnproxy.Input), closedCallback func()) *dissector {
 	return &dissector{
 		connLimit:         connLimit,
 		chunk:             chunk,
 		db:                db,
 		conf:              conf,
+		staleCallback:     staleCallback,
 		dissectedCallback: dissectedCallback,
 		closedCallback:    closedCallback,
 		dissectChannel:    make(chan *uconnproxy.Input),
@@ -56,7 +59,7 @@ func (d *dissector) start() {
 
 		for datum := range d.dissectChannel {
 
-			matchNoStrobeKey := datum.Hosts.BSONKey()
+			matchNoStrobeKey := datum.Hosts.Canonical().BSONKey()
 
 			// we are able to filter out already flagged strobes here
 			// because we use the uconnproxy table to access them. The uconnproxy table has
@@ -77,14 +80,18 @@ func (d *dissector) start() {
 				{"$match": matchNoStrobeKey},
 				{"$limit": 1},
 				{"$project": bson.M{
-					"ts":    "$dat.ts",
-					"count": "$dat.count",
+					"ts":      "$dat.ts",
+					"bytes":   "$dat.bytes",
+					"count":   "$dat.count",
+					"proxies": "$dat.proxies",
 				}},
 				{"$unwind": "$count"},
 				{"$group": bson.M{
-					"_id":   "$_id",
-					"ts":    bson.M{"$first": "$ts"},
-					"count": bson.M{"$sum": "$count"},
+					"_id":     "$_id",
+					"ts":      bson.M{"$first": "$ts"},
+					"bytes":   bson.M{"$first": "$bytes"},
+					"count":   bson.M{"$sum": "$count"},
+					"proxies": bson.M{"$first": "$proxies"},
 				}},
 				{"$match": bson.M{"count": bson.M{"$gt": d.conf.S.BeaconProxy.DefaultConnectionThresh}}},
 				{"$unwind": "$ts"},
@@ -93,36 +100,68 @@ func (d *dissector) start() {
 					"_id":     "$_id",
 					"ts":      bson.M{"$addToSet": "$ts"},
 					"ts_full": bson.M{"$push": "$ts"},
+					"bytes":   bson.M{"$first": "$bytes"},
+					"count":   bson.M{"$first": "$count"},
+					"proxies": bson.M{"$first": "$proxies"},
+				}},
+				// connections imported by older versions of RITA have no sizes,
+				// so their documents must be kept when the sizes are unwound
+				{"$unwind": bson.M{"path": "$bytes", "preserveNullAndEmptyArrays": true}},
+				{"$unwind": bson.M{"path": "$bytes", "preserveNullAndEmptyArrays": true}},
+				{"$group": bson.M{
+					"_id":     "$_id",
+					"ts":      bson.M{"$first": "$ts"},
+					"ts_full": bson.M{"$first": "$ts_full"},
+					"bytes":   bson.M{"$push": "$bytes"},
 					"count":   bson.M{"$first": "$count"},
+					"proxies": bson.M{"$first": "$proxies"},
 				}},
 				{"$project": bson.M{
 					"_id":     "$_id",
 					"ts":      1,
 					"ts_full": 1,
+					"bytes":   1,
 					"count":   1,
+					"proxies": 1,
 				}},
 			}
 
 			var res struct {
-				Count  int64   `bson:"count"`
-				Ts     []int64 `bson:"ts"`
-				TsFull []int64 `bson:"ts_full"`
+				Count   int64             `bson:"count"`
+				Ts      []int64           `bson:"ts"`
+				TsFull  []int64           `bson:"ts_full"`
+				Bytes   []int64           `bson:"bytes"`
+				Proxies [][]data.UniqueIP `bson:"proxies"`
 			}
 
 			_ = ssn.DB(d.db.GetSelectedDB()).C(d.conf.T.Structure.UniqueConnProxyTable).Pipe(uconnProxyFindQuery).AllowDiskUse().One(&res)
 
 			// Check for errors and parse results
 			// this is here because it will still return an empty document even if there are no results
+			dissected := false
 			if res.Count > 0 {
+				// leave tiny connections such as failed CONNECTs out of the
+				// timestamps which are scored
+				if d.conf.S.BeaconProxy.MinConnBytes > 0 {
+					res.Ts, res.TsFull, res.Bytes = filterSmallConnections(res.TsFull, res.Bytes, d.conf.S.BeaconProxy.MinConnBytes)
+				}
+
 				connection := &uconnproxy.Input{
 					Hosts:           datum.Hosts,
 					Proxy:           datum.Proxy,
+					Proxies:         make(data.UniqueIPSet),
 					ConnectionCount: res.Count,
 				}
+				// gather the proxies of every chunk still held by the uconnproxy document
+				for _, proxy := range datum.Proxies {
+					connection.Proxies.Insert(proxy)
+				}
+				connection.AddStoredProxies(res.Proxies)
 
 				// avoid passing unnecessary data if conn is a strobe
 				if connection.ConnectionCount > d.connLimit {
 					d.dissectedCallback(connection)
+					dissected = true
 				} else { // otherwise, parse timestamps
 
 					// the analysis worker requires that we have over UNIQUE 3 timestamps
@@ -130,12 +169,61 @@ func (d *dissector) start() {
 					if len(res.Ts) > 3 {
 						connection.TsList = res.Ts
 						connection.TsListFull = res.TsFull
+						connection.BytesList = res.Bytes
 
 						d.dissectedCallback(connection)
+						dissected = true
 					}
 				}
 			}
+
+			// once connections are removed from a pair, its proxy beacon is
+			// left over from data which is gone if the pair no longer qualifies
+			if !dissected && d.staleCallback != nil {
+				d.staleCallback(database.BulkChanges{
+					d.conf.T.BeaconProxy.BeaconProxyTable: []database.BulkChange{{
+						Selector: datum.Hosts.Canonical().BSONKey(),
+						Remove:   true,
+					}},
+				})
+			}
 		}
 		d.dissectWg.Done()
 	}()
 }
+
+// filterSmallConnections removes the connections which transferred fewer than
+// minBytes from the timestamps and sizes of a proxied unique connection. It
+// returns the unique timestamps, every timestamp, and the sizes of the
+// remaining connections. tsFull and bytes hold the timestamp and size of each
+// connection in the same order. If their lengths differ, some connections
+// were imported without sizes, so the timestamps can't be paired with the
+// sizes and are returned unfiltered.
+func filterSmallConnections(tsFull []int64, bytes []int64, minBytes int64) ([]int64, []int64, []int64) {
+	if len(tsFull) != len(bytes) {
+		return uniqueTimestamps(tsFull), tsFull, bytes
+	}
+
+	filteredTs := make([]int64, 0, len(tsFull))
+	filteredBytes := make([]int64, 0, len(bytes))
+	for i, size := range bytes {
+		if size >= minBytes {
+			filteredTs = append(filteredTs, tsFull[i])
+			filteredBytes = append(filteredBytes, size)
+		}
+	}
+	return uniqueTimestamps(filteredTs), filteredTs, filteredBytes
+}
+
+// uniqueTimestamps returns the distinct timestamps in the order they first appear
+func uniqueTimestamps(tsList []int64) []int64 {
+	seen := make(map[int64]struct{}, len(tsList))
+	unique := make([]int64, 0, len(tsList))
+	for _, ts := range tsList {
+		if _, ok := seen[ts]; !ok {
+			seen[ts] = struct{}{}
+			unique = append(unique, ts)
+		}
+	}
+	return unique
+}
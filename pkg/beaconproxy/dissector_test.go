@@ -0,0 +1,43 @@
+package beaconproxy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFilterSmallConnections(t *testing.T) {
+	// a beacon every minute whose schedule is broken up by failed CONNECTs
+	tsFull := []int64{1600000000, 1600000005, 1600000060, 1600000061, 1600000120, 1600000180, 1600000180}
+	bytes := []int64{900, 0, 912, 40, 905, 0, 910}
+
+	ts, filteredFull, filteredBytes := filterSmallConnections(tsFull, bytes, 100)
+
+	// the tiny connections don't contribute intervals
+	assert.Equal(t, newTestTimestamps(1600000000, 60, 60, 60), ts)
+	assert.Equal(t, newTestTimestamps(1600000000, 60, 60, 60), filteredFull)
+	assert.Equal(t, []int64{900, 912, 905, 910}, filteredBytes)
+}
+
+func TestFilterSmallConnectionsDuplicateTimestamps(t *testing.T) {
+	// connections sharing a timestamp are each kept in the full list
+	ts, full, _ := filterSmallConnections(
+		[]int64{1600000000, 1600000000, 1600000060},
+		[]int64{500, 700, 20},
+		100,
+	)
+	assert.Equal(t, []int64{1600000000}, ts)
+	assert.Equal(t, []int64{1600000000, 1600000000}, full)
+}
+
+func TestFilterSmallConnectionsMissingSizes(t *testing.T) {
+	// connections imported without sizes can't be paired with their
+	// timestamps, so every timestamp is kept
+	tsFull := []int64{1600000000, 1600000060, 1600000060, 1600000120}
+	bytes := []int64{0, 900}
+
+	ts, filteredFull, filteredBytes := filterSmallConnections(tsFull, bytes, 100)
+	assert.Equal(t, []int64{1600000000, 1600000060, 1600000120}, ts)
+	assert.Equal(t, tsFull, filteredFull)
+	assert.Equal(t, bytes, filteredBytes)
+}
@@ -0,0 +1,139 @@
+package beaconproxy
+
+import (
+	"math"
+	"math/cmplx"
+)
+
+const (
+	// maxFreqBins limits the number of bins the timestamps are counted into
+	// so that long running pairs don't require huge transforms
+	maxFreqBins = 1 << 14
+
+	// freqPadding is the factor the binned series is zero padded by to
+	// sample the spectrum between the integer frequencies
+	freqPadding = 2
+
+	// freqRefineSteps is the number of frequencies evaluated on each side of
+	// the strongest frequency of the transform to find the exact peak
+	freqRefineSteps = 10
+)
+
+// getTsFreqScore measures the strength of the dominant frequency of the
+// sorted connection timestamps. The timestamps are counted into bins and
+// transformed with a discrete Fourier transform. The power of the strongest
+// frequency which repeats at least twice within the timestamps is compared
+// to the power of a perfect beacon, which is the square of the number of
+// connections. Connections at a fixed interval score 1 regardless of how
+// many beats are skipped, while random connections score close to 0.
+// Returns a score of 0 if there are fewer than four timestamps.
+func getTsFreqScore(sortedTs []int64) float64 {
+	if len(sortedTs) < 4 || sortedTs[len(sortedTs)-1]-sortedTs[0] < 3 {
+		return 0
+	}
+
+	start := sortedTs[0]
+	span := sortedTs[len(sortedTs)-1] - start
+
+	// bin the timestamps. Bins are at least a second wide and cover the
+	// span of the timestamps in at most maxFreqBins bins.
+	binWidth := (span + maxFreqBins) / maxFreqBins
+	binCount := int(span/binWidth) + 1
+	size := nextPowerOfTwo(binCount * freqPadding)
+
+	// the mean is removed so the constant count of connections does not
+	// leak into the low frequencies
+	series := make([]complex128, size)
+	for _, ts := range sortedTs {
+		series[(ts-start)/binWidth]++
+	}
+	mean := float64(len(sortedTs)) / float64(binCount)
+	for i := 0; i < binCount; i++ {
+		series[i] -= complex(mean, 0)
+	}
+
+	spectrum := fft(series)
+
+	// frequencies slower than two repetitions over the span of the
+	// timestamps can't show periodic behavior
+	minFreq := 2 * size / binCount
+	peak, peakPower := 0, 0.0
+	for k := minFreq; k <= size/2; k++ {
+		power := real(spectrum[k])*real(spectrum[k]) + imag(spectrum[k])*imag(spectrum[k])
+		if power > peakPower {
+			peak, peakPower = k, power
+		}
+	}
+	if peak == 0 {
+		return 0
+	}
+
+	// the peak likely falls between the sampled frequencies, so the power
+	// is evaluated directly from the timestamps around the strongest one
+	for step := -freqRefineSteps; step <= freqRefineSteps; step++ {
+		freq := (float64(peak) + float64(step)/freqRefineSteps) / float64(size*int(binWidth))
+		if power := timestampPower(sortedTs, freq); power > peakPower {
+			peakPower = power
+		}
+	}
+
+	connCount := float64(len(sortedTs))
+	score := peakPower / (connCount * connCount)
+	if score > 1 {
+		score = 1
+	}
+	return math.Ceil(score*1000) / 1000
+}
+
+// timestampPower returns the power of the timestamps at the frequency freq in
+// cycles per second
+func timestampPower(sortedTs []int64, freq float64) float64 {
+	var sum complex128
+	for _, ts := range sortedTs {
+		sum += cmplx.Exp(complex(0, -2*math.Pi*freq*float64(ts-sortedTs[0])))
+	}
+	return real(sum)*real(sum) + imag(sum)*imag(sum)
+}
+
+// fft returns the discrete Fourier transform of the series using the radix-2
+// Cooley-Tukey algorithm. The length of the series must be a power of two.
+func fft(series []complex128) []complex128 {
+	n := len(series)
+	out := make([]complex128, n)
+	copy(out, series)
+
+	// reorder the series by the bit reversal of each index
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			out[i], out[j] = out[j], out[i]
+		}
+	}
+
+	for length := 2; length <= n; length <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(length)))
+		for i := 0; i < n; i += length {
+			twiddle := complex(1, 0)
+			for j := 0; j < length/2; j++ {
+				even, odd := out[i+j], out[i+j+length/2]*twiddle
+				out[i+j] = even + odd
+				out[i+j+length/2] = even - odd
+				twiddle *= step
+			}
+		}
+	}
+	return out
+}
+
+// nextPowerOfTwo returns the smallest power of two which is at least n
+func nextPowerOfTwo(n int) int {
+	size := 1
+	for size < n {
+		size <<= 1
+	}
+	return size
+}
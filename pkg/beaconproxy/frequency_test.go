@@ -0,0 +1,59 @@
+package beaconproxy
+
+import (
+	"math"
+	"math/cmplx"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFFT(t *testing.T) {
+	series := []complex128{1, 2, 0, -1, 3, 0, 0, 1}
+	spectrum := fft(series)
+
+	// compare against the definition of the discrete Fourier transform
+	for k := range series {
+		var expected complex128
+		for n, x := range series {
+			expected += x * cmplx.Exp(complex(0, -2*math.Pi*float64(k*n)/float64(len(series))))
+		}
+		assert.InDelta(t, real(expected), real(spectrum[k]), 1e-9, "real part of frequency %d", k)
+		assert.InDelta(t, imag(expected), imag(spectrum[k]), 1e-9, "imaginary part of frequency %d", k)
+	}
+
+	assert.Equal(t, 1, nextPowerOfTwo(1))
+	assert.Equal(t, 8, nextPowerOfTwo(5))
+	assert.Equal(t, 8, nextPowerOfTwo(8))
+}
+
+// TestGetTsFreqScore ranks a clean 60 second beacon, a beacon whose 60
+// second intervals are jittered, and random traffic
+func TestGetTsFreqScore(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	newTimestamps := func(interval func() int64) []int64 {
+		tsList := []int64{1600000000}
+		for i := 1; i < 48; i++ {
+			tsList = append(tsList, tsList[i-1]+interval())
+		}
+		return tsList
+	}
+
+	clean := getTsFreqScore(newTimestamps(func() int64 { return 60 }))
+	jittered := getTsFreqScore(newTimestamps(func() int64 { return 55 + rng.Int63n(11) }))
+	random := getTsFreqScore(newTimestamps(func() int64 { return int64(rng.ExpFloat64()*60) + 1 }))
+
+	assert.Equal(t, 1.0, clean)
+	assert.True(t, clean > jittered, "jitter should weaken the dominant frequency")
+	assert.True(t, jittered > random, "a jittered beacon should keep a stronger frequency than random traffic")
+	assert.True(t, random < 0.3, "random traffic should not have a dominant frequency, got %v", random)
+
+	// skipped beats keep to the schedule even though the intervals are multimodal
+	flipping := getTsFreqScore(newTimestamps(func() int64 { return 30 * (1 + rng.Int63n(2)) }))
+	assert.Equal(t, 1.0, flipping)
+
+	// too few timestamps can't be measured
+	assert.Equal(t, 0.0, getTsFreqScore([]int64{0, 60, 120}))
+	assert.Equal(t, 0.0, getTsFreqScore([]int64{0, 0, 0, 1}))
+}
@@ -2,6 +2,7 @@ package beaconproxy
 
 import (
 	"fmt"
+	"net"
 	"runtime"
 
 	"github.com/activecm/rita/config"
@@ -12,6 +13,7 @@ import (
 	"github.com/activecm/rita/util"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/vbauerster/mpb"
 	"github.com/vbauerster/mpb/decor"
 
@@ -72,6 +74,20 @@ func (r *repo) CreateIndexes() error {
 // Upsert derives beacon statistics from the given unique proxy connections and creates
 // summaries for the given local hosts. The results are pushed to MongoDB.
 func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
+	r.analyze(uconnProxyMap, hostMap, minTimestamp, maxTimestamp, false)
+}
+
+// Refresh works like Upsert for unique proxy connections whose data was partly removed,
+// such as when a chunk is evicted from a rolling database. The proxy beacons of pairs
+// which no longer qualify for the analysis are removed.
+func (r *repo) Refresh(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
+	r.analyze(uconnProxyMap, hostMap, minTimestamp, maxTimestamp, true)
+}
+
+// analyze runs the proxy beacon analysis pipeline over the unique proxy connections.
+// If prune is set, the proxy beacons of the unique proxy connections which don't
+// qualify for the analysis are removed.
+func (r *repo) analyze(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64, prune bool) {
 
 	session := r.database.Session.Copy()
 	defer session.Close()
@@ -79,7 +95,7 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 	// Create the workers
 
 	// stage 6 - write out results
-	writerWorker := database.NewBulkWriter(
+	writerWorker := database.NewWriter(
 		r.database,
 		r.config,
 		r.log,
@@ -95,6 +111,7 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 		r.database,
 		r.config,
 		r.log,
+		analyzerWorkers(r.config),
 		writerWorker.Collect,
 		writerWorker.Close,
 	)
@@ -119,12 +136,18 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 		sorterWorker.close,
 	)
 
+	var staleCallback func(database.BulkChanges)
+	if prune {
+		staleCallback = writerWorker.Collect
+	}
+
 	// stage 2 - get and vet beacon details
 	dissectorWorker := newDissector(
 		int64(r.config.S.Strobe.ConnectionLimit),
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
 		r.config,
+		staleCallback,
 		siphonWorker.collect,
 		siphonWorker.close,
 	)
@@ -134,9 +157,9 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 		dissectorWorker.start()
 		siphonWorker.start()
 		sorterWorker.start()
-		analyzerWorker.start()
 		writerWorker.Start()
 	}
+	analyzerWorker.start()
 
 	// progress bar for troubleshooting
 	p := mpb.New(mpb.WithWidth(20))
@@ -179,7 +202,7 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 	}
 
 	// initialize a new writer for the summarizer
-	writerWorker = database.NewBulkWriter(r.database, r.config, r.log, true, "beaconsProxy")
+	writerWorker = database.NewWriter(r.database, r.config, r.log, true, "beaconsProxy")
 	summarizerWorker := newSummarizer(
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
@@ -216,3 +239,49 @@ func (r *repo) Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[st
 	// start the closing cascade (this will also close the other channels)
 	summarizerWorker.close()
 }
+
+// Rescore analyzes every proxied unique connection stored in the dataset
+// again and overwrites the results of the existing proxy beacons. The unique
+// connections are read from MongoDB rather than from parsed logs, so scoring
+// changes can be applied without importing the logs again.
+func (r *repo) Rescore(minTimestamp, maxTimestamp int64) error {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	uconnProxyMap := make(map[string]*uconnproxy.Input)
+	hostMap := make(map[string]*host.Input)
+	internal := util.ParseSubnets(r.config.S.Filtering.InternalSubnets)
+
+	// strobes are skipped by the dissector, so there's no need to read them
+	var stored uconnproxy.StoredConnection
+	iter := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.UniqueConnProxyTable).
+		Find(bson.M{"strobeFQDN": bson.M{"$ne": true}}).Select(uconnproxy.StoredConnectionFields).Iter()
+
+	for iter.Next(&stored) {
+		input := stored.Input()
+		uconnProxyMap[input.Hosts.MapKey()] = input
+
+		src := input.Hosts.UniqueSrcIP.Unpair()
+		if util.ContainsIP(internal, net.ParseIP(src.IP)) {
+			hostMap[src.MapKey()] = &host.Input{Host: src, IsLocal: true}
+		}
+		stored = uconnproxy.StoredConnection{}
+	}
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	r.Upsert(uconnProxyMap, hostMap, minTimestamp, maxTimestamp)
+	return nil
+}
+
+// analyzerWorkers returns the number of goroutines to score proxy beacons with.
+// The analyzer starts one for every two CPU cores like the other stages, but no
+// more than Analysis MaxThreads since only that many may work at once.
+func analyzerWorkers(conf *config.Config) int {
+	workers := util.Max(1, runtime.NumCPU()/2)
+	if conf.S.Analysis.MaxThreads > 0 {
+		workers = util.Min(workers, conf.S.Analysis.MaxThreads)
+	}
+	return workers
+}
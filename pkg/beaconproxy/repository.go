@@ -13,6 +13,8 @@ type (
 	Repository interface {
 		CreateIndexes() error
 		Upsert(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64)
+		Refresh(uconnProxyMap map[string]*uconnproxy.Input, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64)
+		Rescore(minTimestamp, maxTimestamp int64) error
 	}
 
 	//TSData ...
@@ -24,17 +26,29 @@ type (
 		Dispersion int64   `bson:"dispersion"`
 	}
 
+	//DSData holds the data size statistics of a proxy beacon
+	DSData struct {
+		Range      int64   `bson:"range"`
+		Mode       int64   `bson:"mode"`
+		ModeCount  int64   `bson:"mode_count"`
+		Skew       float64 `bson:"skew"`
+		Dispersion int64   `bson:"dispersion"`
+		Score      float64 `bson:"score"`
+	}
+
 	//Result represents a beacon proxy between a source IP and
 	// an fqdn.
 	Result struct {
-		FQDN           string        `bson:"fqdn"`
-		SrcIP          string        `bson:"src"`
-		SrcNetworkName string        `bson:"src_network_name"`
-		SrcNetworkUUID bson.Binary   `bson:"src_network_uuid"`
-		Connections    int64         `bson:"connection_count"`
-		Ts             TSData        `bson:"ts"`
-		Score          float64       `bson:"score"`
-		Proxy          data.UniqueIP `bson:"proxy"`
+		FQDN           string          `bson:"fqdn"`
+		SrcIP          string          `bson:"src"`
+		SrcNetworkName string          `bson:"src_network_name"`
+		SrcNetworkUUID bson.Binary     `bson:"src_network_uuid"`
+		Connections    int64           `bson:"connection_count"`
+		Ts             TSData          `bson:"ts"`
+		Ds             DSData          `bson:"ds"`
+		Score          float64         `bson:"score"`
+		Proxy          data.UniqueIP   `bson:"proxy"`
+		ProxyList      []data.UniqueIP `bson:"proxy_list,omitempty"`
 	}
 
 	//StrobeResult represents a unique connection with a large amount
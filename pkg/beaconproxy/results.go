@@ -1,6 +1,7 @@
 package beaconproxy
 
 import (
+	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/resources"
 	"github.com/globalsign/mgo/bson"
 )
@@ -18,3 +19,13 @@ func Results(res *resources.Resources, cutoffScore float64) ([]Result, error) {
 
 	return beaconsProxy, err
 }
+
+//Proxies returns every proxy which serviced the proxy beacon. Results stored
+//before the proxy list was recorded, or which went through a single proxy,
+//only hold the proxy field.
+func (r Result) Proxies() []data.UniqueIP {
+	if len(r.ProxyList) > 0 {
+		return r.ProxyList
+	}
+	return []data.UniqueIP{r.Proxy}
+}
@@ -0,0 +1,32 @@
+package beaconproxy
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultProxies(t *testing.T) {
+	// results stored before the proxy list was recorded only hold the proxy
+	encoded, err := bson.Marshal(bson.M{"fqdn": "c2.example.com", "proxy": bson.M{"ip": "10.0.0.254"}})
+	require.Nil(t, err)
+	var old Result
+	require.Nil(t, bson.Unmarshal(encoded, &old))
+	assert.Equal(t, []data.UniqueIP{{IP: "10.0.0.254"}}, old.Proxies())
+
+	multi := Result{
+		Proxy:     data.UniqueIP{IP: "10.0.1.254"},
+		ProxyList: []data.UniqueIP{{IP: "10.0.0.254"}, {IP: "10.0.1.254"}},
+	}
+	assert.Equal(t, multi.ProxyList, multi.Proxies())
+
+	// the list is left out of results with a single proxy
+	encoded, err = bson.Marshal(Result{Proxy: data.UniqueIP{IP: "10.0.0.254"}})
+	require.Nil(t, err)
+	var doc bson.M
+	require.Nil(t, bson.Unmarshal(encoded, &doc))
+	assert.NotContains(t, doc, "proxy_list")
+}
@@ -62,6 +62,8 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
 
 		for res := range a.analysisChannel {
 
@@ -188,7 +190,9 @@ func (a *analyzer) start() {
 			}
 
 			// connection count scoring
-			tsConnDiv := (float64(a.tsMax) - float64(a.tsMin)) / 3600
+			// count connections over at least an hour so a dataset whose
+			// timestamps fall within a single instant doesn't divide by zero
+			tsConnDiv := math.Max((float64(a.tsMax)-float64(a.tsMin))/3600, 1)
 			tsConnCountScore := float64(res.ConnectionCount) / tsConnDiv
 			if tsConnCountScore > 1.0 {
 				tsConnCountScore = 1.0
@@ -199,7 +203,11 @@ func (a *analyzer) start() {
 			dsScore := math.Ceil(((dsSkewScore+dsMadmScore+dsSmallnessScore)/3.0)*1000) / 1000
 
 			// calculate duration score
-			duration := math.Ceil((float64(res.TsList[tsLength]-res.TsList[0])/(float64(a.tsMax)-float64(a.tsMin)))*1000) / 1000
+			// a dataset without a time range can't show persistence
+			duration := 0.0
+			if a.tsMax > a.tsMin {
+				duration = math.Ceil((float64(res.TsList[tsLength]-res.TsList[0])/(float64(a.tsMax)-float64(a.tsMin)))*1000) / 1000
+			}
 			if duration > 1.0 {
 				duration = 1.0
 			}
@@ -0,0 +1,38 @@
+package beaconsni
+
+import (
+	"sort"
+
+	"github.com/activecm/rita/pkg/data"
+)
+
+// capFQDNsPerSource keeps the maxFQDNs FQDNs each source connected to the
+// most, so that a host contacting thousands of FQDNs, as with a domain
+// generation algorithm, does not produce thousands of SNI beacons. Ties are
+// broken by FQDN so that the same FQDNs are kept on each run. The connection
+// counts are indexed by the same keys as the selectors.
+func capFQDNsPerSource(selectors map[string]data.UniqueSrcFQDNPair, connCounts map[string]int64, maxFQDNs int) map[string]data.UniqueSrcFQDNPair {
+	// group the selector keys by source
+	bySource := make(map[string][]string)
+	for key, pair := range selectors {
+		srcKey := pair.UniqueSrcIP.Unpair().MapKey()
+		bySource[srcKey] = append(bySource[srcKey], key)
+	}
+
+	capped := make(map[string]data.UniqueSrcFQDNPair, len(selectors))
+	for _, keys := range bySource {
+		if len(keys) > maxFQDNs {
+			sort.Slice(keys, func(i, j int) bool {
+				if connCounts[keys[i]] != connCounts[keys[j]] {
+					return connCounts[keys[i]] > connCounts[keys[j]]
+				}
+				return selectors[keys[i]].FQDN < selectors[keys[j]].FQDN
+			})
+			keys = keys[:maxFQDNs]
+		}
+		for _, key := range keys {
+			capped[key] = selectors[key]
+		}
+	}
+	return capped
+}
@@ -0,0 +1,55 @@
+package beaconsni
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapFQDNsPerSource(t *testing.T) {
+	selectors := make(map[string]data.UniqueSrcFQDNPair)
+	connCounts := make(map[string]int64)
+	add := func(src, fqdn string, conns int64) {
+		pair := data.NewUniqueSrcFQDNPair(data.UniqueIP{IP: src}, fqdn)
+		selectors[pair.MapKey()] = pair
+		connCounts[pair.MapKey()] += conns
+	}
+
+	// a host querying generated domains alongside a real beacon
+	for i := 0; i < 100; i++ {
+		add("10.0.0.1", fmt.Sprintf("dga%03d.example.com", i), int64(i%5+1))
+	}
+	add("10.0.0.1", "c2.example.com", 300)
+	add("10.0.0.1", "update.example.com", 40)
+	// the TLS and HTTP connections to an FQDN are counted together
+	add("10.0.0.1", "cdn.example.com", 30)
+	add("10.0.0.1", "cdn.example.com", 30)
+
+	// a host under the limit keeps every FQDN
+	add("10.0.0.2", "one.example.com", 1)
+	add("10.0.0.2", "two.example.com", 2)
+
+	capped := capFQDNsPerSource(selectors, connCounts, 5)
+
+	var kept1, kept2 []string
+	for _, pair := range capped {
+		switch pair.SrcIP {
+		case "10.0.0.1":
+			kept1 = append(kept1, pair.FQDN)
+		case "10.0.0.2":
+			kept2 = append(kept2, pair.FQDN)
+		}
+	}
+
+	// ties between the generated domains are broken by name
+	assert.ElementsMatch(t, []string{
+		"c2.example.com", "cdn.example.com", "update.example.com", "dga004.example.com", "dga009.example.com",
+	}, kept1)
+	assert.ElementsMatch(t, []string{"one.example.com", "two.example.com"}, kept2)
+
+	for key, pair := range capped {
+		assert.Equal(t, selectors[key], pair, "capped selectors should keep their keys")
+	}
+}
@@ -74,16 +74,32 @@ func (r *repo) CreateIndexes() error {
 // created for the given local hosts in MongoDB.
 func (r *repo) Upsert(tlsMap map[string]*sniconn.TLSInput, httpMap map[string]*sniconn.HTTPInput, hostMap map[string]*host.Input, minTimestamp, maxTimestamp int64) {
 	selectors := make(map[string]data.UniqueSrcFQDNPair)
+	connCounts := make(map[string]int64)
 	for tlsKey, tlsValue := range tlsMap {
 		selectors[tlsKey] = tlsValue.Hosts
+		connCounts[tlsKey] += tlsValue.ConnectionCount
 	}
 
 	for httpKey, httpValue := range httpMap {
 		selectors[httpKey] = httpValue.Hosts
+		connCounts[httpKey] += httpValue.ConnectionCount
+	}
+
+	// bound the number of FQDNs analyzed for each source
+	if maxFQDNs := r.config.S.BeaconSNI.MaxFQDNsPerHost; maxFQDNs > 0 {
+		total := len(selectors)
+		selectors = capFQDNsPerSource(selectors, connCounts, maxFQDNs)
+		if skipped := total - len(selectors); skipped > 0 {
+			r.log.WithFields(log.Fields{
+				"Module":          "beaconsni",
+				"MaxFQDNsPerHost": maxFQDNs,
+				"Skipped":         skipped,
+			}).Info("Skipped the least contacted FQDNs of hosts over the FQDN limit")
+		}
 	}
 
 	//Create the workers
-	writerWorker := database.NewBulkWriter(
+	writerWorker := database.NewWriter(
 		r.database,
 		r.config,
 		r.log,
@@ -175,7 +191,7 @@ func (r *repo) Upsert(tlsMap map[string]*sniconn.TLSInput, httpMap map[string]*s
 	}
 
 	// initialize a new writer for the summarizer
-	writerWorker = database.NewBulkWriter(r.database, r.config, r.log, true, "beaconSNI")
+	writerWorker = database.NewWriter(r.database, r.config, r.log, true, "beaconSNI")
 	summarizerWorker := newSummarizer(
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
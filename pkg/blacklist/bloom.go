@@ -0,0 +1,86 @@
+package blacklist
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// bloomFalsePositiveRate is the share of indicators which are not blacklisted
+// but still pass the bloom filter and require an exact lookup
+const bloomFalsePositiveRate = 0.01
+
+// BloomFilter is a probabilistic set of blacklisted indicators. It never
+// reports that a blacklisted indicator is absent, so lookups for indicators
+// it rejects can be skipped. Indicators it accepts may still not be
+// blacklisted and must be confirmed with an exact lookup.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64 // number of bits
+	hashes uint64 // number of bits set per indicator
+}
+
+// NewBloomFilter creates a BloomFilter sized to hold count indicators with the
+// given false positive rate
+func NewBloomFilter(count int, falsePositiveRate float64) *BloomFilter {
+	if count < 1 {
+		count = 1
+	}
+	n := float64(count)
+
+	// optimal size and number of hashes for the expected number of indicators
+	size := uint64(math.Ceil(-n * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	if size < 64 {
+		size = 64
+	}
+	hashes := uint64(math.Round(float64(size) / n * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add inserts an indicator into the filter
+func (b *BloomFilter) Add(indicator string) {
+	h1, h2 := bloomHashes(indicator)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		b.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// MayContain returns false if the indicator was never added to the filter
+func (b *BloomFilter) MayContain(indicator string) bool {
+	h1, h2 := bloomHashes(indicator)
+	for i := uint64(0); i < b.hashes; i++ {
+		bit := (h1 + i*h2) % b.size
+		if b.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Check reports whether the indicator is blacklisted. The exact lookup is only
+// made if the indicator may be in the filter. A nil filter always performs the lookup.
+func (b *BloomFilter) Check(indicator string, lookup func(string) (bool, error)) (bool, error) {
+	if b != nil && !b.MayContain(indicator) {
+		return false, nil
+	}
+	return lookup(indicator)
+}
+
+// bloomHashes derives the two hashes which are combined to select the bits of
+// an indicator. The second hash is odd so that it never repeats the first bit.
+func bloomHashes(indicator string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(indicator))
+	h1 := h.Sum64()
+	h.Write([]byte{0})
+	h2 := h.Sum64() | 1
+	return h1, h2
+}
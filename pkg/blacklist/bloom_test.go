@@ -0,0 +1,116 @@
+package blacklist
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestIndicators(prefix string, count int) []string {
+	indicators := make([]string, count)
+	for i := range indicators {
+		indicators[i] = fmt.Sprintf("%s%d.%d.%d", prefix, i/65536, (i/256)%256, i%256)
+	}
+	return indicators
+}
+
+func TestBloomFilterNoFalseNegatives(t *testing.T) {
+	blacklisted := append(newTestIndicators("10.", 20000), "bad.example.com", "")
+
+	filter := NewBloomFilter(len(blacklisted), bloomFalsePositiveRate)
+	for _, indicator := range blacklisted {
+		filter.Add(indicator)
+	}
+
+	for _, indicator := range blacklisted {
+		require.True(t, filter.MayContain(indicator), "blacklisted indicator %q was rejected", indicator)
+	}
+
+	// most indicators which were never added are rejected
+	falsePositives := 0
+	absent := newTestIndicators("172.", 20000)
+	for _, indicator := range absent {
+		if filter.MayContain(indicator) {
+			falsePositives++
+		}
+	}
+	rate := float64(falsePositives) / float64(len(absent))
+	assert.True(t, rate < 3*bloomFalsePositiveRate, "false positive rate %v is too high", rate)
+}
+
+func TestBloomFilterUndersized(t *testing.T) {
+	// a filter holding more indicators than it was sized for loses accuracy
+	// but still never rejects a blacklisted indicator
+	blacklisted := newTestIndicators("10.", 5000)
+	filter := NewBloomFilter(10, bloomFalsePositiveRate)
+	for _, indicator := range blacklisted {
+		filter.Add(indicator)
+	}
+	for _, indicator := range blacklisted {
+		require.True(t, filter.MayContain(indicator), "blacklisted indicator %q was rejected", indicator)
+	}
+}
+
+func TestBloomFilterCheck(t *testing.T) {
+	blacklisted := map[string]bool{"203.0.113.7": true, "198.51.100.1": true}
+
+	// a small filter so that false positives are easy to find
+	filter := NewBloomFilter(len(blacklisted), 0.3)
+	for indicator := range blacklisted {
+		filter.Add(indicator)
+	}
+
+	var lookups []string
+	lookup := func(indicator string) (bool, error) {
+		lookups = append(lookups, indicator)
+		return blacklisted[indicator], nil
+	}
+
+	// blacklisted indicators are confirmed by the exact lookup
+	for indicator := range blacklisted {
+		lookups = nil
+		found, err := filter.Check(indicator, lookup)
+		require.Nil(t, err)
+		assert.True(t, found)
+		assert.Equal(t, []string{indicator}, lookups)
+	}
+
+	var falsePositive, rejected string
+	for _, indicator := range newTestIndicators("192.", 1000) {
+		if filter.MayContain(indicator) {
+			falsePositive = indicator
+		} else {
+			rejected = indicator
+		}
+	}
+	require.NotEmpty(t, falsePositive, "expected the small filter to produce a false positive")
+	require.NotEmpty(t, rejected)
+
+	// indicators the filter rejects are never looked up
+	lookups = nil
+	found, err := filter.Check(rejected, lookup)
+	require.Nil(t, err)
+	assert.False(t, found)
+	assert.Empty(t, lookups)
+
+	// the exact lookup rules out false positives
+	lookups = nil
+	found, err = filter.Check(falsePositive, lookup)
+	require.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, []string{falsePositive}, lookups)
+
+	// errors from the exact lookup are passed along
+	_, err = filter.Check("203.0.113.7", func(string) (bool, error) { return false, assert.AnError })
+	assert.Equal(t, assert.AnError, err)
+
+	// without a filter every indicator is looked up
+	var nilFilter *BloomFilter
+	lookups = nil
+	found, err = nilFilter.Check(rejected, lookup)
+	require.Nil(t, err)
+	assert.False(t, found)
+	assert.Equal(t, []string{rejected}, lookups)
+}
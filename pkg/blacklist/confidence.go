@@ -0,0 +1,91 @@
+package blacklist
+
+import (
+	"math"
+	"sort"
+
+	"github.com/activecm/rita/config"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// FeedWeight returns how much a blacklist feed is trusted, from 0 to 1. Feeds
+// are named by rita-bl: the built in feed is "feodo tracker" and the custom
+// blacklists are named by their path or URL.
+func FeedWeight(conf config.BlacklistedStaticCfg, feed string) float64 {
+	if weight, ok := conf.FeedWeights[feed]; ok {
+		return weight
+	}
+	return conf.DefaultFeedWeight
+}
+
+// Confidence combines the weights of the feeds which list an indicator into
+// the confidence that the indicator is malicious, from 0 to 1. Each feed is
+// treated as independent evidence, so the confidence is the chance that at
+// least one of the feeds is right: 1 - (1 - w1) * (1 - w2) * ... A single feed
+// gives its own weight and repeats of a feed are only counted once. The
+// confidence is rounded to three places.
+func Confidence(conf config.BlacklistedStaticCfg, feeds []string) float64 {
+	seen := make(map[string]bool, len(feeds))
+	doubt := 1.0
+	for _, feed := range feeds {
+		if seen[feed] {
+			continue
+		}
+		seen[feed] = true
+		doubt *= 1 - FeedWeight(conf, feed)
+	}
+	return math.Round((1-doubt)*1000) / 1000
+}
+
+// feedLookup returns a lookup of the sorted names of the feeds which list an
+// indicator in a collection of the blacklist database
+func feedLookup(coll *mgo.Collection) func(string) ([]string, error) {
+	return func(indicator string) ([]string, error) {
+		var feeds []string
+		err := coll.Find(bson.M{"index": indicator}).Distinct("list", &feeds)
+		sort.Strings(feeds)
+		return feeds, err
+	}
+}
+
+// CheckIndicator checks an indicator against a collection of the blacklist
+// database and returns the update which records the result on its host or
+// hostname document. Blacklisted indicators are marked with the feeds which
+// list them and their Confidence. The blacklist database is only queried if
+// the indicator passes the filter.
+func CheckIndicator(coll *mgo.Collection, indicator string, filter *IndicatorSet, conf config.BlacklistedStaticCfg) (bson.M, error) {
+	blacklisted, err := filter.Check(indicator, indicatorLookup(coll))
+	if err != nil || !blacklisted {
+		return matchUpdate(conf, blacklisted, nil), err
+	}
+
+	feeds, err := feedLookup(coll)(indicator)
+	return matchUpdate(conf, blacklisted, feeds), err
+}
+
+// matchUpdate sets the blacklisted flag of a host or hostname along with the
+// feeds which list it and their confidence. The feeds and confidence are
+// removed from indicators which aren't blacklisted.
+func matchUpdate(conf config.BlacklistedStaticCfg, blacklisted bool, feeds []string) bson.M {
+	if !blacklisted {
+		return bson.M{
+			"$set":   bson.M{"blacklisted": false},
+			"$unset": bson.M{"bl_feeds": "", "bl_confidence": ""},
+		}
+	}
+
+	// the feeds are unknown if the lookup failed, in which case the indicator
+	// is trusted as if a single unweighted feed listed it
+	confidence := conf.DefaultFeedWeight
+	if len(feeds) > 0 {
+		confidence = Confidence(conf, feeds)
+	}
+	return bson.M{
+		"$set": bson.M{
+			"blacklisted":   true,
+			"bl_feeds":      feeds,
+			"bl_confidence": confidence,
+		},
+	}
+}
@@ -0,0 +1,94 @@
+package blacklist
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFeedWeights trusts the built in feed more than a custom blacklist
+var testFeedWeights = config.BlacklistedStaticCfg{
+	DefaultFeedWeight: 0.5,
+	FeedWeights: map[string]float64{
+		"feodo tracker":              0.9,
+		"/etc/rita/watchlist.txt":    0.2,
+		"https://example.com/bl.txt": 0,
+	},
+}
+
+func TestFeedWeight(t *testing.T) {
+	assert.Equal(t, 0.9, FeedWeight(testFeedWeights, "feodo tracker"))
+	assert.Equal(t, 0.0, FeedWeight(testFeedWeights, "https://example.com/bl.txt"))
+	assert.Equal(t, 0.5, FeedWeight(testFeedWeights, "/etc/rita/unweighted.txt"))
+}
+
+func TestConfidenceSingleFeed(t *testing.T) {
+	// a single feed gives its own weight
+	assert.Equal(t, 0.9, Confidence(testFeedWeights, []string{"feodo tracker"}))
+	assert.Equal(t, 0.2, Confidence(testFeedWeights, []string{"/etc/rita/watchlist.txt"}))
+	assert.Equal(t, 0.5, Confidence(testFeedWeights, []string{"/etc/rita/unweighted.txt"}))
+
+	// repeats of a feed don't add to the confidence
+	assert.Equal(t, 0.2, Confidence(testFeedWeights, []string{"/etc/rita/watchlist.txt", "/etc/rita/watchlist.txt"}))
+
+	assert.Equal(t, 0.0, Confidence(testFeedWeights, nil))
+}
+
+func TestConfidenceMultipleFeeds(t *testing.T) {
+	// 1 - (1 - 0.9) * (1 - 0.2)
+	assert.Equal(t, 0.92, Confidence(testFeedWeights, []string{"feodo tracker", "/etc/rita/watchlist.txt"}))
+	// 1 - (1 - 0.9) * (1 - 0.2) * (1 - 0.5)
+	assert.Equal(t, 0.96, Confidence(testFeedWeights, []string{"feodo tracker", "/etc/rita/watchlist.txt", "/etc/rita/unweighted.txt"}))
+
+	// agreeing feeds are more convincing than either alone, and a feed
+	// weighted 0 doesn't change the confidence
+	weak := []string{"/etc/rita/watchlist.txt", "/etc/rita/unweighted.txt"}
+	assert.Equal(t, 0.6, Confidence(testFeedWeights, weak))
+	assert.Equal(t, 0.6, Confidence(testFeedWeights, append(weak, "https://example.com/bl.txt")))
+
+	// the order of the feeds doesn't matter
+	assert.Equal(t,
+		Confidence(testFeedWeights, []string{"feodo tracker", "/etc/rita/unweighted.txt"}),
+		Confidence(testFeedWeights, []string{"/etc/rita/unweighted.txt", "feodo tracker"}),
+	)
+}
+
+func TestMatchUpdate(t *testing.T) {
+	update := matchUpdate(testFeedWeights, true, []string{"/etc/rita/watchlist.txt", "feodo tracker"})
+	assert.Equal(t, bson.M{"$set": bson.M{
+		"blacklisted":   true,
+		"bl_feeds":      []string{"/etc/rita/watchlist.txt", "feodo tracker"},
+		"bl_confidence": 0.92,
+	}}, update)
+
+	// a blacklisted indicator whose feeds couldn't be found is trusted as one unweighted feed
+	update = matchUpdate(testFeedWeights, true, nil)
+	assert.Equal(t, 0.5, update["$set"].(bson.M)["bl_confidence"])
+
+	// indicators which aren't blacklisted drop their feeds
+	update = matchUpdate(testFeedWeights, false, nil)
+	assert.Equal(t, bson.M{"blacklisted": false}, update["$set"])
+	assert.Contains(t, update["$unset"], "bl_feeds")
+	assert.Contains(t, update["$unset"], "bl_confidence")
+}
+
+func TestRecheckUpdate(t *testing.T) {
+	feeds := func(string) ([]string, error) { return []string{"feodo tracker"}, nil }
+
+	update, err := recheckUpdate(testFeedWeights, "203.0.113.5", true, feeds)
+	require.Nil(t, err)
+	assert.Equal(t, 0.9, update["$set"].(bson.M)["bl_confidence"])
+
+	update, err = recheckUpdate(testFeedWeights, "203.0.113.5", false, feeds)
+	require.Nil(t, err)
+	assert.Equal(t, false, update["$set"].(bson.M)["blacklisted"])
+
+	_, err = recheckUpdate(testFeedWeights, "203.0.113.5", true, func(string) ([]string, error) {
+		return nil, errors.New("connection refused")
+	})
+	assert.NotNil(t, err)
+}
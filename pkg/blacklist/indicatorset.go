@@ -0,0 +1,264 @@
+package blacklist
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/activecm/rita-bl/list"
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// IndicatorSet holds the blacklisted indicators of one type so that most
+// lookups against the blacklist database can be skipped. Small sets are kept
+// in memory as a BloomFilter. Sets larger than the configured maximum are
+// spilled to a sorted file on disk which is searched instead.
+type IndicatorSet struct {
+	filter *BloomFilter
+	disk   *diskIndicatorSet
+}
+
+// Check reports whether the indicator is blacklisted. The exact lookup is only
+// made when the set can't rule the indicator out. A nil set always performs the lookup.
+func (s *IndicatorSet) Check(indicator string, lookup func(string) (bool, error)) (bool, error) {
+	if s == nil {
+		return lookup(indicator)
+	}
+	if s.disk != nil {
+		// the file holds every indicator so it answers without a lookup
+		return s.disk.Contains(indicator)
+	}
+	return s.filter.Check(indicator, lookup)
+}
+
+// Close removes the files backing an on-disk set
+func (s *IndicatorSet) Close() error {
+	if s == nil || s.disk == nil {
+		return nil
+	}
+	return s.disk.Close()
+}
+
+// LoadIPSet builds an IndicatorSet of the blacklisted IPs in the blacklist database
+func LoadIPSet(db *database.DB, conf config.BlacklistedStaticCfg) (*IndicatorSet, error) {
+	return loadIndicatorSet(db, conf, list.BlacklistedIPType)
+}
+
+// LoadHostnameSet builds an IndicatorSet of the blacklisted hostnames in the blacklist database
+func LoadHostnameSet(db *database.DB, conf config.BlacklistedStaticCfg) (*IndicatorSet, error) {
+	return loadIndicatorSet(db, conf, list.BlacklistedHostnameType)
+}
+
+// loadIndicatorSet builds an IndicatorSet from the index of every entry of the
+// given type, spilling to disk if there are more than MaxInMemoryIndicators
+func loadIndicatorSet(db *database.DB, conf config.BlacklistedStaticCfg, entryType list.BlacklistedEntryType) (*IndicatorSet, error) {
+	ssn := db.Session.Copy()
+	defer ssn.Close()
+
+	coll := ssn.DB(conf.BlacklistDatabase).C(string(entryType))
+
+	count, err := coll.Count()
+	if err != nil {
+		return nil, err
+	}
+
+	if conf.MaxInMemoryIndicators > 0 && count > conf.MaxInMemoryIndicators {
+		disk, err := loadDiskIndicatorSet(coll)
+		if err != nil {
+			return nil, err
+		}
+		return &IndicatorSet{disk: disk}, nil
+	}
+
+	filter := NewBloomFilter(count, bloomFalsePositiveRate)
+
+	var entry struct {
+		Index string `bson:"index"`
+	}
+	iter := coll.Find(nil).Select(bson.M{"index": 1}).Iter()
+	for iter.Next(&entry) {
+		filter.Add(entry.Index)
+	}
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+	return &IndicatorSet{filter: filter}, nil
+}
+
+// loadDiskIndicatorSet writes the index of every entry in the collection to a
+// diskIndicatorSet. The entries are sorted by MongoDB using the index rita-bl
+// keeps on them, so they never have to be held in memory at once.
+func loadDiskIndicatorSet(coll *mgo.Collection) (*diskIndicatorSet, error) {
+	writer, err := newDiskIndicatorSetWriter("")
+	if err != nil {
+		return nil, err
+	}
+
+	var entry struct {
+		Index string `bson:"index"`
+	}
+	iter := coll.Find(nil).Select(bson.M{"index": 1}).Sort("index").Iter()
+	for iter.Next(&entry) {
+		if err := writer.Add(entry.Index); err != nil {
+			iter.Close()
+			writer.Abort()
+			return nil, err
+		}
+	}
+	if err := iter.Close(); err != nil {
+		writer.Abort()
+		return nil, err
+	}
+	return writer.Finish()
+}
+
+// diskIndicatorSet is an exact set of indicators stored in two files. The data
+// file holds the indicators back to back in ascending order and the offsets
+// file holds the big endian uint64 end offset of each indicator in the data file.
+// Indicators are found with a binary search using positioned reads, which
+// makes the set safe to search from several goroutines.
+type diskIndicatorSet struct {
+	data    *os.File
+	offsets *os.File
+	count   int64
+}
+
+// Contains returns true if the indicator is in the set
+func (d *diskIndicatorSet) Contains(indicator string) (bool, error) {
+	low, high := int64(0), d.count
+	for low < high {
+		mid := low + (high-low)/2
+		stored, err := d.indicator(mid)
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case stored == indicator:
+			return true, nil
+		case stored < indicator:
+			low = mid + 1
+		default:
+			high = mid
+		}
+	}
+	return false, nil
+}
+
+// indicator reads the i-th indicator of the set
+func (d *diskIndicatorSet) indicator(i int64) (string, error) {
+	var bounds [16]byte
+	var start, end uint64
+	if i == 0 {
+		if _, err := d.offsets.ReadAt(bounds[8:], 0); err != nil {
+			return "", err
+		}
+		end = binary.BigEndian.Uint64(bounds[8:])
+	} else {
+		if _, err := d.offsets.ReadAt(bounds[:], (i-1)*8); err != nil {
+			return "", err
+		}
+		start = binary.BigEndian.Uint64(bounds[:8])
+		end = binary.BigEndian.Uint64(bounds[8:])
+	}
+
+	buf := make([]byte, end-start)
+	if _, err := d.data.ReadAt(buf, int64(start)); err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
+// Close closes and removes the files backing the set
+func (d *diskIndicatorSet) Close() error {
+	var firstErr error
+	for _, file := range []*os.File{d.data, d.offsets} {
+		if err := file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if err := os.Remove(file.Name()); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// diskIndicatorSetWriter fills a diskIndicatorSet with indicators given in ascending order
+type diskIndicatorSetWriter struct {
+	set     *diskIndicatorSet
+	data    *bufio.Writer
+	offsets *bufio.Writer
+	end     uint64
+	last    string
+}
+
+// newDiskIndicatorSetWriter creates the files of a diskIndicatorSet in dir.
+// The default directory for temporary files is used if dir is empty.
+func newDiskIndicatorSetWriter(dir string) (*diskIndicatorSetWriter, error) {
+	data, err := ioutil.TempFile(dir, "rita-bl-data-")
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := ioutil.TempFile(dir, "rita-bl-offsets-")
+	if err != nil {
+		data.Close()
+		os.Remove(data.Name())
+		return nil, err
+	}
+
+	return &diskIndicatorSetWriter{
+		set:     &diskIndicatorSet{data: data, offsets: offsets},
+		data:    bufio.NewWriter(data),
+		offsets: bufio.NewWriter(offsets),
+	}, nil
+}
+
+// Add appends an indicator to the set. Repeats of the last indicator are
+// skipped and an indicator sorting before the last one is an error.
+func (w *diskIndicatorSetWriter) Add(indicator string) error {
+	if w.set.count > 0 {
+		if indicator == w.last {
+			return nil
+		}
+		if indicator < w.last {
+			return fmt.Errorf("blacklist indicator %q is out of order: it follows %q", indicator, w.last)
+		}
+	}
+
+	if _, err := w.data.WriteString(indicator); err != nil {
+		return err
+	}
+	w.end += uint64(len(indicator))
+
+	var offset [8]byte
+	binary.BigEndian.PutUint64(offset[:], w.end)
+	if _, err := w.offsets.Write(offset[:]); err != nil {
+		return err
+	}
+
+	w.last = indicator
+	w.set.count++
+	return nil
+}
+
+// Finish flushes the indicators to disk and returns the completed set
+func (w *diskIndicatorSetWriter) Finish() (*diskIndicatorSet, error) {
+	if err := w.data.Flush(); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	if err := w.offsets.Flush(); err != nil {
+		w.Abort()
+		return nil, err
+	}
+	return w.set, nil
+}
+
+// Abort removes the files of an unfinished set
+func (w *diskIndicatorSetWriter) Abort() {
+	w.set.Close()
+}
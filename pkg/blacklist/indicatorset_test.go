@@ -0,0 +1,117 @@
+package blacklist
+
+import (
+	"io/ioutil"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDiskSet writes the indicators to an on-disk indicator set in dir
+func newTestDiskSet(t *testing.T, dir string, indicators []string) *IndicatorSet {
+	sorted := append([]string(nil), indicators...)
+	sort.Strings(sorted)
+
+	writer, err := newDiskIndicatorSetWriter(dir)
+	require.Nil(t, err)
+	for _, indicator := range sorted {
+		require.Nil(t, writer.Add(indicator))
+	}
+	disk, err := writer.Finish()
+	require.Nil(t, err)
+	return &IndicatorSet{disk: disk}
+}
+
+func TestIndicatorSetBackendsMatch(t *testing.T) {
+	blacklisted := append(newTestIndicators("10.", 20000), "bad.example.com", "a", "")
+	// repeats occur when an indicator is on several lists
+	blacklisted = append(blacklisted, "bad.example.com", "10.0.0.1")
+
+	memory, lookup := newTestBlacklist(blacklisted...)
+	disk := newTestDiskSet(t, t.TempDir(), blacklisted)
+	defer disk.Close()
+
+	candidates := append(append(blacklisted, newTestIndicators("172.", 20000)...),
+		"good.example.com", "bad.example.co", "bad.example.comm", "0", "zzz")
+	for _, indicator := range candidates {
+		fromMemory, err := memory.Check(indicator, lookup)
+		require.Nil(t, err)
+		fromDisk, err := disk.Check(indicator, lookup)
+		require.Nil(t, err)
+		require.Equal(t, fromMemory, fromDisk, "the backends disagree on %q", indicator)
+	}
+}
+
+func TestIndicatorSetDiskSkipsLookups(t *testing.T) {
+	disk := newTestDiskSet(t, t.TempDir(), []string{"198.51.100.1", "203.0.113.7"})
+	defer disk.Close()
+
+	lookup := func(indicator string) (bool, error) {
+		t.Fatalf("unexpected lookup of %q", indicator)
+		return false, nil
+	}
+
+	found, err := disk.Check("203.0.113.7", lookup)
+	require.Nil(t, err)
+	assert.True(t, found)
+
+	found, err = disk.Check("203.0.113.8", lookup)
+	require.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestIndicatorSetDiskEmpty(t *testing.T) {
+	disk := newTestDiskSet(t, t.TempDir(), nil)
+	defer disk.Close()
+
+	found, err := disk.Check("203.0.113.7", nil)
+	require.Nil(t, err)
+	assert.False(t, found)
+
+	found, err = disk.Check("", nil)
+	require.Nil(t, err)
+	assert.False(t, found)
+}
+
+func TestIndicatorSetDiskOutOfOrder(t *testing.T) {
+	dir := t.TempDir()
+	writer, err := newDiskIndicatorSetWriter(dir)
+	require.Nil(t, err)
+
+	require.Nil(t, writer.Add("203.0.113.7"))
+	assert.NotNil(t, writer.Add("198.51.100.1"))
+
+	writer.Abort()
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, files)
+}
+
+func TestIndicatorSetCloseRemovesFiles(t *testing.T) {
+	dir := t.TempDir()
+	disk := newTestDiskSet(t, dir, []string{"203.0.113.7"})
+
+	files, err := ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Len(t, files, 2)
+
+	require.Nil(t, disk.Close())
+	files, err = ioutil.ReadDir(dir)
+	require.Nil(t, err)
+	assert.Empty(t, files)
+
+	// in-memory and missing sets have nothing to close
+	memory, _ := newTestBlacklist("203.0.113.7")
+	assert.Nil(t, memory.Close())
+	var missing *IndicatorSet
+	assert.Nil(t, missing.Close())
+}
+
+func TestIndicatorSetNil(t *testing.T) {
+	var set *IndicatorSet
+	found, err := set.Check("203.0.113.7", func(string) (bool, error) { return true, nil })
+	require.Nil(t, err)
+	assert.True(t, found)
+}
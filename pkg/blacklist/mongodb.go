@@ -58,7 +58,7 @@ func (r *repo) CreateIndexes() error {
 func (r *repo) Upsert() {
 
 	// Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "bl_updater")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "bl_updater")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
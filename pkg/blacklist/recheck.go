@@ -0,0 +1,164 @@
+package blacklist
+
+import (
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
+)
+
+// RecheckResult counts the hosts and hostnames whose blacklisted flag was
+// changed by Recheck
+type RecheckResult struct {
+	ListedHosts      int
+	ClearedHosts     int
+	ListedHostnames  int
+	ClearedHostnames int
+}
+
+// Recheck re-evaluates the hosts and hostnames already in the dataset against
+// the indicators currently in the blacklist database and updates the ones whose
+// blacklisted flag changed. The feeds and confidence of every blacklisted host
+// and hostname are refreshed to follow the current feeds and FeedWeights. The peers of hosts which are no longer blacklisted
+// drop their records of them, and the peer records of the blacklisted hosts are
+// rebuilt from the unique connections.
+func (r *repo) Recheck() (RecheckResult, error) {
+	var result RecheckResult
+
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	blDB := ssn.DB(r.config.S.Blacklisted.BlacklistDatabase)
+	hosts := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.HostTable)
+	hostnames := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.DNS.HostnamesTable)
+
+	ipFilter, err := LoadIPSet(r.database, r.config.S.Blacklisted)
+	if err != nil {
+		return result, err
+	}
+	defer ipFilter.Close()
+
+	var host struct {
+		data.UniqueIP `bson:",inline"`
+		Blacklisted   bool `bson:"blacklisted"`
+	}
+	var cleared []data.UniqueIP
+
+	iter := hosts.Find(nil).Select(bson.M{"ip": 1, "network_uuid": 1, "network_name": 1, "blacklisted": 1}).Iter()
+	for iter.Next(&host) {
+		blacklisted, changed, err := recheckFlag(host.IP, host.Blacklisted, ipFilter, indicatorLookup(blDB.C("ip")))
+		if err != nil {
+			iter.Close()
+			return result, err
+		}
+		if changed || blacklisted {
+			update, err := recheckUpdate(r.config.S.Blacklisted, host.IP, blacklisted, feedLookup(blDB.C("ip")))
+			if err == nil {
+				err = hosts.Update(host.UniqueIP.BSONKey(), update)
+			}
+			if err != nil {
+				iter.Close()
+				return result, err
+			}
+		}
+		if changed {
+			if blacklisted {
+				result.ListedHosts++
+			} else {
+				result.ClearedHosts++
+				cleared = append(cleared, host.UniqueIP)
+			}
+		}
+		host.UniqueIP, host.Blacklisted = data.UniqueIP{}, false
+	}
+	if err := iter.Close(); err != nil {
+		return result, err
+	}
+
+	for _, ip := range cleared {
+		_, err := hosts.UpdateAll(
+			bson.M{"dat": bson.M{"$elemMatch": ip.PrefixedBSONKey("bl")}},
+			bson.M{"$pull": bson.M{"dat": ip.PrefixedBSONKey("bl")}},
+		)
+		if err != nil {
+			return result, err
+		}
+	}
+
+	hostnameFilter, err := LoadHostnameSet(r.database, r.config.S.Blacklisted)
+	if err != nil {
+		return result, err
+	}
+	defer hostnameFilter.Close()
+
+	var hostname struct {
+		Host        string `bson:"host"`
+		Blacklisted bool   `bson:"blacklisted"`
+	}
+
+	iter = hostnames.Find(nil).Select(bson.M{"host": 1, "blacklisted": 1}).Iter()
+	for iter.Next(&hostname) {
+		blacklisted, changed, err := recheckFlag(hostname.Host, hostname.Blacklisted, hostnameFilter, indicatorLookup(blDB.C("hostname")))
+		if err != nil {
+			iter.Close()
+			return result, err
+		}
+		if changed || blacklisted {
+			update, err := recheckUpdate(r.config.S.Blacklisted, hostname.Host, blacklisted, feedLookup(blDB.C("hostname")))
+			if err == nil {
+				err = hostnames.Update(bson.M{"host": hostname.Host}, update)
+			}
+			if err != nil {
+				iter.Close()
+				return result, err
+			}
+		}
+		if changed {
+			if blacklisted {
+				result.ListedHostnames++
+			} else {
+				result.ClearedHostnames++
+			}
+		}
+		hostname.Host, hostname.Blacklisted = "", false
+	}
+	if err := iter.Close(); err != nil {
+		return result, err
+	}
+
+	r.Upsert()
+
+	return result, nil
+}
+
+// recheckFlag checks an indicator against the current blacklist and reports
+// whether it is blacklisted and whether that differs from its stored flag
+func recheckFlag(indicator string, stored bool, filter *IndicatorSet, lookup func(string) (bool, error)) (bool, bool, error) {
+	blacklisted, err := filter.Check(indicator, lookup)
+	if err != nil {
+		return stored, false, err
+	}
+	return blacklisted, blacklisted != stored, nil
+}
+
+// recheckUpdate returns the update which records the rechecked flag of an
+// indicator. The feeds listing a blacklisted indicator are looked up again.
+func recheckUpdate(conf config.BlacklistedStaticCfg, indicator string, blacklisted bool, feeds func(string) ([]string, error)) (bson.M, error) {
+	if !blacklisted {
+		return matchUpdate(conf, false, nil), nil
+	}
+	listing, err := feeds(indicator)
+	if err != nil {
+		return nil, err
+	}
+	return matchUpdate(conf, true, listing), nil
+}
+
+// indicatorLookup returns an exact lookup of indicators in a collection of the
+// blacklist database
+func indicatorLookup(coll *mgo.Collection) func(string) (bool, error) {
+	return func(indicator string) (bool, error) {
+		count, err := coll.Find(bson.M{"index": indicator}).Count()
+		return count > 0, err
+	}
+}
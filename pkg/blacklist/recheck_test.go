@@ -0,0 +1,62 @@
+package blacklist
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestBlacklist returns an in-memory indicator set and exact lookup of the indicators
+func newTestBlacklist(indicators ...string) (*IndicatorSet, func(string) (bool, error)) {
+	filter := NewBloomFilter(len(indicators), bloomFalsePositiveRate)
+	listed := make(map[string]bool)
+	for _, indicator := range indicators {
+		filter.Add(indicator)
+		listed[indicator] = true
+	}
+	return &IndicatorSet{filter: filter}, func(indicator string) (bool, error) {
+		return listed[indicator], nil
+	}
+}
+
+func TestRecheckFlagNewIndicator(t *testing.T) {
+	// the host was clean when the dataset was analyzed
+	filter, lookup := newTestBlacklist("198.51.100.7")
+	blacklisted, changed, err := recheckFlag("203.0.113.5", false, filter, lookup)
+	require.Nil(t, err)
+	assert.False(t, blacklisted)
+	assert.False(t, changed)
+
+	// and matches once its indicator is added to the feed
+	filter, lookup = newTestBlacklist("198.51.100.7", "203.0.113.5")
+	blacklisted, changed, err = recheckFlag("203.0.113.5", false, filter, lookup)
+	require.Nil(t, err)
+	assert.True(t, blacklisted)
+	assert.True(t, changed)
+
+	// hosts which were already blacklisted are left alone
+	blacklisted, changed, err = recheckFlag("198.51.100.7", true, filter, lookup)
+	require.Nil(t, err)
+	assert.True(t, blacklisted)
+	assert.False(t, changed)
+}
+
+func TestRecheckFlagRemovedIndicator(t *testing.T) {
+	filter, lookup := newTestBlacklist("198.51.100.7")
+	blacklisted, changed, err := recheckFlag("bad.example.com", true, filter, lookup)
+	require.Nil(t, err)
+	assert.False(t, blacklisted)
+	assert.True(t, changed)
+}
+
+func TestRecheckFlagLookupError(t *testing.T) {
+	filter, _ := newTestBlacklist("203.0.113.5")
+	blacklisted, changed, err := recheckFlag("203.0.113.5", false, filter, func(string) (bool, error) {
+		return false, errors.New("connection refused")
+	})
+	assert.NotNil(t, err)
+	assert.False(t, blacklisted)
+	assert.False(t, changed)
+}
@@ -8,6 +8,7 @@ import (
 type Repository interface {
 	CreateIndexes() error
 	Upsert()
+	Recheck() (RecheckResult, error)
 }
 
 // connectionPeer records how many connections were made to/ from a given host and how many bytes were sent/ received
@@ -18,12 +19,14 @@ type connectionPeer struct {
 }
 
 // IPResult represtes a blacklisted IP and summary data
-// about the connections involving that IP
+// about the connections involving that IP. Confidence combines the weights
+// of the blacklist feeds which list the IP.
 type IPResult struct {
 	Host              data.UniqueIP   `bson:",inline"`
 	Connections       int             `bson:"conn_count"`
 	UniqueConnections int             `bson:"uconn_count"`
 	TotalBytes        int             `bson:"total_bytes"`
+	Confidence        float64         `bson:"confidence"`
 	Peers             []data.UniqueIP `bson:"peers"`
 }
 
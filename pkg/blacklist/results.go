@@ -110,27 +110,46 @@ func HostnameResults(res *resources.Resources, sort string, limit int, noLimit b
 
 //SrcIPResults finds blacklisted source IPs in the database and the IPs of the
 //hosts which the blacklisted IP connected to. The results will be sorted in
-//descending order keyed on of {uconn_count, conn_count, total_bytes} depending on the value
+//descending order keyed on of {uconn_count, conn_count, total_bytes, confidence} depending on the value
 //of sort. limit and noLimit control how many results are returned.
 func SrcIPResults(res *resources.Resources, sort string, limit int, noLimit bool) ([]IPResult, error) {
-	return ipResults(res, sort, limit, noLimit, true)
+	return ipResults(res, srcIPResultsQuery(sort, limit, noLimit))
 }
 
 //DstIPResults finds blacklisted destination IPs in the database and the IPs of the
 //hosts which connected to the blacklisted IP. The results will be sorted in
-//descending order keyed on of {uconn_count, conn_count, total_bytes} depending on the value
+//descending order keyed on of {uconn_count, conn_count, total_bytes, confidence} depending on the value
 //of sort. limit and noLimit control how many results are returned.
 func DstIPResults(res *resources.Resources, sort string, limit int, noLimit bool) ([]IPResult, error) {
-	return ipResults(res, sort, limit, false, noLimit)
+	return ipResults(res, dstIPResultsQuery(sort, limit, noLimit))
+}
+
+//srcIPResultsQuery builds the host aggregation used by SrcIPResults
+func srcIPResultsQuery(sort string, limit int, noLimit bool) []bson.M {
+	return ipResultsQuery(sort, limit, noLimit, true)
+}
+
+//dstIPResultsQuery builds the host aggregation used by DstIPResults
+func dstIPResultsQuery(sort string, limit int, noLimit bool) []bson.M {
+	return ipResultsQuery(sort, limit, noLimit, false)
 }
 
-//ipResults implements SrcIPResults and DstIPResults. Set sourceDestFlag to true
-//to find blacklisted source IPs. Set sourceDestFlag to false to find blacklisted
-//destination IPs.
-func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, sourceDestFlag bool) ([]IPResult, error) {
+//ipResults runs an aggregation built by ipResultsQuery against the hosts collection
+func ipResults(res *resources.Resources, blIPQuery []bson.M) ([]IPResult, error) {
 	ssn := res.DB.Session.Copy()
 	defer ssn.Close()
 
+	var blIPs []IPResult
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.HostTable).Pipe(blIPQuery).AllowDiskUse().All(&blIPs)
+
+	return blIPs, err
+}
+
+//ipResultsQuery implements srcIPResultsQuery and dstIPResultsQuery. Set
+//sourceDestFlag to true to find blacklisted source IPs. Set sourceDestFlag to
+//false to find blacklisted destination IPs.
+func ipResultsQuery(sort string, limit int, noLimit bool, sourceDestFlag bool) []bson.M {
 	var hostMatch bson.M
 	var blHostField string
 	var blPeerField string
@@ -153,16 +172,15 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 			}}
 	}
 
-	var blIPs []IPResult
-
 	blIPQuery := []bson.M{
 		// find blacklisted source/ destination hosts
 		{"$match": hostMatch},
 		// only select ip info from hosts collection
 		{"$project": bson.M{
-			"ip":           1,
-			"network_uuid": 1,
-			"network_name": 1,
+			"ip":            1,
+			"network_uuid":  1,
+			"network_name":  1,
+			"bl_confidence": 1,
 		}},
 		// join on both src/dst and src/dst_network_uuid
 		{"$lookup": bson.M{
@@ -185,6 +203,7 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 			"ip":                1,
 			"network_uuid":      1,
 			"network_name":      1,
+			"bl_confidence":     1,
 			"peer_ip":           "$uconn." + blPeerField,
 			"peer_network_uuid": "$uconn." + blPeerField + "_network_uuid",
 			"peer_network_name": "$uconn." + blPeerField + "_network_name",
@@ -213,6 +232,8 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 			// use one of the network names associated with the network_uuid
 			// for this partial result
 			"peer_network_name": bson.M{"$last": "$peer_network_name"},
+			// hosts marked before feed weights were recorded are fully trusted
+			"confidence": bson.M{"$max": bson.M{"$ifNull": []interface{}{"$bl_confidence", 1}}},
 			// compute the partial sums over connections and bytes
 			"conns":  bson.M{"$sum": "$conns"},
 			"tbytes": bson.M{"$sum": "$tbytes"},
@@ -223,6 +244,7 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 			"ip":           "$_id.ip",
 			"network_uuid": "$_id.network_uuid",
 			"network_name": "$network_name",
+			"confidence":   1,
 			"peer": bson.M{
 				"ip":           "$_id.peer_ip",
 				"network_uuid": "$_id.peer_network_uuid",
@@ -239,9 +261,10 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 				"network_uuid": "$network_uuid",
 				"network_name": "$network_name",
 			},
-			"peers":  bson.M{"$addToSet": "$peer"},
-			"conns":  bson.M{"$sum": "$conns"},
-			"tbytes": bson.M{"$sum": "$tbytes"},
+			"peers":      bson.M{"$addToSet": "$peer"},
+			"conns":      bson.M{"$sum": "$conns"},
+			"tbytes":     bson.M{"$sum": "$tbytes"},
+			"confidence": bson.M{"$max": "$confidence"},
 		}},
 		// move the id fields back out and add uconn_count
 		{"$project": bson.M{
@@ -253,6 +276,7 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 			"conn_count":   "$conns",
 			"uconn_count":  bson.M{"$size": bson.M{"$ifNull": []interface{}{"$peers", []interface{}{}}}},
 			"total_bytes":  "$tbytes",
+			"confidence":   1,
 		}},
 		{"$sort": bson.M{sort: -1}},
 	}
@@ -261,8 +285,5 @@ func ipResults(res *resources.Resources, sort string, limit int, noLimit bool, s
 		blIPQuery = append(blIPQuery, bson.M{"$limit": limit})
 	}
 
-	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.HostTable).Pipe(blIPQuery).AllowDiskUse().All(&blIPs)
-
-	return blIPs, err
-
+	return blIPQuery
 }
@@ -0,0 +1,35 @@
+package blacklist
+
+import (
+	"testing"
+
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIPResultsQueryDirection(t *testing.T) {
+	countField := func(query []bson.M) string {
+		for _, cond := range query[0]["$match"].(bson.M)["$and"].([]bson.M) {
+			for field := range cond {
+				if field != "blacklisted" {
+					return field
+				}
+			}
+		}
+		return ""
+	}
+
+	// unlimited queries must still look for the requested side of the connections
+	assert.Equal(t, "dat.count_src", countField(srcIPResultsQuery("conn_count", 0, true)))
+	assert.Equal(t, "dat.count_dst", countField(dstIPResultsQuery("conn_count", 0, true)))
+	assert.Equal(t, "dat.count_src", countField(srcIPResultsQuery("conn_count", 10, false)))
+	assert.Equal(t, "dat.count_dst", countField(dstIPResultsQuery("conn_count", 10, false)))
+}
+
+func TestIPResultsQueryLimit(t *testing.T) {
+	limited := dstIPResultsQuery("conn_count", 10, false)
+	assert.Equal(t, bson.M{"$limit": 10}, limited[len(limited)-1])
+
+	unlimited := dstIPResultsQuery("conn_count", 10, true)
+	assert.NotContains(t, unlimited[len(unlimited)-1], "$limit")
+}
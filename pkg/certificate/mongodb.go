@@ -63,7 +63,7 @@ func (r *repo) CreateIndexes() error {
 // Upsert records the given certificate data in MongoDB
 func (r *repo) Upsert(certMap map[string]*Input) {
 	// Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "certificate")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "certificate")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
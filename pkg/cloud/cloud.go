@@ -0,0 +1,127 @@
+package cloud
+
+import (
+	"bufio"
+	// embed is imported for the bundled range table
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/activecm/rita/config"
+)
+
+// bundledRanges holds the cloud provider ranges shipped with RITA
+//
+//go:embed ranges.txt
+var bundledRanges string
+
+type (
+	// Ranges matches addresses against the published IP ranges of cloud providers
+	Ranges struct {
+		ranges []providerRange // most specific range first
+	}
+
+	// providerRange is a published range of a cloud provider
+	providerRange struct {
+		provider string
+		network  *net.IPNet
+	}
+)
+
+// LoadRanges returns the cloud provider ranges selected by the CloudProviders
+// config section. The bundled ranges are used unless a RangesFile is set, in
+// which case the file replaces them. Returns nil if the annotation is disabled.
+func LoadRanges(conf *config.Config) (*Ranges, error) {
+	if !conf.S.Cloud.Enabled {
+		return nil, nil
+	}
+
+	if conf.S.Cloud.RangesFile == "" {
+		return ParseRanges(strings.NewReader(bundledRanges))
+	}
+
+	file, err := os.Open(conf.S.Cloud.RangesFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	ranges, err := ParseRanges(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", conf.S.Cloud.RangesFile, err)
+	}
+	return ranges, nil
+}
+
+// ParseRanges reads a range table holding a provider name and a CIDR range on
+// each line. Blank lines and lines starting with # are skipped. Provider names
+// are lower cased.
+func ParseRanges(reader io.Reader) (*Ranges, error) {
+	var ranges []providerRange
+
+	scanner := bufio.NewScanner(reader)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected a provider and a CIDR range", lineNum)
+		}
+
+		_, network, err := net.ParseCIDR(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+		ranges = append(ranges, providerRange{provider: strings.ToLower(fields[0]), network: network})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// prefer the most specific range when the ranges of providers overlap
+	sort.SliceStable(ranges, func(i, j int) bool {
+		iOnes, _ := ranges[i].network.Mask.Size()
+		jOnes, _ := ranges[j].network.Mask.Size()
+		return iOnes > jOnes
+	})
+
+	return &Ranges{ranges: ranges}, nil
+}
+
+// Provider returns the cloud provider whose published ranges hold the address,
+// or an empty string if the address isn't in any of the ranges
+func (r *Ranges) Provider(address string) string {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return ""
+	}
+	for _, providerRange := range r.ranges {
+		if providerRange.network.Contains(ip) {
+			return providerRange.provider
+		}
+	}
+	return ""
+}
+
+// Providers returns the names of the cloud providers in the table
+func (r *Ranges) Providers() []string {
+	seen := make(map[string]bool)
+	var providers []string
+	for _, providerRange := range r.ranges {
+		if !seen[providerRange.provider] {
+			seen[providerRange.provider] = true
+			providers = append(providers, providerRange.provider)
+		}
+	}
+	sort.Strings(providers)
+	return providers
+}
@@ -0,0 +1,86 @@
+package cloud
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBundledRanges(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	require.True(t, conf.S.Cloud.Enabled, "the annotation should be enabled by default")
+
+	ranges, err := LoadRanges(conf)
+	require.Nil(t, err)
+	require.NotNil(t, ranges)
+
+	assert.Equal(t, []string{"aws", "azure", "gcp"}, ranges.Providers())
+
+	cases := map[string]string{
+		"3.5.140.2":     "aws",
+		"54.200.10.20":  "aws",
+		"2600:1f18::1":  "aws",
+		"35.190.247.1":  "gcp",
+		"104.197.3.9":   "gcp",
+		"2600:1901::1":  "gcp",
+		"40.76.4.15":    "azure",
+		"13.70.1.1":     "azure",
+		"2603:1030::1":  "azure",
+		"203.0.113.7":   "",
+		"192.168.1.10":  "",
+		"not an ip":     "",
+		"2001:db8::100": "",
+	}
+	for address, provider := range cases {
+		assert.Equal(t, provider, ranges.Provider(address), address)
+	}
+}
+
+func TestParseRanges(t *testing.T) {
+	ranges, err := ParseRanges(strings.NewReader(`
+# comments and blank lines are skipped
+Example 198.51.100.0/24
+
+other 198.51.100.128/25
+`))
+	require.Nil(t, err)
+	assert.Equal(t, []string{"example", "other"}, ranges.Providers())
+	assert.Equal(t, "example", ranges.Provider("198.51.100.7"))
+	assert.Equal(t, "other", ranges.Provider("198.51.100.200"), "the most specific range should be preferred")
+
+	_, err = ParseRanges(strings.NewReader("example 198.51.100.0/33\n"))
+	assert.NotNil(t, err)
+
+	_, err = ParseRanges(strings.NewReader("example\n"))
+	assert.NotNil(t, err)
+}
+
+func TestLoadRanges(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// a ranges file replaces the bundled ranges
+	conf.S.Cloud.RangesFile = filepath.Join(t.TempDir(), "ranges.txt")
+	require.Nil(t, ioutil.WriteFile(conf.S.Cloud.RangesFile, []byte("custom 203.0.113.0/24\n"), 0644))
+
+	ranges, err := LoadRanges(conf)
+	require.Nil(t, err)
+	assert.Equal(t, []string{"custom"}, ranges.Providers())
+	assert.Equal(t, "custom", ranges.Provider("203.0.113.7"))
+	assert.Equal(t, "", ranges.Provider("3.5.140.2"))
+
+	conf.S.Cloud.RangesFile = filepath.Join(t.TempDir(), "missing.txt")
+	_, err = LoadRanges(conf)
+	assert.NotNil(t, err)
+
+	conf.S.Cloud.Enabled = false
+	ranges, err = LoadRanges(conf)
+	assert.Nil(t, err)
+	assert.Nil(t, ranges)
+}
@@ -14,7 +14,8 @@ type UniqueSrcFQDNPair struct {
 	FQDN        string `bson:"fqdn"`
 }
 
-//NewUniqueSrcFQDNPair binds a pair of UniqueIPs and an FQDN
+//NewUniqueSrcFQDNPair binds a pair of UniqueIPs and an FQDN. The pair is
+//returned in canonical form.
 func NewUniqueSrcFQDNPair(source UniqueIP, fqdn string) UniqueSrcFQDNPair {
 	return UniqueSrcFQDNPair{
 		UniqueSrcIP: UniqueSrcIP{
@@ -23,7 +24,16 @@ func NewUniqueSrcFQDNPair(source UniqueIP, fqdn string) UniqueSrcFQDNPair {
 			SrcNetworkName: source.NetworkName,
 		},
 		FQDN: fqdn,
-	}
+	}.Canonical()
+}
+
+//Canonical returns the pair with its source IP and, if the FQDN is an IP
+//address, its FQDN in canonical form. The same logical pair always produces
+//the same MapKey and BSONKey once it is canonical.
+func (p UniqueSrcFQDNPair) Canonical() UniqueSrcFQDNPair {
+	p.SrcIP = CanonicalIP(p.SrcIP)
+	p.FQDN = CanonicalIP(p.FQDN)
+	return p
 }
 
 //MapKey generates a string which may be used to index a Unique SrcIP / FQDN pair. Concatenates IPs and UUIDs.
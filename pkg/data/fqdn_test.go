@@ -0,0 +1,33 @@
+package data
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUniqueSrcFQDNPairCanonical(t *testing.T) {
+	src := NewUniqueIP(net.ParseIP("10.0.0.1"), "", "")
+
+	// every spelling of the same source and proxied address is one pair
+	canonical := NewUniqueSrcFQDNPair(src, "2001:db8::1")
+	for _, pair := range []UniqueSrcFQDNPair{
+		NewUniqueSrcFQDNPair(src, "2001:0DB8:0000:0000:0000:0000:0000:0001"),
+		NewUniqueSrcFQDNPair(src, "[2001:db8::1]"),
+		UniqueSrcFQDNPair{UniqueSrcIP: UniqueSrcIP{SrcIP: "::ffff:10.0.0.1", SrcNetworkUUID: src.NetworkUUID, SrcNetworkName: src.NetworkName}, FQDN: "2001:DB8::1"}.Canonical(),
+	} {
+		assert.Equal(t, canonical.MapKey(), pair.MapKey())
+		assert.Equal(t, canonical.BSONKey(), pair.BSONKey())
+	}
+
+	// the stored form of an address which is already canonical is unchanged
+	assert.Equal(t, canonical, canonical.Canonical())
+	assert.Equal(t, "10.0.0.1", canonical.SrcIP)
+	assert.Equal(t, "2001:db8::1", canonical.FQDN)
+
+	// hostnames and distinct sources remain distinct pairs
+	assert.Equal(t, "Example.com", NewUniqueSrcFQDNPair(src, "Example.com").FQDN)
+	other := NewUniqueIP(net.ParseIP("::ffff:10.0.0.2"), "", "")
+	assert.NotEqual(t, canonical.MapKey(), NewUniqueSrcFQDNPair(other, "2001:db8::1").MapKey())
+}
@@ -20,6 +20,33 @@ type UniqueIP struct {
 	NetworkName string      `bson:"network_name"`
 }
 
+//ParseIP parses an IP address the same way as net.ParseIP, but also accepts
+//IPv6 addresses wrapped in brackets or carrying a zone identifier, such as
+//[2001:db8::1] or fe80::1%eth0. The zone only names the interface the address
+//was seen on, so it is dropped. Returns nil if addr is not an IP address.
+func ParseIP(addr string) net.IP {
+	if strings.HasPrefix(addr, "[") && strings.HasSuffix(addr, "]") {
+		addr = addr[1 : len(addr)-1]
+	}
+	if zone := strings.IndexByte(addr, '%'); zone != -1 {
+		addr = addr[:zone]
+	}
+	return net.ParseIP(addr)
+}
+
+//CanonicalIP returns the canonical text form of an IP address so that every
+//spelling of an address produces the same key. IPv4-mapped IPv6 addresses
+//become IPv4 addresses, IPv6 addresses are compressed and lower cased, and
+//zone identifiers are dropped. Strings which are not IP addresses, such as
+//hostnames, are returned unchanged.
+func CanonicalIP(addr string) string {
+	ip := ParseIP(addr)
+	if ip == nil {
+		return addr
+	}
+	return ip.String()
+}
+
 //NewUniqueIP returns a new UniqueIP. If the given ip is publicly routable, the resulting UniqueIP's
 //NetworkUUID and NetworkName will be set to PublicNetworkUUID and PublicNetworkName respectively.
 //Otherwise, the NetworkUUID and NetworkName will be set based on the provided agentName and agentUUID.
@@ -216,6 +243,25 @@ func (p UniqueIPPair) BSONKey() bson.M {
 	return key
 }
 
+//Hash generates a fixed size hash of the pair's IPs and Network UUIDs
+func (p UniqueIPPair) Hash() util.FixedStringHash {
+	return util.NewFixedStringHash(
+		p.SrcIP,
+		string([]byte{p.SrcNetworkUUID.Kind}), string(p.SrcNetworkUUID.Data),
+		p.DstIP,
+		string([]byte{p.DstNetworkUUID.Kind}), string(p.DstNetworkUUID.Data),
+	)
+}
+
+//BSONHashKey generates a BSON map which selects a given source/destination UniqueIP pair
+//by the fixed size hash stored in the pair_hash field. This is an alternative to BSONKey
+//which compares faster when there are millions of pairs.
+func (p UniqueIPPair) BSONHashKey() bson.M {
+	return bson.M{
+		"pair_hash": bson.Binary{Kind: bson.BinaryGeneric, Data: p.Hash().Bytes()},
+	}
+}
+
 //UniqueIPSet is a set of UniqueIPs which contains at most one instance of each UniqueIP
 //this implementation is based on a slice of UniqueIPs rather than a map[string]UniqueIP
 //since it requires less RAM.
@@ -2,6 +2,7 @@ package data
 
 import (
 	"net"
+	"strconv"
 	"testing"
 
 	"github.com/activecm/rita/util"
@@ -51,3 +52,115 @@ func TestNewUniqueIP(t *testing.T) {
 	assert.Equal(t, util.PublicNetworkUUID.Data, ip.NetworkUUID.Data, "uuid binary set to flag value for public ip with valid network data")
 	assert.Equal(t, util.PublicNetworkName, ip.NetworkName, "net name set to flag value for public ip with valid network data")
 }
+
+func TestUniqueIPPairHash(t *testing.T) {
+	netA := NewUniqueIP(net.ParseIP("10.0.0.1"), "ff0d0776-0cdc-4a10-b793-522bcd48a560", "a")
+	netB := NewUniqueIP(net.ParseIP("10.0.0.1"), "3d1d4e68-8a0b-4c61-a2a6-4d9fb088bd61", "b")
+	public := NewUniqueIP(net.ParseIP("8.8.8.8"), "", "")
+
+	pair := NewUniqueIPPair(netA, public)
+	assert.Equal(t, pair.Hash(), NewUniqueIPPair(netA, public).Hash(), "the hash should be deterministic")
+	assert.NotEqual(t, pair.Hash(), NewUniqueIPPair(public, netA).Hash(), "reversed pairs should not collide")
+	assert.NotEqual(t, pair.Hash(), NewUniqueIPPair(netB, public).Hash(), "the same IP on distinct networks should not collide")
+
+	// the network name is for display and is not part of the key
+	renamed := netA
+	renamed.NetworkName = "renamed"
+	assert.Equal(t, pair.Hash(), NewUniqueIPPair(renamed, public).Hash())
+
+	key := pair.BSONHashKey()
+	assert.Equal(t, bson.Binary{Kind: bson.BinaryGeneric, Data: pair.Hash().Bytes()}, key["pair_hash"])
+
+	// distinct pairs never share a hash
+	hashes := make(map[util.FixedStringHash]string)
+	for i := 0; i < 256; i++ {
+		for j := 0; j < 256; j++ {
+			src := NewUniqueIP(net.ParseIP("10.0."+strconv.Itoa(i)+"."+strconv.Itoa(j)), "", "")
+			for _, dst := range []UniqueIP{public, netA} {
+				p := NewUniqueIPPair(src, dst)
+				hash := p.Hash()
+				existing, ok := hashes[hash]
+				assert.False(t, ok && existing != p.MapKey(), "%s collides with %s", p.MapKey(), existing)
+				hashes[hash] = p.MapKey()
+			}
+		}
+	}
+	assert.Len(t, hashes, 256*256*2)
+}
+
+func BenchmarkUniqueIPPairKeys(b *testing.B) {
+	pairs := make([]UniqueIPPair, 4096)
+	for i := range pairs {
+		src := NewUniqueIP(net.ParseIP("10.0."+strconv.Itoa(i/256)+"."+strconv.Itoa(i%256)), "", "")
+		dst := NewUniqueIP(net.ParseIP("52.1."+strconv.Itoa(i%256)+"."+strconv.Itoa(i/256)), "", "")
+		pairs[i] = NewUniqueIPPair(src, dst)
+	}
+
+	b.Run("BSONKey", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pairs[i%len(pairs)].BSONKey()
+		}
+	})
+
+	b.Run("BSONHashKey", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			pairs[i%len(pairs)].BSONHashKey()
+		}
+	})
+
+	// comparing fixed size keys is what makes the hashed index faster
+	strKeys := make(map[string]int, len(pairs))
+	hashKeys := make(map[util.FixedStringHash]int, len(pairs))
+	for i, pair := range pairs {
+		strKeys[pair.MapKey()] = i
+		hashKeys[pair.Hash()] = i
+	}
+	strLookups := make([]string, len(pairs))
+	hashLookups := make([]util.FixedStringHash, len(pairs))
+	for i, pair := range pairs {
+		strLookups[i] = pair.MapKey()
+		hashLookups[i] = pair.Hash()
+	}
+
+	b.Run("MapKeyLookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = strKeys[strLookups[i%len(strLookups)]]
+		}
+	})
+
+	b.Run("HashLookup", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = hashKeys[hashLookups[i%len(hashLookups)]]
+		}
+	})
+}
+
+func TestParseIP(t *testing.T) {
+	assert.Equal(t, net.ParseIP("fe80::1"), ParseIP("fe80::1%eth0"), "zone identifier is dropped")
+	assert.Equal(t, net.ParseIP("2001:db8::1"), ParseIP("[2001:db8::1]"), "brackets are removed")
+	assert.Equal(t, net.ParseIP("10.0.0.1"), ParseIP("10.0.0.1"), "IPv4 is parsed as usual")
+	assert.Nil(t, ParseIP("example.com"), "hostnames are not IP addresses")
+	assert.Nil(t, ParseIP("[example.com]"), "bracketed hostnames are not IP addresses")
+}
+
+func TestCanonicalIP(t *testing.T) {
+	testCases := []struct {
+		msg       string
+		spellings []string
+		canonical string
+	}{
+		{"IPv4-mapped IPv6 addresses become IPv4", []string{"::ffff:10.0.0.1", "::FFFF:10.0.0.1", "0:0:0:0:0:ffff:a00:1", "10.0.0.1"}, "10.0.0.1"},
+		{"expanded IPv6 is compressed", []string{"2001:0db8:0000:0000:0000:0000:0000:0001", "2001:db8:0:0:0:0:0:1", "2001:db8::1"}, "2001:db8::1"},
+		{"mixed case hex is lower cased", []string{"2001:DB8::AbCd", "2001:db8::abcd", "[2001:Db8::ABCD]"}, "2001:db8::abcd"},
+		{"zone identifiers are dropped", []string{"fe80::1%eth0", "FE80::1%en0", "fe80:0:0:0:0:0:0:1"}, "fe80::1"},
+		{"hostnames are unchanged", []string{"Example.com"}, "Example.com"},
+	}
+
+	for _, test := range testCases {
+		for _, spelling := range test.spellings {
+			assert.Equal(t, test.canonical, CanonicalIP(spelling), "%s: %s", test.msg, spelling)
+		}
+	}
+}
@@ -53,6 +53,9 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
+
 		ssn := a.db.Session.Copy()
 		defer ssn.Close()
 		for data := range a.analysisChannel {
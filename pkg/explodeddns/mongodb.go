@@ -66,7 +66,7 @@ func (r *repo) CreateIndexes() error {
 func (r *repo) Upsert(domainMap map[string]int) {
 
 	//Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "exploded_dns")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "exploded_dns")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
@@ -3,6 +3,7 @@ package host
 import (
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/blacklist"
 
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
@@ -18,6 +19,7 @@ type (
 		conf             *config.Config             // contains details needed to access MongoDB
 		db               *database.DB               // provides access to MongoDB
 		log              *log.Logger                // logger for writing out errors and warnings
+		blFilter         *blacklist.IndicatorSet    // screens out hosts which can't be blacklisted (nil checks every host)
 		analyzedCallback func(database.BulkChanges) // called on each analyzed result
 		closedCallback   func()                     // called when .close() is called and no more calls to analyzedCallback will be made
 		analysisChannel  chan *Input                // holds unanalyzed data
@@ -26,12 +28,14 @@ type (
 )
 
 // newAnalyzer creates a new collector for gathering data
-func newAnalyzer(chunk int, conf *config.Config, db *database.DB, log *log.Logger, analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
+func newAnalyzer(chunk int, conf *config.Config, db *database.DB, log *log.Logger, blFilter *blacklist.IndicatorSet,
+	analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
 	return &analyzer{
 		chunk:            chunk,
 		conf:             conf,
 		log:              log,
 		db:               db,
+		blFilter:         blFilter,
 		analyzedCallback: analyzedCallback,
 		closedCallback:   closedCallback,
 		analysisChannel:  make(chan *Input),
@@ -54,6 +58,9 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
+
 		ssn := a.db.Session.Copy()
 		defer ssn.Close()
 
@@ -64,7 +71,7 @@ func (a *analyzer) start() {
 
 			mainUpdate := mainQuery(datum, a.chunk)
 
-			blUpdate, err := blQuery(datum, ssn, a.conf.S.Blacklisted.BlacklistDatabase) // TODO: Move to BL package
+			blUpdate, err := blQuery(datum, ssn, a.conf.S.Blacklisted, a.blFilter) // TODO: Move to BL package
 			if err != nil {
 				a.log.WithFields(log.Fields{
 					"Module": "host",
@@ -101,17 +108,11 @@ func mainQuery(datum *Input, chunk int) bson.M {
 	}
 }
 
-// blQuery marks the given host as blacklisted or not
-func blQuery(datum *Input, ssn *mgo.Session, blDB string) (bson.M, error) {
-	// check if blacklisted destination
-	blCount, err := ssn.DB(blDB).C("ip").Find(bson.M{"index": datum.Host.IP}).Count()
-	blacklisted := blCount > 0
-
-	return bson.M{
-		"$set": bson.M{
-			"blacklisted": blacklisted,
-		},
-	}, err
+// blQuery marks the given host as blacklisted or not, along with the feeds which
+// list it and how confident they are. The blacklist database is only queried if
+// the host passes the blacklist indicator set.
+func blQuery(datum *Input, ssn *mgo.Session, blConf config.BlacklistedStaticCfg, blFilter *blacklist.IndicatorSet) (bson.M, error) {
+	return blacklist.CheckIndicator(ssn.DB(blConf.BlacklistDatabase).C("ip"), datum.Host.IP, blFilter, blConf)
 }
 
 // connCountsQuery records the number of connections this host has been a part of
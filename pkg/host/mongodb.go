@@ -5,6 +5,7 @@ import (
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/blacklist"
 	"github.com/activecm/rita/util"
 
 	"github.com/globalsign/mgo"
@@ -62,13 +63,23 @@ func (r *repo) Upsert(hostMap map[string]*Input) {
 	// 1st Phase: Analysis
 
 	// Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "host")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "host")
+
+	// load the blacklisted indicators up front so that most lookups can be skipped.
+	// Every indicator is looked up if the set can't be loaded.
+	blFilter, err := blacklist.LoadIPSet(r.database, r.config.S.Blacklisted)
+	if err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "host",
+		}).Error(err)
+	}
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
 		r.config,
 		r.database,
 		r.log,
+		blFilter,
 		writerWorker.Collect,
 		writerWorker.Close,
 	)
@@ -98,4 +109,10 @@ func (r *repo) Upsert(hostMap map[string]*Input) {
 
 	// start the closing cascade (this will also close the other channels)
 	analyzerWorker.close()
+
+	if err := blFilter.Close(); err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "host",
+		}).Error(err)
+	}
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/blacklist"
 	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 
@@ -19,6 +20,7 @@ type (
 		db               *database.DB               // provides access to MongoDB
 		conf             *config.Config             // contains details needed to access MongoDB
 		log              *log.Logger                // logger for writing out errors and warnings
+		blFilter         *blacklist.IndicatorSet    // screens out hostnames which can't be blacklisted (nil checks every hostname)
 		analyzedCallback func(database.BulkChanges) // called on each analyzed result
 		closedCallback   func()                     // called when .close() is called and no more calls to analyzedCallback will be made
 		analysisChannel  chan *Input                // holds unanalyzed data
@@ -27,12 +29,14 @@ type (
 )
 
 // newAnalyzer creates a new collector for parsing hostnames
-func newAnalyzer(chunk int, db *database.DB, conf *config.Config, log *log.Logger, analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
+func newAnalyzer(chunk int, db *database.DB, conf *config.Config, log *log.Logger, blFilter *blacklist.IndicatorSet,
+	analyzedCallback func(database.BulkChanges), closedCallback func()) *analyzer {
 	return &analyzer{
 		chunk:            chunk,
 		db:               db,
 		conf:             conf,
 		log:              log,
+		blFilter:         blFilter,
 		analyzedCallback: analyzedCallback,
 		closedCallback:   closedCallback,
 		analysisChannel:  make(chan *Input),
@@ -55,6 +59,9 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
+
 		ssn := a.db.Session.Copy()
 		defer ssn.Close()
 
@@ -68,7 +75,7 @@ func (a *analyzer) start() {
 
 			mainUpdate := mainQuery(datum, a.chunk)
 
-			blUpdate, err := blQuery(datum, ssn, a.conf.S.Blacklisted.BlacklistDatabase) // TODO: Move to BL package
+			blUpdate, err := blQuery(datum, ssn, a.conf.S.Blacklisted, a.blFilter) // TODO: Move to BL package
 			if err != nil {
 				a.log.WithFields(log.Fields{
 					"Module": "hostname",
@@ -111,15 +118,9 @@ func mainQuery(datum *Input, chunk int) bson.M {
 	}
 }
 
-// blQuery marks the given hostname as blacklisted or not
-func blQuery(datum *Input, ssn *mgo.Session, blDB string) (bson.M, error) {
-	// check if blacklisted destination
-	blCount, err := ssn.DB(blDB).C("hostname").Find(bson.M{"index": datum.Host}).Count()
-	blacklisted := blCount > 0
-
-	return bson.M{
-		"$set": bson.M{
-			"blacklisted": blacklisted,
-		},
-	}, err
+// blQuery marks the given hostname as blacklisted or not, along with the feeds which
+// list it and how confident they are. The blacklist database is only queried if
+// the hostname passes the blacklist indicator set.
+func blQuery(datum *Input, ssn *mgo.Session, blConf config.BlacklistedStaticCfg, blFilter *blacklist.IndicatorSet) (bson.M, error) {
+	return blacklist.CheckIndicator(ssn.DB(blConf.BlacklistDatabase).C("hostname"), datum.Host, blFilter, blConf)
 }
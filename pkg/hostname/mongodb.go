@@ -5,6 +5,7 @@ import (
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/blacklist"
 	"github.com/activecm/rita/util"
 	"github.com/globalsign/mgo"
 	log "github.com/sirupsen/logrus"
@@ -64,13 +65,23 @@ func (r *repo) CreateIndexes() error {
 func (r *repo) Upsert(hostnameMap map[string]*Input) {
 
 	// Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "hostname")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "hostname")
+
+	// load the blacklisted indicators up front so that most lookups can be skipped.
+	// Every indicator is looked up if the set can't be loaded.
+	blFilter, err := blacklist.LoadHostnameSet(r.database, r.config.S.Blacklisted)
+	if err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "hostname",
+		}).Error(err)
+	}
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
 		r.config,
 		r.log,
+		blFilter,
 		writerWorker.Collect,
 		writerWorker.Close,
 	)
@@ -108,4 +119,9 @@ func (r *repo) Upsert(hostnameMap map[string]*Input) {
 	// start the closing cascade (this will also close the other channels)
 	analyzerWorker.close()
 
+	if err := blFilter.Close(); err != nil {
+		r.log.WithFields(log.Fields{
+			"Module": "hostname",
+		}).Error(err)
+	}
 }
@@ -0,0 +1,133 @@
+package hostrole
+
+import (
+	"bytes"
+	"net"
+	"sort"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+)
+
+// Role describes how an internal host behaves on the network
+type Role string
+
+const (
+	// Server hosts receive many connections
+	Server Role = "server"
+	// Client hosts make many connections
+	Client Role = "client"
+	// Scanner hosts connect to many distinct hosts
+	Scanner Role = "scanner"
+	// BeaconSource hosts are the source of a high scoring beacon
+	BeaconSource Role = "beacon-source"
+)
+
+type (
+	// HostProfile summarizes the behavior of an internal host along with the
+	// roles it was classified into
+	HostProfile struct {
+		Host           data.UniqueIP `bson:"host"`
+		ConnectionsOut int64         `bson:"connections_out"`
+		ConnectionsIn  int64         `bson:"connections_in"`
+		Destinations   int           `bson:"destinations"`     // distinct hosts the host connected to
+		MaxBeaconScore float64       `bson:"max_beacon_score"` // highest score of the beacons from the host
+		Roles          []Role        `bson:"roles"`
+	}
+
+	// Builder assembles the behavior of each internal host and classifies them
+	Builder struct {
+		thresholds config.HostRolesStaticCfg
+		hosts      map[string]*HostProfile
+	}
+)
+
+// HasRole returns true if the host was classified into the role
+func (h HostProfile) HasRole(role Role) bool {
+	for _, r := range h.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns the roles of a host with the given behavior. A host
+// may have any number of roles.
+func Classify(h HostProfile, thresholds config.HostRolesStaticCfg) []Role {
+	var roles []Role
+	if h.ConnectionsIn >= thresholds.ServerMinConnections {
+		roles = append(roles, Server)
+	}
+	if h.ConnectionsOut >= thresholds.ClientMinConnections {
+		roles = append(roles, Client)
+	}
+	if h.Destinations >= thresholds.ScannerMinDestinations {
+		roles = append(roles, Scanner)
+	}
+	if h.MaxBeaconScore > 0 && h.MaxBeaconScore >= thresholds.BeaconMinScore {
+		roles = append(roles, BeaconSource)
+	}
+	return roles
+}
+
+// NewBuilder creates a Builder which classifies hosts using the thresholds
+func NewBuilder(thresholds config.HostRolesStaticCfg) *Builder {
+	return &Builder{
+		thresholds: thresholds,
+		hosts:      make(map[string]*HostProfile),
+	}
+}
+
+// AddHosts registers the internal hosts along with their connection counts.
+// Only registered hosts are listed in the results.
+func (b *Builder) AddHosts(hosts []HostProfile) {
+	for _, host := range hosts {
+		profile, ok := b.hosts[host.Host.MapKey()]
+		if !ok {
+			profile = &HostProfile{Host: host.Host}
+			b.hosts[host.Host.MapKey()] = profile
+		}
+		profile.ConnectionsOut += host.ConnectionsOut
+		profile.ConnectionsIn += host.ConnectionsIn
+	}
+}
+
+// AddDestinationCounts records how many distinct hosts each registered host
+// connected to
+func (b *Builder) AddDestinationCounts(counts []DestinationCount) {
+	for _, count := range counts {
+		if profile, ok := b.hosts[count.Host.MapKey()]; ok {
+			profile.Destinations += count.Destinations
+		}
+	}
+}
+
+// AddBeacons records the highest score of the beacons from each registered host
+func (b *Builder) AddBeacons(results []beacon.Result) {
+	for _, result := range results {
+		profile, ok := b.hosts[result.UniqueSrcIP.Unpair().MapKey()]
+		if ok && result.Score > profile.MaxBeaconScore {
+			profile.MaxBeaconScore = result.Score
+		}
+	}
+}
+
+// Results classifies each registered host and returns them sorted by address
+func (b *Builder) Results() []HostProfile {
+	results := make([]HostProfile, 0, len(b.hosts))
+	for _, profile := range b.hosts {
+		profile.Roles = Classify(*profile, b.thresholds)
+		results = append(results, *profile)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		ipI, ipJ := net.ParseIP(results[i].Host.IP), net.ParseIP(results[j].Host.IP)
+		if cmp := bytes.Compare(ipI.To16(), ipJ.To16()); cmp != 0 {
+			return cmp < 0
+		}
+		return results[i].Host.NetworkName < results[j].Host.NetworkName
+	})
+	return results
+}
@@ -0,0 +1,85 @@
+package hostrole
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testThresholds = config.HostRolesStaticCfg{
+	ServerMinConnections:   1000,
+	ClientMinConnections:   1000,
+	ScannerMinDestinations: 250,
+	BeaconMinScore:         0.8,
+}
+
+func testIP(ip string) data.UniqueIP {
+	return data.UniqueIP{IP: ip, NetworkUUID: util.UnknownPrivateNetworkUUID, NetworkName: util.UnknownPrivateNetworkName}
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		msg     string
+		profile HostProfile
+		roles   []Role
+	}{
+		{"quiet host", HostProfile{ConnectionsOut: 20, ConnectionsIn: 5, Destinations: 3}, nil},
+		{"file server", HostProfile{ConnectionsOut: 10, ConnectionsIn: 50000, Destinations: 2}, []Role{Server}},
+		{"workstation", HostProfile{ConnectionsOut: 4000, ConnectionsIn: 12, Destinations: 80}, []Role{Client}},
+		{"scanner", HostProfile{ConnectionsOut: 300, Destinations: 300}, []Role{Scanner}},
+		{"infected workstation", HostProfile{ConnectionsOut: 1500, Destinations: 40, MaxBeaconScore: 0.93}, []Role{Client, BeaconSource}},
+		{"low scoring beacon", HostProfile{ConnectionsOut: 100, Destinations: 4, MaxBeaconScore: 0.5}, nil},
+		{"exact thresholds", HostProfile{ConnectionsOut: 1000, ConnectionsIn: 1000, Destinations: 250, MaxBeaconScore: 0.8},
+			[]Role{Server, Client, Scanner, BeaconSource}},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.roles, Classify(test.profile, testThresholds), test.msg)
+	}
+
+	// a beacon source threshold of 0 still requires a beacon
+	noBeaconThresh := testThresholds
+	noBeaconThresh.BeaconMinScore = 0
+	assert.Empty(t, Classify(HostProfile{}, noBeaconThresh))
+}
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder(testThresholds)
+
+	builder.AddHosts([]HostProfile{
+		{Host: testIP("10.0.0.10"), ConnectionsOut: 30, ConnectionsIn: 80000},
+		{Host: testIP("10.0.0.2"), ConnectionsOut: 2500, ConnectionsIn: 3},
+		{Host: testIP("10.0.0.3"), ConnectionsOut: 600},
+	})
+	builder.AddDestinationCounts([]DestinationCount{
+		{Host: testIP("10.0.0.2"), Destinations: 20},
+		{Host: testIP("10.0.0.3"), Destinations: 600},
+		// hosts which weren't registered are not listed
+		{Host: testIP("203.0.113.1"), Destinations: 1000},
+	})
+	builder.AddBeacons([]beacon.Result{
+		{UniqueIPPair: data.NewUniqueIPPair(testIP("10.0.0.2"), testIP("203.0.113.5")), Score: 0.7},
+		{UniqueIPPair: data.NewUniqueIPPair(testIP("10.0.0.2"), testIP("203.0.113.6")), Score: 0.85},
+		// the destination of a beacon is not a beacon source
+		{UniqueIPPair: data.NewUniqueIPPair(testIP("203.0.113.7"), testIP("10.0.0.10")), Score: 0.99},
+	})
+
+	results := builder.Results()
+	require.Len(t, results, 3)
+
+	// sorted by address rather than by string
+	assert.Equal(t, HostProfile{Host: testIP("10.0.0.2"), ConnectionsOut: 2500, ConnectionsIn: 3, Destinations: 20,
+		MaxBeaconScore: 0.85, Roles: []Role{Client, BeaconSource}}, results[0])
+	assert.Equal(t, HostProfile{Host: testIP("10.0.0.3"), ConnectionsOut: 600, Destinations: 600,
+		Roles: []Role{Scanner}}, results[1])
+	assert.Equal(t, HostProfile{Host: testIP("10.0.0.10"), ConnectionsOut: 30, ConnectionsIn: 80000,
+		Roles: []Role{Server}}, results[2])
+
+	assert.True(t, results[0].HasRole(BeaconSource))
+	assert.False(t, results[2].HasRole(Client))
+}
@@ -0,0 +1,102 @@
+package hostrole
+
+import (
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/resources"
+	"github.com/globalsign/mgo/bson"
+)
+
+// DestinationCount holds how many distinct hosts a host connected to
+type DestinationCount struct {
+	Host         data.UniqueIP `bson:"host"`
+	Destinations int           `bson:"destinations"`
+}
+
+// Results classifies each internal host in the selected database using the
+// HostRoles thresholds from the config
+func Results(res *resources.Resources) ([]HostProfile, error) {
+	builder := NewBuilder(res.Config.S.HostRoles)
+
+	hosts, err := HostResults(res)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddHosts(hosts)
+
+	counts, err := DestinationCountResults(res)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddDestinationCounts(counts)
+
+	if res.Config.S.Beacon.Enabled {
+		beacons, err := beacon.Results(res, 0)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBeacons(beacons)
+	}
+
+	return builder.Results(), nil
+}
+
+// HostResults returns the internal hosts along with the number of
+// connections they made and received, summed across every chunk
+func HostResults(res *resources.Resources) ([]HostProfile, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var hosts []HostProfile
+
+	hostQuery := []bson.M{
+		{"$match": bson.M{"local": true}},
+		{"$project": bson.M{
+			"_id": 0,
+			"host": bson.M{
+				"ip":           "$ip",
+				"network_uuid": "$network_uuid",
+				"network_name": "$network_name",
+			},
+			"connections_out": bson.M{"$sum": "$dat.count_src"},
+			"connections_in":  bson.M{"$sum": "$dat.count_dst"},
+		}},
+	}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.HostTable).Pipe(hostQuery).AllowDiskUse().All(&hosts)
+
+	return hosts, err
+}
+
+// DestinationCountResults returns how many distinct hosts each source
+// connected to
+func DestinationCountResults(res *resources.Resources) ([]DestinationCount, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var counts []DestinationCount
+
+	countQuery := []bson.M{
+		{"$group": bson.M{
+			"_id": bson.M{
+				"ip":           "$src",
+				"network_uuid": "$src_network_uuid",
+			},
+			"network_name": bson.M{"$first": "$src_network_name"},
+			"destinations": bson.M{"$sum": 1},
+		}},
+		{"$project": bson.M{
+			"_id": 0,
+			"host": bson.M{
+				"ip":           "$_id.ip",
+				"network_uuid": "$_id.network_uuid",
+				"network_name": "$network_name",
+			},
+			"destinations": 1,
+		}},
+	}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.UniqueConnTable).Pipe(countQuery).AllowDiskUse().All(&counts)
+
+	return counts, err
+}
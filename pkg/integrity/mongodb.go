@@ -0,0 +1,209 @@
+package integrity
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/remover"
+	"github.com/globalsign/mgo/bson"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type repo struct {
+	database *database.DB
+	metaDB   *database.MetaDB
+	config   *config.Config
+	log      *log.Logger
+}
+
+// reference links an analysis collection to the connection collection its
+// documents are derived from
+type reference struct {
+	collection string
+	target     string
+	keyFields  []string
+}
+
+// NewMongoRepository creates a new integrity checker for the selected database
+func NewMongoRepository(db *database.DB, metaDB *database.MetaDB, conf *config.Config, logger *log.Logger) Repository {
+	return &repo{
+		database: db,
+		metaDB:   metaDB,
+		config:   conf,
+		log:      logger,
+	}
+}
+
+// Check looks for analysis documents which no longer have a matching connection
+// record and for documents which reference chunks the MetaDB does not track
+func (r *repo) Check() (Report, error) {
+	report := Report{Database: r.database.GetSelectedDB()}
+
+	for _, ref := range r.references() {
+		ids, err := r.findOrphans(ref)
+		if err != nil {
+			return report, err
+		}
+		if len(ids) > 0 {
+			report.Orphans = append(report.Orphans, OrphanedDocuments{
+				Collection:     ref.collection,
+				ReferenceTable: ref.target,
+				DocumentIDs:    ids,
+			})
+		}
+	}
+
+	info, err := r.metaDB.GetDBMetaInfo(report.Database)
+	if err != nil {
+		return report, err
+	}
+
+	for _, collection := range r.modules() {
+		used, err := r.usedCIDs(collection)
+		if err != nil {
+			return report, err
+		}
+		dangling := danglingCIDs(used, info.CIDList)
+		if len(dangling) > 0 {
+			report.DanglingChunks = append(report.DanglingChunks, DanglingChunks{
+				Collection: collection,
+				CIDs:       dangling,
+			})
+		}
+	}
+
+	return report, nil
+}
+
+// Repair removes the orphaned documents and the data stored under dangling
+// chunk ids found by Check
+func (r *repo) Repair(report Report) error {
+	err := r.database.Writable()
+	if err != nil {
+		return err
+	}
+
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	for _, orphans := range report.Orphans {
+		_, err := ssn.DB(report.Database).C(orphans.Collection).RemoveAll(
+			bson.M{"_id": bson.M{"$in": orphans.DocumentIDs}},
+		)
+		if err != nil {
+			return fmt.Errorf("could not remove orphaned documents from %s: %v", orphans.Collection, err)
+		}
+	}
+
+	chunkRemover := remover.NewMongoRemover(r.database, r.config, r.log)
+	for _, cid := range report.uniqueCIDs() {
+		err := chunkRemover.Remove(cid)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// references lists the analysis collections which are derived from connection
+// collections along with the fields which link them
+func (r *repo) references() []reference {
+	pairFields := []string{"src", "src_network_uuid", "dst", "dst_network_uuid"}
+	fqdnFields := []string{"src", "src_network_uuid", "fqdn"}
+	return []reference{
+		{r.config.T.Beacon.BeaconTable, r.config.T.Structure.UniqueConnTable, pairFields},
+		{r.config.T.BeaconProxy.BeaconProxyTable, r.config.T.Structure.UniqueConnProxyTable, fqdnFields},
+		{r.config.T.BeaconSNI.BeaconSNITable, r.config.T.Structure.SNIConnTable, fqdnFields},
+	}
+}
+
+// modules lists every collection which stores data by chunk id. This matches
+// the collections cleared out by the remover.
+func (r *repo) modules() []string {
+	return []string{
+		r.config.T.Beacon.BeaconTable,
+		r.config.T.BeaconProxy.BeaconProxyTable,
+		r.config.T.BeaconSNI.BeaconSNITable,
+		r.config.T.Structure.HostTable,
+		r.config.T.Structure.UniqueConnTable,
+		r.config.T.Structure.UniqueConnProxyTable,
+		r.config.T.Structure.SNIConnTable,
+		r.config.T.DNS.ExplodedDNSTable,
+		r.config.T.DNS.HostnamesTable,
+		r.config.T.Cert.CertificateTable,
+		r.config.T.UserAgent.UserAgentTable,
+	}
+}
+
+// findOrphans returns the ids of documents in ref.collection which do not have
+// a matching document in ref.target
+func (r *repo) findOrphans(ref reference) ([]bson.ObjectId, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	db := ssn.DB(r.database.GetSelectedDB())
+
+	projection := bson.M{}
+	for _, field := range ref.keyFields {
+		projection[field] = 1
+	}
+
+	var orphans []bson.ObjectId
+	var doc bson.M
+
+	iter := db.C(ref.collection).Find(nil).Select(projection).Iter()
+	for iter.Next(&doc) {
+		selector := bson.M{}
+		for _, field := range ref.keyFields {
+			selector[field] = doc[field]
+		}
+
+		count, err := db.C(ref.target).Find(selector).Limit(1).Count()
+		if err != nil {
+			iter.Close()
+			return nil, err
+		}
+
+		if count == 0 {
+			if id, ok := doc["_id"].(bson.ObjectId); ok {
+				orphans = append(orphans, id)
+			}
+		}
+		doc = nil
+	}
+
+	return orphans, iter.Close()
+}
+
+// usedCIDs returns the distinct chunk ids referenced by the documents in a
+// collection. Chunk ids are stored both at the top level of a document and
+// within the per chunk entries of the dat array.
+func (r *repo) usedCIDs(collection string) ([]int, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	c := ssn.DB(r.database.GetSelectedDB()).C(collection)
+
+	seen := make(map[int]bool)
+	for _, field := range []string{"cid", "dat.cid"} {
+		var cids []int
+		err := c.Find(nil).Distinct(field, &cids)
+		if err != nil {
+			return nil, err
+		}
+		for _, cid := range cids {
+			seen[cid] = true
+		}
+	}
+
+	used := make([]int, 0, len(seen))
+	for cid := range seen {
+		used = append(used, cid)
+	}
+	sort.Ints(used)
+	return used, nil
+}
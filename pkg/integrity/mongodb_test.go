@@ -0,0 +1,83 @@
+// +build integration
+
+package integrity
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/resources"
+	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckAndRepair(t *testing.T) {
+	res := resources.InitIntegrationTestingResources(t)
+
+	db := "tmp_test_integrity"
+	res.DB.SelectDB(db)
+	defer func() {
+		res.MetaDB.DeleteDB(db)
+		res.DB.Session.DB(db).DropDatabase()
+	}()
+
+	// only chunk 0 holds data according to the MetaDB
+	require.Nil(t, res.MetaDB.AddNewDB(db, 0, 2))
+	require.Nil(t, res.MetaDB.SetChunk(0, db, true))
+
+	pair := func(dst string) bson.M {
+		return bson.M{
+			"src":              "10.0.0.1",
+			"src_network_uuid": util.UnknownPrivateNetworkUUID,
+			"dst":              dst,
+			"dst_network_uuid": util.PublicNetworkUUID,
+		}
+	}
+	withChunk := func(doc bson.M, cid int) bson.M {
+		doc["cid"] = cid
+		doc["dat"] = []bson.M{{"cid": cid}}
+		return doc
+	}
+
+	mongo := res.DB.Session.DB(db)
+	require.Nil(t, mongo.C(res.Config.T.Structure.UniqueConnTable).Insert(withChunk(pair("203.0.113.1"), 0)))
+
+	// one beacon still has its connections, the other's were removed
+	beacons := mongo.C(res.Config.T.Beacon.BeaconTable)
+	require.Nil(t, beacons.Insert(withChunk(pair("203.0.113.1"), 0)))
+	orphan := withChunk(pair("203.0.113.2"), 0)
+	orphan["_id"] = bson.NewObjectId()
+	require.Nil(t, beacons.Insert(orphan))
+
+	// a host was left with data from a chunk the MetaDB doesn't track
+	hosts := mongo.C(res.Config.T.Structure.HostTable)
+	require.Nil(t, hosts.Insert(bson.M{"ip": "10.0.0.1", "network_uuid": util.UnknownPrivateNetworkUUID, "dat": []bson.M{{"cid": 0}, {"cid": 1}}}))
+
+	repo := NewMongoRepository(res.DB, res.MetaDB, res.Config, res.Log)
+	report, err := repo.Check()
+	require.Nil(t, err)
+	assert.False(t, report.Consistent())
+
+	require.Len(t, report.Orphans, 1)
+	assert.Equal(t, res.Config.T.Beacon.BeaconTable, report.Orphans[0].Collection)
+	assert.Equal(t, []bson.ObjectId{orphan["_id"].(bson.ObjectId)}, report.Orphans[0].DocumentIDs)
+
+	require.Len(t, report.DanglingChunks, 1)
+	assert.Equal(t, res.Config.T.Structure.HostTable, report.DanglingChunks[0].Collection)
+	assert.Equal(t, []int{1}, report.DanglingChunks[0].CIDs)
+
+	require.Nil(t, repo.Repair(report))
+
+	// the orphaned beacon and the untracked chunk are gone, the rest is kept
+	count, err := beacons.Find(nil).Count()
+	require.Nil(t, err)
+	assert.Equal(t, 1, count)
+	count, err = beacons.FindId(orphan["_id"]).Count()
+	require.Nil(t, err)
+	assert.Equal(t, 0, count)
+
+	report, err = repo.Check()
+	require.Nil(t, err)
+	assert.True(t, report.Consistent())
+}
@@ -0,0 +1,69 @@
+package integrity
+
+import (
+	"github.com/activecm/rita/database"
+	"github.com/globalsign/mgo/bson"
+)
+
+// Repository checks the referential consistency of a dataset
+type Repository interface {
+	Check() (Report, error)
+	Repair(report Report) error
+}
+
+type (
+	// OrphanedDocuments lists the documents in an analysis collection which
+	// reference hosts with no matching connection record
+	OrphanedDocuments struct {
+		Collection     string          // collection holding the orphaned documents
+		ReferenceTable string          // collection which should hold the matching connection records
+		DocumentIDs    []bson.ObjectId // ids of the orphaned documents
+	}
+
+	// DanglingChunks lists the chunk ids referenced by documents in a collection
+	// which the MetaDB does not record as holding data
+	DanglingChunks struct {
+		Collection string
+		CIDs       []int
+	}
+
+	// Report holds the results of checking a dataset
+	Report struct {
+		Database       string
+		Orphans        []OrphanedDocuments
+		DanglingChunks []DanglingChunks
+	}
+)
+
+// Consistent returns true if no issues were found in the dataset
+func (r Report) Consistent() bool {
+	return len(r.Orphans) == 0 && len(r.DanglingChunks) == 0
+}
+
+// danglingCIDs returns the chunk ids in usedCIDs which are out of range for the
+// dataset or which are not marked as set in cidList
+func danglingCIDs(usedCIDs []int, cidList []database.ChunkState) []int {
+	var dangling []int
+	for _, cid := range usedCIDs {
+		if cid < 0 || cid >= len(cidList) || !cidList[cid].Set {
+			dangling = append(dangling, cid)
+		}
+	}
+	return dangling
+}
+
+// uniqueCIDs returns the distinct chunk ids referenced across every collection
+// in the report
+func (r Report) uniqueCIDs() []int {
+	seen := make(map[int]bool)
+	var cids []int
+	for _, entry := range r.DanglingChunks {
+		for _, cid := range entry.CIDs {
+			if !seen[cid] {
+				seen[cid] = true
+				cids = append(cids, cid)
+			}
+		}
+	}
+	return cids
+}
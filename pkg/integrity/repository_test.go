@@ -0,0 +1,33 @@
+package integrity
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/database"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDanglingCIDs(t *testing.T) {
+	cidList := []database.ChunkState{{Set: true}, {Set: false}, {Set: true}}
+
+	assert.Nil(t, danglingCIDs([]int{0, 2}, cidList))
+	assert.Equal(t, []int{1}, danglingCIDs([]int{0, 1, 2}, cidList))
+
+	// chunk ids past the end of the list are not tracked by the MetaDB
+	assert.Equal(t, []int{3, 7}, danglingCIDs([]int{0, 3, 7}, cidList))
+
+	// a database without a chunk list has no valid chunks
+	assert.Equal(t, []int{0}, danglingCIDs([]int{0}, nil))
+}
+
+func TestReportConsistent(t *testing.T) {
+	report := Report{Database: "test"}
+	assert.True(t, report.Consistent())
+
+	report.DanglingChunks = []DanglingChunks{
+		{Collection: "host", CIDs: []int{1, 3}},
+		{Collection: "uconn", CIDs: []int{3}},
+	}
+	assert.False(t, report.Consistent())
+	assert.Equal(t, []int{1, 3}, report.uniqueCIDs())
+}
@@ -0,0 +1,107 @@
+package redact
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+)
+
+// hashPrefix marks an address which was replaced by its keyed hash
+const hashPrefix = "redacted-"
+
+// hashLength is the number of hex characters of the keyed hash which are kept.
+// 12 characters (48 bits) keeps collisions between hosts unlikely while
+// keeping the output readable.
+const hashLength = 12
+
+// Redactor masks the configured address fields of results before they are
+// shared. The same address always maps to the same masked value.
+type Redactor struct {
+	enabled      bool
+	src          bool
+	dst          bool
+	zero         bool
+	key          []byte
+	internal     []*net.IPNet
+	internalOnly bool
+}
+
+// NewRedactor creates a Redactor from the Redaction config section. Redaction
+// is applied if it is enabled in the config or if force is set.
+func NewRedactor(conf *config.Config, force bool) *Redactor {
+	r := &Redactor{
+		enabled:      conf.S.Redaction.Enabled || force,
+		zero:         conf.S.Redaction.Method == "zero",
+		key:          []byte(conf.S.Redaction.Key),
+		internal:     util.ParseSubnets(conf.S.Filtering.InternalSubnets),
+		internalOnly: conf.S.Redaction.InternalOnly,
+	}
+	for _, field := range conf.S.Redaction.Fields {
+		switch field {
+		case "src":
+			r.src = true
+		case "dst":
+			r.dst = true
+		}
+	}
+	return r
+}
+
+// Enabled returns true if the Redactor will mask any addresses
+func (r *Redactor) Enabled() bool {
+	return r.enabled && (r.src || r.dst)
+}
+
+// Src masks a source address if source addresses are redacted
+func (r *Redactor) Src(ip string) string {
+	if !r.enabled || !r.src {
+		return ip
+	}
+	return r.mask(ip)
+}
+
+// Dst masks a destination address if destination addresses are redacted
+func (r *Redactor) Dst(ip string) string {
+	if !r.enabled || !r.dst {
+		return ip
+	}
+	return r.mask(ip)
+}
+
+// SrcIP masks the address of a UniqueSrcIP
+func (r *Redactor) SrcIP(src data.UniqueSrcIP) data.UniqueSrcIP {
+	src.SrcIP = r.Src(src.SrcIP)
+	return src
+}
+
+// Pair masks the addresses of a UniqueIPPair
+func (r *Redactor) Pair(pair data.UniqueIPPair) data.UniqueIPPair {
+	pair.SrcIP = r.Src(pair.SrcIP)
+	pair.DstIP = r.Dst(pair.DstIP)
+	return pair
+}
+
+// mask replaces a single address. Addresses outside of InternalSubnets are
+// kept when InternalOnly is set.
+func (r *Redactor) mask(ip string) string {
+	parsed := net.ParseIP(ip)
+	if r.internalOnly && (parsed == nil || !util.ContainsIP(r.internal, parsed)) {
+		return ip
+	}
+
+	if r.zero {
+		if parsed != nil && parsed.To4() == nil {
+			return net.IPv6zero.String()
+		}
+		return net.IPv4zero.String()
+	}
+
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(ip))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil))[:hashLength]
+}
@@ -0,0 +1,106 @@
+package redact
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRedactor(t *testing.T, fields []string, method, key string, internalOnly bool) *Redactor {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+	conf.S.Filtering.InternalSubnets = []string{"10.0.0.0/8", "fd00::/8"}
+	conf.S.Redaction.Fields = fields
+	conf.S.Redaction.Method = method
+	conf.S.Redaction.Key = key
+	conf.S.Redaction.InternalOnly = internalOnly
+	return NewRedactor(conf, true)
+}
+
+func TestRedactorHash(t *testing.T) {
+	r := newTestRedactor(t, []string{"src"}, "hash", "secret", true)
+	require.True(t, r.Enabled())
+
+	masked := r.Src("10.0.0.1")
+	assert.True(t, strings.HasPrefix(masked, hashPrefix), "internal source %s should be hashed", masked)
+	assert.Len(t, masked, len(hashPrefix)+hashLength)
+	assert.NotContains(t, masked, "10.0.0.1")
+
+	// the mapping is deterministic, including across redactors with the same key
+	assert.Equal(t, masked, r.Src("10.0.0.1"))
+	assert.Equal(t, masked, newTestRedactor(t, []string{"src"}, "hash", "secret", true).Src("10.0.0.1"))
+
+	// distinct hosts map to distinct values
+	assert.NotEqual(t, masked, r.Src("10.0.0.2"))
+
+	// the key changes the mapping
+	assert.NotEqual(t, masked, newTestRedactor(t, []string{"src"}, "hash", "other", true).Src("10.0.0.1"))
+
+	// IPv6 internal addresses are masked too
+	assert.True(t, strings.HasPrefix(r.Src("fd00::1"), hashPrefix))
+
+	// external sources are preserved
+	assert.Equal(t, "8.8.8.8", r.Src("8.8.8.8"))
+
+	// destinations are not configured for redaction
+	assert.Equal(t, "10.0.0.1", r.Dst("10.0.0.1"))
+}
+
+func TestRedactorZero(t *testing.T) {
+	r := newTestRedactor(t, []string{"src", "dst"}, "zero", "", false)
+
+	assert.Equal(t, "0.0.0.0", r.Src("10.0.0.1"))
+	assert.Equal(t, "::", r.Src("fd00::1"))
+
+	// InternalOnly is off so external addresses are masked as well
+	assert.Equal(t, "0.0.0.0", r.Dst("8.8.8.8"))
+	assert.Equal(t, "::", r.Dst("2001:4860:4860::8888"))
+}
+
+func TestRedactorPair(t *testing.T) {
+	r := newTestRedactor(t, []string{"src", "dst"}, "hash", "secret", true)
+
+	pair := data.NewUniqueIPPair(
+		data.UniqueIP{IP: "10.0.0.1", NetworkName: "lan"},
+		data.UniqueIP{IP: "8.8.8.8", NetworkName: "public"},
+	)
+	masked := r.Pair(pair)
+
+	assert.Equal(t, r.Src("10.0.0.1"), masked.SrcIP)
+	assert.Equal(t, "8.8.8.8", masked.DstIP, "external destinations should be preserved")
+	assert.Equal(t, "lan", masked.SrcNetworkName)
+	assert.Equal(t, "10.0.0.1", pair.SrcIP, "the original pair should not be modified")
+
+	// internal destinations are masked the same way as internal sources
+	reversed := r.Pair(data.NewUniqueIPPair(
+		data.UniqueIP{IP: "8.8.8.8"},
+		data.UniqueIP{IP: "10.0.0.1"},
+	))
+	assert.Equal(t, masked.SrcIP, reversed.DstIP)
+
+	src := r.SrcIP(pair.UniqueSrcIP)
+	assert.Equal(t, masked.SrcIP, src.SrcIP)
+}
+
+func TestRedactorDisabled(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	r := NewRedactor(conf, false)
+	assert.False(t, r.Enabled())
+	assert.Equal(t, "10.0.0.1", r.Src("10.0.0.1"))
+
+	// enabling redaction in the config has the same effect as forcing it
+	conf.S.Redaction.Enabled = true
+	r = NewRedactor(conf, false)
+	assert.True(t, r.Enabled())
+	assert.NotEqual(t, "10.0.0.1", r.Src("10.0.0.1"))
+
+	// no fields means nothing is redacted
+	conf.S.Redaction.Fields = nil
+	assert.False(t, NewRedactor(conf, true).Enabled())
+}
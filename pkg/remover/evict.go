@@ -0,0 +1,57 @@
+package remover
+
+import (
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/pkg/uconnproxy"
+)
+
+// Evicted holds the unique connections which had data in an evicted chunk
+// along with their local hosts, and the time range of the chunks which remain
+type Evicted struct {
+	UconnMap      map[string]*uconn.Input
+	HostMap       map[string]*host.Input
+	UconnProxyMap map[string]*uconnproxy.Input
+	ProxyHostMap  map[string]*host.Input
+	MinTimestamp  int64
+	MaxTimestamp  int64
+}
+
+// Evict removes the oldest chunk of a rolling database and passes the unique
+// connections which had data in it to rescore, so that their beacons only
+// reflect the remaining chunks. The current chunk is always kept, so nothing is
+// removed once the database holds a single chunk. Returns the removed chunk and
+// whether a chunk was removed.
+func Evict(repo Repository, info database.DBMetaInfo, rescore func(Evicted) error) (int, bool, error) {
+	chunks := info.SetChunks()
+	if len(chunks) < 2 {
+		return 0, false, nil
+	}
+	cid := chunks[0]
+
+	// the connections have to be gathered before their data in the chunk is removed
+	var evicted Evicted
+	var err error
+	evicted.UconnMap, evicted.HostMap, err = repo.ChunkConnections(cid)
+	if err != nil {
+		return cid, false, err
+	}
+	evicted.UconnProxyMap, evicted.ProxyHostMap, err = repo.ChunkProxyConnections(cid)
+	if err != nil {
+		return cid, false, err
+	}
+
+	err = repo.Remove(cid)
+	if err != nil {
+		return cid, false, err
+	}
+
+	// the beacons are rescored over the time range of the remaining chunks
+	evicted.MinTimestamp, evicted.MaxTimestamp, err = repo.TSRange()
+	if err != nil {
+		return cid, true, err
+	}
+
+	return cid, true, rescore(evicted)
+}
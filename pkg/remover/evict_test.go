@@ -0,0 +1,178 @@
+package remover
+
+import (
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/pkg/uconnproxy"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepo holds the unique connections with data in each chunk and the
+// time range of each chunk
+type fakeRepo struct {
+	chunks    map[int][]data.UniqueIPPair
+	ranges    map[int][2]int64
+	removeErr error
+}
+
+func (f *fakeRepo) Remove(cid int) error {
+	if f.removeErr != nil {
+		return f.removeErr
+	}
+	delete(f.chunks, cid)
+	delete(f.ranges, cid)
+	return nil
+}
+
+func (f *fakeRepo) ChunkProxyConnections(cid int) (map[string]*uconnproxy.Input, map[string]*host.Input, error) {
+	return make(map[string]*uconnproxy.Input), make(map[string]*host.Input), nil
+}
+
+func (f *fakeRepo) TSRange() (int64, int64, error) {
+	var min, max int64
+	for _, tsRange := range f.ranges {
+		if min == 0 || tsRange[0] < min {
+			min = tsRange[0]
+		}
+		if tsRange[1] > max {
+			max = tsRange[1]
+		}
+	}
+	return min, max, nil
+}
+
+func (f *fakeRepo) ChunkConnections(cid int) (map[string]*uconn.Input, map[string]*host.Input, error) {
+	uconnMap := make(map[string]*uconn.Input)
+	hostMap := make(map[string]*host.Input)
+	for _, pair := range f.chunks[cid] {
+		addChunkConnection(uconnMap, hostMap, pair, util.ParseSubnets([]string{"10.0.0.0/8"}))
+	}
+	return uconnMap, hostMap, nil
+}
+
+// chunkCount returns the number of chunks holding data for a unique connection
+func (f *fakeRepo) chunkCount(pair data.UniqueIPPair) int {
+	count := 0
+	for _, pairs := range f.chunks {
+		for _, chunkPair := range pairs {
+			if chunkPair.MapKey() == pair.MapKey() {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+func newTestPair(src, dst string) data.UniqueIPPair {
+	return data.NewUniqueIPPair(data.NewUniqueIP(net.ParseIP(src), "", ""), data.NewUniqueIP(net.ParseIP(dst), "", ""))
+}
+
+func newTestInfo(currentChunk int, set ...int) database.DBMetaInfo {
+	info := database.DBMetaInfo{
+		Rolling:      true,
+		TotalChunks:  2,
+		CurrentChunk: currentChunk,
+		CIDList:      make([]database.ChunkState, 2),
+	}
+	for _, cid := range set {
+		info.CIDList[cid].Set = true
+	}
+	return info
+}
+
+func TestEvict(t *testing.T) {
+	both := newTestPair("10.0.0.1", "203.0.113.1")
+	oldest := newTestPair("10.0.0.2", "203.0.113.2")
+	current := newTestPair("10.0.0.3", "203.0.113.3")
+
+	repo := &fakeRepo{
+		chunks: map[int][]data.UniqueIPPair{
+			0: {both, oldest},
+			1: {both, current},
+		},
+		ranges: map[int][2]int64{0: {100, 200}, 1: {300, 400}},
+	}
+
+	// the scores are recomputed from the chunks which remain
+	scores := make(map[string]int)
+	var hosts []string
+	var tsRange [2]int64
+	rescore := func(evicted Evicted) error {
+		for key, entry := range evicted.UconnMap {
+			scores[key] = repo.chunkCount(entry.Hosts)
+		}
+		for _, entry := range evicted.HostMap {
+			hosts = append(hosts, entry.Host.IP)
+		}
+		tsRange = [2]int64{evicted.MinTimestamp, evicted.MaxTimestamp}
+		return nil
+	}
+
+	cid, evicted, err := Evict(repo, newTestInfo(1, 0, 1), rescore)
+	require.Nil(t, err)
+	assert.True(t, evicted)
+	assert.Equal(t, 0, cid)
+
+	// only the current chunk is left
+	assert.Equal(t, map[int][]data.UniqueIPPair{1: {both, current}}, repo.chunks)
+
+	// and the pairs which had data in the removed chunk are rescored
+	assert.Equal(t, map[string]int{both.MapKey(): 1, oldest.MapKey(): 0}, scores)
+	assert.ElementsMatch(t, []string{"10.0.0.1", "10.0.0.2"}, hosts)
+
+	// over the time range of the current chunk only
+	assert.Equal(t, [2]int64{300, 400}, tsRange)
+
+	// evicting again leaves the current chunk in place
+	scores = make(map[string]int)
+	cid, evicted, err = Evict(repo, newTestInfo(1, 1), rescore)
+	require.Nil(t, err)
+	assert.False(t, evicted)
+	assert.Equal(t, 0, cid)
+	assert.Equal(t, map[int][]data.UniqueIPPair{1: {both, current}}, repo.chunks)
+	assert.Empty(t, scores)
+}
+
+func TestEvictRemoveError(t *testing.T) {
+	repo := &fakeRepo{
+		chunks:    map[int][]data.UniqueIPPair{0: {newTestPair("10.0.0.1", "203.0.113.1")}},
+		removeErr: errors.New("not writable"),
+	}
+
+	rescored := false
+	_, evicted, err := Evict(repo, newTestInfo(1, 0, 1), func(Evicted) error {
+		rescored = true
+		return nil
+	})
+	assert.NotNil(t, err)
+	assert.False(t, evicted)
+	assert.False(t, rescored)
+}
+
+func TestAddChunkConnection(t *testing.T) {
+	uconnMap := make(map[string]*uconn.Input)
+	hostMap := make(map[string]*host.Input)
+	internal := util.ParseSubnets([]string{"10.0.0.0/8"})
+
+	external := newTestPair("203.0.113.1", "10.0.0.1")
+	addChunkConnection(uconnMap, hostMap, external, internal)
+
+	require.Contains(t, uconnMap, external.MapKey())
+	assert.False(t, uconnMap[external.MapKey()].IsLocalSrc)
+	assert.True(t, uconnMap[external.MapKey()].IsLocalDst)
+
+	// only the internal host is summarized
+	require.Len(t, hostMap, 1)
+	for _, entry := range hostMap {
+		assert.Equal(t, "10.0.0.1", entry.Host.IP)
+		assert.True(t, entry.IsLocal)
+	}
+}
@@ -2,11 +2,17 @@ package remover
 
 import (
 	"fmt"
+	"net"
 	"runtime"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/pkg/uconnproxy"
 	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo"
 	"github.com/globalsign/mgo/bson"
 
 	log "github.com/sirupsen/logrus"
@@ -29,13 +35,17 @@ func NewMongoRemover(db *database.DB, conf *config.Config, logger *log.Logger) R
 
 // Upsert loops through every new uconn ....
 func (r *remover) Remove(cid int) error {
+	err := r.database.Writable()
+	if err != nil {
+		return err
+	}
 
 	fmt.Println("\t[-] Removing matching chunk: ", cid)
 
 	// first we need to use the entries being removed from hostnames to reduce the
 	// subdomain count in exploded dns. This is done so we don't have to keep Unique
 	// long lists of subdomains, and is the only special-case deletion
-	err := r.reduceDNSSubCount(cid)
+	err = r.reduceDNSSubCount(cid)
 	if err != nil {
 		return fmt.Errorf("\t[!] Failed to remove update exploded dns collection for removal: %v", err)
 	}
@@ -47,6 +57,90 @@ func (r *remover) Remove(cid int) error {
 	return nil
 }
 
+// ChunkConnections returns the unique connections with data in the given chunk
+// along with their local hosts. Once the chunk is removed, the beacons between
+// these hosts no longer match their connections and need to be rescored.
+func (r *remover) ChunkConnections(cid int) (map[string]*uconn.Input, map[string]*host.Input, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	uconnMap := make(map[string]*uconn.Input)
+	hostMap := make(map[string]*host.Input)
+	internal := util.ParseSubnets(r.config.S.Filtering.InternalSubnets)
+
+	var stored uconn.StoredConnection
+	iter := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.UniqueConnTable).
+		Find(bson.M{"dat.cid": cid}).Select(uconn.StoredConnectionFields).Iter()
+
+	for iter.Next(&stored) {
+		addChunkConnection(uconnMap, hostMap, stored.UniqueIPPair, internal)
+		stored = uconn.StoredConnection{}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, nil, err
+	}
+	return uconnMap, hostMap, nil
+}
+
+// ChunkProxyConnections returns the unique proxy connections with data in the given
+// chunk along with their local hosts. Like the beacons, the proxy beacons between
+// these hosts and FQDNs need to be rescored once the chunk is removed.
+func (r *remover) ChunkProxyConnections(cid int) (map[string]*uconnproxy.Input, map[string]*host.Input, error) {
+	ssn := r.database.Session.Copy()
+	defer ssn.Close()
+
+	uconnProxyMap := make(map[string]*uconnproxy.Input)
+	hostMap := make(map[string]*host.Input)
+	internal := util.ParseSubnets(r.config.S.Filtering.InternalSubnets)
+
+	var stored uconnproxy.StoredConnection
+	iter := ssn.DB(r.database.GetSelectedDB()).C(r.config.T.Structure.UniqueConnProxyTable).
+		Find(bson.M{"dat.cid": cid}).Select(uconnproxy.StoredConnectionFields).Iter()
+
+	for iter.Next(&stored) {
+		input := stored.Input()
+		uconnProxyMap[input.Hosts.MapKey()] = input
+
+		src := input.Hosts.UniqueSrcIP.Unpair()
+		if util.ContainsIP(internal, net.ParseIP(src.IP)) {
+			hostMap[src.MapKey()] = &host.Input{Host: src, IsLocal: true}
+		}
+		stored = uconnproxy.StoredConnection{}
+	}
+	if err := iter.Close(); err != nil {
+		return nil, nil, err
+	}
+	return uconnProxyMap, hostMap, nil
+}
+
+// TSRange returns the time range of the unique connections which remain in the
+// database. A database without any connections has an empty range.
+func (r *remover) TSRange() (int64, int64, error) {
+	min, max, err := uconn.TimestampRange(r.database, r.config)
+	if err == mgo.ErrNotFound {
+		return 0, 0, nil
+	}
+	return min, max, err
+}
+
+// addChunkConnection adds the unique connection between a pair of hosts to
+// uconnMap and adds the hosts which are in the internal subnets to hostMap
+func addChunkConnection(uconnMap map[string]*uconn.Input, hostMap map[string]*host.Input,
+	pair data.UniqueIPPair, internal []*net.IPNet) {
+
+	input := uconn.StoredConnection{UniqueIPPair: pair}.Input(internal)
+	uconnMap[pair.MapKey()] = input
+
+	if input.IsLocalSrc {
+		src := pair.UniqueSrcIP.Unpair()
+		hostMap[src.MapKey()] = &host.Input{Host: src, IsLocal: true}
+	}
+	if input.IsLocalDst {
+		dst := pair.UniqueDstIP.Unpair()
+		hostMap[dst.MapKey()] = &host.Input{Host: dst, IsLocal: true}
+	}
+}
+
 func (r *remover) reduceDNSSubCount(cid int) error {
 	ssn := r.database.Session.Copy()
 	defer ssn.Close()
@@ -1,8 +1,17 @@
 package remover
 
+import (
+	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/pkg/uconnproxy"
+)
+
 // Repository ....
 type Repository interface {
 	Remove(int) error
+	ChunkConnections(int) (map[string]*uconn.Input, map[string]*host.Input, error)
+	ChunkProxyConnections(int) (map[string]*uconnproxy.Input, map[string]*host.Input, error)
+	TSRange() (int64, int64, error)
 }
 
 //update ....
@@ -100,6 +100,19 @@ func (w *writer) startCIDRemover() {
 				}).Error(err)
 			}
 
+			// beacons may also hold the score each chunk received
+			if data == w.conf.T.Beacon.BeaconTable {
+				info, err = ssn.DB(w.db.GetSelectedDB()).C(data).UpdateAll(bson.M{"score_history.cid": w.cid}, bson.M{"$pull": bson.M{"score_history": bson.M{"cid": w.cid}}})
+				if err != nil {
+					w.log.WithFields(log.Fields{
+						"Module":  "remover",
+						"Info":    info,
+						"Data":    data,
+						"Message": "failed to delete chunk score history",
+					}).Error(err)
+				}
+			}
+
 		}
 		w.writeWg.Done()
 	}()
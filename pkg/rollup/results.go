@@ -0,0 +1,79 @@
+package rollup
+
+import (
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/resources"
+	"github.com/activecm/rita/util"
+)
+
+// longConnThresh is the duration in seconds a connection must exceed to be
+// counted as a long connection, matching show-long-connections
+const longConnThresh = 60
+
+// Results rolls up the findings of each enabled module in the selected
+// database per internal host. Only beacons scoring above minScore are counted.
+func Results(res *resources.Resources, minScore float64) ([]HostFindings, error) {
+	builder := NewBuilder(util.ParseSubnets(res.Config.S.Filtering.InternalSubnets))
+
+	if res.Config.S.Beacon.Enabled {
+		beacons, err := beacon.Results(res, minScore)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBeacons(beacons)
+	}
+
+	if res.Config.S.BeaconProxy.Enabled {
+		beacons, err := beaconproxy.Results(res, minScore)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBeaconsProxy(beacons)
+	}
+
+	if res.Config.S.BeaconSNI.Enabled {
+		beacons, err := beaconsni.Results(res, minScore)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBeaconsSNI(beacons)
+	}
+
+	strobes, err := beacon.StrobeResults(res, -1, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddStrobes(strobes)
+
+	longConns, err := uconn.LongConnResults(res, longConnThresh, 0, true)
+	if err != nil {
+		return nil, err
+	}
+	builder.AddLongConnections(longConns)
+
+	if res.Config.S.Blacklisted.Enabled {
+		srcIPs, err := blacklist.SrcIPResults(res, "conn_count", 0, true)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBlacklistedIPs(srcIPs)
+
+		dstIPs, err := blacklist.DstIPResults(res, "conn_count", 0, true)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBlacklistedIPs(dstIPs)
+
+		hostnames, err := blacklist.HostnameResults(res, "conn_count", 0, true)
+		if err != nil {
+			return nil, err
+		}
+		builder.AddBlacklistedHostnames(hostnames)
+	}
+
+	return builder.Results(), nil
+}
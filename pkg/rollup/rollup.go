@@ -0,0 +1,178 @@
+package rollup
+
+import (
+	"net"
+	"sort"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+)
+
+type (
+	// HostFindings counts the findings of each analysis module which involve
+	// an internal host
+	HostFindings struct {
+		Host            data.UniqueIP `bson:"host"`
+		Beacons         int           `bson:"beacons"`
+		BeaconsProxy    int           `bson:"beacons_proxy"`
+		BeaconsSNI      int           `bson:"beacons_sni"`
+		Strobes         int           `bson:"strobes"`
+		LongConnections int           `bson:"long_connections"`
+		Blacklisted     int           `bson:"blacklisted"` // blacklisted IPs and hostnames the host talked to
+		MaxScore        float64       `bson:"max_score"`   // highest score of the host's beacons
+	}
+
+	// Builder assembles the findings of each module into a HostFindings
+	// per internal host
+	Builder struct {
+		internal []*net.IPNet
+		hosts    map[string]*HostFindings
+	}
+)
+
+// Total returns the number of findings involving the host
+func (h HostFindings) Total() int {
+	return h.Beacons + h.BeaconsProxy + h.BeaconsSNI + h.Strobes + h.LongConnections + h.Blacklisted
+}
+
+// NewBuilder creates a Builder which attributes findings to the hosts in the
+// internal subnets
+func NewBuilder(internal []*net.IPNet) *Builder {
+	return &Builder{
+		internal: internal,
+		hosts:    make(map[string]*HostFindings),
+	}
+}
+
+// host returns the findings of the first internal host of the candidates
+// or nil if none of them are internal
+func (b *Builder) host(candidates ...data.UniqueIP) *HostFindings {
+	for _, candidate := range candidates {
+		ip := net.ParseIP(candidate.IP)
+		if ip == nil || !util.ContainsIP(b.internal, ip) {
+			continue
+		}
+
+		key := candidate.MapKey()
+		findings, ok := b.hosts[key]
+		if !ok {
+			findings = &HostFindings{Host: candidate}
+			b.hosts[key] = findings
+		}
+		return findings
+	}
+	return nil
+}
+
+// pairHost returns the findings of the internal host of the pair, preferring
+// the source
+func (b *Builder) pairHost(pair data.UniqueIPPair) *HostFindings {
+	return b.host(pair.UniqueSrcIP.Unpair(), pair.UniqueDstIP.Unpair())
+}
+
+// AddBeacons counts the beacons of each internal host
+func (b *Builder) AddBeacons(results []beacon.Result) {
+	for _, result := range results {
+		if findings := b.pairHost(result.UniqueIPPair); findings != nil {
+			findings.Beacons++
+			findings.MaxScore = maxScore(findings.MaxScore, result.Score)
+		}
+	}
+}
+
+// AddBeaconsProxy counts the proxy beacons of each internal host
+func (b *Builder) AddBeaconsProxy(results []beaconproxy.Result) {
+	for _, result := range results {
+		src := data.UniqueIP{IP: result.SrcIP, NetworkUUID: result.SrcNetworkUUID, NetworkName: result.SrcNetworkName}
+		if findings := b.host(src); findings != nil {
+			findings.BeaconsProxy++
+			findings.MaxScore = maxScore(findings.MaxScore, result.Score)
+		}
+	}
+}
+
+// AddBeaconsSNI counts the SNI beacons of each internal host
+func (b *Builder) AddBeaconsSNI(results []beaconsni.Result) {
+	for _, result := range results {
+		if findings := b.host(result.UniqueSrcIP.Unpair()); findings != nil {
+			findings.BeaconsSNI++
+			findings.MaxScore = maxScore(findings.MaxScore, result.Score)
+		}
+	}
+}
+
+// AddStrobes counts the strobes of each internal host
+func (b *Builder) AddStrobes(results []beacon.StrobeResult) {
+	for _, result := range results {
+		if findings := b.pairHost(result.UniqueIPPair); findings != nil {
+			findings.Strobes++
+		}
+	}
+}
+
+// AddLongConnections counts the long connections of each internal host
+func (b *Builder) AddLongConnections(results []uconn.LongConnResult) {
+	for _, result := range results {
+		if findings := b.pairHost(result.UniqueIPPair); findings != nil {
+			findings.LongConnections++
+		}
+	}
+}
+
+// AddBlacklistedIPs counts the blacklisted IPs each internal host talked to
+func (b *Builder) AddBlacklistedIPs(results []blacklist.IPResult) {
+	for _, result := range results {
+		for _, peer := range result.Peers {
+			if findings := b.host(peer); findings != nil {
+				findings.Blacklisted++
+			}
+		}
+	}
+}
+
+// AddBlacklistedHostnames counts the blacklisted hostnames each internal
+// host connected to
+func (b *Builder) AddBlacklistedHostnames(results []blacklist.HostnameResult) {
+	for _, result := range results {
+		for _, src := range result.ConnectedHosts {
+			if findings := b.host(src); findings != nil {
+				findings.Blacklisted++
+			}
+		}
+	}
+}
+
+// Results returns the findings of each internal host, sorted by the highest
+// beacon score and then the number of findings, both descending
+func (b *Builder) Results() []HostFindings {
+	results := make([]HostFindings, 0, len(b.hosts))
+	for _, findings := range b.hosts {
+		results = append(results, *findings)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].MaxScore != results[j].MaxScore {
+			return results[i].MaxScore > results[j].MaxScore
+		}
+		if results[i].Total() != results[j].Total() {
+			return results[i].Total() > results[j].Total()
+		}
+		if results[i].Host.IP != results[j].Host.IP {
+			return results[i].Host.IP < results[j].Host.IP
+		}
+		return results[i].Host.NetworkName < results[j].Host.NetworkName
+	})
+	return results
+}
+
+func maxScore(a, b float64) float64 {
+	if b > a {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,87 @@
+package rollup
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/beacon"
+	"github.com/activecm/rita/pkg/beaconproxy"
+	"github.com/activecm/rita/pkg/beaconsni"
+	"github.com/activecm/rita/pkg/blacklist"
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/pkg/uconn"
+	"github.com/activecm/rita/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testIP(ip string) data.UniqueIP {
+	return data.UniqueIP{IP: ip, NetworkUUID: util.UnknownPrivateNetworkUUID, NetworkName: util.UnknownPrivateNetworkName}
+}
+
+func testPair(src, dst string) data.UniqueIPPair {
+	return data.NewUniqueIPPair(testIP(src), testIP(dst))
+}
+
+func TestBuilder(t *testing.T) {
+	builder := NewBuilder(util.ParseSubnets([]string{"10.0.0.0/8"}))
+
+	builder.AddBeacons([]beacon.Result{
+		{UniqueIPPair: testPair("10.0.0.1", "203.0.113.1"), Score: 0.9},
+		{UniqueIPPair: testPair("10.0.0.1", "203.0.113.2"), Score: 0.7},
+		{UniqueIPPair: testPair("10.0.0.1", "203.0.113.3"), Score: 0.5},
+		{UniqueIPPair: testPair("10.0.0.2", "203.0.113.1"), Score: 0.6},
+		// an external host beaconing in is attributed to the internal destination
+		{UniqueIPPair: testPair("198.51.100.1", "10.0.0.3"), Score: 0.4},
+	})
+	builder.AddBeaconsProxy([]beaconproxy.Result{
+		{SrcIP: "10.0.0.2", SrcNetworkUUID: util.UnknownPrivateNetworkUUID, SrcNetworkName: util.UnknownPrivateNetworkName, FQDN: "c2.example.com", Score: 0.95},
+	})
+	builder.AddBeaconsSNI([]beaconsni.Result{
+		{UniqueSrcFQDNPair: data.NewUniqueSrcFQDNPair(testIP("10.0.0.3"), "cdn.example.com"), Score: 0.3},
+	})
+	builder.AddStrobes([]beacon.StrobeResult{
+		{UniqueIPPair: testPair("10.0.0.3", "203.0.113.9"), ConnectionCount: 90000},
+	})
+	builder.AddLongConnections([]uconn.LongConnResult{
+		{UniqueIPPair: testPair("10.0.0.4", "203.0.113.5"), MaxDuration: 7200},
+	})
+	builder.AddBlacklistedIPs([]blacklist.IPResult{
+		{Host: testIP("203.0.113.66"), Peers: []data.UniqueIP{testIP("10.0.0.1"), testIP("10.0.0.4")}},
+	})
+	builder.AddBlacklistedHostnames([]blacklist.HostnameResult{
+		{Host: "bad.example.com", ConnectedHosts: []data.UniqueIP{testIP("10.0.0.4")}},
+		// external hosts are not rolled up
+		{Host: "worse.example.com", ConnectedHosts: []data.UniqueIP{testIP("198.51.100.7")}},
+	})
+
+	results := builder.Results()
+	require.Len(t, results, 4)
+
+	// ordered by the highest beacon score, then the number of findings
+	assert.Equal(t, HostFindings{Host: testIP("10.0.0.2"), Beacons: 1, BeaconsProxy: 1, MaxScore: 0.95}, results[0])
+	assert.Equal(t, HostFindings{Host: testIP("10.0.0.1"), Beacons: 3, Blacklisted: 1, MaxScore: 0.9}, results[1])
+	assert.Equal(t, HostFindings{Host: testIP("10.0.0.3"), Beacons: 1, BeaconsSNI: 1, Strobes: 1, MaxScore: 0.4}, results[2])
+	assert.Equal(t, HostFindings{Host: testIP("10.0.0.4"), LongConnections: 1, Blacklisted: 2}, results[3])
+	assert.Equal(t, 4, results[1].Total())
+}
+
+func TestBuilderNetworks(t *testing.T) {
+	builder := NewBuilder(util.ParseSubnets([]string{"10.0.0.0/8"}))
+
+	// the same address in different networks are different hosts
+	other := data.UniqueIP{IP: "10.0.0.1", NetworkUUID: util.PublicNetworkUUID, NetworkName: "branch"}
+	builder.AddBeacons([]beacon.Result{
+		{UniqueIPPair: testPair("10.0.0.1", "203.0.113.1"), Score: 0.5},
+		{UniqueIPPair: data.NewUniqueIPPair(other, testIP("203.0.113.1")), Score: 0.5},
+	})
+
+	results := builder.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, util.UnknownPrivateNetworkName, results[0].Host.NetworkName)
+	assert.Equal(t, "branch", results[1].Host.NetworkName)
+
+	// nothing is rolled up without internal subnets
+	builder = NewBuilder(nil)
+	builder.AddBeacons([]beacon.Result{{UniqueIPPair: testPair("10.0.0.1", "203.0.113.1"), Score: 0.5}})
+	assert.Empty(t, builder.Results())
+}
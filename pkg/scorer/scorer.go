@@ -0,0 +1,243 @@
+package scorer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activecm/rita/config"
+)
+
+// maxResponseBytes limits the length of a single response line
+const maxResponseBytes = 1 << 20
+
+// ErrClosed is returned once the external scorer has stopped. The scorer is
+// not restarted, so the remaining beacons receive the built-in scores only.
+var ErrClosed = errors.New("external scorer has stopped")
+
+type (
+	// Request describes a beacon sent to the external scorer. The built-in
+	// scores are included so the scorer may build on them.
+	Request struct {
+		Src            string             `json:"src"`
+		SrcNetworkName string             `json:"src_network_name"`
+		Dst            string             `json:"dst"`
+		DstNetworkName string             `json:"dst_network_name"`
+		Timestamps     []int64            `json:"timestamps"`
+		Intervals      []int64            `json:"intervals"`
+		OrigBytes      []int64            `json:"orig_bytes"`
+		RespBytes      []int64            `json:"resp_bytes"`
+		Scores         map[string]float64 `json:"scores"`
+	}
+
+	// Response is the result of scoring a beacon. The fields are stored with
+	// the beacon alongside the score. A non-empty Error rejects the beacon.
+	Response struct {
+		Score  float64                `json:"score"`
+		Fields map[string]interface{} `json:"fields,omitempty"`
+		Error  string                 `json:"error,omitempty"`
+	}
+
+	// Scorer scores beacons outside of RITA
+	Scorer interface {
+		Score(req Request) (Response, error)
+		Close() error
+	}
+
+	// Process is a Scorer which exchanges newline delimited JSON with a
+	// long running command. Each Request is written to the command's stdin
+	// as a single line and the command must answer with a single Response
+	// line on its stdout. Requests are sent one at a time.
+	Process struct {
+		cmd       *exec.Cmd
+		stdin     io.WriteCloser
+		responses chan []byte
+		timeout   time.Duration
+		done      chan struct{}
+		waitErr   error
+
+		mu  sync.Mutex
+		err error // set once the scorer has failed
+	}
+)
+
+// Start runs the external scorer configured in the Beacon ExternalScorer
+// section. Returns a nil Scorer if the external scorer is disabled.
+func Start(conf *config.Config) (Scorer, error) {
+	scorerConf := conf.S.Beacon.ExternalScorer
+	if !scorerConf.Enabled {
+		return nil, nil
+	}
+	return StartProcess(scorerConf.Command, scorerConf.Timeout)
+}
+
+// StartProcess runs the command as an external scorer. Each beacon must be
+// scored within the timeout or the command is stopped.
+func StartProcess(command []string, timeout time.Duration) (*Process, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no external scorer command was given")
+	}
+
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Stderr = os.Stderr
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("could not start external scorer %s: %w", command[0], err)
+	}
+
+	p := &Process{
+		cmd:       cmd,
+		stdin:     stdin,
+		responses: make(chan []byte),
+		timeout:   timeout,
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxResponseBytes)
+		for scanner.Scan() {
+			line := make([]byte, len(scanner.Bytes()))
+			copy(line, scanner.Bytes())
+			p.responses <- line
+		}
+		close(p.responses)
+
+		// the output must be read before waiting on the command
+		p.waitErr = cmd.Wait()
+		close(p.done)
+	}()
+
+	return p, nil
+}
+
+// Score sends the beacon to the external scorer and waits for its response.
+// If the scorer fails to read the beacon and answer in time, exits, or
+// answers with malformed JSON, it is stopped and every later call returns
+// ErrClosed.
+func (p *Process) Score(req Request) (Response, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err != nil {
+		return Response{}, ErrClosed
+	}
+
+	var body bytes.Buffer
+	if err := json.NewEncoder(&body).Encode(req); err != nil {
+		return Response{}, fmt.Errorf("could not encode beacon for external scorer: %w", err)
+	}
+
+	// the timeout covers sending the beacon as well, since a scorer which
+	// stops reading its stdin would otherwise block the write forever
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	written := make(chan error, 1)
+	go func() {
+		_, err := p.stdin.Write(body.Bytes())
+		written <- err
+	}()
+
+	select {
+	case err := <-written:
+		if err != nil {
+			return Response{}, p.fail(fmt.Errorf("could not send beacon to external scorer: %w", err))
+		}
+	case <-timer.C:
+		return Response{}, p.fail(fmt.Errorf("external scorer did not read the beacon within %v", p.timeout))
+	}
+
+	var line []byte
+	select {
+	case received, ok := <-p.responses:
+		if !ok {
+			return Response{}, p.fail(errors.New("external scorer exited before responding"))
+		}
+		line = received
+	case <-timer.C:
+		return Response{}, p.fail(fmt.Errorf("external scorer did not respond within %v", p.timeout))
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		return Response{}, p.fail(fmt.Errorf("could not parse external scorer response: %w", err))
+	}
+	if resp.Error != "" {
+		return Response{}, fmt.Errorf("external scorer rejected beacon: %s", resp.Error)
+	}
+
+	// the fields are stored as external.<field>, so a name holding a path or
+	// an operator would change the wrong part of the beacon
+	for field := range resp.Fields {
+		if !validField(field) {
+			return Response{}, fmt.Errorf("external scorer returned invalid field %q: fields must not be empty or contain '.' or '$'", field)
+		}
+	}
+	return resp, nil
+}
+
+// validField reports whether a field returned by the scorer can be stored
+// with the beacon
+func validField(field string) bool {
+	return field != "" && !strings.ContainsAny(field, ".$")
+}
+
+// fail stops the scorer after an error. Must be called with the lock held.
+func (p *Process) fail(err error) error {
+	p.err = err
+	p.stdin.Close()
+	p.cmd.Process.Kill()
+	return err
+}
+
+// Close signals the end of the beacons by closing the scorer's stdin and
+// waits for it to exit. The scorer is killed if it does not exit within the
+// timeout.
+func (p *Process) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.err == nil {
+		p.err = ErrClosed
+		p.stdin.Close()
+	}
+
+	// drain any unexpected output so the reader is not left blocking
+	go func() {
+		for range p.responses {
+		}
+	}()
+
+	timer := time.NewTimer(p.timeout)
+	defer timer.Stop()
+
+	select {
+	case <-p.done:
+	case <-timer.C:
+		p.cmd.Process.Kill()
+		<-p.done
+		return fmt.Errorf("external scorer did not exit within %v", p.timeout)
+	}
+
+	// failed scorers were killed on purpose
+	if p.err != ErrClosed {
+		return nil
+	}
+	return p.waitErr
+}
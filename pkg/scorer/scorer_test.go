@@ -0,0 +1,187 @@
+package scorer
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/activecm/rita/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// helperScorerEnv is set when the test binary is run as a mock external scorer
+const helperScorerEnv = "RITA_TEST_HELPER_SCORER"
+
+// helperScorerCommand runs this test binary as a mock external scorer in the
+// given mode
+func helperScorerCommand(t *testing.T, mode string) []string {
+	t.Setenv(helperScorerEnv, "1")
+	return []string{os.Args[0], "-test.run=TestHelperScorer", "--", mode}
+}
+
+// TestHelperScorer is not a real test. It is run by the other tests as a mock
+// external scorer which scores beacons by how many of their intervals match
+// the first interval.
+func TestHelperScorer(t *testing.T) {
+	if os.Getenv(helperScorerEnv) != "1" {
+		return
+	}
+	mode := os.Args[len(os.Args)-1]
+	if mode == "noread" {
+		time.Sleep(time.Minute)
+	}
+
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var req Request
+		if err := json.Unmarshal(scanner.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+
+		switch mode {
+		case "exit":
+			os.Exit(1)
+		case "hang":
+			time.Sleep(time.Minute)
+		case "garbage":
+			fmt.Println("not json")
+			continue
+		case "reject":
+			encoder.Encode(Response{Error: "no intervals"})
+			continue
+		case "badfield":
+			encoder.Encode(Response{Score: 1, Fields: map[string]interface{}{"$inc": 1}})
+			continue
+		}
+
+		matching := 0
+		for _, interval := range req.Intervals {
+			if interval == req.Intervals[0] {
+				matching++
+			}
+		}
+		encoder.Encode(Response{
+			Score: float64(matching) / float64(len(req.Intervals)),
+			Fields: map[string]interface{}{
+				"matching_intervals": matching,
+				"ts_score":           req.Scores["ts"],
+			},
+		})
+	}
+	os.Exit(0)
+}
+
+func TestProcessScore(t *testing.T) {
+	p, err := StartProcess(helperScorerCommand(t, "score"), 10*time.Second)
+	require.Nil(t, err)
+
+	resp, err := p.Score(Request{
+		Src:       "10.0.0.1",
+		Dst:       "203.0.113.7",
+		Intervals: []int64{60, 60, 60, 90},
+		Scores:    map[string]float64{"ts": 0.8},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, 0.75, resp.Score)
+	assert.Equal(t, map[string]interface{}{"matching_intervals": 3.0, "ts_score": 0.8}, resp.Fields)
+
+	// the same process scores every beacon
+	resp, err = p.Score(Request{Intervals: []int64{30, 30}})
+	require.Nil(t, err)
+	assert.Equal(t, 1.0, resp.Score)
+
+	assert.Nil(t, p.Close())
+
+	_, err = p.Score(Request{Intervals: []int64{30, 30}})
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestProcessScoreErrors(t *testing.T) {
+	cases := []struct {
+		mode    string
+		stopped bool // whether the scorer is stopped after the error
+	}{
+		{"reject", false},
+		{"badfield", false},
+		{"exit", true},
+		{"hang", true},
+		{"garbage", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.mode, func(t *testing.T) {
+			p, err := StartProcess(helperScorerCommand(t, c.mode), time.Second)
+			require.Nil(t, err)
+			defer p.Close()
+
+			_, err = p.Score(Request{Intervals: []int64{60, 60}})
+			require.NotNil(t, err)
+			assert.NotEqual(t, ErrClosed, err)
+
+			_, err = p.Score(Request{Intervals: []int64{60, 60}})
+			if c.stopped {
+				assert.Equal(t, ErrClosed, err, "a failed scorer should not be used again")
+			} else {
+				assert.NotEqual(t, ErrClosed, err, "a rejected beacon should not stop the scorer")
+			}
+		})
+	}
+}
+
+func TestStart(t *testing.T) {
+	conf, err := config.LoadTestingConfig("")
+	require.Nil(t, err)
+
+	// the built-in scoring is used by default
+	s, err := Start(conf)
+	assert.Nil(t, err)
+	assert.Nil(t, s)
+
+	conf.S.Beacon.ExternalScorer.Enabled = true
+	conf.S.Beacon.ExternalScorer.Command = []string{"/nonexistent/rita-scorer"}
+	_, err = Start(conf)
+	assert.NotNil(t, err)
+
+	_, err = StartProcess(nil, time.Second)
+	assert.NotNil(t, err)
+	assert.False(t, errors.Is(err, ErrClosed))
+}
+
+func TestProcessScoreWriteTimeout(t *testing.T) {
+	p, err := StartProcess(helperScorerCommand(t, "noread"), time.Second)
+	require.Nil(t, err)
+	defer p.Close()
+
+	// the beacon is larger than the pipe buffer, so the write blocks until
+	// the scorer is stopped
+	intervals := make([]int64, 1<<17)
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Score(Request{Intervals: intervals})
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		require.NotNil(t, err)
+		assert.NotEqual(t, ErrClosed, err)
+	case <-time.After(10 * time.Second):
+		t.Fatal("a scorer which stops reading should not block Score")
+	}
+
+	_, err = p.Score(Request{Intervals: []int64{60, 60}})
+	assert.Equal(t, ErrClosed, err)
+}
+
+func TestValidField(t *testing.T) {
+	assert.True(t, validField("matching_intervals"))
+	assert.False(t, validField(""))
+	assert.False(t, validField("a.b"))
+	assert.False(t, validField("$set"))
+}
@@ -0,0 +1,305 @@
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo/bson"
+)
+
+type (
+	// Document is the representation of a single change to the analysis
+	// results which is delivered to the sinks. Result holds the document
+	// left in MongoDB by the change. Changes which were not applied to
+	// MongoDB, such as those of a dry run, hold their Update instead.
+	Document struct {
+		Collection string      `json:"collection"`
+		Database   string      `json:"database"`
+		Selector   interface{} `json:"selector"`
+		Result     interface{} `json:"result,omitempty"`
+		Update     interface{} `json:"update,omitempty"`
+		Upsert     bool        `json:"upsert,omitempty"`
+		Remove     bool        `json:"remove,omitempty"`
+	}
+
+	// FileSink appends the analysis results to a file as JSON lines. The
+	// file is opened again by the next write after the sink is closed.
+	FileSink struct {
+		path       string
+		selectedDB func() string
+		lock       sync.Mutex
+		file       *os.File // nil while the sink is closed
+	}
+
+	// ElasticSink indexes the analysis results with the Elasticsearch bulk API
+	ElasticSink struct {
+		url        string
+		index      string
+		selectedDB func() string
+		client     *http.Client
+	}
+
+	// KafkaSink produces the analysis results to a topic via the Kafka REST proxy
+	KafkaSink struct {
+		url        string
+		topic      string
+		selectedDB func() string
+		client     *http.Client
+	}
+)
+
+// New creates the sink described by an entry of the Sinks section of the
+// config file. selectedDB reports the name of the dataset being analyzed,
+// which is recorded with each result.
+func New(sinkConf config.SinkStaticCfg, selectedDB func() string) (database.Sink, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+
+	switch sinkConf.Type {
+	case "file":
+		file, err := openFile(sinkConf.Path)
+		if err != nil {
+			return nil, err
+		}
+		return &FileSink{path: sinkConf.Path, selectedDB: selectedDB, file: file}, nil
+	case "elasticsearch":
+		return &ElasticSink{
+			url: strings.TrimSuffix(sinkConf.URL, "/"), index: sinkConf.Index, selectedDB: selectedDB, client: client,
+		}, nil
+	case "kafka":
+		return &KafkaSink{
+			url: strings.TrimSuffix(sinkConf.URL, "/"), topic: sinkConf.Topic, selectedDB: selectedDB, client: client,
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q", sinkConf.Type)
+}
+
+// documents converts a group of changes to the documents sent to the sinks.
+// The writers hand the sinks the documents left in MongoDB as upserts which
+// replace the selected document, so an update without any update operators
+// is the result of the change.
+func documents(data database.BulkChanges, db string) []Document {
+	var docs []Document
+	for collection, changes := range data {
+		for _, change := range changes {
+			doc := Document{
+				Collection: collection,
+				Database:   db,
+				Selector:   change.Selector,
+				Remove:     change.Remove,
+			}
+			if isResult(change.Update) {
+				doc.Result = change.Update
+			} else {
+				doc.Update = change.Update
+				doc.Upsert = change.Upsert
+			}
+			docs = append(docs, doc)
+		}
+	}
+	return docs
+}
+
+// isResult reports whether an update is a whole document rather than a set
+// of update operators
+func isResult(update interface{}) bool {
+	var fields map[string]interface{}
+	switch doc := update.(type) {
+	case bson.M:
+		fields = doc
+	case map[string]interface{}:
+		fields = doc
+	default:
+		return false
+	}
+	for field := range fields {
+		if strings.HasPrefix(field, "$") {
+			return false
+		}
+	}
+	return true
+}
+
+// documentID derives a stable ID for the results of a selector, so that
+// each result replaces the previous version of itself
+func documentID(doc Document) (string, error) {
+	selector, err := json.Marshal(doc.Selector)
+	if err != nil {
+		return "", err
+	}
+	return util.NewFixedStringHash(doc.Database, doc.Collection, string(selector)).String(), nil
+}
+
+// openFile opens a file for appending, creating it if needed
+func openFile(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+}
+
+// Name identifies the sink in error reporting
+func (s *FileSink) Name() string {
+	return "file " + s.path
+}
+
+// Write appends each change as a line of JSON
+func (s *FileSink) Write(data database.BulkChanges) error {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	for _, doc := range documents(data, s.selectedDB()) {
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		file, err := openFile(s.path)
+		if err != nil {
+			return err
+		}
+		s.file = file
+	}
+	_, err := s.file.Write(buffer.Bytes())
+	return err
+}
+
+// Close closes the file until the next write
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Name identifies the sink in error reporting
+func (s *ElasticSink) Name() string {
+	return "elasticsearch " + s.url
+}
+
+// Write indexes the result of each change under an ID derived from its
+// selector, so the results which are updated again replace their earlier
+// versions. Removed results are deleted. Changes which don't hold a result
+// can't be indexed and are skipped.
+func (s *ElasticSink) Write(data database.BulkChanges) error {
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	count := 0
+	for _, doc := range documents(data, s.selectedDB()) {
+		if !doc.Remove && doc.Result == nil {
+			continue
+		}
+		id, err := documentID(doc)
+		if err != nil {
+			return err
+		}
+
+		meta := map[string]string{"_index": s.index, "_id": id}
+		if doc.Remove {
+			if err := encoder.Encode(map[string]interface{}{"delete": meta}); err != nil {
+				return err
+			}
+		} else {
+			if err := encoder.Encode(map[string]interface{}{"update": meta}); err != nil {
+				return err
+			}
+			if err := encoder.Encode(map[string]interface{}{"doc": doc, "doc_as_upsert": true}); err != nil {
+				return err
+			}
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+
+	resp, err := s.client.Post(s.url+"/_bulk", "application/x-ndjson", &body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+
+	// the bulk API reports failed documents in the body of a successful response
+	var result struct {
+		Errors bool `json:"errors"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("could not read the bulk response: %w", err)
+	}
+	if result.Errors {
+		return fmt.Errorf("some of the %d documents could not be indexed", count)
+	}
+	return nil
+}
+
+// Close releases the idle connections to Elasticsearch
+func (s *ElasticSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// Name identifies the sink in error reporting
+func (s *KafkaSink) Name() string {
+	return "kafka " + s.url
+}
+
+// Write produces each change as a record of the topic
+func (s *KafkaSink) Write(data database.BulkChanges) error {
+	docs := documents(data, s.selectedDB())
+	if len(docs) == 0 {
+		return nil
+	}
+
+	type record struct {
+		Value Document `json:"value"`
+	}
+	records := struct {
+		Records []record `json:"records"`
+	}{}
+	for _, doc := range docs {
+		records.Records = append(records.Records, record{Value: doc})
+	}
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.client.Post(s.url+"/topics/"+s.topic, "application/vnd.kafka.json.v2+json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// Close releases the idle connections to the Kafka REST proxy
+func (s *KafkaSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// checkResponse returns an error holding the start of the body if the request failed
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < http.StatusBadRequest {
+		return nil
+	}
+	message, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(message)))
+}
@@ -0,0 +1,194 @@
+package sink
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	"github.com/activecm/rita/database"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testChanges holds the document left in MongoDB by a change, as the writers
+// hand it to the sinks
+func testChanges() database.BulkChanges {
+	return database.BulkChanges{
+		"beacon": []database.BulkChange{{
+			Selector: map[string]string{"src": "10.0.0.1", "dst": "203.0.113.7"},
+			Update:   bson.M{"src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9},
+			Upsert:   true,
+		}},
+	}
+}
+
+// testDryRunChanges holds a change which was not applied to MongoDB
+func testDryRunChanges() database.BulkChanges {
+	return database.BulkChanges{
+		"beacon": []database.BulkChange{{
+			Selector: map[string]string{"src": "10.0.0.1", "dst": "203.0.113.7"},
+			Update:   bson.M{"$set": bson.M{"score": 0.9}},
+			Upsert:   true,
+		}},
+	}
+}
+
+func selectedDB() string {
+	return "dataset"
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.jsonl")
+	fileSink, err := New(config.SinkStaticCfg{Type: "file", Path: path}, selectedDB)
+	require.Nil(t, err)
+
+	require.Nil(t, fileSink.Write(testChanges()))
+	require.Nil(t, fileSink.Write(testChanges()))
+
+	// the sink is closed by each writer, so it opens the file again
+	require.Nil(t, fileSink.Close())
+	require.Nil(t, fileSink.Close())
+	require.Nil(t, fileSink.Write(testDryRunChanges()))
+	require.Nil(t, fileSink.Close())
+
+	file, err := os.Open(path)
+	require.Nil(t, err)
+	defer file.Close()
+
+	var docs []Document
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var doc Document
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &doc))
+		docs = append(docs, doc)
+	}
+	require.Len(t, docs, 3, "each change should be appended as a line")
+	assert.Equal(t, "beacon", docs[0].Collection)
+	assert.Equal(t, "dataset", docs[0].Database)
+	assert.Equal(t, map[string]interface{}{"src": "10.0.0.1", "dst": "203.0.113.7"}, docs[0].Selector)
+	assert.Equal(t, map[string]interface{}{"src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9}, docs[0].Result)
+	assert.Nil(t, docs[0].Update)
+	assert.False(t, docs[0].Upsert)
+
+	// the changes of a dry run keep their update
+	assert.Nil(t, docs[2].Result)
+	assert.Equal(t, map[string]interface{}{"$set": map[string]interface{}{"score": 0.9}}, docs[2].Update)
+	assert.True(t, docs[2].Upsert)
+}
+
+func TestElasticSink(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path+"\n"+string(body))
+		if strings.Contains(string(body), "rejected") {
+			w.Write([]byte(`{"errors": true}`))
+			return
+		}
+		w.Write([]byte(`{"errors": false}`))
+	}))
+	defer server.Close()
+
+	elasticSink, err := New(config.SinkStaticCfg{Type: "elasticsearch", URL: server.URL + "/", Index: "rita"}, selectedDB)
+	require.Nil(t, err)
+	require.Nil(t, elasticSink.Write(testChanges()))
+	require.Nil(t, elasticSink.Write(testChanges()))
+
+	require.Len(t, requests, 2)
+	lines := strings.Split(strings.TrimSpace(requests[0]), "\n")
+	require.Len(t, lines, 3)
+	assert.Equal(t, "/_bulk", lines[0])
+
+	// the result is upserted under an ID derived from its selector
+	var action struct {
+		Update struct {
+			Index string `json:"_index"`
+			ID    string `json:"_id"`
+		} `json:"update"`
+	}
+	require.Nil(t, json.Unmarshal([]byte(lines[1]), &action))
+	assert.Equal(t, "rita", action.Update.Index)
+	assert.NotEmpty(t, action.Update.ID)
+	assert.Equal(t, lines[1], strings.Split(requests[1], "\n")[1], "the result should replace its earlier version")
+
+	var update struct {
+		Doc         Document `json:"doc"`
+		DocAsUpsert bool     `json:"doc_as_upsert"`
+	}
+	require.Nil(t, json.Unmarshal([]byte(lines[2]), &update))
+	assert.True(t, update.DocAsUpsert)
+	assert.Equal(t, "beacon", update.Doc.Collection)
+	assert.Equal(t, map[string]interface{}{"src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9}, update.Doc.Result)
+
+	// a removed result is deleted by the same ID
+	removed := database.BulkChanges{"beacon": []database.BulkChange{{
+		Selector: map[string]string{"src": "10.0.0.1", "dst": "203.0.113.7"},
+		Remove:   true,
+	}}}
+	require.Nil(t, elasticSink.Write(removed))
+	require.Len(t, requests, 3)
+	lines = strings.Split(strings.TrimSpace(requests[2]), "\n")
+	require.Len(t, lines, 2)
+	assert.JSONEq(t, fmt.Sprintf(`{"delete": {"_index": "rita", "_id": %q}}`, action.Update.ID), lines[1])
+
+	// documents rejected by the bulk API are reported
+	rejected := database.BulkChanges{"beacon": []database.BulkChange{{
+		Selector: map[string]string{"src": "rejected"},
+		Update:   bson.M{"src": "rejected"},
+		Upsert:   true,
+	}}}
+	assert.NotNil(t, elasticSink.Write(rejected))
+
+	// nothing is sent without results
+	require.Nil(t, elasticSink.Write(database.BulkChanges{}))
+	require.Nil(t, elasticSink.Write(testDryRunChanges()))
+	assert.Len(t, requests, 4)
+	assert.Nil(t, elasticSink.Close())
+}
+
+func TestKafkaSink(t *testing.T) {
+	status := http.StatusOK
+	var contentType, path string
+	var records struct {
+		Records []struct {
+			Value Document `json:"value"`
+		} `json:"records"`
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType, path = r.Header.Get("Content-Type"), r.URL.Path
+		json.NewDecoder(r.Body).Decode(&records)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	kafkaSink, err := New(config.SinkStaticCfg{Type: "kafka", URL: server.URL, Topic: "rita"}, selectedDB)
+	require.Nil(t, err)
+	require.Nil(t, kafkaSink.Write(testChanges()))
+
+	assert.Equal(t, "/topics/rita", path)
+	assert.Equal(t, "application/vnd.kafka.json.v2+json", contentType)
+	require.Len(t, records.Records, 1)
+	assert.Equal(t, "beacon", records.Records[0].Value.Collection)
+	assert.Equal(t, map[string]interface{}{"src": "10.0.0.1", "dst": "203.0.113.7", "score": 0.9}, records.Records[0].Value.Result)
+
+	status = http.StatusServiceUnavailable
+	assert.NotNil(t, kafkaSink.Write(testChanges()))
+	assert.Nil(t, kafkaSink.Close())
+}
+
+func TestNewUnknownSink(t *testing.T) {
+	_, err := New(config.SinkStaticCfg{Type: "syslog"}, selectedDB)
+	assert.NotNil(t, err)
+
+	_, err = New(config.SinkStaticCfg{Type: "file", Path: filepath.Join(t.TempDir(), "missing", "results.jsonl")}, selectedDB)
+	assert.NotNil(t, err)
+}
@@ -75,7 +75,7 @@ func (r *repo) Upsert(tlsMap map[string]*TLSInput, httpMap map[string]*HTTPInput
 	linkedInputMap := linkInputMaps(tlsMap, httpMap, zeekUIDMap)
 
 	// Create the workers for analysis
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "sniconn")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "sniconn")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
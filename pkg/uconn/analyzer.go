@@ -1,10 +1,12 @@
 package uconn
 
 import (
+	"sort"
 	"sync"
 
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/util"
 	"github.com/globalsign/mgo/bson"
 )
 
@@ -51,10 +53,12 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
 
 		for datum := range a.analysisChannel {
 
-			mainUpdate := mainQuery(datum, a.connLimit, a.chunk)
+			mainUpdate := mainQuery(datum, a.connLimit, a.conf.S.Beacon.UIDSampleSize, a.chunk)
 			openConnsUpdate := openConnectionsQuery(datum)
 
 			totalUpdate := database.MergeBSONMaps(mainUpdate, openConnsUpdate)
@@ -72,7 +76,7 @@ func (a *analyzer) start() {
 }
 
 // mainQuery records the bulk of the information about the communications between two hosts
-func mainQuery(datum *Input, strobeLimit int64, chunk int) bson.M {
+func mainQuery(datum *Input, strobeLimit int64, uidLimit int, chunk int) bson.M {
 
 	// Truncate the protocol/ port tuples we store in the database
 	tuples := datum.Tuples.Items()
@@ -85,12 +89,66 @@ func mainQuery(datum *Input, strobeLimit int64, chunk int) bson.M {
 	// it will not qualify to be downgraded to a beacon until this chunk is
 	// outdated and removed. If only importing once - still just a strobe.
 	ts := datum.TsList
+	respTs := datum.RespTsList
 	bytes := datum.OrigBytesList
+	respBytes := datum.RespBytesList
+	durations := datum.DurationList
+	uids := util.SampleStrings(datum.UIDs, uidLimit)
+	logRefs := util.SampleStrings(datum.LogRefs, uidLimit)
 
-	isStrobe := datum.ConnectionCount >= strobeLimit
+	// only the connections kept for beacon analysis decide if it is a strobe
+	isStrobe := datum.BeaconCount >= strobeLimit
 	if isStrobe {
 		ts = []int64{}
+		respTs = []int64{}
 		bytes = []int64{}
+		respBytes = []int64{}
+		durations = []float64{}
+		uids = []string{}
+		logRefs = []string{}
+	}
+
+	chunkData := bson.M{
+		"count":  datum.ConnectionCount,
+		"bytes":  bytes,
+		"rbytes": respBytes,
+		"uids":   uids,
+		"ts":     ts,
+		"tuples": tuples,
+		"icerts": datum.InvalidCertFlag,
+		"maxdur": datum.MaxDuration,
+		"tbytes": datum.TotalBytes,
+		"tdur":   datum.TotalDuration,
+		"cid":    chunk,
+		// the connections and bytes kept for beacon analysis
+		"bcount":  datum.BeaconCount,
+		"btbytes": datum.BeaconBytes,
+		// the source's packet and byte counts reveal retransmissions
+		"oipbytes": datum.OrigIPBytes,
+		"obytes":   datum.OrigPayloadBytes,
+		"opkts":    datum.OrigPkts,
+		// the ports and protocol of the longest connection for pivoting to the logs
+		"maxdur_conn": datum.MaxDurationConn,
+	}
+
+	// the durations are only collected for ConnectionDurationScoring
+	if datum.DurationList != nil {
+		chunkData["durs"] = durations
+	}
+
+	// the response timestamps are only collected for ResponseTimestampScoring
+	if datum.RespTsList != nil {
+		chunkData["rts"] = respTs
+	}
+
+	// the provenance of the connections is only recorded if it is enabled
+	if len(datum.LogPaths) > 0 {
+		logPaths := datum.LogPaths.Items()
+		sort.Strings(logPaths)
+		chunkData["logs"] = logPaths
+	}
+	if len(datum.LogRefs) > 0 {
+		chunkData["log_refs"] = logRefs
 	}
 
 	return bson.M{
@@ -104,17 +162,7 @@ func mainQuery(datum *Input, strobeLimit int64, chunk int) bson.M {
 		},
 		"$push": bson.M{
 			"dat": bson.M{
-				"$each": []bson.M{{
-					"count":  datum.ConnectionCount,
-					"bytes":  bytes,
-					"ts":     ts,
-					"tuples": tuples,
-					"icerts": datum.InvalidCertFlag,
-					"maxdur": datum.MaxDuration,
-					"tbytes": datum.TotalBytes,
-					"tdur":   datum.TotalDuration,
-					"cid":    chunk,
-				}},
+				"$each": []bson.M{chunkData},
 			},
 		},
 	}
@@ -0,0 +1,140 @@
+package uconn
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMainQueryUIDSample(t *testing.T) {
+	datum := &Input{
+		ConnectionCount: 4,
+		BeaconCount:     4,
+		TsList:          []int64{1, 2, 3, 4},
+		RespTsList:      []int64{2, 3, 4, 5},
+		OrigBytesList:   []int64{10, 10, 10, 10},
+		RespBytesList:   []int64{20, 20, 20, 20},
+		DurationList:    []float64{1, 1, 1, 1},
+		UIDs:            []string{"C1", "C2", "C3", "C4"},
+		Tuples:          make(data.StringSet),
+	}
+
+	chunkData := func(query bson.M) bson.M {
+		return query["$push"].(bson.M)["dat"].(bson.M)["$each"].([]bson.M)[0]
+	}
+
+	// the uid sample for the chunk is capped to the most recent uids
+	query := mainQuery(datum, 100, 2, 0)
+	assert.Equal(t, []string{"C3", "C4"}, chunkData(query)["uids"])
+
+	query = mainQuery(datum, 100, 10, 0)
+	assert.Equal(t, []string{"C1", "C2", "C3", "C4"}, chunkData(query)["uids"])
+	assert.Equal(t, []int64{2, 3, 4, 5}, chunkData(query)["rts"])
+	assert.Equal(t, []float64{1, 1, 1, 1}, chunkData(query)["durs"])
+
+	// strobes do not store uids since they are not analyzed as beacons
+	query = mainQuery(datum, 4, 10, 0)
+	assert.Equal(t, []string{}, chunkData(query)["uids"])
+	assert.Equal(t, []int64{}, chunkData(query)["rts"])
+	assert.Equal(t, []float64{}, chunkData(query)["durs"])
+
+	// response timestamps are not stored unless they were collected
+	datum.RespTsList = nil
+	query = mainQuery(datum, 100, 10, 0)
+	assert.NotContains(t, chunkData(query), "rts")
+
+	// neither are durations
+	datum.DurationList = nil
+	query = mainQuery(datum, 100, 10, 0)
+	assert.NotContains(t, chunkData(query), "durs")
+}
+
+func TestMainQueryProvenance(t *testing.T) {
+	datum := &Input{
+		ConnectionCount: 3,
+		BeaconCount:     3,
+		TsList:          []int64{1, 2, 3},
+		UIDs:            []string{"C1", "C2", "C3"},
+		Tuples:          make(data.StringSet),
+	}
+
+	chunkData := func(query bson.M) bson.M {
+		return query["$push"].(bson.M)["dat"].(bson.M)["$each"].([]bson.M)[0]
+	}
+
+	// nothing is stored without provenance
+	query := mainQuery(datum, 100, 10, 0)
+	assert.NotContains(t, chunkData(query), "logs")
+	assert.NotContains(t, chunkData(query), "log_refs")
+
+	datum.LogPaths = data.StringSet{"/logs/b/conn.log": {}, "/logs/a/conn.log": {}}
+	datum.LogRefs = []string{"/logs/a/conn.log:8", "/logs/a/conn.log:9", "/logs/b/conn.log:8"}
+
+	query = mainQuery(datum, 100, 2, 0)
+	assert.Equal(t, []string{"/logs/a/conn.log", "/logs/b/conn.log"}, chunkData(query)["logs"])
+	assert.Equal(t, []string{"/logs/a/conn.log:9", "/logs/b/conn.log:8"}, chunkData(query)["log_refs"],
+		"the references should be capped like the uids")
+
+	// strobes keep their log paths but not the references to each connection
+	query = mainQuery(datum, 3, 2, 0)
+	assert.Equal(t, []string{"/logs/a/conn.log", "/logs/b/conn.log"}, chunkData(query)["logs"])
+	assert.Equal(t, []string{}, chunkData(query)["log_refs"])
+}
+
+func TestMainQueryBeaconCounts(t *testing.T) {
+	// the connections of excluded services are counted for the unique
+	// connection, but only the included ones decide if it is a strobe
+	datum := &Input{
+		ConnectionCount: 100,
+		TotalBytes:      10000,
+		BeaconCount:     2,
+		BeaconBytes:     300,
+		TsList:          []int64{1, 2},
+		Tuples:          make(data.StringSet),
+	}
+
+	query := mainQuery(datum, 50, 10, 0)
+	assert.Equal(t, false, query["$set"].(bson.M)["strobe"])
+	chunk := query["$push"].(bson.M)["dat"].(bson.M)["$each"].([]bson.M)[0]
+	assert.Equal(t, int64(100), chunk["count"])
+	assert.Equal(t, int64(10000), chunk["tbytes"])
+	assert.Equal(t, int64(2), chunk["bcount"])
+	assert.Equal(t, int64(300), chunk["btbytes"])
+	assert.Equal(t, []int64{1, 2}, chunk["ts"])
+
+	datum.BeaconCount = 50
+	query = mainQuery(datum, 50, 10, 0)
+	assert.Equal(t, true, query["$set"].(bson.M)["strobe"])
+}
+
+func TestMainQueryRetransmissionCounts(t *testing.T) {
+	datum := &Input{
+		ConnectionCount:  2,
+		TsList:           []int64{1, 2},
+		OrigIPBytes:      3000,
+		OrigPayloadBytes: 2000,
+		OrigPkts:         10,
+		Tuples:           make(data.StringSet),
+	}
+
+	chunk := mainQuery(datum, 100, 10, 0)["$push"].(bson.M)["dat"].(bson.M)["$each"].([]bson.M)[0]
+	assert.Equal(t, int64(3000), chunk["oipbytes"])
+	assert.Equal(t, int64(2000), chunk["obytes"])
+	assert.Equal(t, int64(10), chunk["opkts"])
+}
+
+func TestMainQueryLongestConnection(t *testing.T) {
+	datum := &Input{
+		ConnectionCount: 1,
+		TsList:          []int64{1},
+		MaxDuration:     7200,
+		MaxDurationConn: ConnTuple{SrcPort: 51234, DstPort: 443, Proto: "tcp"},
+		Tuples:          make(data.StringSet),
+	}
+
+	chunk := mainQuery(datum, 100, 10, 0)["$push"].(bson.M)["dat"].(bson.M)["$each"].([]bson.M)[0]
+	assert.Equal(t, 7200.0, chunk["maxdur"])
+	assert.Equal(t, ConnTuple{SrcPort: 51234, DstPort: 443, Proto: "tcp"}, chunk["maxdur_conn"])
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/activecm/rita/util"
 
 	"github.com/globalsign/mgo"
+	"github.com/globalsign/mgo/bson"
 	"github.com/vbauerster/mpb"
 	"github.com/vbauerster/mpb/decor"
 
@@ -73,7 +74,7 @@ func (r *repo) Upsert(uconnMap map[string]*Input, hostMap map[string]*host.Input
 	// Phase 1: Analysis
 
 	// Create the workers for analysis
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "uconn")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "uconn")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
@@ -127,7 +128,7 @@ func (r *repo) Upsert(uconnMap map[string]*Input, hostMap map[string]*host.Input
 	}
 
 	// initialize a new writer for the summarizer
-	writerWorker = database.NewBulkWriter(r.database, r.config, r.log, true, "uconn")
+	writerWorker = database.NewWriter(r.database, r.config, r.log, true, "uconn")
 	summarizerWorker := newSummarizer(
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
@@ -164,3 +165,51 @@ func (r *repo) Upsert(uconnMap map[string]*Input, hostMap map[string]*host.Input
 	// start the closing cascade (this will also close the other channels)
 	summarizerWorker.close()
 }
+
+// TimestampRange returns the earliest and latest timestamps of the unique
+// connections in the selected database. Since zeek records connections when they
+// close, some connections that started before the ingested observation period
+// can skew the range, so the range is capped to the last 24 hours for accurate
+// beaconing analysis. Returns mgo.ErrNotFound if there are no timestamps.
+func TimestampRange(db *database.DB, conf *config.Config) (int64, int64, error) {
+	session := db.Session.Copy()
+	defer session.Close()
+
+	// Build query for aggregation
+	timestampQuery := func(order int) []bson.M {
+		return []bson.M{
+			{"$project": bson.M{
+				"_id":     0,
+				"ts":      "$dat.ts",
+				"open_ts": bson.M{"$ifNull": []interface{}{"$open_ts", []interface{}{}}},
+			}},
+			{"$unwind": "$ts"},
+			{"$project": bson.M{"_id": 0, "ts": bson.M{"$concatArrays": []interface{}{"$ts", "$open_ts"}}}},
+			{"$unwind": "$ts"}, // Not an error, must unwind it twice
+			{"$sort": bson.M{"ts": order}},
+			{"$limit": 1},
+		}
+	}
+
+	var resultMin, resultMax struct {
+		Timestamp int64 `bson:"ts"`
+	}
+
+	collection := session.DB(db.GetSelectedDB()).C(conf.T.Structure.UniqueConnTable)
+
+	// sort by the timestamp, limit it to 1 (only returns first result)
+	err := collection.Pipe(timestampQuery(1)).AllowDiskUse().One(&resultMin)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = collection.Pipe(timestampQuery(-1)).AllowDiskUse().One(&resultMax)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tsMinCapped := resultMax.Timestamp - 24*60*60
+	if tsMinCapped > resultMin.Timestamp {
+		resultMin.Timestamp = tsMinCapped
+	}
+	return resultMin.Timestamp, resultMax.Timestamp, nil
+}
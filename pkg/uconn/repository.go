@@ -1,8 +1,12 @@
 package uconn
 
 import (
+	"net"
+
 	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/pkg/host"
+	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo/bson"
 )
 
 // Repository for uconn collection
@@ -18,26 +22,91 @@ type Input struct {
 	IsLocalSrc         bool
 	IsLocalDst         bool
 	TotalBytes         int64
+	BeaconCount        int64 // the connections kept for beacon analysis by the Beacon IncludedServices
+	BeaconBytes        int64 // the bytes of the connections kept for beacon analysis
+	OrigIPBytes        int64 // the IP bytes sent by the source, including retransmissions
+	OrigPayloadBytes   int64 // the payload bytes sent by the source, excluding retransmissions
+	OrigPkts           int64 // the packets sent by the source
 	MaxDuration        float64
+	MaxDurationConn    ConnTuple // the ports and protocol of the longest connection
 	TotalDuration      float64
 	TsList             []int64
+	RespTsList         []int64 // the last activity of each connection
 	UniqueTsListLength int64
 	OrigBytesList      []int64
+	RespBytesList      []int64
+	DurationList       []float64
+	UIDs               []string
+	LogPaths           data.StringSet // the log files the connections were read from
+	LogRefs            []string       // "<path>:<line>" references to the connection records
 	Tuples             data.StringSet
 	InvalidCertFlag    bool
 	UPPSFlag           bool
 	ConnStateMap       map[string]*ConnState
 }
 
+// StoredConnection identifies the connections between two hosts of a uconn
+// document so that they can be analyzed again without parsing the logs
+type StoredConnection struct {
+	data.UniqueIPPair `bson:",inline"`
+}
+
+// StoredConnectionFields selects the fields of a StoredConnection from a
+// uconn document
+var StoredConnectionFields = bson.M{
+	"src":              1,
+	"src_network_uuid": 1,
+	"src_network_name": 1,
+	"dst":              1,
+	"dst_network_uuid": 1,
+	"dst_network_name": 1,
+}
+
+// Input reconstructs the Input of the stored connections, marking the hosts
+// which are in the internal subnets as local. The connection details are
+// left empty since the beacon analysis reads them from the uconn document.
+func (s StoredConnection) Input(internal []*net.IPNet) *Input {
+	return &Input{
+		Hosts:      s.UniqueIPPair,
+		IsLocalSrc: util.ContainsIP(internal, net.ParseIP(s.SrcIP)),
+		IsLocalDst: util.ContainsIP(internal, net.ParseIP(s.DstIP)),
+	}
+}
+
+// ConnTuple holds the ports and protocol of a single connection
+// between two hosts
+type ConnTuple struct {
+	SrcPort int    `bson:"src_port"`
+	DstPort int    `bson:"dst_port"`
+	Proto   string `bson:"proto"`
+}
+
 // LongConnResult represents a pair of hosts that communicated and
-// the longest connection between those hosts.
+// the longest connection between those hosts. ConnTuple holds the
+// ports and protocol of the longest connection.
 type LongConnResult struct {
 	data.UniqueIPPair `bson:",inline"`
+	ConnTuple         `bson:",inline"`
 	MaxDuration       float64  `bson:"maxdur"`
 	Tuples            []string `bson:"tuples"`
 	Open              bool     `bson:"open"`
 }
 
+// TupleResult represents a pair of hosts that communicated and
+// the port:protocol:service tuples of their connections
+type TupleResult struct {
+	data.UniqueIPPair `bson:",inline"`
+	Tuples            []string `bson:"tuples"`
+}
+
+// ConnResult represents a pair of hosts that communicated and
+// the totals of the connections between those hosts.
+type ConnResult struct {
+	data.UniqueIPPair `bson:",inline"`
+	Connections       int64 `bson:"connection_count"`
+	TotalBytes        int64 `bson:"total_bytes"`
+}
+
 // OpenConnResult represents a pair of hosts that currently
 // have an open connection. It shows the current number of
 // bytes that have been transferred, the total duration thus far,
@@ -0,0 +1,40 @@
+package uconn
+
+import (
+	"net"
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/activecm/rita/util"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredConnectionInput(t *testing.T) {
+	pair := data.NewUniqueIPPair(
+		data.NewUniqueIP(net.ParseIP("10.0.0.1"), "", ""),
+		data.NewUniqueIP(net.ParseIP("203.0.113.1"), "", ""),
+	)
+
+	// read back from a uconn document as written by the analyzer
+	doc := pair.BSONKey()
+	doc["src_network_name"] = pair.SrcNetworkName
+	doc["dst_network_name"] = pair.DstNetworkName
+	doc["strobe"] = false
+	doc["dat"] = []bson.M{{"count": 5, "ts": []int64{1600000000}, "cid": 0}}
+	encoded, err := bson.Marshal(doc)
+	require.Nil(t, err)
+	var stored StoredConnection
+	require.Nil(t, bson.Unmarshal(encoded, &stored))
+
+	input := stored.Input(util.ParseSubnets([]string{"10.0.0.0/8"}))
+	assert.Equal(t, pair.MapKey(), input.Hosts.MapKey())
+	assert.Equal(t, pair.SrcNetworkName, input.Hosts.SrcNetworkName)
+	assert.True(t, input.IsLocalSrc)
+	assert.False(t, input.IsLocalDst)
+
+	// the connection details are read from MongoDB by the beacon analysis
+	assert.Zero(t, input.ConnectionCount)
+	assert.Nil(t, input.TsList)
+}
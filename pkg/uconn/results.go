@@ -1,6 +1,7 @@
 package uconn
 
 import (
+	"github.com/activecm/rita/pkg/data"
 	"github.com/activecm/rita/resources"
 	"github.com/globalsign/mgo/bson"
 )
@@ -26,6 +27,16 @@ func LongConnResults(res *resources.Resources, thresh int, limit int, noLimit bo
 			"maxdur":           "$dat.maxdur",
 			"tuples":           bson.M{"$ifNull": []interface{}{"$dat.tuples", []interface{}{}}},
 			"open":             1,
+			// keep the five-tuple of the longest connection across the chunks
+			"longest": bson.M{"$reduce": bson.M{
+				"input":        "$dat",
+				"initialValue": bson.M{"maxdur": -1},
+				"in": bson.M{"$cond": []interface{}{
+					bson.M{"$gt": []interface{}{"$$this.maxdur", "$$value.maxdur"}},
+					bson.M{"maxdur": "$$this.maxdur", "conn": "$$this.maxdur_conn"},
+					"$$value",
+				}},
+			}},
 		}},
 		{"$unwind": "$maxdur"},
 		{"$unwind": "$tuples"},
@@ -41,6 +52,7 @@ func LongConnResults(res *resources.Resources, thresh int, limit int, noLimit bo
 			"dst_network_name": bson.M{"$first": "$dst_network_name"},
 			"tuples":           bson.M{"$addToSet": "$tuples"},
 			"open":             bson.M{"$first": "$open"},
+			"longest":          bson.M{"$first": "$longest"},
 		}},
 		{"$project": bson.M{
 			"maxdur":           1,
@@ -52,6 +64,9 @@ func LongConnResults(res *resources.Resources, thresh int, limit int, noLimit bo
 			"dst_network_name": 1,
 			"tuples":           bson.M{"$slice": []interface{}{"$tuples", 5}},
 			"open":             1,
+			"src_port":         "$longest.conn.src_port",
+			"dst_port":         "$longest.conn.dst_port",
+			"proto":            "$longest.conn.proto",
 		}},
 		{"$sort": bson.M{"maxdur": -1}},
 	}
@@ -66,6 +81,40 @@ func LongConnResults(res *resources.Resources, thresh int, limit int, noLimit bo
 
 }
 
+//ConnResults returns each pair of hosts which communicated along with the total
+//connection count and bytes between them. The results will be sorted, descending by
+//connection count. limit and noLimit control how many results are returned.
+func ConnResults(res *resources.Resources, limit int, noLimit bool) ([]ConnResult, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var connResults []ConnResult
+
+	connQuery := []bson.M{
+		{"$project": bson.M{
+			"_id":              0,
+			"src":              1,
+			"src_network_uuid": 1,
+			"src_network_name": 1,
+			"dst":              1,
+			"dst_network_uuid": 1,
+			"dst_network_name": 1,
+			"connection_count": bson.M{"$sum": "$dat.count"},
+			"total_bytes":      bson.M{"$sum": "$dat.tbytes"},
+		}},
+		{"$sort": bson.M{"connection_count": -1}},
+	}
+
+	if !noLimit {
+		connQuery = append(connQuery, bson.M{"$limit": limit})
+	}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.UniqueConnTable).Pipe(connQuery).AllowDiskUse().All(&connResults)
+
+	return connResults, err
+
+}
+
 //OpenConnResults returns open connections. The results will be sorted, descending by duration.
 //limit and noLimit control how many results are returned.
 func OpenConnResults(res *resources.Resources, thresh int, limit int, noLimit bool) ([]OpenConnResult, error) {
@@ -114,3 +163,94 @@ func OpenConnResults(res *resources.Resources, thresh int, limit int, noLimit bo
 	return openConnResults, err
 
 }
+
+//tupleResultsBatchSize limits the number of pairs matched by each TupleResults query
+const tupleResultsBatchSize = 1000
+
+//TupleResults returns the port:protocol:service tuples stored for each of the
+//given pairs of hosts. Only the unique connections of those pairs are read.
+func TupleResults(res *resources.Resources, pairs []data.UniqueIPPair) ([]TupleResult, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var tupleResults []TupleResult
+
+	for start := 0; start < len(pairs); start += tupleResultsBatchSize {
+		end := start + tupleResultsBatchSize
+		if end > len(pairs) {
+			end = len(pairs)
+		}
+
+		var batch []TupleResult
+		err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.UniqueConnTable).Pipe(tupleQuery(pairs[start:end])).AllowDiskUse().All(&batch)
+		if err != nil {
+			return nil, err
+		}
+		tupleResults = append(tupleResults, batch...)
+	}
+
+	return tupleResults, nil
+}
+
+//tupleQuery returns the pipeline which gathers the tuples of the given pairs.
+//Each pair is matched on the unique connection index.
+func tupleQuery(pairs []data.UniqueIPPair) []bson.M {
+	selectors := make([]bson.M, 0, len(pairs))
+	for _, pair := range pairs {
+		selectors = append(selectors, pair.BSONKey())
+	}
+
+	return []bson.M{
+		{"$match": bson.M{"$or": selectors}},
+		{"$project": bson.M{
+			"src":              1,
+			"src_network_uuid": 1,
+			"src_network_name": 1,
+			"dst":              1,
+			"dst_network_uuid": 1,
+			"dst_network_name": 1,
+			"tuples":           bson.M{"$ifNull": []interface{}{"$dat.tuples", []interface{}{}}},
+		}},
+		{"$unwind": "$tuples"},
+		{"$unwind": "$tuples"}, // not an error, must be done twice
+		{"$group": bson.M{
+			"_id":              "$_id",
+			"src":              bson.M{"$first": "$src"},
+			"src_network_uuid": bson.M{"$first": "$src_network_uuid"},
+			"src_network_name": bson.M{"$first": "$src_network_name"},
+			"dst":              bson.M{"$first": "$dst"},
+			"dst_network_uuid": bson.M{"$first": "$dst_network_uuid"},
+			"dst_network_name": bson.M{"$first": "$dst_network_name"},
+			"tuples":           bson.M{"$addToSet": "$tuples"},
+		}},
+	}
+}
+
+//TimestampResults returns the timestamps of the connections from src to dst
+//stored in each chunk of their unique connections. Strobes do not store
+//their timestamps.
+func TimestampResults(res *resources.Resources, src, dst string) ([]int64, error) {
+	ssn := res.DB.Session.Copy()
+	defer ssn.Close()
+
+	var uconns []struct {
+		Dat []struct {
+			Ts []int64 `bson:"ts"`
+		} `bson:"dat"`
+	}
+
+	uconnQuery := bson.M{"src": src, "dst": dst}
+
+	err := ssn.DB(res.DB.GetSelectedDB()).C(res.Config.T.Structure.UniqueConnTable).Find(uconnQuery).Select(bson.M{"dat.ts": 1}).All(&uconns)
+	if err != nil {
+		return nil, err
+	}
+
+	var timestamps []int64
+	for _, uconn := range uconns {
+		for _, dat := range uconn.Dat {
+			timestamps = append(timestamps, dat.Ts...)
+		}
+	}
+	return timestamps, nil
+}
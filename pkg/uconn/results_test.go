@@ -0,0 +1,22 @@
+package uconn
+
+import (
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTupleQueryMatchesPairs(t *testing.T) {
+	pairs := []data.UniqueIPPair{
+		data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.1"}, data.UniqueIP{IP: "203.0.113.1"}),
+		data.NewUniqueIPPair(data.UniqueIP{IP: "10.0.0.2"}, data.UniqueIP{IP: "203.0.113.2"}),
+	}
+
+	// only the unique connections of the given pairs are read
+	query := tupleQuery(pairs)
+	require.NotEmpty(t, query)
+	assert.Equal(t, bson.M{"$or": []bson.M{pairs[0].BSONKey(), pairs[1].BSONKey()}}, query[0]["$match"])
+}
@@ -54,6 +54,8 @@ func (s *summarizer) close() {
 func (s *summarizer) start() {
 	s.summaryWg.Add(1)
 	go func() {
+		s.conf.R.AnalysisLimiter.Acquire()
+		defer s.conf.R.AnalysisLimiter.Release()
 
 		ssn := s.db.Session.Copy()
 		defer ssn.Close()
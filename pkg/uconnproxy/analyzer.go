@@ -54,6 +54,8 @@ func (a *analyzer) close() {
 func (a *analyzer) start() {
 	a.analysisWg.Add(1)
 	go func() {
+		a.conf.R.AnalysisLimiter.Acquire()
+		defer a.conf.R.AnalysisLimiter.Release()
 
 		for datum := range a.analysisChannel {
 
@@ -61,7 +63,7 @@ func (a *analyzer) start() {
 
 			a.analyzedCallback(database.BulkChanges{
 				a.conf.T.Structure.UniqueConnProxyTable: []database.BulkChange{{
-					Selector: datum.Hosts.BSONKey(),
+					Selector: datum.Hosts.Canonical().BSONKey(),
 					Update:   mainUpdate,
 					Upsert:   true,
 				}},
@@ -80,27 +82,37 @@ func mainQuery(datum *Input, strobeLimit int64, chunk int) bson.M {
 	// it will not qualify to be downgraded to a proxy beacon until this chunk is
 	// outdated and removed. If only importing once - still just a strobe.
 	ts := datum.TsList
+	bytes := datum.BytesList
 
 	isStrobe := datum.ConnectionCount >= strobeLimit
 	if isStrobe {
 		ts = []int64{}
+		bytes = []int64{}
 	}
 
-	return bson.M{
+	query := bson.M{
 		"$set": bson.M{
 			"strobeFQDN":       isStrobe,
 			"cid":              chunk,
 			"src_network_name": datum.Hosts.SrcNetworkName,
 			"proxy":            datum.Proxy,
 		},
+		// the proxies are recorded with each chunk so that the proxies of
+		// outdated chunks are removed along with their connections
 		"$push": bson.M{
 			"dat": bson.M{
 				"$each": []bson.M{{
-					"count": datum.ConnectionCount,
-					"ts":    ts,
-					"cid":   chunk,
+					"count":   datum.ConnectionCount,
+					"ts":      ts,
+					"bytes":   bytes,
+					"proxies": datum.SortedProxies(),
+					"cid":     chunk,
 				}},
 			},
 		},
+		// older versions of RITA stored the proxies of the latest import here
+		"$unset": bson.M{"proxy_list": ""},
 	}
+
+	return query
 }
@@ -64,7 +64,7 @@ func (r *repo) CreateIndexes() error {
 // Upsert records the given proxy connection data in MongoDB
 func (r *repo) Upsert(uconnProxyMap map[string]*Input) {
 	// Create the workers
-	writerWorker := database.NewBulkWriter(r.database, r.config, r.log, true, "uconnproxy")
+	writerWorker := database.NewWriter(r.database, r.config, r.log, true, "uconnproxy")
 
 	analyzerWorker := newAnalyzer(
 		r.config.S.Rolling.CurrentChunk,
@@ -1,7 +1,10 @@
 package uconnproxy
 
 import (
+	"sort"
+
 	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
 )
 
 // Repository for uconnproxy collection
@@ -17,10 +20,94 @@ type Repository interface {
 // Contains a list of unique time stamps for the
 // connections out from the Src to the FQDN via the
 // proxy server and a count of the connections.
+// BytesList holds the HTTP body bytes of each
+// connection.
+// ForwardedTsList and ForwardedBytesList hold the
+// connections a proxy forwarded on behalf of the Src
+// until they are collapsed into the connections of
+// the Src.
+// Proxy holds the first proxy seen servicing the
+// connections while Proxies holds every proxy seen.
 type Input struct {
-	Hosts           data.UniqueSrcFQDNPair
-	TsList          []int64
-	TsListFull      []int64
-	Proxy           data.UniqueIP
-	ConnectionCount int64
+	Hosts              data.UniqueSrcFQDNPair
+	TsList             []int64
+	TsListFull         []int64
+	BytesList          []int64
+	ForwardedTsList    []int64
+	ForwardedBytesList []int64
+	Proxy              data.UniqueIP
+	Proxies            data.UniqueIPSet
+	ConnectionCount    int64
+}
+
+// SortedProxies returns every proxy which serviced the connections sorted by IP address
+func (i *Input) SortedProxies() []data.UniqueIP {
+	proxies := i.Proxies.Items()
+	sort.Slice(proxies, func(a, b int) bool {
+		return proxies[a].MapKey() < proxies[b].MapKey()
+	})
+	return proxies
+}
+
+// ProxyList returns the proxies which serviced the connections sorted by
+// IP address. It returns nil if the connections went through a single proxy
+// since the proxy is already recorded by Proxy.
+func (i *Input) ProxyList() []data.UniqueIP {
+	if len(i.Proxies) < 2 {
+		return nil
+	}
+	return i.SortedProxies()
+}
+
+// AddStoredProxies adds the proxies recorded for each chunk of a uconnproxy
+// document to the proxies of the input
+func (i *Input) AddStoredProxies(chunkProxies [][]data.UniqueIP) {
+	if i.Proxies == nil {
+		i.Proxies = make(data.UniqueIPSet)
+	}
+	for _, proxies := range chunkProxies {
+		for _, proxy := range proxies {
+			i.Proxies.Insert(proxy)
+		}
+	}
+}
+
+// StoredConnection identifies the proxied connections of a uconnproxy
+// document so that they can be analyzed again without parsing the logs
+type StoredConnection struct {
+	data.UniqueSrcFQDNPair `bson:",inline"`
+	Proxy                  data.UniqueIP `bson:"proxy"`
+	Dat                    []struct {
+		Proxies []data.UniqueIP `bson:"proxies"`
+	} `bson:"dat"`
+}
+
+// StoredConnectionFields selects the fields of a StoredConnection from a
+// uconnproxy document
+var StoredConnectionFields = bson.M{
+	"src":              1,
+	"src_network_uuid": 1,
+	"src_network_name": 1,
+	"fqdn":             1,
+	"proxy":            1,
+	"dat.proxies":      1,
+}
+
+// Input reconstructs the Input of the stored connections. The timestamps
+// and sizes are left empty since the proxy beacon analysis reads them from
+// the uconnproxy document.
+func (s StoredConnection) Input() *Input {
+	proxies := make(data.UniqueIPSet)
+	proxies.Insert(s.Proxy)
+	for _, datum := range s.Dat {
+		for _, proxy := range datum.Proxies {
+			proxies.Insert(proxy)
+		}
+	}
+
+	return &Input{
+		Hosts:   s.UniqueSrcFQDNPair,
+		Proxy:   s.Proxy,
+		Proxies: proxies,
+	}
 }
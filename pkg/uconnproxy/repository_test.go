@@ -0,0 +1,83 @@
+package uconnproxy
+
+import (
+	"net"
+	"testing"
+
+	"github.com/activecm/rita/pkg/data"
+	"github.com/globalsign/mgo/bson"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestStoredConnection reads a StoredConnection back from a uconnproxy
+// document as written by the analyzer
+func newTestStoredConnection(t *testing.T, datum *Input) StoredConnection {
+	query := mainQuery(datum, 100, 0)
+	doc := datum.Hosts.BSONKey()
+	for key, value := range query["$set"].(bson.M) {
+		doc[key] = value
+	}
+	doc["dat"] = query["$push"].(bson.M)["dat"].(bson.M)["$each"]
+
+	encoded, err := bson.Marshal(doc)
+	require.Nil(t, err)
+	var stored StoredConnection
+	require.Nil(t, bson.Unmarshal(encoded, &stored))
+	return stored
+}
+
+func TestStoredConnectionInput(t *testing.T) {
+	src := data.NewUniqueIP(net.ParseIP("10.0.0.1"), "", "")
+	proxy := data.NewUniqueIP(net.ParseIP("10.0.0.250"), "", "")
+
+	datum := &Input{
+		Hosts:           data.NewUniqueSrcFQDNPair(src, "example.com"),
+		Proxy:           proxy,
+		Proxies:         data.UniqueIPSet{proxy.MapKey(): proxy},
+		ConnectionCount: 20,
+		TsList:          []int64{1600000000, 1600000060},
+	}
+
+	input := newTestStoredConnection(t, datum).Input()
+	assert.Equal(t, datum.Hosts.MapKey(), input.Hosts.MapKey())
+	assert.Equal(t, "example.com", input.Hosts.FQDN)
+	assert.Equal(t, proxy, input.Proxy)
+	assert.Equal(t, datum.Proxies, input.Proxies)
+	assert.Nil(t, input.ProxyList())
+
+	// the connection details are read from MongoDB by the proxy beacon analysis
+	assert.Zero(t, input.ConnectionCount)
+	assert.Nil(t, input.TsList)
+}
+
+func TestStoredConnectionInputProxyList(t *testing.T) {
+	src := data.NewUniqueIP(net.ParseIP("10.0.0.1"), "", "")
+	proxy := data.NewUniqueIP(net.ParseIP("10.0.0.250"), "", "")
+	other := data.NewUniqueIP(net.ParseIP("10.0.0.251"), "", "")
+
+	datum := &Input{
+		Hosts:   data.NewUniqueSrcFQDNPair(src, "example.com"),
+		Proxy:   proxy,
+		Proxies: data.UniqueIPSet{proxy.MapKey(): proxy, other.MapKey(): other},
+	}
+
+	input := newTestStoredConnection(t, datum).Input()
+	assert.Equal(t, proxy, input.Proxy)
+	assert.Equal(t, datum.ProxyList(), input.ProxyList())
+}
+
+func TestAddStoredProxies(t *testing.T) {
+	proxy := data.UniqueIP{IP: "10.0.0.250"}
+	other := data.UniqueIP{IP: "10.0.0.251"}
+
+	// the current import only saw one proxy, but an earlier chunk saw another
+	input := &Input{Proxy: proxy, Proxies: data.UniqueIPSet{proxy.MapKey(): proxy}}
+	input.AddStoredProxies([][]data.UniqueIP{{proxy}, {other, proxy}})
+	assert.Equal(t, []data.UniqueIP{proxy, other}, input.ProxyList())
+
+	// once the chunk with the other proxy is removed, only one proxy remains
+	input = &Input{Proxy: proxy, Proxies: data.UniqueIPSet{proxy.MapKey(): proxy}}
+	input.AddStoredProxies([][]data.UniqueIP{{proxy}, nil})
+	assert.Nil(t, input.ProxyList())
+}
@@ -78,7 +78,7 @@ func (r *repo) Upsert(userAgentMap map[string]*Input) {
 	}
 
 	// Create the workers
-	writerWorker := database.NewBulkWriter(
+	writerWorker := database.NewWriter(
 		r.database,
 		r.config,
 		r.log,
@@ -124,7 +124,7 @@ func (r *repo) Upsert(userAgentMap map[string]*Input) {
 	// 2nd Phase: Summarize
 
 	// initialize a new writer for the summarizer
-	writerWorker = database.NewBulkWriter(r.database, r.config, r.log, true, "useragent")
+	writerWorker = database.NewWriter(r.database, r.config, r.log, true, "useragent")
 	summarizerWorker := newSummarizer(
 		r.config.S.Rolling.CurrentChunk,
 		r.database,
@@ -25,20 +25,42 @@ func initLogger(logConfig *config.LogStaticCfg) *log.Logger {
 	logs.Out = ioutil.Discard
 	logs.Hooks = make(log.LevelHooks)
 
-	switch logConfig.LogLevel {
+	logs.Level = logLevel(logConfig.LogLevel)
+	if logConfig.LogToFile {
+		addFileLogger(logs, logConfig.RitaLogPath)
+	}
+	return logs
+}
+
+// logLevel converts a RITA log level from 0 (error) to 3 (debug) to a logrus level
+func logLevel(level int) log.Level {
+	switch level {
 	case 3:
-		logs.Level = log.DebugLevel
+		return log.DebugLevel
 	case 2:
-		logs.Level = log.InfoLevel
+		return log.InfoLevel
 	case 1:
-		logs.Level = log.WarnLevel
+		return log.WarnLevel
 	case 0:
-		logs.Level = log.ErrorLevel
+		return log.ErrorLevel
 	}
-	if logConfig.LogToFile {
-		addFileLogger(logs, logConfig.RitaLogPath)
+	return log.PanicLevel
+}
+
+// initStageLogger creates the logger for a stage of an import. It shares the
+// output and hooks of logger, including hooks added later, but logs at its own
+// level. A level of -1 returns logger itself.
+func initStageLogger(logger *log.Logger, level int) *log.Logger {
+	if level < 0 {
+		return logger
+	}
+	return &log.Logger{
+		Out:       logger.Out,
+		Formatter: logger.Formatter,
+		Hooks:     logger.Hooks,
+		Level:     logLevel(level),
+		ExitFunc:  logger.ExitFunc,
 	}
-	return logs
 }
 
 func addFileLogger(logger *log.Logger, logPath string) {
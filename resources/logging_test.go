@@ -0,0 +1,54 @@
+package resources
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/activecm/rita/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitStageLogger(t *testing.T) {
+	logConfig := &config.LogStaticCfg{
+		LogLevel:  2,
+		LogLevels: config.LogLevelsStaticCfg{Import: 3, Analysis: 0},
+	}
+	logger := initLogger(logConfig)
+
+	importLog := initStageLogger(logger, logConfig.LogLevels.Import)
+	analysisLog := initStageLogger(logger, logConfig.LogLevels.Analysis)
+	assert.Equal(t, log.DebugLevel, importLog.Level)
+	assert.Equal(t, log.ErrorLevel, analysisLog.Level)
+	assert.Equal(t, log.InfoLevel, logger.Level, "the stages must not change the global level")
+
+	// hooks added after the stage loggers are created still receive their entries
+	hook := test.NewLocal(logger)
+
+	importLog.Debug("parsed conn.log")
+	analysisLog.Warn("beacon analysis is slow")
+	analysisLog.Error("could not build beacons")
+	logger.Debug("global debug")
+
+	entries := hook.AllEntries()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "parsed conn.log", entries[0].Message)
+		assert.Equal(t, "could not build beacons", entries[1].Message)
+	}
+}
+
+func TestInitStageLoggerDefault(t *testing.T) {
+	logger := initLogger(&config.LogStaticCfg{LogLevel: 1})
+	var buf bytes.Buffer
+	logger.Out = &buf
+
+	// a stage without its own level logs through the global logger
+	stageLog := initStageLogger(logger, -1)
+	assert.Same(t, logger, stageLog)
+
+	stageLog.Info("hidden")
+	stageLog.Warn("shown")
+	assert.NotContains(t, buf.String(), "hidden")
+	assert.Contains(t, buf.String(), "shown")
+}
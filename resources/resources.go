@@ -7,28 +7,57 @@ import (
 	"github.com/activecm/mgorus"
 	"github.com/activecm/rita/config"
 	"github.com/activecm/rita/database"
+	"github.com/activecm/rita/pkg/sink"
 	log "github.com/sirupsen/logrus"
 )
 
 type (
 	// Resources provides a data structure for passing system Resources
 	Resources struct {
-		Config *config.Config
-		Log    *log.Logger
-		DB     *database.DB
-		MetaDB *database.MetaDB
+		Config      *config.Config
+		Log         *log.Logger
+		ImportLog   *log.Logger // logs the reading and parsing of log files
+		AnalysisLog *log.Logger // logs the analysis of the parsed records
+		DB          *database.DB
+		MetaDB      *database.MetaDB
 	}
 )
 
 // InitResources grabs the configuration file and intitializes the configuration data
 // returning a *Resources object which has all of the necessary configuration information
 func InitResources(userConfig string) *Resources {
+	return initResources(userConfig, "", false)
+}
+
+// InitReadOnlyResources works like InitResources but forbids writing to the
+// database regardless of the MongoDB ReadOnly setting in the configuration file
+func InitReadOnlyResources(userConfig string) *Resources {
+	return initResources(userConfig, "", true)
+}
+
+// InitConnectionResources works like InitResources but connects to the MongoDB
+// cluster registered under the connection alias in the configuration file.
+// An empty alias uses the default connection.
+func InitConnectionResources(userConfig string, connection string, readOnly bool) *Resources {
+	return initResources(userConfig, connection, readOnly)
+}
+
+func initResources(userConfig string, connection string, readOnly bool) *Resources {
 	conf, err := config.LoadConfig(userConfig)
 	if err != nil {
 		fmt.Fprintf(os.Stdout, "Failed to config: %s\n", err.Error())
 		os.Exit(-1)
 	}
 
+	if err := conf.UseConnection(connection); err != nil {
+		fmt.Fprintf(os.Stdout, "Failed to config: %s\n", err.Error())
+		os.Exit(-1)
+	}
+
+	if readOnly {
+		conf.S.MongoDB.ReadOnly = true
+	}
+
 	// Fire up the logging system
 	log := initLogger(&conf.S.Log)
 
@@ -43,7 +72,8 @@ func InitResources(userConfig string) *Resources {
 	metaDB := database.NewMetaDB(conf, db.Session, log)
 
 	//Begin logging to the metadatabase
-	if conf.S.Log.LogToDB {
+	//logging to the metadatabase is a write, so it is skipped in read-only mode
+	if conf.S.Log.LogToDB && !conf.S.MongoDB.ReadOnly {
 		log.Hooks.Add(
 			mgorus.NewHookerFromSession(
 				db.Session, conf.S.MongoDB.MetaDB, conf.T.Log.RitaLogTable,
@@ -53,10 +83,27 @@ func InitResources(userConfig string) *Resources {
 
 	//bundle up the system resources
 	r := &Resources{
-		Config: conf,
-		Log:    log,
-		DB:     db,
-		MetaDB: metaDB,
+		Config:      conf,
+		Log:         log,
+		ImportLog:   initStageLogger(log, conf.S.Log.LogLevels.Import),
+		AnalysisLog: initStageLogger(log, conf.S.Log.LogLevels.Analysis),
+		DB:          db,
+		MetaDB:      metaDB,
 	}
 	return r
 }
+
+// OpenSinks sets up the sinks in the Sinks section of the config file, which
+// receive a copy of the analysis results. It is only called by the commands
+// which analyze data. A sink which can't be set up is skipped so the results
+// still reach MongoDB.
+func (r *Resources) OpenSinks() {
+	for _, sinkConf := range r.Config.S.Sinks {
+		resultSink, err := sink.New(sinkConf, r.DB.GetSelectedDB)
+		if err != nil {
+			r.Log.WithField("Sink", sinkConf.Type).Error("Failed to set up result sink: ", err)
+			continue
+		}
+		r.DB.AddSink(resultSink)
+	}
+}
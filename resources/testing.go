@@ -54,10 +54,12 @@ func InitIntegrationTestingResources(t *testing.T) *Resources {
 
 	//bundle up the system resources
 	r := &Resources{
-		Config: conf,
-		Log:    log,
-		DB:     db,
-		MetaDB: metaDB,
+		Config:      conf,
+		Log:         log,
+		ImportLog:   initStageLogger(log, conf.S.Log.LogLevels.Import),
+		AnalysisLog: initStageLogger(log, conf.S.Log.LogLevels.Analysis),
+		DB:          db,
+		MetaDB:      metaDB,
 	}
 	return r
 }
@@ -96,10 +98,12 @@ func InitTestResources() *Resources {
 
 	//bundle up the system resources
 	r := &Resources{
-		Config: conf,
-		Log:    log,
-		DB:     db,
-		MetaDB: metaDB,
+		Config:      conf,
+		Log:         log,
+		ImportLog:   initStageLogger(log, conf.S.Log.LogLevels.Import),
+		AnalysisLog: initStageLogger(log, conf.S.Log.LogLevels.Analysis),
+		DB:          db,
+		MetaDB:      metaDB,
 	}
 	return r
 }
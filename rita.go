@@ -14,7 +14,7 @@ func main() {
 	app := cli.NewApp()
 	app.Name = "rita"
 	app.Usage = "Look for evil needles in big haystacks."
-	app.Flags = []cli.Flag{commands.ConfigFlag}
+	app.Flags = []cli.Flag{commands.ConfigFlag, commands.ReadOnlyFlag, commands.ConnectionFlag}
 
 	cli.VersionPrinter = commands.GetVersionPrinter()
 
@@ -25,7 +25,17 @@ func main() {
 
 	// Define commands used with this application
 	app.Commands = commands.Commands()
-	app.Before = commands.SetConfigFilePath
+	app.Before = func(c *cli.Context) error {
+		err := commands.SetConfigFilePath(c)
+		if err != nil {
+			return err
+		}
+		err = commands.SetReadOnly(c)
+		if err != nil {
+			return err
+		}
+		return commands.SetConnection(c)
+	}
 
 	runtime.GOMAXPROCS(runtime.NumCPU())
 	app.Run(os.Args)
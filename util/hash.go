@@ -0,0 +1,41 @@
+package util
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+//FixedStringHashSize is the number of bytes held by a FixedStringHash
+const FixedStringHashSize = 16
+
+//FixedStringHash is a fixed size digest of a list of strings. It indexes and
+//compares faster than the strings it was derived from.
+type FixedStringHash [FixedStringHashSize]byte
+
+//NewFixedStringHash hashes the given strings in order. Each string is prefixed
+//with its length so lists which concatenate to the same text hash differently,
+//e.g. ("ab", "c") and ("a", "bc").
+func NewFixedStringHash(strs ...string) FixedStringHash {
+	hasher := sha256.New()
+	var length [binary.MaxVarintLen64]byte
+	for _, str := range strs {
+		n := binary.PutUvarint(length[:], uint64(len(str)))
+		hasher.Write(length[:n])
+		hasher.Write([]byte(str))
+	}
+
+	var hash FixedStringHash
+	copy(hash[:], hasher.Sum(nil))
+	return hash
+}
+
+//Bytes returns the digest as a byte slice
+func (h FixedStringHash) Bytes() []byte {
+	return h[:]
+}
+
+//String returns the digest as hex
+func (h FixedStringHash) String() string {
+	return hex.EncodeToString(h[:])
+}
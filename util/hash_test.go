@@ -0,0 +1,37 @@
+package util
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFixedStringHash(t *testing.T) {
+	hash := NewFixedStringHash("10.0.0.1", "8.8.8.8")
+	assert.Len(t, hash.Bytes(), FixedStringHashSize)
+	assert.Len(t, hash.String(), 2*FixedStringHashSize)
+
+	// the hash is deterministic
+	assert.Equal(t, hash, NewFixedStringHash("10.0.0.1", "8.8.8.8"))
+
+	// order matters
+	assert.NotEqual(t, hash, NewFixedStringHash("8.8.8.8", "10.0.0.1"))
+
+	// strings which concatenate to the same text must not collide
+	assert.NotEqual(t, NewFixedStringHash("ab", "c"), NewFixedStringHash("a", "bc"))
+	assert.NotEqual(t, NewFixedStringHash("abc"), NewFixedStringHash("abc", ""))
+	assert.NotEqual(t, NewFixedStringHash("10.0.0.1", "1.1.1.1"), NewFixedStringHash("10.0.0.11", ".1.1.1"))
+}
+
+func BenchmarkNewFixedStringHash(b *testing.B) {
+	srcs := make([]string, 1024)
+	for i := range srcs {
+		srcs[i] = "10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256)
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewFixedStringHash(srcs[i%len(srcs)], "8.8.8.8")
+	}
+}
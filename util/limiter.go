@@ -0,0 +1,33 @@
+package util
+
+// Limiter caps the number of goroutines doing work at the same time. Each
+// goroutine calls Acquire before it starts working and Release once it is
+// done. A nil Limiter places no limit on the goroutines.
+type Limiter struct {
+	slots chan struct{}
+}
+
+// NewLimiter creates a Limiter which lets max goroutines work at once.
+// A max of 0 or less returns a nil Limiter which does not limit anything.
+func NewLimiter(max int) *Limiter {
+	if max <= 0 {
+		return nil
+	}
+	return &Limiter{slots: make(chan struct{}, max)}
+}
+
+// Acquire blocks until the calling goroutine may start working
+func (l *Limiter) Acquire() {
+	if l == nil {
+		return
+	}
+	l.slots <- struct{}{}
+}
+
+// Release frees the slot taken by Acquire for another goroutine
+func (l *Limiter) Release() {
+	if l == nil {
+		return
+	}
+	<-l.slots
+}
@@ -0,0 +1,70 @@
+package util
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// runLimitedWorkers starts the given number of workers which each hold the
+// limiter while they work and returns the most workers seen working at once
+func runLimitedWorkers(limiter *Limiter, workers int) int64 {
+	var active, maxActive int64
+	wg := new(sync.WaitGroup)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limiter.Acquire()
+			defer limiter.Release()
+
+			current := atomic.AddInt64(&active, 1)
+			for {
+				seen := atomic.LoadInt64(&maxActive)
+				if current <= seen || atomic.CompareAndSwapInt64(&maxActive, seen, current) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt64(&active, -1)
+		}()
+	}
+	wg.Wait()
+	return maxActive
+}
+
+func TestLimiter(t *testing.T) {
+	// workers from several pools share the same limiter
+	limiter := NewLimiter(3)
+	var maxActive int64
+	wg := new(sync.WaitGroup)
+	results := make([]int64, 4)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = runLimitedWorkers(limiter, 8)
+		}(i)
+	}
+	wg.Wait()
+	for _, result := range results {
+		if result > maxActive {
+			maxActive = result
+		}
+	}
+	assert.LessOrEqual(t, maxActive, int64(3))
+
+	assert.Equal(t, int64(3), runLimitedWorkers(NewLimiter(3), 12), "the limit should be used once there is enough work")
+	assert.Equal(t, int64(1), runLimitedWorkers(NewLimiter(1), 4))
+}
+
+func TestLimiterUnlimited(t *testing.T) {
+	assert.Nil(t, NewLimiter(0))
+	assert.Nil(t, NewLimiter(-1))
+
+	// a nil limiter lets every worker run
+	assert.Equal(t, int64(6), runLimitedWorkers(nil, 6))
+}
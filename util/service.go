@@ -0,0 +1,62 @@
+package util
+
+import "strings"
+
+// ServiceFilter limits an analysis to the connections which used one of the
+// services an analyst cares about, such as HTTP, HTTPS, and DNS. The Beacon
+// IncludedServices filter is applied to each connection as the logs are
+// parsed, so the connections of other services never become beacon candidates.
+type ServiceFilter struct {
+	services [][]string // port, protocol, and optionally service of each included service
+}
+
+// NewServiceFilter creates a ServiceFilter from "port:protocol" and
+// "port:protocol:service" entries. No filter is returned if there are no
+// entries, since every connection is included then. The entries are checked
+// when the config is loaded.
+func NewServiceFilter(entries []string) *ServiceFilter {
+	if len(entries) == 0 {
+		return nil
+	}
+	s := &ServiceFilter{}
+	for _, entry := range entries {
+		s.services = append(s.services, ParseService(entry))
+	}
+	return s
+}
+
+// Included reports whether the port:protocol:service tuple of a connection
+// matches an included service. A nil filter includes every connection.
+func (s *ServiceFilter) Included(tuple string) bool {
+	if s == nil {
+		return true
+	}
+	for _, service := range s.services {
+		if MatchService(service, tuple) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseService splits a "port:protocol" or "port:protocol:service" entry
+// into its lower cased fields
+func ParseService(entry string) []string {
+	return strings.Split(strings.ToLower(entry), ":")
+}
+
+// MatchService reports whether a port:protocol:service tuple matches the
+// fields of a parsed service entry. Entries without a service name match the
+// port and protocol regardless of the service Zeek identified.
+func MatchService(service []string, tuple string) bool {
+	fields := strings.Split(strings.ToLower(tuple), ":")
+	if len(fields) < len(service) {
+		return false
+	}
+	for i := range service {
+		if fields[i] != service[i] {
+			return false
+		}
+	}
+	return true
+}
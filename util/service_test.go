@@ -0,0 +1,38 @@
+package util
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceFilter(t *testing.T) {
+	s := NewServiceFilter([]string{"80:tcp", "443:tcp", "53:udp:dns"})
+
+	testCases := []struct {
+		msg      string
+		tuple    string
+		included bool
+	}{
+		{"http", "80:tcp:http", true},
+		{"https without a recognized service", "443:tcp:-", true},
+		{"dns", "53:udp:dns", true},
+		{"service name must match when listed", "53:udp:-", false},
+		{"protocol must match", "443:udp:quic", false},
+		{"ntp is excluded", "123:udp:ntp", false},
+		{"ssh on another port is excluded", "2222:tcp:ssh", false},
+		{"no tuple", "", false},
+	}
+
+	for _, test := range testCases {
+		assert.Equal(t, test.included, s.Included(test.tuple), test.msg)
+	}
+}
+
+func TestServiceFilterEmpty(t *testing.T) {
+	// every connection is a beacon candidate without any included services
+	s := NewServiceFilter(nil)
+	assert.Nil(t, s)
+	assert.True(t, s.Included("123:udp:ntp"))
+	assert.True(t, s.Included(""))
+}
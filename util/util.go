@@ -110,6 +110,15 @@ func Int64InSlice(value int64, list []int64) bool {
 	return false
 }
 
+//SampleStrings returns a copy of the last max elements of the array. These are
+//the most recently appended elements when the array is built up over time.
+func SampleStrings(list []string, max int) []string {
+	start := Max(0, len(list)-Max(0, max))
+	sample := make([]string, len(list)-start)
+	copy(sample, list[start:])
+	return sample
+}
+
 const (
 	day  = time.Minute * 60 * 24
 	year = 365 * day
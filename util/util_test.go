@@ -108,3 +108,18 @@ func TestStringInSlice(t *testing.T) {
 	}
 
 }
+
+func TestSampleStrings(t *testing.T) {
+	list := []string{"a", "b", "c", "d"}
+
+	assert.Equal(t, []string{"c", "d"}, SampleStrings(list, 2))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, SampleStrings(list, 4))
+	assert.Equal(t, []string{"a", "b", "c", "d"}, SampleStrings(list, 10))
+	assert.Equal(t, []string{}, SampleStrings(list, 0))
+	assert.Equal(t, []string{}, SampleStrings(nil, 5))
+
+	// the sample must not alias the input
+	sample := SampleStrings(list, 2)
+	sample[0] = "z"
+	assert.Equal(t, "c", list[2])
+}